@@ -0,0 +1,133 @@
+// Package access serves the stats of the private channels only to the api
+// tokens allowed them, the others to anyone unless a token is required:
+//
+//	a := access.New(map[string][]string{"zkpq": {"#bots"}}, tokens, false)
+//	http.ListenAndServe(":8080", a.Handler(srv))
+//
+// The handlers served find what the request may read with RequestVisibility.
+package access
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// All is the scope of the tokens that may read every private channel, and the
+// channel of the networks whose channels are all private.
+const All = "*"
+
+// token is an api token and the channels it may read, keyed as the private
+// channels.
+type token struct {
+	token   []byte
+	allowed map[string]bool
+}
+
+// Access is who may read the stats of the channels served.
+type Access struct {
+	// private are the private channels by network and channel in lower
+	// case apart by a space, with the channel * for every channel of the
+	// network.
+	private      map[string]bool
+	tokens       []token
+	requireToken bool
+}
+
+// New makes the access to the private channels of each network, or all of
+// them with *, for the tokens allowed the networks and channels apart by a
+// space, or every channel with *. Without a token, only the public channels
+// are read, when no token is required.
+func New(private map[string][]string, tokens map[string][]string, requireToken bool) *Access {
+	a := &Access{
+		private:      make(map[string]bool),
+		tokens:       make([]token, 0, len(tokens)),
+		requireToken: requireToken,
+	}
+	for network, channels := range private {
+		for _, channel := range channels {
+			a.private[strings.ToLower(network+" "+channel)] = true
+		}
+	}
+	for t, scopes := range tokens {
+		allowed := make(map[string]bool, len(scopes))
+		for _, scope := range scopes {
+			allowed[strings.ToLower(strings.Join(strings.Fields(scope), " "))] = true
+		}
+		a.tokens = append(a.tokens, token{token: []byte(t), allowed: allowed})
+	}
+	return a
+}
+
+// allowed are the channels a token may read, ok is false for unknown tokens.
+// Every token is compared in constant time, so how long it takes tells
+// nothing of the tokens.
+func (a *Access) allowed(t string) (allowed map[string]bool, ok bool) {
+	for _, known := range a.tokens {
+		if subtle.ConstantTimeCompare(known.token, []byte(t)) == 1 {
+			allowed, ok = known.allowed, true
+		}
+	}
+	return allowed, ok
+}
+
+// Visibility tells if the stats of a channel may be served, every channel's
+// may when it's nil.
+type Visibility func(network, channel string) bool
+
+// Allows checks if the stats of the channel may be served.
+func (v Visibility) Allows(network, channel string) bool {
+	return v == nil || v(network, channel)
+}
+
+// Visibility is what a token may read, the public channels only without one
+// or with an unknown one.
+func (a *Access) Visibility(t string, ok bool) Visibility {
+	var allowed map[string]bool
+	if ok {
+		allowed, _ = a.allowed(t)
+	}
+	return func(network, channel string) bool {
+		network, channel = strings.ToLower(network), strings.ToLower(channel)
+		key := network + " " + channel
+		all := network + " " + All
+		if !a.private[key] && !a.private[all] {
+			return true
+		}
+		return allowed[All] || allowed[all] || allowed[key]
+	}
+}
+
+// requestToken is the token of the request, as a bearer token or ?token=.
+func requestToken(r *http.Request) (string, bool) {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer "), true
+	}
+	t := r.URL.Query().Get("token")
+	return t, len(t) > 0
+}
+
+// visibilityKey is the key of the visibility of a request in its context.
+type visibilityKey struct{}
+
+// Handler serves the requests to h with what their token may read, those
+// with an unknown token, or without one when it's required, are turned away.
+func (a *Access) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := requestToken(r)
+		if _, known := a.allowed(t); ok && !known || !ok && a.requireToken {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ircstats"`)
+			http.Error(w, "Unknown token.", http.StatusUnauthorized)
+			return
+		}
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), visibilityKey{}, a.Visibility(t, ok))))
+	})
+}
+
+// RequestVisibility is what the request may read, every channel when it
+// didn't go through an access handler.
+func RequestVisibility(r *http.Request) Visibility {
+	v, _ := r.Context().Value(visibilityKey{}).(Visibility)
+	return v
+}
@@ -0,0 +1,102 @@
+package access
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testAccess makes #bots of zkpq private, and every channel of other.
+func testAccess(requireToken bool) *Access {
+	return New(
+		map[string][]string{"zkpq": {"#Bots"}, "other": {All}},
+		map[string][]string{
+			"hunter2": {"ZKPQ  #bots"},
+			"s3cret":  {All},
+			"nothing": nil,
+		},
+		requireToken,
+	)
+}
+
+func TestAccess_Visibility(t *testing.T) {
+	t.Parallel()
+
+	a := testAccess(false)
+	tests := []struct {
+		token            string
+		ok               bool
+		network, channel string
+		want             bool
+	}{
+		{"", false, "zkpq", "#deviate", true},
+		{"", false, "zkpq", "#bots", false},
+		{"", false, "other", "#deviate", false},
+		{"hunter2", true, "zkpq", "#BOTS", true},
+		{"hunter2", true, "other", "#deviate", false},
+		{"s3cret", true, "other", "#deviate", true},
+		{"nothing", true, "zkpq", "#bots", false},
+		{"nothing", true, "zkpq", "#deviate", true},
+		{"hunter3", true, "zkpq", "#bots", false},
+		{"hunter", true, "zkpq", "#bots", false},
+	}
+	for _, test := range tests {
+		if got := a.Visibility(test.token, test.ok).Allows(test.network, test.channel); got != test.want {
+			t.Errorf("%q should see %s %s: %v, sees it: %v", test.token, test.network, test.channel, test.want, got)
+		}
+	}
+
+	var all Visibility
+	if !all.Allows("zkpq", "#bots") {
+		t.Error("Should allow every channel without a visibility.")
+	}
+}
+
+func TestAccess_Handler(t *testing.T) {
+	t.Parallel()
+
+	var seen Visibility
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestVisibility(r)
+	})
+
+	tests := []struct {
+		requireToken bool
+		url, bearer  string
+		code         int
+		private      bool
+	}{
+		{false, "/", "", 200, false},
+		{false, "/", "hunter2", 200, true},
+		{false, "/?token=hunter2", "", 200, true},
+		{false, "/", "hunter3", 401, false},
+		{false, "/?token=hunter", "", 401, false},
+		{true, "/", "", 401, false},
+		{true, "/?token=nothing", "", 200, false},
+	}
+	for _, test := range tests {
+		seen = nil
+		r := httptest.NewRequest("GET", test.url, nil)
+		if len(test.bearer) > 0 {
+			r.Header.Set("Authorization", "Bearer "+test.bearer)
+		}
+		w := httptest.NewRecorder()
+		testAccess(test.requireToken).Handler(h).ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s with %q should be served with %d, was %d", test.url, test.bearer, test.code, w.Code)
+		}
+		if w.Code == 401 {
+			if len(w.Header().Get("WWW-Authenticate")) == 0 {
+				t.Errorf("%s with %q should be asked for a token.", test.url, test.bearer)
+			}
+			continue
+		}
+		if seen == nil || seen.Allows("zkpq", "#bots") != test.private || !seen.Allows("zkpq", "#deviate") {
+			t.Errorf("%s with %q should see the private channel: %v", test.url, test.bearer, test.private)
+		}
+	}
+
+	if RequestVisibility(httptest.NewRequest("GET", "/", nil)) != nil {
+		t.Error("Should see every channel without an access handler.")
+	}
+}
@@ -0,0 +1,232 @@
+package aggregate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Defaults used when the forwarder's settings are left empty.
+const (
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 2 * time.Second
+
+	queueSize = 10000
+)
+
+// Forwarder is a stats.MessageSink sending the messages counted by a
+// collector to a Server. Failed batches are sent again until they go
+// through, messages are dropped when too many are waiting.
+type Forwarder struct {
+	// URL is the address the server is listening on, or a nats:// url to
+	// publish the batches on NATS under the subject in its path instead, see
+	// NATSSource.
+	URL   string
+	Token string
+
+	BatchSize     int
+	FlushInterval time.Duration
+
+	HTTP *http.Client
+
+	queue   chan Event
+	dropped uint64
+
+	mut sync.Mutex
+	seq uint64
+
+	natsMut sync.Mutex
+	nats    *natsConn
+}
+
+// NewForwarder creates a forwarder sending to the server at url.
+func NewForwarder(url, token string) *Forwarder {
+	return &Forwarder{
+		URL:           url,
+		Token:         token,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		queue:         make(chan Event, queueSize),
+		// sequences start from the clock so they keep increasing when
+		// the collector restarts
+		seq: uint64(time.Now().UnixNano()),
+	}
+}
+
+// Sink queues a counted message to be forwarded.
+func (f *Forwarder) Sink(m stats.SinkMessage) {
+	f.Add(Event{
+		Kind:     m.Kind.String(),
+		Network:  m.Network,
+		Channel:  m.Channel,
+		Hostmask: m.Hostmask,
+		Date:     m.Date,
+		Message:  m.Message,
+	})
+}
+
+// Add queues an event to be forwarded, numbering it.
+func (f *Forwarder) Add(ev Event) {
+	f.mut.Lock()
+	f.seq++
+	ev.Seq = f.seq
+	f.mut.Unlock()
+
+	select {
+	case f.queue <- ev:
+	default:
+		atomic.AddUint64(&f.dropped, 1)
+	}
+}
+
+// Dropped is the number of events dropped because too many were waiting.
+func (f *Forwarder) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// Run sends the queued events every flush interval, or sooner when a batch
+// fills up, until stop is closed. Failed sends are passed to errs when it
+// isn't nil. The events still queued are sent once more before returning.
+func (f *Forwarder) Run(stop <-chan struct{}, errs func(error)) {
+	size := f.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	interval := f.FlushInterval
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var batch []Event
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := f.Send(batch); err != nil {
+			if errs != nil {
+				errs(err)
+			}
+			return
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		// hold off reading the queue while a failing batch is full, the
+		// queue fills up and new events are dropped instead
+		queue := f.queue
+		if len(batch) >= size {
+			queue = nil
+		}
+
+		select {
+		case ev := <-queue:
+			if batch = append(batch, ev); len(batch) >= size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-stop:
+		drain:
+			for len(batch) < size {
+				select {
+				case ev := <-f.queue:
+					batch = append(batch, ev)
+				default:
+					break drain
+				}
+			}
+			flush()
+			return
+		}
+	}
+}
+
+// Send posts a batch of events to the server.
+func (f *Forwarder) Send(events []Event) (Result, error) {
+	var res Result
+
+	b, err := json.Marshal(events)
+	if err != nil {
+		return res, err
+	}
+
+	if strings.HasPrefix(f.URL, "nats://") {
+		if err = f.publish(b); err == nil {
+			res.Added = len(events)
+		}
+		return res, err
+	}
+
+	req, err := http.NewRequest("POST", f.URL, bytes.NewReader(b))
+	if err != nil {
+		return res, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+f.Token)
+
+	client := f.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return res, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&res)
+	return res, err
+}
+
+// publish publishes a batch on NATS, waiting for the server to have it. The
+// connection is kept for the next batches.
+func (f *Forwarder) publish(b []byte) error {
+	f.natsMut.Lock()
+	defer f.natsMut.Unlock()
+
+	u, err := url.Parse(f.URL)
+	if err != nil {
+		return err
+	}
+	if u.User == nil && len(f.Token) > 0 {
+		u.User = url.User(f.Token)
+	}
+
+	if f.nats == nil {
+		if f.nats, err = dialNATS(u); err != nil {
+			return err
+		}
+	}
+
+	f.nats.setDeadline(time.Now().Add(30 * time.Second))
+	if err = f.nats.publish(natsSubject(u), b); err == nil {
+		err = f.nats.flush()
+	}
+	if err != nil {
+		f.nats.Close()
+		f.nats = nil
+		return err
+	}
+	f.nats.setDeadline(time.Time{})
+	return nil
+}
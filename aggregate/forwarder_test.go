@@ -0,0 +1,68 @@
+package aggregate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestForwarder(t *testing.T) {
+	t.Parallel()
+
+	central, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(central, map[string]string{"a-token": "a", "b-token": "b"})
+
+	var failing int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	// two collectors sitting in the same channel
+	var collectors []*Forwarder
+	for _, token := range []string{"a-token", "b-token"} {
+		f := NewForwarder(server.URL, token)
+		local, err := stats.NewStats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		local.SetOptions(stats.Options{Sink: f})
+		local.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", date, "hello")
+		collectors = append(collectors, f)
+	}
+
+	for _, f := range collectors {
+		ev := <-f.queue
+		if ev.Hostmask != "dylan!d@zqz.ca" || ev.Seq == 0 {
+			t.Error("Wrong event:", ev)
+		}
+		if _, err := f.Send([]Event{ev}); err == nil {
+			t.Error("Should report failed sends.")
+		}
+		f.queue <- ev
+	}
+
+	atomic.StoreInt32(&failing, 0)
+	for _, f := range collectors {
+		f.Add(event(0, "bye", date))
+
+		stop := make(chan struct{})
+		close(stop)
+		f.Run(stop, func(err error) { t.Error(err) })
+	}
+
+	u := central.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 2 {
+		t.Error("Should have counted the messages the collectors shared once:", u)
+	}
+}
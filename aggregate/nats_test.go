@@ -0,0 +1,193 @@
+package aggregate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// natsServer is a tiny NATS server routing messages between its clients.
+type natsServer struct {
+	l     net.Listener
+	token string
+
+	mut  sync.Mutex
+	subs []natsSub
+	acks []string
+}
+
+type natsSub struct {
+	subject string
+	sid     string
+	w       io.Writer
+	mut     *sync.Mutex
+}
+
+func newNATSServer(t *testing.T, token string) *natsServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ns := &natsServer{l: l, token: token}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go ns.serve(conn)
+		}
+	}()
+	return ns
+}
+
+func (ns *natsServer) url(user string) string {
+	return "nats://" + user + ns.l.Addr().String()
+}
+
+func (ns *natsServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	wmut := &sync.Mutex{}
+	write := func(format string, args ...interface{}) {
+		wmut.Lock()
+		fmt.Fprintf(conn, format, args...)
+		wmut.Unlock()
+	}
+
+	write("INFO {\"server_id\":\"test\",\"auth_required\":%v}\r\n", len(ns.token) > 0)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "CONNECT":
+			if !strings.Contains(line, `"auth_token":"`+ns.token+`"`) && len(ns.token) > 0 {
+				write("-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			ns.mut.Lock()
+			ns.subs = append(ns.subs, natsSub{fields[1], fields[len(fields)-1], conn, wmut})
+			ns.mut.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[len(fields)-1])
+			payload := make([]byte, size+2)
+			if _, err = io.ReadFull(r, payload); err != nil {
+				return
+			}
+			reply := ""
+			if len(fields) == 4 {
+				reply = fields[2]
+			}
+			ns.route(fields[1], reply, payload[:size])
+		}
+	}
+}
+
+func (ns *natsServer) route(subject, reply string, payload []byte) {
+	ns.mut.Lock()
+	defer ns.mut.Unlock()
+
+	if strings.HasPrefix(subject, "ack.") {
+		ns.acks = append(ns.acks, subject)
+		return
+	}
+	for _, sub := range ns.subs {
+		if sub.subject == subject || strings.HasSuffix(sub.subject, ".>") && strings.HasPrefix(subject, strings.TrimSuffix(sub.subject, ">")) {
+			sub.mut.Lock()
+			if len(reply) > 0 {
+				fmt.Fprintf(sub.w, "MSG %s %s %s %d\r\n%s\r\n", subject, sub.sid, reply, len(payload), payload)
+			} else {
+				fmt.Fprintf(sub.w, "MSG %s %s %d\r\n%s\r\n", subject, sub.sid, len(payload), payload)
+			}
+			sub.mut.Unlock()
+		}
+	}
+}
+
+func (ns *natsServer) subscribed() bool {
+	ns.mut.Lock()
+	defer ns.mut.Unlock()
+	return len(ns.subs) > 0
+}
+
+func (ns *natsServer) acked() bool {
+	ns.mut.Lock()
+	defer ns.mut.Unlock()
+	return len(ns.acks) > 0
+}
+
+func TestNATS(t *testing.T) {
+	t.Parallel()
+
+	ns := newNATSServer(t, "hunter2")
+	defer ns.l.Close()
+
+	central, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := NewNATSSource(NewServer(central, nil), ns.url("hunter2@"))
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		src.Run(stop, func(err error) { t.Error(err) })
+		close(done)
+	}()
+	for !ns.subscribed() {
+		time.Sleep(time.Millisecond)
+	}
+
+	f := NewForwarder(ns.url("")+"/"+DefaultSubject+".shell-box", "hunter2")
+	res, err := f.Send([]Event{event(1, "hello", date), event(2, "there", date)})
+	if err != nil || res.Added != 2 {
+		t.Fatal("Should publish the batch:", res, err)
+	}
+	if _, err = f.Send([]Event{event(2, "there", date)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a replayed message expecting an acknowledgement
+	ns.route(DefaultSubject+".other", "ack.1", []byte(`{"kind":"message","network":"zkpq","channel":"#deviate","hostmask":"aaron!a@zqz.ca","date":"2014-03-01T08:00:00Z","message":"hi"}`))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !ns.acked() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	close(stop)
+	<-done
+
+	if u := central.GetUser("zkpq", "dylan"); u == nil || u.Lines != 2 {
+		t.Error("Should count the published batch once:", u)
+	}
+	if u := central.GetUser("zkpq", "aaron"); u == nil || u.Lines != 1 {
+		t.Error("Should count single events:", u)
+	}
+	if !ns.acked() {
+		t.Error("Should acknowledge messages with a reply subject.")
+	}
+
+	bad := NewForwarder(ns.url("")+"/"+DefaultSubject+".bad", "wrong")
+	if _, err = bad.Send([]Event{event(1, "nope", date)}); err == nil || !strings.Contains(err.Error(), "Authorization") {
+		t.Error("Should fail with bad credentials:", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Package aggregate merges the messages seen by collectors running on other
+// hosts into a single Stats. Collectors forward the messages they see over
+// http with a Forwarder, the Server counts them once no matter how many
+// collectors sat in the channel.
+package aggregate
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// DefaultWindow is how far apart the same message reported by two collectors
+// may be dated when it has no msgid.
+const DefaultWindow = 5 * time.Second
+
+// maxBody is the largest batch of events accepted in a request.
+const maxBody = 16 << 20
+
+// Event is a message seen by a collector.
+type Event struct {
+	// Seq numbers the events of a collector in increasing order, so a batch
+	// sent again after a failed request isn't counted twice. Events without
+	// a Seq are always counted.
+	Seq      uint64    `json:"seq,omitempty"`
+	Kind     string    `json:"kind"`
+	Network  string    `json:"network"`
+	Channel  string    `json:"channel,omitempty"`
+	Hostmask string    `json:"hostmask"`
+	Date     time.Time `json:"date"`
+	Message  string    `json:"message"`
+	// MsgID is the IRCv3 msgid of the message when the server sent one.
+	MsgID string `json:"msgid,omitempty"`
+}
+
+// Result is the reply to a batch of events.
+type Result struct {
+	Added      int `json:"added"`
+	Duplicates int `json:"duplicates"`
+	Invalid    int `json:"invalid"`
+}
+
+// Server is an http.Handler accepting batches of events posted as a json
+// array, authenticated by a bearer token per collector.
+type Server struct {
+	Stats *stats.Stats
+	// Tokens maps the token of each collector to its name.
+	Tokens map[string]string
+	// Window is how far apart copies of a message without a msgid may be
+	// dated, the clocks of the collectors should be at least this close.
+	Window time.Duration
+
+	mut    sync.Mutex
+	seqs   map[string]uint64
+	recent map[string]*sighting
+	newest time.Time
+	pruned time.Time
+}
+
+// sighting is a message without a msgid recently reported by collectors.
+type sighting struct {
+	date       time.Time
+	collectors map[string]struct{}
+}
+
+// NewServer creates a server counting into s for the collectors with the
+// tokens.
+func NewServer(s *stats.Stats, tokens map[string]string) *Server {
+	return &Server{
+		Stats:  s,
+		Tokens: tokens,
+		Window: DefaultWindow,
+	}
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Events must be posted.", http.StatusMethodNotAllowed)
+		return
+	}
+
+	collector, ok := srv.Tokens[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	if !ok {
+		http.Error(w, "Unknown collector.", http.StatusUnauthorized)
+		return
+	}
+
+	var events []Event
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody)).Decode(&events); err != nil {
+		http.Error(w, "Bad events: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(srv.Add(collector, events))
+}
+
+// Add counts the events reported by a collector.
+func (srv *Server) Add(collector string, events []Event) Result {
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	if srv.seqs == nil {
+		srv.seqs = make(map[string]uint64)
+		srv.recent = make(map[string]*sighting)
+	}
+
+	var res Result
+	for _, ev := range events {
+		kind, ok := stats.ParseMsgKind(ev.Kind)
+		if !ok || len(ev.Network) == 0 || len(ev.Hostmask) == 0 || ev.Date.IsZero() {
+			res.Invalid++
+			continue
+		}
+
+		if ev.Seq > 0 {
+			if ev.Seq <= srv.seqs[collector] {
+				res.Duplicates++
+				continue
+			}
+			srv.seqs[collector] = ev.Seq
+		}
+
+		if len(ev.MsgID) == 0 && srv.seen(collector, ev) {
+			res.Duplicates++
+			continue
+		}
+
+		if srv.Stats.AddMessageID(ev.MsgID, kind, ev.Network, ev.Channel, ev.Hostmask, ev.Date, ev.Message) {
+			res.Added++
+		} else {
+			res.Duplicates++
+		}
+	}
+
+	srv.prune()
+	return res
+}
+
+// seen checks if another collector already reported the message. A
+// collector reporting the same message twice means it was said twice.
+func (srv *Server) seen(collector string, ev Event) bool {
+	window := srv.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	key := strings.ToLower(ev.Network+"\x00"+ev.Channel+"\x00"+ev.Hostmask) + "\x00" + ev.Kind + "\x00" + ev.Message
+	if s, ok := srv.recent[key]; ok {
+		if d := ev.Date.Sub(s.date); d < window && d > -window {
+			if _, ok := s.collectors[collector]; !ok {
+				s.collectors[collector] = struct{}{}
+				return true
+			}
+		}
+	}
+
+	if ev.Date.After(srv.newest) {
+		srv.newest = ev.Date
+	}
+	srv.recent[key] = &sighting{
+		date:       ev.Date,
+		collectors: map[string]struct{}{collector: struct{}{}},
+	}
+	return false
+}
+
+// prune forgets the sightings too old to match a copy anymore, measured from
+// the newest message, at most once a minute.
+func (srv *Server) prune() {
+	if srv.newest.Sub(srv.pruned) < time.Minute {
+		return
+	}
+	srv.pruned = srv.newest
+
+	window := srv.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	// collectors forward their messages within seconds, a few minutes is
+	// plenty of leeway for slow batches
+	oldest := srv.newest.Add(-window - 5*time.Minute)
+	for key, s := range srv.recent {
+		if s.date.Before(oldest) {
+			delete(srv.recent, key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package aggregate
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+var date = time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+func event(seq uint64, message string, d time.Time) Event {
+	return Event{
+		Seq:      seq,
+		Kind:     "message",
+		Network:  "zkpq",
+		Channel:  "#deviate",
+		Hostmask: "dylan!d@zqz.ca",
+		Date:     d,
+		Message:  message,
+	}
+}
+
+func TestServer_Add(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(s, nil)
+
+	res := srv.Add("a", []Event{event(1, "hello", date), event(2, "hello", date.Add(time.Second))})
+	if res.Added != 2 {
+		t.Error("A collector repeating a message should count twice:", res)
+	}
+
+	res = srv.Add("b", []Event{event(7, "hello", date.Add(2*time.Second)), event(8, "bye", date.Add(3*time.Second))})
+	if res.Added != 1 || res.Duplicates != 1 {
+		t.Error("Should skip the copy another collector reported:", res)
+	}
+
+	res = srv.Add("b", []Event{event(8, "bye", date.Add(3*time.Second))})
+	if res.Duplicates != 1 {
+		t.Error("Should skip events sent again:", res)
+	}
+
+	res = srv.Add("b", []Event{event(9, "hello", date.Add(time.Minute))})
+	if res.Added != 1 {
+		t.Error("Should count copies outside of the window:", res)
+	}
+
+	withID := event(0, "tagged", date)
+	withID.MsgID = "abc"
+	res = srv.Add("a", []Event{withID})
+	res2 := srv.Add("b", []Event{withID})
+	if res.Added != 1 || res2.Duplicates != 1 {
+		t.Error("Should skip repeated msgids:", res, res2)
+	}
+
+	bad := event(0, "hi", date)
+	bad.Kind = "shout"
+	if res = srv.Add("a", []Event{bad, event(0, "hi", time.Time{})}); res.Invalid != 2 {
+		t.Error("Should reject invalid events:", res)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 5 {
+		t.Error("Wrong number of lines counted:", u)
+	}
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(s, map[string]string{"secret": "a"})
+
+	post := func(token string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		return w
+	}
+
+	body, _ := json.Marshal([]Event{event(1, "hello", date)})
+	if w := post("wrong", body); w.Code != http.StatusUnauthorized {
+		t.Error("Should reject unknown collectors:", w.Code)
+	}
+	if w := post("secret", []byte("[{")); w.Code != http.StatusBadRequest {
+		t.Error("Should reject bad events:", w.Code)
+	}
+
+	w := post("secret", body)
+	var res Result
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil || w.Code != http.StatusOK || res.Added != 1 {
+		t.Error("Should have added the event:", w.Code, res, err)
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, r)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Error("Should only accept posts:", rec.Code)
+	}
+}
@@ -0,0 +1,58 @@
+package stats
+
+import "strings"
+
+// alias finds the channel the messages of a channel are counted in, see
+// Options.ChannelAliases. ok is false for channels that alias no other.
+func (o *Options) alias(network, channel string) (aliasNetwork, aliasChannel string, ok bool) {
+	if len(o.ChannelAliases) == 0 || len(channel) == 0 {
+		return network, channel, false
+	}
+
+	target, found := o.ChannelAliases[importKey(network, channel)]
+	i := strings.IndexByte(target, ' ')
+	if !found || i <= 0 || i == len(target)-1 {
+		return network, channel, false
+	}
+	return target[:i], target[i+1:], true
+}
+
+// aliasNetwork is the network the messages of a channel are counted on.
+func (o *Options) aliasNetwork(network, channel string) string {
+	network, _, _ = o.alias(network, channel)
+	return network
+}
+
+// aliasSources are the channels other channels alias, each channel counting
+// where its messages were said in Channel.Sources.
+func aliasSources(aliases map[string]string) map[string]bool {
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	targets := make(map[string]bool, len(aliases))
+	for _, target := range aliases {
+		targets[strings.ToLower(target)] = true
+	}
+	return targets
+}
+
+// addSource counts a message of a channel others alias in the channel it was
+// said in, source is its network and channel apart by a space.
+func (s *Stats) addSource(c *Channel, network, source string) {
+	if !s.opts.aliased[importKey(network, c.Name)] {
+		return
+	}
+
+	if c.Sources == nil {
+		c.Sources = make(map[string]uint)
+	}
+	c.Sources[strings.ToLower(source)]++
+}
+
+// Alias is the channel the messages of a channel are counted in, the channel
+// itself when it aliases no other, see Options.ChannelAliases.
+func (tx *ReadTx) Alias(network, channel string) (aliasNetwork, aliasChannel string) {
+	aliasNetwork, aliasChannel, _ = tx.s.opts.alias(network, channel)
+	return aliasNetwork, aliasChannel
+}
@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats_ChannelAliases(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{ChannelAliases: map[string]string{
+		"TEST_NETWORK #Test-Bridge": "test_network #test",
+		"other #test":               "test_network #Test",
+	}})
+
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, date, "foo")
+	s.AddMessage(Msg, network, "#test-bridge", "alice", date, "bar")
+	s.AddMessage(Msg, "other", channel, "bob", date, "baz")
+	s.AddMessage(Msg, "other", "#elsewhere", "bob", date, "qux")
+
+	c := s.GetChannel(network, channel)
+	if c == nil || c.MessageCount != 3 {
+		t.Fatal("Should count the messages of the aliases in the channel, counted", c)
+	}
+	if s.GetChannel(network, "#test-bridge") != nil || s.GetChannel("other", channel) != nil {
+		t.Error("Should not count the aliases on their own.")
+	}
+	want := map[string]uint{
+		"test_network #test":        1,
+		"test_network #test-bridge": 1,
+		"other #test":               1,
+	}
+	if len(c.Sources) != len(want) {
+		t.Fatal("Should break the messages down by where they were said, have", c.Sources)
+	}
+	for source, count := range want {
+		if c.Sources[source] != count {
+			t.Errorf("Should count %d messages from %s, counted %d", count, source, c.Sources[source])
+		}
+	}
+
+	if u := s.GetUser(network, "bob"); u == nil || u.MessageCount != 1 {
+		t.Error("Should count bob's aliased message on the network of the channel.")
+	}
+	if u := s.GetUser("other", "bob"); u == nil || u.MessageCount != 1 {
+		t.Error("Should count bob's other messages on their own network.")
+	}
+	if c := s.GetChannel("other", "#elsewhere"); c == nil || len(c.Sources) != 0 {
+		t.Error("Should not break down the channels nothing aliases.")
+	}
+
+	s.View(func(tx *ReadTx) {
+		if n, c := tx.Alias("other", "#TEST"); n != network || c != "#Test" {
+			t.Error("Should find the channel an alias is counted in, found", n, c)
+		}
+		if n, c := tx.Alias("other", "#elsewhere"); n != "other" || c != "#elsewhere" {
+			t.Error("Should find the channels nothing aliases themselves, found", n, c)
+		}
+	})
+}
+
+func TestStats_ChannelAliasesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{ChannelAliases: map[string]string{"oftc #chan-bridge": "libera #chan"}})
+
+	const messages = 200
+	var wg sync.WaitGroup
+	for _, channel := range []string{"#chan-bridge", "#chan-\x07bridge", "#other"} {
+		wg.Add(1)
+		go func(channel string) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				s.AddMessage(Msg, "oftc", channel, hostmask, time.Now(), "lol")
+			}
+		}(channel)
+	}
+	wg.Wait()
+
+	if c := s.GetChannel("libera", "#chan"); c == nil || c.MessageCount != 2*messages {
+		t.Error("Should count the aliased messages as sanitized on the network of the alias:", c)
+	}
+	if c := s.GetChannel("oftc", "#other"); c == nil || c.MessageCount != messages {
+		t.Error("Should count the other messages on their network:", c)
+	}
+	if s.GetChannel("oftc", "#chan-bridge") != nil {
+		t.Error("Should not count the alias on its own.")
+	}
+}
+
+func TestOptions_alias(t *testing.T) {
+	t.Parallel()
+
+	o := &Options{ChannelAliases: map[string]string{
+		"net #a": "net #b",
+		"net #c": "#d",
+		"net #e": "net ",
+	}}
+	if n, c, ok := o.alias("NET", "#A"); !ok || n != "net" || c != "#b" {
+		t.Error("Should alias the channel in any case, aliased to", n, c, ok)
+	}
+	for _, channel := range []string{"#c", "#e", "#b", ""} {
+		if n, c, ok := o.alias("net", channel); ok || n != "net" || c != channel {
+			t.Errorf("Should not alias %q, aliased to %s %s", channel, n, c)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"sort"
+	"time"
+)
+
+// BatchMessage is a message waiting to be added with AddBatch.
+type BatchMessage struct {
+	Kind     MsgKind
+	Network  string
+	Channel  string
+	Hostmask string
+	Date     time.Time
+	Message  string
+}
+
+// AddBatch adds many messages at once, for example when importing logs. The
+// messages are added in chronological order so that aggregates depending on
+// order stay correct even if the batch was assembled out of order.
+func (s *Stats) AddBatch(batch []BatchMessage) {
+	s.lock()
+	defer s.mut.Unlock()
+
+	s.addBatch(batch)
+}
+
+func (s *Stats) addBatch(batch []BatchMessage) {
+	sort.SliceStable(batch, func(i, j int) bool {
+		return batch[i].Date.Before(batch[j].Date)
+	})
+
+	for _, m := range batch {
+		s.addRaw(RawMessage{
+			Kind:     m.Kind,
+			Network:  m.Network,
+			Channel:  m.Channel,
+			Hostmask: m.Hostmask,
+			Date:     m.Date,
+			Message:  m.Message,
+		})
+	}
+}
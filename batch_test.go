@@ -0,0 +1,31 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats_AddBatch(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	s.AddBatch([]BatchMessage{
+		{Msg, network, channel, "late", start.Add(time.Hour), "bye"},
+		{Msg, network, channel, "early", start, "hi"},
+	})
+
+	c := s.GetChannel(network, channel)
+	if c == nil {
+		t.Fatal("Should have added the channel.")
+	}
+
+	if !c.LastActive.Equal(start.Add(time.Hour)) {
+		t.Error("Should add the messages in chronological order.")
+	}
+
+	if early := s.GetUser(network, "early"); c.DailySpeakers.First[early.ID] != 1 {
+		t.Error("early should have spoken first.")
+	}
+}
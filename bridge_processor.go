@@ -0,0 +1,95 @@
+package stats
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/aarondl/ultimateq/irc"
+)
+
+func init() {
+	RegisterProcessor("bridge", func(args string) (Processor, error) {
+		var bots []string
+		for _, bot := range strings.Split(args, ",") {
+			if bot = strings.TrimSpace(bot); len(bot) > 0 {
+				bots = append(bots, bot)
+			}
+		}
+		if len(bots) == 0 {
+			return nil, errors.New("The bridge processor needs the nicks of the bridge bots, eg. bridge=discordbot,matrixbot")
+		}
+		return NewBridgeProcessor(bots...), nil
+	})
+}
+
+// bridgeMessage and bridgeAction match the ways bridges put the nick of the
+// real sender in front of the messages they relay: <nick> message,
+// [nick] message and * nick action.
+var (
+	bridgeMessage = regexp.MustCompile(`^(?:<([^<>\s]+)>|\[([^\[\]\s]+)\]) (.*)$`)
+	bridgeAction  = regexp.MustCompile(`^\* ([^\s]+) (.*)$`)
+	// zero width characters some bridges put in nicks so they don't
+	// highlight the users on irc
+	bridgeNickJunk = strings.NewReplacer("\u200b", "", "\u200c", "", "\u200d", "", "\u2060", "", "\ufeff", "")
+)
+
+// BridgeProcessor credits the messages relayed by bridge bots to the users
+// who really sent them, stripping the prefixes bridges add such as
+// <discord_user>. Relayed messages are tagged bridge with the bot's nick.
+type BridgeProcessor struct {
+	Bots map[string]struct{}
+	// Network limits the processor to the bots on one network, when empty
+	// the bots are relays on every network.
+	Network string
+}
+
+// NewBridgeProcessor creates a processor for the bridge bots with the nicks.
+func NewBridgeProcessor(bots ...string) *BridgeProcessor {
+	b := &BridgeProcessor{Bots: make(map[string]struct{}, len(bots))}
+	for _, bot := range bots {
+		b.Bots[strings.ToLower(bot)] = struct{}{}
+	}
+	return b
+}
+
+// Process rewrites a message relayed by a bridge bot.
+func (b *BridgeProcessor) Process(m *ProcessedMessage) bool {
+	if m.Kind != Msg && m.Kind != Notice {
+		return true
+	}
+
+	if len(b.Network) > 0 && !strings.EqualFold(b.Network, m.Network) {
+		return true
+	}
+
+	bot := irc.Nick(m.Hostmask)
+	if _, ok := b.Bots[strings.ToLower(bot)]; !ok {
+		return true
+	}
+
+	nick, message := "", ""
+	// bridges often color the nicks they relay
+	plain := stripFormatting(m.Message)
+	if match := bridgeMessage.FindStringSubmatch(plain); match != nil {
+		nick, message = match[1]+match[2], match[3]
+	} else if match = bridgeAction.FindStringSubmatch(plain); match != nil {
+		nick, message = match[1], match[2]
+		m.Kind = Action
+	} else {
+		// the bridge speaking for itself
+		return true
+	}
+
+	if nick = bridgeNickJunk.Replace(nick); len(nick) == 0 {
+		return true
+	}
+
+	m.Hostmask = nick
+	m.Message = message
+	m.Tag("bridge", bot)
+	return true
+}
+
+// Counted does nothing.
+func (b *BridgeProcessor) Counted(m ProcessedMessage) {}
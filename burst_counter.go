@@ -0,0 +1,114 @@
+package stats
+
+import "time"
+
+const (
+	burstWindow           = time.Minute
+	defaultFloodThreshold = 10
+	maxFloodEvents        = 50
+)
+
+// BurstCounter measures how bursty someone's messages are. Recent holds the
+// times of the messages inside the last minute.
+type BurstCounter struct {
+	Recent       []time.Time
+	MaxPerMinute uint
+	Floods       uint
+	Flooding     bool
+}
+
+// addMessage records a message and reports whether it started a flood, which
+// is threshold or more messages within a minute.
+func (b *BurstCounter) addMessage(message *Message, threshold uint) bool {
+	cutoff := message.Date.Add(-burstWindow)
+
+	i := 0
+	for i < len(b.Recent) && !b.Recent[i].After(cutoff) {
+		i++
+	}
+	b.Recent = append(b.Recent[i:], message.Date)
+
+	n := uint(len(b.Recent))
+	if n > b.MaxPerMinute {
+		b.MaxPerMinute = n
+	}
+
+	if n < threshold {
+		b.Flooding = false
+		return false
+	}
+
+	if b.Flooding {
+		return false
+	}
+
+	b.Flooding = true
+	b.Floods++
+	return true
+}
+
+// FloodEvent is a period where a user flooded a channel.
+type FloodEvent struct {
+	UserID   uint
+	Start    time.Time
+	End      time.Time
+	Messages uint
+}
+
+// FloodHistory keeps the most recent floods of a channel.
+type FloodHistory struct {
+	Floods []FloodEvent
+}
+
+// addFlood starts a new flood event made up of the user's recent messages.
+func (f *FloodHistory) addFlood(user *User) {
+	if len(f.Floods) >= maxFloodEvents {
+		f.Floods = f.Floods[1:]
+	}
+
+	recent := user.Bursts.Recent
+	f.Floods = append(f.Floods, FloodEvent{
+		UserID:   user.ID,
+		Start:    recent[0],
+		End:      recent[len(recent)-1],
+		Messages: uint(len(recent)),
+	})
+}
+
+// extendFlood adds a message to the user's current flood event.
+func (f *FloodHistory) extendFlood(message *Message) {
+	for i := len(f.Floods) - 1; i >= 0; i-- {
+		if f.Floods[i].UserID == message.UserID {
+			f.Floods[i].End = message.Date
+			f.Floods[i].Messages++
+			return
+		}
+	}
+}
+
+// floodThreshold returns the number of messages per minute that is a flood.
+func (o Options) floodThreshold() uint {
+	if o.FloodThreshold == 0 {
+		return defaultFloodThreshold
+	}
+
+	return o.FloodThreshold
+}
+
+// addBurst measures the burstiness of the user and records channel floods.
+func (s *Stats) addBurst(c *Channel, u *User, cu *User, message *Message) {
+	threshold := s.opts.floodThreshold()
+
+	u.Bursts.addMessage(message, threshold)
+
+	if cu == nil || c == nil {
+		return
+	}
+
+	wasFlooding := cu.Bursts.Flooding
+	if cu.Bursts.addMessage(message, threshold) {
+		c.FloodHistory.addFlood(cu)
+	} else if wasFlooding && cu.Bursts.Flooding {
+		c.FloodHistory.extendFlood(message)
+	}
+}
@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBurstCounter(t *testing.T) {
+	t.Parallel()
+
+	var b BurstCounter
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if b.addMessage(&Message{Date: start.Add(time.Duration(i) * time.Second)}, 3) != (i == 2) {
+			t.Error("Should only start a flood on the third message.", i)
+		}
+	}
+
+	if !b.Flooding || b.Floods != 1 || b.MaxPerMinute != 3 {
+		t.Error("Should be flooding.", b)
+	}
+
+	if b.addMessage(&Message{Date: start.Add(5 * time.Second)}, 3) {
+		t.Error("Should not start a second flood while flooding.")
+	}
+
+	if b.addMessage(&Message{Date: start.Add(2 * time.Minute)}, 3); b.Flooding {
+		t.Error("Should have stopped flooding.")
+	}
+
+	if len(b.Recent) != 1 || b.MaxPerMinute != 4 {
+		t.Error("Should forget messages outside the window.", b)
+	}
+}
+
+func TestStats_FloodHistory(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{FloodThreshold: 3})
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, start.Add(time.Duration(i)*time.Second), "spam")
+	}
+	s.AddMessage(Msg, network, channel, "other", start, "hi")
+
+	c := s.GetChannel(network, channel)
+	if len(c.Floods) != 1 {
+		t.Fatal("Should have one flood.")
+	}
+
+	if f := c.Floods[0]; f.Messages != 5 || !f.End.Equal(start.Add(4*time.Second)) {
+		t.Error("Should extend the flood with every message.", f)
+	}
+
+	if u := s.GetUser(network, nick); u.Bursts.Floods != 1 || u.Bursts.MaxPerMinute != 5 {
+		t.Error("Should count the user's flood.", u.Bursts)
+	}
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// encodeCanonical encodes the stats as indented json, whose maps are written
+// in the order of their keys. Unlike gob the same stats encode to the same
+// bytes, see Options.Canonical. The channels must all be loaded, see
+// loadChannels. The counters embedded in channels, users and networks are
+// tagged so json keeps their fields apart.
+func (s *Stats) encodeCanonical(b *bytes.Buffer) error {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "\t")
+	return enc.Encode(s)
+}
+
+// loadChannels loads the channels not loaded yet, json has no room for the
+// rest of a channel still encoded. The stats aren't saved as json when one
+// fails to load. It's called with the stats locked for reading.
+func (s *Stats) loadChannels() error {
+	for _, c := range s.Channels {
+		if c.load(s) == nil {
+			return c.loadErr
+		}
+	}
+	return nil
+}
+
+// isCanonical checks if a database was saved as json, by the start of the
+// object encodeCanonical writes. A gob stream starts with a type definition,
+// whose id is never a quote or white space.
+func isCanonical(r *bufio.Reader) bool {
+	start, err := r.Peek(2)
+	return err == nil && start[0] == '{' && (start[1] == '\n' || start[1] == '"')
+}
+
+// decodeCanonical decodes stats saved as json. The empty slices json keeps
+// are dropped as gob drops them, so the stats are the same as they would be
+// loaded from gob.
+func decodeCanonical(r *bufio.Reader) (*Stats, error) {
+	var stats Stats
+	if err := json.NewDecoder(r).Decode(&stats); err != nil {
+		return nil, err
+	}
+
+	dropEmpty(reflect.ValueOf(&stats).Elem())
+	return &stats, nil
+}
+
+// dropEmpty sets the empty slices in the exported fields of v to nil, v must
+// be settable. Gob keeps empty maps, so does json.
+func dropEmpty(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			dropEmpty(v.Elem())
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				dropEmpty(f)
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			dropEmpty(v.Index(i))
+		}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			dropEmpty(v.Index(i))
+		}
+	case reflect.Map:
+		// map values aren't settable, they're copied and put back
+		iter := v.MapRange()
+		for iter.Next() {
+			e := reflect.New(iter.Value().Type()).Elem()
+			e.Set(iter.Value())
+			dropEmpty(e)
+			v.SetMapIndex(iter.Key(), e)
+		}
+	}
+}
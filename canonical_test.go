@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// canonicalStats adds the same messages to new stats every time.
+func canonicalStats() *Stats {
+	s := newStats()
+	s.SetOptions(Options{
+		Canonical:         true,
+		LanguageDetector:  NewStopwordDetector(),
+		SentimentAnalyzer: NewLexiconAnalyzer(),
+	})
+
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 200; i++ {
+		nick := fmt.Sprintf("user%d!u@host", i%7)
+		channel := fmt.Sprintf("#chan%d", i%3)
+		s.AddMessage(Msg, network, channel, nick, date.Add(time.Duration(i)*time.Minute), benchMessages[i%len(benchMessages)])
+	}
+	s.AddMessage(Topic, network, "#chan0", hostmask, date, "the topic")
+	s.AddMessage(Kick, network, "#chan1", hostmask, date, "user1 bye")
+
+	// the random quotes are the only thing that differs
+	for _, u := range s.Users {
+		u.Quotes.Random = Message{}
+		for _, cu := range u.ChannelUsers {
+			cu.Quotes.Random = Message{}
+		}
+	}
+	for _, c := range s.Channels {
+		c.Quotes.Random = Message{}
+	}
+	for _, n := range s.Networks {
+		n.Quotes.Random = Message{}
+	}
+	return s
+}
+
+func TestStats_SaveCanonical(t *testing.T) {
+	var b bytes.Buffer
+	fileOpener = &fakeFileOpener{&b}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	a, c := canonicalStats(), canonicalStats()
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	first := append([]byte(nil), b.Bytes()...)
+	b.Reset()
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, b.Bytes()) {
+		t.Fatal("Should save the same stats to the same bytes.")
+	}
+
+	loaded, err := loadDatabase(context.Background(), defaultPath)
+	if err != nil || loaded == nil {
+		t.Fatal("Should load canonical stats:", err)
+	}
+
+	b.Reset()
+	a.SetOptions(Options{})
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+	gob, err := loadDatabase(context.Background(), defaultPath)
+	if err != nil || gob == nil {
+		t.Fatal("Should load gob:", err)
+	}
+	for _, ch := range gob.Channels {
+		ch.load(gob)
+	}
+	if !reflect.DeepEqual(loaded, gob) {
+		t.Error("Should load canonical stats as they're loaded from gob.")
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	json, _ := ioutil.ReadAll(r)
+	if !bytes.HasPrefix(json, []byte("{\n\t\"Channels\": {")) {
+		t.Errorf("Should save indented json: %.40q", json)
+	}
+}
+
+func TestStats_loadBrokenCanonical(t *testing.T) {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	gz.Write([]byte("{\n\t\"Channels\": {"))
+	gz.Close()
+
+	fileOpener = &fakeFileOpener{&b}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	if s, err := loadDatabase(context.Background(), defaultPath); err == nil || s != nil {
+		t.Error("Should fail loading broken json:", s)
+	}
+}
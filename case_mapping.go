@@ -0,0 +1,108 @@
+package stats
+
+import "strings"
+
+// CaseMapping is how a network tells nicks apart, irc servers announce theirs
+// with CASEMAPPING in ISUPPORT. Nicks that fold to the same are one user.
+type CaseMapping int
+
+// These are the case mappings of irc, and the one of chats that aren't irc.
+const (
+	// UnicodeCase folds the case of every letter, it's the default so the
+	// nicks of chats that aren't irc fold as they read.
+	UnicodeCase CaseMapping = iota
+	// ASCIICase folds A to Z only.
+	ASCIICase
+	// RFC1459Case folds A to Z and []\~ to {}|^, irc's default.
+	RFC1459Case
+	// StrictRFC1459Case folds A to Z and []\ to {}|.
+	StrictRFC1459Case
+)
+
+var caseMappingNames = map[CaseMapping]string{
+	UnicodeCase:       "unicode",
+	ASCIICase:         "ascii",
+	RFC1459Case:       "rfc1459",
+	StrictRFC1459Case: "strict-rfc1459",
+}
+
+// String returns the name of the case mapping as servers announce it.
+func (m CaseMapping) String() string {
+	if name, ok := caseMappingNames[m]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// ParseCaseMapping finds the case mapping with the name returned by String.
+// Servers announcing rfc7613 fold unicode.
+func ParseCaseMapping(name string) (CaseMapping, bool) {
+	name = strings.ToLower(name)
+	if name == "rfc7613" {
+		return UnicodeCase, true
+	}
+
+	for m, n := range caseMappingNames {
+		if n == name {
+			return m, true
+		}
+	}
+
+	return 0, false
+}
+
+// Fold folds the case of a nick, nicks that fold to the same are the same.
+func (m CaseMapping) Fold(nick string) string {
+	if m == UnicodeCase {
+		return strings.ToLower(nick)
+	}
+
+	// most nicks fold to themselves, they're only copied once they don't
+	var folded []byte
+	for i := 0; i < len(nick); i++ {
+		c := nick[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			c += 'a' - 'A'
+		case m == ASCIICase:
+		case c == '[':
+			c = '{'
+		case c == ']':
+			c = '}'
+		case c == '\\':
+			c = '|'
+		case c == '~' && m == RFC1459Case:
+			c = '^'
+		}
+
+		if c != nick[i] && folded == nil {
+			folded = []byte(nick)
+		}
+		if folded != nil {
+			folded[i] = c
+		}
+	}
+
+	if folded == nil {
+		return nick
+	}
+	return string(folded)
+}
+
+// caseMapping is the case mapping nicks are folded with on a network.
+func (o *Options) caseMapping(network string) CaseMapping {
+	if m, ok := o.CaseMappings[strings.ToLower(network)]; ok {
+		return m
+	}
+	return o.CaseMapping
+}
+
+// fold folds the case of a nick as the network does, the users of the
+// network are indexed by their folded nicks.
+func (n *Network) fold(nick string) string {
+	if n.stats == nil {
+		return strings.ToLower(nick)
+	}
+	return n.stats.opts.caseMapping(n.Name).Fold(nick)
+}
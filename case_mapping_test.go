@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCaseMapping_Fold(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		mapping CaseMapping
+		nick    string
+		expect  string
+	}{
+		{UnicodeCase, "Ärger[m]", "ärger[m]"},
+		{ASCIICase, "Ärger[M]", "Ärger[m]"},
+		{RFC1459Case, "Bob[a]\\~", "bob{a}|^"},
+		{StrictRFC1459Case, "Bob[a]\\~", "bob{a}|~"},
+		{RFC1459Case, "bob", "bob"},
+		{RFC1459Case, "", ""},
+	}
+
+	for _, test := range tests {
+		if got := test.mapping.Fold(test.nick); got != test.expect {
+			t.Errorf("%s folded %q to %q, want %q", test.mapping, test.nick, got, test.expect)
+		}
+	}
+}
+
+func TestParseCaseMapping(t *testing.T) {
+	t.Parallel()
+
+	for m := range caseMappingNames {
+		if got, ok := ParseCaseMapping(m.String()); !ok || got != m {
+			t.Errorf("Should parse %s, got %s", m, got)
+		}
+	}
+	if m, ok := ParseCaseMapping("RFC7613"); !ok || m != UnicodeCase {
+		t.Error("Should fold rfc7613 as unicode, got", m)
+	}
+	if _, ok := ParseCaseMapping("ebcdic"); ok {
+		t.Error("Shouldn't parse an unknown case mapping.")
+	}
+}
+
+func TestStats_caseMappingOptions(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "[Bob]", time.Now(), "hi")
+	s.AddMessage(Msg, network, channel, "{bob}", time.Now(), "hi")
+	if len(s.Users) != 2 {
+		t.Fatal("Should tell [Bob] and {bob} apart by default, have", len(s.Users))
+	}
+
+	// the users are indexed again as the network folds them
+	s.SetOptions(Options{CaseMapping: RFC1459Case})
+	s.AddMessage(Msg, network, channel, "{BOB}", time.Now(), "hi")
+	if len(s.Users) != 2 {
+		t.Error("Should add {BOB} to a user already known, have", len(s.Users))
+	}
+	if u := s.GetUser(network, "[bob]"); u == nil {
+		t.Error("Should find the user by the folded nick.")
+	}
+}
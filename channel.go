@@ -4,45 +4,72 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Channel struct {
 	HourlyChart
-	LastTopics
-	URLCounter
-	WordCounter
-	SwearCounter
-	EmoticonCounter
-	ConsecutiveLines
+	LastTopics       `json:"LastTopics"`
+	URLCounter       `json:"URLCounter"`
+	WordCounter      `json:"WordCounter"`
+	SwearCounter     `json:"SwearCounter"`
+	EmoticonCounter  `json:"EmoticonCounter"`
+	ConsecutiveLines `json:"ConsecutiveLines"`
 	QuestionsCount
 	ExclamationsCount
 	AllCapsCount
 	NickReferences
-
-	ID         uint
-	Name       string
-	Topic      string
-	JoinCount  uint
-	PartCount  uint
-	UserIDs    map[uint]struct{}
+	LanguageCounter `json:"LanguageCounter"`
+	FloodHistory    `json:"FloodHistory"`
+	HashtagCounter  `json:"HashtagCounter"`
+	HandleCounter   `json:"HandleCounter"`
+
+	ID        uint
+	Name      string
+	Topic     string
+	JoinCount uint
+	PartCount uint
+	UserIDs   map[uint]struct{}
+	NetworkID uint
+	// MessageRanges are the ids of the messages, MessageCount is how many
+	// messages were counted, MessageRanges forgets the ones pruned.
+	MessageRanges IDRanges
+	MessageCount  uint
+	// MessageIDs are the ids of stats saved before MessageRanges, they're
+	// moved to it when loaded.
 	MessageIDs []uint
-	NetworkID  uint
 
 	TopConsecutiveLines TopTokenArray
 	LastActive          time.Time
+	Corrections         uint
 	Quotes              quotes
+	Mood                MoodSeries
+	TextByKind          KindTextCounters
+	DailySpeakers       DailySpeakers
+	Starters            ConversationStarters
+	Reactions           ReactionTracker
+	Leaderboard         LeaderboardHistory
+	Days                DayCounter
+	// Sources are the messages counted in a channel that others alias by
+	// the network and channel they were said in, in lower case apart by a
+	// space, its own included. See Options.ChannelAliases.
+	Sources map[string]uint
+
+	// lazy is the rest of a channel loaded with only its summary, see load.
+	lazy    []byte
+	loadErr error
+	lazyMut sync.Mutex
 }
 
 func newChannel(id uint, network *Network, name string) *Channel {
 	return &Channel{
-		ID:         id,
-		Name:       name,
-		JoinCount:  0,
-		PartCount:  0,
-		UserIDs:    make(map[uint]struct{}, 0),
-		MessageIDs: make([]uint, 0),
-		NetworkID:  network.ID,
+		ID:        id,
+		Name:      name,
+		JoinCount: 0,
+		PartCount: 0,
+		UserIDs:   make(map[uint]struct{}, 0),
+		NetworkID: network.ID,
 
 		URLCounter:       NewURLCounter(),
 		WordCounter:      NewWordCounter(),
@@ -51,39 +78,67 @@ func newChannel(id uint, network *Network, name string) *Channel {
 		ConsecutiveLines: NewConsecutiveLines(),
 		LastTopics:       NewLastTopics(),
 		NickReferences:   make(NickReferences),
+		LanguageCounter:  NewLanguageCounter(),
+		HashtagCounter:   NewHashtagCounter(),
+		HandleCounter:    NewHandleCounter(),
+		Mood:             make(MoodSeries),
+		TextByKind:       make(KindTextCounters),
+		DailySpeakers:    NewDailySpeakers(),
+		Starters:         NewConversationStarters(),
+		Leaderboard:      NewLeaderboardHistory(),
 	}
 }
 
 // String returns a the name of the channel and the number of messages inside.
 func (c *Channel) String() string {
-	return fmt.Sprintf("Channel: %s, Messages: %d", c.Name, len(c.MessageIDs))
+	return fmt.Sprintf("Channel: %s, Messages: %d", c.Name, c.MessageCount)
 }
 
 // AddMessageID adds a message id to the list of message ids.
 func (c *Channel) addMessage(network *Network, message *Message, user *User) {
-	c.MessageIDs = append(c.MessageIDs, message.ID)
+	if network.keepsID(message) {
+		c.MessageRanges.add(message.ID)
+	}
+	c.MessageCount++
 
 	c.addUserID(message.UserID)
+	// a message played back late didn't interrupt the lines around it
+	late := message.Date.Before(c.LastActive)
 
 	if message.Kind == Msg {
 		c.HourlyChart.addMessage(message)
 		c.Quotes.addMessage(message)
 		c.URLCounter.addMessage(message)
-		c.WordCounter.addMessage(message)
+		if !message.features.NoWords {
+			c.WordCounter.addTokens(network.wordTokens(message))
+		}
 		c.SwearCounter.addMessage(message)
 		c.EmoticonCounter.addMessage(message)
-		c.ConsecutiveLines.addMessage(message, user)
+		if !late {
+			c.ConsecutiveLines.addMessage(message, user)
+		}
 		c.QuestionsCount.addMessage(message)
 		c.ExclamationsCount.addMessage(message)
 		c.AllCapsCount.addMessage(message)
 		c.NickReferences.addMessage(network, c, message)
+		c.HashtagCounter.addMessage(message)
+		c.HandleCounter.addMessage(message)
+	}
+
+	if message.Kind.isChat() {
+		c.TextByKind.addTokens(message.Kind, network.wordTokens(message))
+		c.DailySpeakers.addMessage(message)
+		c.Leaderboard.addMessage(message)
+		c.Days.addMessage(message)
 	}
 
 	if message.Kind == Topic {
 		c.LastTopics.addMessage(message)
 	}
 
-	c.LastActive = message.Date
+	if message.Date.After(c.LastActive) {
+		c.LastActive = message.Date
+	}
 }
 
 // AddUserID
@@ -95,10 +150,10 @@ func (c *Channel) addUserID(id uint) {
 func (c *Channel) addKick(stats *Stats, message *Message) {
 	network := stats.Networks[c.NetworkID]
 
-	targetName := strings.ToLower(strings.Split(message.Message, " ")[0])
+	targetName := network.fold(strings.Split(message.Message, " ")[0])
 	kickerID := message.UserID
 
-	kicker := stats.Users[kickerID]
+	kicker, _ := stats.user(kickerID)
 	kicker.KickCounters.Sent++
 
 	if target, ok := network.users[targetName]; ok {
@@ -113,8 +168,8 @@ func (c *Channel) addAction(stats *Stats, message *Message) {
 	network := stats.Networks[c.NetworkID]
 
 	if m := slapsRegex.FindStringSubmatch(message.Message); m != nil {
-		receiver := network.users[strings.ToLower(m[1])]
-		sender := stats.Users[message.UserID]
+		receiver := network.users[network.fold(m[1])]
+		sender, _ := stats.user(message.UserID)
 		c.addSlap(sender, receiver)
 	}
 }
@@ -0,0 +1,85 @@
+package stats
+
+import "sort"
+
+// ChannelOverlap is the number of active users two channels share.
+type ChannelOverlap struct {
+	A     string `json:"a"`
+	B     string `json:"b"`
+	Users uint   `json:"users"`
+}
+
+// ChannelDistribution returns the fraction of a user's lines that were said
+// in each channel, keyed by lowercased channel name.
+func (u *User) ChannelDistribution() map[string]float64 {
+	var total uint
+	for _, cu := range u.ChannelUsers {
+		total += cu.Lines
+	}
+
+	dist := make(map[string]float64, len(u.ChannelUsers))
+	if total == 0 {
+		return dist
+	}
+
+	for name, cu := range u.ChannelUsers {
+		dist[name] = float64(cu.Lines) / float64(total)
+	}
+
+	return dist
+}
+
+// ChannelOverlap counts, for every pair of channels on the network, how many
+// users said at least minLines lines in both. Pairs are sorted with the
+// largest overlap first.
+func (n *Network) ChannelOverlap(minLines uint) []ChannelOverlap {
+	counts := make(map[[2]string]uint)
+
+	for _, id := range n.UserIDs {
+		u := n.stats.Users[id]
+
+		active := make([]string, 0, len(u.ChannelUsers))
+		for name, cu := range u.ChannelUsers {
+			if cu.Lines >= minLines && cu.Lines > 0 {
+				active = append(active, name)
+			}
+		}
+		sort.Strings(active)
+
+		for i := range active {
+			for j := i + 1; j < len(active); j++ {
+				counts[[2]string{active[i], active[j]}]++
+			}
+		}
+	}
+
+	pairs := make([]ChannelOverlap, 0, len(counts))
+	for pair, users := range counts {
+		pairs = append(pairs, ChannelOverlap{
+			A:     n.channelName(pair[0]),
+			B:     n.channelName(pair[1]),
+			Users: users,
+		})
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Users != pairs[j].Users {
+			return pairs[i].Users > pairs[j].Users
+		}
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+
+	return pairs
+}
+
+// channelName returns the display name of a channel from its lowercased key.
+func (n *Network) channelName(key string) string {
+	if c, ok := n.channels[key]; ok {
+		return c.Name
+	}
+
+	return key
+}
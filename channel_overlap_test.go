@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUser_ChannelDistribution(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, "#a", nick, time.Now(), "one")
+	s.AddMessage(Msg, network, "#a", nick, time.Now(), "two")
+	s.AddMessage(Msg, network, "#a", nick, time.Now(), "three")
+	s.AddMessage(Msg, network, "#B", nick, time.Now(), "four")
+
+	dist := s.GetUser(network, nick).ChannelDistribution()
+
+	if dist["#a"] != 0.75 || dist["#b"] != 0.25 {
+		t.Error("Distribution is incorrect:", dist)
+	}
+}
+
+func TestNetwork_ChannelOverlap(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	for _, n := range []string{"alice", "bob", "carol"} {
+		s.AddMessage(Msg, network, "#A", n, time.Now(), "hi")
+		s.AddMessage(Msg, network, "#b", n, time.Now(), "hi")
+	}
+	s.AddMessage(Msg, network, "#c", "alice", time.Now(), "hi")
+	s.AddMessage(Msg, network, "#c", "alice", time.Now(), "hi")
+	s.AddMessage(Msg, network, "#c", "bob", time.Now(), "hi")
+	s.AddMessage(Join, network, "#d", "bob", time.Now(), "")
+
+	n := s.GetNetwork(network)
+	pairs := n.ChannelOverlap(1)
+
+	if len(pairs) != 3 {
+		t.Fatal("Should have three channel pairs, Got:", pairs)
+	}
+
+	if p := pairs[0]; p.A != "#A" || p.B != "#b" || p.Users != 3 {
+		t.Error("#A and #b should share the most users, Got:", p)
+	}
+
+	if pairs = n.ChannelOverlap(2); len(pairs) != 0 {
+		t.Error("Nobody said two lines in two channels, Got:", pairs)
+	}
+}
@@ -1,16 +1,43 @@
 package stats
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestChannel_Stringer(t *testing.T) {
 	t.Parallel()
 
 	c := &Channel{
-		Name:       "foo",
-		MessageIDs: []uint{1, 2, 3},
+		Name:          "foo",
+		MessageRanges: idRanges([]uint{1, 2, 3}),
+		MessageCount:  3,
 	}
 
 	if c.String() != "Channel: foo, Messages: 3" {
 		t.Error("Did not return correct string.")
 	}
 }
+
+func TestChannel_DailySpeakers(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	s.AddMessage(Msg, network, channel, "early", day, "morning")
+	s.AddMessage(Join, network, channel, "late", day.Add(time.Hour), "")
+	s.AddMessage(Msg, network, channel, "late", day.Add(2*time.Hour), "night")
+	s.AddMessage(Part, network, channel, "early", day.Add(3*time.Hour), "")
+
+	c := s.GetChannel(network, channel)
+	early, late := s.GetUser(network, "early"), s.GetUser(network, "late")
+
+	if c.DailySpeakers.First[early.ID] != 1 {
+		t.Error("early should have spoken first.")
+	}
+
+	if c.DailySpeakers.Last[late.ID] != 1 || c.DailySpeakers.Last[early.ID] != 0 {
+		t.Error("late should have the last word.")
+	}
+}
@@ -0,0 +1,105 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Charset is a single byte charset that old logs and clients send instead of
+// UTF-8. Only the bytes from 0x80 on differ from ASCII.
+type Charset struct {
+	Name string
+	high [128]rune
+}
+
+// The charsets most often found in place of UTF-8 on irc.
+var (
+	Latin1 = newCharset("iso-8859-1", nil)
+	Latin9 = newCharset("iso-8859-15", map[byte]rune{
+		0xa4: '€', 0xa6: 'Š', 0xa8: 'š', 0xb4: 'Ž', 0xb8: 'ž', 0xbc: 'Œ', 0xbd: 'œ', 0xbe: 'Ÿ',
+	})
+	// CP1252 keeps the bytes windows leaves undefined as the C1 controls of
+	// Latin1, as browsers do.
+	CP1252 = newCharset("windows-1252", map[byte]rune{
+		0x80: '€', 0x82: '‚', 0x83: 'ƒ', 0x84: '„', 0x85: '…', 0x86: '†', 0x87: '‡',
+		0x88: 'ˆ', 0x89: '‰', 0x8a: 'Š', 0x8b: '‹', 0x8c: 'Œ', 0x8e: 'Ž',
+		0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”', 0x95: '•', 0x96: '–', 0x97: '—',
+		0x98: '˜', 0x99: '™', 0x9a: 'š', 0x9b: '›', 0x9c: 'œ', 0x9e: 'ž', 0x9f: 'Ÿ',
+	})
+)
+
+// charsetNames are the names and aliases LookupCharset knows.
+var charsetNames = map[string]*Charset{
+	"latin1":       Latin1,
+	"iso-8859-1":   Latin1,
+	"latin9":       Latin9,
+	"iso-8859-15":  Latin9,
+	"cp1252":       CP1252,
+	"windows-1252": CP1252,
+}
+
+// newCharset creates a charset that's Latin1 but for the bytes in diff.
+func newCharset(name string, diff map[byte]rune) *Charset {
+	c := &Charset{Name: name}
+	for i := range c.high {
+		c.high[i] = rune(0x80 + i)
+	}
+	for b, r := range diff {
+		c.high[b-0x80] = r
+	}
+	return c
+}
+
+// LookupCharset finds a charset by its name, eg. latin1 or cp1252. UTF-8 has
+// no fallback, it's nil.
+func LookupCharset(name string) (*Charset, error) {
+	name = strings.ToLower(name)
+	switch name {
+	case "", "utf8", "utf-8":
+		return nil, nil
+	}
+
+	if c, ok := charsetNames[name]; ok {
+		return c, nil
+	}
+	return nil, fmt.Errorf("Unknown charset %s, the charsets are: latin1, latin9, cp1252", name)
+}
+
+// Decode turns text into valid UTF-8. The UTF-8 in it is kept, the bytes
+// that aren't are decoded from the charset, so lines mixing the two read
+// right. Those bytes are replaced by U+FFFD when the charset is nil. Valid
+// UTF-8 is returned as it is.
+func (c *Charset) Decode(text string) string {
+	if utf8.ValidString(text) {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) + len(text)/2)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if r == utf8.RuneError && size == 1 && c != nil {
+			r = c.high[text[i]-0x80]
+		}
+		b.WriteRune(r)
+		i += size
+	}
+	return b.String()
+}
+
+// charset is the charset the messages of a network fall back to.
+func (o *Options) charset(network string) *Charset {
+	if c, ok := o.Charsets[strings.ToLower(network)]; ok {
+		return c
+	}
+	return o.Charset
+}
+
+// decode makes the text of a message valid UTF-8, see Charset.Decode.
+func (o *Options) decode(raw *RawMessage) {
+	c := o.charset(raw.Network)
+	raw.Channel = c.Decode(raw.Channel)
+	raw.Hostmask = c.Decode(raw.Hostmask)
+	raw.Message = c.Decode(raw.Message)
+}
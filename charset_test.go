@@ -0,0 +1,71 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCharset_Decode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		charset *Charset
+		text    string
+		want    string
+	}{
+		{CP1252, "café", "café"},
+		{CP1252, "caf\xe9", "café"},
+		{CP1252, "\x93quoted\x94 \x80", "“quoted” €"},
+		{CP1252, "\x81", "\u0081"},
+		{Latin1, "\x80caf\xe9", "\u0080café"},
+		{Latin9, "\xa4 \xbd", "€ œ"},
+		// utf-8 and the fallback on the same line
+		{Latin1, "café and caf\xe9", "café and café"},
+		{nil, "caf\xe9", "caf�"},
+		{nil, "plain", "plain"},
+	}
+
+	for _, test := range tests {
+		if got := test.charset.Decode(test.text); got != test.want {
+			t.Errorf("%q should decode to %q, Got: %q", test.text, test.want, got)
+		}
+	}
+}
+
+func TestLookupCharset(t *testing.T) {
+	t.Parallel()
+
+	if c, err := LookupCharset("Windows-1252"); err != nil || c != CP1252 {
+		t.Error("Should find charsets by name:", c, err)
+	}
+	if c, err := LookupCharset("latin1"); err != nil || c != Latin1 {
+		t.Error("Should find charsets by alias:", c, err)
+	}
+	if c, err := LookupCharset("UTF-8"); err != nil || c != nil {
+		t.Error("UTF-8 should have no fallback:", c, err)
+	}
+	if _, err := LookupCharset("ebcdic"); err == nil {
+		t.Error("Should reject unknown charsets.")
+	}
+}
+
+func TestStats_Charset(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{
+		Charset:  CP1252,
+		Charsets: map[string]*Charset{"Other": nil},
+	})
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "\x93na\xefve\x94")
+	s.AddMessage(Msg, "other", channel, hostmask, time.Now(), "na\xefve")
+
+	u := s.GetUser(network, nick)
+	if u.Quotes.Last.Message != "“naïve”" || u.Letters != 7 {
+		t.Error("Should decode the charset before counting:", u.Quotes.Last.Message, u.Letters)
+	}
+	if u := s.GetUser("other", nick); u.Quotes.Last.Message != "na�ve" {
+		t.Error("Should replace what isn't UTF-8 without a charset:", u.Quotes.Last.Message)
+	}
+}
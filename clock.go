@@ -0,0 +1,106 @@
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the stats what time it is, see Options.Clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the time of the system, the clock of the stats by default.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a clock that's only moved by hand, to test what the stats do
+// at a time.
+type ManualClock struct {
+	mut sync.Mutex
+	now time.Time
+}
+
+// NewManualClock creates a clock stopped at now.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the time the clock was set to.
+func (c *ManualClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	return c.now
+}
+
+// Set moves the clock to now.
+func (c *ManualClock) Set(now time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.now = now
+}
+
+// Advance moves the clock on by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+// ReplayClock is at the date of the latest message the stats counted, for
+// replaying logs as if they were said now: today is the day of the replay,
+// and the retention is counted back from it. It only moves forward, and it's
+// the time of the system until a message is counted. The dates of a replay
+// are never in its future, MaxFutureSkew rejects none of them.
+type ReplayClock struct {
+	mut sync.Mutex
+	now time.Time
+}
+
+// Now returns the date of the latest message counted.
+func (c *ReplayClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.now.IsZero() {
+		return time.Now()
+	}
+	return c.now
+}
+
+// observe moves the clock to the date of a message dated after it.
+func (c *ReplayClock) observe(date time.Time) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if date.After(c.now) {
+		c.now = date
+	}
+}
+
+// now is the time of the clock of the options.
+func (o *Options) now() time.Time {
+	if o.Clock == nil {
+		return systemClock{}.Now()
+	}
+	return o.Clock.Now()
+}
+
+// Now is the time of the clock of the stats, see Options.Clock.
+func (s *Stats) Now() time.Time {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.opts.now()
+}
+
+// Now is Stats.Now inside of View.
+func (tx *ReadTx) Now() time.Time {
+	return tx.s.opts.now()
+}
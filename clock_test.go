@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2014, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewManualClock(start)
+	if !c.Now().Equal(start) {
+		t.Error("Should stop at the time given:", c.Now())
+	}
+	c.Advance(time.Hour)
+	if !c.Now().Equal(start.Add(time.Hour)) {
+		t.Error("Should advance by the duration:", c.Now())
+	}
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Error("Should be set back:", c.Now())
+	}
+}
+
+func TestStats_Clock(t *testing.T) {
+	t.Parallel()
+
+	day := time.Date(2014, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(day)
+	s := newStats()
+	s.SetOptions(Options{Clock: clock, MaxFutureSkew: time.Hour, Retention: 24 * time.Hour})
+
+	s.AddMessage(Msg, network, channel, hostmask, day.Add(-48*time.Hour), "old")
+	s.AddMessage(Msg, network, channel, hostmask, day.Add(-time.Hour), "this morning")
+	s.AddMessage(Msg, network, channel, hostmask, day.Add(2*time.Hour), "from the future")
+	if !s.Now().Equal(day) {
+		t.Error("Should take the time of the clock:", s.Now())
+	}
+
+	if c := s.GetChannel(network, channel); c.MessageCount != 2 {
+		t.Error("Should bound the dates by the clock, counted", c.MessageCount)
+	}
+	if sum, _ := s.ChannelSummary(network, channel); sum.LinesToday != 1 {
+		t.Error("Should count the lines of the day of the clock, counted", sum.LinesToday)
+	}
+
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if c := s.GetChannel(network, channel); c.MessageRanges.Len() != 1 {
+		t.Error("Should prune by the clock:", c.MessageRanges.All())
+	}
+
+	clock.Advance(24 * time.Hour)
+	if sum, _ := s.ChannelSummary(network, channel); sum.LinesToday != 0 {
+		t.Error("Should count no lines on the next day, counted", sum.LinesToday)
+	}
+}
+
+func TestReplayClock(t *testing.T) {
+	t.Parallel()
+
+	clock := &ReplayClock{}
+	if time.Since(clock.Now()) > time.Minute {
+		t.Error("Should be the time of the system before a message is counted:", clock.Now())
+	}
+
+	s := newStats()
+	s.SetOptions(Options{Clock: clock, MaxFutureSkew: time.Minute})
+	day := time.Date(2014, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, day, "first")
+	s.AddMessage(Msg, network, channel, hostmask, day.Add(time.Hour), "an hour later")
+	s.AddMessage(Msg, network, channel, hostmask, day.Add(time.Minute), "played back late")
+
+	if !clock.Now().Equal(day.Add(time.Hour)) {
+		t.Error("Should follow the latest message counted:", clock.Now())
+	}
+	if c := s.GetChannel(network, channel); c.MessageCount != 3 {
+		t.Error("Should reject none of the replay, counted", c.MessageCount)
+	}
+	if sum, _ := s.ChannelSummary(network, channel); sum.LinesToday != 3 {
+		t.Error("Should count the lines of the day replayed, counted", sum.LinesToday)
+	}
+}
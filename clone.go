@@ -0,0 +1,123 @@
+package stats
+
+import "reflect"
+
+// pkgPath is the path of this package, its structs are cloned field by field.
+var pkgPath = reflect.TypeOf(Stats{}).PkgPath()
+
+// clone copies what's saved of the stats, so they can be encoded while
+// messages are added again. The maps, slices and structs are copied, the
+// clone shares nothing that changes with the stats. It's called with the
+// stats locked for reading.
+func (s *Stats) clone() *Stats {
+	clone := &Stats{opts: s.opts}
+	cloneValue(reflect.ValueOf(clone).Elem(), reflect.ValueOf(s).Elem())
+	return clone
+}
+
+// clone copies the channel as it's saved. A channel that isn't loaded has
+// nothing to copy but its summary, the rest is kept encoded as it was read.
+func (c *Channel) clone() *Channel {
+	c.lazyMut.Lock()
+	defer c.lazyMut.Unlock()
+
+	clone := &Channel{lazy: c.lazy, loadErr: c.loadErr}
+	cloneValue(reflect.ValueOf(clone).Elem(), reflect.ValueOf(c).Elem())
+	return clone
+}
+
+// cloneValue deeply copies src into dst, which must be settable. Only the
+// exported fields of the structs of this package are copied, they're all
+// that's saved. The structs of other packages, eg. time.Time, are values
+// copied as they are.
+func cloneValue(dst, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			return
+		}
+		if c, ok := src.Interface().(*Channel); ok {
+			dst.Set(reflect.ValueOf(c.clone()))
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		cloneValue(p.Elem(), src.Elem())
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		e := reflect.New(src.Elem().Type()).Elem()
+		cloneValue(e, src.Elem())
+		dst.Set(e)
+	case reflect.Struct:
+		if src.Type().PkgPath() != pkgPath {
+			dst.Set(src)
+			return
+		}
+		for i := 0; i < src.NumField(); i++ {
+			if f := dst.Field(i); f.CanSet() {
+				cloneValue(f, src.Field(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			cloneValue(dst.Index(i), src.Index(i))
+		}
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		c := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		if plain(src.Type().Elem()) {
+			reflect.Copy(c, src)
+		} else {
+			for i := 0; i < src.Len(); i++ {
+				cloneValue(c.Index(i), src.Index(i))
+			}
+		}
+		dst.Set(c)
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		if plain(src.Type().Elem()) {
+			for iter.Next() {
+				m.SetMapIndex(iter.Key(), iter.Value())
+			}
+		} else {
+			e := reflect.New(src.Type().Elem()).Elem()
+			for iter.Next() {
+				e.Set(reflect.Zero(e.Type()))
+				cloneValue(e, iter.Value())
+				m.SetMapIndex(iter.Key(), e)
+			}
+		}
+		dst.Set(m)
+	default:
+		dst.Set(src)
+	}
+}
+
+// plain checks if the values of a type are copied whole by assigning them:
+// numbers, strings and the structs and arrays of them.
+func plain(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return false
+	case reflect.Array:
+		return plain(t.Elem())
+	case reflect.Struct:
+		if t.PkgPath() != pkgPath {
+			return true
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if !plain(t.Field(i).Type) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestStats_clone(t *testing.T) {
+	t.Parallel()
+
+	s := canonicalStats()
+	clone := s.clone()
+
+	var a, b bytes.Buffer
+	if err := s.encodeCanonical(&a); err != nil {
+		t.Fatal(err)
+	}
+	if err := clone.encodeCanonical(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Should clone everything that's saved.")
+	}
+
+	s.AddMessage(Msg, network, "#chan0", hostmask, time.Now(), "some more foo")
+	s.Users[1].ChannelUsers["#chan0"].WordCounter.All["foo"] = 100
+	b.Reset()
+	if err := clone.encodeCanonical(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Should not change the clone as messages are added.")
+	}
+}
+
+func TestChannel_cloneLazy(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	s = saveLoad(t, s)
+	c := s.Channels[1].clone()
+	if c.loaded() || !bytes.Equal(c.lazy, s.Channels[1].lazy) || c.Name != channel || c.MessageCount != 1 {
+		t.Errorf("Should clone the summary and keep the rest encoded: %+v", c.summary())
+	}
+
+	if c = saveLoad(t, s).GetChannel(network, channel); c == nil || c.WordCounter.All["foo"] != 1 {
+		t.Error("Should save the channel as it was read:", c)
+	}
+}
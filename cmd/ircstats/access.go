@@ -0,0 +1,14 @@
+package main
+
+import "github.com/DylanJ/stats/access"
+
+// access is who may read the stats served by the configuration: the private
+// channels are served only to the api_tokens allowed them. The configuration
+// must be valid.
+func (c *config) access() *access.Access {
+	private := make(map[string][]string, len(c.Networks))
+	for _, n := range c.Networks {
+		private[n.Name] = append(private[n.Name], n.Private...)
+	}
+	return access.New(private, c.APITokens, c.RequireToken)
+}
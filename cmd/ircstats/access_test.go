@@ -0,0 +1,125 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// accessConfig makes #bots of zkpq private, and every channel of other.
+func accessConfig() *config {
+	return &config{
+		Networks: []networkConfig{
+			{Name: "zkpq", Server: "localhost:6667", Nick: "bot", Private: []string{"#Bots"}},
+			{Name: "other", Server: "localhost:6667", Nick: "bot", Private: []string{"*"}},
+		},
+		APITokens: map[string][]string{
+			"hunter2": {"ZKPQ #bots"},
+			"s3cret":  {"*"},
+			"nothing": nil,
+		},
+	}
+}
+
+func TestAccess_handler(t *testing.T) {
+	t.Parallel()
+
+	conf := accessConfig()
+	srv := conf.access().Handler(newReportServer(exportStats(t), nil))
+
+	tests := []struct {
+		url, token string
+		code       int
+		want       []string
+		unwanted   []string
+	}{
+		{"/export.csv", "", 200, []string{"#deviate,dylan"}, []string{"#bots", "scott"}},
+		{"/export.csv", "hunter2", 200, []string{"#bots,dylan"}, []string{"scott"}},
+		{"/export.csv?token=s3cret", "", 200, []string{"#bots,dylan", "scott"}, nil},
+		{"/?top=0", "", 200, []string{"#deviate on zkpq"}, []string{"#bots", "on other"}},
+		{"/?top=0&token=hunter2", "", 200, []string{"#bots on zkpq", "&amp;token=hunter2"}, []string{"on other"}},
+		{"/top.json?network=zkpq&channel=%23bots", "", 404, nil, nil},
+		{"/top.json?network=zkpq&channel=%23bots", "hunter2", 200, []string{`"nick": "dylan"`}, nil},
+		{"/search.json?network=other&channel=%23deviate&q=yo", "", 404, nil, nil},
+		{"/export.csv", "hunter3", 401, nil, nil},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", test.url, nil)
+		if len(test.token) > 0 {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, r)
+		if w.Code != test.code {
+			t.Errorf("%s with %q should be served with %d, was %d", test.url, test.token, test.code, w.Code)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(w.Body.String(), want) {
+				t.Errorf("%s with %q should contain %s, is %s", test.url, test.token, want, w.Body.String())
+			}
+		}
+		for _, unwanted := range test.unwanted {
+			if strings.Contains(w.Body.String(), unwanted) {
+				t.Errorf("%s with %q should not contain %s, is %s", test.url, test.token, unwanted, w.Body.String())
+			}
+		}
+	}
+
+	conf.RequireToken = true
+	srv = conf.access().Handler(newReportServer(exportStats(t), nil))
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/export.csv", nil))
+	if w.Code != 401 || len(w.Header().Get("WWW-Authenticate")) == 0 {
+		t.Error("Should turn away the requests without a token when one is required, served", w.Code)
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/export.csv?token=nothing", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "#deviate,dylan") {
+		t.Error("Should serve the public channels to the tokens, served", w.Code)
+	}
+}
+
+func TestAccess_searchAliases(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ircstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	raw, err := stats.OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetOptions(stats.Options{
+		RawStore:       raw,
+		ChannelAliases: map[string]string{"bridge #bots-relay": "zkpq #bots"},
+	})
+	s.AddMessage(stats.Msg, "bridge", "#bots-relay", "dylan!d@zqz.ca", time.Now(), "!stats")
+
+	srv := accessConfig().access().Handler(newReportServer(s, nil))
+	for _, token := range []string{"", "nothing"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", "/search.json?network=bridge&channel=%23bots-relay&q=stats&token="+token, nil))
+		if w.Code != 404 {
+			t.Errorf("Should not find the messages of the aliases of a private channel with %q, served %d: %s", token, w.Code, w.Body.String())
+		}
+	}
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/search.json?network=bridge&channel=%23bots-relay&q=stats&token=hunter2", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "!stats") {
+		t.Errorf("Should find them with a token allowed the private channel, served %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats/statsbot"
+)
+
+const (
+	dialTimeout    = 30 * time.Second
+	reconnectDelay = 30 * time.Second
+)
+
+// client is a minimal irc client that joins channels and feeds everything it
+// sees into the stats.
+type client struct {
+	config  networkConfig
+	handler *statsbot.Handler
+
+	// mut guards conn, announcements are sent from other goroutines
+	mut  sync.Mutex
+	conn io.ReadWriteCloser
+	nick string
+	caps []string
+
+	// history is the most messages the server plays back for a single
+	// CHATHISTORY request, or 0 when it doesn't have chathistory.
+	history int
+	// batches are the pages of history being played back and requested the
+	// time each channel's last page was requested after
+	batches   map[string]*historyBatch
+	requested map[string]time.Time
+}
+
+func newClient(c networkConfig, h *statsbot.Handler) *client {
+	return &client{
+		config:  c,
+		handler: h,
+		nick:    c.Nick,
+	}
+}
+
+// run connects to the network and reconnects whenever the connection drops.
+func (c *client) run() {
+	for {
+		if err := c.connect(); err != nil {
+			slog.Error("Failed connecting", "network", c.config.Name, "err", err)
+		} else if err = c.serve(); err != nil {
+			slog.Warn("Disconnected", "network", c.config.Name, "err", err)
+		}
+
+		time.Sleep(reconnectDelay)
+	}
+}
+
+func (c *client) connect() error {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+
+	var conn net.Conn
+	var err error
+	if c.config.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.config.Server, &tls.Config{
+			InsecureSkipVerify: c.config.Insecure,
+		})
+	} else {
+		conn, err = dialer.Dial("tcp", c.config.Server)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.mut.Lock()
+	c.conn = conn
+	c.mut.Unlock()
+	return nil
+}
+
+// serve registers with the server and handles lines until the connection is
+// closed.
+func (c *client) serve() error {
+	defer c.conn.Close()
+
+	c.caps = nil
+	c.history, c.batches, c.requested = 0, nil, nil
+	c.send("CAP LS 302")
+	if len(c.config.Password) > 0 {
+		c.send("PASS " + c.config.Password)
+	}
+	c.send("NICK " + c.nick)
+	c.send(fmt.Sprintf("USER %s 0 * :%s", c.config.User, c.config.Realname))
+
+	scanner := bufio.NewScanner(c.conn)
+	for scanner.Scan() {
+		l, err := parseLine(scanner.Text())
+		if err != nil {
+			continue
+		}
+
+		c.handle(l)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (c *client) handle(l *line) {
+	switch l.command {
+	case "PING":
+		c.send("PONG :" + l.arg(0))
+	case "CAP":
+		c.handleCap(l)
+	case "AUTHENTICATE":
+		sasl := c.config.SASL
+		payload := sasl.User + "\x00" + sasl.User + "\x00" + sasl.Password
+		c.send("AUTHENTICATE " + base64.StdEncoding.EncodeToString([]byte(payload)))
+	case "903", "904", "905", "906", "907":
+		// sasl finished, successfully or not
+		c.send("CAP END")
+	case "433":
+		// nick in use
+		c.nick += "_"
+		c.send("NICK " + c.nick)
+	case "001":
+		c.handler.SetNick(c.config.Name, l.arg(0))
+		if len(c.config.Channels) > 0 {
+			c.send("JOIN " + strings.Join(c.config.Channels, ","))
+		}
+	case "005":
+		c.handleISupport(l)
+	case "BATCH":
+		c.handleBatch(l)
+	default:
+		c.trackHistory(l)
+		channel, reply := c.handler.HandleTagged(c.config.Name, l.command, l.prefix, l.args, l.tags)
+		for _, line := range c.handler.Lines(channel, reply) {
+			c.handler.Sent(c.config.Name, channel, line)
+			c.send("PRIVMSG " + channel + " :" + line)
+		}
+	}
+}
+
+// wantedCaps are the capabilities requested when the server has them.
+// server-time and batch date the history bouncers play back, message-tags
+// carries the msgids used to skip history that was already counted and
+// chathistory fetches the history missed while disconnected.
+var wantedCaps = []string{"server-time", "batch", "message-tags", "draft/chathistory"}
+
+func (c *client) handleCap(l *line) {
+	switch strings.ToUpper(l.arg(1)) {
+	case "LS":
+		// multiline replies mark every line but the last with a *
+		caps := l.arg(2)
+		if caps == "*" {
+			c.caps = append(c.caps, strings.Fields(l.arg(3))...)
+			return
+		}
+		c.caps = append(c.caps, strings.Fields(caps)...)
+		c.requestCaps()
+	case "ACK":
+		if c.config.SASL != nil && strings.Contains(" "+l.arg(2)+" ", " sasl ") {
+			c.send("AUTHENTICATE PLAIN")
+			return
+		}
+		c.send("CAP END")
+	case "NAK":
+		c.send("CAP END")
+	}
+}
+
+// requestCaps requests the wanted capabilities the server offers.
+func (c *client) requestCaps() {
+	wanted := wantedCaps
+	if c.config.SASL != nil {
+		wanted = append(wanted[:len(wanted):len(wanted)], "sasl")
+	}
+
+	var req []string
+	for _, capability := range c.caps {
+		// values such as sasl=PLAIN,EXTERNAL follow the name
+		name := strings.SplitN(capability, "=", 2)[0]
+		for _, w := range wanted {
+			if name == w {
+				req = append(req, name)
+			}
+		}
+	}
+
+	if len(req) == 0 {
+		c.send("CAP END")
+		return
+	}
+	c.send("CAP REQ :" + strings.Join(req, " "))
+}
+
+func (c *client) send(line string) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if c.conn == nil {
+		return
+	}
+	if _, err := io.WriteString(c.conn, line+"\r\n"); err != nil {
+		slog.Error("Failed writing", "network", c.config.Name, "err", err)
+	}
+}
+
+// Privmsg sends a message to a channel or user, for the announcer.
+func (c *client) Privmsg(target string, args ...interface{}) error {
+	text := fmt.Sprint(args...)
+	c.handler.Sent(c.config.Name, target, text)
+	c.send("PRIVMSG " + target + " :" + text)
+	return nil
+}
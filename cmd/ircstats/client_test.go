@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/statsbot"
+)
+
+func TestClient_serve(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(networkConfig{
+		Name:     "network",
+		Nick:     "bot",
+		User:     "bot",
+		Realname: "bot",
+		SASL:     &saslLogin{User: "bot", Password: "pass"},
+		Channels: []string{"#a", "#b"},
+	}, statsbot.New(s))
+
+	server, conn := net.Pipe()
+	c.conn = conn
+
+	done := make(chan error)
+	go func() { done <- c.serve() }()
+
+	r := bufio.NewReader(server)
+	expect := func(want string) {
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want+"\r\n" {
+			t.Errorf("Expected: %q, Got: %q", want, got)
+		}
+	}
+	send := func(line string) {
+		io.WriteString(server, line+"\r\n")
+	}
+
+	expect("CAP LS 302")
+	expect("NICK bot")
+	expect("USER bot 0 * :bot")
+	send(":server CAP * LS * :multi-prefix sasl=PLAIN")
+	send(":server CAP * LS :server-time")
+	expect("CAP REQ :sasl server-time")
+	send(":server CAP * ACK :sasl server-time")
+	expect("AUTHENTICATE PLAIN")
+	send("AUTHENTICATE +")
+	expect("AUTHENTICATE Ym90AGJvdABwYXNz")
+	send(":server 903 bot :SASL authentication successful")
+	expect("CAP END")
+	send(":server 001 bot :Welcome")
+	expect("JOIN #a,#b")
+	send("PING :12345")
+	expect("PONG :12345")
+	send("@time=2014-03-01T08:00:00.000Z;msgid=abc :alice!a@host PRIVMSG #a :!stats alice")
+	send("@time=2014-03-01T08:00:00.000Z;msgid=abc :alice!a@host PRIVMSG #a :!stats alice")
+	send(":alice!a@host PRIVMSG #a :!stats alice")
+	expect("PRIVMSG #a :alice: 2 lines, 4 words, 2.0 words per line")
+
+	server.Close()
+	<-done
+
+	if s.GetChannel("network", "#a") == nil {
+		t.Error("Should have collected stats for #a.")
+	}
+}
+
+func TestClient_Privmsg(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(networkConfig{Name: "network"}, statsbot.New(s))
+	if err := c.Privmsg("#a", "not connected"); err != nil {
+		t.Error("Should drop messages while disconnected:", err)
+	}
+
+	server, conn := net.Pipe()
+	c.conn = conn
+	go c.Privmsg("#a", "hello ", "there")
+
+	line, err := bufio.NewReader(server).ReadString('\n')
+	if err != nil || line != "PRIVMSG #a :hello there\r\n" {
+		t.Errorf("Wrong line: %q %v", line, err)
+	}
+}
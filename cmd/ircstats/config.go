@@ -0,0 +1,894 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/access"
+	"github.com/DylanJ/stats/digest"
+	"github.com/DylanJ/stats/influx"
+	"github.com/DylanJ/stats/locale"
+	"github.com/DylanJ/stats/statsbot"
+)
+
+const defaultSaveInterval = 5 * time.Minute
+
+// config is the json configuration file of the irc client, for example:
+//
+//	{
+//	  "save_interval": "5m",
+//	  "min_save_interval": "30s",
+//	  "timezone": "Europe/Berlin",
+//	  "charset": "cp1252",
+//	  "networks": [{
+//	    "name": "freenode",
+//	    "server": "chat.freenode.net:6697",
+//	    "tls": true,
+//	    "nick": "statsbot",
+//	    "sasl": {"user": "statsbot", "password": "hunter2"},
+//	    "channels": ["#go-nuts"],
+//	    "relay_bots": ["discordbot"],
+//	    "timezone": "America/Toronto",
+//	    "features": {"#go-nuts": {"quotes": false, "words": false}},
+//	    "announce": {"summary": true, "records": true, "karma": true, "timezone": "America/Toronto"}
+//	  }],
+//	  "processors": ["bridge=discordbot,matrixbot"],
+//	  "raw_store": "raw.jsonl",
+//	  "retention": "2160h",
+//	  "retentions": {"urls": "8760h", "aggregates": "forever"},
+//	  "influx": {"url": "http://localhost:8086/write?db=ircstats", "interval": "1m"},
+//	  "elasticsearch": {"url": "http://localhost:9200", "index": "ircstats-{2006.01}"}
+//	}
+//
+// A collector forwards what it sees to an aggregation server with
+//
+//	"forward": {"url": "http://stats.zqz.ca:7070/", "token": "hunter2"}
+//
+// and the server, which needs no networks of its own, accepts the collectors
+// with the tokens it is given:
+//
+//	"aggregate": {"listen": ":7070", "collectors": {"hunter2": "shell-box"}}
+//
+// Collectors may publish to NATS instead, on a subject of their own under the
+// one the server subscribes to:
+//
+//	"forward": {"url": "nats://hunter2@nats.zqz.ca:4222/ircstats.events.shell-box"}
+//	"aggregate": {"nats": {"url": "nats://hunter2@nats.zqz.ca:4222", "subject": "ircstats.events"}}
+//
+// Weekly digests of the channels of a network are posted to a Matrix room or
+// a Discord webhook with
+//
+//	"digests": [{
+//	  "network": "freenode",
+//	  "channels": ["#go-nuts"],
+//	  "timezone": "America/Toronto",
+//	  "matrix": {"homeserver": "https://matrix.org", "access_token": "hunter2", "room": "!abc:matrix.org"},
+//	  "discord_webhook": "https://discord.com/api/webhooks/1/hunter2"
+//	}]
+//
+// The metrics of the stats are served as expvars on /debug/vars, along with
+// the profiles of net/http/pprof on /debug/pprof/, with
+//
+//	"debug_listen": "localhost:6060"
+//
+// The report and exports of the stats are served, see ircstats serve -help,
+// with
+//
+//	"listen": ":8080"
+//
+// The stats of the private channels of a network are only served to the
+// api_tokens allowed them, and none are served without a token when
+// require_token:
+//
+//	"api_tokens": {"hunter2": ["freenode #staff"], "s3cret": ["*"]},
+//	"networks": [{"name": "freenode", "private": ["#staff"], ...}]
+//
+// The report and digests are written in English unless a locale is set, one
+// of those built in or of the json catalogs given, see locale.Load:
+//
+//	"locale": "pt-BR",
+//	"catalogs": ["pt-BR.json"]
+//
+// The users of the channels with the pseudonyms feature are counted by
+// pseudonyms of their nicks, made with a secret key, rather than their nicks
+// and hostmasks:
+//
+//	"pseudonym_key": "hunter2",
+//	"networks": [{"features": {"#support": {"pseudonyms": true}}, ...}]
+//
+// A hosted bot keeps the stats of unrelated communities apart by owner, each
+// in their own database of the namespaces_dir with their own raw store,
+// retention and timezone. The networks of an owner are counted in their
+// stats only, and their report is served under /o/<owner>/:
+//
+//	"namespaces_dir": "/var/lib/ircstats",
+//	"owners": {"acme": {"raw_store": "acme.jsonl", "retention": "720h"}},
+//	"networks": [{"name": "acme", "owner": "acme", ...}]
+//
+// Kafka has no client built in, pipe a consumer such as kcat into the ingest
+// command instead.
+type config struct {
+	SaveInterval string          `json:"save_interval"`
+	Networks     []networkConfig `json:"networks"`
+	// MinSaveInterval is the least time between two saves, the saves asked
+	// for sooner are coalesced.
+	MinSaveInterval string `json:"min_save_interval"`
+	// LogLevel is the least level logged, debug, info, warn or error, info
+	// when empty. Saves and the messages turned away are logged at debug.
+	LogLevel string `json:"log_level"`
+	// RawStore is a file keeping every message as a json line, so the stats
+	// can be counted again with ircstats rebuild.
+	RawStore string `json:"raw_store"`
+	// Retention is how long messages are kept in the raw store and by id,
+	// forever when empty. The counters keep counting them.
+	Retention string `json:"retention"`
+	// Retentions keep the urls, quotes and aggregates for retentions of
+	// their own.
+	Retentions retentionsConfig `json:"retentions"`
+	// MaxFutureSkew is how far past now a message may be dated, those
+	// dated later by broken clocks are rejected, or dated now when
+	// clamp_dates. Dates are trusted when empty.
+	MaxFutureSkew string `json:"max_future_skew"`
+	ClampDates    bool   `json:"clamp_dates"`
+	// MaxMessageLength is the longest text of a message kept, in bytes,
+	// 4096 when 0 and not cut when negative.
+	MaxMessageLength int `json:"max_message_length"`
+	// CountSelf counts the lines the bot says itself, its replies and
+	// announcements.
+	CountSelf bool `json:"count_self"`
+	// CommandInterval lets each user run a command every interval, after
+	// a burst of CommandBurst (3 when 0). Commands are unlimited when
+	// empty.
+	CommandInterval string `json:"command_interval"`
+	CommandBurst    int    `json:"command_burst"`
+	// AggregateOnly counts the messages without keeping their text, so no
+	// raw store nor anything else logging them may be configured.
+	AggregateOnly bool `json:"aggregate_only"`
+	// PseudonymKey is the secret the pseudonyms of the nicks of the
+	// channels with the pseudonyms feature are made with, keep it to link
+	// the users across rebuilds. See stats.Options.PseudonymKey.
+	PseudonymKey string `json:"pseudonym_key"`
+	// ChannelAliases count the messages of a channel in another, keyed and
+	// valued by network and channel apart by a space, such as
+	// {"oftc #chan-bridge": "libera #chan"}. See
+	// stats.Options.ChannelAliases.
+	ChannelAliases map[string]string `json:"channel_aliases"`
+	// Processors are run on every message, see stats.NewProcessor.
+	Processors []string         `json:"processors"`
+	Influx     *influxConfig    `json:"influx"`
+	Elastic    *elasticConfig   `json:"elasticsearch"`
+	Forward    *forwardConfig   `json:"forward"`
+	Aggregate  *aggregateConfig `json:"aggregate"`
+	Digests    []digestConfig   `json:"digests"`
+	// Listen is the address serving the report and exports of the stats,
+	// see ircstats serve -help.
+	Listen string `json:"listen"`
+	// APITokens are the tokens the served stats are read with, as bearer
+	// tokens or ?token=, and the private channels each may read: "network
+	// #channel", "network *" for those of a network or "*" for all of them.
+	// RequireToken serves nothing to the requests without a token.
+	APITokens    map[string][]string `json:"api_tokens"`
+	RequireToken bool                `json:"require_token"`
+	// DebugListen is the address serving the metrics and profiles, keep it
+	// off public interfaces.
+	DebugListen string `json:"debug_listen"`
+	// Timezone is the timezone the hours and days of the stats are read
+	// in, UTC when empty.
+	Timezone string `json:"timezone"`
+	// Locale is the locale of the report and digests, English when empty.
+	// Catalogs are json files of other locales, see locale.Load.
+	Locale   string   `json:"locale"`
+	Catalogs []string `json:"catalogs"`
+	// Charset is the charset of the messages that aren't UTF-8, such as
+	// latin1 or cp1252. Their text that isn't UTF-8 is dropped when empty.
+	Charset string `json:"charset"`
+	// Owners are the namespaces of the stats kept apart, keyed by their
+	// owner, see stats.Namespaces. Their databases are in NamespacesDir,
+	// "owners" when empty.
+	Owners        map[string]ownerConfig `json:"owners"`
+	NamespacesDir string                 `json:"namespaces_dir"`
+}
+
+// defaultNamespacesDir is the directory of the databases of the owners
+// unless another is given.
+const defaultNamespacesDir = "owners"
+
+// ownerConfig is what an owner's stats are kept with, in place of the
+// raw_store, retention and timezone of the whole configuration. Nothing is
+// shared with the rest of the stats, so an owner has no raw store unless
+// they're given one.
+type ownerConfig struct {
+	RawStore  string `json:"raw_store"`
+	Retention string `json:"retention"`
+	Timezone  string `json:"timezone"`
+}
+
+// retentionsConfig are how long the urls, quotes and aggregates are kept,
+// durations or "forever", the retention when empty, eg.
+//
+//	"retention": "720h",
+//	"retentions": {"urls": "8760h", "aggregates": "forever"}
+//
+// keeps the text of the messages for 30 days, the urls for a year and the
+// counters of the users and days forever. See stats.Retentions.
+type retentionsConfig struct {
+	URLs       string `json:"urls"`
+	Quotes     string `json:"quotes"`
+	Aggregates string `json:"aggregates"`
+}
+
+// forever is the retention of what's never pruned.
+const forever = "forever"
+
+// retentions are the retentions of the categories.
+func (c retentionsConfig) retentions() (stats.Retentions, error) {
+	parse := func(name, retention string) (time.Duration, error) {
+		switch retention {
+		case "":
+			return 0, nil
+		case forever:
+			return -1, nil
+		}
+		d, err := time.ParseDuration(retention)
+		if err == nil && d <= 0 {
+			err = fmt.Errorf("%s must be positive", retention)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", name, err)
+		}
+		return d, nil
+	}
+
+	var r stats.Retentions
+	var err error
+	if r.URLs, err = parse("urls", c.URLs); err != nil {
+		return r, err
+	}
+	if r.Quotes, err = parse("quotes", c.Quotes); err != nil {
+		return r, err
+	}
+	r.Aggregates, err = parse("aggregates", c.Aggregates)
+	return r, err
+}
+
+// any checks if a category has a retention of its own, not forever.
+func (c retentionsConfig) any() bool {
+	r, _ := c.retentions()
+	return r.URLs > 0 || r.Quotes > 0 || r.Aggregates > 0
+}
+
+// influxConfig pushes the counters to an InfluxDB or VictoriaMetrics write
+// endpoint when set.
+type influxConfig struct {
+	URL      string `json:"url"`
+	Token    string `json:"token"`
+	Interval string `json:"interval"`
+}
+
+// elasticConfig indexes every message into Elasticsearch when set.
+type elasticConfig struct {
+	URL      string `json:"url"`
+	Index    string `json:"index"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type networkConfig struct {
+	Name     string     `json:"name"`
+	Server   string     `json:"server"`
+	TLS      bool       `json:"tls"`
+	Insecure bool       `json:"insecure"`
+	Password string     `json:"password"`
+	Nick     string     `json:"nick"`
+	User     string     `json:"user"`
+	Realname string     `json:"realname"`
+	SASL     *saslLogin `json:"sasl"`
+	Channels []string   `json:"channels"`
+	// RelayBots are the nicks of the bots relaying messages from other
+	// chats, their <nick> message lines are credited to the nick.
+	RelayBots []string `json:"relay_bots"`
+	// Announce, when set, announces daily summaries, record days and karma
+	// in the channels of the network.
+	Announce *announceConfig `json:"announce"`
+	// Timezone is the timezone the network's hours and days are read in,
+	// when it isn't the one of the whole configuration.
+	Timezone string `json:"timezone"`
+	// Charset is the charset of the network's messages that aren't UTF-8,
+	// when it isn't the one of the whole configuration.
+	Charset string `json:"charset"`
+	// CaseMapping is how the network tells nicks apart, the CASEMAPPING
+	// its servers announce: ascii, rfc1459 or strict-rfc1459. The case of
+	// every letter is folded when empty.
+	CaseMapping string `json:"case_mapping"`
+	// Features turn off what's expensive to count or keep of the channels
+	// named, such as the quotes and word counters of a channel of
+	// thousands.
+	Features map[string]featureConfig `json:"features"`
+	// Owner is the owner whose stats the network is counted in, one of
+	// the owners of the configuration. It's counted in the stats of
+	// data.db when empty.
+	Owner string `json:"owner"`
+	// Private are the channels whose stats are only served to the
+	// api_tokens allowed them, every channel of the network for "*".
+	Private []string `json:"private"`
+}
+
+// featureConfig turns off the features of a channel set to false, those left
+// out stay on, see stats.Features. Pseudonyms turns on counting the users of
+// the channel by pseudonyms of their nicks.
+type featureConfig struct {
+	Quotes     *bool `json:"quotes"`
+	Words      *bool `json:"words"`
+	URLs       *bool `json:"urls"`
+	Storage    *bool `json:"storage"`
+	Pseudonyms bool  `json:"pseudonyms"`
+}
+
+// features are the features of the channel.
+func (f featureConfig) features() stats.Features {
+	off := func(on *bool) bool { return on != nil && !*on }
+	return stats.Features{
+		NoQuotes:   off(f.Quotes),
+		NoWords:    off(f.Words),
+		NoURLs:     off(f.URLs),
+		NoStorage:  off(f.Storage),
+		Pseudonyms: f.Pseudonyms,
+	}
+}
+
+type announceConfig struct {
+	Summary  bool     `json:"summary"`
+	Records  bool     `json:"records"`
+	Karma    bool     `json:"karma"`
+	Channels []string `json:"channels"`
+	// Timezone is the timezone whose midnight ends the day of the summaries.
+	Timezone string `json:"timezone"`
+}
+
+// digestConfig posts the weekly digests of a network to Matrix, Discord or
+// both.
+type digestConfig struct {
+	Network  string   `json:"network"`
+	Channels []string `json:"channels"`
+	// Timezone is the timezone whose midnight starts the week.
+	Timezone       string        `json:"timezone"`
+	Matrix         *matrixConfig `json:"matrix"`
+	DiscordWebhook string        `json:"discord_webhook"`
+}
+
+type matrixConfig struct {
+	Homeserver  string `json:"homeserver"`
+	AccessToken string `json:"access_token"`
+	Room        string `json:"room"`
+}
+
+// forwardConfig sends every message to an aggregation server when set.
+type forwardConfig struct {
+	URL   string `json:"url"`
+	Token string `json:"token"`
+}
+
+// aggregateConfig runs an aggregation server when set. Collectors maps the
+// token of each collector to its name.
+type aggregateConfig struct {
+	Listen     string            `json:"listen"`
+	Collectors map[string]string `json:"collectors"`
+	// NATS, when set, also counts the events published on NATS.
+	NATS *natsConfig `json:"nats"`
+}
+
+type natsConfig struct {
+	URL     string `json:"url"`
+	Subject string `json:"subject"`
+	Queue   string `json:"queue"`
+}
+
+type saslLogin struct {
+	User     string `json:"user"`
+	Password string `json:"password"`
+}
+
+// loadConfig reads and validates a configuration file.
+func loadConfig(filename string) (*config, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var c config
+	if err = json.NewDecoder(f).Decode(&c); err != nil {
+		return nil, fmt.Errorf("Failed to parse %s: %v", filename, err)
+	}
+
+	return &c, c.validate()
+}
+
+func (c *config) validate() error {
+	if len(c.Networks) == 0 && c.Aggregate == nil {
+		return errors.New("Must configure at least one network.")
+	}
+
+	// the networks are told apart by their names, such as for their owners
+	names := make(map[string]int, len(c.Networks))
+	for i := range c.Networks {
+		n := &c.Networks[i]
+		if len(n.Name) == 0 || len(n.Server) == 0 || len(n.Nick) == 0 {
+			return fmt.Errorf("Network %d must have a name, server and nick.", i)
+		}
+		if j, ok := names[strings.ToLower(n.Name)]; ok {
+			return fmt.Errorf("Network %d has the name %s of network %d.", i, n.Name, j)
+		}
+		names[strings.ToLower(n.Name)] = i
+		if len(n.User) == 0 {
+			n.User = n.Nick
+		}
+		if len(n.Realname) == 0 {
+			n.Realname = n.Nick
+		}
+		if n.Announce != nil {
+			if _, err := n.Announce.location(); err != nil {
+				return fmt.Errorf("Network %d has a bad announce timezone: %v", i, err)
+			}
+		}
+		if _, ok := c.Owners[n.Owner]; len(n.Owner) > 0 && !ok {
+			return fmt.Errorf("Network %d has the unknown owner %s.", i, n.Owner)
+		}
+		for channel, f := range n.Features {
+			if f.Pseudonyms && len(c.PseudonymKey) == 0 {
+				return fmt.Errorf("Network %d needs a pseudonym_key for the pseudonyms of %s.", i, channel)
+			}
+		}
+		for _, channel := range n.Private {
+			if len(channel) == 0 || strings.ContainsAny(channel, " \t") {
+				return fmt.Errorf("Network %d has the bad private channel %q.", i, channel)
+			}
+		}
+	}
+
+	for token, scopes := range c.APITokens {
+		if len(token) == 0 {
+			return errors.New("Bad api_tokens: a token can't be empty.")
+		}
+		for _, scope := range scopes {
+			if fields := strings.Fields(scope); scope != access.All && len(fields) != 2 {
+				return fmt.Errorf("Bad api_tokens: %q must be a network and channel, or *.", scope)
+			}
+		}
+	}
+	if c.RequireToken && len(c.APITokens) == 0 {
+		return errors.New("Can't require_token without api_tokens.")
+	}
+
+	for owner, o := range c.Owners {
+		if err := stats.ValidOwner(owner); err != nil {
+			return err
+		}
+		if _, err := o.retention(); err != nil {
+			return fmt.Errorf("Owner %s has a bad retention: %v", owner, err)
+		}
+		if _, err := loadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("Owner %s has a bad timezone: %v", owner, err)
+		}
+		if c.AggregateOnly && len(o.RawStore) > 0 {
+			return fmt.Errorf("Owner %s can't keep a raw_store when aggregate_only.", owner)
+		}
+	}
+
+	if _, err := c.saveInterval(); err != nil {
+		return fmt.Errorf("Bad save_interval: %v", err)
+	}
+
+	if _, err := c.minSaveInterval(); err != nil {
+		return fmt.Errorf("Bad min_save_interval: %v", err)
+	}
+
+	if _, err := c.logLevel(); err != nil {
+		return fmt.Errorf("Bad log_level: %v", err)
+	}
+
+	if _, err := c.retention(); err != nil {
+		return fmt.Errorf("Bad retention: %v", err)
+	}
+
+	if _, err := c.Retentions.retentions(); err != nil {
+		return fmt.Errorf("Bad retentions: %v", err)
+	}
+
+	if _, err := c.maxFutureSkew(); err != nil {
+		return fmt.Errorf("Bad max_future_skew: %v", err)
+	}
+
+	if _, err := c.commandLimiter(); err != nil {
+		return fmt.Errorf("Bad command_interval: %v", err)
+	}
+
+	if _, _, err := c.locations(); err != nil {
+		return fmt.Errorf("Bad timezone: %v", err)
+	}
+
+	if _, err := c.locale(); err != nil {
+		return fmt.Errorf("Bad locale: %v", err)
+	}
+
+	if _, _, err := c.charsets(); err != nil {
+		return fmt.Errorf("Bad charset: %v", err)
+	}
+
+	if _, err := c.caseMappings(); err != nil {
+		return fmt.Errorf("Bad case_mapping: %v", err)
+	}
+
+	for alias, target := range c.ChannelAliases {
+		if len(strings.Fields(alias)) != 2 || len(strings.Fields(target)) != 2 {
+			return fmt.Errorf("Bad channel_aliases: %q and %q must each be a network and channel.", alias, target)
+		}
+	}
+
+	if c.AggregateOnly && (len(c.RawStore) > 0 || c.Elastic != nil || c.Forward != nil) {
+		return errors.New("Can't keep a raw_store, index into elasticsearch or forward messages when aggregate_only.")
+	}
+
+	if _, err := c.newProcessors(); err != nil {
+		return err
+	}
+
+	if c.Influx != nil {
+		if len(c.Influx.URL) == 0 {
+			return errors.New("Influx must have a url.")
+		}
+		if _, err := c.Influx.interval(); err != nil {
+			return fmt.Errorf("Bad influx interval: %v", err)
+		}
+	}
+
+	if c.Elastic != nil && len(c.Elastic.URL) == 0 {
+		return errors.New("Elasticsearch must have a url.")
+	}
+
+	if f := c.Forward; f != nil && (len(f.URL) == 0 || len(f.Token) == 0 && !strings.HasPrefix(f.URL, "nats://")) {
+		return errors.New("Forward must have a url and token.")
+	}
+
+	if a := c.Aggregate; a != nil {
+		if a.NATS != nil && !strings.HasPrefix(a.NATS.URL, "nats://") {
+			return errors.New("Aggregate nats must have a nats:// url.")
+		}
+		if (len(a.Listen) > 0 || a.NATS == nil) && (len(a.Listen) == 0 || len(a.Collectors) == 0) {
+			return errors.New("Aggregate must have a listen address and collectors.")
+		}
+	}
+
+	for i, d := range c.Digests {
+		if len(d.Network) == 0 {
+			return fmt.Errorf("Digest %d must have a network.", i)
+		}
+		if d.Matrix == nil && len(d.DiscordWebhook) == 0 {
+			return fmt.Errorf("Digest %d must have a matrix room or discord webhook.", i)
+		}
+		if m := d.Matrix; m != nil && (len(m.Homeserver) == 0 || len(m.AccessToken) == 0 || len(m.Room) == 0) {
+			return fmt.Errorf("Digest %d must have a matrix homeserver, access_token and room.", i)
+		}
+		if _, err := d.location(); err != nil {
+			return fmt.Errorf("Digest %d has a bad timezone: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// options are the options of the stats, but for their raw store and sinks.
+// The configuration must be valid.
+func (c *config) options() stats.Options {
+	opts := stats.Options{}
+	opts.Processors, _ = c.newProcessors()
+	opts.Retention, _ = c.retention()
+	opts.Retentions, _ = c.Retentions.retentions()
+	opts.MaxFutureSkew, _ = c.maxFutureSkew()
+	opts.ClampDates = c.ClampDates
+	opts.MaxMessageLength = c.MaxMessageLength
+	opts.MinSaveInterval, _ = c.minSaveInterval()
+	opts.Location, opts.Locations, _ = c.locations()
+	opts.Charset, opts.Charsets, _ = c.charsets()
+	opts.CaseMappings, _ = c.caseMappings()
+	opts.ChannelFeatures = c.channelFeatures()
+	opts.ChannelAliases = c.ChannelAliases
+	if len(c.PseudonymKey) > 0 {
+		opts.PseudonymKey = []byte(c.PseudonymKey)
+	}
+	opts.AggregateOnly = c.AggregateOnly
+	return opts
+}
+
+// ownerOptions are the options of an owner's stats: those of the
+// configuration with the owner's retention and timezone, but for their raw
+// store. The configuration must be valid.
+func (c *config) ownerOptions(owner string) stats.Options {
+	opts := c.options()
+	o := c.Owners[owner]
+	opts.Retention, _ = o.retention()
+	if loc, _ := loadLocation(o.Timezone); loc != nil {
+		opts.Location = loc
+	}
+	return opts
+}
+
+// networkOwner is the owner of the network named, empty for the networks
+// counted in data.db.
+func (c *config) networkOwner(network string) string {
+	for _, n := range c.Networks {
+		if strings.EqualFold(n.Name, network) {
+			return n.Owner
+		}
+	}
+	return ""
+}
+
+// namespaces keeps the stats of the owners in the namespaces directory.
+func (c *config) namespaces() *stats.Namespaces {
+	dir := c.NamespacesDir
+	if len(dir) == 0 {
+		dir = defaultNamespacesDir
+	}
+	ns := stats.NewNamespaces(dir)
+	ns.Options = c.ownerOptions
+	return ns
+}
+
+func (o ownerConfig) retention() (time.Duration, error) {
+	if len(o.Retention) == 0 {
+		return 0, nil
+	}
+
+	return time.ParseDuration(o.Retention)
+}
+
+func (c *config) saveInterval() (time.Duration, error) {
+	if len(c.SaveInterval) == 0 {
+		return defaultSaveInterval, nil
+	}
+
+	return time.ParseDuration(c.SaveInterval)
+}
+
+// defaultCommandBurst is how many commands a user runs at once when the
+// commands are limited.
+const defaultCommandBurst = 3
+
+// commandLimiter limits the commands of each user, it's nil when they're
+// unlimited.
+func (c *config) commandLimiter() (*statsbot.RateLimiter, error) {
+	if len(c.CommandInterval) == 0 {
+		return nil, nil
+	}
+
+	every, err := time.ParseDuration(c.CommandInterval)
+	if err != nil {
+		return nil, err
+	}
+	burst := c.CommandBurst
+	if burst <= 0 {
+		burst = defaultCommandBurst
+	}
+	return statsbot.NewRateLimiter(burst, every), nil
+}
+
+func (c *config) logLevel() (slog.Level, error) {
+	var level slog.Level
+	if len(c.LogLevel) == 0 {
+		return level, nil
+	}
+
+	err := level.UnmarshalText([]byte(c.LogLevel))
+	return level, err
+}
+
+// logger logs to standard error from the log_level on.
+func (c *config) logger() *slog.Logger {
+	level, _ := c.logLevel()
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+}
+
+func (c *config) minSaveInterval() (time.Duration, error) {
+	if len(c.MinSaveInterval) == 0 {
+		return 0, nil
+	}
+
+	return time.ParseDuration(c.MinSaveInterval)
+}
+
+func (c *config) retention() (time.Duration, error) {
+	if len(c.Retention) == 0 {
+		return 0, nil
+	}
+
+	return time.ParseDuration(c.Retention)
+}
+
+func (c *config) maxFutureSkew() (time.Duration, error) {
+	if len(c.MaxFutureSkew) == 0 {
+		return 0, nil
+	}
+
+	return time.ParseDuration(c.MaxFutureSkew)
+}
+
+func (c *influxConfig) interval() (time.Duration, error) {
+	if len(c.Interval) == 0 {
+		return influx.DefaultInterval, nil
+	}
+
+	return time.ParseDuration(c.Interval)
+}
+
+// location is nil without a timezone, announcements then end the days the
+// stats are read in.
+func (c *announceConfig) location() (*time.Location, error) {
+	return loadLocation(c.Timezone)
+}
+
+// location is nil without a timezone, digests then start the weeks the stats
+// are read in.
+func (c *digestConfig) location() (*time.Location, error) {
+	return loadLocation(c.Timezone)
+}
+
+// locations are the timezones the stats read the days of the networks in.
+func (c *config) locations() (*time.Location, map[string]*time.Location, error) {
+	loc, err := loadLocation(c.Timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	locs := make(map[string]*time.Location)
+	for _, n := range c.Networks {
+		nloc, err := loadLocation(n.Timezone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", n.Name, err)
+		}
+		if nloc != nil {
+			locs[n.Name] = nloc
+		}
+	}
+	return loc, locs, nil
+}
+
+// charsets are the charsets the stats decode the messages of the networks
+// that aren't UTF-8 from.
+func (c *config) charsets() (*stats.Charset, map[string]*stats.Charset, error) {
+	charset, err := stats.LookupCharset(c.Charset)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	charsets := make(map[string]*stats.Charset)
+	for _, n := range c.Networks {
+		if len(n.Charset) == 0 {
+			continue
+		}
+		nc, err := stats.LookupCharset(n.Charset)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", n.Name, err)
+		}
+		charsets[n.Name] = nc
+	}
+	return charset, charsets, nil
+}
+
+// channelFeatures are the features of the channels of the networks, keyed as
+// stats.Options.ChannelFeatures.
+func (c *config) channelFeatures() map[string]stats.Features {
+	features := make(map[string]stats.Features)
+	for _, n := range c.Networks {
+		for channel, f := range n.Features {
+			features[n.Name+" "+channel] = f.features()
+		}
+	}
+	return features
+}
+
+// locale loads the catalogs and looks up the locale of the report and
+// digests.
+func (c *config) locale() (*locale.Locale, error) {
+	for _, path := range c.Catalogs {
+		l, err := locale.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		locale.Register(l)
+	}
+
+	l, ok := locale.Lookup(c.Locale)
+	if !ok {
+		return nil, fmt.Errorf("unknown locale %s, the locales are %s", c.Locale, strings.Join(locale.Names(), ", "))
+	}
+	return l, nil
+}
+
+// caseMappings are the case mappings of the networks that fold nicks as irc
+// does rather than by every letter.
+func (c *config) caseMappings() (map[string]stats.CaseMapping, error) {
+	mappings := make(map[string]stats.CaseMapping)
+	for _, n := range c.Networks {
+		if len(n.CaseMapping) == 0 {
+			continue
+		}
+		m, ok := stats.ParseCaseMapping(n.CaseMapping)
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown case mapping %s", n.Name, n.CaseMapping)
+		}
+		mappings[n.Name] = m
+	}
+	return mappings, nil
+}
+
+func loadLocation(timezone string) (*time.Location, error) {
+	if len(timezone) == 0 {
+		return nil, nil
+	}
+
+	return time.LoadLocation(timezone)
+}
+
+// newDigesters creates a digester for every place the digests of a network
+// are posted to, writing them in the locale.
+func (c *digestConfig) newDigesters(s *stats.Stats, l *locale.Locale) []*digest.Digester {
+	var posters []digest.Poster
+	if m := c.Matrix; m != nil {
+		posters = append(posters, &digest.MatrixRoom{Homeserver: m.Homeserver, AccessToken: m.AccessToken, Room: m.Room})
+	}
+	if len(c.DiscordWebhook) > 0 {
+		posters = append(posters, &digest.DiscordWebhook{URL: c.DiscordWebhook})
+	}
+
+	var digesters []*digest.Digester
+	for _, p := range posters {
+		d := digest.NewDigester(s, p, c.Network)
+		d.Channels = c.Channels
+		d.Location, _ = c.location()
+		d.Locale = l
+		digesters = append(digesters, d)
+	}
+	return digesters
+}
+
+// newProcessors creates the processors from their specs, after unwrapping
+// the messages of the relay bots of each network. The relay bots are known by
+// their pseudonyms too in the channels with pseudonyms.
+func (c *config) newProcessors() ([]stats.Processor, error) {
+	pseudonyms := stats.Options{PseudonymKey: []byte(c.PseudonymKey), CaseMappings: make(map[string]stats.CaseMapping)}
+	mappings, _ := c.caseMappings()
+	for network, m := range mappings {
+		pseudonyms.CaseMappings[strings.ToLower(network)] = m
+	}
+
+	var processors []stats.Processor
+	for _, n := range c.Networks {
+		if len(n.RelayBots) > 0 {
+			bots := n.RelayBots
+			if len(c.PseudonymKey) > 0 {
+				bots = append([]string(nil), bots...)
+				for _, bot := range n.RelayBots {
+					bots = append(bots, pseudonyms.Pseudonym(n.Name, bot))
+				}
+			}
+			b := stats.NewBridgeProcessor(bots...)
+			b.Network = n.Name
+			processors = append(processors, b)
+		}
+	}
+	for _, spec := range c.Processors {
+		p, err := stats.NewProcessor(spec)
+		if err != nil {
+			return nil, err
+		}
+		processors = append(processors, p)
+	}
+	return processors, nil
+}
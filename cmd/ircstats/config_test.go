@@ -0,0 +1,562 @@
+package main
+
+import (
+	"io/ioutil"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/locale"
+)
+
+func TestConfig_validate(t *testing.T) {
+	t.Parallel()
+
+	c := &config{}
+	if c.validate() == nil {
+		t.Error("Should require a network.")
+	}
+
+	c.Networks = []networkConfig{{Name: "net", Server: "localhost:6667"}}
+	if c.validate() == nil {
+		t.Error("Should require a nick.")
+	}
+
+	c.Networks[0].Nick = "bot"
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+
+	if c.Networks[0].User != "bot" || c.Networks[0].Realname != "bot" {
+		t.Error("Should default user and realname to the nick.")
+	}
+
+	if d, _ := c.saveInterval(); d != defaultSaveInterval {
+		t.Error("Should use the default save interval.")
+	}
+
+	c.SaveInterval = "1m"
+	if d, _ := c.saveInterval(); d != time.Minute {
+		t.Error("Should parse the save interval.")
+	}
+
+	c.SaveInterval = "soon"
+	if c.validate() == nil {
+		t.Error("Should reject bad save intervals.")
+	}
+	c.SaveInterval = ""
+
+	c.MinSaveInterval = "30s"
+	if d, _ := c.minSaveInterval(); d != 30*time.Second {
+		t.Error("Should parse the min save interval.")
+	}
+	c.MinSaveInterval = "often"
+	if c.validate() == nil {
+		t.Error("Should reject bad min save intervals.")
+	}
+	c.MinSaveInterval = ""
+
+	c.LogLevel = "DEBUG"
+	if l, _ := c.logLevel(); l != slog.LevelDebug {
+		t.Error("Should parse the log level.")
+	}
+	c.LogLevel = "chatty"
+	if c.validate() == nil {
+		t.Error("Should reject bad log levels.")
+	}
+	c.LogLevel = ""
+
+	if l, _ := c.commandLimiter(); l != nil {
+		t.Error("Should not limit commands by default.")
+	}
+	c.CommandInterval = "10s"
+	if l, _ := c.commandLimiter(); l == nil || l.Burst != 3 || l.Every != 10*time.Second {
+		t.Error("Should limit commands every interval:", l)
+	}
+	c.CommandInterval = "sometimes"
+	if c.validate() == nil {
+		t.Error("Should reject bad command intervals.")
+	}
+	c.CommandInterval = ""
+
+	c.MaxFutureSkew = "5m"
+	if d, _ := c.maxFutureSkew(); d != 5*time.Minute {
+		t.Error("Should parse the max future skew.")
+	}
+	c.MaxFutureSkew = "later"
+	if c.validate() == nil {
+		t.Error("Should reject bad max future skews.")
+	}
+	c.MaxFutureSkew = ""
+
+	c.ChannelAliases = map[string]string{"oftc #chan-bridge": "libera #chan"}
+	if err := c.validate(); err != nil {
+		t.Error("Should accept channel aliases:", err)
+	}
+	c.ChannelAliases = map[string]string{"oftc #chan-bridge": "#chan"}
+	if c.validate() == nil {
+		t.Error("Should reject channel aliases without a network.")
+	}
+	c.ChannelAliases = nil
+
+	if d, _ := c.retention(); d != 0 {
+		t.Error("Should keep messages forever by default.")
+	}
+
+	c.Retention = "720h"
+	if d, _ := c.retention(); d != 720*time.Hour {
+		t.Error("Should parse the retention.")
+	}
+
+	c.Retention = "a while"
+	if c.validate() == nil {
+		t.Error("Should reject bad retentions.")
+	}
+	c.Retention = ""
+
+	c.Retentions = retentionsConfig{URLs: "8760h", Aggregates: forever}
+	if err := c.validate(); err != nil {
+		t.Error("Should accept the retentions of the categories:", err)
+	}
+	if r := c.options().Retentions; r != (stats.Retentions{URLs: 8760 * time.Hour, Aggregates: -1}) {
+		t.Error("Should keep the categories for their retentions, forever for negative ones:", r)
+	}
+	for _, bad := range []retentionsConfig{{Quotes: "a while"}, {URLs: "-24h"}, {Aggregates: "0s"}} {
+		c.Retentions = bad
+		if c.validate() == nil {
+			t.Error("Should reject the bad retentions", bad)
+		}
+	}
+}
+
+func TestConfig_validateInflux(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks: []networkConfig{{Name: "net", Server: "localhost:6667", Nick: "bot"}},
+		Influx:   &influxConfig{},
+	}
+	if c.validate() == nil {
+		t.Error("Should require an influx url.")
+	}
+
+	c.Influx.URL = "http://localhost:8086/write?db=ircstats"
+	c.Influx.Interval = "later"
+	if c.validate() == nil {
+		t.Error("Should reject bad influx intervals.")
+	}
+
+	c.Influx.Interval = "30s"
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+	if d, _ := c.Influx.interval(); d != 30*time.Second {
+		t.Error("Should parse the influx interval.")
+	}
+}
+
+func TestConfig_validateElastic(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks: []networkConfig{{Name: "net", Server: "localhost:6667", Nick: "bot"}},
+		Elastic:  &elasticConfig{Index: "ircstats"},
+	}
+	if c.validate() == nil {
+		t.Error("Should require an elasticsearch url.")
+	}
+
+	c.Elastic.URL = "http://localhost:9200"
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+}
+
+func TestConfig_validateAggregateOnly(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks:      []networkConfig{{Name: "net", Server: "localhost:6667", Nick: "bot"}},
+		RawStore:      "raw.jsonl",
+		AggregateOnly: true,
+	}
+	if c.validate() == nil {
+		t.Error("Should not keep a raw store when aggregate only.")
+	}
+
+	c.RawStore = ""
+	c.Elastic = &elasticConfig{URL: "http://localhost:9200"}
+	if c.validate() == nil {
+		t.Error("Should not index messages when aggregate only.")
+	}
+
+	c.Elastic = nil
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+}
+
+func TestConfig_validateAggregate(t *testing.T) {
+	t.Parallel()
+
+	c := &config{Aggregate: &aggregateConfig{Listen: ":7070"}}
+	if c.validate() == nil {
+		t.Error("Should require collectors.")
+	}
+
+	c.Aggregate.Collectors = map[string]string{"hunter2": "shell"}
+	if err := c.validate(); err != nil {
+		t.Error("An aggregation server should not need networks:", err)
+	}
+
+	c.Forward = &forwardConfig{URL: "http://localhost:7070/"}
+	if c.validate() == nil {
+		t.Error("Should require a forward token.")
+	}
+
+	c.Forward.URL = "nats://localhost:4222/ircstats.events.shell"
+	if err := c.validate(); err != nil {
+		t.Error("Should not require a token for nats:", err)
+	}
+
+	c.Aggregate = &aggregateConfig{NATS: &natsConfig{URL: "localhost:4222"}}
+	if c.validate() == nil {
+		t.Error("Should require a nats url.")
+	}
+	c.Aggregate.NATS.URL = "nats://localhost:4222"
+	if err := c.validate(); err != nil {
+		t.Error("Should not need to listen when subscribed to nats:", err)
+	}
+}
+
+func TestConfig_validateAnnounce(t *testing.T) {
+	t.Parallel()
+
+	c := &config{Networks: []networkConfig{{
+		Name: "net", Server: "localhost:6667", Nick: "bot",
+		Announce: &announceConfig{Summary: true, Timezone: "Nowhere/Special"},
+	}}}
+	if c.validate() == nil {
+		t.Error("Should reject bad announce timezones.")
+	}
+
+	c.Networks[0].Announce.Timezone = "UTC"
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+}
+
+func TestConfig_validateProcessors(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks:   []networkConfig{{Name: "net", Server: "localhost:6667", Nick: "bot"}},
+		Processors: []string{"bridge"},
+	}
+	if c.validate() == nil {
+		t.Error("Should reject bad processors.")
+	}
+
+	c.Processors = []string{"bridge=discordbot"}
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+	if p, _ := c.newProcessors(); len(p) != 1 {
+		t.Error("Should create the processors.")
+	}
+
+	c.Networks[0].RelayBots = []string{"relay"}
+	p, _ := c.newProcessors()
+	if len(p) != 2 {
+		t.Fatal("Should add a processor for the relay bots.")
+	}
+	if b, ok := p[0].(*stats.BridgeProcessor); !ok || b.Network != "net" {
+		t.Error("The relay bots should be scoped to their network:", p[0])
+	}
+}
+
+func TestConfig_validateDigests(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks: []networkConfig{{Name: "net", Server: "localhost:6667", Nick: "bot"}},
+		Digests:  []digestConfig{{Network: "net"}},
+	}
+	if c.validate() == nil {
+		t.Error("Should require somewhere to post the digests.")
+	}
+
+	c.Digests[0].Matrix = &matrixConfig{Homeserver: "https://matrix.org"}
+	if c.validate() == nil {
+		t.Error("Should require a whole matrix room.")
+	}
+
+	c.Digests[0].Matrix.AccessToken, c.Digests[0].Matrix.Room = "hunter2", "!abc:matrix.org"
+	c.Digests[0].Timezone = "Nowhere/Special"
+	if c.validate() == nil {
+		t.Error("Should reject bad timezones.")
+	}
+
+	c.Digests[0].Timezone = "UTC"
+	c.Digests[0].DiscordWebhook = "https://discord.com/api/webhooks/1/hunter2"
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := c.Digests[0].newDigesters(s, locale.French); len(d) != 2 || d[0].Network != "net" || d[1].Location != time.UTC || d[1].Locale != locale.French {
+		t.Error("Should post to both matrix and discord:", d)
+	}
+}
+
+func TestConfig_locale(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ircstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &config{Networks: []networkConfig{{Name: "net", Server: "localhost:6667", Nick: "bot"}}}
+	if l, err := c.locale(); err != nil || l != locale.English {
+		t.Error("Should default to English:", l, err)
+	}
+
+	c.Locale = "FR"
+	if l, _ := c.locale(); l != locale.French {
+		t.Error("Should look up the locale:", l)
+	}
+
+	c.Locale = "x-ircstats-test"
+	if c.validate() == nil {
+		t.Error("Should reject unknown locales.")
+	}
+
+	catalog := filepath.Join(dir, "catalog.json")
+	if err := ioutil.WriteFile(catalog, []byte(`{"name": "x-ircstats-test", "messages": {"Lines": "Lignes"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c.Catalogs = []string{catalog}
+	if l, err := c.locale(); err != nil || l.Message("Lines") != "Lignes" {
+		t.Error("Should load the locale from its catalog:", l, err)
+	}
+
+	c.Catalogs = []string{filepath.Join(dir, "missing.json")}
+	if c.validate() == nil {
+		t.Error("Should reject missing catalogs.")
+	}
+}
+
+func TestConfig_locations(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks: []networkConfig{
+			{Name: "net", Server: "localhost:6667", Nick: "bot"},
+			{Name: "other", Server: "localhost:6667", Nick: "bot", Timezone: "UTC"},
+		},
+	}
+	if loc, locs, err := c.locations(); err != nil || loc != nil || len(locs) != 1 || locs["other"] != time.UTC {
+		t.Error("Should only set the timezones configured:", loc, locs, err)
+	}
+
+	c.Timezone = "UTC"
+	if loc, _, _ := c.locations(); loc != time.UTC {
+		t.Error("Should load the timezone of the configuration:", loc)
+	}
+
+	c.Networks[0].Timezone = "Nowhere/Special"
+	if c.validate() == nil {
+		t.Error("Should reject bad timezones of networks.")
+	}
+	c.Networks[0].Timezone = ""
+
+	c.Timezone = "Nowhere/Special"
+	if c.validate() == nil {
+		t.Error("Should reject bad timezones.")
+	}
+}
+
+func TestConfig_charsets(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Charset: "latin1",
+		Networks: []networkConfig{
+			{Name: "net", Server: "localhost:6667", Nick: "bot"},
+			{Name: "other", Server: "localhost:6667", Nick: "bot", Charset: "cp1252"},
+		},
+	}
+	if charset, charsets, err := c.charsets(); err != nil || charset != stats.Latin1 || len(charsets) != 1 || charsets["other"] != stats.CP1252 {
+		t.Error("Should look up the charsets configured:", charset, charsets, err)
+	}
+
+	c.Networks[0].Charset = "klingon"
+	if c.validate() == nil {
+		t.Error("Should reject bad charsets of networks.")
+	}
+	c.Networks[0].Charset = ""
+
+	c.Charset = "klingon"
+	if c.validate() == nil {
+		t.Error("Should reject bad charsets.")
+	}
+}
+
+func TestConfig_caseMappings(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks: []networkConfig{
+			{Name: "net", Server: "localhost:6667", Nick: "bot"},
+			{Name: "other", Server: "localhost:6667", Nick: "bot", CaseMapping: "rfc1459"},
+		},
+	}
+	if mappings, err := c.caseMappings(); err != nil || len(mappings) != 1 || mappings["other"] != stats.RFC1459Case {
+		t.Error("Should look up the case mappings configured:", mappings, err)
+	}
+
+	c.Networks[0].CaseMapping = "klingon"
+	if c.validate() == nil {
+		t.Error("Should reject bad case mappings.")
+	}
+}
+
+func TestConfig_options(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Retention:        "24h",
+		MaxMessageLength: 100,
+		Charset:          "latin1",
+		Processors:       []string{"bridge=relay"},
+		Networks: []networkConfig{
+			{Name: "net", Server: "localhost:6667", Nick: "bot", CaseMapping: "ascii"},
+		},
+	}
+	off, on := false, true
+	c.Networks[0].Features = map[string]featureConfig{"#big": {Quotes: &off, Words: &on}}
+	opts := c.options()
+	if opts.Retention != 24*time.Hour || opts.MaxMessageLength != 100 || opts.Charset != stats.Latin1 {
+		t.Error("Should set the options configured:", opts)
+	}
+	if len(opts.Processors) != 1 || opts.CaseMappings["net"] != stats.ASCIICase {
+		t.Error("Should set the processors and case mappings:", opts.Processors, opts.CaseMappings)
+	}
+	if f := opts.ChannelFeatures["net #big"]; f != (stats.Features{NoQuotes: true}) {
+		t.Error("Should turn off the features set to false only:", f)
+	}
+}
+
+func TestConfig_validateOwners(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Retention: "24h",
+		Networks:  []networkConfig{{Name: "acme", Server: "localhost:6667", Nick: "bot", Owner: "acme"}},
+	}
+	if c.validate() == nil {
+		t.Error("Should reject networks of unknown owners.")
+	}
+
+	c.Owners = map[string]ownerConfig{"acme": {Retention: "1h", Timezone: "Europe/Berlin"}}
+	if err := c.validate(); err != nil {
+		t.Error("Should be valid:", err)
+	}
+	opts := c.ownerOptions("acme")
+	if opts.Retention != time.Hour || opts.Location == nil || opts.Location.String() != "Europe/Berlin" {
+		t.Error("Should keep the owner's stats with their own retention and timezone:", opts.Retention, opts.Location)
+	}
+	if c.networkOwner("ACME") != "acme" || c.networkOwner("other") != "" {
+		t.Error("Should find the owner of a network.")
+	}
+	c.Networks = append(c.Networks, networkConfig{Name: "ACME", Server: "localhost:6668", Nick: "bot"})
+	if c.validate() == nil {
+		t.Error("Should reject networks of the same name, which would be counted for the first's owner.")
+	}
+	c.Networks = c.Networks[:1]
+	if ns := c.namespaces(); ns.Path("acme") != filepath.Join(defaultNamespacesDir, "acme.db") {
+		t.Error("Should keep the owners' databases in the default directory:", ns.Path("acme"))
+	}
+
+	for _, bad := range []ownerConfig{{Retention: "forever"}, {Timezone: "Mars/Olympus"}} {
+		c.Owners["acme"] = bad
+		if c.validate() == nil {
+			t.Error("Should reject the bad owner:", bad)
+		}
+	}
+
+	c.Owners = map[string]ownerConfig{"Not Valid": {}}
+	c.Networks[0].Owner = ""
+	if c.validate() == nil {
+		t.Error("Should reject bad owner keys.")
+	}
+
+	c.Owners = map[string]ownerConfig{"acme": {RawStore: "acme.jsonl"}}
+	c.AggregateOnly = true
+	if c.validate() == nil {
+		t.Error("Should not keep an owner's raw store when aggregate_only.")
+	}
+}
+
+func TestConfig_pseudonyms(t *testing.T) {
+	t.Parallel()
+
+	c := &config{
+		Networks: []networkConfig{{
+			Name: "net", Server: "localhost:6667", Nick: "bot",
+			RelayBots: []string{"relay"},
+			Features:  map[string]featureConfig{"#support": {Pseudonyms: true}},
+		}},
+	}
+	if c.validate() == nil {
+		t.Error("Should require a pseudonym key for the channels with pseudonyms.")
+	}
+
+	c.PseudonymKey = "hunter2"
+	if err := c.validate(); err != nil {
+		t.Fatal("Should be valid:", err)
+	}
+	opts := c.options()
+	if string(opts.PseudonymKey) != "hunter2" || !opts.ChannelFeatures["net #support"].Pseudonyms {
+		t.Error("Should count the users of the channel by their pseudonyms:", opts.ChannelFeatures)
+	}
+
+	b, ok := opts.Processors[0].(*stats.BridgeProcessor)
+	if !ok {
+		t.Fatal("Should unwrap the messages of the relay bots:", opts.Processors)
+	}
+	if _, ok := b.Bots[opts.Pseudonym("net", "relay")]; !ok || len(b.Bots) != 2 {
+		t.Error("Should know the relay bots by their pseudonyms too:", b.Bots)
+	}
+}
+
+func TestConfig_validateAccess(t *testing.T) {
+	t.Parallel()
+
+	c := accessConfig()
+	if err := c.validate(); err != nil {
+		t.Error("Should accept the private channels and api tokens:", err)
+	}
+
+	bad := []func(c *config){
+		func(c *config) { c.Networks[0].Private = []string{""} },
+		func(c *config) { c.Networks[0].Private = []string{"#a #b"} },
+		func(c *config) { c.APITokens = map[string][]string{"": {"*"}} },
+		func(c *config) { c.APITokens = map[string][]string{"hunter2": {"#bots"}} },
+		func(c *config) { c.APITokens, c.RequireToken = nil, true },
+	}
+	for i, change := range bad {
+		c := accessConfig()
+		change(c)
+		if c.validate() == nil {
+			t.Error("Should reject the bad access", i)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/access"
+)
+
+var exportUsage = `
+export writes the stats of the users of every channel in data.db, one record
+per user of each channel, as json or csv. The records are sorted by network
+and channel, and by the lines of the users. Only the channels of a network, or
+a single channel, are exported when given as options.
+
+ircstats export [options]
+`
+
+// exportRow is the record of a user in a channel, as exported.
+type exportRow struct {
+	Network      string `json:"network"`
+	Channel      string `json:"channel"`
+	Nick         string `json:"nick"`
+	Lines        uint   `json:"lines"`
+	Words        uint   `json:"words"`
+	Letters      uint   `json:"letters"`
+	Questions    uint   `json:"questions"`
+	Exclamations uint   `json:"exclamations"`
+	AllCaps      uint   `json:"allcaps"`
+	Swears       uint   `json:"swears"`
+	Quote        string `json:"quote"`
+}
+
+// exportColumns are the columns of the csv export, in the order of the fields
+// of exportRow.
+var exportColumns = []string{"network", "channel", "nick", "lines", "words", "letters", "questions", "exclamations", "allcaps", "swears", "quote"}
+
+// export runs the export command with its arguments.
+func export(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "json", "The format of the export, json or csv.")
+	network := fs.String("network", "", "The network to export, every network when empty.")
+	channel := fs.String("channel", "", "The channel to export, every channel when empty.")
+	out := fs.String("out", "", "The file to write, standard out when empty.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s export:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, exportUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	var rows []exportRow
+	s.View(func(tx *stats.ReadTx) {
+		rows = exportRows(tx, *network, *channel, nil)
+	})
+
+	return writeFile(*out, func(w io.Writer) error {
+		return writeExport(w, *format, rows)
+	})
+}
+
+// writeFile calls write with the file at path, or with standard out when
+// path is empty.
+func writeFile(path string, write func(w io.Writer) error) error {
+	if len(path) == 0 {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// exportRows builds the records of the users of the channels, of every
+// network or channel when they're empty, those visible only. What's past its retention but not
+// pruned yet is left out: the users not seen since the cutoff of the
+// aggregates and the quotes said before theirs.
+func exportRows(tx *stats.ReadTx, network, channel string, visible access.Visibility) []exportRow {
+	cutoffs := tx.Cutoffs()
+	var rows []exportRow
+	for _, c := range channels(tx, network, channel) {
+		n := tx.Networks[c.NetworkID]
+		if !visible.Allows(n.Name, c.Name) {
+			continue
+		}
+		for _, u := range channelUsers(tx, c) {
+			if !u.LastSeen.IsZero() && !stats.Kept(cutoffs.Aggregates, u.LastSeen) {
+				continue
+			}
+			// users without a random quote yet are quoted by their last
+			quote := u.Quotes.Random
+			if quote.ID == 0 {
+				quote = u.Quotes.Last
+			}
+			if !stats.Kept(cutoffs.Quotes, quote.Date) {
+				quote = stats.Message{}
+			}
+			rows = append(rows, exportRow{
+				Network:      n.Name,
+				Channel:      c.Name,
+				Nick:         u.Nick,
+				Lines:        u.Lines,
+				Words:        u.Words,
+				Letters:      u.Letters,
+				Questions:    uint(u.QuestionsCount),
+				Exclamations: uint(u.ExclamationsCount),
+				AllCaps:      uint(u.AllCapsCount),
+				Swears:       u.SwearCounter.Count,
+				Quote:        quote.Message,
+			})
+		}
+	}
+	return rows
+}
+
+// channels lists the channels of every network, or of the network, by the
+// names of their networks and their own. Only the channel named is listed
+// when one is. Those that failed to load are left out.
+func channels(tx *stats.ReadTx, network, channel string) []*stats.Channel {
+	var list []*stats.Channel
+	for _, n := range tx.Networks {
+		if len(network) > 0 && !strings.EqualFold(n.Name, network) {
+			continue
+		}
+
+		for _, id := range n.ChannelIDs {
+			c := tx.Channel(id)
+			if c == nil || len(channel) > 0 && !strings.EqualFold(c.Name, channel) {
+				continue
+			}
+			list = append(list, c)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		ni, nj := tx.Networks[list[i].NetworkID].Name, tx.Networks[list[j].NetworkID].Name
+		if ni != nj {
+			return ni < nj
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// channelUser is a user as counted in a channel, with the nick the user has
+// now.
+type channelUser struct {
+	*stats.User
+	Nick string
+}
+
+// channelUsers lists the users of a channel as counted in it, by their lines
+// and then their nicks.
+func channelUsers(tx *stats.ReadTx, c *stats.Channel) []channelUser {
+	key := strings.ToLower(c.Name)
+	users := make([]channelUser, 0, len(c.UserIDs))
+	for id := range c.UserIDs {
+		u, ok := tx.Users[id]
+		if !ok {
+			continue
+		}
+		if cu, ok := u.ChannelUsers[key]; ok {
+			users = append(users, channelUser{cu, u.Nick})
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Lines != users[j].Lines {
+			return users[i].Lines > users[j].Lines
+		}
+		return users[i].Nick < users[j].Nick
+	})
+	return users
+}
+
+// writeExport writes the records in the format, as a json array or as csv
+// with a header.
+func writeExport(w io.Writer, format string, rows []exportRow) error {
+	switch format {
+	case "json":
+		if rows == nil {
+			rows = []exportRow{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(exportColumns)
+		for _, r := range rows {
+			cw.Write([]string{
+				r.Network, r.Channel, r.Nick,
+				strconv.FormatUint(uint64(r.Lines), 10),
+				strconv.FormatUint(uint64(r.Words), 10),
+				strconv.FormatUint(uint64(r.Letters), 10),
+				strconv.FormatUint(uint64(r.Questions), 10),
+				strconv.FormatUint(uint64(r.Exclamations), 10),
+				strconv.FormatUint(uint64(r.AllCaps), 10),
+				strconv.FormatUint(uint64(r.Swears), 10),
+				r.Quote,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	return fmt.Errorf("Unknown format %s, the formats are: json, csv", format)
+}
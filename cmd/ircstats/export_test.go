@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// exportStats are stats with two channels of a network and one of another.
+func exportStats(t *testing.T) *stats.Stats {
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now(), "hello there")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron!a@zqz.ca", time.Now(), "hi")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron!a@zqz.ca", time.Now(), "how are you?")
+	s.AddMessage(stats.Msg, "zkpq", "#bots", "dylan!d@zqz.ca", time.Now(), "!stats")
+	s.AddMessage(stats.Msg, "other", "#deviate", "scott!s@zqz.ca", time.Now(), "yo")
+	return s
+}
+
+func TestExportRows(t *testing.T) {
+	t.Parallel()
+
+	s := exportStats(t)
+	var rows []exportRow
+	s.View(func(tx *stats.ReadTx) {
+		rows = exportRows(tx, "", "", nil)
+	})
+
+	want := []struct {
+		network, channel, nick string
+		lines                  uint
+	}{
+		{"other", "#deviate", "scott", 1},
+		{"zkpq", "#bots", "dylan", 1},
+		{"zkpq", "#deviate", "aaron", 2},
+		{"zkpq", "#deviate", "dylan", 1},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("Should export %d rows, exported %d: %v", len(want), len(rows), rows)
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.Network != w.network || r.Channel != w.channel || r.Nick != w.nick || r.Lines != w.lines {
+			t.Errorf("Row %d should be %v, is %v", i, w, r)
+		}
+	}
+	if rows[2].Questions != 1 || rows[2].Words != 4 {
+		t.Error("Should export the counters of the user in the channel:", rows[2])
+	}
+
+	s.View(func(tx *stats.ReadTx) {
+		rows = exportRows(tx, "ZKPQ", "#DEVIATE", nil)
+	})
+	if len(rows) != 2 || rows[0].Nick != "aaron" {
+		t.Error("Should only export the channel asked for:", rows)
+	}
+}
+
+func TestExportRows_retentions(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now().AddDate(0, 0, -100), "hello there")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron!a@zqz.ca", time.Now(), "hi")
+
+	var rows []exportRow
+	s.SetOptions(stats.Options{Retentions: stats.Retentions{Quotes: 60 * 24 * time.Hour}})
+	s.View(func(tx *stats.ReadTx) {
+		rows = exportRows(tx, "", "", nil)
+	})
+	if len(rows) != 2 || rows[0].Quote != "hi" || rows[1].Nick != "dylan" || len(rows[1].Quote) != 0 {
+		t.Error("Should leave out the quotes past their retention:", rows)
+	}
+
+	s.SetOptions(stats.Options{Retentions: stats.Retentions{Aggregates: 60 * 24 * time.Hour}})
+	s.View(func(tx *stats.ReadTx) {
+		rows = exportRows(tx, "", "", nil)
+	})
+	if len(rows) != 1 || rows[0].Nick != "aaron" {
+		t.Error("Should leave out the users past the retention of the aggregates:", rows)
+	}
+}
+
+func TestWriteExport(t *testing.T) {
+	t.Parallel()
+
+	rows := []exportRow{
+		{Network: "zkpq", Channel: "#deviate", Nick: "dylan", Lines: 2, Words: 3, Quote: `said "hi", then left`},
+	}
+
+	var b bytes.Buffer
+	if err := writeExport(&b, "json", rows); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []exportRow
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil || len(decoded) != 1 || decoded[0] != rows[0] {
+		t.Error("Should export the rows as json:", decoded, err)
+	}
+
+	b.Reset()
+	if err := writeExport(&b, "csv", rows); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || len(records[0]) != len(exportColumns) || records[1][2] != "dylan" || records[1][3] != "2" || records[1][10] != rows[0].Quote {
+		t.Error("Should export the rows as csv with a header:", records)
+	}
+
+	b.Reset()
+	if err := writeExport(&b, "json", nil); err != nil || b.String() != "[]\n" {
+		t.Errorf("Should export no rows as an empty array, exported %q: %v", b.String(), err)
+	}
+	if err := writeExport(&b, "xml", rows); err == nil {
+		t.Error("Should refuse unknown formats.")
+	}
+}
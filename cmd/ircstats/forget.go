@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/DylanJ/stats"
+)
+
+var forgetUsage = `
+forget forgets a user of data.db and the raw_store of the configuration: their
+counters, quotes, hostmask and messages, see stats.ForgetUser. The totals of
+the channels stay. With -export it first writes all the stats have about the
+user as json, to answer their data request, and with -keep it only writes
+that. It should not run while ircstats is collecting into the same data.db.
+
+ircstats forget -network name -nick nick [options]
+`
+
+// forget runs the forget command with its arguments.
+func forget(args []string) error {
+	fs := flag.NewFlagSet("forget", flag.ExitOnError)
+	configFile := fs.String("config", "ircstats.json", "The configuration file with the raw store.")
+	network := fs.String("network", "", "The network of the user.")
+	nick := fs.String("nick", "", "The nick of the user.")
+	export := fs.String("export", "", "The file to write the user's data to first, - for standard output.")
+	keep := fs.Bool("keep", false, "Only export the user's data, forget nothing.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s forget:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, forgetUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if len(*network) == 0 || len(*nick) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	if *keep && len(*export) == 0 {
+		return errors.New("-keep needs -export, there's nothing to do otherwise.")
+	}
+
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer
+	switch *export {
+	case "":
+	case "-":
+		w = os.Stdout
+	default:
+		f, err := os.Create(*export)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err = forgetWith(s, conf, *network, *nick, w, *keep); err != nil || *keep {
+		return err
+	}
+	return s.SaveNow()
+}
+
+// forgetWith forgets the user with the raw store of the configuration, after
+// writing their data to export when it isn't nil. Nothing is forgotten when
+// keep.
+func forgetWith(s *stats.Stats, conf *config, network, nick string, export io.Writer, keep bool) error {
+	opts := conf.options()
+	if len(conf.RawStore) > 0 {
+		raw, err := stats.OpenFileRawStore(conf.RawStore)
+		if err != nil {
+			return err
+		}
+		defer raw.Close()
+		opts.RawStore = raw
+	}
+	s.SetOptions(opts)
+
+	if export != nil {
+		data, err := s.UserData(network, nick)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(export)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(data); err != nil {
+			return err
+		}
+	}
+	if keep {
+		return nil
+	}
+	return s.ForgetUser(network, nick)
+}
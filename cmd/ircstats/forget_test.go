@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestForgetWith(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "forget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := exportStats(t)
+	conf := &config{RawStore: filepath.Join(dir, "raw.jsonl")}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now(), "not kept")
+
+	var export bytes.Buffer
+	if err = forgetWith(s, conf, "zkpq", "aaron", &export, true); err != nil {
+		t.Fatal(err)
+	}
+	var data stats.UserData
+	if err = json.Unmarshal(export.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.User == nil || data.User.Nick != "aaron" || data.User.Lines != 2 {
+		t.Error("Should export the user's data:", export.String())
+	}
+	if s.GetUser("zkpq", "aaron") == nil {
+		t.Error("Should forget nothing when keeping the user.")
+	}
+
+	if err = forgetWith(s, conf, "zkpq", "aaron", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetUser("zkpq", "aaron") != nil || s.GetUser("zkpq", "dylan") == nil {
+		t.Error("Should forget the user only.")
+	}
+	if c := s.GetChannel("zkpq", "#deviate"); c.MessageCount != 4 {
+		t.Error("Should keep the totals of the channel:", c.MessageCount)
+	}
+
+	if err = forgetWith(s, conf, "zkpq", "nobody", nil, false); !errors.Is(err, stats.ErrUnknownUser) {
+		t.Error("Should not forget unknown users, got", err)
+	}
+}
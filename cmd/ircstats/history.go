@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/aarondl/ultimateq/irc"
+)
+
+// historyLimit is the most messages asked for at once when the server
+// doesn't limit CHATHISTORY requests itself.
+const historyLimit = 100
+
+// historyTime is the timestamp format of CHATHISTORY requests.
+const historyTime = "2006-01-02T15:04:05.000Z"
+
+// historyBatch is a page of history the server is playing back.
+type historyBatch struct {
+	channel string
+	lines   int
+	last    time.Time
+}
+
+// handleISupport learns if the server plays back history from its
+// CHATHISTORY=<limit> token.
+func (c *client) handleISupport(l *line) {
+	// the nick comes first and a description last
+	for i := 1; i < len(l.args)-1; i++ {
+		kv := strings.SplitN(l.args[i], "=", 2)
+		if kv[0] != "CHATHISTORY" {
+			continue
+		}
+
+		c.history = historyLimit
+		if len(kv) == 2 {
+			if limit, err := strconv.Atoi(kv[1]); err == nil && limit > 0 && limit < historyLimit {
+				c.history = limit
+			}
+		}
+	}
+}
+
+// trackHistory requests the history missed since the last message counted in
+// a channel when the client joins it, and counts the lines of the pages being
+// played back.
+func (c *client) trackHistory(l *line) {
+	if ref, ok := l.tags["batch"]; ok {
+		if b := c.batches[ref]; b != nil {
+			b.lines++
+			if t, err := time.Parse(time.RFC3339Nano, l.tags["time"]); err == nil && t.After(b.last) {
+				b.last = t
+			}
+		}
+		return
+	}
+
+	if l.command != "JOIN" || c.history == 0 || !strings.EqualFold(irc.Nick(l.prefix), c.nick) {
+		return
+	}
+
+	channel := l.arg(0)
+	s := c.handler.Stats
+	var since time.Time
+	s.View(func(tx *stats.ReadTx) {
+		if ch := tx.GetChannel(c.config.Name, strings.ToLower(channel)); ch != nil {
+			since = ch.LastActive
+		}
+	})
+
+	// channels never seen before have no gaps to fill
+	if !since.IsZero() {
+		c.requestHistory(channel, since)
+	}
+}
+
+// requestHistory asks for a page of the history of a channel after a time.
+func (c *client) requestHistory(channel string, after time.Time) {
+	if c.requested == nil {
+		c.requested = make(map[string]time.Time)
+	}
+	c.requested[strings.ToLower(channel)] = after
+	c.send("CHATHISTORY AFTER " + channel + " timestamp=" + after.UTC().Format(historyTime) + " " + strconv.Itoa(c.history))
+}
+
+// handleBatch keeps track of the chathistory batches, requesting the next
+// page when a page came back full.
+func (c *client) handleBatch(l *line) {
+	ref := l.arg(0)
+	switch {
+	case strings.HasPrefix(ref, "+"):
+		if l.arg(1) != "chathistory" && l.arg(1) != "draft/chathistory" {
+			return
+		}
+		if c.batches == nil {
+			c.batches = make(map[string]*historyBatch)
+		}
+		c.batches[ref[1:]] = &historyBatch{channel: l.arg(2)}
+	case strings.HasPrefix(ref, "-"):
+		b := c.batches[ref[1:]]
+		if b == nil {
+			return
+		}
+		delete(c.batches, ref[1:])
+
+		// a full page means there's more, unless it's all from a single
+		// instant that would be asked for again
+		if b.lines >= c.history && b.last.After(c.requested[strings.ToLower(b.channel)]) {
+			c.requestHistory(b.channel, b.last)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/statsbot"
+)
+
+// bufferConn collects the lines a client sends.
+type bufferConn struct {
+	bytes.Buffer
+}
+
+func (b *bufferConn) Close() error { return nil }
+
+// lines returns the lines sent since the last call.
+func (b *bufferConn) lines() []string {
+	sent := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	b.Reset()
+	if len(sent) == 1 && len(sent[0]) == 0 {
+		return nil
+	}
+	return sent
+}
+
+func TestClient_history(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "network", "#a", "alice!a@host", time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC), "before")
+	s.AddMessage(stats.Msg, "network", "#b", "alice!a@host", time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC), "before")
+
+	c := newClient(networkConfig{Name: "network", Nick: "bot"}, statsbot.New(s))
+	conn := &bufferConn{}
+	c.conn = conn
+	handle := func(raw string) {
+		l, err := parseLine(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.handle(l)
+	}
+
+	handle(":bot!b@host JOIN #b")
+	if sent := conn.lines(); sent != nil {
+		t.Error("Should not request history the server doesn't have:", sent)
+	}
+
+	handle(":server 005 bot CHANTYPES=# CHATHISTORY=2 :are supported by this server")
+	handle(":bot!b@host JOIN #new")
+	if sent := conn.lines(); sent != nil {
+		t.Error("Should only request the history of channels seen before:", sent)
+	}
+
+	handle(":bot!b@host JOIN #A")
+	if sent := conn.lines(); len(sent) != 1 || sent[0] != "CHATHISTORY AFTER #A timestamp=2014-03-01T08:00:00.000Z 2" {
+		t.Fatal("Should request the history since the last message:", sent)
+	}
+
+	handle("BATCH +1 chathistory #A")
+	handle("@batch=1;time=2014-03-01T09:00:00.000Z;msgid=m1 :alice!a@host PRIVMSG #A :missed")
+	handle("@batch=1;time=2014-03-01T10:00:00.000Z;msgid=m2 :alice!a@host PRIVMSG #A :!stats alice")
+	handle("BATCH -1")
+	if sent := conn.lines(); len(sent) != 1 || sent[0] != "CHATHISTORY AFTER #A timestamp=2014-03-01T10:00:00.000Z 2" {
+		t.Fatal("Should request the next page after a full one, without running commands:", sent)
+	}
+
+	handle("BATCH +2 chathistory #A")
+	handle("@batch=2;time=2014-03-01T10:00:00.000Z;msgid=m2 :alice!a@host PRIVMSG #A :!stats alice")
+	handle("@batch=2;time=2014-03-01T11:00:00.000Z;msgid=m3 :alice!a@host PRIVMSG #A :caught up")
+	handle("BATCH -2")
+	handle("BATCH +3 chathistory #A")
+	handle("@batch=3;time=2014-03-01T11:00:00.000Z;msgid=m4 :alice!a@host PRIVMSG #A :same time")
+	handle("@batch=3;time=2014-03-01T11:00:00.000Z;msgid=m5 :alice!a@host PRIVMSG #A :same time")
+	handle("BATCH -3")
+	handle("BATCH +4 chathistory #A")
+	handle("BATCH -4")
+	if sent := conn.lines(); len(sent) != 1 || sent[0] != "CHATHISTORY AFTER #A timestamp=2014-03-01T11:00:00.000Z 2" {
+		t.Error("Should stop at short pages and pages that don't move on:", sent)
+	}
+
+	handle(":alice!a@host JOIN #a")
+	if sent := conn.lines(); sent != nil {
+		t.Error("Should only request history on its own joins:", sent)
+	}
+
+	if u := s.GetUser("network", "alice"); u == nil || u.MessageRanges.Len() != 8 {
+		t.Error("Should count the history once:", u)
+	}
+}
+
+func TestClient_handleISupport(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(networkConfig{Name: "network", Nick: "bot"}, statsbot.New(s))
+	for raw, want := range map[string]int{
+		":server 005 bot CHATHISTORY=50 :are supported":   50,
+		":server 005 bot CHATHISTORY=0 :are supported":    historyLimit,
+		":server 005 bot CHATHISTORY :are supported":      historyLimit,
+		":server 005 bot CHATHISTORY=1000 :are supported": historyLimit,
+	} {
+		c.history = 0
+		l, _ := parseLine(raw)
+		c.handleISupport(l)
+		if c.history != want {
+			t.Errorf("%s: Expected: %d, Got: %d", raw, want, c.history)
+		}
+	}
+}
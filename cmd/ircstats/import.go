@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/importer"
+)
+
+var importUsage = `
+import counts the logs of a client or bot into data.db, with the processors,
+charsets and case mappings of the configuration when one is given. Lines
+falling in a range of a channel's logs that was already imported are skipped.
+It should not run while ircstats is collecting into the same data.db.
+
+Logs are files, directories of logs, http urls of published logs or
+s3://bucket/prefix urls, the scanner documents them all. The formats are: %s.
+The slack and mbox formats import Slack workspace export zips and mailing list
+archives, their channels and the formats that read them from the paths of the
+logs need no channel. An interrupt stops the import, the logs imported before
+it are saved. With -dry-run nothing is saved, the messages each channel would
+count and their dates are written instead, to check a format reads the logs.
+
+ircstats import [options] <logs...>
+`
+
+// importLogs runs the import command with its arguments.
+func importLogs(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configFile := fs.String("config", "", "The configuration file with the options of the stats, none when empty.")
+	format := fs.String("format", "weechat", "The format of the logs.")
+	network := fs.String("network", "", "The network the logs are counted under.")
+	channel := fs.String("channel", "", "The channel the logs are counted under.")
+	timezone := fs.String("timezone", "", "The timezone the logs were written in, UTC when empty.")
+	dryRun := fs.Bool("dry-run", false, "Report what would be imported without saving it.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s import:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, importUsage, strings.Join(append(importer.Names(), "slack", "mbox"), ", "))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("Must give at least one log to import.")
+	}
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s, err := stats.NewStatsContext(ctx)
+	if err != nil {
+		return err
+	}
+	var opts stats.Options
+	if len(*configFile) > 0 {
+		conf, err := loadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		opts = conf.options()
+	}
+	tally := &importer.Tally{}
+	if *dryRun {
+		opts.Sink = tally
+	}
+	s.SetOptions(opts)
+
+	im := importer.New(s)
+	im.Network, im.Channel = *network, *channel
+	err = importSources(ctx, im, *format, loc, fs.Args())
+	if *dryRun {
+		writeTally(os.Stdout, tally.Channels())
+		if errors.Is(err, context.Canceled) {
+			return errors.New("Interrupted, nothing was saved.")
+		}
+		return err
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		if serr := s.SaveNow(); serr != nil {
+			return serr
+		}
+		return errors.New("Interrupted, the logs imported before were saved.")
+	case err != nil:
+		return err
+	}
+
+	return s.SaveNow()
+}
+
+// writeTally writes what a dry run imported, a channel per line.
+func writeTally(w io.Writer, channels []importer.ChannelTally) {
+	var total uint
+	for _, c := range channels {
+		name := c.Channel
+		if len(name) == 0 {
+			name = "(outside channels)"
+		}
+		fmt.Fprintf(w, "%s %s: %d messages from %s to %s\n", c.Network, name, c.Messages, c.From.Format(time.RFC3339), c.To.Format(time.RFC3339))
+		total += c.Messages
+	}
+	fmt.Fprintf(w, "Would import %d messages, nothing was saved.\n", total)
+}
+
+// importSources imports the logs of the format, see importUsage, until the
+// context is done.
+func importSources(ctx context.Context, im *importer.Importer, format string, loc *time.Location, sources []string) error {
+	var f importer.Factory
+	fromPath := format == "slack" || format == "mbox"
+	if !fromPath {
+		var ok bool
+		if f, ok = importer.Lookup(format); !ok {
+			return fmt.Errorf("Unknown format %s, the formats are: %s", format, strings.Join(append(importer.Names(), "slack", "mbox"), ", "))
+		}
+		_, fromPath = f(loc).(importer.PathParser)
+	}
+
+	switch {
+	case len(im.Network) == 0 && !fromPath:
+		return errors.New("Must specify the network.")
+	case len(im.Channel) == 0 && !fromPath:
+		return errors.New("Must specify the channel.")
+	}
+
+	for _, src := range sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var err error
+		switch {
+		case format == "slack":
+			err = im.ImportSlack(src)
+		case format == "mbox":
+			err = im.ImportMbox(src)
+		case strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://"):
+			err = importer.NewFetcher(im, f, loc).FetchContext(ctx, src)
+		case strings.HasPrefix(src, "s3://"):
+			s3 := importer.NewS3Importer(im, f, loc)
+			s3.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+			s3.Region = os.Getenv("AWS_REGION")
+			s3.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+			s3.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+			s3.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+			err = s3.ImportContext(ctx, src)
+		default:
+			err = im.ImportPathContext(ctx, src, f, loc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
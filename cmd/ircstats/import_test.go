@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/importer"
+)
+
+func TestImportSources(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ircstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	log := "2014-03-01 08:00:05\tdylan\thello there\n" +
+		"2014-03-01 08:01:00\taaron\thi dylan\n"
+	path := filepath.Join(dir, "deviate.log")
+	if err = ioutil.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := importer.New(s)
+
+	if err = importSources(context.Background(), im, "weechat", time.UTC, []string{path}); err == nil || !strings.Contains(err.Error(), "network") {
+		t.Error("Should require the network:", err)
+	}
+	im.Network = "zkpq"
+	if err = importSources(context.Background(), im, "weechat", time.UTC, []string{path}); err == nil || !strings.Contains(err.Error(), "channel") {
+		t.Error("Should require the channel:", err)
+	}
+	im.Channel = "#deviate"
+	if err = importSources(context.Background(), im, "latex", time.UTC, []string{path}); err == nil || !strings.Contains(err.Error(), "mbox") {
+		t.Error("Should list the formats for unknown ones:", err)
+	}
+
+	if err = importSources(context.Background(), im, "weechat", time.UTC, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	if c := s.GetChannel("zkpq", "#deviate"); c == nil || c.MessageCount != 2 {
+		t.Error("Should import the logs of the directory:", c)
+	}
+
+	// the logs were already imported
+	if err = importSources(context.Background(), im, "weechat", time.UTC, []string{path}); err != nil {
+		t.Fatal(err)
+	}
+	if c := s.GetChannel("zkpq", "#deviate"); c.MessageCount != 2 {
+		t.Error("Should skip the logs imported before, counted", c.MessageCount)
+	}
+
+	var b strings.Builder
+	writeTally(&b, []importer.ChannelTally{{Network: "zkpq", Channel: "#deviate", Messages: 2, From: time.Date(2014, 3, 1, 8, 0, 5, 0, time.UTC), To: time.Date(2014, 3, 1, 8, 1, 0, 0, time.UTC)}})
+	if want := "zkpq #deviate: 2 messages from 2014-03-01T08:00:05Z to 2014-03-01T08:01:00Z\nWould import 2 messages, nothing was saved.\n"; b.String() != want {
+		t.Errorf("Should write the tally %q, wrote %q", want, b.String())
+	}
+
+	// znc logs name their network and channel in their paths
+	im.Network, im.Channel = "", ""
+	if err = importSources(context.Background(), im, "znc", time.UTC, []string{filepath.Join(dir, "missing")}); err == nil || strings.Contains(err.Error(), "Must specify") {
+		t.Error("Shouldn't require the network of formats reading it from the path:", err)
+	}
+}
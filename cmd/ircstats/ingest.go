@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/importer"
+)
+
+var ingestUsage = `
+ingest counts the lines piped to standard in, so other programs can feed
+messages into data.db. It should not run while ircstats is collecting into the
+same data.db.
+
+The tsv format has five fields separated by tabs, the message takes the rest of
+the line:
+  <date>	<kind>	<channel>	<nick>	<message>
+
+The json format has an object with the same fields on each line:
+  {"date": "2014-03-01T08:00:00Z", "kind": "message", "channel": "#deviate", "nick": "dylan", "message": "hello"}
+
+Dates are RFC 3339 or seconds since the epoch, lines without one are dated when
+they are read. Kinds are message, action, notice, join, part, quit, kick, mode,
+topic or nick, and default to message. The channel may be left empty when it's
+given as an option. Any of the log formats of the scanner may be used as well:
+%s.
+
+ircstats ingest [options]
+`
+
+// ingest runs the ingest command with its arguments.
+func ingest(args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ExitOnError)
+	format := fs.String("format", "tsv", "The format of the lines.")
+	network := fs.String("network", "", "The network the lines are counted under.")
+	channel := fs.String("channel", "", "The channel of lines that don't name one.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s ingest:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, ingestUsage, strings.Join(importer.Names(), ", "))
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	st, err := newIngestStream(s, *format, *network, *channel)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- st.ReadLines(os.Stdin) }()
+
+	ticker := time.NewTicker(defaultSaveInterval)
+	defer ticker.Stop()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	for {
+		select {
+		case <-ticker.C:
+			save(s)
+		case <-signals:
+			save(s)
+			return nil
+		case err := <-done:
+			save(s)
+			return err
+		}
+	}
+}
+
+// newIngestStream creates a stream counting lines of the format into s.
+func newIngestStream(s *stats.Stats, format, network, channel string) (*importer.Stream, error) {
+	if len(network) == 0 {
+		return nil, errors.New("Must specify the network.")
+	}
+
+	f, ok := importer.Lookup(format)
+	if !ok {
+		return nil, fmt.Errorf("Unknown format %s, the formats are: %s", format, strings.Join(importer.Names(), ", "))
+	}
+
+	im := importer.New(s)
+	im.Network = network
+	return importer.NewStream(im, f(time.UTC), importer.Source{Name: "stdin", Channel: channel}), nil
+}
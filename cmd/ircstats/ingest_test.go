@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestNewIngestStream(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newIngestStream(s, "tsv", "", ""); err == nil {
+		t.Error("Should require a network.")
+	}
+	if _, err := newIngestStream(s, "csv", "zkpq", ""); err == nil || !strings.Contains(err.Error(), "json") {
+		t.Error("Should list the formats for unknown ones:", err)
+	}
+
+	st, err := newIngestStream(s, "json", "zkpq", "#deviate")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := `{"nick": "dylan", "message": "hello"}` + "\n" +
+		`{"nick": "dylan", "channel": "#other", "kind": "action", "message": "waves"}` + "\n"
+	if err = st.ReadLines(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.MessageRanges.Len() != 2 {
+		t.Error("Should have counted both lines.")
+	}
+	if s.GetChannel("zkpq", "#deviate") == nil || s.GetChannel("zkpq", "#other") == nil {
+		t.Error("Should count lines in their channel or the default one.")
+	}
+}
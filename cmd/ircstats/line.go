@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+var errEmptyLine = errors.New("empty line")
+
+// line is a single parsed irc protocol line.
+type line struct {
+	tags    map[string]string
+	prefix  string
+	command string
+	args    []string
+}
+
+var tagUnescaper = strings.NewReplacer(
+	`\:`, ";",
+	`\s`, " ",
+	`\\`, `\`,
+	`\r`, "\r",
+	`\n`, "\n",
+)
+
+// parseLine parses a line of the irc protocol including IRCv3 message tags.
+func parseLine(raw string) (*line, error) {
+	raw = strings.TrimRight(raw, "\r\n")
+	l := &line{}
+
+	if strings.HasPrefix(raw, "@") {
+		var tags string
+		tags, raw = split(raw[1:])
+
+		l.tags = make(map[string]string)
+		for _, tag := range strings.Split(tags, ";") {
+			kv := strings.SplitN(tag, "=", 2)
+			if len(kv) == 2 {
+				l.tags[kv[0]] = tagUnescaper.Replace(kv[1])
+			} else {
+				l.tags[kv[0]] = ""
+			}
+		}
+	}
+
+	if strings.HasPrefix(raw, ":") {
+		l.prefix, raw = split(raw[1:])
+	}
+
+	l.command, raw = split(raw)
+	if len(l.command) == 0 {
+		return nil, errEmptyLine
+	}
+	l.command = strings.ToUpper(l.command)
+
+	for len(raw) > 0 {
+		if raw[0] == ':' {
+			l.args = append(l.args, raw[1:])
+			break
+		}
+
+		var arg string
+		arg, raw = split(raw)
+		l.args = append(l.args, arg)
+	}
+
+	return l, nil
+}
+
+// split returns the first space separated word and the rest of the string.
+func split(s string) (string, string) {
+	s = strings.TrimLeft(s, " ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], strings.TrimLeft(s[i+1:], " ")
+	}
+
+	return s, ""
+}
+
+// arg returns the i'th argument or an empty string.
+func (l *line) arg(i int) string {
+	if i < len(l.args) {
+		return l.args[i]
+	}
+
+	return ""
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+
+	l, err := parseLine("@time=2014-03-01T08:00:00.000Z;msgid=a\\sb;flag :bob!b@host privmsg #chan :hello there\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l.tags["time"] != "2014-03-01T08:00:00.000Z" || l.tags["msgid"] != "a b" {
+		t.Error("Tags are incorrect:", l.tags)
+	}
+
+	if _, ok := l.tags["flag"]; !ok {
+		t.Error("Should keep tags without a value.")
+	}
+
+	if l.prefix != "bob!b@host" || l.command != "PRIVMSG" {
+		t.Error("Prefix or command is incorrect:", l.prefix, l.command)
+	}
+
+	if len(l.args) != 2 || l.arg(0) != "#chan" || l.arg(1) != "hello there" {
+		t.Error("Args are incorrect:", l.args)
+	}
+
+	if l.arg(5) != "" {
+		t.Error("Should return empty string for missing args.")
+	}
+
+	if l, _ = parseLine("PING :server"); l.prefix != "" || l.arg(0) != "server" {
+		t.Error("Should parse lines without a prefix.")
+	}
+
+	if _, err = parseLine(""); err == nil {
+		t.Error("Should fail on empty lines.")
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/DylanJ/stats"
+)
+
+var usage = `
+ircstats collects stats about everything said on chat networks into data.db
+and turns them into reports. Its commands are:
+
+  serve     connects to the configured irc networks, joins their channels and
+            collects their stats, serving the report and exports on listen
+  import    counts the logs of a client or bot, see ircstats import -help
+  ingest    counts lines piped to it, see ircstats ingest -help
+  export    writes the stats of the users of every channel as json or csv
+  report    writes the report of the channels as a static html page
+  prune     forgets the messages older than the retention
+  forget    forgets a user, or exports all the stats have about them
+  optout    stops counting a user and forgets them, or counts them again
+  merge     counts the raw stores of other collectors into data.db
+  rebuild   counts the messages kept in the raw store again from scratch
+  validate  checks data.db for problems, and repairs them with -repair
+  shell     answers queries about data.db typed at a prompt
+
+ircstats runs serve when no command is given.
+
+ircstats [command] [options]
+`
+
+// commands are the commands of ircstats by name, and what they print when
+// they fail.
+var commands = map[string]struct {
+	run     func(args []string) error
+	failure string
+}{
+	"serve":    {serve, "Failed serving the stats:"},
+	"import":   {importLogs, "Failed importing logs:"},
+	"ingest":   {ingest, "Failed ingesting lines:"},
+	"export":   {export, "Failed exporting the stats:"},
+	"report":   {report, "Failed writing the report:"},
+	"prune":    {pruneStats, "Failed pruning the stats:"},
+	"forget":   {forget, "Failed forgetting the user:"},
+	"optout":   {optOut, "Failed opting the user out:"},
+	"merge":    {merge, "Failed merging the raw stores:"},
+	"rebuild":  {rebuild, "Failed rebuilding the stats:"},
+	"validate": {validate, "Failed validating the stats:"},
+	"shell":    {shell, "Failed querying the stats:"},
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %s.\n", name)
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintln(os.Stderr, cmd.failure, err)
+		os.Exit(1)
+	}
+}
+
+func save(s *stats.Stats) {
+	if err := s.Save(); err != nil {
+		slog.Error("Failed saving", "err", err)
+	}
+}
+
+// prune forgets the messages older than the retention.
+func prune(s *stats.Stats) {
+	if err := s.Prune(); err != nil {
+		slog.Error("Failed pruning old messages", "err", err)
+	}
+}
+
+// flush writes the messages waiting in the raw store to its file.
+func flush(raw *stats.FileRawStore) {
+	if raw == nil {
+		return
+	}
+
+	if err := raw.Flush(); err != nil {
+		slog.Error("Failed writing the raw store", "err", err)
+	}
+}
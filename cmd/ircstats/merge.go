@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DylanJ/stats"
+)
+
+var mergeUsage = `
+merge counts the messages kept in the raw stores of other collectors into
+data.db, as if they had been collected here, and keeps them in the raw_store
+of the configuration. Messages with a msgid already counted are skipped, and
+so are those without one counted within the dedup window, so stores that
+overlap are merged once. It should not run while ircstats is collecting into
+the same data.db.
+
+ircstats merge [options] <raw stores...>
+`
+
+// merge runs the merge command with its arguments.
+func merge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	configFile := fs.String("config", "ircstats.json", "The configuration file with the options of the stats.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s merge:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, mergeUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		return errors.New("Must give at least one raw store to merge.")
+	}
+
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	opts := conf.options()
+	if len(conf.RawStore) > 0 {
+		raw, err := stats.OpenFileRawStore(conf.RawStore)
+		if err != nil {
+			return err
+		}
+		defer raw.Close()
+		opts.RawStore = raw
+	}
+	s.SetOptions(opts)
+
+	for _, path := range fs.Args() {
+		counted, skipped, err := mergeRawStore(s, path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Merged %d messages of %s, skipped %d.\n", counted, path, skipped)
+	}
+
+	return s.SaveNow()
+}
+
+// mergeRawStore counts the messages of the raw store at path into s, returning
+// how many were counted and how many were skipped, as already counted or
+// dated out of bounds.
+func mergeRawStore(s *stats.Stats, path string) (counted, skipped uint64, err error) {
+	// opening the store would create it
+	if _, err = os.Stat(path); err != nil {
+		return 0, 0, err
+	}
+	raw, err := stats.OpenFileRawStore(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer raw.Close()
+
+	var replayed uint64
+	before := s.Metrics().Messages
+	err = raw.Replay(func(m stats.RawMessage) {
+		s.AddMessageID(m.MsgID, m.Kind, m.Network, m.Channel, m.Hostmask, m.Date, m.Message)
+		replayed++
+	})
+	counted = s.Metrics().Messages - before
+	return counted, replayed - counted, err
+}
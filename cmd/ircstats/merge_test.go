@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestMergeRawStore(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ircstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "raw.jsonl")
+	raw, err := stats.OpenFileRawStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collected, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	collected.SetOptions(stats.Options{RawStore: raw})
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	collected.AddMessageID("a1", stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", date, "hello")
+	collected.AddMessageID("", stats.Msg, "zkpq", "#deviate", "aaron!a@zqz.ca", date.Add(time.Minute), "hi")
+	if err = raw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a collector that saw the same message
+	s.AddMessageID("a1", stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", date, "hello")
+
+	counted, skipped, err := mergeRawStore(s, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted != 1 || skipped != 1 {
+		t.Errorf("Should count the message not counted yet, counted %d and skipped %d", counted, skipped)
+	}
+	if c := s.GetChannel("zkpq", "#deviate"); c.MessageCount != 2 {
+		t.Error("Should count every message once, counted", c.MessageCount)
+	}
+
+	if _, _, err = mergeRawStore(s, filepath.Join(dir, "missing.jsonl")); err == nil {
+		t.Error("Should fail merging a store that doesn't exist.")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "missing.jsonl")); !os.IsNotExist(err) {
+		t.Error("Shouldn't create the store that's missing.")
+	}
+}
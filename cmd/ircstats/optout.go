@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/DylanJ/stats"
+)
+
+var optOutUsage = `
+optout opts a user out of data.db: the lines they say from then on are neither
+counted nor stored, and what was counted of them is forgotten, in the
+raw_store of the configuration too, see stats.OptOut. With -undo their lines
+are counted again. With -list it writes the users who opted out. Users opt out
+themselves with !optout, which forgets nothing since their nicks don't prove
+who they are: run optout for them once they're known to be who they say. It
+should not run while ircstats is collecting into the same data.db.
+
+ircstats optout -network name -nick nick [options]
+ircstats optout -list
+`
+
+// optOut runs the optout command with its arguments.
+func optOut(args []string) error {
+	fs := flag.NewFlagSet("optout", flag.ExitOnError)
+	configFile := fs.String("config", "ircstats.json", "The configuration file with the raw store.")
+	network := fs.String("network", "", "The network of the user.")
+	nick := fs.String("nick", "", "The nick of the user.")
+	undo := fs.Bool("undo", false, "Count the lines of the user again.")
+	list := fs.Bool("list", false, "Write the users who opted out.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s optout:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, optOutUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if !*list && (len(*network) == 0 || len(*nick) == 0) {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+	if *list {
+		writeOptOuts(os.Stdout, s)
+		return nil
+	}
+
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if err = optOutWith(s, conf, *network, *nick, *undo); err != nil {
+		return err
+	}
+	return s.SaveNow()
+}
+
+// optOutWith opts the user out with the raw store of the configuration, or
+// in when undo.
+func optOutWith(s *stats.Stats, conf *config, network, nick string, undo bool) error {
+	if undo {
+		if !s.OptIn(network, nick) {
+			return fmt.Errorf("%s on %s didn't opt out.", nick, network)
+		}
+		return nil
+	}
+
+	opts := conf.options()
+	if len(conf.RawStore) > 0 {
+		raw, err := stats.OpenFileRawStore(conf.RawStore)
+		if err != nil {
+			return err
+		}
+		defer raw.Close()
+		opts.RawStore = raw
+	}
+	s.SetOptions(opts)
+	return s.OptOut(network, nick)
+}
+
+// writeOptOuts writes the network and nick of the users who opted out, one
+// per line.
+func writeOptOuts(w io.Writer, s *stats.Stats) {
+	for _, user := range s.OptedOutUsers() {
+		fmt.Fprintln(w, user)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestOptOutWith(t *testing.T) {
+	t.Parallel()
+
+	s := exportStats(t)
+	if err := optOutWith(s, &config{}, "zkpq", "aaron", false); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetUser("zkpq", "aaron") != nil {
+		t.Error("Should forget the user who opted out.")
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron!a@zqz.ca", time.Now(), "still here")
+	if s.GetUser("zkpq", "aaron") != nil {
+		t.Error("Should not count the user who opted out.")
+	}
+
+	var list bytes.Buffer
+	writeOptOuts(&list, s)
+	if list.String() != "zkpq aaron\n" {
+		t.Error("Should list the users who opted out:", list.String())
+	}
+
+	if err := optOutWith(s, &config{}, "zkpq", "aaron", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := optOutWith(s, &config{}, "zkpq", "aaron", true); err == nil {
+		t.Error("Should not opt in a user who didn't opt out.")
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+var pruneUsage = `
+prune forgets the messages in data.db and the raw_store of the configuration
+that are older than its retention, or than -retention, and the urls, quotes
+and aggregates older than their retentions. The counters keep counting them,
+see stats.Prune. serve prunes every save_interval already, run
+prune when it isn't running, such as after shortening the retention. It should
+not run while ircstats is collecting into the same data.db. With -dry-run
+it only reports what it would forget in each channel.
+
+ircstats prune [options]
+`
+
+// pruneStats runs the prune command with its arguments.
+func pruneStats(args []string) error {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	configFile := fs.String("config", "ircstats.json", "The configuration file with the retention and raw store.")
+	retention := fs.Duration("retention", 0, "The retention to prune with, that of the configuration when 0.")
+	dryRun := fs.Bool("dry-run", false, "Report what would be forgotten without forgetting it.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s prune:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, pruneUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	var plan io.Writer
+	if *dryRun {
+		plan = os.Stdout
+	}
+	if err = pruneWith(s, conf, *retention, plan); err != nil || *dryRun {
+		return err
+	}
+	return s.SaveNow()
+}
+
+// pruneWith prunes s with the options of the configuration, and the
+// retention when it's set. When plan isn't nil nothing is pruned, what would
+// be is written to it instead.
+func pruneWith(s *stats.Stats, conf *config, retention time.Duration, plan io.Writer) error {
+	opts := conf.options()
+	if retention > 0 {
+		opts.Retention = retention
+	}
+	if opts.Retention <= 0 && !conf.Retentions.any() {
+		return errors.New("The configuration has no retention to prune with, set one or give -retention.")
+	}
+
+	if len(conf.RawStore) > 0 {
+		raw, err := stats.OpenFileRawStore(conf.RawStore)
+		if err != nil {
+			return err
+		}
+		defer raw.Close()
+		opts.RawStore = raw
+	}
+
+	s.SetOptions(opts)
+	if plan == nil {
+		return s.Prune()
+	}
+
+	p, err := s.PlanPrune()
+	if err != nil {
+		return err
+	}
+	writePrunePlan(plan, p)
+	return nil
+}
+
+// writePrunePlan writes what would be pruned, a channel per line.
+func writePrunePlan(w io.Writer, p stats.PrunePlan) {
+	for _, c := range []struct {
+		name   string
+		cutoff time.Time
+	}{{"urls", p.Cutoffs.URLs}, {"quotes", p.Cutoffs.Quotes}, {"aggregates", p.Cutoffs.Aggregates}} {
+		if !c.cutoff.IsZero() {
+			fmt.Fprintf(w, "Would forget the %s dated before %s.\n", c.name, c.cutoff.Format("2006-01-02 15:04 MST"))
+		}
+	}
+	if p.Before.IsZero() {
+		fmt.Fprintln(w, "Would keep the messages, and forget in:")
+	} else {
+		fmt.Fprintf(w, "Would forget the messages dated before %s:\n", p.Before.Format("2006-01-02 15:04 MST"))
+	}
+	for _, c := range p.Channels {
+		fmt.Fprintf(w, "%s %s: %d messages", c.Network, c.Channel, c.Messages)
+		if c.Messages > 0 {
+			fmt.Fprintf(w, " from %s to %s", c.From.Format("2006-01-02"), c.To.Format("2006-01-02"))
+		}
+		fmt.Fprintf(w, ", %d users\n", c.Users)
+	}
+	fmt.Fprintf(w, "%d messages in all, %d of them in the raw store.\n", p.Messages, p.Raw)
+}
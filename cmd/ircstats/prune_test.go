@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestPruneWith(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now().Add(-72*time.Hour), "old")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now(), "new")
+
+	if err = pruneWith(s, &config{}, 0, nil); err == nil {
+		t.Error("Should require a retention.")
+	}
+
+	var plan bytes.Buffer
+	if err = pruneWith(s, &config{Retention: "24h"}, 0, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan.String(), "zkpq #deviate: 1 messages from") || !strings.Contains(plan.String(), "1 messages in all") {
+		t.Error("Should report what would be forgotten:", plan.String())
+	}
+	if c := s.GetChannel("zkpq", "#deviate"); c.MessageRanges.Len() != 2 {
+		t.Error("Should not forget anything in a dry run.")
+	}
+
+	if err = pruneWith(s, &config{Retention: "2160h"}, 24*time.Hour, nil); err != nil {
+		t.Fatal(err)
+	}
+	c := s.GetChannel("zkpq", "#deviate")
+	if c.MessageRanges.Len() != 1 || c.MessageCount != 2 {
+		t.Error("Should forget the message older than the retention given and keep counting it:", c.MessageRanges.Len(), c.MessageCount)
+	}
+}
+
+func TestPruneWith_retentions(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now().Add(-72*time.Hour), "old http://old.com")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now(), "new")
+
+	conf := &config{Retentions: retentionsConfig{URLs: "24h", Quotes: forever}}
+	var plan bytes.Buffer
+	if err = pruneWith(s, conf, 0, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(plan.String(), "Would forget the urls dated before") || !strings.Contains(plan.String(), "Would keep the messages") || strings.Contains(plan.String(), "quotes") {
+		t.Error("Should report the categories that would be pruned:", plan.String())
+	}
+
+	if err = pruneWith(s, conf, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	c := s.GetChannel("zkpq", "#deviate")
+	if _, ok := c.URLCounter.All["http://old.com"]; ok || c.MessageRanges.Len() != 2 {
+		t.Error("Should only forget the old urls:", c.URLCounter.All, c.MessageRanges.Len())
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DylanJ/stats"
+)
+
+var rebuildUsage = `
+rebuild throws away the counters in data.db and counts the messages kept in
+the raw_store of the configuration again, running its processors on them. Run
+it after upgrading to fix a counter or to count history with new processors.
+It should not run while ircstats is collecting into the same data.db.
+
+ircstats rebuild [options]
+`
+
+// rebuild runs the rebuild command with its arguments.
+func rebuild(args []string) error {
+	fs := flag.NewFlagSet("rebuild", flag.ExitOnError)
+	configFile := fs.String("config", "ircstats.json", "The configuration file naming the raw store.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s rebuild:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, rebuildUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	if err = rebuildStats(s, conf); err != nil {
+		return err
+	}
+
+	return s.Save()
+}
+
+// rebuildStats counts the raw store of the configuration into s again.
+func rebuildStats(s *stats.Stats, conf *config) error {
+	if len(conf.RawStore) == 0 {
+		return errors.New("The configuration has no raw_store to rebuild from.")
+	}
+
+	raw, err := stats.OpenFileRawStore(conf.RawStore)
+	if err != nil {
+		return err
+	}
+	defer raw.Close()
+
+	processors, err := conf.newProcessors()
+	if err != nil {
+		return err
+	}
+
+	s.SetOptions(stats.Options{Processors: processors, RawStore: raw})
+	return s.Rebuild()
+}
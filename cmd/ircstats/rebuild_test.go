@@ -0,0 +1,51 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestRebuildStats(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ircstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rebuildStats(s, &config{}); err == nil {
+		t.Error("Should require a raw store.")
+	}
+
+	conf := &config{RawStore: filepath.Join(dir, "raw.jsonl"), Processors: []string{"bridge=relay"}}
+	raw, err := stats.OpenFileRawStore(conf.RawStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collected, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	collected.SetOptions(stats.Options{RawStore: raw})
+	collected.AddMessage(stats.Msg, "zkpq", "#deviate", "relay!r@zqz.ca", time.Now(), "<dylan> hello")
+	if err = raw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = rebuildStats(s, conf); err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.MessageRanges.Len() != 1 {
+		t.Error("Should count the raw messages with the processors:", u)
+	}
+}
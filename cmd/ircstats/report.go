@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/access"
+	"github.com/DylanJ/stats/locale"
+)
+
+var reportUsage = `
+report writes the report of the channels in data.db as a single html page
+with no assets to serve: the users who said the most in each channel, the
+words and urls said the most and the hours they were said in. The hours are
+those of the timezones of the configuration when one is given, and the
+report is in the locale of the configuration unless another is asked for.
+
+ircstats report [options]
+`
+
+// defaultReportTop is how many users, words and urls the report lists for
+// each channel unless asked for another number.
+const defaultReportTop = 10
+
+// reportPage is what the report shows.
+type reportPage struct {
+	Generated time.Time
+	Channels  []reportChannel
+	// Search links the words to the messages they were said in, only the
+	// served report can search them.
+	Search bool
+	// Locale is the language of the report, English when nil.
+	Locale *locale.Locale
+	// Base is the path the served report is under, /o/<owner> for the
+	// report of an owner.
+	Base string
+	// Token is the ?token= the served report was asked with, its links
+	// pass it on.
+	Token string
+}
+
+// T translates a message of the report, see locale.Locale.Sprintf.
+func (p reportPage) T(format string, args ...interface{}) string {
+	return p.Locale.Sprintf(format, args...)
+}
+
+// Num writes a number as the locale of the report does.
+func (p reportPage) Num(n interface{}) string {
+	return p.Locale.Number(n)
+}
+
+// Time writes a date as the locale of the report does.
+func (p reportPage) Time(t time.Time) string {
+	return p.Locale.Time(t)
+}
+
+// Lang is the language tag of the report.
+func (p reportPage) Lang() string {
+	if p.Locale == nil {
+		return locale.English.Name
+	}
+	return p.Locale.Name
+}
+
+// reportChannel is the report of a channel.
+type reportChannel struct {
+	Network string
+	Name    string
+	Lines   uint
+	Users   []exportRow
+	Words   []stats.TopToken
+	URLs    []stats.TopToken
+	Hours   []reportHour
+}
+
+// reportHour is an hour of the day in the chart of a channel, Percent is its
+// lines as a percentage of the busiest hour's.
+type reportHour struct {
+	Hour    int
+	Lines   int
+	Percent int
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>ircstats</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { padding: 0.2em 0.6em; text-align: left; vertical-align: top; }
+td.n { text-align: right; }
+.bar { background: #4a7; height: 0.8em; }
+.quote { color: #666; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>ircstats</h1>
+<p>{{.T "Generated %s." (.Time .Generated)}}</p>
+{{range $c := .Channels}}
+<h2>{{$.T "%s on %s" .Name .Network}}</h2>
+<p>{{$.T "%s messages." ($.Num .Lines)}}</p>
+<table>
+<tr><th>{{$.T "Nick"}}</th><th>{{$.T "Lines"}}</th><th>{{$.T "Words"}}</th><th>{{$.T "Random quote"}}</th></tr>
+{{range .Users}}<tr><td>{{.Nick}}</td><td class="n">{{$.Num .Lines}}</td><td class="n">{{$.Num .Words}}</td><td class="quote">{{.Quote}}</td></tr>
+{{end}}</table>
+<table>
+<tr><th>{{$.T "Hour"}}</th><th>{{$.T "Lines"}}</th><th></th></tr>
+{{range .Hours}}<tr><td>{{printf "%02d:00" .Hour}}</td><td class="n">{{$.Num .Lines}}</td><td style="width: 20em"><div class="bar" style="width: {{.Percent}}%"></div></td></tr>
+{{end}}</table>
+{{if .Words}}<h3>{{$.T "Most used words"}}</h3>
+<table>{{range .Words}}<tr><td>{{if $.Search}}<a href="{{$.Base}}/search.json?network={{$c.Network}}&amp;channel={{$c.Name}}&amp;q={{.Token}}{{with $.Token}}&amp;token={{.}}{{end}}">{{.Token}}</a>{{else}}{{.Token}}{{end}}</td><td class="n">{{$.Num .Count}}</td></tr>
+{{end}}</table>{{end}}
+{{if .URLs}}<h3>{{$.T "Most pasted urls"}}</h3>
+<table>{{range .URLs}}<tr><td>{{.Token}}</td><td class="n">{{$.Num .Count}}</td></tr>
+{{end}}</table>{{end}}
+{{else}}
+<p>{{.T "Nothing was counted yet."}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// report runs the report command with its arguments.
+func report(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	configFile := fs.String("config", "", "The configuration file with the timezones, UTC when empty.")
+	network := fs.String("network", "", "The network to report on, every network when empty.")
+	channel := fs.String("channel", "", "The channel to report on, every channel when empty.")
+	top := fs.Int("top", defaultReportTop, "How many users, words and urls are listed, 0 for all of them.")
+	lang := fs.String("locale", "", "The locale of the report, such as fr or de, English when empty.")
+	out := fs.String("out", "", "The file to write, standard out when empty.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s report:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, reportUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s, err := stats.NewStatsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	l := locale.English
+	if len(*configFile) > 0 {
+		conf, err := loadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		var opts stats.Options
+		opts.Location, opts.Locations, _ = conf.locations()
+		s.SetOptions(opts)
+		l, _ = conf.locale()
+	}
+	if len(*lang) > 0 {
+		var ok bool
+		if l, ok = locale.Lookup(*lang); !ok {
+			return fmt.Errorf("Unknown locale %s, the locales are %s.", *lang, strings.Join(locale.Names(), ", "))
+		}
+	}
+
+	var page reportPage
+	s.View(func(tx *stats.ReadTx) {
+		page, err = buildReport(ctx, tx, *network, *channel, *top, nil)
+	})
+	if err != nil {
+		return err
+	}
+	page.Locale = l
+
+	return writeFile(*out, func(w io.Writer) error {
+		return reportTemplate.Execute(w, page)
+	})
+}
+
+// buildReport builds the report of the channels, of every network or channel
+// when they're empty, those visible only, until the context is done. The
+// channels are built in parallel, by as many workers as there are CPUs.
+func buildReport(ctx context.Context, tx *stats.ReadTx, network, channel string, top int, visible access.Visibility) (reportPage, error) {
+	page := reportPage{Generated: time.Now()}
+	rows := exportRows(tx, network, channel, visible)
+
+	var list []*stats.Channel
+	var users [][]exportRow
+	for _, c := range channels(tx, network, channel) {
+		n := tx.Networks[c.NetworkID]
+		if !visible.Allows(n.Name, c.Name) {
+			continue
+		}
+
+		// the rows are in the order of the channels
+		i := 0
+		for i < len(rows) && rows[i].Network == n.Name && rows[i].Channel == c.Name {
+			i++
+		}
+		list = append(list, c)
+		users = append(users, rows[:stats.Limit(top, i)])
+		rows = rows[i:]
+	}
+
+	indexes := make(chan int, len(list))
+	for i := range list {
+		indexes <- i
+	}
+	close(indexes)
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(list) {
+		workers = len(list)
+	}
+
+	// every worker fills in the channels it took, there's nothing to guard
+	page.Channels = make([]reportChannel, len(list))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ctx.Err() != nil {
+					return
+				}
+				page.Channels[i] = reportChannelOf(tx, list[i], users[i], top)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return reportPage{}, err
+	}
+	return page, nil
+}
+
+// reportChannelOf builds the report of a channel with the rows of its users.
+func reportChannelOf(tx *stats.ReadTx, c *stats.Channel, users []exportRow, top int) reportChannel {
+	n := tx.Networks[c.NetworkID]
+	rc := reportChannel{
+		Network: n.Name,
+		Name:    c.Name,
+		Lines:   c.MessageCount,
+		Users:   users,
+		Words:   c.WordCounter.TopN(top),
+		URLs:    c.URLCounter.TopN(top),
+	}
+
+	chart := c.HourlyChart.In(tx.Location(n.Name, c.Name), tx.Now())
+	busiest := 0
+	for _, lines := range chart {
+		if lines > busiest {
+			busiest = lines
+		}
+	}
+	for hour, lines := range chart {
+		h := reportHour{Hour: hour, Lines: lines}
+		if busiest > 0 {
+			h.Percent = lines * 100 / busiest
+		}
+		rc.Hours = append(rc.Hours, h)
+	}
+	return rc
+}
+
+// newReportServer serves the report and the exports of the stats, see
+// serveUsage. The report is in the locale given unless another is asked for.
+func newReportServer(s *stats.Stats, l *locale.Locale) http.Handler {
+	return newReportServerAt(s, l, "")
+}
+
+// newOwnersServer serves the report of the stats at / and those of the owners
+// opened in the namespaces under /o/<owner>/.
+func newOwnersServer(s *stats.Stats, ns *stats.Namespaces, l *locale.Locale) http.Handler {
+	owners := make(map[string]http.Handler)
+	for _, owner := range ns.Owners() {
+		owned, _ := ns.Lookup(owner)
+		owners[owner] = newReportServerAt(owned, l, "/o/"+owner)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", newReportServer(s, l))
+	mux.HandleFunc("/o/", func(w http.ResponseWriter, r *http.Request) {
+		owner, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/o/"), "/")
+		h, ok := owners[owner]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		r = r.Clone(r.Context())
+		r.URL.Path, r.URL.RawPath = "/"+rest, ""
+		h.ServeHTTP(w, r)
+	})
+	return mux
+}
+
+// newReportServerAt is newReportServer with the report's links under base.
+func newReportServerAt(s *stats.Stats, l *locale.Locale, base string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		top, err := strconv.Atoi(r.FormValue("top"))
+		if err != nil {
+			top = defaultReportTop
+		}
+
+		var page reportPage
+		s.View(func(tx *stats.ReadTx) {
+			page, err = buildReport(r.Context(), tx, r.FormValue("network"), r.FormValue("channel"), top, access.RequestVisibility(r))
+		})
+		if err != nil {
+			// the client went away
+			return
+		}
+		page.Search = true
+		page.Base = base
+		page.Token = r.URL.Query().Get("token")
+		page.Locale = l
+		if asked, ok := locale.Lookup(r.FormValue("locale")); ok && len(r.FormValue("locale")) > 0 {
+			page.Locale = asked
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		reportTemplate.Execute(w, page)
+	})
+
+	for _, format := range []string{"json", "csv"} {
+		format := format
+		mux.HandleFunc("/export."+format, func(w http.ResponseWriter, r *http.Request) {
+			var rows []exportRow
+			s.View(func(tx *stats.ReadTx) {
+				rows = exportRows(tx, r.FormValue("network"), r.FormValue("channel"), access.RequestVisibility(r))
+			})
+			if format == "json" {
+				w.Header().Set("Content-Type", "application/json")
+			} else {
+				w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			}
+			writeExport(w, format, rows)
+		})
+	}
+
+	mux.HandleFunc("/search.json", func(w http.ResponseWriter, r *http.Request) {
+		// the raw store keeps the channels the messages were said in, the
+		// private channels they're counted in are as unknown as those
+		// never counted
+		network, channel := r.FormValue("network"), r.FormValue("channel")
+		var aliasNetwork, aliasChannel string
+		s.View(func(tx *stats.ReadTx) {
+			aliasNetwork, aliasChannel = tx.Alias(network, channel)
+		})
+		visible := access.RequestVisibility(r)
+		if !visible.Allows(network, channel) || !visible.Allows(aliasNetwork, aliasChannel) {
+			http.NotFound(w, r)
+			return
+		}
+		limits := stats.SearchLimits{
+			Regexp: len(r.FormValue("regexp")) > 0,
+			Nick:   r.FormValue("nick"),
+		}
+		limits.Max, _ = strconv.Atoi(r.FormValue("max"))
+
+		found, err := s.Search(network, channel, r.FormValue("q"), limits)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results := make([]searchResult, 0, len(found))
+		for _, m := range found {
+			h, _ := stats.ParseHostmask(m.Hostmask)
+			results = append(results, searchResult{Date: m.Date, Nick: h.Nick, Message: m.Message})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	})
+
+	mux.HandleFunc("/top.json", func(w http.ResponseWriter, r *http.Request) {
+		var cursor stats.RankCursor
+		var err error
+		if len(r.FormValue("cursor")) > 0 {
+			if cursor, err = stats.ParseRankCursor(r.FormValue("cursor")); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		max, err := strconv.Atoi(r.FormValue("max"))
+		if err != nil || max <= 0 {
+			max = defaultReportTop
+		}
+
+		var page topPage
+		found := false
+		s.View(func(tx *stats.ReadTx) {
+			c := tx.GetChannel(r.FormValue("network"), r.FormValue("channel"))
+			if c == nil || !access.RequestVisibility(r).Allows(tx.Networks[c.NetworkID].Name, c.Name) {
+				return
+			}
+			found = true
+			it := c.RankIterator(tx)
+			if len(r.FormValue("cursor")) > 0 {
+				it.SeekCursor(cursor)
+			}
+			page.Ranks = make([]stats.Rank, 0, max)
+			for len(page.Ranks) < max {
+				rank, ok := it.Next()
+				if !ok {
+					return
+				}
+				page.Ranks = append(page.Ranks, rank)
+			}
+			if last := page.Ranks[max-1]; last.Position < it.Len() {
+				next, _ := it.Cursor()
+				page.Next = next.String()
+			}
+		})
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(page)
+	})
+
+	return mux
+}
+
+// topPage is a page of the talkers of a channel served by /top.json, Next is
+// the cursor of the next page, empty on the last.
+type topPage struct {
+	Ranks []stats.Rank `json:"ranks"`
+	Next  string       `json:"next,omitempty"`
+}
+
+// searchResult is a message found by /search.json.
+type searchResult struct {
+	Date    time.Time `json:"date"`
+	Nick    string    `json:"nick"`
+	Message string    `json:"message"`
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/locale"
+)
+
+func TestBuildReport(t *testing.T) {
+	t.Parallel()
+
+	s := exportStats(t)
+	var page reportPage
+	var err error
+	s.View(func(tx *stats.ReadTx) {
+		page, err = buildReport(context.Background(), tx, "zkpq", "", 1, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Channels) != 2 {
+		t.Fatal("Should report on the channels of the network:", page.Channels)
+	}
+	c := page.Channels[1]
+	if c.Name != "#deviate" || c.Lines != 3 || len(c.Users) != 1 || c.Users[0].Nick != "aaron" {
+		t.Error("Should report the top users of the channel:", c)
+	}
+	if len(c.Words) != 1 || len(c.Hours) != 24 {
+		t.Error("Should report the top words and every hour:", c.Words, c.Hours)
+	}
+	busiest := 0
+	for _, h := range c.Hours {
+		if h.Percent > busiest {
+			busiest = h.Percent
+		}
+	}
+	if busiest != 100 {
+		t.Error("Should chart the hours against the busiest one:", c.Hours)
+	}
+
+	var b bytes.Buffer
+	if err := reportTemplate.Execute(&b, page); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "<h2>#deviate on zkpq</h2>") || !strings.Contains(b.String(), `<html lang="en">`) {
+		t.Error("Should render the channels.")
+	}
+
+	b.Reset()
+	page.Locale = locale.German
+	if err := reportTemplate.Execute(&b, page); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<html lang="de">`, "<h2>#deviate auf zkpq</h2>", "<p>3 Nachrichten.</p>", "<th>Zeilen</th>"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Should render the report in German with %s:\n%s", want, b.String())
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.View(func(tx *stats.ReadTx) {
+		_, err = buildReport(ctx, tx, "zkpq", "", 1, nil)
+	})
+	if err != context.Canceled {
+		t.Error("Should give up once the context is done, got", err)
+	}
+}
+
+func TestBuildReport_manyChannels(t *testing.T) {
+	t.Parallel()
+
+	s := exportStats(t)
+	for i := 0; i < 50; i++ {
+		channel := fmt.Sprintf("#c%02d", i)
+		for j := 0; j <= i%3; j++ {
+			s.AddMessage(stats.Msg, "many", channel, fmt.Sprintf("nick%d!n@zqz.ca", j), time.Now(), "hi")
+		}
+	}
+
+	var page reportPage
+	var err error
+	s.View(func(tx *stats.ReadTx) {
+		page, err = buildReport(context.Background(), tx, "many", "", 0, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Channels) != 50 {
+		t.Fatal("Should report on every channel, reported", len(page.Channels))
+	}
+	for i, c := range page.Channels {
+		if want := fmt.Sprintf("#c%02d", i); c.Name != want || len(c.Users) != i%3+1 {
+			t.Errorf("Should report %s with its %d users in order, reported %s with %d", want, i%3+1, c.Name, len(c.Users))
+		}
+		for _, u := range c.Users {
+			if u.Channel != c.Name {
+				t.Errorf("Should report the users of %s only, reported %s", c.Name, u.Channel)
+			}
+		}
+	}
+}
+
+func TestReportServer(t *testing.T) {
+	t.Parallel()
+
+	s := exportStats(t)
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "mallory!m@zqz.ca", time.Now(), "<script>alert(1)</script>")
+	srv := newReportServer(s, nil)
+
+	tests := []struct {
+		url, contentType, want string
+	}{
+		{"/?network=zkpq&channel=%23deviate&top=0", "text/html; charset=utf-8", "&lt;script&gt;"},
+		{"/?locale=fr", "text/html; charset=utf-8", "<h2>#deviate sur zkpq</h2>"},
+		{"/export.json?network=other", "application/json", `"nick": "scott"`},
+		{"/export.csv", "text/csv; charset=utf-8", "network,channel,nick"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if got := w.Header().Get("Content-Type"); got != test.contentType {
+			t.Errorf("%s should be served as %s, was %s", test.url, test.contentType, got)
+		}
+		if !strings.Contains(w.Body.String(), test.want) {
+			t.Errorf("%s should contain %s, is %s", test.url, test.want, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/assets/foo.js", nil))
+	if w.Code != 404 {
+		t.Error("Should serve nothing else, served", w.Code)
+	}
+}
+
+func TestOwnersServer(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "owners")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ns := stats.NewNamespaces(dir)
+	acme, err := ns.Open("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme.AddMessage(stats.Msg, "acme", "#lobby", "wile!w@acme.com", time.Now(), "gophers everywhere")
+	srv := newOwnersServer(exportStats(t), ns, nil)
+
+	tests := []struct {
+		url, want, unwanted string
+	}{
+		{"/export.csv", "dylan", "wile"},
+		{"/o/acme/export.csv", "wile", "dylan"},
+		{"/o/acme/?top=0", `href="/o/acme/search.json?network=acme&amp;channel=%23lobby&amp;q=gophers"`, "#deviate"},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", test.url, nil))
+		if body := w.Body.String(); !strings.Contains(body, test.want) || strings.Contains(body, test.unwanted) {
+			t.Errorf("%s should serve only the stats of its owner, served %s", test.url, body)
+		}
+	}
+
+	for _, url := range []string{"/o/nobody/export.csv", "/o/acme/assets/foo.js"} {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != 404 {
+			t.Error(url, "should not be found, served", w.Code)
+		}
+	}
+}
+
+func TestReportServer_top(t *testing.T) {
+	t.Parallel()
+
+	s := exportStats(t)
+	srv := newReportServer(s, nil)
+
+	var names []string
+	url := "/top.json?network=zkpq&channel=%23deviate&max=1"
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		srv.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		var page topPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Ranks) != 1 || page.Ranks[0].Position != len(names)+1 {
+			t.Fatal("Should list a talker a page:", page)
+		}
+		names = append(names, page.Ranks[0].Nick)
+		if len(page.Next) == 0 {
+			break
+		}
+		url = "/top.json?network=zkpq&channel=%23deviate&max=1&cursor=" + page.Next
+	}
+	if len(names) != 2 || names[0] != "aaron" {
+		t.Error("Should page through the talkers of #deviate, paged", names)
+	}
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/top.json?network=zkpq&channel=%23deviate&cursor=!", nil))
+	if w.Code != 400 {
+		t.Error("Should refuse a bad cursor, served", w.Code)
+	}
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/top.json?network=zkpq&channel=%23nowhere", nil))
+	if w.Code != 404 {
+		t.Error("Should not find unknown channels, served", w.Code)
+	}
+}
+
+func TestReportServer_search(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "ircstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newReportServer(s, nil)
+
+	w := httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/search.json?network=zkpq&channel=%23deviate&q=gophers", nil))
+	if w.Code != 400 {
+		t.Error("Should refuse searching without a raw store, served", w.Code)
+	}
+
+	raw, err := stats.OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	s.SetOptions(stats.Options{RawStore: raw})
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now(), "gophers gophers gophers")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron!a@zqz.ca", time.Now(), "I like Gophers")
+	s.AddMessage(stats.Msg, "zkpq", "#bots", "aaron!a@zqz.ca", time.Now(), "gophers")
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/search.json?network=zkpq&channel=%23deviate&q=gophers&nick=AARON", nil))
+	var found []searchResult
+	if err = json.Unmarshal(w.Body.Bytes(), &found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Nick != "aaron" || found[0].Message != "I like Gophers" {
+		t.Error("Should find the message of aaron in #deviate, found", found)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/search.json?network=zkpq&channel=%23deviate&q=%28&regexp=1", nil))
+	if w.Code != 400 {
+		t.Error("Should refuse a bad regexp, served", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.ServeHTTP(w, httptest.NewRequest("GET", "/?network=zkpq&channel=%23deviate", nil))
+	if !strings.Contains(w.Body.String(), `href="/search.json?network=zkpq&amp;channel=%23deviate&amp;q=gophers"`) {
+		t.Error("Should link the words to their search, is", w.Body.String())
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/aggregate"
+	"github.com/DylanJ/stats/elastic"
+	"github.com/DylanJ/stats/influx"
+	"github.com/DylanJ/stats/statsbot"
+)
+
+var serveUsage = `
+serve connects to the irc networks of the configuration, joins their channels
+and collects stats about everything that is said into data.db, saving it every
+save_interval. The report and exports of the stats are served on the listen
+address of the configuration when set:
+
+  /             the report of every channel, or of ?network= and ?channel=,
+                in the locale of the configuration or ?locale=
+  /export.json  the stats of the users of every channel, see ircstats export
+  /export.csv   the same as csv
+  /search.json  the messages of ?network= and ?channel= that match ?q=, or
+                the regexp ?q= with ?regexp=1, of ?nick= when given, the
+                ?max= most recent; only those still in the raw store
+  /top.json     the ?max= top talkers of ?network= and ?channel=, with the
+                cursor of the next page to pass as ?cursor=
+
+The networks of the owners of the configuration are counted in the databases
+of their namespaces instead, their report and exports are served the same
+under /o/<owner>/, eg. /o/acme/export.json.
+
+The private channels of the configuration are only served to the api_tokens
+allowed them, given as "Authorization: Bearer <token>" or ?token=; they're
+left out of the report and exports of the others, and not found by
+/search.json and /top.json. Unknown tokens are turned away, and so are the
+requests without a token when require_token.
+
+ircstats serve [options]
+`
+
+// serve runs the serve command with its arguments.
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configFile := fs.String("config", "ircstats.json", "The configuration file to load.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s serve:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, serveUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	conf, err := loadConfig(*configFile)
+	if err != nil {
+		return fmt.Errorf("Failed loading configuration: %v", err)
+	}
+
+	slog.SetDefault(conf.logger())
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+	logRepaired(s)
+
+	stop := make(chan struct{})
+	var sinks stats.MessageSinks
+	if conf.Elastic != nil {
+		sink := elastic.New(conf.Elastic.URL)
+		if len(conf.Elastic.Index) > 0 {
+			sink.Index = conf.Elastic.Index
+		}
+		sink.Username, sink.Password = conf.Elastic.Username, conf.Elastic.Password
+		sinks = append(sinks, sink)
+		go sink.Run(stop, func(err error) {
+			slog.Error("Failed indexing messages", "err", err)
+		})
+	}
+	if conf.Forward != nil {
+		f := aggregate.NewForwarder(conf.Forward.URL, conf.Forward.Token)
+		sinks = append(sinks, f)
+		go f.Run(stop, func(err error) {
+			slog.Error("Failed forwarding messages", "err", err)
+		})
+	}
+
+	h := statsbot.New(s)
+	h.CountSelf = conf.CountSelf
+	h.Limiter, _ = conf.commandLimiter()
+
+	ns := conf.namespaces()
+	owners, err := openOwners(conf, ns)
+	if err != nil {
+		return err
+	}
+	defer owners.close()
+
+	var clients []*client
+	for _, n := range conf.Networks {
+		nstats, nh := s, h
+		if o, ok := owners[n.Owner]; ok {
+			nstats, nh = o.stats, o.handler
+		}
+		c := newClient(n, nh)
+		clients = append(clients, c)
+
+		if n.Announce != nil {
+			a := statsbot.NewAnnouncer(nstats, c, n.Name)
+			a.Summary, a.Records, a.Karma = n.Announce.Summary, n.Announce.Records, n.Announce.Karma
+			a.Channels = n.Announce.Channels
+			a.Location, _ = n.Announce.location()
+			go a.Run(stop)
+		}
+	}
+	for owner, o := range owners {
+		opts := conf.ownerOptions(owner)
+		if o.raw != nil {
+			opts.RawStore = o.raw
+		}
+		o.stats.SetOptions(opts)
+	}
+
+	opts := conf.options()
+	var raw *stats.FileRawStore
+	if len(conf.RawStore) > 0 {
+		if raw, err = stats.OpenFileRawStore(conf.RawStore); err != nil {
+			return fmt.Errorf("Failed opening the raw store: %v", err)
+		}
+		defer raw.Close()
+		opts.RawStore = raw
+	}
+	if len(sinks) > 0 {
+		opts.Sink = sinks
+	}
+	s.SetOptions(opts)
+	if len(conf.DebugListen) > 0 {
+		s.PublishExpvar("ircstats")
+		go func() {
+			slog.Error("Debug server stopped", "err", http.ListenAndServe(conf.DebugListen, nil))
+		}()
+	}
+	l, _ := conf.locale()
+	if len(conf.Listen) > 0 {
+		srv := newReportServer(s, l)
+		if len(owners) > 0 {
+			srv = newOwnersServer(s, ns, l)
+		}
+		srv = conf.access().Handler(srv)
+		go func() {
+			slog.Error("Report server stopped", "err", http.ListenAndServe(conf.Listen, srv))
+		}()
+	}
+	for _, c := range clients {
+		go c.run()
+	}
+
+	if conf.Aggregate != nil {
+		srv := aggregate.NewServer(s, conf.Aggregate.Collectors)
+		if len(conf.Aggregate.Listen) > 0 {
+			go func() {
+				slog.Error("Aggregation server stopped", "err", http.ListenAndServe(conf.Aggregate.Listen, srv))
+			}()
+		}
+		if n := conf.Aggregate.NATS; n != nil {
+			src := aggregate.NewNATSSource(srv, n.URL)
+			if len(n.Subject) > 0 {
+				src.Subject = n.Subject
+			}
+			src.Queue = n.Queue
+			go src.Run(stop, func(err error) {
+				slog.Error("Lost the nats connection", "err", err)
+			})
+		}
+	}
+
+	if conf.Influx != nil {
+		e := influx.New(s, conf.Influx.URL)
+		e.Token = conf.Influx.Token
+		e.Interval, _ = conf.Influx.interval()
+		go e.Run(stop, func(err error) {
+			slog.Error("Failed pushing to influx", "err", err)
+		})
+	}
+
+	for _, dc := range conf.Digests {
+		ds := s
+		if o, ok := owners[conf.networkOwner(dc.Network)]; ok {
+			ds = o.stats
+		}
+		for _, d := range dc.newDigesters(ds, l) {
+			go d.Run(stop, func(err error) {
+				slog.Error("Failed posting the digest", "err", err)
+			})
+		}
+	}
+
+	interval, _ := conf.saveInterval()
+	ticker := time.NewTicker(interval)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	for {
+		select {
+		case <-ticker.C:
+			prune(s)
+			save(s)
+			flush(raw)
+			for _, o := range owners {
+				prune(o.stats)
+				save(o.stats)
+				flush(o.raw)
+			}
+		case <-signals:
+			close(stop)
+			if err := s.SaveNow(); err != nil {
+				slog.Error("Failed saving", "err", err)
+			}
+			flush(raw)
+			if err := ns.SaveNow(); err != nil {
+				slog.Error("Failed saving the owners", "err", err)
+			}
+			for _, o := range owners {
+				flush(o.raw)
+			}
+			return nil
+		}
+	}
+}
+
+// owner is the namespace of an owner as it's served: their stats, the
+// handler of the commands of their networks and their raw store.
+type owner struct {
+	stats   *stats.Stats
+	handler *statsbot.Handler
+	raw     *stats.FileRawStore
+}
+
+// owners are the namespaces served by owner.
+type owners map[string]*owner
+
+// openOwners opens the stats and raw store of every owner of the
+// configuration.
+func openOwners(conf *config, ns *stats.Namespaces) (owners, error) {
+	opened := make(owners, len(conf.Owners))
+	for name, oc := range conf.Owners {
+		s, err := ns.Open(name)
+		if err != nil {
+			opened.close()
+			return nil, fmt.Errorf("Failed opening the stats of %s: %v", name, err)
+		}
+		logRepaired(s)
+		o := &owner{stats: s, handler: statsbot.New(s)}
+		o.handler.CountSelf = conf.CountSelf
+		o.handler.Limiter, _ = conf.commandLimiter()
+		if len(oc.RawStore) > 0 {
+			if o.raw, err = stats.OpenFileRawStore(oc.RawStore); err != nil {
+				opened.close()
+				return nil, fmt.Errorf("Failed opening the raw store of %s: %v", name, err)
+			}
+		}
+		opened[name] = o
+	}
+	return opened, nil
+}
+
+// logRepaired logs the problems repaired when the stats were loaded, they're
+// repaired in the database once it's saved.
+func logRepaired(s *stats.Stats) {
+	for _, err := range s.Repaired() {
+		slog.Warn("Repaired the stats", "err", err, "path", s.Path())
+	}
+}
+
+// close closes the raw stores of the owners.
+func (all owners) close() {
+	for _, o := range all {
+		if o.raw != nil {
+			o.raw.Close()
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+var shellUsage = `
+shell reads data.db and answers queries typed at its prompt, for poking at the
+stats without writing Go or serving them. It reads data.db as it was last
+saved, what serve collected since isn't in it yet. Type help at the prompt for
+the queries.
+
+ircstats shell [options]
+`
+
+var shellHelp = `Queries, a channel is looked up in the current network, or in every network
+when there's none:
+  networks                   lists the networks
+  channels [network]         lists the channels of a network
+  network <network>          makes a network the current one
+  top <network|channel> [n]  lists who said the most
+  words <network|channel> [n]
+  urls <network|channel> [n] lists the words or urls said the most
+  seen <nick>                tells when a nick was last seen
+  user <nick>                tells what a nick said
+  quit                       leaves the shell`
+
+// defaultShellTop is how long the lists of the shell are unless asked for
+// another number.
+const defaultShellTop = 10
+
+// shell runs the shell command with its arguments.
+func shell(args []string) error {
+	fs := flag.NewFlagSet("shell", flag.ExitOnError)
+	network := fs.String("network", "", "The network the queries start in, none when empty.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s shell:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, shellUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	sh := &queryShell{stats: s, network: *network, out: os.Stdout, prompt: "ircstats> "}
+	return sh.run(os.Stdin)
+}
+
+// queryShell answers the queries of the shell command.
+type queryShell struct {
+	stats *stats.Stats
+	// network is the current network, the channels of queries are looked
+	// up in it.
+	network string
+	out     io.Writer
+	prompt  string
+}
+
+// run answers the queries read until the input ends or quit is typed.
+func (sh *queryShell) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(sh.out, sh.prompt)
+		if !scanner.Scan() {
+			fmt.Fprintln(sh.out)
+			return scanner.Err()
+		}
+
+		args := strings.Fields(scanner.Text())
+		if len(args) == 0 {
+			continue
+		}
+		if cmd := strings.ToLower(args[0]); cmd == "quit" || cmd == "exit" {
+			return nil
+		}
+		fmt.Fprintln(sh.out, sh.query(args))
+	}
+}
+
+// query answers a query, split into its words.
+func (sh *queryShell) query(args []string) string {
+	cmd, args := strings.ToLower(args[0]), args[1:]
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+	n := defaultShellTop
+	if len(args) > 1 {
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return fmt.Sprintf("%s isn't a number of entries", args[1])
+		}
+	}
+
+	if cmd == "network" {
+		if len(args) == 0 {
+			sh.network = ""
+			return "No current network."
+		}
+		var found bool
+		sh.stats.View(func(tx *stats.ReadTx) {
+			found = tx.GetNetwork(args[0]) != nil
+		})
+		if !found {
+			return "There's no network " + args[0]
+		}
+		sh.network = args[0]
+		return "The current network is " + args[0]
+	}
+
+	var reply string
+	sh.stats.View(func(tx *stats.ReadTx) {
+		switch cmd {
+		case "help":
+			reply = shellHelp
+		case "networks":
+			reply = sh.networks(tx)
+		case "channels":
+			reply = sh.channels(tx, arg(0))
+		case "top":
+			reply = sh.top(tx, arg(0), n)
+		case "words", "urls":
+			reply = sh.tokens(tx, cmd, arg(0), n)
+		case "seen":
+			reply = sh.seen(tx, arg(0))
+		case "user":
+			reply = sh.user(tx, arg(0))
+		default:
+			reply = fmt.Sprintf("Unknown query %s, type help for the queries.", cmd)
+		}
+	})
+	return reply
+}
+
+func (sh *queryShell) networks(tx *stats.ReadTx) string {
+	networks := make([]*stats.Network, 0, len(tx.Networks))
+	for _, n := range tx.Networks {
+		networks = append(networks, n)
+	}
+	if len(networks) == 0 {
+		return "There are no networks yet."
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+
+	lines := make([]string, len(networks))
+	for i, n := range networks {
+		lines[i] = fmt.Sprintf("%s: %d messages in %d channels by %d users", n.Name, n.MessageCount, len(n.ChannelIDs), len(n.UserIDs))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (sh *queryShell) channels(tx *stats.ReadTx, network string) string {
+	if len(network) == 0 {
+		network = sh.network
+	}
+	n := tx.GetNetwork(network)
+	if n == nil {
+		return "Which network? Name one, or make one current with network <network>."
+	}
+
+	list := channels(tx, n.Name, "")
+	if len(list) == 0 {
+		return n.Name + " has no channels."
+	}
+	lines := make([]string, len(list))
+	for i, c := range list {
+		lines[i] = fmt.Sprintf("%s: %d messages by %d users", c.Name, c.MessageCount, len(c.UserIDs))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// target finds the network or channel a query is about: the network named,
+// or the channel of the name in the current network or in the only network
+// it's in.
+func (sh *queryShell) target(tx *stats.ReadTx, name string) (*stats.Network, *stats.Channel, string) {
+	switch {
+	case len(name) == 0:
+		return nil, nil, "Which network or channel?"
+	case !strings.ContainsRune("#&!+", rune(name[0])):
+		if n := tx.GetNetwork(name); n != nil {
+			return n, nil, ""
+		}
+		return nil, nil, "There's no network " + name
+	}
+
+	found := channels(tx, sh.network, name)
+	switch {
+	case len(found) == 0 && len(sh.network) > 0:
+		return nil, nil, fmt.Sprintf("There's no channel %s on %s", name, sh.network)
+	case len(found) == 0:
+		return nil, nil, "There's no channel " + name
+	case len(found) > 1:
+		return nil, nil, fmt.Sprintf("%s is on %d networks, choose one with network <network>", name, len(found))
+	}
+	return tx.Networks[found[0].NetworkID], found[0], ""
+}
+
+func (sh *queryShell) top(tx *stats.ReadTx, name string, n int) string {
+	network, c, problem := sh.target(tx, name)
+	if len(problem) > 0 {
+		return problem
+	}
+
+	var users []channelUser
+	if c != nil {
+		users = channelUsers(tx, c)
+	} else {
+		for _, id := range network.UserIDs {
+			if u, ok := tx.Users[id]; ok {
+				users = append(users, channelUser{u, u.Nick})
+			}
+		}
+		sort.Slice(users, func(i, j int) bool {
+			if users[i].Lines != users[j].Lines {
+				return users[i].Lines > users[j].Lines
+			}
+			return users[i].Nick < users[j].Nick
+		})
+	}
+	if len(users) == 0 {
+		return "Nobody said anything in " + name
+	}
+
+	users = users[:stats.Limit(n, len(users))]
+	lines := make([]string, len(users))
+	for i, u := range users {
+		lines[i] = fmt.Sprintf("%d. %s: %d lines, %d words", i+1, u.Nick, u.Lines, u.Words)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (sh *queryShell) tokens(tx *stats.ReadTx, kind, name string, n int) string {
+	network, c, problem := sh.target(tx, name)
+	if len(problem) > 0 {
+		return problem
+	}
+
+	var words, urls stats.TopTokenArray
+	if c != nil {
+		words, urls = c.WordCounter.TopN(n), c.URLCounter.TopN(n)
+	} else {
+		words, urls = network.WordCounter.TopN(n), network.URLCounter.TopN(n)
+	}
+	top := words
+	if kind == "urls" {
+		top = urls
+	}
+	if len(top) == 0 {
+		return fmt.Sprintf("No %s were counted in %s", kind, name)
+	}
+
+	lines := make([]string, len(top))
+	for i, t := range top {
+		lines[i] = fmt.Sprintf("%d. %s (%d)", i+1, t.Token, t.Count)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// users finds the users with the nick, in the current network or in every
+// network when there's none.
+func (sh *queryShell) users(tx *stats.ReadTx, nick string) []*stats.User {
+	var users []*stats.User
+	for _, n := range tx.Networks {
+		if len(sh.network) > 0 && !strings.EqualFold(n.Name, sh.network) {
+			continue
+		}
+		if u := tx.GetUser(n.Name, nick); u != nil {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].NetworkID < users[j].NetworkID })
+	return users
+}
+
+func (sh *queryShell) seen(tx *stats.ReadTx, nick string) string {
+	if len(nick) == 0 {
+		return "Who? seen <nick>"
+	}
+
+	users := sh.users(tx, nick)
+	if len(users) == 0 {
+		return "Nobody has seen " + nick
+	}
+	lines := make([]string, len(users))
+	for i, u := range users {
+		ago := time.Since(u.LastSeen) / time.Second * time.Second
+		lines[i] = fmt.Sprintf("%s was last seen on %s %v ago, at %s", u.Nick, tx.Networks[u.NetworkID].Name, ago, u.LastSeen.Format(time.RFC3339))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (sh *queryShell) user(tx *stats.ReadTx, nick string) string {
+	if len(nick) == 0 {
+		return "Who? user <nick>"
+	}
+
+	users := sh.users(tx, nick)
+	if len(users) == 0 {
+		return "Nobody has seen " + nick
+	}
+	lines := make([]string, len(users))
+	for i, u := range users {
+		lines[i] = fmt.Sprintf("%s on %s: %d lines, %d words, %.1f words per line, in %d channels",
+			u.Nick, tx.Networks[u.NetworkID].Name, u.Lines, u.Words, u.WordsPerLine(), len(u.ChannelUsers))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestQueryShell(t *testing.T) {
+	t.Parallel()
+
+	sh := &queryShell{stats: exportStats(t)}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"networks", "zkpq: 4 messages in 2 channels by 2 users"},
+		{"channels", "Which network?"},
+		{"channels zkpq", "#bots: 1 messages by 1 users\n#deviate: 3 messages by 2 users"},
+		{"top #bots", "1. dylan: 1 lines, 1 words"},
+		{"top #deviate", "#deviate is on 2 networks"},
+		{"top zkpq 1", "1. aaron: 2 lines, 4 words"},
+		{"top zkpq many", "many isn't a number"},
+		{"urls #bots", "No urls were counted in #bots"},
+		{"seen DYLAN", "dylan was last seen on zkpq"},
+		{"seen mallory", "Nobody has seen mallory"},
+		{"user aaron", "aaron on zkpq: 2 lines, 4 words, 2.0 words per line, in 1 channels"},
+		{"network nowhere", "There's no network nowhere"},
+		{"network zkpq", "The current network is zkpq"},
+		{"top #deviate 5", "1. aaron: 2 lines, 4 words\n2. dylan: 1 lines, 2 words"},
+		{"words #deviate 1", "1. hello (1)"},
+		{"top #nowhere", "There's no channel #nowhere on zkpq"},
+		{"drop tables", "Unknown query drop"},
+	}
+	for _, test := range tests {
+		if got := sh.query(strings.Fields(test.query)); !strings.Contains(got, test.want) {
+			t.Errorf("%s should answer %q, answered %q", test.query, test.want, got)
+		}
+	}
+}
+
+func TestQueryShell_run(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	sh := &queryShell{stats: exportStats(t), network: "other", out: &out, prompt: "> "}
+	if err := sh.run(strings.NewReader("\ntop #deviate\nquit\ntop zkpq\n")); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "> > 1. scott: 1 lines, 1 words\n> " {
+		t.Errorf("Should answer the queries until quit, answered %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/DylanJ/stats"
+)
+
+var validateUsage = `
+validate checks that every channel of data.db decodes, and that the
+invariants tying its networks, channels and users together hold, printing the
+problems found. Those with the invariants are repaired when data.db is loaded,
+-repair saves the repaired data.db. The channels that fail to decode are kept
+as they are. It exits with an error while problems remain.
+
+ircstats validate [options]
+`
+
+// validate runs the validate command with its arguments.
+func validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "Save data.db with the problems repaired.")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s validate:\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, validateUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	s, err := stats.NewStats()
+	if err != nil {
+		return err
+	}
+
+	repaired, broken := validateStats(s)
+	for _, err := range repaired {
+		fmt.Println(err)
+	}
+	for _, err := range broken {
+		fmt.Println(err)
+	}
+
+	if *repair && len(repaired) > 0 {
+		if err = s.SaveNow(); err != nil {
+			return err
+		}
+		fmt.Printf("Saved data.db with %d problems repaired.\n", len(repaired))
+		repaired = nil
+	}
+
+	if n := len(repaired) + len(broken); n > 0 {
+		return fmt.Errorf("Found %d problems in data.db.", n)
+	}
+	fmt.Println("data.db is valid.")
+	return nil
+}
+
+// validateStats decodes every channel of s and validates it, returning the
+// problems that were repaired and the channels that failed to decode.
+func validateStats(s *stats.Stats) (repaired, broken []error) {
+	s.View(func(tx *stats.ReadTx) {
+		for id := range tx.Channels {
+			tx.Channel(id)
+		}
+	})
+
+	repaired = append(s.Repaired(), s.Validate()...)
+	return repaired, s.LoadErrors()
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestValidateStats(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", time.Now(), "hello")
+
+	if repaired, broken := validateStats(s); len(repaired) > 0 || len(broken) > 0 {
+		t.Error("Should find no problems:", repaired, broken)
+	}
+
+	s.Update(func(tx *stats.WriteTx) {
+		n := tx.GetNetwork("zkpq")
+		n.ChannelIDs = append(n.ChannelIDs, 42)
+	})
+	repaired, broken := validateStats(s)
+	if len(repaired) != 1 || len(broken) > 0 {
+		t.Error("Should repair the missing channel:", repaired, broken)
+	}
+}
@@ -8,7 +8,7 @@ import (
 func TestConsecutiveLines(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Msg, network, channel, "aaron", time.Now(), "some foo")
 	s.AddMessage(Msg, network, channel, "aaron", time.Now(), "some foo")
 	s.AddMessage(Msg, network, channel, "zamn", time.Now(), "some foo")
@@ -31,4 +31,9 @@ func TestConsecutiveLines(t *testing.T) {
 	if cl.TopUsers[0].Count != 3 {
 		t.Error("Top user should have 3 consecutive lines.")
 	}
+
+	s.AddMessage(Msg, network, channel, "aaron", time.Now(), "some foo")
+	if cl = s.Channels[1].ConsecutiveLines; cl.TopUsers[0].Token != "aaron" || cl.TopUsers[0].Count != 3 {
+		t.Error("Should keep the longest run of a user:", cl.TopUsers)
+	}
 }
@@ -0,0 +1,20 @@
+package stats
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader reads until the context is done, so long reads can be
+// cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
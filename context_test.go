@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStats_contextDone(t *testing.T) {
+	var b bytes.Buffer
+	fileOpener = &fakeFileOpener{&b}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.SaveNowContext(ctx); err != context.Canceled {
+		t.Error("Should give up saving once the context is done, got", err)
+	}
+	if err := s.SaveContext(ctx); err != context.Canceled {
+		t.Error("Should give up the save, got", err)
+	}
+	if b.Len() > 0 {
+		t.Error("Should not write data.db once given up on.")
+	}
+
+	if err := s.SaveNow(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStatsContext(ctx); err == nil {
+		t.Error("Should give up loading once the context is done.")
+	}
+	if loaded, err := NewStatsContext(context.Background()); err != nil || loaded.GetChannel(network, channel) == nil {
+		t.Error("Should load the stats:", err)
+	}
+}
@@ -0,0 +1,54 @@
+package stats
+
+import "time"
+
+const defaultSilenceThreshold = 30 * time.Minute
+
+// ConversationStarters credits users whose messages break a silence in a
+// channel. The counter is keyed by nick so Top is the leaderboard.
+type ConversationStarters struct {
+	TokenCounter
+	LastMessage time.Time
+}
+
+func NewConversationStarters() ConversationStarters {
+	return ConversationStarters{
+		TokenCounter: NewTokenCounter(),
+	}
+}
+
+// addMessage reports whether the message started a conversation, meaning
+// that nobody had said anything for at least the silence threshold.
+func (cs *ConversationStarters) addMessage(message *Message, user *User, silence time.Duration) bool {
+	if message.Date.Before(cs.LastMessage) {
+		return false
+	}
+
+	last := cs.LastMessage
+	cs.LastMessage = message.Date
+
+	if last.IsZero() || message.Date.Sub(last) < silence {
+		return false
+	}
+
+	cs.addToken(user.Nick)
+	user.ConversationStarts++
+	return true
+}
+
+// silenceThreshold returns how long a channel must be silent before a
+// message counts as starting a conversation.
+func (o Options) silenceThreshold() time.Duration {
+	if o.SilenceThreshold <= 0 {
+		return defaultSilenceThreshold
+	}
+
+	return o.SilenceThreshold
+}
+
+// addStarter credits the user when their message starts a conversation.
+func (s *Stats) addStarter(c *Channel, u *User, cu *User, message *Message) {
+	if c.Starters.addMessage(message, u, s.opts.silenceThreshold()) && cu != nil {
+		cu.ConversationStarts++
+	}
+}
@@ -0,0 +1,57 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConversationStarters(t *testing.T) {
+	t.Parallel()
+
+	cs := NewConversationStarters()
+	u := &User{Nick: "bob"}
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	if cs.addMessage(&Message{Date: start}, u, time.Hour) {
+		t.Error("The very first message should not count.")
+	}
+
+	if cs.addMessage(&Message{Date: start.Add(59 * time.Minute)}, u, time.Hour) {
+		t.Error("Should not count messages before the silence threshold.")
+	}
+
+	if !cs.addMessage(&Message{Date: start.Add(3 * time.Hour)}, u, time.Hour) {
+		t.Error("Should count messages breaking the silence.")
+	}
+
+	if cs.addMessage(&Message{Date: start}, u, time.Hour) {
+		t.Error("Should ignore messages from the past.")
+	}
+
+	if u.ConversationStarts != 1 || cs.All["bob"] != 1 {
+		t.Error("Should have credited bob once.")
+	}
+}
+
+func TestStats_ConversationStarters(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{SilenceThreshold: 10 * time.Minute})
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	s.AddMessage(Msg, network, channel, "alice", start, "hi")
+	s.AddMessage(Join, network, channel, hostmask, start.Add(20*time.Minute), "")
+	s.AddMessage(Msg, network, channel, hostmask, start.Add(30*time.Minute), "anyone here?")
+	s.AddMessage(Msg, network, channel, "alice", start.Add(31*time.Minute), "yes")
+
+	c := s.GetChannel(network, channel)
+	if len(c.Starters.Top) != 1 || c.Starters.Top[0].Token != nick {
+		t.Error("Should have credited phish.", c.Starters.Top)
+	}
+
+	u := s.GetUser(network, nick)
+	if u.ConversationStarts != 1 || u.ChannelUsers[channel].ConversationStarts != 1 {
+		t.Error("Should count the start for the user.")
+	}
+}
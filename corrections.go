@@ -0,0 +1,86 @@
+package stats
+
+import (
+	"regexp"
+	"strings"
+)
+
+var correctionRegex = regexp.MustCompile(`^s/((?:\\/|[^/])+)/((?:\\/|[^/])*)(?:/([gi]*))?$`)
+
+// correction is a sed-style s/old/new/ correction of a previous message.
+type correction struct {
+	old    *regexp.Regexp
+	new    string
+	global bool
+}
+
+// parseCorrection returns the correction described by a message, or nil if
+// the message is not a correction.
+func parseCorrection(message string) *correction {
+	m := correctionRegex.FindStringSubmatch(strings.TrimSpace(message))
+	if m == nil {
+		return nil
+	}
+
+	old := regexp.QuoteMeta(strings.Replace(m[1], `\/`, "/", -1))
+	if strings.Contains(m[3], "i") {
+		old = "(?i)" + old
+	}
+
+	return &correction{
+		old:    regexp.MustCompile(old),
+		new:    strings.Replace(m[2], `\/`, "/", -1),
+		global: strings.Contains(m[3], "g"),
+	}
+}
+
+// apply returns the corrected text and whether the correction matched.
+func (c *correction) apply(text string) (string, bool) {
+	if !c.old.MatchString(text) {
+		return text, false
+	}
+
+	if c.global {
+		return c.old.ReplaceAllLiteralString(text, c.new), true
+	}
+
+	loc := c.old.FindStringIndex(text)
+	return text[:loc[0]] + c.new + text[loc[1]:], true
+}
+
+// addCorrection counts a correction if it applies to the user's previous
+// message, optionally rewriting that message so quotes show what was meant.
+func (s *Stats) addCorrection(n *Network, c *Channel, u *User, cu *User, corr *correction) {
+	previous := u.Quotes.Last
+	if cu != nil {
+		previous = cu.Quotes.Last
+	}
+
+	if previous.ID == 0 || previous.Kind != Msg {
+		return
+	}
+
+	corrected, ok := corr.apply(previous.Message)
+	if !ok {
+		return
+	}
+
+	u.SelfCorrections++
+	if cu != nil {
+		cu.SelfCorrections++
+	}
+	if c != nil {
+		c.Corrections++
+	}
+
+	if s.opts.ApplyCorrections {
+		n.Quotes.correct(previous.ID, corrected)
+		u.Quotes.correct(previous.ID, corrected)
+		if cu != nil {
+			cu.Quotes.correct(previous.ID, corrected)
+		}
+		if c != nil {
+			c.Quotes.correct(previous.ID, corrected)
+		}
+	}
+}
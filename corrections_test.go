@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCorrection_parse(t *testing.T) {
+	t.Parallel()
+
+	if c := parseCorrection("this is not s/a/correction"); c != nil {
+		t.Error("Should not parse a regular message.")
+	}
+
+	tests := []struct {
+		correction string
+		text       string
+		expect     string
+		ok         bool
+	}{
+		{"s/teh/the/", "teh cat and teh dog", "the cat and teh dog", true},
+		{"s/teh/the", "teh cat", "the cat", true},
+		{"s/teh/the/g", "teh cat and teh dog", "the cat and the dog", true},
+		{"s/TEH/the/i", "teh cat", "the cat", true},
+		{`s/a\/b/c/`, "a/b", "c", true},
+		{"s/.*/x/", "hello", "hello", false},
+		{"s/dog//", "cat dog", "cat ", true},
+	}
+
+	for _, test := range tests {
+		c := parseCorrection(test.correction)
+		if c == nil {
+			t.Errorf("Should parse %q", test.correction)
+			continue
+		}
+
+		if got, ok := c.apply(test.text); got != test.expect || ok != test.ok {
+			t.Errorf("%q on %q, Expected: %q %v, Got: %q %v",
+				test.correction, test.text, test.expect, test.ok, got, ok)
+		}
+	}
+}
+
+func TestStats_AddCorrection(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "i love teh internet")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "s/teh/the/")
+
+	u := s.Users[1]
+	if u.SelfCorrections != 1 {
+		t.Error("Should count the self correction.")
+	}
+
+	if s.Channels[1].Corrections != 1 {
+		t.Error("Should count the correction in the channel.")
+	}
+
+	if u.ChannelUsers[channel].Quotes.Last.Message != "s/teh/the/" {
+		t.Error("Should not apply corrections by default.")
+	}
+
+	s.SetOptions(Options{ApplyCorrections: true})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "i love teh internet")
+	// the correction becomes the last quote, the random ones show it
+	cu := u.ChannelUsers[channel]
+	u.Quotes.Random, cu.Quotes.Random = u.Quotes.Last, cu.Quotes.Last
+	s.addCorrection(s.Networks[1], s.Channels[1], u, cu, parseCorrection("s/teh/the/"))
+
+	if q := u.Quotes.Random.Message; q != "i love the internet" {
+		t.Error("Should have applied the correction, Got:", q)
+	}
+	if q := cu.Quotes.Random.Message; q != "i love the internet" {
+		t.Error("Should have applied the correction to the channel's user, Got:", q)
+	}
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "s/nothing/here/")
+
+	if u.SelfCorrections != 2 {
+		t.Error("Should not count corrections that don't apply.")
+	}
+}
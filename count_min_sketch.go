@@ -0,0 +1,83 @@
+package stats
+
+import "hash/fnv"
+
+// SketchOptions configure the size of a count-min sketch. A sketch uses
+// Width*Depth counters regardless of how many distinct tokens it sees; a
+// larger Width lowers the overestimation and a larger Depth lowers the odds
+// of a bad estimate.
+type SketchOptions struct {
+	Width uint
+	Depth uint
+}
+
+// enabled checks if the options describe a usable sketch.
+func (o SketchOptions) enabled() bool {
+	return o.Width > 0 && o.Depth > 0
+}
+
+// CountMinSketch approximately counts tokens in a fixed amount of memory.
+// Estimates are never lower than the real count.
+type CountMinSketch struct {
+	Width  uint
+	Depth  uint
+	Counts []uint
+}
+
+// NewCountMinSketch creates a sketch with depth rows of width counters.
+func NewCountMinSketch(width, depth uint) *CountMinSketch {
+	return &CountMinSketch{
+		Width:  width,
+		Depth:  depth,
+		Counts: make([]uint, width*depth),
+	}
+}
+
+// indexes returns the counter index of the token for every row.
+func (c *CountMinSketch) indexes(token string) []uint {
+	h := fnv.New64a()
+	h.Write([]byte(token))
+	sum := h.Sum64()
+
+	// double hashing: derive every row's hash from two halves of one hash.
+	h1, h2 := uint(sum&0xffffffff), uint(sum>>32)|1
+
+	idx := make([]uint, c.Depth)
+	for i := uint(0); i < c.Depth; i++ {
+		idx[i] = i*c.Width + (h1+i*h2)%c.Width
+	}
+
+	return idx
+}
+
+// add counts the token once and returns its new estimated count. Only the
+// smallest counters are incremented (conservative update) which keeps the
+// overestimation down.
+func (c *CountMinSketch) add(token string) uint {
+	idx := c.indexes(token)
+	estimate := c.min(idx) + 1
+
+	for _, i := range idx {
+		if c.Counts[i] < estimate {
+			c.Counts[i] = estimate
+		}
+	}
+
+	return estimate
+}
+
+// Estimate returns the estimated count of a token.
+func (c *CountMinSketch) Estimate(token string) uint {
+	return c.min(c.indexes(token))
+}
+
+func (c *CountMinSketch) min(idx []uint) uint {
+	m := c.Counts[idx[0]]
+	for _, i := range idx[1:] {
+		if c.Counts[i] < m {
+			m = c.Counts[i]
+		}
+	}
+
+	return m
+}
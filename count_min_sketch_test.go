@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCountMinSketch(t *testing.T) {
+	t.Parallel()
+
+	c := NewCountMinSketch(64, 4)
+
+	if c.Estimate("foo") != 0 {
+		t.Error("Should not have seen foo.")
+	}
+
+	for i := 0; i < 10; i++ {
+		c.add("foo")
+	}
+	c.add("bar")
+
+	if c.Estimate("foo") != 10 {
+		t.Error("Should estimate foo exactly, Got:", c.Estimate("foo"))
+	}
+
+	if c.Estimate("bar") < 1 {
+		t.Error("Should never underestimate.")
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.add(fmt.Sprintf("token%d", i))
+	}
+
+	if len(c.Counts) != 64*4 {
+		t.Error("Should not grow.")
+	}
+
+	if c.Estimate("foo") < 10 {
+		t.Error("Should never underestimate.")
+	}
+}
+
+func TestTokenCounter_Bounded(t *testing.T) {
+	t.Parallel()
+
+	tc := NewTokenCounter()
+	tc.bound(SketchOptions{Width: 128, Depth: 4})
+
+	for i := 0; i < 200; i++ {
+		tc.addToken(fmt.Sprintf("rare%d", i))
+	}
+	for i := 0; i < 20; i++ {
+		tc.addToken("common")
+	}
+
+	if tc.All != nil {
+		t.Error("Should not keep exact counts.")
+	}
+
+	if tc.Top[0].Token != "common" || tc.Top[0].Count < 20 {
+		t.Error("Should track the top token, Got:", tc.Top[0])
+	}
+
+	if tc.CountOf("common") < 20 {
+		t.Error("Should estimate the count.")
+	}
+
+	if tc.Count != 220 {
+		t.Error("Should still count every token.")
+	}
+}
+
+func TestStats_TopKOptions(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{TopK: SketchOptions{Width: 256, Depth: 3}})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "tree foo http://google.com")
+
+	if c := s.Channels[1]; c.WordCounter.Sketch == nil || c.URLCounter.Sketch == nil {
+		t.Error("Channel counters should be bounded.")
+	}
+
+	if n := s.Networks[1]; n.WordCounter.CountOf("tree") != 1 {
+		t.Error("Network counters should be bounded.")
+	}
+
+	u := s.Users[1]
+	if u.WordCounter.Sketch == nil || u.ChannelUsers[channel].WordCounter.Sketch == nil {
+		t.Error("User counters should be bounded.")
+	}
+}
@@ -1,15 +1,27 @@
 package stats
 
-import "strings"
+import (
+	"strings"
+	"unicode"
+)
 
 type QuestionsCount uint
 type ExclamationsCount uint
 type AllCapsCount uint
 type BasicTextCounters struct {
+	CharClassCounters
+
 	Words   uint
 	Letters uint
 	Lines   uint
 }
+type CharClassCounters struct {
+	Digits      uint
+	Punctuation uint
+	Uppercase   uint
+	Emoji       uint
+}
+type KindTextCounters map[MsgKind]BasicTextCounters
 type SendRecvCounters struct {
 	Sent     uint
 	Received uint
@@ -81,9 +93,52 @@ func (c *BasicTextCounters) LettersPerLine() float64 {
 	return float64(c.Letters) / float64(c.Lines)
 }
 
-func countSuffixes(message string, suffix string) int {
+// addText counts a line of text said with the given kind of message.
+func (k KindTextCounters) addText(kind MsgKind, text string) {
+	k.addTokens(kind, tokenize(text))
+}
+
+// addTokens counts a line of text said with the given kind of message. Only
+// chat is counted, events such as joins and kicks aren't lines of text.
+func (k KindTextCounters) addTokens(kind MsgKind, t *Tokens) {
+	if !kind.isChat() {
+		return
+	}
+
+	c := k[kind]
+	c.addTokens(t)
+	k[kind] = c
+}
+
+// Fraction returns the fraction of lines that were of the given kind.
+func (k KindTextCounters) Fraction(kind MsgKind) float64 {
+	total := k.Total().Lines
+	if total == 0 || !kind.isChat() {
+		return 0
+	}
+
+	return float64(k[kind].Lines) / float64(total)
+}
+
+// Total adds up the text counters of every kind of chat, for the words per
+// line of messages, actions and notices alike. Events counted by stats saved
+// before they were left out aren't part of it.
+func (k KindTextCounters) Total() BasicTextCounters {
+	var total BasicTextCounters
+	for kind, c := range k {
+		if !kind.isChat() {
+			continue
+		}
+		total.Words += c.Words
+		total.Letters += c.Letters
+		total.Lines += c.Lines
+		total.CharClassCounters.add(c.CharClassCounters)
+	}
+	return total
+}
+
+func countSuffixes(words []string, suffix string) int {
 	count := 0
-	words := strings.Fields(message)
 
 	for _, word := range words {
 		if strings.HasSuffix(word, suffix) {
@@ -94,39 +149,97 @@ func countSuffixes(message string, suffix string) int {
 	return count
 }
 
-func (a *AllCapsCount) addMessage(message *Message) {
-	hasCapitalChar := false
+// minAllCaps is the least upper case letters a line needs to be shouted,
+// so that lines like "OK" or "I" aren't.
+const minAllCaps = 3
 
-	for _, c := range message.Message {
-		if c > 'A' && c < 'Z' {
-			hasCapitalChar = true
-		}
+func (a *AllCapsCount) addMessage(message *Message) {
+	if message.Kind.isChat() && message.tokens().AllCaps {
+		*a++
+	}
+}
 
-		if c > 'a' && c < 'z' {
-			return
+// isAllCaps checks if a line of text is shouted: it has upper case letters,
+// in any script, and no lower case ones. Letters without case don't count.
+func isAllCaps(text string) bool {
+	upper := 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLower(r):
+			return false
+		case unicode.IsUpper(r):
+			upper++
 		}
 	}
 
-	if hasCapitalChar {
-		*a++
-	}
+	return upper >= minAllCaps
 }
 
+// The counters of text only count chat, the text of events such as a kick's
+// reason isn't a line said.
+
 func (q *QuestionsCount) addMessage(message *Message) {
-	*q += QuestionsCount(countSuffixes(message.Message, "?"))
+	if message.Kind.isChat() {
+		*q += QuestionsCount(countSuffixes(message.tokens().Fields, "?"))
+	}
 }
 
 func (e *ExclamationsCount) addMessage(message *Message) {
-	*e += ExclamationsCount(countSuffixes(message.Message, "!"))
+	if message.Kind.isChat() {
+		*e += ExclamationsCount(countSuffixes(message.tokens().Fields, "!"))
+	}
 }
 
 // addMessage
 func (c *BasicTextCounters) addMessage(message *Message) {
-	words := strings.Fields(message.Message)
-	letters := strings.Replace(message.Message, " ", "", -1)
+	if message.Kind.isChat() {
+		c.addTokens(message.tokens())
+	}
+}
+
+// Density returns how many of every letter counted belong to a class, for
+// example c.Density(c.Punctuation).
+func (c *BasicTextCounters) Density(class uint) float64 {
+	if c.Letters == 0 {
+		return 0
+	}
+
+	return float64(class) / float64(c.Letters)
+}
 
-	// maybe use a regex to filter out ^a-z
-	c.Letters += uint(len(letters))
-	c.Words += uint(len(words))
+// addText counts the words and letters of a line of text.
+func (c *BasicTextCounters) addText(text string) {
+	c.addTokens(tokenize(text))
+}
+
+// addTokens counts the words and letters of a line of tokens.
+func (c *BasicTextCounters) addTokens(t *Tokens) {
+	c.Letters += t.Letters
+	c.Words += t.Words
+	c.CharClassCounters.add(t.Classes)
 	c.Lines++
 }
+
+// add adds up the character classes of two counters.
+func (c *CharClassCounters) add(o CharClassCounters) {
+	c.Digits += o.Digits
+	c.Punctuation += o.Punctuation
+	c.Uppercase += o.Uppercase
+	c.Emoji += o.Emoji
+}
+
+// addText tallies the character classes of a line of text.
+func (c *CharClassCounters) addText(text string) {
+	for _, r := range text {
+		switch {
+		case unicode.IsDigit(r):
+			c.Digits++
+		case unicode.IsPunct(r):
+			c.Punctuation++
+		case unicode.IsUpper(r):
+			c.Uppercase++
+		case isEmoji(r):
+			c.Emoji++
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package stats
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestModeCounters(t *testing.T) {
 	t.Parallel()
@@ -182,3 +185,138 @@ func TestAllCapsCount(t *testing.T) {
 		t.Error("Should not have added another all caps sentence.")
 	}
 }
+
+func TestIsAllCaps(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		expect bool
+	}{
+		{"ABZ", true},
+		{"AZ az", false},
+		{"zebra ABC", false},
+		{"ÉCOUTE ÇA", true},
+		{"ÉCOUTE çA", false},
+		{"ПРИВЕТ МИР", true},
+		{"ПРИВЕТ мир", false},
+		{"WHY 漢字", true},
+		{"OK", false},
+		{"I", false},
+		{"!!#$^ 123", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		if got := isAllCaps(test.text); got != test.expect {
+			t.Errorf("%q Expected: %v, Got: %v", test.text, test.expect, got)
+		}
+	}
+}
+
+func TestKindTextCounters(t *testing.T) {
+	t.Parallel()
+
+	k := make(KindTextCounters)
+
+	if k.Fraction(Action) != 0 {
+		t.Error("Should have no actions.")
+	}
+
+	k.addText(Msg, "hello there")
+	k.addText(Msg, "how are you")
+	k.addText(Msg, "good")
+	k.addText(Action, "waves")
+
+	if c := k[Msg]; c.Lines != 3 || c.Words != 6 {
+		t.Error("Should have counted the messages separately.")
+	}
+
+	if k.Fraction(Action) != 0.25 {
+		t.Error("A quarter of the lines should be actions.")
+	}
+}
+
+func TestKindTextCounters_Total(t *testing.T) {
+	t.Parallel()
+
+	k := make(KindTextCounters)
+	k.addText(Msg, "hello there")
+	k.addText(Action, "waves at 1 person")
+	k.addText(Kick, "aaron why?!")
+	k.addText(Join, "")
+	// a join counted by stats saved before events were left out
+	k[Join] = BasicTextCounters{Lines: 6}
+
+	total := k.Total()
+	if total.Lines != 2 || total.Words != 6 || total.Digits != 1 {
+		t.Errorf("Should only add up the chat, got %+v", total)
+	}
+	if wpl := total.WordsPerLine(); wpl != 3 {
+		t.Error("Should have 3 words per line, got", wpl)
+	}
+	if _, ok := k[Kick]; ok {
+		t.Error("Shouldn't count the kick as text.")
+	}
+	if k.Fraction(Msg) != 0.5 || k.Fraction(Join) != 0 {
+		t.Error("Should only count the fractions of chat:", k.Fraction(Msg), k.Fraction(Join))
+	}
+}
+
+func TestStats_eventsArentLines(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello there?")
+	s.AddMessage(Join, network, channel, hostmask, time.Now(), "")
+	s.AddMessage(Kick, network, channel, hostmask, time.Now(), "aaron get out! now!")
+	s.AddMessage(Part, network, channel, hostmask, time.Now(), "bye? BYE!")
+	s.AddMessage(Topic, network, channel, hostmask, time.Now(), "WHAT IS THIS?")
+
+	u := s.GetUser(network, nick)
+	c := s.GetChannel(network, channel)
+	if u.MessageCount != 5 || u.Lines != 1 || u.Words != 2 || u.WordsPerLine() != 2 {
+		t.Errorf("Should only count the message as a line, counted %d lines of %d words", u.Lines, u.Words)
+	}
+	if u.QuestionsCount != 1 || u.ExclamationsCount != 0 || u.AllCapsCount != 0 {
+		t.Error("Should only count the message's questions:", u.QuestionsCount, u.ExclamationsCount, u.AllCapsCount)
+	}
+	if c.QuestionsCount != 1 || c.ExclamationsCount != 0 || c.AllCapsCount != 0 {
+		t.Error("Should only count the message's questions in the channel:", c.QuestionsCount, c.ExclamationsCount, c.AllCapsCount)
+	}
+	if total := c.TextByKind.Total(); total.Lines != 1 || len(c.TextByKind) != 1 {
+		t.Error("Should only count the text of the message by kind:", c.TextByKind)
+	}
+}
+
+func TestCharClassCounters(t *testing.T) {
+	t.Parallel()
+
+	c := &BasicTextCounters{}
+
+	if c.Density(c.Digits) != 0 {
+		t.Error("Should have no density without letters.")
+	}
+
+	c.addText("Call 555-1234, NOW! 😂")
+
+	if c.Digits != 7 {
+		t.Error("Should have 7 digits, Got:", c.Digits)
+	}
+
+	if c.Punctuation != 3 {
+		t.Error("Should have 3 punctuation marks, Got:", c.Punctuation)
+	}
+
+	if c.Uppercase != 4 {
+		t.Error("Should have 4 uppercase letters, Got:", c.Uppercase)
+	}
+
+	if c.Emoji != 1 {
+		t.Error("Should have 1 emoji, Got:", c.Emoji)
+	}
+
+	if d := c.Density(c.Digits); d != 7.0/18 {
+		t.Error("Digit density is incorrect, Got:", d)
+	}
+}
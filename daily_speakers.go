@@ -0,0 +1,136 @@
+package stats
+
+import "time"
+
+const dayFormat = "2006-01-02"
+
+// speakerHours is how many hours Hours keeps, enough for the day that just
+// ended in any timezone.
+const speakerHours = 48
+
+// DailySpeakers tracks who speaks first and last on every day in a channel.
+// First and Last count the days each user ID opened or closed the channel,
+// Last includes the current day, which may still change. FirstAt and LastAt
+// let messages of the current day that arrive out of order still count. The
+// days are UTC's, Hours keeps the speakers of the last hours to find those
+// of a day in another timezone, see Between.
+type DailySpeakers struct {
+	Day         string
+	FirstUserID uint
+	LastUserID  uint
+	FirstAt     time.Time
+	LastAt      time.Time
+
+	First map[uint]uint
+	Last  map[uint]uint
+	Hours []SpeakerHour
+}
+
+// SpeakerHour is who spoke first and last in an hour, starting at Hour.
+type SpeakerHour struct {
+	Hour        time.Time
+	FirstUserID uint
+	LastUserID  uint
+	FirstAt     time.Time
+	LastAt      time.Time
+}
+
+// NewDailySpeakers initializes the counts.
+func NewDailySpeakers() DailySpeakers {
+	return DailySpeakers{
+		First: make(map[uint]uint),
+		Last:  make(map[uint]uint),
+	}
+}
+
+// addMessage
+func (d *DailySpeakers) addMessage(message *Message) {
+	d.addHour(message)
+	day := message.Date.Format(dayFormat)
+
+	switch {
+	case day < d.Day:
+		// messages of days that are already over can't change the result
+		return
+	case day > d.Day:
+		d.Day = day
+		d.FirstUserID, d.FirstAt = message.UserID, message.Date
+		d.LastUserID, d.LastAt = message.UserID, message.Date
+		d.First[message.UserID]++
+		d.Last[message.UserID]++
+	case message.Date.Before(d.FirstAt):
+		decrement(d.First, d.FirstUserID)
+		d.FirstUserID, d.FirstAt = message.UserID, message.Date
+		d.First[message.UserID]++
+	case message.Date.Before(d.LastAt):
+		// played back from earlier in the day, neither first nor last
+	case d.LastUserID != message.UserID:
+		decrement(d.Last, d.LastUserID)
+		d.LastUserID, d.LastAt = message.UserID, message.Date
+		d.Last[message.UserID]++
+	default:
+		d.LastAt = message.Date
+	}
+}
+
+// addHour counts the message in the speakers of its hour, the hours older
+// than those kept are skipped.
+func (d *DailySpeakers) addHour(message *Message) {
+	hour := message.Date.Truncate(time.Hour)
+
+	i := len(d.Hours)
+	for i > 0 && d.Hours[i-1].Hour.After(hour) {
+		i--
+	}
+	if i > 0 && d.Hours[i-1].Hour.Equal(hour) {
+		h := &d.Hours[i-1]
+		if message.Date.Before(h.FirstAt) {
+			h.FirstUserID, h.FirstAt = message.UserID, message.Date
+		}
+		if !message.Date.Before(h.LastAt) {
+			h.LastUserID, h.LastAt = message.UserID, message.Date
+		}
+		return
+	}
+
+	newest := hour
+	if len(d.Hours) > 0 && d.Hours[len(d.Hours)-1].Hour.After(newest) {
+		newest = d.Hours[len(d.Hours)-1].Hour
+	}
+	oldest := newest.Add(-speakerHours * time.Hour)
+	if !hour.After(oldest) {
+		return
+	}
+
+	d.Hours = append(d.Hours, SpeakerHour{})
+	copy(d.Hours[i+1:], d.Hours[i:])
+	d.Hours[i] = SpeakerHour{hour, message.UserID, message.UserID, message.Date, message.Date}
+
+	kept := 0
+	for kept < len(d.Hours) && !d.Hours[kept].Hour.After(oldest) {
+		kept++
+	}
+	d.Hours = append(d.Hours[:0], d.Hours[kept:]...)
+}
+
+// Between returns who spoke first and last in the hours starting from from
+// until to, among the hours kept. False is returned when nobody spoke.
+func (d DailySpeakers) Between(from, to time.Time) (first, last uint, ok bool) {
+	for _, h := range d.Hours {
+		if h.Hour.Before(from) || !h.Hour.Before(to) {
+			continue
+		}
+		if !ok {
+			first = h.FirstUserID
+		}
+		last, ok = h.LastUserID, true
+	}
+	return first, last, ok
+}
+
+// decrement lowers a count, removing it when it reaches zero.
+func decrement(counts map[uint]uint, id uint) {
+	if counts[id]--; counts[id] == 0 {
+		delete(counts, id)
+	}
+}
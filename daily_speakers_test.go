@@ -0,0 +1,90 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailySpeakers(t *testing.T) {
+	t.Parallel()
+
+	d := NewDailySpeakers()
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	d.addMessage(&Message{UserID: 1, Date: day})
+	d.addMessage(&Message{UserID: 2, Date: day.Add(time.Hour)})
+	d.addMessage(&Message{UserID: 3, Date: day.Add(2 * time.Hour)})
+
+	if d.First[1] != 1 || d.FirstUserID != 1 {
+		t.Error("User 1 should be the early bird.")
+	}
+
+	if d.Last[3] != 1 || len(d.Last) != 1 {
+		t.Error("User 3 should have the last word, Got:", d.Last)
+	}
+
+	next := day.Add(24 * time.Hour)
+	d.addMessage(&Message{UserID: 3, Date: next})
+	d.addMessage(&Message{UserID: 1, Date: day.Add(3 * time.Hour)})
+
+	if d.First[3] != 1 || d.Last[3] != 2 {
+		t.Error("User 3 should open and close the second day.")
+	}
+
+	if d.Last[1] != 0 {
+		t.Error("Should ignore messages from previous days.")
+	}
+}
+
+func TestDailySpeakers_OutOfOrder(t *testing.T) {
+	t.Parallel()
+
+	d := NewDailySpeakers()
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	d.addMessage(&Message{UserID: 2, Date: day.Add(time.Hour)})
+	d.addMessage(&Message{UserID: 3, Date: day.Add(3 * time.Hour)})
+	d.addMessage(&Message{UserID: 1, Date: day})
+	d.addMessage(&Message{UserID: 4, Date: day.Add(2 * time.Hour)})
+
+	if d.FirstUserID != 1 || d.First[1] != 1 || d.First[2] != 0 {
+		t.Error("Played back messages should still open the day:", d.First)
+	}
+
+	if d.LastUserID != 3 || d.Last[4] != 0 {
+		t.Error("Played back messages should not close the day:", d.Last)
+	}
+}
+
+func TestDailySpeakers_Between(t *testing.T) {
+	t.Parallel()
+
+	d := NewDailySpeakers()
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	d.addMessage(&Message{UserID: 1, Date: day.Add(-50 * time.Hour)})
+	d.addMessage(&Message{UserID: 2, Date: day})
+	d.addMessage(&Message{UserID: 3, Date: day.Add(30 * time.Minute)})
+	d.addMessage(&Message{UserID: 4, Date: day.Add(5 * time.Hour)})
+	d.addMessage(&Message{UserID: 5, Date: day.Add(10 * time.Minute)})
+	d.addMessage(&Message{UserID: 6, Date: day.Add(-time.Hour)})
+
+	// from 5am in utc
+	toronto := time.FixedZone("EST", -5*60*60)
+	from := time.Date(2014, 3, 1, 0, 0, 0, 0, toronto)
+	if first, last, ok := d.Between(from, from.AddDate(0, 0, 1)); !ok || first != 6 || last != 4 {
+		t.Error("Should find the speakers of the day in toronto:", first, last, ok)
+	}
+	if first, last, ok := d.Between(day, day.Add(time.Hour)); !ok || first != 2 || last != 3 {
+		t.Error("Played back messages should not change the speakers of an hour:", first, last, ok)
+	}
+	if _, _, ok := d.Between(day.AddDate(0, 0, -3), day.AddDate(0, 0, -2)); ok {
+		t.Error("Should forget the hours older than those kept:", d.Hours)
+	}
+	if first, _, ok := d.Between(from, day); !ok || first != 6 {
+		t.Error("Should keep late hours in order:", d.Hours)
+	}
+	if len(d.Hours) != 3 {
+		t.Error("Should keep the hours spoken in:", d.Hours)
+	}
+}
@@ -0,0 +1,35 @@
+package stats
+
+import "time"
+
+// checkDate checks the date of a message is within the bounds of the options,
+// see Options.MaxFutureSkew and NotBefore. A date out of bounds is clamped to
+// the bound it crossed with ClampDates, otherwise the message is rejected and
+// checkDate returns false.
+func (s *Stats) checkDate(raw *RawMessage) bool {
+	o := &s.opts
+	if raw.Date.IsZero() || o.MaxFutureSkew <= 0 && o.NotBefore.IsZero() {
+		return true
+	}
+
+	var bound time.Time
+	switch now := o.now(); {
+	case o.MaxFutureSkew > 0 && raw.Date.After(now.Add(o.MaxFutureSkew)):
+		bound = now
+	case !o.NotBefore.IsZero() && raw.Date.Before(o.NotBefore):
+		bound = o.NotBefore
+	default:
+		return true
+	}
+
+	if !o.ClampDates {
+		s.metrics.rejectedDates.Add(1)
+		o.logger().Debug("Rejected a message out of bounds", "network", raw.Network, "channel", raw.Channel, "date", raw.Date)
+		return false
+	}
+
+	s.metrics.clampedDates.Add(1)
+	o.logger().Debug("Clamped the date of a message", "network", raw.Network, "channel", raw.Channel, "date", raw.Date, "to", bound)
+	raw.Date = bound.In(raw.Date.Location())
+	return true
+}
@@ -0,0 +1,58 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats_dateBounds(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newStats()
+	s.SetOptions(Options{MaxFutureSkew: time.Hour, NotBefore: start})
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "now")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now().Add(30*time.Minute), "a little ahead")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now().Add(2*time.Hour), "from the future")
+	s.AddMessage(Msg, network, channel, hostmask, time.Unix(0, 0), "from 1970")
+	s.AddMessage(Msg, network, channel, hostmask, start, "on the first day")
+
+	c := s.GetChannel(network, channel)
+	if c.MessageCount != 3 {
+		t.Error("Should reject the messages dated out of bounds, counted", c.MessageCount)
+	}
+	if m := s.Metrics(); m.RejectedDates != 2 || m.ClampedDates != 0 {
+		t.Error("Should count the rejected dates:", m.RejectedDates, m.ClampedDates)
+	}
+	if c.LastActive.After(time.Now().Add(time.Hour)) {
+		t.Error("Shouldn't be active in the future:", c.LastActive)
+	}
+}
+
+func TestStats_dateBoundsClamp(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := newStats()
+	s.SetOptions(Options{MaxFutureSkew: time.Minute, NotBefore: start, ClampDates: true})
+
+	before := time.Now()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now().AddDate(1, 0, 0), "from the future")
+	u := s.GetUser(network, nick)
+	if u.LastSeen.Before(before) || u.LastSeen.After(time.Now()) {
+		t.Error("Should date the message from the future now, dated", u.LastSeen)
+	}
+
+	s.AddMessage(Msg, "other", channel, hostmask, time.Unix(0, 0), "from 1970")
+	if u := s.GetUser("other", nick); !u.LastSeen.Equal(start) {
+		t.Error("Should date the message from 1970 when the channel started, dated", u.LastSeen)
+	}
+
+	if m := s.Metrics(); m.ClampedDates != 2 || m.RejectedDates != 0 {
+		t.Error("Should count the clamped dates:", m.ClampedDates, m.RejectedDates)
+	}
+	if c := s.GetChannel(network, channel); c.MessageCount != 1 {
+		t.Error("Should count the clamped message, counted", c.MessageCount)
+	}
+}
@@ -0,0 +1,106 @@
+package stats
+
+import "time"
+
+// DayCounter counts the lines said on every day, to find the record days of a
+// channel. The days are UTC's, Hours splits them by the hour so they can be
+// read in any timezone, see In. Days counted before the hours were have
+// none, they're read as they were counted.
+type DayCounter struct {
+	Lines map[string]uint
+	Hours map[string][24]uint
+}
+
+// addMessage counts the message on its day.
+func (d *DayCounter) addMessage(message *Message) {
+	if d.Lines == nil {
+		d.Lines = make(map[string]uint)
+	}
+	if d.Hours == nil {
+		d.Hours = make(map[string][24]uint)
+	}
+
+	day := message.Date.Format(dayFormat)
+	d.Lines[day]++
+	hours := d.Hours[day]
+	hours[message.Date.Hour()]++
+	d.Hours[day] = hours
+}
+
+// Day returns the lines said on a day, formatted as 2006-01-02.
+func (d DayCounter) Day(day string) uint {
+	return d.Lines[day]
+}
+
+// DayIn returns the lines said on a day of a timezone, formatted as
+// 2006-01-02. The hours are on the day they start on in the timezone, with
+// offsets that aren't whole hours they're read to the hour before.
+func (d DayCounter) DayIn(day string, loc *time.Location) uint {
+	start, err := time.ParseInLocation(dayFormat, day, loc)
+	if err != nil {
+		return 0
+	}
+	end := start.AddDate(0, 0, 1)
+
+	var lines uint
+	counted := false
+	for hour := start.UTC().Truncate(time.Hour); hour.Before(end); hour = hour.Add(time.Hour) {
+		if hour.In(loc).Format(dayFormat) != day {
+			continue
+		}
+		if hours, ok := d.Hours[hour.Format(dayFormat)]; ok {
+			lines += hours[hour.Hour()]
+			counted = true
+		}
+	}
+	if !counted {
+		return d.Lines[day]
+	}
+	return lines
+}
+
+// In turns the days into those of a timezone, see DayIn.
+func (d DayCounter) In(loc *time.Location) DayCounter {
+	local := DayCounter{Lines: make(map[string]uint, len(d.Lines))}
+	for day, lines := range d.Lines {
+		hours, ok := d.Hours[day]
+		if !ok {
+			local.Lines[day] += lines
+			continue
+		}
+
+		start, err := time.Parse(dayFormat, day)
+		if err != nil {
+			continue
+		}
+		for hour, lines := range hours {
+			if lines > 0 {
+				local.Lines[start.Add(time.Duration(hour)*time.Hour).In(loc).Format(dayFormat)] += lines
+			}
+		}
+	}
+	return local
+}
+
+// Record returns the day with the most lines, ties go to the earlier day.
+// Days given in except are skipped.
+func (d DayCounter) Record(except ...string) (day string, lines uint) {
+	for k, n := range d.Lines {
+		if contains(except, k) {
+			continue
+		}
+		if n > lines || (n == lines && k < day) {
+			day, lines = k, n
+		}
+	}
+	return day, lines
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
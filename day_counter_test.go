@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDayCounter(t *testing.T) {
+	t.Parallel()
+
+	var d DayCounter
+	day := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		d.addMessage(&Message{Date: day})
+	}
+	for i := 0; i < 3; i++ {
+		d.addMessage(&Message{Date: day.AddDate(0, 0, 1)})
+	}
+	d.addMessage(&Message{Date: day.AddDate(0, 0, 2)})
+
+	if d.Day("2014-01-02") != 3 || d.Day("2013-01-01") != 0 {
+		t.Error("Wrong day counts:", d.Lines)
+	}
+
+	if day, lines := d.Record(); day != "2014-01-01" || lines != 3 {
+		t.Error("Ties should go to the earlier day:", day, lines)
+	}
+
+	if day, lines := d.Record("2014-01-01", "2014-01-02"); day != "2014-01-03" || lines != 1 {
+		t.Error("Should skip the days given:", day, lines)
+	}
+}
+
+func TestDayCounter_In(t *testing.T) {
+	t.Parallel()
+
+	var d DayCounter
+	day := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.addMessage(&Message{Date: day.Add(2 * time.Hour)})
+	d.addMessage(&Message{Date: day.Add(6 * time.Hour)})
+	d.addMessage(&Message{Date: day.Add(23 * time.Hour)})
+	// counted before the hours were
+	d.Lines["2013-12-01"] = 5
+
+	toronto := time.FixedZone("EST", -5*60*60)
+	if d.DayIn("2013-12-31", toronto) != 1 || d.DayIn("2014-01-01", toronto) != 2 {
+		t.Error("Should read the days in the timezone:", d.Hours)
+	}
+	if d.DayIn("2013-12-01", toronto) != 5 || d.DayIn("bad", toronto) != 0 {
+		t.Error("Should read the days without hours as they were counted.")
+	}
+
+	local := d.In(toronto)
+	if local.Day("2013-12-31") != 1 || local.Day("2014-01-01") != 2 || local.Day("2013-12-01") != 5 {
+		t.Error("Should turn the days into the timezone's:", local.Lines)
+	}
+	if day, lines := local.Record(); day != "2013-12-01" || lines != 5 {
+		t.Error("Should find the record of the timezone's days:", day, lines)
+	}
+
+	india := time.FixedZone("IST", (5*60+30)*60)
+	for _, day := range []string{"2014-01-01", "2014-01-02"} {
+		if d.DayIn(day, india) != d.In(india).Day(day) {
+			t.Error("Should read the hours of a day the same way:", day)
+		}
+	}
+}
+
+func TestChannel_Days(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	day := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, day, "hello")
+	s.AddMessage(Action, network, channel, hostmask, day, "waves")
+	s.AddMessage(Join, network, channel, hostmask, day, "")
+
+	if c := s.GetChannel(network, channel); c.Days.Day("2014-01-01") != 2 {
+		t.Error("Should count the chat lines of each day:", c.Days.Lines)
+	}
+}
@@ -0,0 +1,245 @@
+// Package digest sums up the week of the channels of a network and posts it
+// off irc, to a Matrix room or a Discord webhook, for the members of a
+// community who read about a channel rather than in it:
+//
+//	d := digest.NewDigester(s, &digest.DiscordWebhook{URL: hook}, "zkpq")
+//	go d.Run(stop, func(err error) { log.Println(err) })
+package digest
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/locale"
+)
+
+const dayFormat = "2006-01-02"
+
+// Digest sums up a week in a channel.
+type Digest struct {
+	Network string
+	Channel string
+	// Week is the ISO week, eg. 2014-W09, that started on From.
+	Week string
+	From time.Time
+
+	Lines uint
+	// BusiestDay is the day of the week most lines were said on.
+	BusiestDay   string
+	BusiestLines uint
+	// Champion is the nick of the user who said the most lines.
+	Champion      string
+	ChampionLines uint
+	// Topics are the topics set during the week, oldest first.
+	Topics []string
+
+	// Locale is the language String writes the digest in, English when
+	// nil.
+	Locale *locale.Locale
+}
+
+// String renders the digest as a few lines of plain text.
+func (d Digest) String() string {
+	l := d.Locale
+	var b strings.Builder
+	b.WriteString(l.Sprintf("%s in %s: %s lines.", d.Week, d.Channel, l.Number(d.Lines)))
+	if d.BusiestLines > 0 {
+		day := d.BusiestDay
+		if t, err := time.Parse(dayFormat, day); err == nil {
+			day = l.Date(t)
+		}
+		b.WriteString("\n" + l.Sprintf("Busiest day: %s with %s lines.", day, l.Number(d.BusiestLines)))
+	}
+	if d.ChampionLines > 0 {
+		b.WriteString("\n" + l.Sprintf("Top talker: %s with %s lines.", d.Champion, l.Number(d.ChampionLines)))
+	}
+	for _, topic := range d.Topics {
+		b.WriteString("\n" + l.Sprintf("New topic: %s", topic))
+	}
+	return b.String()
+}
+
+// Weekly sums up the week starting on from, a monday at midnight, in a
+// channel. The days are read in the timezone of from, the champion is the
+// one of the week in UTC. False is returned when nothing was said that week.
+// It reads the stats inside of View.
+func Weekly(tx *stats.ReadTx, c *stats.Channel, network string, from time.Time) (Digest, bool) {
+	year, week := from.ISOWeek()
+	d := Digest{
+		Network: network,
+		Channel: c.Name,
+		Week:    fmt.Sprintf("%04d-W%02d", year, week),
+		From:    from,
+	}
+
+	to := from.AddDate(0, 0, 7)
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		name := day.Format(dayFormat)
+		lines := c.Days.DayIn(name, from.Location())
+		d.Lines += lines
+		if lines > d.BusiestLines {
+			d.BusiestDay, d.BusiestLines = name, lines
+		}
+	}
+	if d.Lines == 0 {
+		return d, false
+	}
+
+	for _, champ := range c.Leaderboard.Champions() {
+		if champ.Week == d.Week {
+			if u, ok := tx.Users[champ.UserID]; ok {
+				d.Champion, d.ChampionLines = u.Nick, champ.Lines
+			}
+		}
+	}
+
+	for _, m := range c.Topics {
+		if !m.Date.Before(from) && m.Date.Before(to) {
+			d.Topics = append(d.Topics, m.Message)
+		}
+	}
+
+	return d, true
+}
+
+// Poster posts a digest somewhere.
+type Poster interface {
+	Post(d Digest) error
+}
+
+// Digester posts the digests of the channels of a network every monday at
+// midnight, for the week that just ended.
+type Digester struct {
+	Stats   *stats.Stats
+	Poster  Poster
+	Network string
+	// Channels limits the digests to these channels, when empty every
+	// channel of the network is summed up.
+	Channels []string
+	// Location is the timezone whose midnight starts the week, by default
+	// the one the stats read the network's days in.
+	Location *time.Location
+	// Locale is the language the digests are written in, English when nil.
+	Locale *locale.Locale
+}
+
+// NewDigester creates a digester posting the digests of a network.
+func NewDigester(s *stats.Stats, p Poster, network string) *Digester {
+	return &Digester{
+		Stats:   s,
+		Poster:  p,
+		Network: network,
+	}
+}
+
+// Digests sums up the week containing date in every channel that was active,
+// sorted by channel. The channels are summed up in parallel, by as many
+// workers as there are CPUs.
+func (d *Digester) Digests(date time.Time) []Digest {
+	from := d.weekStart(date)
+
+	var digests []Digest
+	d.Stats.View(func(tx *stats.ReadTx) {
+		n := tx.GetNetwork(d.Network)
+		if n == nil {
+			return
+		}
+
+		ids := make(chan uint, len(n.ChannelIDs))
+		for _, id := range n.ChannelIDs {
+			if c := tx.Channels[id]; c != nil && d.digests(c.Name) {
+				ids <- id
+			}
+		}
+		close(ids)
+
+		workers := runtime.GOMAXPROCS(0)
+		if workers > len(ids) {
+			workers = len(ids)
+		}
+
+		var mut sync.Mutex
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for i := 0; i < workers; i++ {
+			go func() {
+				defer wg.Done()
+				for id := range ids {
+					c := tx.Channel(id)
+					if c == nil {
+						continue
+					}
+					if digest, ok := Weekly(tx, c, d.Network, from); ok {
+						digest.Locale = d.Locale
+						mut.Lock()
+						digests = append(digests, digest)
+						mut.Unlock()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	sort.Slice(digests, func(i, j int) bool { return digests[i].Channel < digests[j].Channel })
+	return digests
+}
+
+// Post posts the digests of the week containing date, stopping at the first
+// that fails.
+func (d *Digester) Post(date time.Time) error {
+	for _, digest := range d.Digests(date) {
+		if err := d.Poster.Post(digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run posts the digests every week until stop is closed. Failures are passed
+// to errs when it isn't nil.
+func (d *Digester) Run(stop <-chan struct{}, errs func(error)) {
+	for {
+		timer := time.NewTimer(time.Until(d.weekStart(time.Now()).AddDate(0, 0, 7)))
+
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			// the tail of the week that just ended
+			if err := d.Post(now.Add(-12 * time.Hour)); err != nil && errs != nil {
+				errs(err)
+			}
+		}
+	}
+}
+
+// digests checks if the digester covers a channel.
+func (d *Digester) digests(channel string) bool {
+	if len(d.Channels) == 0 {
+		return true
+	}
+	for _, c := range d.Channels {
+		if strings.EqualFold(c, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// weekStart is the monday at midnight starting the week of date.
+func (d *Digester) weekStart(date time.Time) time.Time {
+	loc := d.Location
+	if loc == nil {
+		loc = d.Stats.Location(d.Network, "")
+	}
+	date = date.In(loc)
+	monday := int(date.Weekday()+6) % 7
+	return time.Date(date.Year(), date.Month(), date.Day()-monday, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,179 @@
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/locale"
+)
+
+type fakePoster struct {
+	posted []Digest
+	err    error
+}
+
+func (f *fakePoster) Post(d Digest) error {
+	f.posted = append(f.posted, d)
+	return f.err
+}
+
+// monday is the start of 2014-W10.
+var monday = time.Date(2014, 3, 3, 0, 0, 0, 0, time.UTC)
+
+func TestDigester_Digests(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron", monday.Add(-time.Hour), "last week")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan", monday.Add(time.Hour), "one")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan", monday.AddDate(0, 0, 2), "two")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "aaron", monday.AddDate(0, 0, 2), "three")
+	s.AddMessage(stats.Topic, "zkpq", "#deviate", "aaron", monday.AddDate(0, 0, 3), "go away")
+	s.AddMessage(stats.Msg, "zkpq", "#quiet", "aaron", monday.AddDate(0, 0, -3), "hello")
+	s.AddMessage(stats.Msg, "zkpq", "#ignored", "aaron", monday, "hello")
+
+	d := NewDigester(s, &fakePoster{}, "zkpq")
+	d.Channels = []string{"#DEVIATE", "#quiet"}
+	d.Location = time.UTC
+
+	digests := d.Digests(monday.AddDate(0, 0, 6))
+	if len(digests) != 1 {
+		t.Fatal("Should sum up the active channels it covers:", digests)
+	}
+
+	digest := digests[0]
+	if digest.Week != "2014-W10" || !digest.From.Equal(monday) || digest.Channel != "#deviate" {
+		t.Error("Wrong week:", digest.Week, digest.From, digest.Channel)
+	}
+	if digest.Lines != 3 || digest.BusiestDay != "2014-03-05" || digest.BusiestLines != 2 {
+		t.Error("Wrong lines:", digest.Lines, digest.BusiestDay, digest.BusiestLines)
+	}
+	if digest.Champion != "dylan" || digest.ChampionLines != 2 {
+		t.Error("Wrong champion:", digest.Champion, digest.ChampionLines)
+	}
+	if len(digest.Topics) != 1 || digest.Topics[0] != "go away" {
+		t.Error("Wrong topics:", digest.Topics)
+	}
+
+	text := digest.String()
+	for _, want := range []string{"2014-W10 in #deviate: 3 lines.", "Busiest day: 2014-03-05", "Top talker: dylan with 2 lines.", "New topic: go away"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Digest should contain %q:\n%s", want, text)
+		}
+	}
+
+	d.Locale = locale.German
+	text = d.Digests(monday)[0].String()
+	for _, want := range []string{"2014-W10 in #deviate: 3 Zeilen.", "Aktivster Tag: 05.03.2014 mit 2 Zeilen.", "Neues Thema: go away"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Digest should be in German with %q:\n%s", want, text)
+		}
+	}
+
+	if d.Digests(monday.AddDate(0, 0, 14)) != nil {
+		t.Error("Should not sum up quiet weeks.")
+	}
+	d.Network = "other"
+	if d.Digests(monday) != nil {
+		t.Error("Should not sum up unknown networks.")
+	}
+}
+
+func TestDigester_Post(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan", monday, "hello")
+	s.AddMessage(stats.Msg, "zkpq", "#other", "dylan", monday, "hello")
+
+	p := &fakePoster{}
+	d := NewDigester(s, p, "zkpq")
+	d.Location = time.UTC
+	if err := d.Post(monday); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.posted) != 2 || p.posted[0].Channel != "#deviate" || p.posted[1].Channel != "#other" {
+		t.Error("Should post every digest:", p.posted)
+	}
+
+	p = &fakePoster{err: errors.New("down")}
+	d.Poster = p
+	if err := d.Post(monday); err == nil || len(p.posted) != 1 {
+		t.Error("Should stop at the first failure:", err, len(p.posted))
+	}
+}
+
+func TestDigester_DigestsLocation(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sunday night in toronto, then monday
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan", monday.Add(2*time.Hour), "late")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan", monday.Add(6*time.Hour), "one")
+	s.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan", monday.Add(7*time.Hour), "two")
+
+	d := NewDigester(s, nil, "zkpq")
+	d.Location = time.FixedZone("EST", -5*60*60)
+	digests := d.Digests(monday.Add(6 * time.Hour))
+	if len(digests) != 1 || digests[0].Lines != 2 || digests[0].BusiestDay != "2014-03-03" {
+		t.Error("Should read the days in the digester's timezone:", digests)
+	}
+}
+
+func TestDigester_DigestsParallel(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		s.AddMessage(stats.Msg, "zkpq", fmt.Sprintf("#chan%02d", i), "dylan", monday, "hello")
+	}
+
+	d := NewDigester(s, nil, "zkpq")
+	d.Location = time.UTC
+	digests := d.Digests(monday)
+	if len(digests) != 50 {
+		t.Fatal("Should sum up every channel:", len(digests))
+	}
+	for i, digest := range digests {
+		if want := fmt.Sprintf("#chan%02d", i); digest.Channel != want || digest.Lines != 1 {
+			t.Error("Should sort the digests by channel:", digest.Channel, want)
+		}
+	}
+}
+
+func TestDigester_weekStart(t *testing.T) {
+	t.Parallel()
+
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	d := &Digester{Location: toronto}
+	// sunday night in toronto, monday in utc
+	start := d.weekStart(time.Date(2014, 3, 10, 2, 0, 0, 0, time.UTC))
+	if want := time.Date(2014, 3, 3, 0, 0, 0, 0, toronto); !start.Equal(want) {
+		t.Error("Wrong start of the week:", start)
+	}
+
+	d.Location = time.UTC
+	if start = d.weekStart(monday); !start.Equal(monday) {
+		t.Error("A monday at midnight starts its own week:", start)
+	}
+}
@@ -0,0 +1,111 @@
+package digest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// discordLimit is the most characters a Discord message may hold.
+const discordLimit = 2000
+
+var txnCount uint64
+
+// MatrixRoom posts digests as notices in a Matrix room.
+type MatrixRoom struct {
+	// Homeserver is the base url of the homeserver, eg. https://matrix.org.
+	Homeserver  string
+	AccessToken string
+	// Room is the id of the room, eg. !abc:matrix.org, the user of the
+	// access token must have joined it.
+	Room string
+
+	HTTP *http.Client
+}
+
+// Post sends the digest to the room.
+func (m *MatrixRoom) Post(d Digest) error {
+	body, err := json.Marshal(map[string]string{
+		"msgtype": "m.notice",
+		"body":    d.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	txn := fmt.Sprintf("ircstats.%d.%d", time.Now().UnixNano(), atomic.AddUint64(&txnCount, 1))
+	endpoint := strings.TrimSuffix(m.Homeserver, "/") + "/_matrix/client/r0/rooms/" +
+		url.PathEscape(m.Room) + "/send/m.room.message/" + txn
+
+	req, err := http.NewRequest("PUT", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	return send(m.HTTP, req, "matrix")
+}
+
+// DiscordWebhook posts digests through a Discord webhook.
+type DiscordWebhook struct {
+	// URL is the url of the webhook,
+	// eg. https://discord.com/api/webhooks/<id>/<token>.
+	URL string
+	// Username overrides the name of the webhook when set.
+	Username string
+
+	HTTP *http.Client
+}
+
+// Post sends the digest to the webhook's channel.
+func (w *DiscordWebhook) Post(d Digest) error {
+	content := d.String()
+	if runes := []rune(content); len(runes) > discordLimit {
+		content = string(runes[:discordLimit-1]) + "…"
+	}
+
+	msg := map[string]string{"content": content}
+	if len(w.Username) > 0 {
+		msg["username"] = w.Username
+	}
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return send(w.HTTP, req, "discord")
+}
+
+// send does a request, failing on any answer but a success.
+func send(client *http.Client, req *http.Request, service string) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: posting the digest failed: %s: %s", service, resp.Status, strings.TrimSpace(string(body)))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
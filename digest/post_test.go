@@ -0,0 +1,79 @@
+package digest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testDigest = Digest{Channel: "#deviate", Week: "2014-W10", Lines: 3}
+
+func TestMatrixRoom(t *testing.T) {
+	t.Parallel()
+
+	var path, auth string
+	var body map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.EscapedPath(), r.Header.Get("Authorization")
+		if r.Method != "PUT" {
+			t.Error("Should put the event:", r.Method)
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer srv.Close()
+
+	m := &MatrixRoom{Homeserver: srv.URL + "/", AccessToken: "hunter2", Room: "!room:zqz.ca"}
+	if err := m.Post(testDigest); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(path, "/_matrix/client/r0/rooms/%21room:zqz.ca/send/m.room.message/ircstats.") {
+		t.Error("Wrong path:", path)
+	}
+	if auth != "Bearer hunter2" {
+		t.Error("Wrong authorization:", auth)
+	}
+	if body["msgtype"] != "m.notice" || body["body"] != testDigest.String() {
+		t.Error("Wrong event:", body)
+	}
+}
+
+func TestDiscordWebhook(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]string
+	fail := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail {
+			http.Error(w, `{"message": "Unknown Webhook"}`, http.StatusNotFound)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	w := &DiscordWebhook{URL: srv.URL, Username: "ircstats"}
+	if err := w.Post(testDigest); err != nil {
+		t.Fatal(err)
+	}
+	if body["content"] != testDigest.String() || body["username"] != "ircstats" {
+		t.Error("Wrong message:", body)
+	}
+
+	long := testDigest
+	long.Topics = []string{strings.Repeat("a", 3000)}
+	if err := w.Post(long); err != nil {
+		t.Fatal(err)
+	}
+	if n := len([]rune(body["content"])); n != discordLimit {
+		t.Error("Should cut long digests to the limit:", n)
+	}
+
+	fail = true
+	if err := w.Post(testDigest); err == nil || !strings.Contains(err.Error(), "Unknown Webhook") {
+		t.Error("Should fail with the answer of discord:", err)
+	}
+}
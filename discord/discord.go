@@ -0,0 +1,301 @@
+// Package discord feeds Discord gateway events into a Stats database so
+// communities that moved from IRC to Discord keep their stats. Each guild is
+// counted as a network and its text channels as channels. The package does
+// not connect to the gateway itself, hand it the dispatch events received by
+// any gateway client.
+package discord
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Payload is a gateway payload, dispatch events have an op of 0.
+type Payload struct {
+	Op   int             `json:"op"`
+	Type string          `json:"t"`
+	Data json.RawMessage `json:"d"`
+}
+
+type user struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+type member struct {
+	User    *user  `json:"user"`
+	Nick    string `json:"nick"`
+	GuildID string `json:"guild_id"`
+}
+
+type channel struct {
+	ID      string `json:"id"`
+	GuildID string `json:"guild_id"`
+	Name    string `json:"name"`
+}
+
+type guild struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Channels []channel `json:"channels"`
+	Members  []member  `json:"members"`
+}
+
+type message struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channel_id"`
+	GuildID   string    `json:"guild_id"`
+	Author    user      `json:"author"`
+	Member    *member   `json:"member"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	Mentions  []struct {
+		user
+		Member *member `json:"member"`
+	} `json:"mentions"`
+}
+
+// Adapter maps gateway events onto AddMessage calls.
+type Adapter struct {
+	Stats *stats.Stats
+	// Networks names the guilds by id, guilds not in it are named after
+	// themselves.
+	Networks map[string]string
+	// IgnoreBots skips the messages of bot accounts.
+	IgnoreBots bool
+
+	mut      sync.Mutex
+	guilds   map[string]string // guild id to name
+	channels map[string]string // channel id to name
+	nicks    map[string]string // guild id + user id to nick
+}
+
+// New creates an adapter feeding the stats.
+func New(s *stats.Stats) *Adapter {
+	return &Adapter{
+		Stats:      s,
+		Networks:   make(map[string]string),
+		IgnoreBots: true,
+	}
+}
+
+// HandlePayload decodes a raw gateway payload and handles it if it's a
+// dispatch event.
+func (a *Adapter) HandlePayload(raw []byte) (bool, error) {
+	var p Payload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return false, err
+	}
+	if p.Op != 0 {
+		return false, nil
+	}
+
+	return a.HandleDispatch(p.Type, p.Data), nil
+}
+
+// HandleDispatch handles a dispatch event by its type, returning true if it
+// was counted in the stats.
+func (a *Adapter) HandleDispatch(typ string, data json.RawMessage) bool {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	switch typ {
+	case "GUILD_CREATE", "GUILD_UPDATE":
+		var g guild
+		if json.Unmarshal(data, &g) == nil {
+			a.learnGuild(g)
+		}
+	case "CHANNEL_CREATE", "CHANNEL_UPDATE":
+		var c channel
+		if json.Unmarshal(data, &c) == nil {
+			a.learnChannel(c)
+		}
+	case "MESSAGE_CREATE":
+		var m message
+		if json.Unmarshal(data, &m) == nil {
+			return a.addMessage(m)
+		}
+	case "GUILD_MEMBER_ADD":
+		var m member
+		if json.Unmarshal(data, &m) == nil && m.User != nil {
+			a.learnNick(m.GuildID, m)
+			return a.add(stats.Join, m.GuildID, "", *m.User, time.Now(), "")
+		}
+	case "GUILD_MEMBER_REMOVE":
+		var m member
+		if json.Unmarshal(data, &m) == nil && m.User != nil {
+			return a.add(stats.Quit, m.GuildID, "", *m.User, time.Now(), "")
+		}
+	case "GUILD_MEMBER_UPDATE":
+		var m member
+		if json.Unmarshal(data, &m) == nil && m.User != nil {
+			return a.updateNick(m)
+		}
+	}
+
+	return false
+}
+
+func (a *Adapter) addMessage(m message) bool {
+	if len(m.GuildID) == 0 || (a.IgnoreBots && m.Author.Bot) {
+		return false
+	}
+	if m.Member != nil {
+		m.Member.User = &m.Author
+		a.learnNick(m.GuildID, *m.Member)
+	}
+
+	content := a.content(m)
+	if len(strings.TrimSpace(content)) == 0 {
+		return false
+	}
+
+	return a.add(stats.Msg, m.GuildID, a.channelName(m.ChannelID), m.Author, m.Timestamp, content)
+}
+
+// updateNick counts the change of a member's nick in a guild.
+func (a *Adapter) updateNick(m member) bool {
+	key := m.GuildID + " " + m.User.ID
+	old, known := a.nicks[key]
+	a.learnNick(m.GuildID, m)
+
+	if !known || old == a.nicks[key] {
+		return false
+	}
+
+	mask := old + "!" + m.User.ID + "@discord"
+	a.Stats.AddMessage(stats.Nick, a.network(m.GuildID), "", mask, time.Now(), a.nicks[key])
+	return true
+}
+
+func (a *Adapter) add(kind stats.MsgKind, guildID, channel string, u user, date time.Time, msg string) bool {
+	if a.IgnoreBots && u.Bot {
+		return false
+	}
+
+	a.Stats.AddMessage(kind, a.network(guildID), channel, a.hostmask(guildID, u), date, msg)
+	return true
+}
+
+var (
+	userMention    = regexp.MustCompile(`<@!?(\d+)>`)
+	channelMention = regexp.MustCompile(`<#(\d+)>`)
+	customEmoji    = regexp.MustCompile(`<a?(:\w+:)\d+>`)
+)
+
+// content rewrites the mentions and custom emoji of a message into plain
+// text, so that <@123> counts as a reference to that user's nick.
+func (a *Adapter) content(m message) string {
+	names := make(map[string]string, len(m.Mentions))
+	for _, u := range m.Mentions {
+		if u.Member != nil {
+			u.Member.User = &u.user
+			a.learnNick(m.GuildID, *u.Member)
+		}
+		names[u.ID] = a.nick(m.GuildID, u.user)
+	}
+
+	content := userMention.ReplaceAllStringFunc(m.Content, func(s string) string {
+		if name, ok := names[userMention.FindStringSubmatch(s)[1]]; ok {
+			return name
+		}
+		return s
+	})
+	content = channelMention.ReplaceAllStringFunc(content, func(s string) string {
+		return a.channelName(channelMention.FindStringSubmatch(s)[1])
+	})
+	return customEmoji.ReplaceAllString(content, "$1")
+}
+
+func (a *Adapter) learnGuild(g guild) {
+	if a.guilds == nil {
+		a.guilds = make(map[string]string)
+	}
+	a.guilds[g.ID] = g.Name
+
+	for _, c := range g.Channels {
+		c.GuildID = g.ID
+		a.learnChannel(c)
+	}
+	for _, m := range g.Members {
+		a.learnNick(g.ID, m)
+	}
+}
+
+func (a *Adapter) learnChannel(c channel) {
+	if a.channels == nil {
+		a.channels = make(map[string]string)
+	}
+	a.channels[c.ID] = "#" + c.Name
+}
+
+func (a *Adapter) learnNick(guildID string, m member) {
+	if m.User == nil {
+		return
+	}
+	if a.nicks == nil {
+		a.nicks = make(map[string]string)
+	}
+
+	name := m.Nick
+	if len(name) == 0 {
+		name = displayName(*m.User)
+	}
+	a.nicks[guildID+" "+m.User.ID] = nickOf(name, *m.User)
+}
+
+// network is the name of the network a guild is counted under.
+func (a *Adapter) network(guildID string) string {
+	if name, ok := a.Networks[guildID]; ok {
+		return name
+	}
+	if name, ok := a.guilds[guildID]; ok && len(name) > 0 {
+		return name
+	}
+	return guildID
+}
+
+func (a *Adapter) channelName(id string) string {
+	if name, ok := a.channels[id]; ok {
+		return name
+	}
+	return "#" + id
+}
+
+// nick is the name a user is counted under in a guild: their guild nick,
+// their display name or their username.
+func (a *Adapter) nick(guildID string, u user) string {
+	if name, ok := a.nicks[guildID+" "+u.ID]; ok {
+		return name
+	}
+	return nickOf(displayName(u), u)
+}
+
+// hostmask builds a hostmask out of the nick and id of a user, so users stay
+// apart even when they share a nick.
+func (a *Adapter) hostmask(guildID string, u user) string {
+	return a.nick(guildID, u) + "!" + u.ID + "@discord"
+}
+
+func displayName(u user) string {
+	if len(u.GlobalName) > 0 {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// nickOf turns a name into something usable as a nick, names with spaces or
+// hostmask characters fall back to the username.
+func nickOf(name string, u user) string {
+	if len(name) == 0 || strings.ContainsAny(name, " \t!@") {
+		return u.Username
+	}
+	return name
+}
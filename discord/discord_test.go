@@ -0,0 +1,93 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+const (
+	guildCreate = `{"op":0,"t":"GUILD_CREATE","d":{"id":"1","name":"zkpq","channels":[{"id":"10","name":"deviate"}],
+		"members":[{"user":{"id":"100","username":"dylan"}},{"user":{"id":"101","username":"aaron"},"nick":"aaronbot"}]}}`
+	messageCreate = `{"op":0,"t":"MESSAGE_CREATE","d":{"id":"1000","channel_id":"10","guild_id":"1",
+		"author":{"id":"101","username":"aaron"},"member":{"nick":"aaron"},
+		"content":"hey <@100> see <#10> <:pogchamp:5555>","timestamp":"2014-03-01T08:00:00+00:00",
+		"mentions":[{"id":"100","username":"dylan"}]}}`
+	botMessage = `{"op":0,"t":"MESSAGE_CREATE","d":{"id":"1001","channel_id":"10","guild_id":"1",
+		"author":{"id":"102","username":"robot","bot":true},"content":"beep","timestamp":"2014-03-01T08:00:01+00:00"}}`
+	nickUpdate = `{"op":0,"t":"GUILD_MEMBER_UPDATE","d":{"guild_id":"1","user":{"id":"100","username":"dylan"},"nick":"dilly"}}`
+	heartbeat  = `{"op":11}`
+)
+
+func TestAdapter_HandlePayload(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s)
+
+	for _, p := range []string{guildCreate, messageCreate, botMessage, heartbeat} {
+		if _, err := a.HandlePayload([]byte(p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Should count the guild as a network and its channel.")
+	}
+
+	u := s.GetUser("zkpq", "aaron")
+	if u == nil || u.Lines != 1 {
+		t.Fatal("Should have counted aaron's message.")
+	}
+
+	if s.GetUser("zkpq", "robot") != nil {
+		t.Error("Should ignore bots.")
+	}
+}
+
+func TestAdapter_content(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s)
+	a.HandleDispatch("CHANNEL_CREATE", []byte(`{"id":"10","name":"deviate"}`))
+
+	m := message{GuildID: "1", Content: "hey <@!100> see <#10> <a:pogchamp:5555>"}
+	m.Mentions = append(m.Mentions, struct {
+		user
+		Member *member `json:"member"`
+	}{user: user{ID: "100", Username: "dylan", GlobalName: "Dylan J"}})
+
+	if got, want := a.content(m), "hey dylan see #deviate :pogchamp:"; got != want {
+		t.Errorf("Want %q, got %q", want, got)
+	}
+}
+
+func TestAdapter_NickChange(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s)
+
+	a.HandlePayload([]byte(guildCreate))
+	if ok, _ := a.HandlePayload([]byte(nickUpdate)); !ok {
+		t.Fatal("Should count the nick change.")
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.NickChanges != 1 {
+		t.Error("Should count the nick change for the old nick.")
+	}
+	if ok, _ := a.HandlePayload([]byte(nickUpdate)); ok {
+		t.Error("Should not count an update that kept the nick.")
+	}
+}
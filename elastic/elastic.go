@@ -0,0 +1,243 @@
+// Package elastic indexes every counted message into Elasticsearch, so the
+// history of the channels can be searched while the stats keep the
+// aggregates.
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Defaults used when the sink's settings are left empty.
+const (
+	DefaultIndex         = "ircstats"
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = 5 * time.Second
+
+	queueSize = 10000
+)
+
+// Sink is a stats.MessageSink that queues messages and sends them to
+// Elasticsearch in bulk from Run.
+type Sink struct {
+	// URL is the base url of the cluster, eg. http://localhost:9200.
+	URL string
+	// Index is the index documents are written to. It may contain a time
+	// layout in braces, eg. ircstats-{2006.01}, to split it by the date of
+	// the messages.
+	Index    string
+	Username string
+	Password string
+
+	BatchSize     int
+	FlushInterval time.Duration
+
+	HTTP *http.Client
+
+	queue   chan stats.SinkMessage
+	dropped uint64
+}
+
+// Document is the indexed form of a message.
+type Document struct {
+	Network  string    `json:"network"`
+	Channel  string    `json:"channel,omitempty"`
+	Nick     string    `json:"nick"`
+	Hostmask string    `json:"hostmask"`
+	Kind     string    `json:"kind"`
+	Date     time.Time `json:"@timestamp"`
+	Message  string    `json:"message"`
+}
+
+// New creates a sink writing to the cluster at url.
+func New(url string) *Sink {
+	return &Sink{
+		URL:           strings.TrimRight(url, "/"),
+		Index:         DefaultIndex,
+		BatchSize:     DefaultBatchSize,
+		FlushInterval: DefaultFlushInterval,
+		queue:         make(chan stats.SinkMessage, queueSize),
+	}
+}
+
+// Sink queues a message to be indexed. Messages are dropped rather than
+// holding up the stats when the queue is full.
+func (s *Sink) Sink(m stats.SinkMessage) {
+	select {
+	case s.queue <- m:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Dropped is the number of messages dropped because the queue was full.
+func (s *Sink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Run sends the queued messages every flush interval, or sooner when a batch
+// fills up, until stop is closed. Failed sends are passed to errs when it
+// isn't nil. The messages still queued are sent before returning.
+func (s *Sink) Run(stop <-chan struct{}, errs func(error)) {
+	size := s.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	interval := s.FlushInterval
+	if interval <= 0 {
+		interval = DefaultFlushInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	batch := make([]stats.SinkMessage, 0, size)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.Send(batch); err != nil && errs != nil {
+			errs(err)
+		}
+		batch = batch[:0]
+	}
+	add := func(m stats.SinkMessage) {
+		if batch = append(batch, m); len(batch) >= size {
+			flush()
+		}
+	}
+
+	for {
+		select {
+		case m := <-s.queue:
+			add(m)
+		case <-ticker.C:
+			flush()
+		case <-stop:
+			for {
+				select {
+				case m := <-s.queue:
+					add(m)
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+// bulkResponse is the part of a bulk response needed to find failed items.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// Send indexes the messages with a single bulk request.
+func (s *Sink) Send(batch []stats.SinkMessage) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	for _, m := range batch {
+		action := map[string]map[string]string{"index": {
+			"_index": s.index(m.Date),
+			"_id":    fmt.Sprint(m.ID),
+		}}
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if err := enc.Encode(NewDocument(m)); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest("POST", s.URL+"/_bulk", &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if len(s.Username) > 0 {
+		req.SetBasicAuth(s.Username, s.Password)
+	}
+
+	client := s.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var br bulkResponse
+	if err = json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		return err
+	}
+	if !br.Errors {
+		return nil
+	}
+
+	failed := 0
+	var reason string
+	for _, item := range br.Items {
+		for _, result := range item {
+			if result.Status/100 != 2 {
+				failed++
+				if len(reason) == 0 {
+					reason = result.Error.Type + ": " + result.Error.Reason
+				}
+			}
+		}
+	}
+	return fmt.Errorf("Failed to index %d of %d messages, %s", failed, len(batch), reason)
+}
+
+// index is the name of the index for a message sent at date.
+func (s *Sink) index(date time.Time) string {
+	index := s.Index
+	if len(index) == 0 {
+		index = DefaultIndex
+	}
+
+	start := strings.IndexByte(index, '{')
+	end := strings.IndexByte(index, '}')
+	if start < 0 || end < start {
+		return index
+	}
+
+	return index[:start] + date.UTC().Format(index[start+1:end]) + index[end+1:]
+}
+
+// NewDocument converts a counted message to the document indexed for it.
+func NewDocument(m stats.SinkMessage) Document {
+	return Document{
+		Network:  m.Network,
+		Channel:  m.Channel,
+		Nick:     m.Nick,
+		Hostmask: m.Hostmask,
+		Kind:     m.Kind.String(),
+		Date:     m.Date,
+		Message:  m.Message,
+	}
+}
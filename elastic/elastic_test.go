@@ -0,0 +1,161 @@
+package elastic
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+var date = time.Date(2014, 3, 1, 10, 0, 0, 0, time.UTC)
+
+type bulkServer struct {
+	*httptest.Server
+	lines chan []string
+	fail  bool
+}
+
+func newBulkServer() *bulkServer {
+	b := &bulkServer{lines: make(chan []string, 10)}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			http.NotFound(w, r)
+			return
+		}
+		if user, pass, _ := r.BasicAuth(); user != "elastic" || pass != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var lines []string
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		b.lines <- lines
+
+		if b.fail {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},` +
+				`{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad date"}}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	return b
+}
+
+func newSink(url string) *Sink {
+	s := New(url + "/")
+	s.Username, s.Password = "elastic", "secret"
+	return s
+}
+
+func TestSink_Send(t *testing.T) {
+	t.Parallel()
+
+	b := newBulkServer()
+	defer b.Close()
+
+	s := newSink(b.URL)
+	s.Index = "ircstats-{2006.01}"
+	err := s.Send([]stats.SinkMessage{{
+		ID: 7, Kind: stats.Action, Network: "zkpq", Channel: "#deviate",
+		Nick: "dylan", Hostmask: "dylan!d@zqz.ca", Date: date, Message: "waves",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := <-b.lines
+	if len(lines) != 2 {
+		t.Fatal("Should send an action and a document:", lines)
+	}
+	if lines[0] != `{"index":{"_id":"7","_index":"ircstats-2014.03"}}` {
+		t.Error("Wrong action:", lines[0])
+	}
+
+	var doc Document
+	if err = json.Unmarshal([]byte(lines[1]), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Kind != "action" || doc.Nick != "dylan" || doc.Channel != "#deviate" ||
+		!doc.Date.Equal(date) || doc.Message != "waves" {
+		t.Error("Wrong document:", doc)
+	}
+	if !strings.Contains(lines[1], `"@timestamp":"2014-03-01T10:00:00Z"`) {
+		t.Error("Should store the date as @timestamp:", lines[1])
+	}
+}
+
+func TestSink_SendErrors(t *testing.T) {
+	t.Parallel()
+
+	b := newBulkServer()
+	b.fail = true
+	defer b.Close()
+
+	s := newSink(b.URL)
+	err := s.Send([]stats.SinkMessage{{ID: 1, Date: date}, {ID: 2, Date: date}})
+	if err == nil || !strings.Contains(err.Error(), "1 of 2") || !strings.Contains(err.Error(), "bad date") {
+		t.Error("Should report the failed items:", err)
+	}
+
+	s.Password = "wrong"
+	if err = s.Send([]stats.SinkMessage{{ID: 1, Date: date}}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Error("Should report failed requests:", err)
+	}
+}
+
+func TestSink_Run(t *testing.T) {
+	t.Parallel()
+
+	b := newBulkServer()
+	defer b.Close()
+
+	st, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newSink(b.URL)
+	s.BatchSize = 2
+	st.SetOptions(stats.Options{Sink: s})
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.Run(stop, func(err error) { t.Error(err) })
+		close(done)
+	}()
+
+	for i := 0; i < 3; i++ {
+		st.AddMessage(stats.Msg, "zkpq", "#deviate", "dylan!d@zqz.ca", date, "hello")
+	}
+
+	if lines := <-b.lines; len(lines) != 4 {
+		t.Error("Should send a full batch right away:", lines)
+	}
+
+	close(stop)
+	<-done
+	if lines := <-b.lines; len(lines) != 2 {
+		t.Error("Should send the rest when stopped:", lines)
+	}
+}
+
+func TestSink_Dropped(t *testing.T) {
+	t.Parallel()
+
+	s := New("http://localhost:9200")
+	for i := 0; i < queueSize+2; i++ {
+		s.Sink(stats.SinkMessage{})
+	}
+
+	if s.Dropped() != 2 {
+		t.Error("Should drop messages when the queue is full:", s.Dropped())
+	}
+}
@@ -1,7 +1,5 @@
 package stats
 
-import "strings"
-
 var emoticons = map[string]struct{}{
 	":D":  struct{}{},
 	";D":  struct{}{},
@@ -30,16 +28,34 @@ func NewEmoticonCounter() EmoticonCounter {
 }
 
 func (s *EmoticonCounter) addMessage(message *Message) {
-	words := strings.Fields(message.Message)
+	t := message.tokens()
+	for _, word := range t.Fields {
+		if _, ok := emoticons[word]; ok || isShortcode(word) {
+			s.addToken(t.intern(word))
+		}
+	}
+}
 
-	for _, word := range words {
-		if _, ok := emoticons[word]; ok {
-			s.addToken(word)
+// isShortcode checks if a word is a named emoji such as :tada:, the way
+// Slack, Discord and Twitch emotes are written down.
+func isShortcode(word string) bool {
+	if len(word) < 3 || word[0] != ':' || word[len(word)-1] != ':' {
+		return false
+	}
+	for _, r := range word[1 : len(word)-1] {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '+') {
+			return false
 		}
 	}
+	return true
 }
 
-// TopEmoticon
+// TopEmoticon returns the most used emoticon, it's empty when no emoticons
+// were used.
 func (s *EmoticonCounter) TopEmoticon() TopToken {
+	if len(s.TokenCounter.Top) == 0 {
+		return TopToken{}
+	}
+
 	return s.TokenCounter.Top[0]
 }
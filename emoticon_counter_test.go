@@ -13,6 +13,9 @@ func TestEmoticonCounter(t *testing.T) {
 	if len(tc.All) != 0 {
 		t.Error("All Emoticons should be empty.")
 	}
+	if tok := tc.TopEmoticon(); tok != (TopToken{}) {
+		t.Error("Top emoticon should be empty:", tok)
+	}
 
 	m := &Message{Message: "he:Dllo :D world :D :("}
 	tc.addMessage(m)
@@ -40,3 +43,19 @@ func TestEmoticonCounter(t *testing.T) {
 		t.Error("Top emoticon is incorrect")
 	}
 }
+
+func TestEmoticonCounter_shortcodes(t *testing.T) {
+	t.Parallel()
+
+	tc := NewEmoticonCounter()
+	tc.addMessage(&Message{Message: ":tada: shipped :Kappa: :+1: at 12:30: :: :not an emoji:"})
+
+	for _, emoji := range []string{":tada:", ":Kappa:", ":+1:"} {
+		if tc.All[emoji] != 1 {
+			t.Errorf("Should count %s.", emoji)
+		}
+	}
+	if len(tc.All) != 3 {
+		t.Error("Should only count shortcodes:", tc.All)
+	}
+}
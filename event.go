@@ -0,0 +1,208 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidEvent is the error of the events AddEvent refuses, errors.Is
+// tells them apart.
+var ErrInvalidEvent = errors.New("Invalid event")
+
+// Event is something a user said or did, typed so that every kind carries
+// what it needs and nothing else: a QuitEvent has no channel, a KickEvent
+// has a target. Add them with AddEvent, which checks them.
+type Event interface {
+	// raw is the message the event is counted as, or why it isn't one.
+	raw() (RawMessage, error)
+}
+
+// Origin is who did an event, on which network and when. Every event has
+// one.
+type Origin struct {
+	Network string
+	// Hostmask is the user's, nick!user@host on irc or only the nick.
+	// Servers aren't users, their events are refused.
+	Hostmask string
+	Date     time.Time
+	// MsgID is the IRCv3 msgid of the event, events with one are only
+	// added once, see AddMessageID.
+	MsgID string
+}
+
+// PrivMsgEvent is a message said in a channel.
+type PrivMsgEvent struct {
+	Origin
+	Channel string
+	Text    string
+}
+
+// ActionEvent is an action done in a channel, /me on irc.
+type ActionEvent struct {
+	Origin
+	Channel string
+	Text    string
+}
+
+// NoticeEvent is a notice sent to a channel.
+type NoticeEvent struct {
+	Origin
+	Channel string
+	Text    string
+}
+
+// JoinEvent is a user joining a channel.
+type JoinEvent struct {
+	Origin
+	Channel string
+}
+
+// PartEvent is a user leaving a channel, with an optional reason.
+type PartEvent struct {
+	Origin
+	Channel string
+	Reason  string
+}
+
+// QuitEvent is a user leaving the network, with an optional reason.
+type QuitEvent struct {
+	Origin
+	Reason string
+}
+
+// KickEvent is a user kicking the nick Target out of a channel, with an
+// optional reason.
+type KickEvent struct {
+	Origin
+	Channel string
+	Target  string
+	Reason  string
+}
+
+// NickEvent is a user changing their nick to NewNick.
+type NickEvent struct {
+	Origin
+	NewNick string
+}
+
+// TopicEvent is a user setting the topic of a channel.
+type TopicEvent struct {
+	Origin
+	Channel string
+	Topic   string
+}
+
+// ModeEvent is a user setting the modes of a channel, eg. "+o bob".
+type ModeEvent struct {
+	Origin
+	Channel string
+	Modes   string
+}
+
+func (e PrivMsgEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Msg, e.Channel, e.Text)
+}
+
+func (e ActionEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Action, e.Channel, e.Text)
+}
+
+func (e NoticeEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Notice, e.Channel, e.Text)
+}
+
+func (e JoinEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Join, e.Channel, "")
+}
+
+func (e PartEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Part, e.Channel, e.Reason)
+}
+
+func (e QuitEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Quit, "", e.Reason)
+}
+
+func (e KickEvent) raw() (RawMessage, error) {
+	if !validNick(e.Target) {
+		return RawMessage{}, fmt.Errorf("%w: a kick needs the nick of its target, not %q", ErrInvalidEvent, e.Target)
+	}
+	return e.Origin.raw(Kick, e.Channel, strings.TrimSpace(e.Target+" "+e.Reason))
+}
+
+func (e NickEvent) raw() (RawMessage, error) {
+	if !validNick(e.NewNick) {
+		return RawMessage{}, fmt.Errorf("%w: a nick change needs the new nick, not %q", ErrInvalidEvent, e.NewNick)
+	}
+	return e.Origin.raw(Nick, "", e.NewNick)
+}
+
+func (e TopicEvent) raw() (RawMessage, error) {
+	return e.Origin.raw(Topic, e.Channel, e.Topic)
+}
+
+func (e ModeEvent) raw() (RawMessage, error) {
+	if len(strings.TrimSpace(e.Modes)) == 0 {
+		return RawMessage{}, fmt.Errorf("%w: a mode change needs its modes", ErrInvalidEvent)
+	}
+	return e.Origin.raw(Mode, e.Channel, e.Modes)
+}
+
+// raw checks the origin of an event and makes it the message of the kind, in
+// the channel for the kinds of events that happen in one.
+func (o Origin) raw(kind MsgKind, channel, message string) (RawMessage, error) {
+	switch {
+	case len(o.Network) == 0:
+		return RawMessage{}, fmt.Errorf("%w: a %s needs a network", ErrInvalidEvent, kind)
+	case o.Date.IsZero():
+		return RawMessage{}, fmt.Errorf("%w: a %s needs a date", ErrInvalidEvent, kind)
+	}
+
+	h, ok := ParseHostmask(o.Hostmask)
+	switch {
+	case !ok:
+		return RawMessage{}, fmt.Errorf("%w: %q isn't a hostmask", ErrInvalidEvent, o.Hostmask)
+	case h.IsServer():
+		return RawMessage{}, fmt.Errorf("%w: %s is a server, only the events of users are counted", ErrInvalidEvent, h.Nick)
+	}
+
+	inChannel := kind != Quit && kind != Nick
+	if inChannel && len(strings.TrimSpace(channel)) == 0 {
+		return RawMessage{}, fmt.Errorf("%w: a %s needs a channel", ErrInvalidEvent, kind)
+	}
+
+	return RawMessage{
+		MsgID:    o.MsgID,
+		Kind:     kind,
+		Network:  o.Network,
+		Channel:  channel,
+		Hostmask: o.Hostmask,
+		Date:     o.Date,
+		Message:  message,
+	}, nil
+}
+
+// validNick checks that a nick is a hostmask's nick on its own.
+func validNick(nick string) bool {
+	h, ok := ParseHostmask(nick)
+	return ok && h.Nick == nick
+}
+
+// AddEvent checks an event and adds it to the stats. Events that aren't
+// valid are refused with an ErrInvalidEvent rather than counted wrong, such
+// as those missing their channel, or from servers. Events with a msgid added
+// before are skipped, as AddMessageID skips them.
+func (s *Stats) AddEvent(e Event) error {
+	raw, err := e.raw()
+	if err != nil {
+		return err
+	}
+
+	n := s.lockRaw(&raw)
+	defer s.unlockNetwork(n)
+
+	s.addMessageID(n, raw)
+	return nil
+}
@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats_AddEvent(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	o := Origin{Network: network, Hostmask: hostmask, Date: time.Now()}
+
+	events := []Event{
+		JoinEvent{o, channel},
+		PrivMsgEvent{o, channel, "hello there"},
+		ActionEvent{o, channel, "slaps aaron around a bit with a large trout"},
+		NoticeEvent{o, channel, "psst"},
+		TopicEvent{o, channel, "the topic"},
+		ModeEvent{o, channel, "+o"},
+		KickEvent{o, channel, "aaron", "bye"},
+		PartEvent{o, channel, "gone"},
+		NickEvent{o, "phish_"},
+		QuitEvent{o, "see ya"},
+	}
+	for _, e := range events {
+		if err := s.AddEvent(e); err != nil {
+			t.Errorf("Should add %T: %v", e, err)
+		}
+	}
+
+	c := s.GetChannel(network, channel)
+	u := s.GetUser(network, nick)
+	if c.MessageCount != 8 || u.MessageCount != 10 {
+		t.Errorf("Should count 8 events in the channel and 10 of the user, counted %d and %d", c.MessageCount, u.MessageCount)
+	}
+	if topics := c.LastTopics.Topics; len(topics) != 1 || topics[0].Message != "the topic" {
+		t.Error("Should keep the topic, have", topics)
+	}
+	if u.KickCounters.Sent != 1 || u.ModeCounters.Ops != 1 || u.NickChanges != 1 || u.Lines != 1 {
+		t.Error("Should count the kick, mode, nick change and message of the user.")
+	}
+}
+
+func TestStats_AddEventInvalid(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	o := Origin{Network: network, Hostmask: hostmask, Date: time.Now()}
+
+	tests := []struct {
+		event  Event
+		reason string
+	}{
+		{PrivMsgEvent{Origin{Hostmask: hostmask, Date: time.Now()}, channel, "hi"}, "needs a network"},
+		{PrivMsgEvent{Origin{Network: network, Hostmask: hostmask}, channel, "hi"}, "needs a date"},
+		{PrivMsgEvent{Origin{Network: network, Hostmask: "bad nick", Date: time.Now()}, channel, "hi"}, "isn't a hostmask"},
+		{NoticeEvent{Origin{Network: network, Hostmask: "irc.server.net", Date: time.Now()}, channel, "hi"}, "is a server"},
+		{PrivMsgEvent{o, "", "hi"}, "a message needs a channel"},
+		{JoinEvent{o, " "}, "a join needs a channel"},
+		{KickEvent{o, channel, "", "bye"}, "needs the nick of its target"},
+		{KickEvent{o, channel, "aaron!a@b", "bye"}, "needs the nick of its target"},
+		{NickEvent{o, "new nick"}, "needs the new nick"},
+		{ModeEvent{o, channel, " "}, "needs its modes"},
+	}
+	for _, test := range tests {
+		err := s.AddEvent(test.event)
+		if !errors.Is(err, ErrInvalidEvent) || !strings.Contains(err.Error(), test.reason) {
+			t.Errorf("%+v should be refused as it %s, got %v", test.event, test.reason, err)
+		}
+	}
+
+	if len(s.Networks) != 0 || len(s.Users) != 0 {
+		t.Error("Shouldn't add anything of the events refused.")
+	}
+}
+
+func TestStats_AddEventMsgID(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	e := PrivMsgEvent{Origin{Network: network, Hostmask: hostmask, Date: time.Now(), MsgID: "abc"}, channel, "hi"}
+
+	s.Update(func(tx *WriteTx) {
+		for i := 0; i < 2; i++ {
+			if err := tx.AddEvent(e); err != nil {
+				t.Error("Should add the event:", err)
+			}
+		}
+	})
+
+	if c := s.GetChannel(network, channel); c.MessageCount != 1 {
+		t.Error("Should add the event with a msgid once, counted", c.MessageCount)
+	}
+}
@@ -0,0 +1,45 @@
+package stats
+
+import "strings"
+
+// Features turns off what's expensive to count or keep of the messages of a
+// channel, a channel of thousands of users may do without what a channel of
+// ten can afford. Everything is counted and kept by default, see
+// Options.Features and ChannelFeatures.
+type Features struct {
+	// NoQuotes keeps no quotes of the channel's messages, in the channel
+	// nor in its network and users, so s/old/new/ corrections of them
+	// aren't applied either.
+	NoQuotes bool
+	// NoWords doesn't count the words of the channel's messages in the word
+	// counters, the words and letters of the users are counted still.
+	NoWords bool
+	// NoURLs doesn't count the urls pasted in the channel.
+	NoURLs bool
+	// NoStorage keeps the channel's messages in neither the raw store nor
+	// by id, as if only aggregates were kept of them, see AggregateOnly.
+	NoStorage bool
+	// Pseudonyms counts and keeps the users of the channel by pseudonyms
+	// of their nicks rather than their nicks and hostmasks, in the raw
+	// store too, see Options.Pseudonym. The processors see the pseudonyms,
+	// the relay bots of a BridgeProcessor are named by theirs. A user seen
+	// in channels with and without pseudonyms is counted as two.
+	Pseudonyms bool
+}
+
+// features are the features of a channel, those of its network when the
+// channel is empty.
+func (o *Options) features(network, channel string) Features {
+	if len(o.ChannelFeatures) > 0 {
+		if len(channel) > 0 {
+			if f, ok := o.ChannelFeatures[importKey(network, channel)]; ok {
+				return f
+			}
+		}
+		if f, ok := o.ChannelFeatures[strings.ToLower(network)]; ok {
+			return f
+		}
+	}
+
+	return o.Features
+}
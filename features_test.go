@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOptions_features(t *testing.T) {
+	t.Parallel()
+
+	o := &Options{
+		Features: Features{NoQuotes: true},
+		ChannelFeatures: map[string]Features{
+			"test_network":       {NoWords: true},
+			"test_network #test": {NoURLs: true},
+		},
+	}
+	if f := o.features(network, channel); f != (Features{NoURLs: true}) {
+		t.Error("Should use the features of the channel:", f)
+	}
+	if f := o.features(network, "#other"); f != (Features{NoWords: true}) {
+		t.Error("Should use the features of the network for its other channels:", f)
+	}
+	if f := o.features("other", channel); f != (Features{NoQuotes: true}) {
+		t.Error("Should use the features of every channel otherwise:", f)
+	}
+}
+
+// recordingStore is a raw store keeping what it's given in memory.
+type recordingStore struct {
+	messages []RawMessage
+}
+
+func (r *recordingStore) Append(m RawMessage) { r.messages = append(r.messages, m) }
+
+func (r *recordingStore) Replay(f func(RawMessage)) error {
+	for _, m := range r.messages {
+		f(m)
+	}
+	return nil
+}
+
+func TestStats_Features(t *testing.T) {
+	t.Parallel()
+
+	raw := &recordingStore{}
+	s := newStats()
+	s.SetOptions(Options{
+		RawStore: raw,
+		ChannelFeatures: map[string]Features{
+			"Test_Network #Big": {NoQuotes: true, NoWords: true, NoURLs: true, NoStorage: true},
+		},
+	})
+	s.AddMessage(Msg, network, "#big", hostmask, time.Now(), "look at https://zqz.ca")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello https://zqz.ca")
+
+	big, small := s.GetChannel(network, "#big"), s.GetChannel(network, channel)
+	if big.Quotes.Last.ID != 0 || len(big.WordCounter.Top) != 0 || len(big.URLCounter.Top) != 0 || big.MessageRanges.Len() != 0 {
+		t.Error("Should count nothing that's turned off in the channel:", big.Quotes.Last, big.WordCounter.Top, big.URLCounter.Top, big.MessageRanges.Len())
+	}
+	if big.MessageCount != 1 {
+		t.Error("Should count the message in the channel:", big.MessageCount)
+	}
+	if len(small.WordCounter.Top) == 0 || len(small.URLCounter.Top) != 1 || small.Quotes.Last.ID == 0 || small.MessageRanges.Len() != 1 {
+		t.Error("Should count everything in the other channel.")
+	}
+
+	u := s.GetUser(network, nick)
+	if u.Quotes.Last.Message != "hello https://zqz.ca" || u.MessageRanges.Len() != 1 || u.MessageCount != 2 {
+		t.Error("Should count the user's messages of the channel as the channel does:", u.Quotes.Last, u.MessageRanges.Len())
+	}
+	if cu := u.ChannelUsers["#big"]; cu.Words != 3 || len(cu.WordCounter.Top) != 0 {
+		t.Error("Should count the words of the user without the word counter:", cu.Words, cu.WordCounter.Top)
+	}
+	if n := s.GetNetwork(network); len(n.URLCounter.Top) != 1 || n.URLCounter.Top[0].Count != 1 {
+		t.Error("Should count the urls of the network but for the channel's:", n.URLCounter.Top)
+	}
+	if len(raw.messages) != 1 || raw.messages[0].Channel != channel {
+		t.Error("Should store only the messages of channels storing them:", raw.messages)
+	}
+}
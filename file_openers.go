@@ -3,6 +3,7 @@ package stats
 import (
 	"bytes"
 	"io"
+	"io/ioutil"
 	"os"
 )
 
@@ -37,9 +38,9 @@ func (o osFileOpener) Create(name string) (io.WriteCloser, error) {
 	return os.Create(name)
 }
 
-// Open
+// Open reads what was written from the start, every time it's opened.
 func (o *fakeFileOpener) Open(name string) (io.ReadCloser, error) {
-	return o, nil
+	return ioutil.NopCloser(bytes.NewReader(o.Bytes())), nil
 }
 
 // Create
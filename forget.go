@@ -0,0 +1,262 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ErrUnknownUser is the error of the users ForgetUser and UserData don't
+// know.
+var ErrUnknownUser = errors.New("Unknown user")
+
+// UserData is all the stats have about a user, for the data requests of
+// users: their counters in the network and in each channel, by the channel's
+// name in lower case, and the messages of theirs still in the raw store.
+type UserData struct {
+	Network  string       `json:"network"`
+	User     *User        `json:"user"`
+	Messages []RawMessage `json:"messages"`
+}
+
+// UserData collects what the stats have about the nick on the network. The
+// messages are those of the raw store, when there is one, said by the nick,
+// relayed for it by the bots of the bridge processors or changing a nick to it.
+func (s *Stats) UserData(network, nick string) (UserData, error) {
+	s.rlock()
+	defer s.runlock()
+
+	n, u, err := s.lookupForgotten(network, nick)
+	if err != nil {
+		return UserData{}, err
+	}
+
+	data := UserData{Network: n.Name}
+	clone := &User{}
+	cloneValue(reflect.ValueOf(clone).Elem(), reflect.ValueOf(u).Elem())
+	data.User = clone
+
+	if s.opts.RawStore != nil {
+		err = s.opts.RawStore.Replay(func(raw RawMessage) {
+			if s.saidBy(n, u, raw) {
+				data.Messages = append(data.Messages, raw)
+			}
+		})
+	}
+	return data, err
+}
+
+// ForgetUser forgets the nick on the network: their counters, quotes,
+// hostmask and messages, in the raw store too when it's a RawForgetter. The
+// totals of the channels and network they were counted in are kept, with
+// nothing left to tell what of them was the user's: the records and
+// leaderboards they're named in drop them, and the mentions of their nick by
+// others are forgotten. The message ids already added are still skipped.
+func (s *Stats) ForgetUser(network, nick string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.forgetUser(network, nick)
+}
+
+// forgetUser is ForgetUser with the stats locked for writing.
+func (s *Stats) forgetUser(network, nick string) error {
+	n, u, err := s.lookupForgotten(network, nick)
+	if err != nil {
+		return err
+	}
+
+	// the channels are loaded first, nothing is forgotten when one fails
+	channels := make([]*Channel, 0, len(n.ChannelIDs))
+	for _, id := range n.ChannelIDs {
+		c := s.Channels[id].load(s)
+		if c == nil {
+			return s.Channels[id].loadErr
+		}
+		channels = append(channels, c)
+	}
+	if f, ok := s.opts.RawStore.(RawForgetter); ok {
+		if err := f.ForgetRaw(func(raw RawMessage) bool { return s.saidBy(n, u, raw) }); err != nil {
+			return err
+		}
+	}
+
+	folded := n.fold(u.Nick)
+	for _, c := range channels {
+		c.forgetUser(u, folded)
+	}
+	n.Quotes.forgetUser(u.ID)
+
+	ids := n.UserIDs[:0]
+	for _, id := range n.UserIDs {
+		if id == u.ID {
+			continue
+		}
+		ids = append(ids, id)
+		other := s.Users[id]
+		delete(other.NickReferences, folded)
+		for _, cu := range other.ChannelUsers {
+			delete(cu.NickReferences, folded)
+		}
+	}
+	n.UserIDs = ids
+	delete(n.users, folded)
+	delete(s.Users, u.ID)
+
+	s.opts.logger().Info("Forgot a user", "network", n.Name, "nick", u.Nick)
+	return nil
+}
+
+// lookupForgotten finds the network and user to forget or collect the data
+// of.
+func (s *Stats) lookupForgotten(network, nick string) (*Network, *User, error) {
+	n := s.lookupNetwork(network)
+	if n == nil {
+		return nil, nil, fmt.Errorf("%w: no network %s", ErrUnknownUser, network)
+	}
+	u, ok := n.users[n.fold(nick)]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: no %s on %s", ErrUnknownUser, nick, n.Name)
+	}
+	return n, u, nil
+}
+
+// saidBy checks if a raw message of the network was said by the user, relayed
+// for them by a bridge, or changed a nick to theirs.
+func (s *Stats) saidBy(n *Network, u *User, raw RawMessage) bool {
+	if !strings.EqualFold(s.opts.aliasNetwork(raw.Network, raw.Channel), n.Name) {
+		return false
+	}
+	folded := n.fold(u.Nick)
+	if raw.Kind == Nick && n.fold(raw.Message) == folded {
+		return true
+	}
+	h, ok := ParseHostmask(s.creditedHostmask(raw))
+	return ok && n.fold(h.Nick) == folded
+}
+
+// creditedHostmask is the hostmask a raw message was counted for, that of the
+// user the bridge processors credit the messages their bots relay to.
+func (s *Stats) creditedHostmask(raw RawMessage) string {
+	network, channel, _ := s.opts.alias(raw.Network, raw.Channel)
+	pm := ProcessedMessage{
+		Kind:     raw.Kind,
+		Network:  network,
+		Channel:  channel,
+		Hostmask: raw.Hostmask,
+		Date:     raw.Date,
+		Message:  raw.Message,
+	}
+	for _, p := range s.opts.Processors {
+		// the other processors may keep what they're given, bridges don't
+		if b, ok := p.(*BridgeProcessor); ok {
+			b.Process(&pm)
+		}
+	}
+	if pm.Hostmask != raw.Hostmask && s.opts.features(raw.Network, raw.Channel).Pseudonyms {
+		return s.opts.pseudonymHostmask(network, pm.Hostmask)
+	}
+	return pm.Hostmask
+}
+
+// forgetUser drops what the channel keeps of the user by their id and nick,
+// its counters are kept.
+func (c *Channel) forgetUser(u *User, folded string) {
+	delete(c.UserIDs, u.ID)
+	delete(c.NickReferences, folded)
+	c.Quotes.forgetUser(u.ID)
+
+	topics := c.LastTopics.Topics[:0]
+	for _, t := range c.LastTopics.Topics {
+		if t.UserID != u.ID {
+			topics = append(topics, t)
+		}
+	}
+	c.LastTopics.Topics = topics
+
+	if c.ConsecutiveLines.UserID == u.ID {
+		c.ConsecutiveLines.UserID, c.ConsecutiveLines.Count = 0, 0
+	}
+	c.ConsecutiveLines.TopUsers.remove(u.Nick)
+	c.TopConsecutiveLines.remove(u.Nick)
+	c.Starters.forget(u.Nick)
+
+	if c.Reactions.LastUserID == u.ID {
+		c.Reactions.LastUserID = 0
+	}
+
+	floods := c.FloodHistory.Floods[:0]
+	for _, f := range c.FloodHistory.Floods {
+		if f.UserID != u.ID {
+			floods = append(floods, f)
+		}
+	}
+	c.FloodHistory.Floods = floods
+
+	c.DailySpeakers.forgetUser(u.ID)
+	c.Leaderboard.forgetUser(u.ID)
+}
+
+// forgetUser drops the quotes of the user.
+func (q *quotes) forgetUser(id uint) {
+	if q.Last.UserID == id {
+		q.Last = Message{}
+	}
+	if q.Random.UserID == id {
+		q.Random = Message{}
+	}
+}
+
+// forgetUser drops the days and hours the user spoke first or last, nobody
+// did as far as the speakers know.
+func (d *DailySpeakers) forgetUser(id uint) {
+	delete(d.First, id)
+	delete(d.Last, id)
+	if d.FirstUserID == id {
+		d.FirstUserID = 0
+	}
+	if d.LastUserID == id {
+		d.LastUserID = 0
+	}
+	for i := range d.Hours {
+		h := &d.Hours[i]
+		if h.FirstUserID == id {
+			h.FirstUserID = 0
+		}
+		if h.LastUserID == id {
+			h.LastUserID = 0
+		}
+	}
+}
+
+// forgetUser drops the lines of the user and the weeks they were #1.
+func (l *LeaderboardHistory) forgetUser(id uint) {
+	delete(l.Lines, id)
+	for _, lines := range l.Past {
+		delete(lines, id)
+	}
+
+	weeks := l.Weeks[:0]
+	for _, w := range l.Weeks {
+		if w.UserID != id {
+			weeks = append(weeks, w)
+		}
+	}
+	l.Weeks = weeks
+}
+
+// forget drops the count of a token, what the sketch of a bounded counter
+// counted of it can't be told apart from the rest and stays.
+func (tc *TokenCounter) forget(token string) {
+	delete(tc.All, token)
+	tc.Top.remove(token)
+}
+
+// remove drops a token from the top, the token that would have followed the
+// last isn't known and isn't put in its place.
+func (a *TopTokenArray) remove(token string) {
+	if i := a.index(token); i >= 0 {
+		*a = append((*a)[:i], (*a)[i+1:]...)
+	}
+}
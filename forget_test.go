@@ -0,0 +1,159 @@
+package stats
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats_ForgetUser(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "forget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.SetOptions(Options{RawStore: store})
+
+	alice := "alice!a@zqz.ca"
+	date := time.Date(2014, 3, 5, 12, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, alice, date, "hello")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(time.Hour), "hey alice")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(time.Hour+time.Second), "how are you?")
+	s.AddMessage(Topic, network, channel, hostmask, date.Add(time.Hour+2*time.Second), "phish was here")
+	s.AddMessage(Msg, network, channel, alice, date.Add(time.Hour+3*time.Second), "fine phish, thanks")
+
+	data, err := s.UserData(network, "PHISH")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.Network != network || data.User.Nick != nick || data.User.Lines != 2 || data.User.Hostmask != hostmask {
+		t.Error("Should give the user's counters:", data.User)
+	}
+	if len(data.Messages) != 3 || data.Messages[0].Message != "hey alice" {
+		t.Error("Should give the user's messages still in the raw store:", data.Messages)
+	}
+	if len(data.User.ChannelUsers) != 1 {
+		t.Error("Should give the user's counters in each channel:", data.User.ChannelUsers)
+	}
+
+	c := s.GetChannel(network, channel)
+	lines := c.MessageCount
+	if err = s.ForgetUser(network, nick); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.GetUser(network, nick) != nil {
+		t.Error("Should forget the user.")
+	}
+	u := s.GetUser(network, "alice")
+	if u == nil || u.Lines != 2 {
+		t.Fatal("Should keep the other users:", u)
+	}
+	if _, ok := u.NickReferences[nick]; ok {
+		t.Error("Should forget the mentions of the user's nick:", u.NickReferences)
+	}
+
+	if _, ok := c.UserIDs[data.User.ID]; ok || c.MessageCount != lines {
+		t.Error("Should drop the user from the channel but keep its totals:", c.UserIDs, c.MessageCount)
+	}
+	for _, topic := range c.LastTopics.Topics {
+		if topic.UserID == data.User.ID {
+			t.Error("Should forget the topics the user set:", topic)
+		}
+	}
+	if c.Quotes.Last.UserID == data.User.ID || c.Quotes.Random.UserID == data.User.ID {
+		t.Error("Should forget the user's quotes:", c.Quotes)
+	}
+	if _, ok := c.Starters.All[nick]; ok || c.Starters.Top.index(nick) >= 0 {
+		t.Error("Should forget the conversations the user started:", c.Starters.All)
+	}
+	if _, ok := c.Leaderboard.Lines[data.User.ID]; ok {
+		t.Error("Should forget the user's lines of the week:", c.Leaderboard.Lines)
+	}
+
+	var kept []string
+	if err = store.Replay(func(raw RawMessage) { kept = append(kept, raw.Message) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0] != "hello" || kept[1] != "fine phish, thanks" {
+		t.Error("Should forget the user's messages in the raw store:", kept)
+	}
+
+	if err = s.ForgetUser(network, nick); !errors.Is(err, ErrUnknownUser) {
+		t.Error("Should not forget a user twice, got", err)
+	}
+	if _, err = s.UserData("other_network", nick); !errors.Is(err, ErrUnknownUser) {
+		t.Error("Should know no users of unknown networks, got", err)
+	}
+}
+
+func TestStats_ForgetUserBridged(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "forget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.SetOptions(Options{RawStore: store, Processors: []Processor{NewBridgeProcessor("relay")}})
+
+	date := time.Date(2014, 3, 5, 12, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, "relay!r@zqz.ca", date, "<alice> hi")
+	s.AddMessage(Msg, network, channel, "relay!r@zqz.ca", date.Add(time.Second), "<bob> hey alice")
+	s.AddMessage(Msg, network, channel, "relay!r@zqz.ca", date.Add(2*time.Second), "relaying for everyone")
+	s.AddMessage(Msg, network, channel, "alice!a@zqz.ca", date.Add(3*time.Second), "back on irc")
+
+	data, err := s.UserData(network, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Messages) != 2 || data.Messages[0].Message != "<alice> hi" {
+		t.Error("Should give the messages bridged for the user:", data.Messages)
+	}
+
+	if err = s.ForgetUser(network, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	if err = store.Replay(func(raw RawMessage) { kept = append(kept, raw.Message) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[0] != "<bob> hey alice" || kept[1] != "relaying for everyone" {
+		t.Error("Should forget the messages bridged for the user in the raw store:", kept)
+	}
+}
+
+func TestTopTokenArray_remove(t *testing.T) {
+	t.Parallel()
+
+	var a TopTokenArray
+	a.insert("a", 3)
+	a.insert("b", 2)
+	a.insert("c", 1)
+	a.remove("b")
+	a.remove("z")
+	if len(a) != 2 || a[0].Token != "a" || a[1].Token != "c" {
+		t.Error("Should remove the token only:", a)
+	}
+}
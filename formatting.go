@@ -0,0 +1,96 @@
+package stats
+
+import "strings"
+
+// The control characters of mIRC's text formatting.
+const (
+	fmtBold          = '\x02'
+	fmtColor         = '\x03'
+	fmtHexColor      = '\x04'
+	fmtReset         = '\x0f'
+	fmtMonospace     = '\x11'
+	fmtReverse       = '\x16'
+	fmtItalic        = '\x1d'
+	fmtStrikethrough = '\x1e'
+	fmtUnderline     = '\x1f'
+)
+
+// stripFormatting removes the bold, italics, colors and other formatting
+// codes from a line of text, so they aren't counted as part of the words and
+// letters. Text without formatting is returned as it is.
+func stripFormatting(text string) string {
+	i := 0
+	for i < len(text) && !isFormatting(text[i]) {
+		i++
+	}
+	if i == len(text) {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	b.WriteString(text[:i])
+
+	for i < len(text) {
+		c := text[i]
+		if !isFormatting(c) {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+
+		i++
+		switch c {
+		case fmtColor:
+			i = skipColor(text, i, 1, 2, isDigitByte)
+		case fmtHexColor:
+			i = skipColor(text, i, 6, 6, isHexByte)
+		}
+	}
+
+	return b.String()
+}
+
+// skipColor skips the foreground and optional background a color code is
+// followed by, eg. 4,12 or FF0000,000000, returning where the text goes on.
+// Colors are from min to max digits long.
+func skipColor(text string, i, min, max int, valid func(byte) bool) int {
+	fg := skipDigits(text, i, min, max, valid)
+	if fg == i {
+		// a lone color code resets the colors, a comma after it is text
+		return i
+	}
+
+	if fg < len(text) && text[fg] == ',' {
+		if bg := skipDigits(text, fg+1, min, max, valid); bg > fg+1 {
+			return bg
+		}
+	}
+	return fg
+}
+
+// skipDigits skips from min to max digits from i on, none when there are
+// fewer than min.
+func skipDigits(text string, i, min, max int, valid func(byte) bool) int {
+	end := i
+	for end < len(text) && end-i < max && valid(text[end]) {
+		end++
+	}
+	if end-i < min {
+		return i
+	}
+	return end
+}
+
+func isFormatting(c byte) bool {
+	switch c {
+	case fmtBold, fmtColor, fmtHexColor, fmtReset, fmtMonospace, fmtReverse,
+		fmtItalic, fmtStrikethrough, fmtUnderline:
+		return true
+	}
+	return false
+}
+
+func isHexByte(b byte) bool {
+	return isDigitByte(b) || b >= 'a' && b <= 'f' || b >= 'A' && b <= 'F'
+}
@@ -0,0 +1,57 @@
+package stats
+
+import "testing"
+
+func TestStripFormatting(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		expect string
+	}{
+		{"plain text", "plain text"},
+		{"\x02bold\x02 and \x1funderlined\x1f", "bold and underlined"},
+		{"\x1ditalic\x0f reset \x16reverse\x1e struck\x11 mono", "italic reset reverse struck mono"},
+		{"\x034red\x03 text", "red text"},
+		{"\x0304,12red on blue\x03", "red on blue"},
+		{"\x03123 more digits", "3 more digits"},
+		{"\x03,5 not a background", ",5 not a background"},
+		{"\x034, comma after", ", comma after"},
+		{"\x04FF0000,00FF00hex\x04 colors", "hex colors"},
+		{"\x04cafe is text", "cafe is text"},
+		{"\x02", ""},
+		{"\x03", ""},
+		{"trailing \x0312,", "trailing ,"},
+		{"ünïcödé \x02stays\x02", "ünïcödé stays"},
+	}
+
+	for _, test := range tests {
+		if got := stripFormatting(test.text); got != test.expect {
+			t.Errorf("%q Expected: %q, Got: %q", test.text, test.expect, got)
+		}
+	}
+}
+
+func TestStripFormatting_allocs(t *testing.T) {
+	text := "nothing to strip in here"
+	if n := testing.AllocsPerRun(100, func() { stripFormatting(text) }); n != 0 {
+		t.Error("Should not allocate for text without formatting:", n)
+	}
+}
+
+func TestTokenize_formatting(t *testing.T) {
+	t.Parallel()
+
+	tok := tokenize("\x02\x034,1LOOK\x03\x02 at \x1fhttp://google.com\x1f")
+	plain := tokenize("LOOK at http://google.com")
+
+	if tok.Letters != plain.Letters || tok.Words != plain.Words || tok.Classes != plain.Classes {
+		t.Errorf("Should not count the formatting: %+v %+v", tok, plain)
+	}
+	if len(tok.URLs) != 1 || tok.URLs[0] != "http://google.com" {
+		t.Error("Should find urls without the formatting:", tok.URLs)
+	}
+	if len(tok.Terms) != 2 || tok.Terms[0] != "look" {
+		t.Error("Should count the words without the formatting:", tok.Terms)
+	}
+}
@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// fuzzSeeds are lines that have broken counters before, or come close:
+// invalid UTF-8, combining marks, zero width joiners, formatting codes and
+// lines that are all separators.
+var fuzzSeeds = []string{
+	"",
+	" ",
+	"hello world",
+	"\xff\xfe\xfd",
+	"é́́",
+	"👩‍👩‍👧‍👦 family",
+	"\x02bold\x02 \x0304,12color\x03 \x1ditalic",
+	"\x03",
+	"\x0399,99",
+	"s/foo/bar/",
+	"s///",
+	"phish: " + nick + ", " + nick,
+	"http:// https://x http://[::1",
+	"#hash @handle :) :(",
+	"ΑΒΓ ΔΕΖ",
+	"!!!???...",
+	"​​​",
+	"\x00\x01\x7f",
+	"+o-v+b" + nick,
+}
+
+// fuzzCounts are the counters that only go up as messages are added.
+type fuzzCounts struct {
+	channelMessages, userMessages uint
+	lines, words, letters         uint
+	chatLines                     uint
+	hours                         uint
+}
+
+// countsOf reads the counters of the channel and the user.
+func countsOf(c *Channel, u *User) fuzzCounts {
+	var k fuzzCounts
+	k.channelMessages = c.MessageCount
+	k.userMessages = u.MessageCount
+	k.lines = u.Lines
+	k.words = u.Words
+	k.letters = u.Letters
+	for _, t := range c.TextByKind {
+		k.chatLines += t.Lines
+	}
+	for _, n := range c.HourlyChart {
+		k.hours += uint(n)
+	}
+	return k
+}
+
+// checkCounts checks the counters didn't go down from before and add up.
+func checkCounts(t *testing.T, before, after fuzzCounts, c *Channel, u *User) {
+	t.Helper()
+
+	if after.channelMessages < before.channelMessages || after.userMessages < before.userMessages ||
+		after.lines < before.lines || after.words < before.words || after.letters < before.letters ||
+		after.chatLines < before.chatLines || after.hours < before.hours {
+		t.Fatalf("Counters went down from %+v to %+v", before, after)
+	}
+
+	// the user's text counters are those of its messages
+	if m := u.TextByKind[Msg]; m.Lines != u.Lines || m.Words != u.Words || m.Letters != u.Letters {
+		t.Errorf("Text counters %+v don't add up to the messages %+v", u.BasicTextCounters, m)
+	}
+	if after.hours != c.TextByKind[Msg].Lines {
+		t.Errorf("Hourly chart counted %d messages, want %d", after.hours, c.TextByKind[Msg].Lines)
+	}
+	if after.chatLines > after.channelMessages {
+		t.Errorf("Counted %d chat lines of %d messages", after.chatLines, after.channelMessages)
+	}
+	if wpl := u.WordsPerLine(); wpl < 0 {
+		t.Errorf("Words per line is %f", wpl)
+	}
+}
+
+func FuzzStats_AddMessage(f *testing.F) {
+	for i, seed := range fuzzSeeds {
+		f.Add(uint8(i), seed)
+	}
+
+	kinds := uint8(len(kindNames))
+	date := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	f.Fuzz(func(t *testing.T, kind uint8, text string) {
+		s := newStats()
+		k := MsgKind(kind % kinds)
+
+		s.AddMessage(Msg, network, channel, hostmask, date, "before")
+		c := s.GetChannel(network, channel)
+		u := s.GetUser(network, nick)
+
+		before := countsOf(c, u)
+		// the text twice, then once in the past as if it was played back
+		for i, d := range []time.Time{date.Add(time.Second), date.Add(time.Minute), date.Add(-time.Hour)} {
+			s.AddMessage(k, network, channel, hostmask, d, text)
+
+			after := countsOf(c, u)
+			checkCounts(t, before, after, c, u)
+			if after.channelMessages != before.channelMessages+1 {
+				t.Fatalf("Message %d: counted %d messages, want %d", i, after.channelMessages, before.channelMessages+1)
+			}
+			before = after
+		}
+
+		for w := range c.WordCounter.All {
+			if !utf8.ValidString(w) {
+				t.Errorf("Counted word %q isn't UTF-8", w)
+			}
+		}
+	})
+}
+
+func FuzzTokenize(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		tok := tokenize(text)
+		if len(tok.Lower) != len(tok.Fields) || len(tok.Terms) > len(tok.Fields) {
+			t.Errorf("Split %d fields into %d lower and %d terms", len(tok.Fields), len(tok.Lower), len(tok.Terms))
+		}
+		if tok.Words > tok.Letters {
+			t.Errorf("Counted %d words of %d letters", tok.Words, tok.Letters)
+		}
+	})
+}
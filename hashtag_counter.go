@@ -0,0 +1,53 @@
+package stats
+
+import (
+	"regexp"
+	"strings"
+)
+
+var tokenRegexHashtag = regexp.MustCompile(`^#([\pL_][\pL\pN_]*)[\?!;,\.:]*$`)
+var tokenRegexHandle = regexp.MustCompile(`^@([\pL\pN_][\pL\pN_\.\-]*[\pL\pN_]|[\pL\pN_])[\?!;,:\.]*$`)
+
+type HashtagCounter struct {
+	TokenCounter
+}
+
+func NewHashtagCounter() HashtagCounter {
+	return HashtagCounter{
+		NewTokenCounter(),
+	}
+}
+
+func (h *HashtagCounter) addMessage(m *Message) {
+	t := m.tokens()
+	for _, word := range t.Fields {
+		if word[0] != '#' {
+			continue
+		}
+		if r := tokenRegexHashtag.FindStringSubmatch(word); r != nil {
+			h.TokenCounter.addToken(t.intern("#" + strings.ToLower(r[1])))
+		}
+	}
+}
+
+type HandleCounter struct {
+	TokenCounter
+}
+
+func NewHandleCounter() HandleCounter {
+	return HandleCounter{
+		NewTokenCounter(),
+	}
+}
+
+func (h *HandleCounter) addMessage(m *Message) {
+	t := m.tokens()
+	for _, word := range t.Fields {
+		if word[0] != '@' {
+			continue
+		}
+		if r := tokenRegexHandle.FindStringSubmatch(word); r != nil {
+			h.TokenCounter.addToken(t.intern("@" + strings.ToLower(r[1])))
+		}
+	}
+}
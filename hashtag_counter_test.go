@@ -0,0 +1,43 @@
+package stats
+
+import "testing"
+
+func TestHashtagCounter(t *testing.T) {
+	t.Parallel()
+
+	tc := NewHashtagCounter()
+
+	m := &Message{Message: "#Golang is great #golang, #go! # #123 foo#bar"}
+	tc.addMessage(m)
+
+	if len(tc.All) != 2 {
+		t.Error("Should have two unique hashtags, Got:", tc.All)
+	}
+
+	if count := tc.All["#golang"]; count != 2 {
+		t.Error("Should count #golang case insensitively.")
+	}
+
+	if tok := tc.Top[0]; tok.Token != "#golang" || tok.Count != 2 {
+		t.Error("Top hashtag is incorrect")
+	}
+}
+
+func TestHandleCounter(t *testing.T) {
+	t.Parallel()
+
+	tc := NewHandleCounter()
+
+	m := &Message{Message: "@alice: ping @Bob.Smith, mail me at foo@bar.com @ @carol."}
+	tc.addMessage(m)
+
+	if len(tc.All) != 3 {
+		t.Error("Should have three unique handles, Got:", tc.All)
+	}
+
+	for _, h := range []string{"@alice", "@bob.smith", "@carol"} {
+		if tc.All[h] != 1 {
+			t.Error("Should have counted", h)
+		}
+	}
+}
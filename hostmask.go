@@ -0,0 +1,68 @@
+package stats
+
+import "strings"
+
+// Hostmask is the source of a message, nick!user@host on irc. Users that
+// don't come from irc, such as those relayed by bridges or imported from
+// logs, often only have a nick.
+type Hostmask struct {
+	Nick string
+	User string
+	Host string
+}
+
+// ParseHostmask splits a hostmask into its parts, a leading : as in raw irc
+// prefixes is dropped. It fails when there's no nick or a part holds spaces,
+// control characters or more separators, such hostmasks aren't counted.
+func ParseHostmask(s string) (Hostmask, bool) {
+	s = strings.TrimPrefix(s, ":")
+
+	var h Hostmask
+	if i := strings.IndexByte(s, '@'); i >= 0 {
+		s, h.Host = s[:i], s[i+1:]
+		if len(h.Host) == 0 {
+			return h, false
+		}
+	}
+	if i := strings.IndexByte(s, '!'); i >= 0 {
+		s, h.User = s[:i], s[i+1:]
+		if len(h.User) == 0 {
+			return h, false
+		}
+	}
+	h.Nick = s
+
+	ok := len(h.Nick) > 0 && validHostmaskPart(h.Nick) && validHostmaskPart(h.User) && validHostmaskPart(h.Host) &&
+		!strings.ContainsAny(h.Host, "!@") && !strings.ContainsRune(h.Nick, ',')
+	return h, ok
+}
+
+// validHostmaskPart checks that a part of a hostmask has no white space or
+// control characters.
+func validHostmaskPart(part string) bool {
+	for i := 0; i < len(part); i++ {
+		if part[i] <= ' ' || part[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// IsServer checks if the hostmask is the name of a server rather than a
+// user, the prefix of the messages an irc server sends itself. Nicks can't
+// hold dots on irc, other chats may allow them.
+func (h Hostmask) IsServer() bool {
+	return len(h.User) == 0 && len(h.Host) == 0 && strings.ContainsRune(h.Nick, '.')
+}
+
+// String joins the parts back into a hostmask.
+func (h Hostmask) String() string {
+	s := h.Nick
+	if len(h.User) > 0 {
+		s += "!" + h.User
+	}
+	if len(h.Host) > 0 {
+		s += "@" + h.Host
+	}
+	return s
+}
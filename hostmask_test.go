@@ -0,0 +1,87 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseHostmask(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		hostmask string
+		expect   Hostmask
+		ok       bool
+	}{
+		{"fish!fish@zqz.ca", Hostmask{"fish", "fish", "zqz.ca"}, true},
+		{":fish!~fish@127.0.0.1", Hostmask{"fish", "~fish", "127.0.0.1"}, true},
+		{"fish", Hostmask{Nick: "fish"}, true},
+		{"fish@zqz.ca", Hostmask{Nick: "fish", Host: "zqz.ca"}, true},
+		{"fish!fish", Hostmask{Nick: "fish", User: "fish"}, true},
+		{"irc.zqz.ca", Hostmask{Nick: "irc.zqz.ca"}, true},
+		{"", Hostmask{}, false},
+		{"!fish@zqz.ca", Hostmask{User: "fish", Host: "zqz.ca"}, false},
+		{"fish!@zqz.ca", Hostmask{Nick: "fish"}, false},
+		{"fish!fish@", Hostmask{}, false},
+		{"fi sh!fish@zqz.ca", Hostmask{"fi sh", "fish", "zqz.ca"}, false},
+		{"fish!fish@zqz.ca@zqz.ca", Hostmask{"fish", "fish@zqz.ca", "zqz.ca"}, false},
+		{"fish\x01!fish@zqz.ca", Hostmask{"fish\x01", "fish", "zqz.ca"}, false},
+		{"fi,sh", Hostmask{Nick: "fi,sh"}, false},
+	}
+
+	for _, test := range tests {
+		h, ok := ParseHostmask(test.hostmask)
+		if ok != test.ok || ok && h != test.expect {
+			t.Errorf("%q Expected: %+v %v, Got: %+v %v", test.hostmask, test.expect, test.ok, h, ok)
+		}
+		if ok && h.String() != test.hostmask && ":"+h.String() != test.hostmask {
+			t.Errorf("%q Should join back, Got: %q", test.hostmask, h.String())
+		}
+	}
+}
+
+func TestHostmask_IsServer(t *testing.T) {
+	t.Parallel()
+
+	for mask, expect := range map[string]bool{
+		"irc.zqz.ca":     true,
+		"fish":           false,
+		"fish!a@zqz.ca":  false,
+		"j.doe!j@zqz.ca": false,
+	} {
+		if h, _ := ParseHostmask(mask); h.IsServer() != expect {
+			t.Errorf("%q Expected: %v", mask, expect)
+		}
+	}
+}
+
+func TestStats_AddMessageHostmask(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "fish!fish@zqz.ca", time.Now(), "hi")
+	s.AddMessage(Msg, network, channel, "fish", time.Now(), "bridged")
+	s.AddMessage(Msg, network, channel, "!nobody@zqz.ca", time.Now(), "no nick")
+	s.AddMessage(Msg, network, channel, "", time.Now(), "nothing")
+	s.AddMessage(Mode, network, channel, "irc.zqz.ca", time.Now(), "+nt")
+	s.AddMessage(Notice, network, "", ":irc.zqz.ca", time.Now(), "*** Looking up your hostname")
+
+	u := s.GetUser(network, "fish")
+	if u == nil || u.Lines != 2 {
+		t.Fatal("Should count the user's messages:", u)
+	}
+	if u.Username != "fish" || u.Host != "zqz.ca" || u.Hostmask != "fish!fish@zqz.ca" {
+		t.Error("Should keep the hostmask the user was last seen with:", u.Username, u.Host, u.Hostmask)
+	}
+	if c := s.GetChannel(network, channel); c.MessageCount != 2 || len(c.UserIDs) != 1 {
+		t.Error("Should not count malformed hostmasks:", c.MessageCount, c.UserIDs)
+	}
+	if s.GetUser(network, "irc.zqz.ca") != nil || len(s.Users) != 1 {
+		t.Error("Should not count servers as users.")
+	}
+
+	s.AddMessage(Msg, network, channel, "fish!~fish@127.0.0.1", time.Now(), "moved")
+	if u.Hostmask != "fish!~fish@127.0.0.1" {
+		t.Error("Should update the hostmask:", u.Hostmask)
+	}
+}
@@ -1,5 +1,8 @@
 package stats
 
+import "time"
+
+// HourlyChart counts the lines said in every hour of the day, in UTC.
 type HourlyChart [24]int
 
 // addMessage adds a message to the chart
@@ -7,3 +10,22 @@ func (h *HourlyChart) addMessage(m *Message) {
 	hour := m.Date.Hour()
 	h[hour]++
 }
+
+// In turns the chart into the hours of a timezone, by the offset it has at
+// now, eg. the time of the clock of the stats, see ReadTx.Now. The chart keeps
+// no dates, so it's only an approximation for the timezones with daylight
+// saving time: the hours counted under another offset are an hour off. Offsets
+// that aren't whole hours are rounded down to the hour.
+func (h HourlyChart) In(loc *time.Location, now time.Time) HourlyChart {
+	_, offset := now.In(loc).Zone()
+	shift := offset / 3600
+	if offset < 0 && offset%3600 != 0 {
+		shift--
+	}
+
+	var local HourlyChart
+	for hour, lines := range h {
+		local[((hour+shift)%24+24)%24] = lines
+	}
+	return local
+}
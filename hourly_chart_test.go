@@ -32,14 +32,14 @@ func TestHourlyChart(t *testing.T) {
 func TestHourlyChartUpdates(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 	n := s.addNetwork(network)
 	c := s.addChannel(n, channel)
 	u := s.addUser(n, nick)
 	cu := u.addChannelUser(channel)
 
 	date := time.Now()
-	hour := date.Hour()
+	hour := date.UTC().Hour()
 
 	if n.HourlyChart[hour] != 0 {
 		t.Error("Network's chart should not have data in it")
@@ -67,3 +67,34 @@ func TestHourlyChartUpdates(t *testing.T) {
 		t.Error("User's chart should have data in it")
 	}
 }
+
+func TestHourlyChart_In(t *testing.T) {
+	t.Parallel()
+
+	var chart HourlyChart
+	chart[0], chart[23] = 1, 2
+
+	if local := chart.In(time.FixedZone("JST", 9*60*60), time.Now()); local[9] != 1 || local[8] != 2 {
+		t.Error("Should move the hours ahead:", local)
+	}
+	if local := chart.In(time.FixedZone("EST", -5*60*60), time.Now()); local[19] != 1 || local[18] != 2 {
+		t.Error("Should move the hours back:", local)
+	}
+	if local := chart.In(time.FixedZone("NST", -(3*60+30)*60), time.Now()); local[20] != 1 {
+		t.Error("Should round offsets down to the hour:", local)
+	}
+	if local := chart.In(time.UTC, time.Now()); local != chart {
+		t.Error("Should keep the hours in utc:", local)
+	}
+
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skip(err)
+	}
+	if local := chart.In(toronto, time.Date(2014, 1, 15, 12, 0, 0, 0, time.UTC)); local[19] != 1 {
+		t.Error("Should move the hours by the offset of the winter:", local)
+	}
+	if local := chart.In(toronto, time.Date(2014, 7, 15, 12, 0, 0, 0, time.UTC)); local[20] != 1 {
+		t.Error("Should move the hours by the offset of the summer:", local)
+	}
+}
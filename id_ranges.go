@@ -0,0 +1,144 @@
+package stats
+
+import "encoding/binary"
+
+// IDRanges holds the ids of messages compactly, instead of a growing slice
+// of ids. Runs of consecutive ids are kept as the gap from the run before
+// and their length, varint encoded, so a user's messages take a couple of
+// bytes each and a network's a couple of bytes for every run of them.
+type IDRanges struct {
+	// Runs are the runs before the last one, End is the last id in them.
+	Runs []byte
+	End  uint
+	// First and Last are the ids starting and ending the last run, Count is
+	// the number of ids.
+	First uint
+	Last  uint
+	Count uint
+}
+
+// add adds an id, ids are added in increasing order and the others are
+// dropped.
+func (r *IDRanges) add(id uint) {
+	switch {
+	case r.Count == 0:
+		r.First, r.Last = id, id
+	case id == r.Last+1:
+		r.Last = id
+	case id > r.Last:
+		r.flush()
+		r.First, r.Last = id, id
+	default:
+		return
+	}
+	r.Count++
+}
+
+// flush encodes the last run.
+func (r *IDRanges) flush() {
+	var b [2 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], uint64(r.First-r.End))
+	n += binary.PutUvarint(b[n:], uint64(r.Last-r.First))
+
+	r.Runs = append(r.Runs, b[:n]...)
+	r.End = r.Last
+}
+
+// Len is the number of ids.
+func (r IDRanges) Len() int {
+	return int(r.Count)
+}
+
+// Each calls f with every id in increasing order.
+func (r IDRanges) Each(f func(id uint)) {
+	r.eachRun(func(first, last uint) {
+		for id := first; id <= last; id++ {
+			f(id)
+		}
+	})
+}
+
+// All returns every id in increasing order.
+func (r IDRanges) All() []uint {
+	ids := make([]uint, 0, r.Count)
+	r.Each(func(id uint) { ids = append(ids, id) })
+	return ids
+}
+
+func (r IDRanges) eachRun(f func(first, last uint)) {
+	if r.Count == 0 {
+		return
+	}
+
+	var end uint
+	for b := r.Runs; len(b) > 0; {
+		gap, n := binary.Uvarint(b)
+		length, m := binary.Uvarint(b[n:])
+		if n <= 0 || m <= 0 {
+			break
+		}
+		b = b[n+m:]
+
+		first := end + uint(gap)
+		end = first + uint(length)
+		f(first, end)
+	}
+	f(r.First, r.Last)
+}
+
+// before returns the first and last of the ids before the oldest one kept, and
+// how many there are.
+func (r IDRanges) before(oldest uint) (first, last, count uint) {
+	r.eachRun(func(start, end uint) {
+		if start >= oldest {
+			return
+		}
+		if end >= oldest {
+			end = oldest - 1
+		}
+		if count == 0 {
+			first = start
+		}
+		last = end
+		count += end - start + 1
+	})
+	return first, last, count
+}
+
+// countBefore is how many ids are before the oldest one kept.
+func (r IDRanges) countBefore(oldest uint) uint {
+	_, _, count := r.before(oldest)
+	return count
+}
+
+// prune drops the ids before the oldest one kept.
+func (r *IDRanges) prune(oldest uint) {
+	if r.Count == 0 || r.First >= oldest && len(r.Runs) == 0 {
+		return
+	}
+
+	var kept IDRanges
+	r.eachRun(func(first, last uint) {
+		if last < oldest {
+			return
+		}
+		if first < oldest {
+			first = oldest
+		}
+		if kept.Count > 0 {
+			kept.flush()
+		}
+		kept.First, kept.Last = first, last
+		kept.Count += last - first + 1
+	})
+	*r = kept
+}
+
+// idRanges moves ids saved in a slice, before they were kept in ranges.
+func idRanges(ids []uint) IDRanges {
+	var r IDRanges
+	for _, id := range ids {
+		r.add(id)
+	}
+	return r
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIDRanges(t *testing.T) {
+	t.Parallel()
+
+	var r IDRanges
+	if r.Len() != 0 || len(r.All()) != 0 {
+		t.Error("Should be empty.")
+	}
+
+	ids := []uint{1, 2, 3, 7, 9, 10, 300, 301, 100000}
+	for _, id := range ids {
+		r.add(id)
+	}
+	r.add(5)
+	r.add(301)
+
+	if r.Len() != len(ids) {
+		t.Error("Wrong length:", r.Len())
+	}
+	if all := r.All(); !reflect.DeepEqual(all, ids) {
+		t.Error("Should drop ids out of order, Got:", all)
+	}
+	if len(r.Runs) > 12 {
+		t.Error("Should encode the runs compactly:", r.Runs)
+	}
+}
+
+func TestIDRanges_prune(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		oldest uint
+		expect []uint
+	}{
+		{0, []uint{2, 3, 4, 8, 10, 11}},
+		{3, []uint{3, 4, 8, 10, 11}},
+		{5, []uint{8, 10, 11}},
+		{11, []uint{11}},
+		{12, []uint{}},
+	}
+
+	for _, test := range tests {
+		r := idRanges([]uint{2, 3, 4, 8, 10, 11})
+		r.prune(test.oldest)
+		if all := r.All(); !reflect.DeepEqual(all, test.expect) || r.Len() != len(test.expect) {
+			t.Errorf("%d Expected: %v, Got: %v (%d)", test.oldest, test.expect, all, r.Len())
+		}
+
+		r.add(20)
+		if all := r.All(); all[len(all)-1] != 20 || r.Len() != len(test.expect)+1 {
+			t.Error("Should keep adding ids after pruning:", all)
+		}
+	}
+}
+
+func TestIDRanges_before(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		oldest             uint
+		first, last, count uint
+	}{
+		{0, 0, 0, 0},
+		{3, 2, 2, 1},
+		{9, 2, 8, 4},
+		{12, 2, 11, 6},
+	}
+
+	r := idRanges([]uint{2, 3, 4, 8, 10, 11})
+	for _, test := range tests {
+		if first, last, count := r.before(test.oldest); first != test.first || last != test.last || count != test.count {
+			t.Errorf("%d Expected: %d-%d (%d), Got: %d-%d (%d)", test.oldest, test.first, test.last, test.count, first, last, count)
+		}
+	}
+}
+
+func TestStats_migrateMessageIDs(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	u := s.GetUser(network, nick)
+	u.MessageRanges, u.MessageIDs, u.MessageCount = IDRanges{}, []uint{1, 2, 4}, 0
+
+	s.buildIndexes()
+	if !reflect.DeepEqual(u.MessageRanges.All(), []uint{1, 2, 4}) || u.MessageIDs != nil {
+		t.Error("Should move the ids to ranges:", u.MessageRanges.All(), u.MessageIDs)
+	}
+	if u.MessageCount != 3 {
+		t.Error("Should count the messages:", u.MessageCount)
+	}
+}
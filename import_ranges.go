@@ -0,0 +1,82 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ImportRange is a span of time whose logs have already been imported.
+type ImportRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ImportRanges are the sorted, non overlapping ranges imported for a channel.
+type ImportRanges []ImportRange
+
+// Covers checks if the date falls inside of one of the ranges.
+func (r ImportRanges) Covers(date time.Time) bool {
+	i := sort.Search(len(r), func(i int) bool {
+		return !r[i].End.Before(date)
+	})
+
+	return i < len(r) && !r[i].Start.After(date)
+}
+
+// add inserts a range, merging it with any ranges it overlaps.
+func (r ImportRanges) add(n ImportRange) ImportRanges {
+	merged := make(ImportRanges, 0, len(r)+1)
+	for _, o := range r {
+		if o.End.Before(n.Start) || o.Start.After(n.End) {
+			merged = append(merged, o)
+			continue
+		}
+		if o.Start.Before(n.Start) {
+			n.Start = o.Start
+		}
+		if o.End.After(n.End) {
+			n.End = o.End
+		}
+	}
+	merged = append(merged, n)
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Start.Before(merged[j].Start)
+	})
+	return merged
+}
+
+func importKey(network, channel string) string {
+	return strings.ToLower(network) + " " + strings.ToLower(channel)
+}
+
+// Imported checks if logs of the channel at the date were already imported.
+func (s *Stats) Imported(network, channel string, date time.Time) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.imported(network, channel, date)
+}
+
+func (s *Stats) imported(network, channel string, date time.Time) bool {
+	return s.Imports[importKey(network, channel)].Covers(date)
+}
+
+// MarkImported records that the logs of a channel from start to end have been
+// imported so that importing them again can skip them.
+func (s *Stats) MarkImported(network, channel string, start, end time.Time) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.markImported(network, channel, start, end)
+}
+
+func (s *Stats) markImported(network, channel string, start, end time.Time) {
+	if s.Imports == nil {
+		s.Imports = make(map[string]ImportRanges)
+	}
+
+	key := importKey(network, channel)
+	s.Imports[key] = s.Imports[key].add(ImportRange{Start: start, End: end})
+}
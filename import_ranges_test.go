@@ -0,0 +1,48 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImportRanges(t *testing.T) {
+	t.Parallel()
+
+	day := func(d int) time.Time {
+		return time.Date(2014, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	var r ImportRanges
+	r = r.add(ImportRange{day(5), day(7)})
+	r = r.add(ImportRange{day(1), day(2)})
+	r = r.add(ImportRange{day(6), day(9)})
+
+	if len(r) != 2 {
+		t.Fatal("Should have merged the overlapping ranges:", r)
+	}
+	if !r[0].Start.Equal(day(1)) || !r[1].Start.Equal(day(5)) || !r[1].End.Equal(day(9)) {
+		t.Error("Ranges are wrong:", r)
+	}
+
+	if !r.Covers(day(2)) || !r.Covers(day(8)) {
+		t.Error("Should cover the imported days.")
+	}
+	if r.Covers(day(3)) || r.Covers(day(10)) {
+		t.Error("Should not cover days that weren't imported.")
+	}
+}
+
+func TestStats_MarkImported(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	s.MarkImported(network, channel, start, start.Add(time.Hour))
+
+	if !s.Imported("TEST_network", channel, start.Add(time.Minute)) {
+		t.Error("Should have imported the range.")
+	}
+	if s.Imported(network, "#other", start.Add(time.Minute)) {
+		t.Error("Should only apply to the channel.")
+	}
+}
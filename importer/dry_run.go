@@ -0,0 +1,74 @@
+package importer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Tally counts the messages imported into each channel, to see what an import
+// would add before adding it: set it as the sink of stats that are thrown
+// away rather than saved, import into them, and read Channels.
+//
+//	tally := &importer.Tally{}
+//	s.SetOptions(stats.Options{Sink: tally})
+type Tally struct {
+	mut      sync.Mutex
+	channels map[string]*ChannelTally
+}
+
+// ChannelTally is what was imported into a channel, the channel is empty for
+// the messages outside channels, such as quits.
+type ChannelTally struct {
+	Network  string
+	Channel  string
+	Messages uint
+	// From and To are the dates of the first and last messages.
+	From time.Time
+	To   time.Time
+}
+
+// Sink counts a message.
+func (t *Tally) Sink(m stats.SinkMessage) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if t.channels == nil {
+		t.channels = make(map[string]*ChannelTally)
+	}
+	key := strings.ToLower(m.Network + " " + m.Channel)
+	c, ok := t.channels[key]
+	if !ok {
+		c = &ChannelTally{Network: m.Network, Channel: m.Channel, From: m.Date, To: m.Date}
+		t.channels[key] = c
+	}
+	c.Messages++
+	if m.Date.Before(c.From) {
+		c.From = m.Date
+	}
+	if m.Date.After(c.To) {
+		c.To = m.Date
+	}
+}
+
+// Channels are the channels counted so far, sorted by network and channel.
+func (t *Tally) Channels() []ChannelTally {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	channels := make([]ChannelTally, 0, len(t.channels))
+	for _, c := range t.channels {
+		channels = append(channels, *c)
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		a, b := channels[i], channels[j]
+		if a.Network != b.Network {
+			return a.Network < b.Network
+		}
+		return a.Channel < b.Channel
+	})
+	return channels
+}
@@ -0,0 +1,42 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestTally(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tally := &Tally{}
+	s.SetOptions(stats.Options{Sink: tally})
+
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#deviate"
+	if _, err := im.Import(Source{Name: "test"}, strings.NewReader(weechatLog), weechat.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Quit, "zkpq", "", "aaron", time.Date(2014, 3, 2, 0, 0, 0, 0, time.UTC), "bye")
+
+	channels := tally.Channels()
+	if len(channels) != 2 {
+		t.Fatal("Should count the channel and the messages outside channels:", channels)
+	}
+	if c := channels[0]; c.Channel != "" || c.Messages != 1 {
+		t.Error("Should count the quit outside channels:", c)
+	}
+	c := channels[1]
+	if c.Network != "zkpq" || c.Channel != "#deviate" || c.Messages != 3 {
+		t.Error("Should count the lines imported:", c)
+	}
+	if from, to := c.From.Format("15:04:05"), c.To.Format("15:04:05"); from != "08:00:00" || to != "08:01:00" {
+		t.Error("Should date the first and last lines, dated", from, to)
+	}
+}
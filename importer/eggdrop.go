@@ -0,0 +1,26 @@
+package importer
+
+import "regexp"
+
+func init() {
+	Register("eggdrop", eggdrop.New)
+}
+
+// eggdrop reads channel logs written by an eggdrop bot. Lines only carry the
+// time of day, the day is taken from the markers eggdrop writes when the day
+// changes.
+var eggdrop = Format{
+	DateFormat: "15:04",
+	DayFormat:  "Mon Jan _2 2006",
+	DayChange:  regexp.MustCompile(`^\[\d\d:\d\d(?::\d\d)?\] --- (?P<day>\w{3} \w{3} [ \d]\d) (?P<year>\d{4})$`),
+
+	Message: regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] <(?P<nick>[^>\s]+)> (?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] (?P<nick>\S+) \((?P<host>[^)]*)\) joined (?P<channel>\S+)\.$`),
+	Part:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] (?P<nick>\S+) \((?P<host>[^)]*)\) left (?P<channel>[^\s.]+)(?: \((?P<message>.*)\))?\.$`),
+	Quit:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] (?P<nick>\S+) \((?P<host>[^)]*)\) left irc: (?P<message>.*)$`),
+	Kick:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] (?P<target>\S+) kicked from (?P<channel>\S+) by (?P<nick>[^\s:]+): (?P<message>.*)$`),
+	Topic:   regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] Topic changed on (?P<channel>\S+) by (?P<nick>[^\s!]+)(?:!\S+)?: (?P<topic>.*)$`),
+	Mode:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] (?P<channel>\S+): mode change '(?P<mode>\S+)[^']*' by (?P<nick>[^\s!]+)(?:!\S+)?$`),
+	Nick:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] Nick change: (?P<nick>\S+) -> (?P<new>\S+)$`),
+	Action:  regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] Action: (?P<nick>\S+) (?P<action>.*)$`),
+}
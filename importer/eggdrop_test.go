@@ -0,0 +1,49 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+const eggdropLog = `[23:58] dylan (dylan@zqz.ca) joined #deviate.
+[23:58] <dylan> hello there
+[23:59] Action: dylan waves
+[00:00] --- Sun Mar  2 2014
+[00:01] Topic changed on #deviate by aaron!aaron@host: welcome
+[00:02] #deviate: mode change '+o dylan' by aaron!aaron@host
+[00:03] knivey kicked from #deviate by aaron: bye
+[00:04] Nick change: dylan -> dylan_
+[00:05] <dylan_> still here
+[00:06] dylan_ (dylan@zqz.ca) left #deviate (peace out).
+[00:07] aaron (aaron@host) left irc: Ping timeout
+`
+
+func TestEggdrop_parse(t *testing.T) {
+	t.Parallel()
+
+	s := importLog(t, eggdrop, eggdropLog)
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Channel should exist.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "welcome" {
+		t.Error("Should have the topic.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.NickChanges != 1 {
+		t.Fatal("Should have counted dylan's line and nick change:", u)
+	}
+
+	if u = s.GetUser("zkpq", "dylan_"); u == nil || u.Lines != 1 {
+		t.Fatal("Should have counted dylan_'s line:", u)
+	}
+	if want := time.Date(2014, 3, 2, 0, 6, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should use the day from the day change marker, Got:", u.LastSeen)
+	}
+
+	if aaron := s.GetUser("zkpq", "aaron"); aaron.Ops != 1 || aaron.KickCounters.Sent != 1 {
+		t.Error("Should count aaron's mode and kick.")
+	}
+}
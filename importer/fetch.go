@@ -0,0 +1,381 @@
+package importer
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults used when the fetcher's settings are left empty.
+const (
+	DefaultFetchDelay   = time.Second
+	DefaultFetchRetries = 3
+
+	defaultArchiveOrg = "https://archive.org"
+)
+
+// Fetcher downloads logs published over http and imports them, for
+// backfilling a channel's stats from its public archives. A url is either a
+// log, an index page linking to logs and to the directories below it, or an
+// archive.org item such as https://archive.org/details/some-logs.
+type Fetcher struct {
+	Importer *Importer
+	Factory  Factory
+	Location *time.Location
+
+	// Delay is the least time between two requests, so archives aren't
+	// hammered.
+	Delay time.Duration
+	// Retries is how many times a failed or broken off download is retried,
+	// resuming where it left off when the server allows it.
+	Retries int
+
+	// Done are the urls of the logs already imported, they are not
+	// downloaded again. Fetched, if set, is called after each log has been
+	// imported so the progress can be saved and an interrupted backfill
+	// resumed.
+	Done    map[string]bool
+	Fetched func(url string)
+
+	// ArchiveOrg is the base url of archive.org, for tests.
+	ArchiveOrg string
+	HTTP       *http.Client
+
+	last time.Time
+}
+
+// NewFetcher creates a fetcher importing the logs with parsers from the
+// factory.
+func NewFetcher(im *Importer, f Factory, loc *time.Location) *Fetcher {
+	return &Fetcher{
+		Importer: im,
+		Factory:  f,
+		Location: loc,
+		Delay:    DefaultFetchDelay,
+		Retries:  DefaultFetchRetries,
+		Done:     make(map[string]bool),
+	}
+}
+
+// Fetch imports the logs at the url.
+func (f *Fetcher) Fetch(rawurl string) error {
+	return f.FetchContext(context.Background(), rawurl)
+}
+
+// FetchContext is Fetch, giving up once the context is done, waits between
+// requests included. The logs imported before are kept and left Done.
+func (f *Fetcher) FetchContext(ctx context.Context, rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+
+	if item, ok := f.archiveItem(u); ok {
+		return f.fetchItem(ctx, item)
+	}
+	if strings.HasSuffix(u.Path, "/") || len(u.Path) == 0 {
+		return f.fetchIndex(ctx, u, make(map[string]bool))
+	}
+	return f.fetchLog(ctx, u)
+}
+
+// archiveItem checks if the url is the page of an archive.org item.
+func (f *Fetcher) archiveItem(u *url.URL) (string, bool) {
+	base, err := url.Parse(f.archiveOrg())
+	if err != nil || !strings.EqualFold(u.Host, base.Host) || !strings.HasPrefix(u.Path, "/details/") {
+		return "", false
+	}
+
+	item := strings.Trim(strings.TrimPrefix(u.Path, "/details/"), "/")
+	return item, len(item) > 0 && !strings.Contains(item, "/")
+}
+
+// fetchItem imports the logs among the files of an archive.org item.
+func (f *Fetcher) fetchItem(ctx context.Context, item string) error {
+	base := f.archiveOrg()
+	resp, err := f.get(ctx, base+"/metadata/"+url.PathEscape(item), 0)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var meta struct {
+		Files []struct {
+			Name string `json:"name"`
+		} `json:"files"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return fmt.Errorf("Bad metadata for %s: %v", item, err)
+	}
+
+	for _, file := range meta.Files {
+		if !isFetchedLog(file.Name) {
+			continue
+		}
+		u, err := url.Parse(base + "/download/" + url.PathEscape(item) + "/" + (&url.URL{Path: file.Name}).EscapedPath())
+		if err != nil {
+			return err
+		}
+		if err = f.fetchLog(ctx, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var indexLink = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'#?]+)["']`)
+
+// fetchIndex imports the logs linked from an index page, and those of the
+// index pages of the directories below it.
+func (f *Fetcher) fetchIndex(ctx context.Context, index *url.URL, seen map[string]bool) error {
+	if seen[index.String()] {
+		return nil
+	}
+	seen[index.String()] = true
+
+	resp, err := f.get(ctx, index.String(), 0)
+	if err != nil {
+		return err
+	}
+	page, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxIndexSize))
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	for _, match := range indexLink.FindAllSubmatch(page, -1) {
+		link, err := index.Parse(string(match[1]))
+		if err != nil || link.Host != index.Host || !strings.HasPrefix(link.Path, index.Path) || link.Path == index.Path {
+			// parents, sorting links and other sites
+			continue
+		}
+		link.RawQuery, link.Fragment = "", ""
+
+		if strings.HasSuffix(link.Path, "/") {
+			err = f.fetchIndex(ctx, link, seen)
+		} else if isFetchedLog(path.Base(link.Path)) {
+			err = f.fetchLog(ctx, link)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxIndexSize is the largest index page read.
+const maxIndexSize = 16 << 20
+
+// isFetchedLog checks if a file linked from an archive is a log, published
+// logs are often named .txt and compressed.
+func isFetchedLog(name string) bool {
+	name = strings.TrimSuffix(name, ".gz")
+	return isLog(name) || path.Ext(name) == ".txt"
+}
+
+// fetchLog downloads and imports a single log.
+func (f *Fetcher) fetchLog(ctx context.Context, u *url.URL) error {
+	key := u.String()
+	if f.Done[key] {
+		return nil
+	}
+
+	p := f.Factory(f.Location)
+	src := Source{Name: key}
+	if pp, ok := p.(PathParser); ok {
+		if src, ok = pp.ParsePath(strings.TrimSuffix(u.Path, ".gz")); !ok {
+			return &PathError{Path: key}
+		}
+		src.Name = key
+	}
+
+	resp, err := f.get(ctx, key, 0)
+	if err != nil {
+		return err
+	}
+	r := &resumingReader{
+		get:   func(offset int64) (*http.Response, error) { return f.get(ctx, key, offset) },
+		body:  resp.Body,
+		tries: f.Retries,
+	}
+	defer r.Close()
+
+	var body io.Reader = r
+	if strings.HasSuffix(u.Path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%s: %v", key, err)
+		}
+		body = gz
+	}
+
+	if _, err = f.Importer.ImportContext(ctx, src, body, p); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+
+	if f.Done == nil {
+		f.Done = make(map[string]bool)
+	}
+	f.Done[key] = true
+	if f.Fetched != nil {
+		f.Fetched(key)
+	}
+	return nil
+}
+
+// get requests a url from the offset on, waiting out the delay between
+// requests and retrying when the server fails or asks to slow down, until the
+// context is done.
+func (f *Fetcher) get(ctx context.Context, rawurl string, offset int64) (*http.Response, error) {
+	client := f.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var err error
+	for try := 0; try <= f.Retries; try++ {
+		if err := f.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, "GET", rawurl, nil); err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+
+		var resp *http.Response
+		if resp, err = client.Do(req); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			f.Importer.logger().Warn("Retrying a download", "url", rawurl, "err", err)
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+			resp.Body.Close()
+			err = fmt.Errorf("%s: %s", rawurl, resp.Status)
+			f.Importer.logger().Warn("Backing off a download", "url", rawurl, "status", resp.Status, "retry_after", resp.Header.Get("Retry-After"))
+			if err := f.backOff(ctx, resp.Header.Get("Retry-After")); err != nil {
+				return nil, err
+			}
+			continue
+		case resp.StatusCode/100 != 2:
+			body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s: %s: %s", rawurl, resp.Status, strings.TrimSpace(string(body)))
+		}
+
+		if offset > 0 && resp.StatusCode != http.StatusPartialContent {
+			// the server can't resume, skip what was already read
+			if _, err = io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				continue
+			}
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+// wait sleeps until the delay since the last request has passed, or the
+// context is done.
+func (f *Fetcher) wait(ctx context.Context) error {
+	if d := f.Delay - time.Since(f.last); d > 0 {
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+	f.last = time.Now()
+	return nil
+}
+
+// backOff waits as long as a server asked in its Retry-After header, within
+// reason, or until the context is done.
+func (f *Fetcher) backOff(ctx context.Context, retryAfter string) error {
+	d := 10 * f.Delay
+	if secs, err := strconv.Atoi(retryAfter); err == nil {
+		d = time.Duration(secs) * time.Second
+	}
+	if d > 5*time.Minute {
+		d = 5 * time.Minute
+	}
+	return sleep(ctx, d)
+}
+
+// sleep waits for d to pass, or returns the error of the context once it's
+// done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *Fetcher) archiveOrg() string {
+	if len(f.ArchiveOrg) == 0 {
+		return defaultArchiveOrg
+	}
+	return strings.TrimSuffix(f.ArchiveOrg, "/")
+}
+
+// resumingReader reads a download, requesting the rest of it again when the
+// connection breaks off.
+type resumingReader struct {
+	// get requests the download from an offset on
+	get  func(offset int64) (*http.Response, error)
+	body io.ReadCloser
+	n    int64
+	// tries is how many times the download may be requested again
+	tries   int
+	retries int
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.body.Read(p)
+		r.n += int64(n)
+		if n > 0 && err != io.EOF {
+			// a broken connection fails the next read too
+			return n, nil
+		}
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if r.retries >= r.tries {
+			return 0, err
+		}
+		r.retries++
+
+		r.body.Close()
+		resp, gerr := r.get(r.n)
+		if gerr != nil {
+			r.body = ioutil.NopCloser(strings.NewReader(""))
+			return 0, err
+		}
+		r.body = resp.Body
+	}
+}
+
+func (r *resumingReader) Close() error {
+	return r.body.Close()
+}
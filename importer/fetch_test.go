@@ -0,0 +1,195 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// newFetcher creates a fetcher of weechat logs of #deviate on zkpq.
+func newFetcher(s *stats.Stats) *Fetcher {
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#deviate"
+
+	weechat, _ := Lookup("weechat")
+	f := NewFetcher(im, weechat, nil)
+	f.Delay = 0
+	return f
+}
+
+func TestFetcher_index(t *testing.T) {
+	t.Parallel()
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	fmt.Fprint(w, "2014-03-02 09:00:00\tcarol\tcompressed\n")
+	w.Close()
+
+	var mut sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		requests[r.URL.Path]++
+		n := requests[r.URL.Path]
+		mut.Unlock()
+
+		switch r.URL.Path {
+		case "/logs/":
+			fmt.Fprint(w, `<a href="../">Parent</a> <a href="?C=N;O=D">Name</a>
+				<a href='2014-03-01.log'>log</a> <a href="sub/">sub</a>
+				<a href="http://example.com/other.log">elsewhere</a> <a href="README.html">readme</a>`)
+		case "/logs/sub/":
+			fmt.Fprint(w, `<a href="/logs/sub/b.txt.gz">b</a> <a href="/logs/">up</a>`)
+		case "/logs/sub/b.txt.gz":
+			w.Write(gz.Bytes())
+		case "/logs/2014-03-01.log":
+			if n == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, weechatLog)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newFetcher(s)
+	var fetched []string
+	f.Fetched = func(url string) {
+		fetched = append(fetched, url)
+	}
+
+	if err := f.Fetch(server.URL + "/logs/"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fetched) != 2 {
+		t.Fatal("Should fetch both logs:", fetched)
+	}
+	if requests["/logs/README.html"] != 0 || requests["/logs/2014-03-01.log"] != 2 {
+		t.Error("Should only fetch logs, retrying when asked to slow down:", requests)
+	}
+
+	for _, nick := range []string{"dylan", "aaron", "carol"} {
+		if u := s.GetUser("zkpq", nick); u == nil || u.Lines != 1 {
+			t.Errorf("Should count the line of %s: %v", nick, u)
+		}
+	}
+
+	if err := f.Fetch(server.URL + "/logs/"); err != nil {
+		t.Fatal(err)
+	}
+	if requests["/logs/2014-03-01.log"] != 2 || len(fetched) != 2 {
+		t.Error("Should not fetch logs again:", requests)
+	}
+}
+
+func TestFetcher_resume(t *testing.T) {
+	t.Parallel()
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+
+		if rng := r.Header.Get("Range"); len(rng) > 0 {
+			from, _ := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			w.WriteHeader(http.StatusPartialContent)
+			fmt.Fprint(w, weechatLog[from:])
+			return
+		}
+
+		// break off half way
+		w.Header().Set("Content-Length", strconv.Itoa(len(weechatLog)))
+		fmt.Fprint(w, weechatLog[:len(weechatLog)/2])
+	}))
+	defer server.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := newFetcher(s).Fetch(server.URL + "/deviate.log"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ranges) != 2 || ranges[1] != fmt.Sprintf("bytes=%d-", len(weechatLog)/2) {
+		t.Error("Should resume where the download broke off:", ranges)
+	}
+	if u := s.GetUser("zkpq", "aaron"); u == nil || u.Lines != 1 {
+		t.Error("Should count the lines after the break.")
+	}
+}
+
+func TestFetcher_archiveOrg(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metadata/deviate-logs":
+			fmt.Fprint(w, `{"files":[{"name":"deviate-logs_meta.xml"},{"name":"logs/2014-03-01.log"}]}`)
+		case "/download/deviate-logs/logs/2014-03-01.log":
+			fmt.Fprint(w, weechatLog)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newFetcher(s)
+	f.ArchiveOrg = server.URL
+
+	if err := f.Fetch(server.URL + "/details/deviate-logs"); err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should import the logs of the item.")
+	}
+
+	if err := f.Fetch(server.URL + "/missing.log"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Error("Should fail on missing logs:", err)
+	}
+}
+
+func TestFetcher_FetchContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := newFetcher(s).FetchContext(ctx, server.URL+"/deviate.log"); err != context.DeadlineExceeded {
+		t.Error("Should give up backing off once the context is done, got", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Should not wait out the Retry-After.")
+	}
+}
@@ -0,0 +1,264 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Format is a log format described by a regex for each kind of line. The
+// regexes capture their fields with named groups: date, nick, host, channel,
+// target, message, mode, topic, action and new. Regexes left nil are not
+// matched. Formats without a DateFormat leave the date of lines unset, for
+// lines that are dated by whatever carried them, such as syslog.
+type Format struct {
+	DateFormat string
+	Message    *regexp.Regexp
+	Join       *regexp.Regexp
+	Part       *regexp.Regexp
+	Kick       *regexp.Regexp
+	Quit       *regexp.Regexp
+	Action     *regexp.Regexp
+	Mode       *regexp.Regexp
+	Topic      *regexp.Regexp
+	Nick       *regexp.Regexp
+	Notice     *regexp.Regexp
+
+	// DayChange, if set, matches the lines that mark the start of a new day
+	// in the log. Its day and optional year are parsed with DayFormat, and
+	// the DateFormat then only holds the time of day.
+	DayChange *regexp.Regexp
+	DayFormat string
+	// NoYear is set when the DateFormat holds the month and day but not the
+	// year, which is then taken from the last day marker.
+	NoYear bool
+
+	// Ignore matches lines a format would otherwise mistake for another
+	// kind of line, such as status lines that look like actions.
+	Ignore *regexp.Regexp
+	// ModeNames translates modes a client logs as words back into modes.
+	ModeNames map[string]string
+
+	// FileInfo, if set, derives the network, channel and day of a log file
+	// from its path. The DateFormat then only holds the time of day.
+	FileInfo func(path string) (Source, bool)
+}
+
+// New creates a parser for the format, it satisfies Factory.
+func (f Format) New(loc *time.Location) LineParser {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	p := &formatParser{format: f, location: loc}
+	p.rules = []rule{
+		{f.Join, stats.Join, "", nil},
+		{f.Part, stats.Part, "message", nil},
+		{f.Quit, stats.Quit, "message", []string{"message"}},
+		{f.Message, stats.Msg, "message", []string{"message"}},
+		{f.Notice, stats.Notice, "message", []string{"message"}},
+		{f.Kick, stats.Kick, "target", []string{"target"}},
+		{f.Mode, stats.Mode, "mode", []string{"mode"}},
+		{f.Topic, stats.Topic, "topic", []string{"topic"}},
+		{f.Nick, stats.Nick, "new", []string{"new"}},
+		{f.Action, stats.Action, "action", []string{"action"}},
+	}
+
+	if f.FileInfo != nil {
+		return &pathFormatParser{p}
+	}
+	return p
+}
+
+// rule turns the lines matched by a regex into a kind of line.
+type rule struct {
+	regex    *regexp.Regexp
+	kind     stats.MsgKind
+	message  string
+	required []string
+}
+
+type formatParser struct {
+	format   Format
+	rules    []rule
+	location *time.Location
+
+	day  time.Time
+	last time.Time
+}
+
+// pathFormatParser is a formatParser whose format reads the source from the
+// path of the log.
+type pathFormatParser struct {
+	*formatParser
+}
+
+func (p *pathFormatParser) ParsePath(path string) (Source, bool) {
+	src, ok := p.format.FileInfo(path)
+	p.day, p.last = src.Day, time.Time{}
+	return src, ok
+}
+
+func (p *formatParser) ParseLine(line string) (Line, bool) {
+	if p.parseDayChange(line) || findData(p.format.Ignore, line) != nil {
+		return Line{}, false
+	}
+
+	for _, rule := range p.rules {
+		r := findData(rule.regex, line)
+		if r == nil {
+			continue
+		}
+
+		nick, dateString := r["nick"], r["date"]
+		if len(nick) == 0 || len(dateString) == 0 && len(p.format.DateFormat) > 0 {
+			return Line{}, false
+		}
+		for _, field := range rule.required {
+			if len(r[field]) == 0 {
+				return Line{}, false
+			}
+		}
+
+		var date time.Time
+		if len(p.format.DateFormat) > 0 {
+			var err error
+			if date, err = p.parseDate(dateString); err != nil {
+				return Line{}, false
+			}
+		}
+
+		message := r[rule.message]
+		if rule.kind == stats.Mode {
+			if m, ok := p.format.ModeNames[message]; ok {
+				message = m
+			}
+		}
+
+		return Line{Kind: rule.kind, Nick: nick, Date: date, Message: message, Channel: r["channel"]}, true
+	}
+
+	return Line{}, false
+}
+
+// parseDate parses the date of a line in the parser's location, adding the
+// day for formats that only log the time of day. When the time goes
+// backwards the day, or year, is assumed to have rolled over.
+func (p *formatParser) parseDate(date string) (time.Time, error) {
+	t, err := time.ParseInLocation(p.format.DateFormat, date, p.location)
+	if err != nil || (p.format.FileInfo == nil && p.format.DayChange == nil) {
+		return t, err
+	}
+
+	d := p.day
+	if p.format.NoYear {
+		t = time.Date(d.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), p.location)
+		if t.Before(p.last) {
+			t = t.AddDate(1, 0, 0)
+		}
+	} else {
+		t = time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), p.location)
+		if t.Before(p.last) {
+			t = t.AddDate(0, 0, 1)
+		}
+	}
+
+	p.day, p.last = t, t
+	return t, nil
+}
+
+// parseDayChange moves the day forward when the line is one of the format's
+// day markers.
+func (p *formatParser) parseDayChange(line string) bool {
+	r := findData(p.format.DayChange, line)
+	if r == nil {
+		return false
+	}
+
+	day := r["day"]
+	if year := r["year"]; len(year) > 0 {
+		day += " " + year
+	}
+
+	if d, err := time.Parse(p.format.DayFormat, day); err == nil {
+		p.day, p.last = d, time.Time{}
+	}
+	return true
+}
+
+// LoadFormat reads a format from a file that starts with a date format and
+// supplies a regex for each of: message, join, part, kick, quit, action,
+// mode, topic and optionally nick, one per line.
+func LoadFormat(r io.Reader) (Format, error) {
+	var f Format
+	var err error
+
+	scanner := bufio.NewScanner(r)
+	var line = 0
+	for ; scanner.Scan(); line++ {
+		switch line {
+		case 0:
+			f.DateFormat = scanner.Text()
+		case 1:
+			f.Message, err = regexp.Compile(scanner.Text())
+		case 2:
+			f.Join, err = regexp.Compile(scanner.Text())
+		case 3:
+			f.Part, err = regexp.Compile(scanner.Text())
+		case 4:
+			f.Kick, err = regexp.Compile(scanner.Text())
+		case 5:
+			f.Quit, err = regexp.Compile(scanner.Text())
+		case 6:
+			f.Action, err = regexp.Compile(scanner.Text())
+		case 7:
+			f.Mode, err = regexp.Compile(scanner.Text())
+		case 8:
+			f.Topic, err = regexp.Compile(scanner.Text())
+		case 9:
+			f.Nick, err = regexp.Compile(scanner.Text())
+		}
+
+		if err != nil {
+			return f, fmt.Errorf("Failed to parse line: %d: %v", line, err)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return f, fmt.Errorf("Failed to parse file: %v", err)
+	}
+	if line != 9 && line != 10 {
+		return f, fmt.Errorf("Must supply a line for each of: dateFormat, message, join, part, kick, quit, action, mode, topic")
+	}
+	return f, nil
+}
+
+func findData(regex *regexp.Regexp, line string) map[string]string {
+	if regex == nil {
+		return nil
+	}
+
+	results := make(map[string]string)
+
+	r := regex.FindStringSubmatch(line)
+
+	if r == nil {
+		return nil
+	}
+
+	names := regex.SubexpNames()
+
+	for i, n := range names[1:] {
+		results[n] = r[i+1]
+	}
+
+	if host := results["host"]; len(host) > 0 {
+		results["nick"] += "!" + host
+	}
+
+	return results
+}
@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestFormat_ParseLine(t *testing.T) {
+	t.Parallel()
+
+	p := weechat.New(nil)
+
+	l, ok := p.ParseLine("2014-03-01 08:00:00\t-->\tdylan (dylan@zqz.ca) has joined #deviate")
+	if !ok || l.Kind != stats.Join || l.Nick != "dylan!dylan@zqz.ca" {
+		t.Error("Should parse the join:", l)
+	}
+
+	l, ok = p.ParseLine("2014-03-01 08:01:00\t--\tdylan is now known as dylan_")
+	if !ok || l.Kind != stats.Nick || l.Nick != "dylan" || l.Message != "dylan_" {
+		t.Error("Should parse the nick change:", l)
+	}
+
+	if _, ok = p.ParseLine("garbage"); ok {
+		t.Error("Should not parse garbage.")
+	}
+}
+
+func TestFormat_Location(t *testing.T) {
+	t.Parallel()
+
+	p := weechat.New(time.FixedZone("EST", -5*60*60))
+
+	l, ok := p.ParseLine("2014-03-01 08:00:00\tdylan\thello")
+	if !ok {
+		t.Fatal("Should parse the message.")
+	}
+
+	if want := time.Date(2014, 3, 1, 13, 0, 0, 0, time.UTC); !l.Date.Equal(want) {
+		t.Error("Should read the date in the given location, Got:", l.Date)
+	}
+}
+
+func TestLoadFormat(t *testing.T) {
+	t.Parallel()
+
+	file := "15:04\n" +
+		`^(?P<date>\S+) <(?P<nick>\S+)> (?P<message>.*)$` + "\n" +
+		strings.Repeat(`^$`+"\n", 7)
+
+	f, err := LoadFormat(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, ok := f.New(nil).ParseLine("08:00 <dylan> hello")
+	if !ok || l.Nick != "dylan" || l.Message != "hello" {
+		t.Error("Should parse with the loaded format:", l)
+	}
+
+	if _, err = LoadFormat(strings.NewReader("15:04\n")); err == nil {
+		t.Error("Should require a regex for each kind of line.")
+	}
+	if _, err = LoadFormat(strings.NewReader("15:04\n(\n")); err == nil {
+		t.Error("Should fail on bad regexes.")
+	}
+}
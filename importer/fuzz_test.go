@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func FuzzParseLine(f *testing.F) {
+	for _, log := range []string{eggdropLog, hexchatLog, irssiLog, limnoriaLog, mircLog, weechatLog, zncLog} {
+		for _, line := range strings.Split(log, "\n") {
+			f.Add(line)
+		}
+	}
+	f.Add("2014-03-01T08:00:00Z\tmessage\t#deviate\tdylan\thello")
+	f.Add("1393660800.5\tnick\t\tdylan!dylan@zqz.ca\tdylan_")
+	f.Add(`{"date":1393660800,"kind":"action","nick":"dylan","message":"waves"}`)
+	f.Add(`{"date":"","nick":"\u0000"}`)
+	f.Add("[99:99] <\xff> \x03")
+
+	f.Fuzz(func(t *testing.T, line string) {
+		for _, name := range Names() {
+			factory, _ := Lookup(name)
+			l, ok := factory(nil).ParseLine(line)
+			if !ok {
+				continue
+			}
+			if l.Kind.String() == "unknown" {
+				t.Errorf("%s parsed %q as an unknown kind %d", name, line, l.Kind)
+			}
+			if len(l.Nick) == 0 {
+				t.Errorf("%s parsed %q without a nick", name, line)
+			}
+
+			s, err := stats.NewStats()
+			if err != nil {
+				t.Fatal(err)
+			}
+			im := New(s)
+			im.Network, im.Channel = "zkpq", "#deviate"
+			if _, err := im.Import(Source{Name: name}, strings.NewReader(line), factory(nil)); err != nil {
+				t.Errorf("%s failed importing %q: %v", name, line, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,38 @@
+package importer
+
+import "regexp"
+
+func init() {
+	Register("hexchat", hexchat.New)
+}
+
+// hexchat reads logs written by HexChat and XChat. Lines carry the month and
+// day but not the year, which is taken from the logging markers.
+var hexchat = Format{
+	DateFormat: "Jan 02 15:04:05",
+	DayFormat:  "Mon Jan _2 2006",
+	DayChange:  regexp.MustCompile(`^\*\*\*\* BEGIN LOGGING AT (?P<day>\w{3} \w{3} [ \d]\d) [0-9:]+ (?P<year>\d{4})$`),
+	NoYear:     true,
+	Ignore:     regexp.MustCompile(`^\w{3} \d\d [0-9:]+ (?:\*|-->|<--|---)\t(?:Now talking on|Topic for|Disconnected|Retrieving) `),
+
+	Message: regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) <[@%&~+]?(?P<nick>[^>\s]+)>\t(?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|-->)\t(?P<nick>\S+) \((?P<host>[^)]*)\) has joined (?P<channel>\S+)$`),
+	Part:    regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|<--)\t(?P<nick>\S+) \((?P<host>[^)]*)\) has left (?P<channel>\S+)(?: \((?P<message>.*)\))?$`),
+	Quit:    regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|<--)\t(?P<nick>\S+)(?: \((?P<host>[^)]*)\))? has quit \((?P<message>.*)\)$`),
+	Kick:    regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|<--)\t(?P<nick>\S+) has kicked (?P<target>\S+) from (?P<channel>\S+) \((?P<message>.*)\)$`),
+	Topic:   regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|---)\t(?P<nick>\S+) has changed the topic to: (?P<topic>.*)$`),
+	Mode:    regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|---)\t(?P<nick>\S+) (?:sets mode )?(?P<mode>[+-]\S+|gives channel operator status|removes channel operator status|gives channel half-operator status|removes channel half-operator status|gives voice|removes voice|sets ban|removes ban)\b.*$`),
+	Nick:    regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) (?:\*|---)\t(?P<nick>\S+) is now known as (?P<new>\S+)$`),
+	Action:  regexp.MustCompile(`^(?P<date>\w{3} \d\d [0-9:]+) \*\t(?P<nick>\S+) (?P<action>.*)$`),
+
+	ModeNames: map[string]string{
+		"gives channel operator status":        "+o",
+		"removes channel operator status":      "-o",
+		"gives channel half-operator status":   "+h",
+		"removes channel half-operator status": "-h",
+		"gives voice":                          "+v",
+		"removes voice":                        "-v",
+		"sets ban":                             "+b",
+		"removes ban":                          "-b",
+	},
+}
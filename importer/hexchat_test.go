@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+const hexchatLog = "**** BEGIN LOGGING AT Wed Dec 31 23:00:00 2014\n" +
+	"Dec 31 23:00:00 *\tNow talking on #deviate\n" +
+	"Dec 31 23:00:01 *\tdylan (dylan@zqz.ca) has joined #deviate\n" +
+	"Dec 31 23:00:05 <dylan>\thello there\n" +
+	"Dec 31 23:01:00 *\tdylan waves\n" +
+	"Dec 31 23:02:00 *\taaron has changed the topic to: welcome\n" +
+	"Dec 31 23:03:00 *\taaron gives channel operator status to dylan\n" +
+	"Dec 31 23:03:30 *\taaron sets mode +m #deviate\n" +
+	"Dec 31 23:04:00 *\taaron has kicked knivey from #deviate (bye)\n" +
+	"Jan 01 00:05:00 *\tdylan is now known as dylan_\n" +
+	"Jan 01 00:06:00 <@dylan_>\thappy new year\n" +
+	"Jan 01 00:07:00 *\tdylan_ (dylan@zqz.ca) has left #deviate (peace out)\n" +
+	"Jan 01 00:08:00 *\taaron has quit (Ping timeout)\n" +
+	"**** ENDING LOGGING AT Thu Jan  1 00:09:00 2015\n"
+
+func TestHexchat_parse(t *testing.T) {
+	t.Parallel()
+
+	s := importLog(t, hexchat, hexchatLog)
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Channel should exist.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "welcome" {
+		t.Error("Should have the topic.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.NickChanges != 1 {
+		t.Fatal("Should have counted dylan's line and nick change:", u)
+	}
+
+	if u = s.GetUser("zkpq", "dylan_"); u == nil {
+		t.Fatal("Should have dylan_.")
+	}
+	if want := time.Date(2015, 1, 1, 0, 7, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should roll over to the next year, Got:", u.LastSeen)
+	}
+
+	aaron := s.GetUser("zkpq", "aaron")
+	if aaron.Ops != 1 || aaron.KickCounters.Sent != 1 {
+		t.Error("Should translate aaron's mode and count the kick.")
+	}
+	if aaron.Deops != 0 || aaron.Lines != 0 {
+		t.Error("Should not mistake the other mode or events for lines.")
+	}
+}
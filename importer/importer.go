@@ -0,0 +1,315 @@
+// Package importer imports the history in log files into stats. Log formats
+// are small LineParsers registered by name, the Importer takes care of
+// walking directories, reporting progress and skipping logs that were
+// already imported.
+package importer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Line is a single event parsed from a log.
+type Line struct {
+	Kind stats.MsgKind
+	// Nick is the nick or full hostmask of the sender.
+	Nick    string
+	Date    time.Time
+	Message string
+	// Channel is set for formats that log several channels together.
+	Channel string
+}
+
+// LineParser parses the lines of a log format. Parsers may keep state between
+// lines, such as the current day, so a new one is made for every log.
+type LineParser interface {
+	// ParseLine parses a line, returning false for lines that hold nothing
+	// to count.
+	ParseLine(line string) (Line, bool)
+}
+
+// Source describes where a log came from.
+type Source struct {
+	Name    string
+	Network string
+	Channel string
+	// Day is the day the log starts on, for formats that only log times.
+	Day time.Time
+}
+
+// PathParser is a LineParser able to tell the source of a log from its path.
+type PathParser interface {
+	LineParser
+	// ParsePath prepares the parser for the log at path.
+	ParsePath(path string) (Source, bool)
+}
+
+// Progress is how far along an import is.
+type Progress struct {
+	Source     string
+	Bytes      int64
+	Lines      int
+	Parsed     int
+	Duplicates int
+	Done       bool
+}
+
+// Importer imports logs into stats.
+type Importer struct {
+	Stats *stats.Stats
+	// Network and Channel, if set, override the source of every log.
+	Network string
+	Channel string
+
+	// Progress, if set, is called every ProgressEvery lines and once a log
+	// has been imported.
+	Progress      func(Progress)
+	ProgressEvery int
+
+	// Logger is where the progress of imports is logged, each log imported
+	// at info and the progress within a log at debug, and the downloads
+	// retried as warnings. slog.Default() when nil.
+	Logger *slog.Logger
+}
+
+// defaultProgressEvery is how many lines pass between progress reports when
+// ProgressEvery is not set.
+const defaultProgressEvery = 10000
+
+// New creates an importer for the stats.
+func New(s *stats.Stats) *Importer {
+	return &Importer{Stats: s}
+}
+
+// Import reads every line of the log and adds what the parser understood to
+// the stats. Lines falling in a range already imported for the channel are
+// skipped, and the range of the new lines is remembered. The stats are locked
+// while the lines are added.
+func (im *Importer) Import(src Source, r io.Reader, p LineParser) (Progress, error) {
+	return im.ImportContext(context.Background(), src, r, p)
+}
+
+// ImportContext is Import, giving up reading the log once the context is
+// done. Nothing of a log given up on is added.
+func (im *Importer) ImportContext(ctx context.Context, src Source, r io.Reader, p LineParser) (Progress, error) {
+	network, channel := im.source(src)
+
+	every := im.ProgressEvery
+	if every <= 0 {
+		every = defaultProgressEvery
+	}
+
+	counter := &countingReader{ctx: ctx, r: r}
+	progress := Progress{Source: src.Name}
+	var lines []Line
+
+	scanner := bufio.NewScanner(counter)
+	for scanner.Scan() {
+		progress.Lines++
+		if l, ok := p.ParseLine(scanner.Text()); ok {
+			lines = append(lines, l)
+		}
+
+		if progress.Lines%every == 0 {
+			progress.Bytes = counter.n
+			progress.Parsed = len(lines)
+			im.logger().Debug("Importing", progress.attrs()...)
+			if im.Progress != nil {
+				im.Progress(progress)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return progress, err
+	}
+
+	progress.Bytes = counter.n
+	progress.Parsed = len(lines)
+
+	im.add(network, channel, lines, &progress, true)
+
+	progress.Done = true
+	im.logger().Info("Imported a log", progress.attrs()...)
+	if im.Progress != nil {
+		im.Progress(progress)
+	}
+	return progress, nil
+}
+
+// logger is where the importer logs, see Logger.
+func (im *Importer) logger() *slog.Logger {
+	if im.Logger == nil {
+		return slog.Default()
+	}
+	return im.Logger
+}
+
+// attrs are the progress as it's logged.
+func (p Progress) attrs() []interface{} {
+	return []interface{}{"source", p.Source, "bytes", p.Bytes, "lines", p.Lines, "parsed", p.Parsed, "duplicates", p.Duplicates}
+}
+
+// source is the network and channel the lines of a source are counted under.
+func (im *Importer) source(src Source) (network, channel string) {
+	network, channel = src.Network, src.Channel
+	if len(im.Network) > 0 {
+		network = im.Network
+	}
+	if len(im.Channel) > 0 {
+		channel = im.Channel
+	}
+	return network, channel
+}
+
+// add adds the lines in a single batch, skipping the lines that were already
+// imported when deduping. Lines naming their channel are counted there unless
+// the importer overrides the channel. The batch is counted inside of the
+// same update as the check, so two imports of a log count it once.
+func (im *Importer) add(network, channel string, lines []Line, progress *Progress, dedupe bool) {
+	im.Stats.Update(func(tx *stats.WriteTx) {
+		im.addTx(tx, network, channel, lines, progress, dedupe)
+	})
+}
+
+func (im *Importer) addTx(tx *stats.WriteTx, network, channel string, lines []Line, progress *Progress, dedupe bool) {
+	ranges := make(map[string]stats.ImportRange)
+	batch := make([]stats.BatchMessage, 0, len(lines))
+
+	for _, l := range lines {
+		c := channel
+		if len(l.Channel) > 0 && len(im.Channel) == 0 {
+			c = l.Channel
+		}
+
+		if dedupe && tx.Imported(network, c, l.Date) {
+			progress.Duplicates++
+			continue
+		}
+
+		r, ok := ranges[c]
+		if !ok || l.Date.Before(r.Start) {
+			r.Start = l.Date
+		}
+		if l.Date.After(r.End) {
+			r.End = l.Date
+		}
+		ranges[c] = r
+
+		if l.Kind == stats.Quit || l.Kind == stats.Nick {
+			c = ""
+		}
+		batch = append(batch, stats.BatchMessage{
+			Kind:     l.Kind,
+			Network:  network,
+			Channel:  c,
+			Hostmask: l.Nick,
+			Date:     l.Date,
+			Message:  l.Message,
+		})
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	tx.AddBatch(batch)
+	for c, r := range ranges {
+		tx.MarkImported(network, c, r.Start, r.End)
+	}
+}
+
+// addLive adds lines as they are written by a logger. They are not checked
+// against the ranges already imported, lines logged in the same second as the
+// last imported line would otherwise be lost.
+func (im *Importer) addLive(src Source, lines []Line) {
+	network, channel := im.source(src)
+	progress := Progress{Source: src.Name, Lines: len(lines), Parsed: len(lines)}
+
+	im.add(network, channel, lines, &progress, false)
+}
+
+// ImportPath imports a log, or every log inside of a directory, using a new
+// parser from the factory for each one. Logs are files ending in .log, or
+// rotated logs with a suffix after the .log.
+func (im *Importer) ImportPath(root string, f Factory, loc *time.Location) error {
+	return im.ImportPathContext(context.Background(), root, f, loc)
+}
+
+// ImportPathContext is ImportPath, giving up once the context is done. The
+// logs imported before are kept.
+func (im *Importer) ImportPathContext(ctx context.Context, root string, f Factory, loc *time.Location) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if path != root && !isLog(filepath.Base(path)) {
+			return nil
+		}
+
+		return im.importFile(ctx, path, f(loc))
+	})
+}
+
+func isLog(name string) bool {
+	return filepath.Ext(name) == ".log" || strings.Contains(name, ".log.")
+}
+
+func (im *Importer) importFile(ctx context.Context, path string, p LineParser) error {
+	src := Source{Name: path}
+	if pp, ok := p.(PathParser); ok {
+		if src, ok = pp.ParsePath(path); !ok {
+			return &PathError{Path: path}
+		}
+		src.Name = path
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = im.ImportContext(ctx, src, file, p)
+	return err
+}
+
+// PathError is returned when a parser can't tell the source of a log from its
+// path.
+type PathError struct {
+	Path string
+}
+
+func (e *PathError) Error() string {
+	return "Can't tell the network, channel and date of " + e.Path
+}
+
+// countingReader counts the bytes read through it, until its context is
+// done.
+type countingReader struct {
+	ctx context.Context
+	r   io.Reader
+	n   int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// importLog imports a log of #deviate on zkpq with the format.
+func importLog(t *testing.T, f Format, log string) *stats.Stats {
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#deviate"
+
+	if _, err := im.Import(Source{Name: "test"}, strings.NewReader(log), f.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+const weechatLog = "2014-03-01 08:00:00\t-->\tdylan (dylan@zqz.ca) has joined #deviate\n" +
+	"2014-03-01 08:00:05\tdylan\thello there\n" +
+	"2014-03-01 08:00:10\t--\tnot a line we know\n" +
+	"2014-03-01 08:01:00\taaron\thi dylan\n"
+
+func TestImporter_Import(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+
+	var reports []Progress
+	im.Progress = func(p Progress) {
+		reports = append(reports, p)
+	}
+	im.ProgressEvery = 2
+
+	src := Source{Name: "deviate.log", Network: "zkpq", Channel: "#deviate"}
+	p, err := im.Import(src, strings.NewReader(weechatLog), weechat.New(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Lines != 4 || p.Parsed != 3 || p.Duplicates != 0 || p.Bytes != int64(len(weechatLog)) || !p.Done {
+		t.Error("Progress is wrong:", p)
+	}
+	if len(reports) != 3 || reports[0].Lines != 2 || reports[0].Done || !reports[2].Done {
+		t.Error("Should have reported progress every two lines and when done:", reports)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Fatal("Should have imported dylan's line.")
+	}
+
+	start := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	if !s.Imported("zkpq", "#deviate", start) {
+		t.Error("Should remember the imported range.")
+	}
+}
+
+func TestImporter_Duplicates(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	src := Source{Name: "deviate.log", Network: "zkpq", Channel: "#deviate"}
+
+	if _, err := im.Import(src, strings.NewReader(weechatLog), weechat.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	more := weechatLog + "2014-03-01 09:00:00\tdylan\tback again\n"
+	p, err := im.Import(src, strings.NewReader(more), weechat.New(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Duplicates != 3 {
+		t.Error("Should have skipped the lines already imported, Got:", p.Duplicates)
+	}
+	if u := s.GetUser("zkpq", "dylan"); u.Lines != 2 {
+		t.Error("Should only have added the new line, Got:", u.Lines)
+	}
+}
+
+func TestImporter_Override(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#other"
+
+	src := Source{Name: "deviate.log", Network: "freenode", Channel: "#deviate"}
+	if _, err := im.Import(src, strings.NewReader(weechatLog), weechat.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.GetChannel("zkpq", "#other") == nil || s.GetNetwork("freenode") != nil {
+		t.Error("Should use the importer's network and channel over the source's.")
+	}
+}
+
+func TestImporter_ImportContext(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#deviate"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := im.ImportContext(ctx, Source{Name: "test"}, strings.NewReader(weechatLog), weechat.New(nil)); err != context.Canceled {
+		t.Error("Should give up once the context is done, got", err)
+	}
+	if err := im.ImportPathContext(ctx, ".", weechat.New, nil); err != context.Canceled {
+		t.Error("Should give up walking once the context is done, got", err)
+	}
+	if u := s.GetUser("zkpq", "dylan"); u != nil {
+		t.Error("Should add nothing of a log given up on.")
+	}
+}
+
+func TestImporter_Logger(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged bytes.Buffer
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#deviate"
+	im.Logger = slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	im.ProgressEvery = 2
+
+	if _, err := im.Import(Source{Name: "deviate.log"}, strings.NewReader(weechatLog), weechat.New(nil)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"level=DEBUG msg=Importing source=deviate.log", "level=INFO msg=\"Imported a log\" source=deviate.log bytes=" + strconv.Itoa(len(weechatLog)) + " lines=4 parsed=3"} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("Should log %s, logged %s", want, logged.String())
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package importer
+
+import "regexp"
+
+func init() {
+	Register("irssi", irssi.New)
+}
+
+// irssi reads logs written with irssi's default log theme. Lines only carry
+// the time of day, the day is taken from the log's open and day change
+// markers.
+var irssi = Format{
+	DateFormat: "15:04",
+	DayFormat:  "Mon Jan 02 2006",
+	DayChange:  regexp.MustCompile(`^--- (?:Log opened|Day changed) (?P<day>\w{3} \w{3} \d{2})(?: [0-9:]+)? (?P<year>\d{4})$`),
+
+	Message: regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? <[ @%&~+]?(?P<nick>[^>\s]+)> (?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- (?P<nick>\S+) \[(?P<host>[^\]]*)\] has joined (?P<channel>\S+)$`),
+	Part:    regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- (?P<nick>\S+) \[(?P<host>[^\]]*)\] has left (?P<channel>\S+) \[(?P<message>.*)\]$`),
+	Quit:    regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- (?P<nick>\S+) \[(?P<host>[^\]]*)\] has quit \[(?P<message>.*)\]$`),
+	Kick:    regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- (?P<target>\S+) was kicked from (?P<channel>\S+) by (?P<nick>\S+) \[(?P<message>.*)\]$`),
+	Topic:   regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- (?P<nick>\S+) changed the topic of (?P<channel>\S+) to: (?P<topic>.*)$`),
+	Mode:    regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- mode/(?P<channel>\S+) \[(?P<mode>\S+)[^\]]*\] by (?P<nick>\S+)$`),
+	Action:  regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)?  \* (?P<nick>\S+) (?P<action>.*)$`),
+	Nick:    regexp.MustCompile(`^(?P<date>\d\d:\d\d)(?::\d\d)? -!- (?P<nick>\S+) is now known as (?P<new>\S+)$`),
+}
@@ -0,0 +1,51 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+const irssiLog = `--- Log opened Sat Mar 01 07:59:12 2014
+08:00 -!- dylan [dylan@zqz.ca] has joined #deviate
+08:00 <@dylan> hello there
+08:01  * dylan waves
+08:02 -!- aaron changed the topic of #deviate to: welcome
+08:03 -!- mode/#deviate [+o dylan] by aaron
+--- Day changed Sun Mar 02 2014
+08:04 -!- knivey was kicked from #deviate by aaron [bye]
+08:05 -!- dylan is now known as dylan_
+08:06 < dylan_> still here
+08:07 -!- dylan_ [dylan@zqz.ca] has left #deviate []
+08:08 -!- aaron [aaron@host] has quit [Ping timeout]
+--- Log closed Sun Mar 02 08:09:00 2014
+`
+
+func TestIrssi_parse(t *testing.T) {
+	t.Parallel()
+
+	s := importLog(t, irssi, irssiLog)
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Channel should exist.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "welcome" {
+		t.Error("Should have the topic.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.NickChanges != 1 {
+		t.Fatal("Should have counted dylan's line and nick change:", u)
+	}
+
+	if u = s.GetUser("zkpq", "dylan_"); u == nil || u.Lines != 1 {
+		t.Fatal("Should have counted dylan_'s line:", u)
+	}
+	if want := time.Date(2014, 3, 2, 8, 7, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should use the day from the day change marker, Got:", u.LastSeen)
+	}
+
+	if aaron := s.GetUser("zkpq", "aaron"); aaron.Ops != 1 || aaron.KickCounters.Sent != 1 {
+		t.Error("Should count aaron's mode and kick.")
+	}
+}
@@ -0,0 +1,25 @@
+package importer
+
+import "regexp"
+
+func init() {
+	Register("limnoria", limnoria.New)
+	Register("supybot", limnoria.New)
+}
+
+// limnoria reads logs written by the ChannelLogger plugin of Limnoria and
+// supybot, with the default timestamp format.
+var limnoria = Format{
+	DateFormat: "2006-01-02T15:04:05",
+
+	Message: regexp.MustCompile(`^(?P<date>\S+)  <[@%&~+]?(?P<nick>[^>\s]+)> (?P<message>.*)$`),
+	Notice:  regexp.MustCompile(`^(?P<date>\S+)  -(?P<nick>[^\s-]+)- (?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<nick>\S+) <(?P<host>[^>]*)> has joined (?P<channel>\S+)$`),
+	Part:    regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<nick>\S+) <(?P<host>[^>]*)> has left (?P<channel>\S+)(?: \((?P<message>.*)\))?$`),
+	Quit:    regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<nick>\S+) <(?P<host>[^>]*)> has quit IRC(?: \((?P<message>.*)\))?$`),
+	Kick:    regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<target>\S+) was kicked by (?P<nick>\S+)(?: \((?P<message>.*)\))?$`),
+	Topic:   regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<nick>\S+) changes topic to (?P<topic>.*)$`),
+	Mode:    regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<nick>\S+) sets mode: (?P<mode>\S+).*$`),
+	Nick:    regexp.MustCompile(`^(?P<date>\S+)  \*\*\* (?P<nick>\S+) is now known as (?P<new>\S+)$`),
+	Action:  regexp.MustCompile(`^(?P<date>\S+)  \* (?P<nick>\S+) (?P<action>.*)$`),
+}
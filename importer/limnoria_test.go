@@ -0,0 +1,54 @@
+package importer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+const limnoriaLog = `2014-03-01T08:00:00  *** dylan <dylan@zqz.ca> has joined #deviate
+2014-03-01T08:00:05  <dylan> hello there
+2014-03-01T08:00:30  -dylan- a notice
+2014-03-01T08:01:00  * dylan waves
+2014-03-01T08:02:00  *** aaron changes topic to welcome
+2014-03-01T08:03:00  *** aaron sets mode: +o dylan
+2014-03-01T08:04:00  *** knivey was kicked by aaron (bye)
+2014-03-01T08:05:00  *** dylan is now known as dylan_
+2014-03-01T08:06:00  <dylan_> still here
+2014-03-01T08:07:00  *** dylan_ <dylan@zqz.ca> has left #deviate (peace out)
+2014-03-01T08:08:00  *** aaron <aaron@host> has quit IRC (Ping timeout)
+`
+
+func TestLimnoria_parse(t *testing.T) {
+	t.Parallel()
+
+	s := importLog(t, limnoria, limnoriaLog)
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Channel should exist.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "welcome" {
+		t.Error("Should have the topic.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.NickChanges != 1 {
+		t.Fatal("Should have counted dylan's line and nick change:", u)
+	}
+	if u.TextByKind.Fraction(stats.Notice) == 0 {
+		t.Error("Should have counted the notice.")
+	}
+
+	if u = s.GetUser("zkpq", "dylan_"); u == nil {
+		t.Fatal("Should have dylan_.")
+	}
+	if want := time.Date(2014, 3, 1, 8, 7, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("LastSeen is wrong, Got:", u.LastSeen)
+	}
+
+	if aaron := s.GetUser("zkpq", "aaron"); aaron.Ops != 1 || aaron.KickCounters.Sent != 1 {
+		t.Error("Should count aaron's mode and kick.")
+	}
+}
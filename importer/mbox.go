@@ -0,0 +1,260 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/DylanJ/stats"
+)
+
+// mboxNetwork is the network a mailing list archive is counted under when
+// the importer doesn't name one.
+const mboxNetwork = "mail"
+
+var (
+	// mboxAttribution matches the line mail clients put above a quote, eg.
+	// On Mon, 3 Mar 2014, Dylan wrote:
+	mboxAttribution = regexp.MustCompile(`(?i)^on\s.*\swrote:$`)
+	mboxListID      = regexp.MustCompile(`<([^<>.]+)[^<>]*>`)
+	mboxReplyPrefix = regexp.MustCompile(`(?i)^((re|fwd?|aw|sv)(\[\d+\])?:\s*)+`)
+)
+
+// ImportMbox imports a mailing list archive in the mbox format, as a
+// network of its own for communities living both on irc and on a list. A
+// list is counted as the channel named after its List-Id, or after the file
+// when the mails don't have one. Senders are counted under their address so
+// they stay the same user across mails, and every line they wrote that isn't
+// quoted counts as a line said. The mails starting a thread also set the
+// topic of the list to their subject, so the topics are the latest threads.
+// The network is mail unless the importer names one.
+func (im *Importer) ImportMbox(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	return im.ImportMboxReader(f, Source{Name: filename, Network: mboxNetwork, Channel: "#" + name})
+}
+
+// ImportMboxReader imports the mails of an mbox, counting those without a
+// List-Id in the channel of the source.
+func (im *Importer) ImportMboxReader(r io.Reader, src Source) error {
+	network, channel := im.source(src)
+	progress := Progress{Source: src.Name}
+	counter := &countingReader{ctx: context.Background(), r: r}
+
+	var lines []Line
+	err := splitMbox(counter, func(raw []byte) {
+		progress.Lines++
+		lines = append(lines, mboxLines(raw)...)
+	})
+	if err != nil {
+		return err
+	}
+	progress.Bytes = counter.n
+	progress.Parsed = len(lines)
+
+	im.add(network, channel, lines, &progress, true)
+
+	progress.Done = true
+	if im.Progress != nil {
+		im.Progress(progress)
+	}
+	return nil
+}
+
+// splitMbox calls f with every mail of an mbox. Mails start with a From line
+// and lines of the body starting with From were quoted with a >.
+func splitMbox(r io.Reader, f func(raw []byte)) error {
+	br := bufio.NewReader(r)
+	var mail bytes.Buffer
+	started := false
+
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			switch {
+			case bytes.HasPrefix(line, []byte("From ")):
+				if started {
+					f(mail.Bytes())
+				}
+				mail.Reset()
+				started = true
+			case started:
+				if trimmed := bytes.TrimLeft(line, ">"); len(trimmed) < len(line) && bytes.HasPrefix(trimmed, []byte("From ")) {
+					line = line[1:]
+				}
+				mail.Write(line)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if started {
+		f(mail.Bytes())
+	}
+	return nil
+}
+
+// mboxLines converts a mail to the lines it is counted as, nothing for mails
+// that can't be read or have no sender or date.
+func mboxLines(raw []byte) []Line {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return nil
+	}
+	date, err := msg.Header.Date()
+	if err != nil {
+		return nil
+	}
+
+	line := Line{Nick: mboxHostmask(from), Date: date}
+	if match := mboxListID.FindStringSubmatch(msg.Header.Get("List-Id")); match != nil {
+		line.Channel = "#" + strings.ToLower(match[1])
+	}
+
+	var lines []Line
+	if len(msg.Header.Get("In-Reply-To")) == 0 && len(msg.Header.Get("References")) == 0 {
+		dec := new(mime.WordDecoder)
+		subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil {
+			subject = msg.Header.Get("Subject")
+		}
+		if subject = mboxReplyPrefix.ReplaceAllString(strings.TrimSpace(subject), ""); len(subject) > 0 {
+			topic := line
+			topic.Kind, topic.Message = stats.Topic, subject
+			lines = append(lines, topic)
+		}
+	}
+
+	line.Kind = stats.Msg
+	for _, text := range mboxText(msg) {
+		line.Message = text
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// mboxHostmask is the hostmask of a sender: the display name, or the local
+// part of the address when the name doesn't make a nick, in front of the
+// address.
+func mboxHostmask(from *mail.Address) string {
+	local, domain := from.Address, ""
+	if i := strings.LastIndexByte(from.Address, '@'); i >= 0 {
+		local, domain = from.Address[:i], from.Address[i+1:]
+	}
+
+	nick := slackNickOf(strings.Join(strings.Fields(from.Name), "_"), local)
+	if len(nick) == 0 {
+		nick = local
+	}
+	return nick + "!" + strings.ToLower(local) + "@" + strings.ToLower(domain)
+}
+
+// mboxText returns the lines the sender wrote in the plain text of a mail,
+// leaving out what they quoted and their signature.
+func mboxText(msg *mail.Message) []string {
+	body, ok := plainText(msg.Header, msg.Body)
+	if !ok {
+		return nil
+	}
+
+	var lines []string
+	for _, text := range strings.Split(string(body), "\n") {
+		text = strings.TrimRight(text, "\r")
+		if text == "-- " {
+			break
+		}
+		text = strings.TrimSpace(text)
+		if len(text) == 0 || strings.HasPrefix(text, ">") || mboxAttribution.MatchString(text) {
+			continue
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
+
+// header is what plainText needs of the headers of a mail or a part.
+type header interface {
+	Get(key string) string
+}
+
+// plainText decodes the text/plain body of a mail or part, taking the first
+// plain part of multipart mails.
+func plainText(h header, body io.Reader) ([]byte, bool) {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				return nil, false
+			}
+			if text, ok := plainText(part.Header, part); ok {
+				return text, true
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return nil, false
+	}
+
+	switch strings.ToLower(h.Get("Content-Transfer-Encoding")) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &newlineSkipper{body})
+	}
+
+	b, err := ioutil.ReadAll(body)
+	return b, err == nil
+}
+
+// newlineSkipper drops the line breaks of base64 bodies.
+type newlineSkipper struct {
+	r io.Reader
+}
+
+func (n *newlineSkipper) Read(p []byte) (int, error) {
+	for {
+		read, err := n.r.Read(p)
+		kept := 0
+		for _, c := range p[:read] {
+			if c != '\r' && c != '\n' {
+				p[kept] = c
+				kept++
+			}
+		}
+		if kept > 0 || err != nil {
+			return kept, err
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+const mbox = `From dylan@zqz.ca Mon Mar  3 08:00:00 2014
+From: Dylan J <Dylan@ZQZ.ca>
+Date: Mon, 03 Mar 2014 08:00:00 +0000
+Subject: [go-nuts] Release party
+List-Id: Go Nuts <golang-nuts.googlegroups.com>
+Message-Id: <1@zqz.ca>
+
+Who's coming?
+>From the sounds of it everyone.
+
+-- 
+Dylan
+From aaron@zqz.ca Mon Mar  3 09:00:00 2014
+From: "Aaron L" <aaron@zqz.ca>
+Date: Mon, 03 Mar 2014 09:00:00 +0000
+Subject: Re: [go-nuts] Release party
+List-Id: Go Nuts <golang-nuts.googlegroups.com>
+In-Reply-To: <1@zqz.ca>
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Me, bringing caf=C3=A9.
+
+On Mon, 3 Mar 2014, Dylan J wrote:
+> Who's coming?
+--b1
+Content-Type: text/html
+
+<p>Me</p>
+--b1--
+From nobody Mon Mar  3 10:00:00 2014
+From: =?utf-8?q?Ren=C3=A9_Phish?= <phish@zqz.ca>
+Date: Mon, 03 Mar 2014 10:00:00 +0000
+Subject: Off list
+Content-Transfer-Encoding: base64
+
+aGVsbG8K
+d29ybGQK
+From broken Mon Mar  3 11:00:00 2014
+From: nobody at all
+Date: soon
+
+not counted
+`
+
+func TestImporter_ImportMboxReader(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+
+	var progress []Progress
+	im.Progress = func(p Progress) { progress = append(progress, p) }
+
+	if err := im.ImportMboxReader(strings.NewReader(mbox), Source{Name: "list", Network: mboxNetwork, Channel: "#list"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.GetChannel("mail", "#golang-nuts")
+	if c == nil {
+		t.Fatal("Should count the list under its List-Id.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "[go-nuts] Release party" {
+		t.Error("Thread roots should set the topic:", c.Topics)
+	}
+
+	dylan := s.GetUser("mail", "dylan_j")
+	if dylan == nil || dylan.Lines != 2 || dylan.Nick != "Dylan_J" {
+		t.Error("Should count the lines of the sender, without the signature:", dylan)
+	}
+	if aaron := s.GetUser("mail", "aaron_l"); aaron == nil || aaron.Lines != 1 {
+		t.Error("Should count the plain text of replies, without quotes:", aaron)
+	}
+
+	if s.GetChannel("mail", "#list") == nil {
+		t.Error("Should count mails without a List-Id in the channel of the source.")
+	}
+	if u := s.GetUser("mail", "rené_phish"); u == nil || u.Lines != 2 {
+		t.Error("Should decode names and base64 bodies:", u)
+	}
+
+	if len(progress) != 1 || progress[0].Lines != 4 || !progress[0].Done {
+		t.Error("Should report the mails read:", progress)
+	}
+
+	if err := im.ImportMboxReader(strings.NewReader(mbox), Source{Name: "list", Network: mboxNetwork, Channel: "#list"}); err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser("mail", "dylan_j"); u.Lines != 2 {
+		t.Error("Should skip mails already imported.")
+	}
+}
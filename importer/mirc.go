@@ -0,0 +1,26 @@
+package importer
+
+import "regexp"
+
+func init() {
+	Register("mirc", mirc.New)
+}
+
+// mirc reads logs written by mIRC with its default timestamp. Lines only carry
+// the time of day, the day is taken from the session start markers.
+var mirc = Format{
+	DateFormat: "15:04",
+	DayFormat:  "Mon Jan 02 2006",
+	DayChange:  regexp.MustCompile(`^Session Start: (?P<day>\w{3} \w{3} \d{2}) [0-9:]+ (?P<year>\d{4})$`),
+	Ignore:     regexp.MustCompile(`^\[\d\d:\d\d(?::\d\d)?\] \* (?:Now talking in|Topic is|Set by|Disconnected|Attempting to rejoin|Rejoined channel|Retrieving) `),
+
+	Message: regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] <[@%&~+]?(?P<nick>[^>\s]+)> (?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) \((?P<host>[^)]*)\) has joined (?P<channel>\S+)$`),
+	Part:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) \((?P<host>[^)]*)\) has left (?P<channel>\S+)(?: \((?P<message>.*)\))?$`),
+	Quit:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) \((?P<host>[^)]*)\) Quit \((?P<message>.*)\)$`),
+	Kick:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<target>\S+) was kicked by (?P<nick>\S+) \((?P<message>.*)\)$`),
+	Topic:   regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) changes topic to '(?P<topic>.*)'$`),
+	Mode:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) sets mode: (?P<mode>\S+).*$`),
+	Nick:    regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) is now known as (?P<new>\S+)$`),
+	Action:  regexp.MustCompile(`^\[(?P<date>\d\d:\d\d)(?::\d\d)?\] \* (?P<nick>\S+) (?P<action>.*)$`),
+}
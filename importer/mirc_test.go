@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+const mircLog = `Session Start: Sat Mar 01 22:00:00 2014
+Session Ident: #deviate
+[22:00] * Now talking in #deviate
+[22:00] * Topic is 'old topic'
+[22:00] * dylan (dylan@zqz.ca) has joined #deviate
+[22:01] <@dylan> hello there
+[22:02] * dylan waves
+[22:03] * aaron changes topic to 'welcome'
+[22:04] * aaron sets mode: +o dylan
+[23:59] * knivey was kicked by aaron (bye)
+[00:05] * dylan is now known as dylan_
+[00:06] <dylan_> past midnight
+[00:07] * dylan_ (dylan@zqz.ca) has left #deviate
+[00:08] * aaron (aaron@host) Quit (Ping timeout)
+Session Close: Sun Mar 02 00:09:00 2014
+`
+
+func TestMirc_parse(t *testing.T) {
+	t.Parallel()
+
+	s := importLog(t, mirc, mircLog)
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Channel should exist.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "welcome" {
+		t.Error("Should only have the changed topic.")
+	}
+
+	if s.GetUser("zkpq", "Now") != nil {
+		t.Error("Should ignore status lines.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.NickChanges != 1 || u.Ops != 0 {
+		t.Fatal("Should have counted dylan's line and nick change:", u)
+	}
+
+	if u = s.GetUser("zkpq", "dylan_"); u == nil {
+		t.Fatal("Should have dylan_.")
+	}
+	if want := time.Date(2014, 3, 2, 0, 7, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should roll over to the next day, Got:", u.LastSeen)
+	}
+
+	if aaron := s.GetUser("zkpq", "aaron"); aaron.Ops != 1 || aaron.KickCounters.Sent != 1 {
+		t.Error("Should count aaron's mode and kick.")
+	}
+}
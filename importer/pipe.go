@@ -0,0 +1,127 @@
+package importer
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func init() {
+	Register("tsv", func(*time.Location) LineParser { return tsvParser{} })
+	Register("json", func(*time.Location) LineParser { return jsonParser{} })
+}
+
+// The tsv and json formats are simple formats for other programs to pipe
+// messages in with, rather than formats written by irc clients.
+//
+// A tsv line holds five fields separated by tabs, the last one takes the rest
+// of the line:
+//
+//	<date>	<kind>	<channel>	<nick>	<message>
+//
+// A json line is an object with the same fields:
+//
+//	{"date": "2014-03-01T08:00:00Z", "kind": "message", "channel": "#deviate", "nick": "dylan", "message": "hello"}
+//
+// The date is RFC 3339 or seconds since the epoch, lines without one are dated
+// when they are read. The kind is one of message, action, notice, join, part,
+// quit, kick, mode, topic or nick and defaults to message. The nick may be a
+// full hostmask. The channel may be left empty when it's given to the
+// importer.
+
+type tsvParser struct{}
+
+func (tsvParser) ParseLine(line string) (Line, bool) {
+	fields := strings.SplitN(strings.TrimRight(line, "\r\n"), "\t", 5)
+	if len(fields) != 5 {
+		return Line{}, false
+	}
+
+	return pipeLine(fields[0], fields[1], fields[2], fields[3], fields[4])
+}
+
+type jsonParser struct{}
+
+type jsonLine struct {
+	Date    json.RawMessage `json:"date"`
+	Kind    string          `json:"kind"`
+	Channel string          `json:"channel"`
+	Nick    string          `json:"nick"`
+	Message string          `json:"message"`
+}
+
+func (jsonParser) ParseLine(line string) (Line, bool) {
+	var l jsonLine
+	if err := json.Unmarshal([]byte(line), &l); err != nil {
+		return Line{}, false
+	}
+
+	// dates may be strings or numbers
+	var date string
+	if err := json.Unmarshal(l.Date, &date); err != nil {
+		date = string(l.Date)
+	}
+
+	return pipeLine(date, l.Kind, l.Channel, l.Nick, l.Message)
+}
+
+func pipeLine(date, kind, channel, nick, message string) (Line, bool) {
+	if len(nick) == 0 {
+		return Line{}, false
+	}
+
+	l := Line{Nick: nick, Channel: channel, Message: message}
+
+	var ok bool
+	if l.Date, ok = pipeDate(date); !ok {
+		return Line{}, false
+	}
+
+	if len(kind) > 0 {
+		if l.Kind, ok = stats.ParseMsgKind(kind); !ok {
+			return Line{}, false
+		}
+	}
+
+	return l, true
+}
+
+// pipeDate parses an RFC 3339 date or seconds since the epoch, an empty date
+// is left zero.
+func pipeDate(date string) (time.Time, bool) {
+	if len(date) == 0 || date == "null" {
+		return time.Time{}, true
+	}
+
+	if d, err := time.Parse(time.RFC3339Nano, date); err == nil {
+		return d, true
+	}
+
+	return parseUnixTime(date)
+}
+
+// parseUnixTime parses seconds since the epoch with an optional fraction,
+// such as the seconds.micros timestamps of slack messages.
+func parseUnixTime(ts string) (time.Time, bool) {
+	secs, frac := ts, ""
+	if i := strings.IndexByte(ts, '.'); i >= 0 {
+		secs, frac = ts[:i], ts[i+1:]
+	}
+
+	s, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var nsec int64
+	if len(frac) > 0 {
+		frac = (frac + "000000000")[:9]
+		if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(s, nsec).UTC(), true
+}
@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestTSVParser(t *testing.T) {
+	t.Parallel()
+
+	p, _ := Lookup("tsv")
+	l, ok := p(nil).ParseLine("2014-03-01T08:00:00Z\taction\t#deviate\tdylan!d@zqz.ca\twaves\tat everyone\r\n")
+	if !ok {
+		t.Fatal("Should parse the line.")
+	}
+	if l.Kind != stats.Action || l.Channel != "#deviate" || l.Nick != "dylan!d@zqz.ca" ||
+		l.Message != "waves\tat everyone" || !l.Date.Equal(time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Error("Wrong line:", l)
+	}
+
+	if l, ok = p(nil).ParseLine("\t\t\tdylan\thello"); !ok || l.Kind != stats.Msg || !l.Date.IsZero() {
+		t.Error("Should default the kind and leave the date empty:", l)
+	}
+
+	bad := []string{
+		"2014-03-01T08:00:00Z\tmessage\t#deviate\tdylan",
+		"yesterday\tmessage\t#deviate\tdylan\thello",
+		"2014-03-01T08:00:00Z\tshout\t#deviate\tdylan\thello",
+		"2014-03-01T08:00:00Z\tmessage\t#deviate\t\thello",
+	}
+	for _, line := range bad {
+		if _, ok := p(nil).ParseLine(line); ok {
+			t.Errorf("Should reject %q", line)
+		}
+	}
+}
+
+func TestJSONParser(t *testing.T) {
+	t.Parallel()
+
+	p, _ := Lookup("json")
+	l, ok := p(nil).ParseLine(`{"date": 1393660800.5, "kind": "topic", "channel": "#deviate", "nick": "dylan", "message": "welcome"}`)
+	if !ok {
+		t.Fatal("Should parse the line.")
+	}
+	if l.Kind != stats.Topic || l.Channel != "#deviate" || l.Message != "welcome" ||
+		!l.Date.Equal(time.Unix(1393660800, int64(time.Second/2))) {
+		t.Error("Wrong line:", l)
+	}
+
+	if l, ok = p(nil).ParseLine(`{"date": "2014-03-01T08:00:00+01:00", "nick": "dylan"}`); !ok ||
+		!l.Date.Equal(time.Date(2014, 3, 1, 7, 0, 0, 0, time.UTC)) {
+		t.Error("Should parse RFC 3339 dates:", l)
+	}
+
+	if _, ok = p(nil).ParseLine(`{"nick": "dylan", "message": "hi"`); ok {
+		t.Error("Should reject bad json.")
+	}
+}
+
+func TestStream_ReadLines(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	im.Network = "zkpq"
+	p, _ := Lookup("tsv")
+	st := NewStream(im, p(nil), Source{Channel: "#deviate"})
+
+	input := "2014-03-01T08:00:00Z\tmessage\t\tdylan\thello\n" +
+		"not a line\n" +
+		"\tmessage\t#other\tdylan\thi\n"
+	before := time.Now()
+	if err := st.ReadLines(strings.NewReader(input)); err != nil {
+		t.Fatal(err)
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 2 {
+		t.Fatal("Should have added both messages:", u)
+	}
+	if s.GetChannel("zkpq", "#deviate") == nil || s.GetChannel("zkpq", "#other") == nil {
+		t.Error("Should count lines in their own channel or the stream's.")
+	}
+	if u.LastSeen.Before(before) {
+		t.Error("Should date undated lines when they are read:", u.LastSeen)
+	}
+}
+func TestParseUnixTime(t *testing.T) {
+	t.Parallel()
+
+	if d, ok := parseUnixTime("1388664000.000200"); !ok || !d.Equal(time.Unix(1388664000, 200000)) {
+		t.Error("Wrong time:", d)
+	}
+	if d, ok := parseUnixTime("1388664000"); !ok || !d.Equal(time.Unix(1388664000, 0)) {
+		t.Error("Wrong time:", d)
+	}
+	if _, ok := parseUnixTime("soon"); ok {
+		t.Error("Should reject bad timestamps.")
+	}
+}
@@ -0,0 +1,47 @@
+package importer
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Factory creates a parser that reads dates in the given location.
+type Factory func(loc *time.Location) LineParser
+
+var (
+	registryMut sync.RWMutex
+	registry    = make(map[string]Factory)
+)
+
+// Register makes a log format available by name. Registering a name twice
+// replaces the first format.
+func Register(name string, f Factory) {
+	registryMut.Lock()
+	defer registryMut.Unlock()
+
+	registry[name] = f
+}
+
+// Lookup finds the log format registered under the name.
+func Lookup(name string) (Factory, bool) {
+	registryMut.RLock()
+	defer registryMut.RUnlock()
+
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Names lists the registered log formats in alphabetical order.
+func Names() []string {
+	registryMut.RLock()
+	defer registryMut.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
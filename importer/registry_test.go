@@ -0,0 +1,40 @@
+package importer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"eggdrop", "hexchat", "irssi", "limnoria", "mirc", "supybot", "weechat", "znc"} {
+		if _, ok := Lookup(name); !ok {
+			t.Error("Should have registered:", name)
+		}
+	}
+
+	Register("test", func(loc *time.Location) LineParser {
+		return weechat.New(loc)
+	})
+
+	if f, ok := Lookup("test"); !ok || f(nil) == nil {
+		t.Error("Should be able to register formats.")
+	}
+
+	found := false
+	names := Names()
+	for i, name := range names {
+		if i > 0 && names[i-1] > name {
+			t.Error("Names should be sorted:", names)
+		}
+		found = found || name == "test"
+	}
+	if !found {
+		t.Error("Names should list the registered format.")
+	}
+
+	if _, ok := Lookup("nope"); ok {
+		t.Error("Should not find unregistered formats.")
+	}
+}
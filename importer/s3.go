@@ -0,0 +1,348 @@
+package importer
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultS3Region is the region of buckets when none is given.
+const DefaultS3Region = "us-east-1"
+
+// emptyHash is the sha256 of an empty payload, every request sent to S3 has
+// one.
+const emptyHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Importer imports the logs archived in an S3 bucket, or any storage
+// speaking its api such as MinIO. Every object under a prefix that looks like
+// a log is streamed and imported, .gz objects are decompressed.
+type S3Importer struct {
+	Importer *Importer
+	Factory  Factory
+	Location *time.Location
+
+	// Endpoint is the base url of the storage, eg. http://localhost:9000 for
+	// MinIO. Buckets are then addressed by path rather than by host. When
+	// empty the buckets are on AWS.
+	Endpoint string
+	// Region is the region of the bucket, DefaultS3Region when empty.
+	Region string
+	// AccessKey and SecretKey sign the requests, SessionToken is added for
+	// temporary credentials. Requests are sent unsigned without an access
+	// key, for public buckets.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+
+	// Retries is how many times a failed or broken off download is retried.
+	Retries int
+
+	// Done are the s3://bucket/key urls of the objects already imported,
+	// they are not downloaded again. Fetched, if set, is called after each
+	// object has been imported.
+	Done    map[string]bool
+	Fetched func(url string)
+
+	HTTP *http.Client
+}
+
+// NewS3Importer creates an importer of the logs in buckets, parsed with
+// parsers from the factory.
+func NewS3Importer(im *Importer, f Factory, loc *time.Location) *S3Importer {
+	return &S3Importer{
+		Importer: im,
+		Factory:  f,
+		Location: loc,
+		Retries:  DefaultFetchRetries,
+		Done:     make(map[string]bool),
+	}
+}
+
+// listBucketResult is the answer of ListObjectsV2.
+type listBucketResult struct {
+	Contents []struct {
+		Key string
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// Import imports the logs under a prefix of a bucket, given as
+// s3://bucket/prefix. Objects are imported in the order of their keys.
+func (s *S3Importer) Import(rawurl string) error {
+	return s.ImportContext(context.Background(), rawurl)
+}
+
+// ImportContext is Import, giving up once the context is done. The objects
+// imported before are kept and left Done.
+func (s *S3Importer) ImportContext(ctx context.Context, rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "s3" || len(u.Host) == 0 {
+		return fmt.Errorf("%s is not an s3://bucket/prefix url", rawurl)
+	}
+	bucket, prefix := u.Host, strings.TrimPrefix(u.Path, "/")
+
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if len(token) > 0 {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.get(ctx, bucket, "", query, 0)
+		if err != nil {
+			return err
+		}
+		var list listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&list)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("Bad listing of %s: %v", rawurl, err)
+		}
+
+		for _, obj := range list.Contents {
+			if !isFetchedLog(path.Base(obj.Key)) {
+				continue
+			}
+			if err = s.importObject(ctx, bucket, obj.Key); err != nil {
+				return err
+			}
+		}
+
+		if !list.IsTruncated || len(list.NextContinuationToken) == 0 {
+			return nil
+		}
+		token = list.NextContinuationToken
+	}
+}
+
+// importObject streams and imports a single object.
+func (s *S3Importer) importObject(ctx context.Context, bucket, key string) error {
+	name := "s3://" + bucket + "/" + key
+	if s.Done[name] {
+		return nil
+	}
+
+	p := s.Factory(s.Location)
+	src := Source{Name: name}
+	if pp, ok := p.(PathParser); ok {
+		if src, ok = pp.ParsePath(strings.TrimSuffix(key, ".gz")); !ok {
+			return &PathError{Path: name}
+		}
+		src.Name = name
+	}
+
+	resp, err := s.get(ctx, bucket, key, nil, 0)
+	if err != nil {
+		return err
+	}
+	r := &resumingReader{
+		get:   func(offset int64) (*http.Response, error) { return s.get(ctx, bucket, key, nil, offset) },
+		body:  resp.Body,
+		tries: s.Retries,
+	}
+	defer r.Close()
+
+	var body io.Reader = r
+	if strings.HasSuffix(key, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		body = gz
+	}
+
+	if _, err = s.Importer.ImportContext(ctx, src, body, p); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+
+	if s.Done == nil {
+		s.Done = make(map[string]bool)
+	}
+	s.Done[name] = true
+	if s.Fetched != nil {
+		s.Fetched(name)
+	}
+	return nil
+}
+
+// get requests an object, or the bucket itself when key is empty, from the
+// offset on, retrying when the storage fails, until the context is done.
+func (s *S3Importer) get(ctx context.Context, bucket, key string, query url.Values, offset int64) (*http.Response, error) {
+	client := s.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	endpoint := s.objectURL(bucket, key)
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var err error
+	for try := 0; try <= s.Retries; try++ {
+		if try > 0 {
+			if err := sleep(ctx, time.Duration(try)*time.Second); err != nil {
+				return nil, err
+			}
+		}
+
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, "GET", endpoint, nil); err != nil {
+			return nil, err
+		}
+		if offset > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		if len(s.AccessKey) > 0 {
+			signV4(req, s.region(), s.AccessKey, s.SecretKey, s.SessionToken, time.Now())
+		}
+
+		var resp *http.Response
+		if resp, err = client.Do(req); err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			s.Importer.logger().Warn("Retrying a download", "url", endpoint, "err", err)
+			continue
+		}
+
+		if resp.StatusCode/100 != 2 {
+			body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+			resp.Body.Close()
+			err = fmt.Errorf("s3://%s/%s: %s: %s", bucket, key, resp.Status, strings.TrimSpace(string(body)))
+			if resp.StatusCode/100 == 5 {
+				// internal errors and slow downs are worth retrying
+				s.Importer.logger().Warn("Retrying a download", "url", endpoint, "err", err)
+				continue
+			}
+			return nil, err
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+// objectURL is the url of an object, addressing the bucket by host on AWS and
+// by path elsewhere.
+func (s *S3Importer) objectURL(bucket, key string) string {
+	key = s3Escape(key, false)
+	if len(s.Endpoint) > 0 {
+		return strings.TrimSuffix(s.Endpoint, "/") + "/" + bucket + "/" + key
+	}
+	return "https://" + bucket + ".s3." + s.region() + ".amazonaws.com/" + key
+}
+
+func (s *S3Importer) region() string {
+	if len(s.Region) == 0 {
+		return DefaultS3Region
+	}
+	return s.Region
+}
+
+// signV4 signs a request without a body to S3 with AWS signature version 4.
+// The host, the range and the x-amz headers are signed.
+func signV4(req *http.Request, region, accessKey, secretKey, sessionToken string, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", emptyHash)
+	if len(sessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	uri := req.URL.EscapedPath()
+	if len(uri) == 0 {
+		uri = "/"
+	}
+	canonical := strings.Join([]string{
+		req.Method,
+		uri,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		emptyHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+secretKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+accessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery sorts and escapes a query the way signatures expect.
+func canonicalQuery(query url.Values) string {
+	var params []string
+	for name, values := range query {
+		for _, v := range values {
+			params = append(params, s3Escape(name, true)+"="+s3Escape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// s3Escape escapes everything but the unreserved characters, and slashes
+// unless asked to.
+func s3Escape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
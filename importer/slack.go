@@ -0,0 +1,289 @@
+package importer
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/DylanJ/stats"
+)
+
+// slackNetwork is the network a Slack export is counted under when the
+// importer doesn't name one.
+const slackNetwork = "slack"
+
+type slackUser struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Profile struct {
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+type slackChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type slackMessage struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype"`
+	User     string `json:"user"`
+	BotID    string `json:"bot_id"`
+	Username string `json:"username"`
+	Text     string `json:"text"`
+	Topic    string `json:"topic"`
+	TS       string `json:"ts"`
+}
+
+var (
+	slackUserRef    = regexp.MustCompile(`<@(\w+)(?:\|([^>]*))?>`)
+	slackChannelRef = regexp.MustCompile(`<#(\w+)(?:\|([^>]*))?>`)
+	slackSpecialRef = regexp.MustCompile(`<!(\w+)(?:\^[^|>]*)?(?:\|([^>]*))?>`)
+	slackLink       = regexp.MustCompile(`<([^@#!][^|>]*)(?:\|[^>]*)?>`)
+	slackEscapes    = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+)
+
+// slackExport is an opened Slack workspace export.
+type slackExport struct {
+	files map[string]*zip.File
+	// root is the directory holding channels.json, exports made by some
+	// tools wrap everything in a directory.
+	root     string
+	nicks    map[string]string
+	channels map[string]string
+}
+
+// ImportSlack imports every channel of a Slack workspace export zip. Channels
+// are counted under their name with a # in front and users under their
+// display name. The network is slack unless the importer names one.
+func (im *Importer) ImportSlack(filename string) error {
+	r, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return im.ImportSlackZip(&r.Reader, filename)
+}
+
+// ImportSlackZip imports the export in an opened zip, name is used for
+// reporting progress.
+func (im *Importer) ImportSlackZip(z *zip.Reader, name string) error {
+	ex := &slackExport{
+		files:    make(map[string]*zip.File, len(z.File)),
+		nicks:    make(map[string]string),
+		channels: make(map[string]string),
+		root:     "-",
+	}
+	for _, f := range z.File {
+		ex.files[f.Name] = f
+		if path.Base(f.Name) == "channels.json" && (ex.root == "-" || len(f.Name) < len(ex.root)) {
+			ex.root = strings.TrimSuffix(f.Name, "channels.json")
+		}
+	}
+	if ex.root == "-" {
+		return fmt.Errorf("%s has no channels.json, is it a Slack export?", name)
+	}
+
+	var users []slackUser
+	if err := ex.decode("users.json", &users); err != nil {
+		return err
+	}
+	for _, u := range users {
+		ex.nicks[u.ID] = slackNick(u)
+	}
+
+	var channels []slackChannel
+	for _, list := range []string{"channels.json", "groups.json"} {
+		var cs []slackChannel
+		if err := ex.decode(list, &cs); err != nil {
+			return err
+		}
+		channels = append(channels, cs...)
+	}
+	for _, c := range channels {
+		ex.channels[c.ID] = c.Name
+	}
+	sort.Slice(channels, func(i, j int) bool { return channels[i].Name < channels[j].Name })
+
+	for _, c := range channels {
+		if err := im.importSlackChannel(ex, name, c.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importSlackChannel imports the day files of one channel.
+func (im *Importer) importSlackChannel(ex *slackExport, name, channel string) error {
+	dir := ex.root + channel + "/"
+	var days []string
+	for file := range ex.files {
+		if strings.HasPrefix(file, dir) && path.Ext(file) == ".json" && !strings.Contains(file[len(dir):], "/") {
+			days = append(days, file[len(ex.root):])
+		}
+	}
+	sort.Strings(days)
+
+	src := Source{Name: name + ":" + channel, Network: slackNetwork, Channel: "#" + channel}
+	network, ch := im.source(src)
+	progress := Progress{Source: src.Name}
+
+	var lines []Line
+	for _, day := range days {
+		var messages []slackMessage
+		if err := ex.decode(day, &messages); err != nil {
+			return err
+		}
+		progress.Bytes += int64(ex.files[ex.root+day].UncompressedSize64)
+		progress.Lines += len(messages)
+
+		for _, m := range messages {
+			lines = append(lines, ex.lines(m)...)
+		}
+	}
+	progress.Parsed = len(lines)
+
+	im.add(network, ch, lines, &progress, true)
+
+	progress.Done = true
+	if im.Progress != nil {
+		im.Progress(progress)
+	}
+	return nil
+}
+
+// decode reads a json file of the export, files that aren't there are left
+// empty.
+func (ex *slackExport) decode(name string, v interface{}) error {
+	f, ok := ex.files[ex.root+name]
+	if !ok {
+		return nil
+	}
+
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err = json.NewDecoder(r).Decode(v); err != nil {
+		return fmt.Errorf("Failed to parse %s: %v", name, err)
+	}
+	return nil
+}
+
+// lines converts a slack message to the lines it is counted as, messages of
+// several lines count as a line each like they would on irc.
+func (ex *slackExport) lines(m slackMessage) []Line {
+	if m.Type != "message" {
+		return nil
+	}
+	date, ok := parseUnixTime(m.TS)
+	if !ok {
+		return nil
+	}
+
+	nick := ex.nick(m)
+	line := Line{Nick: nick, Date: date}
+
+	switch m.Subtype {
+	case "", "thread_broadcast", "file_share", "bot_message":
+		line.Kind = stats.Msg
+	case "me_message":
+		line.Kind = stats.Action
+	case "channel_join", "group_join":
+		line.Kind = stats.Join
+		return []Line{line}
+	case "channel_leave", "group_leave":
+		line.Kind = stats.Part
+		return []Line{line}
+	case "channel_topic", "group_topic":
+		line.Kind = stats.Topic
+		line.Message = ex.text(m.Topic)
+		return []Line{line}
+	default:
+		// edits, deletions, purposes and renames hold nothing to count
+		return nil
+	}
+
+	var lines []Line
+	for _, text := range strings.Split(ex.text(m.Text), "\n") {
+		if text = strings.TrimSpace(text); len(text) == 0 {
+			continue
+		}
+		line.Message = text
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// nick is the hostmask a message is counted under, built from the nick and
+// id of the sender so users stay apart even when they share a nick.
+func (ex *slackExport) nick(m slackMessage) string {
+	id := m.User
+	if len(id) == 0 {
+		id = m.BotID
+	}
+
+	nick, ok := ex.nicks[id]
+	if !ok || (len(m.User) == 0 && len(m.Username) > 0) {
+		nick = slackNickOf(m.Username, id)
+	}
+	return nick + "!" + id + "@slack"
+}
+
+// text rewrites slack's markup into the plain text irc users would have
+// written, so <@U123> counts as a reference to that user's nick.
+func (ex *slackExport) text(s string) string {
+	s = slackUserRef.ReplaceAllStringFunc(s, func(ref string) string {
+		match := slackUserRef.FindStringSubmatch(ref)
+		if nick, ok := ex.nicks[match[1]]; ok {
+			return nick
+		}
+		if len(match[2]) > 0 {
+			return match[2]
+		}
+		return match[1]
+	})
+	s = slackChannelRef.ReplaceAllStringFunc(s, func(ref string) string {
+		match := slackChannelRef.FindStringSubmatch(ref)
+		if name, ok := ex.channels[match[1]]; ok {
+			return "#" + name
+		}
+		if len(match[2]) > 0 {
+			return "#" + match[2]
+		}
+		return "#" + match[1]
+	})
+	s = slackSpecialRef.ReplaceAllStringFunc(s, func(ref string) string {
+		match := slackSpecialRef.FindStringSubmatch(ref)
+		if len(match[2]) > 0 {
+			return match[2]
+		}
+		return "@" + match[1]
+	})
+	s = slackLink.ReplaceAllString(s, "$1")
+	return slackEscapes.Replace(s)
+}
+
+func slackNick(u slackUser) string {
+	if nick := slackNickOf(u.Profile.DisplayName, ""); len(nick) > 0 {
+		return nick
+	}
+	return slackNickOf(u.Name, u.ID)
+}
+
+// slackNickOf turns a name into something usable as a nick, names with
+// spaces or hostmask characters fall back to fallback.
+func slackNickOf(name, fallback string) string {
+	if len(name) == 0 || strings.ContainsAny(name, " \t!@") {
+		return fallback
+	}
+	return name
+}
@@ -0,0 +1,149 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func slackZip(t *testing.T, files map[string]string) *zip.Reader {
+	var b bytes.Buffer
+	w := zip.NewWriter(&b)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+var slackExportFiles = map[string]string{
+	"export/users.json": `[
+		{"id": "U1", "name": "dylan", "profile": {"display_name": "dyl"}},
+		{"id": "U2", "name": "phish", "profile": {"display_name": "Big Phish"}}
+	]`,
+	"export/channels.json": `[{"id": "C1", "name": "general"}, {"id": "C2", "name": "random"}]`,
+	"export/general/2014-01-02.json": `[
+		{"type": "message", "user": "U2", "text": "<@U1> look at <http://zqz.ca|this> &amp; <#C2|random>\nsecond line", "ts": "1388664000.000200"},
+		{"type": "message", "subtype": "message_changed", "ts": "1388664001.000000"}
+	]`,
+	"export/general/2014-01-01.json": `[
+		{"type": "message", "subtype": "channel_join", "user": "U1", "text": "<@U1> has joined the channel", "ts": "1388577600.000100"},
+		{"type": "message", "subtype": "channel_topic", "user": "U1", "topic": "hi <!here>", "ts": "1388577601.000000"},
+		{"type": "message", "subtype": "me_message", "user": "U1", "text": "waves", "ts": "1388577602.000000"},
+		{"type": "message", "subtype": "bot_message", "bot_id": "B1", "username": "deploybot", "text": "deployed", "ts": "1388577603.000000"}
+	]`,
+}
+
+func TestImporter_ImportSlackZip(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	im.Network = "zkpq"
+
+	var progress []Progress
+	im.Progress = func(p Progress) { progress = append(progress, p) }
+
+	if err := im.ImportSlackZip(slackZip(t, slackExportFiles), "export.zip"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.GetChannel("zkpq", "#general")
+	if c == nil {
+		t.Fatal("Should have counted #general.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "hi @here" {
+		t.Error("Should have counted the topic:", c.Topics)
+	}
+
+	dyl := s.GetUser("zkpq", "dyl")
+	if dyl == nil || dyl.MessageRanges.Len() != 3 {
+		t.Fatal("Should use the display name as the nick:", dyl)
+	}
+
+	// the display name of U2 has a space in it so the username is used
+	phish := s.GetUser("zkpq", "phish")
+	if phish == nil || phish.Lines != 2 {
+		t.Fatal("Should count each line of a message:", phish)
+	}
+	if q := phish.Quotes.Last.Message; q != "second line" {
+		t.Error("Wrong last quote:", q)
+	}
+	if phish.LastSeen != time.Unix(1388664000, 200000).UTC() {
+		t.Error("Wrong date:", phish.LastSeen)
+	}
+
+	if bot := s.GetUser("zkpq", "deploybot"); bot == nil || bot.Lines != 1 {
+		t.Error("Should count bot messages under their username.")
+	}
+
+	if len(progress) != 2 || progress[0].Source != "export.zip:general" || progress[0].Parsed != 6 || !progress[0].Done {
+		t.Error("Should report the progress of each channel:", progress)
+	}
+
+	if !s.Imported("zkpq", "#general", time.Unix(1388577602, 0)) {
+		t.Error("Should remember the imported range.")
+	}
+
+	if err := im.ImportSlackZip(slackZip(t, slackExportFiles), "export.zip"); err != nil {
+		t.Fatal(err)
+	}
+	if phish.Lines != 2 || progress[2].Duplicates != 6 {
+		t.Error("Should skip an export imported twice:", phish.Lines, progress[2])
+	}
+}
+
+func TestSlackExport_text(t *testing.T) {
+	t.Parallel()
+
+	ex := &slackExport{
+		nicks:    map[string]string{"U1": "dylan"},
+		channels: map[string]string{"C1": "general"},
+	}
+
+	tests := map[string]string{
+		"<@U1> hi":                      "dylan hi",
+		"<@U9|someone> hi":              "someone hi",
+		"see <#C1> and <#C9|other>":     "see #general and #other",
+		"<!channel> <!subteam^S1|@ops>": "@channel @ops",
+		"<https://zqz.ca/a?b=c&amp;d>":  "https://zqz.ca/a?b=c&d",
+		"<mailto:a@zqz.ca|a@zqz.ca>":    "mailto:a@zqz.ca",
+		"1 &lt; 2 &amp;&amp; 3 &gt; 2":  "1 < 2 && 3 > 2",
+	}
+
+	for in, want := range tests {
+		if got := ex.text(in); got != want {
+			t.Errorf("%q: Expected: %q, Got: %q", in, want, got)
+		}
+	}
+}
+
+func TestImporter_ImportSlackZipNotExport(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	if err := im.ImportSlackZip(slackZip(t, map[string]string{"a.txt": "hi"}), "a.zip"); err == nil {
+		t.Error("Should reject zips that aren't exports.")
+	}
+}
@@ -0,0 +1,297 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stream imports log lines carried by a logging pipeline instead of files,
+// such as syslog or the systemd journal. The content of every message is
+// parsed as a line of the Parser's format, lines the format doesn't date
+// take the date of the message.
+type Stream struct {
+	Importer *Importer
+	Parser   LineParser
+	Source   Source
+	// Tag, if set, only accepts messages logged by the program with this
+	// syslog tag or journal identifier.
+	Tag string
+
+	mut sync.Mutex
+}
+
+// NewStream creates a stream of lines parsed by p.
+func NewStream(im *Importer, p LineParser, src Source) *Stream {
+	return &Stream{Importer: im, Parser: p, Source: src}
+}
+
+// Add imports the content of a message logged by the program with the tag.
+func (st *Stream) Add(tag string, date time.Time, content string) bool {
+	if len(st.Tag) > 0 && tag != st.Tag {
+		return false
+	}
+
+	st.mut.Lock()
+	l, ok := st.Parser.ParseLine(content)
+	st.mut.Unlock()
+	if !ok {
+		return false
+	}
+
+	if l.Date.IsZero() {
+		l.Date = date
+	}
+	st.Importer.addLive(st.Source, []Line{l})
+	return true
+}
+
+// AddSyslog imports a raw syslog message.
+func (st *Stream) AddSyslog(raw string) bool {
+	m, ok := parseSyslog(raw, time.Now())
+	if !ok {
+		return false
+	}
+	return st.Add(m.tag, m.date, m.content)
+}
+
+// ServeSyslog imports the syslog messages received on a datagram socket,
+// until it's closed.
+func (st *Stream) ServeSyslog(conn net.PacketConn) error {
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		st.AddSyslog(string(buf[:n]))
+	}
+}
+
+// ServeSyslogStream imports the syslog messages sent over connections to the
+// listener, framed by newlines or by octet counts, until it's closed.
+func (st *Stream) ServeSyslogStream(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				raw, err := readFrame(r)
+				if err != nil {
+					return
+				}
+				st.AddSyslog(raw)
+			}
+		}()
+	}
+}
+
+// readFrame reads a syslog message framed as described by RFC 6587.
+func readFrame(r *bufio.Reader) (string, error) {
+	b, err := r.Peek(1)
+	if err != nil {
+		return "", err
+	}
+
+	if b[0] < '0' || b[0] > '9' {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err
+	}
+
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, n)
+	if _, err = io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// ReadLines imports every line of a pipe as it is read, lines the format
+// doesn't date are dated when they are read.
+func (st *Stream) ReadLines(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		st.Add(st.Tag, time.Now(), scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// ReadJournal imports the entries of the systemd journal written by
+// journalctl -o json, for example: journalctl -f -o json -t mybot.
+func (st *Stream) ReadJournal(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var entry struct {
+			Message    json.RawMessage `json:"MESSAGE"`
+			Identifier string          `json:"SYSLOG_IDENTIFIER"`
+			Realtime   string          `json:"__REALTIME_TIMESTAMP"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+
+		usec, err := strconv.ParseInt(entry.Realtime, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if message, ok := journalMessage(entry.Message); ok {
+			st.Add(entry.Identifier, time.Unix(0, usec*int64(time.Microsecond)), message)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// journalMessage decodes a journal MESSAGE, which is a string, or an array of
+// bytes when it isn't valid utf-8.
+func journalMessage(raw json.RawMessage) (string, bool) {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s, true
+	}
+
+	var b []byte
+	var ints []int
+	if json.Unmarshal(raw, &ints) != nil {
+		return "", false
+	}
+	for _, i := range ints {
+		b = append(b, byte(i))
+	}
+	return string(b), true
+}
+
+// syslogMessage is the part of a syslog message that matters for stats.
+type syslogMessage struct {
+	date    time.Time
+	tag     string
+	content string
+}
+
+// parseSyslog parses a syslog message in the format of RFC 5424 or the older
+// BSD format of RFC 3164. BSD timestamps don't hold a year, the year of now
+// is used.
+func parseSyslog(raw string, now time.Time) (syslogMessage, bool) {
+	var m syslogMessage
+
+	if !strings.HasPrefix(raw, "<") {
+		return m, false
+	}
+	end := strings.IndexByte(raw, '>')
+	if end < 0 {
+		return m, false
+	}
+	raw = raw[end+1:]
+
+	if strings.HasPrefix(raw, "1 ") {
+		return parseSyslog5424(raw[2:])
+	}
+
+	if len(raw) < 16 {
+		return m, false
+	}
+	date, err := time.ParseInLocation("Jan _2 15:04:05", raw[:15], now.Location())
+	if err != nil {
+		return m, false
+	}
+	m.date = date.AddDate(now.Year(), 0, 0)
+	if m.date.After(now.Add(24 * time.Hour)) {
+		m.date = m.date.AddDate(-1, 0, 0)
+	}
+
+	// HOST TAG[PID]: CONTENT
+	fields := strings.SplitN(raw[16:], " ", 2)
+	if len(fields) != 2 {
+		return m, false
+	}
+	colon := strings.Index(fields[1], ": ")
+	if colon < 0 {
+		return m, false
+	}
+	m.tag = fields[1][:colon]
+	if i := strings.IndexByte(m.tag, '['); i >= 0 {
+		m.tag = m.tag[:i]
+	}
+	m.content = fields[1][colon+2:]
+
+	return m, true
+}
+
+// parseSyslog5424 parses what follows the version of an RFC 5424 message:
+// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG.
+func parseSyslog5424(raw string) (syslogMessage, bool) {
+	var m syslogMessage
+
+	fields := strings.SplitN(raw, " ", 6)
+	if len(fields) != 6 {
+		return m, false
+	}
+
+	date, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return m, false
+	}
+	m.date = date
+	if fields[2] != "-" {
+		m.tag = fields[2]
+	}
+
+	rest := fields[5]
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		for strings.HasPrefix(rest, "[") {
+			end := structuredDataEnd(rest)
+			if end < 0 {
+				return m, false
+			}
+			rest = rest[end+1:]
+		}
+	}
+
+	m.content = strings.TrimPrefix(strings.TrimPrefix(rest, " "), "\ufeff")
+	return m, true
+}
+
+// structuredDataEnd finds the ] closing the structured data element at the
+// start of s, skipping escaped ones inside of values.
+func structuredDataEnd(s string) int {
+	quoted := false
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ']':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// botFormat is the format of a bot that logs every channel it's in.
+var botFormat = Format{
+	Message: regexp.MustCompile(`^(?P<channel>#\S+) <(?P<nick>[^>]+)> (?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^(?P<channel>#\S+) \*\*\* (?P<nick>\S+) joined$`),
+}
+
+func TestParseSyslog(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2014, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	m, ok := parseSyslog("<30>Mar  1 08:00:05 host mybot[123]: #deviate <dylan> hello", now)
+	if !ok || m.tag != "mybot" || m.content != "#deviate <dylan> hello" {
+		t.Error("Should parse bsd messages:", m)
+	}
+	if want := time.Date(2014, 3, 1, 8, 0, 5, 0, time.UTC); !m.date.Equal(want) {
+		t.Error("Should date bsd messages in the current year, Got:", m.date)
+	}
+
+	if m, _ = parseSyslog("<30>Dec 31 23:59:59 host mybot: late", now); m.date.Year() != 2013 {
+		t.Error("Should not date messages in the future, Got:", m.date)
+	}
+
+	m, ok = parseSyslog(`<30>1 2014-03-01T08:00:05.000Z host mybot 123 - [meta key="a\]b"] #deviate <dylan> hello`, now)
+	if !ok || m.tag != "mybot" || m.content != "#deviate <dylan> hello" {
+		t.Error("Should parse rfc 5424 messages:", m)
+	}
+
+	if _, ok = parseSyslog("no priority", now); ok {
+		t.Error("Should not parse garbage.")
+	}
+}
+
+func TestStream_AddSyslog(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	st := NewStream(im, botFormat.New(nil), Source{Network: "zkpq"})
+	st.Tag = "mybot"
+
+	st.AddSyslog("<30>1 2014-03-01T08:00:00Z host mybot - - - #deviate *** dylan joined")
+	st.AddSyslog("<30>1 2014-03-01T08:00:05Z host mybot - - - #deviate <dylan> hello")
+	st.AddSyslog("<30>1 2014-03-01T08:00:06Z host mybot - - - #go <dylan> hi")
+	if st.AddSyslog("<30>1 2014-03-01T08:00:07Z host sshd - - - #deviate <dylan> nope") {
+		t.Error("Should only accept messages with the tag.")
+	}
+
+	if s.GetChannel("zkpq", "#deviate") == nil || s.GetChannel("zkpq", "#go") == nil {
+		t.Fatal("Should count lines in the channel they name.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 2 {
+		t.Fatal("Should have counted dylan's lines.")
+	}
+	if want := time.Date(2014, 3, 1, 8, 0, 6, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should date lines by the syslog message, Got:", u.LastSeen)
+	}
+}
+
+func TestStream_ReadJournal(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	st := NewStream(im, botFormat.New(nil), Source{Network: "zkpq"})
+
+	journal := `{"MESSAGE":"#deviate <dylan> hello","SYSLOG_IDENTIFIER":"mybot","__REALTIME_TIMESTAMP":"1393660805000000"}
+{"MESSAGE":[35,100,101,118,105,97,116,101,32,60,100,121,108,97,110,62,32,104,105],"SYSLOG_IDENTIFIER":"mybot","__REALTIME_TIMESTAMP":"1393660806000000"}
+not json
+`
+	if err := st.ReadJournal(strings.NewReader(journal)); err != nil {
+		t.Fatal(err)
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 2 {
+		t.Fatal("Should have counted dylan's lines.")
+	}
+	if want := time.Unix(1393660806, 0); !u.LastSeen.Equal(want) {
+		t.Error("Should date lines by the journal entry, Got:", u.LastSeen)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	t.Parallel()
+
+	r := bufioReader("<30>first\n17 <30>second\nframed<30>third\n")
+	for _, want := range []string{"<30>first", "<30>second\nframed", "<30>third"} {
+		if got, err := readFrame(r); err != nil || got != want {
+			t.Errorf("Want %q, got %q (%v)", want, got, err)
+		}
+	}
+}
+
+func bufioReader(s string) *bufio.Reader {
+	return bufio.NewReader(strings.NewReader(s))
+}
@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Tailer follows logs as a logger writes them and imports the new lines, so
+// stats can be kept without connecting to irc at all.
+type Tailer struct {
+	Importer *Importer
+	Factory  Factory
+	Location *time.Location
+	// FromStart imports what the logs already hold when they are first
+	// seen, instead of only the lines written after.
+	FromStart bool
+
+	files map[string]*tailedFile
+}
+
+// tailedFile is a log being followed.
+type tailedFile struct {
+	src     Source
+	parser  LineParser
+	offset  int64
+	partial []byte
+}
+
+// NewTailer creates a tailer importing logs parsed by the factory.
+func NewTailer(im *Importer, f Factory, loc *time.Location) *Tailer {
+	return &Tailer{
+		Importer: im,
+		Factory:  f,
+		Location: loc,
+		files:    make(map[string]*tailedFile),
+	}
+}
+
+// Run follows every log in the paths, and the logs created in them later,
+// until stop is closed.
+func (t *Tailer) Run(paths []string, stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	for _, root := range paths {
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return watcher.Add(path)
+			}
+			if path == root || isLog(info.Name()) {
+				if err = watcher.Add(path); err != nil {
+					return err
+				}
+				return t.Poll(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			t.handle(watcher, ev)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (t *Tailer) handle(watcher *fsnotify.Watcher, ev fsnotify.Event) {
+	switch {
+	case ev.Op&fsnotify.Create == fsnotify.Create:
+		info, err := os.Stat(ev.Name)
+		if err != nil {
+			return
+		}
+		if info.IsDir() {
+			watcher.Add(ev.Name)
+			return
+		}
+		if isLog(info.Name()) {
+			// a log created while following is read from its start
+			t.files[ev.Name] = t.open(ev.Name)
+			t.Poll(ev.Name)
+		}
+	case ev.Op&fsnotify.Write == fsnotify.Write:
+		t.Poll(ev.Name)
+	case ev.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		delete(t.files, ev.Name)
+	}
+}
+
+// open starts following a log from its start.
+func (t *Tailer) open(path string) *tailedFile {
+	f := &tailedFile{src: Source{Name: path}, parser: t.Factory(t.Location)}
+	if pp, ok := f.parser.(PathParser); ok {
+		f.src, _ = pp.ParsePath(path)
+		f.src.Name = path
+	}
+	return f
+}
+
+// Poll imports the lines written to a log since it was last polled. A log
+// seen for the first time is read up to its end to learn what the parser
+// needs, such as the current day, but only imported with FromStart.
+func (t *Tailer) Poll(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	f, seen := t.files[path]
+	if !seen || info.Size() < f.offset {
+		// new, or truncated by a log rotation
+		f = t.open(path)
+		t.files[path] = f
+	}
+
+	if _, err = file.Seek(f.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	f.offset += int64(len(data))
+
+	data = append(f.partial, data...)
+	end := bytes.LastIndexByte(data, '\n') + 1
+	f.partial = append([]byte(nil), data[end:]...)
+
+	var lines []Line
+	for _, raw := range bytes.Split(data[:end], []byte{'\n'}) {
+		if l, ok := f.parser.ParseLine(string(bytes.TrimRight(raw, "\r"))); ok {
+			lines = append(lines, l)
+		}
+	}
+
+	if !seen && !t.FromStart || len(lines) == 0 {
+		return nil
+	}
+
+	t.Importer.addLive(f.src, lines)
+	return nil
+}
@@ -0,0 +1,79 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestTailer_Poll(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "tail")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "deviate.log")
+	if err = ioutil.WriteFile(path, []byte("--- Log opened Sat Mar 01 07:59:12 2014\n08:00 < dylan> old line\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	im := New(s)
+	im.Network, im.Channel = "zkpq", "#deviate"
+	tail := NewTailer(im, irssi.New, nil)
+
+	if err = tail.Poll(path); err != nil {
+		t.Fatal(err)
+	}
+	if s.GetUser("zkpq", "dylan") != nil {
+		t.Error("Should not import what the log held before following it.")
+	}
+
+	appendLog := func(text string) {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(text)
+		f.Close()
+
+		if err = tail.Poll(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	appendLog("08:01 < dylan> new line\n08:02 < dylan> half a li")
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 {
+		t.Fatal("Should import the complete new line.")
+	}
+	if want := time.Date(2014, 3, 1, 8, 1, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should know the day from the start of the log, Got:", u.LastSeen)
+	}
+
+	appendLog("ne\n08:02 < dylan> same minute\n")
+	if u.Lines != 3 {
+		t.Error("Should import the finished line and lines in the same minute, Got:", u.Lines)
+	}
+
+	if err = ioutil.WriteFile(path, []byte("--- Log opened Sun Mar 02 00:00:00 2014\n00:01 < dylan> rotated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = tail.Poll(path); err != nil {
+		t.Fatal(err)
+	}
+	if u.Lines != 4 {
+		t.Error("Should read a truncated log from its start, Got:", u.Lines)
+	}
+}
@@ -0,0 +1,22 @@
+package importer
+
+import "regexp"
+
+func init() {
+	Register("weechat", weechat.New)
+}
+
+// weechat reads logs written by WeeChat's logger plugin.
+var weechat = Format{
+	DateFormat: "2006-01-02 15:04:05",
+
+	Message: regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t(?:[@&+])?(?P<nick>[^\s\-]+)\t(?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t-->\t(?P<nick>.*) \((?P<host>.*)\) has joined (?P<channel>(?:&|#)\w+)$`),
+	Quit:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t<--\t(?P<nick>.*) \((?P<host>.*)\) has quit \((?P<message>.*)\)$`),
+	Part:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t<--\t(?P<nick>.*) \((?P<host>.*)\) has left (?P<channel>(?:&|#)\w+)(?: \((?P<message>.*)\))?$`),
+	Kick:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t<--\t(?P<nick>.*) has kicked (?P<target>.*) \((?P<message>.*)\)$`),
+	Topic:   regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t--\t(?P<nick>.*) has changed topic for (?P<channel>(?:&|#)\w+) from "(?P<topic>.*)"$`),
+	Mode:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t--\tMode (?P<channel>(?:&|#)\w+) \[(?P<mode>\S+)[^\]]*\] by (?P<nick>.*)$`),
+	Action:  regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t *\t(?P<nick>.*) (?P<action>.*)$`),
+	Nick:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t--\t(?P<nick>\S+) is now known as (?P<new>\S+)$`),
+}
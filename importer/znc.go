@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("znc", znc.New)
+}
+
+// znc reads logs written by znc's log module, one file per channel and day.
+var znc = Format{
+	DateFormat: "15:04:05",
+	FileInfo:   zncFileInfo,
+
+	Message: regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] <(?P<nick>[^>\s]+)> (?P<message>.*)$`),
+	Join:    regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \*\*\* Joins: (?P<nick>\S+) \((?P<host>[^)]*)\)$`),
+	Part:    regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \*\*\* Parts: (?P<nick>\S+) \((?P<host>[^)]*)\)(?: \((?P<message>.*)\))?$`),
+	Quit:    regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \*\*\* Quits: (?P<nick>\S+) \((?P<host>[^)]*)\) \((?P<message>.*)\)$`),
+	Kick:    regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \*\*\* (?P<target>\S+) was kicked by (?P<nick>\S+) \((?P<message>.*)\)$`),
+	Topic:   regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \*\*\* (?P<nick>\S+) changes topic to '(?P<topic>.*)'$`),
+	Mode:    regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \*\*\* (?P<nick>\S+) sets mode: (?P<mode>\S+).*$`),
+	Action:  regexp.MustCompile(`^\[(?P<date>[0-9:]+)\] \* (?P<nick>\S+) (?P<action>.*)$`),
+}
+
+var zncOldFilename = regexp.MustCompile(`^(?:[^_]+_)?([^_]+)_(.+)_(\d{8})\.log$`)
+
+// zncFileInfo reads the network, channel and day from the path of a znc log.
+// Both the current layout <network>/<channel>/YYYY-MM-DD.log and the old
+// flat [user_]network_channel_YYYYMMDD.log layout are understood.
+func zncFileInfo(path string) (Source, bool) {
+	var info Source
+	base := filepath.Base(path)
+
+	if m := zncOldFilename.FindStringSubmatch(base); m != nil {
+		day, err := time.Parse("20060102", m[3])
+		if err != nil {
+			return info, false
+		}
+		return Source{Network: m[1], Channel: m[2], Day: day}, true
+	}
+
+	day, err := time.Parse("2006-01-02", strings.TrimSuffix(base, ".log"))
+	if err != nil {
+		return info, false
+	}
+
+	dir := filepath.Dir(path)
+	info.Day = day
+	info.Channel = filepath.Base(dir)
+	info.Network = filepath.Base(filepath.Dir(dir))
+
+	return info, info.Channel != "." && info.Network != "."
+}
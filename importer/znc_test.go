@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+const zncLog = `[08:00:01] *** Joins: dylan (dylan@zqz.ca)
+[08:00:05] <dylan> hello there
+[08:01:00] * dylan waves
+[08:02:00] *** aaron changes topic to 'welcome'
+[08:03:00] *** aaron sets mode: +o dylan
+[08:04:00] *** knivey was kicked by aaron (bye)
+[08:05:00] *** Parts: dylan (dylan@zqz.ca) (peace out)
+[08:06:00] *** Quits: aaron (aaron@host) (Ping timeout)
+`
+
+func TestZNC_fileInfo(t *testing.T) {
+	t.Parallel()
+
+	info, ok := zncFileInfo("/home/znc/moddata/log/dylan/freenode/#go-nuts/2014-03-01.log")
+	if !ok {
+		t.Fatal("Should understand the current layout.")
+	}
+
+	day := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+	if info.Network != "freenode" || info.Channel != "#go-nuts" || !info.Day.Equal(day) {
+		t.Error("Info is incorrect:", info)
+	}
+
+	if info, ok = zncFileInfo("logs/dylan_zkpq_#deviate_20140301.log"); !ok {
+		t.Fatal("Should understand the old layout.")
+	}
+
+	if info.Network != "zkpq" || info.Channel != "#deviate" || !info.Day.Equal(day) {
+		t.Error("Info is incorrect:", info)
+	}
+
+	if _, ok = zncFileInfo("notes.log"); ok {
+		t.Error("Should not understand other files.")
+	}
+}
+
+func TestZNC_parseDirectory(t *testing.T) {
+	t.Parallel()
+
+	root, err := ioutil.TempDir("", "znc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	dir := filepath.Join(root, "zkpq", "#deviate")
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filepath.Join(dir, "2014-03-01.log"), []byte(zncLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = New(s).ImportPath(root, znc.New, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Should have taken the network and channel from the path.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.Ops != 0 {
+		t.Fatal("Should have counted dylan's message.")
+	}
+
+	if want := time.Date(2014, 3, 1, 8, 5, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should combine the day of the file with the time, Got:", u.LastSeen)
+	}
+
+	if len(c.Topics) != 1 || c.Topics[0].Message != "welcome" {
+		t.Error("Should have the topic.")
+	}
+
+	if aaron := s.GetUser("zkpq", "aaron"); aaron.Ops != 1 || aaron.KickCounters.Sent != 1 {
+		t.Error("Should count aaron's mode and kick.")
+	}
+}
@@ -0,0 +1,213 @@
+// Package influx pushes the counters of the stats as time series points in
+// the InfluxDB line protocol, which VictoriaMetrics accepts as well, so the
+// trends can be kept and graphed outside of data.db.
+package influx
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// DefaultInterval is how often points are pushed when no interval is set.
+const DefaultInterval = time.Minute
+
+// Exporter writes a point for every channel, user and user in a channel each
+// time it pushes.
+type Exporter struct {
+	Stats *stats.Stats
+	// URL is the write endpoint, eg. http://localhost:8086/write?db=ircstats
+	// for InfluxDB 1, http://localhost:8086/api/v2/write?org=o&bucket=b for
+	// InfluxDB 2 or http://localhost:8428/write for VictoriaMetrics.
+	URL string
+	// Token is sent as the Authorization header for InfluxDB 2.
+	Token    string
+	Interval time.Duration
+
+	HTTP *http.Client
+}
+
+// New creates an exporter pushing to url.
+func New(s *stats.Stats, url string) *Exporter {
+	return &Exporter{
+		Stats:    s,
+		URL:      url,
+		Interval: DefaultInterval,
+	}
+}
+
+// WritePoints writes the points of the stats at now to w.
+func (e *Exporter) WritePoints(w io.Writer, now time.Time) error {
+	var b bytes.Buffer
+	e.Stats.View(func(tx *stats.ReadTx) {
+		writePoints(&b, tx, now.UnixNano())
+	})
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writePoints writes a point for every channel, user and user in a channel.
+func writePoints(b *bytes.Buffer, tx *stats.ReadTx, ts int64) {
+	ids := make([]uint, 0, len(tx.Channels))
+	for id := range tx.Channels {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+
+	for _, id := range ids {
+		c := tx.Channel(id)
+		if c == nil {
+			continue
+		}
+		n, ok := tx.Networks[c.NetworkID]
+		if !ok {
+			continue
+		}
+
+		lines, words, letters := textTotals(c.TextByKind)
+		writePoint(b, "irc_channel", ts,
+			[]string{"network", n.Name, "channel", c.Name},
+			[]string{"lines", "words", "letters", "users", "joins", "parts", "questions", "exclamations", "corrections"},
+			lines, words, letters, uint(len(c.UserIDs)), c.JoinCount, c.PartCount,
+			uint(c.QuestionsCount), uint(c.ExclamationsCount), c.Corrections)
+	}
+
+	ids = ids[:0]
+	for id := range tx.Users {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+
+	for _, id := range ids {
+		u := tx.Users[id]
+		n, ok := tx.Networks[u.NetworkID]
+		if !ok {
+			continue
+		}
+
+		writeUser(b, "irc_user", ts, []string{"network", n.Name, "nick", u.Nick}, u)
+
+		channels := make([]string, 0, len(u.ChannelUsers))
+		for name := range u.ChannelUsers {
+			channels = append(channels, name)
+		}
+		sort.Strings(channels)
+
+		for _, name := range channels {
+			writeUser(b, "irc_channel_user", ts,
+				[]string{"network", n.Name, "channel", name, "nick", u.Nick}, u.ChannelUsers[name])
+		}
+	}
+}
+
+// Push writes the current points to the endpoint.
+func (e *Exporter) Push() error {
+	var b bytes.Buffer
+	if err := e.WritePoints(&b, time.Now()); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", e.URL, &b)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if len(e.Token) > 0 {
+		req.Header.Set("Authorization", "Token "+e.Token)
+	}
+
+	client := e.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// Run pushes every interval until stop is closed, passing failed pushes to
+// errs when it isn't nil.
+func (e *Exporter) Run(stop <-chan struct{}, errs func(error)) {
+	interval := e.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := e.Push(); err != nil && errs != nil {
+				errs(err)
+			}
+		}
+	}
+}
+
+func writeUser(b *bytes.Buffer, measurement string, ts int64, tags []string, u *stats.User) {
+	lines, words, letters := textTotals(u.TextByKind)
+	writePoint(b, measurement, ts, tags,
+		[]string{"lines", "words", "letters", "questions", "exclamations", "all_caps", "kicks_sent", "kicks_received", "slaps_sent", "slaps_received", "nick_changes"},
+		lines, words, letters, uint(u.QuestionsCount), uint(u.ExclamationsCount), uint(u.AllCapsCount),
+		u.KickCounters.Sent, u.KickCounters.Received, u.SlapCounters.Sent, u.SlapCounters.Received, u.NickChanges)
+}
+
+// textTotals sums the text counters of every kind of chat.
+func textTotals(k stats.KindTextCounters) (lines, words, letters uint) {
+	t := k.Total()
+	return t.Lines, t.Words, t.Letters
+}
+
+// writePoint writes a single line of the line protocol, tags are given as
+// key value pairs.
+func writePoint(b *bytes.Buffer, measurement string, ts int64, tags []string, fields []string, values ...uint) {
+	b.WriteString(measurementEscaper.Replace(measurement))
+	for i := 0; i+1 < len(tags); i += 2 {
+		if len(tags[i+1]) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, ",%s=%s", tagEscaper.Replace(tags[i]), tagEscaper.Replace(tags[i+1]))
+	}
+
+	for i, f := range fields {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(b, "%s=%di", tagEscaper.Replace(f), values[i])
+	}
+
+	fmt.Fprintf(b, " %d\n", ts)
+}
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	tagEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// sortIDs sorts ids so points are written in a stable order.
+func sortIDs(ids []uint) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
@@ -0,0 +1,97 @@
+package influx
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+const (
+	network  = "test network"
+	channel  = "#test"
+	hostmask = "phish!phish@zqz.ca"
+)
+
+func newStats(t *testing.T) *stats.Stats {
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddMessage(stats.Msg, network, channel, hostmask, time.Now(), "hello there friend?")
+	s.AddMessage(stats.Msg, network, channel, hostmask, time.Now(), "bye")
+	return s
+}
+
+func TestExporter_WritePoints(t *testing.T) {
+	t.Parallel()
+
+	e := New(newStats(t), "")
+	var b bytes.Buffer
+	if err := e.WritePoints(&b, time.Unix(10, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Should have written 3 points: %q", lines)
+	}
+
+	if !strings.HasPrefix(lines[0], `irc_channel,network=test\ network,channel=#test lines=2i,words=4i,`) {
+		t.Error("Wrong channel point:", lines[0])
+	}
+	if !strings.Contains(lines[0], ",questions=1i,") || !strings.HasSuffix(lines[0], " 10000000000") {
+		t.Error("Wrong channel point:", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], `irc_user,network=test\ network,nick=phish lines=2i,`) {
+		t.Error("Wrong user point:", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], `irc_channel_user,network=test\ network,channel=#test,nick=phish lines=2i,`) {
+		t.Error("Wrong channel user point:", lines[2])
+	}
+}
+
+func TestWritePoint_Escaping(t *testing.T) {
+	t.Parallel()
+
+	var b bytes.Buffer
+	writePoint(&b, "a b,c", 1, []string{"k=1", "v a,l", "empty", ""}, []string{"n"}, 5)
+	if got := b.String(); got != `a\ b\,c,k\=1=v\ a\,l n=5i 1`+"\n" {
+		t.Error("Wrong escaping:", got)
+	}
+}
+
+func TestExporter_Push(t *testing.T) {
+	t.Parallel()
+
+	var body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		body, auth = string(b), r.Header.Get("Authorization")
+		if r.URL.Query().Get("db") != "ircstats" {
+			http.Error(w, "database not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	e := New(newStats(t), server.URL+"/write?db=ircstats")
+	e.Token = "secret"
+	if err := e.Push(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(body, "irc_channel,") || auth != "Token secret" {
+		t.Error("Should have pushed the points:", auth, body)
+	}
+
+	e.URL = server.URL + "/write?db=nope"
+	if err := e.Push(); err == nil || !strings.Contains(err.Error(), "database not found") {
+		t.Error("Should report failed writes:", err)
+	}
+}
@@ -0,0 +1,26 @@
+package stats
+
+// maxInterned is how many strings an interner holds before starting over, so
+// it doesn't grow with every token ever seen when the counters are bounded.
+const maxInterned = 1 << 17
+
+// interner hands out a single copy of the strings the counters store over
+// and over, like words, urls and nicks. The counters of a network, its
+// channels and users share that copy, and it doesn't keep the message it
+// was cut from in memory.
+type interner map[string]string
+
+func (in *interner) intern(s string) string {
+	if c, ok := (*in)[s]; ok {
+		return c
+	}
+
+	if *in == nil || len(*in) >= maxInterned {
+		// the strings already handed out stay shared by their counters
+		*in = make(interner)
+	}
+
+	c := string([]byte(s))
+	(*in)[c] = c
+	return c
+}
@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+// sameString checks if two strings share their bytes.
+func sameString(a, b string) bool {
+	return len(a) == len(b) && (len(a) == 0 || unsafe.StringData(a) == unsafe.StringData(b))
+}
+
+func TestInterner(t *testing.T) {
+	t.Parallel()
+
+	var in interner
+	text := "hello there"
+	a := in.intern(text[:5])
+	if a != "hello" || sameString(a, text[:5]) {
+		t.Error("Should copy the string out of the text:", a)
+	}
+	if b := in.intern(string([]byte("hello"))); !sameString(a, b) {
+		t.Error("Should hand out the same copy.")
+	}
+
+	for i := 0; i < maxInterned; i++ {
+		in.intern(fmt.Sprint(i))
+	}
+	if len(in) > maxInterned {
+		t.Error("Should start over when full:", len(in))
+	}
+}
+
+func TestStats_internTokens(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "bob", time.Now(), "Hello http://zqz.ca #tag")
+	s.AddMessage(Msg, network, channel, "alice", time.Now(), "hello http://zqz.ca #tag")
+
+	key := func(m map[string]uint, token string) string {
+		for k := range m {
+			if k == token {
+				return k
+			}
+		}
+		t.Fatal("Should have counted", token)
+		return ""
+	}
+
+	n, c := s.GetNetwork(network), s.GetChannel(network, channel)
+	bob, alice := s.GetUser(network, "bob"), s.GetUser(network, "alice")
+
+	if w := key(n.WordCounter.All, "hello"); !sameString(w, key(c.WordCounter.All, "hello")) || !sameString(w, key(alice.WordCounter.All, "hello")) {
+		t.Error("Should share the words among the counters.")
+	}
+	if u := key(n.URLCounter.All, "http://zqz.ca"); !sameString(u, key(c.URLCounter.All, "http://zqz.ca")) {
+		t.Error("Should share the urls among the counters.")
+	}
+	if h := key(c.HashtagCounter.All, "#tag"); !sameString(h, c.HashtagCounter.Top[0].Token) {
+		t.Error("Should share the hashtags with the top tokens.")
+	}
+	if !sameString(bob.Nick, n.strings.intern("bob")) {
+		t.Error("Should intern the nicks.")
+	}
+}
@@ -0,0 +1,104 @@
+package stats
+
+import "strings"
+
+// LanguageDetector guesses the language a message is written in. Detect
+// returns a short language code (e.g. "en") or an empty string if the
+// language could not be determined.
+type LanguageDetector interface {
+	Detect(message string) string
+}
+
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "you", "that", "it", "of", "to", "what", "are", "this", "have", "was", "with", "for", "not"},
+	"fr": {"le", "la", "les", "et", "est", "je", "tu", "vous", "pas", "une", "que", "qui", "des", "avec", "pour", "mais"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "du", "ein", "eine", "mit", "auch", "sie", "wir", "aber", "was"},
+	"es": {"el", "los", "las", "y", "es", "yo", "que", "una", "por", "con", "para", "pero", "muy", "como", "esta", "del"},
+	"it": {"il", "gli", "di", "che", "non", "sono", "una", "per", "con", "anche", "questo", "ma", "io", "ho", "come", "della"},
+	"pt": {"os", "um", "uma", "que", "não", "eu", "voce", "você", "com", "para", "mas", "isso", "muito", "como", "do", "da"},
+	"nl": {"de", "het", "een", "en", "ik", "je", "niet", "dat", "van", "op", "met", "maar", "ook", "wat", "zijn", "heb"},
+}
+
+// StopwordDetector is a lightweight LanguageDetector that scores a message by
+// the number of common stopwords it contains for each known language.
+type StopwordDetector struct {
+	// MinMatches is the number of stopwords a message needs before a
+	// language is reported.
+	MinMatches int
+
+	words map[string][]string
+}
+
+// NewStopwordDetector creates a StopwordDetector using the built in word
+// lists.
+func NewStopwordDetector() *StopwordDetector {
+	d := &StopwordDetector{
+		MinMatches: 1,
+		words:      make(map[string][]string),
+	}
+
+	for lang, words := range stopwords {
+		for _, w := range words {
+			d.words[w] = append(d.words[w], lang)
+		}
+	}
+
+	return d
+}
+
+// Detect returns the language with the most stopword matches, or an empty
+// string if there were too few matches or a tie.
+func (d *StopwordDetector) Detect(message string) string {
+	scores := make(map[string]int)
+
+	for _, word := range strings.Fields(strings.ToLower(message)) {
+		word = strings.Trim(word, ".,:;!?\"'()")
+		for _, lang := range d.words[word] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore, tie := "", 0, false
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore:
+			tie = true
+		}
+	}
+
+	if tie || bestScore < d.MinMatches {
+		return ""
+	}
+
+	return best
+}
+
+type LanguageCounter struct {
+	TokenCounter
+}
+
+func NewLanguageCounter() LanguageCounter {
+	return LanguageCounter{
+		NewTokenCounter(),
+	}
+}
+
+func (l *LanguageCounter) addLanguage(lang string) {
+	if lang == "" {
+		return
+	}
+
+	l.addToken(lang)
+}
+
+// DominantLanguage returns the most used language or an empty string if no
+// language has been detected yet.
+func (l *LanguageCounter) DominantLanguage() string {
+	if len(l.Top) == 0 {
+		return ""
+	}
+
+	return l.Top[0].Token
+}
@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStopwordDetector(t *testing.T) {
+	t.Parallel()
+
+	d := NewStopwordDetector()
+
+	if lang := d.Detect("what is the plan for tonight?"); lang != "en" {
+		t.Errorf("Expected: en, Got: %v", lang)
+	}
+
+	if lang := d.Detect("je ne sais pas, mais c'est une idée"); lang != "fr" {
+		t.Errorf("Expected: fr, Got: %v", lang)
+	}
+
+	if lang := d.Detect("ich habe das nicht gesehen"); lang != "de" {
+		t.Errorf("Expected: de, Got: %v", lang)
+	}
+
+	if lang := d.Detect("lol"); lang != "" {
+		t.Errorf("Should not detect a language, Got: %v", lang)
+	}
+
+	d.MinMatches = 3
+	if lang := d.Detect("the plan"); lang != "" {
+		t.Errorf("Should require MinMatches stopwords, Got: %v", lang)
+	}
+}
+
+func TestLanguageCounter(t *testing.T) {
+	t.Parallel()
+
+	l := NewLanguageCounter()
+
+	if l.DominantLanguage() != "" {
+		t.Error("Should not have a dominant language.")
+	}
+
+	l.addLanguage("en")
+	l.addLanguage("")
+	l.addLanguage("fr")
+	l.addLanguage("fr")
+
+	if l.Count != 3 {
+		t.Error("Should ignore undetected languages.")
+	}
+
+	if l.DominantLanguage() != "fr" {
+		t.Error("fr should be the dominant language.")
+	}
+}
+
+func TestStats_LanguageDetection(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "what is the plan")
+
+	if s.Channels[1].LanguageCounter.Count != 0 {
+		t.Error("Should not detect languages without a detector.")
+	}
+
+	s.SetOptions(Options{LanguageDetector: NewStopwordDetector()})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "what is the plan")
+	s.AddMessage(Join, network, channel, hostmask, time.Now(), "")
+
+	if c := s.Channels[1]; c.LanguageCounter.All["en"] != 1 {
+		t.Error("Channel should have one english message.")
+	}
+
+	u := s.Users[1]
+	if u.DominantLanguage() != "en" {
+		t.Error("User should be speaking english.")
+	}
+
+	if cu := u.ChannelUsers[channel]; cu.DominantLanguage() != "en" {
+		t.Error("Channel user should be speaking english.")
+	}
+}
@@ -3,20 +3,33 @@ package stats
 const maxTopics = 5
 
 type LastTopics struct {
-	Topics []*Message
+	Topics []Message
 }
 
 // NewLastTopics
 func NewLastTopics() LastTopics {
 	return LastTopics{
-		Topics: make([]*Message, 0, maxTopics),
+		Topics: make([]Message, 0, maxTopics),
 	}
 }
 
-// addMessage
+// addMessage keeps the topics in the order they were set, even when topics
+// from the past are played back.
 func (l *LastTopics) addMessage(message *Message) {
+	i := len(l.Topics)
+	for i > 0 && message.Date.Before(l.Topics[i-1].Date) {
+		i--
+	}
+
 	if len(l.Topics) >= maxTopics {
+		if i == 0 {
+			return
+		}
 		l.Topics = l.Topics[1:maxTopics]
+		i--
 	}
-	l.Topics = append(l.Topics, message)
+
+	l.Topics = append(l.Topics, Message{})
+	copy(l.Topics[i+1:], l.Topics[i:])
+	l.Topics[i] = message.kept()
 }
@@ -3,6 +3,7 @@ package stats
 import (
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestLastTopics(t *testing.T) {
@@ -26,3 +27,24 @@ func TestLastTopics(t *testing.T) {
 		t.Error("Should have last topic as last element in topics array.")
 	}
 }
+
+func TestLastTopics_OutOfOrder(t *testing.T) {
+	t.Parallel()
+
+	l := NewLastTopics()
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	for i := 1; i <= maxTopics; i++ {
+		l.addMessage(&Message{Message: fmt.Sprint(i), Date: day.Add(time.Duration(i) * time.Hour)})
+	}
+
+	l.addMessage(&Message{Message: "old", Date: day})
+	if l.Topics[0].Message != "1" {
+		t.Error("Should drop topics older than all kept topics.")
+	}
+
+	l.addMessage(&Message{Message: "played back", Date: day.Add(150 * time.Minute)})
+	if len(l.Topics) != maxTopics || l.Topics[0].Message != "2" || l.Topics[1].Message != "played back" {
+		t.Error("Should insert played back topics in order:", l.Topics)
+	}
+}
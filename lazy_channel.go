@@ -0,0 +1,189 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// channelSummary holds the fields of a channel decoded as soon as the stats
+// are loaded. The rest of the channel, its message ids and counters, is only
+// decoded the first time the channel is used, so a bot with a big database
+// starts quickly and pays the memory of the channels actually queried.
+type channelSummary struct {
+	ID           uint
+	NetworkID    uint
+	Name         string
+	Topic        string
+	JoinCount    uint
+	PartCount    uint
+	MessageCount uint
+	LastActive   time.Time
+}
+
+// savedChannel is how a channel is saved: its summary and the whole channel
+// encoded on its own.
+type savedChannel struct {
+	Summary channelSummary
+	Data    []byte
+}
+
+// channelFields is a channel without its methods, for gob to encode it field
+// by field.
+type channelFields Channel
+
+// GobEncode saves the channel, a channel not loaded since the stats were is
+// saved as it was read.
+func (c *Channel) GobEncode() ([]byte, error) {
+	c.lazyMut.Lock()
+	defer c.lazyMut.Unlock()
+
+	saved := savedChannel{
+		Summary: c.summary(),
+		Data:    c.lazy,
+	}
+
+	if saved.Data == nil {
+		var b bytes.Buffer
+		if err := gob.NewEncoder(&b).Encode((*channelFields)(c)); err != nil {
+			return nil, err
+		}
+		saved.Data = b.Bytes()
+	}
+
+	var b bytes.Buffer
+	err := gob.NewEncoder(&b).Encode(saved)
+	return b.Bytes(), err
+}
+
+// GobDecode reads the summary of a channel, the rest is kept to be decoded
+// by load.
+func (c *Channel) GobDecode(b []byte) error {
+	var saved savedChannel
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&saved); err != nil {
+		return err
+	}
+
+	c.setSummary(saved.Summary)
+	c.lazy = saved.Data
+	return nil
+}
+
+func (c *Channel) summary() channelSummary {
+	return channelSummary{
+		c.ID, c.NetworkID, c.Name, c.Topic, c.JoinCount, c.PartCount, c.MessageCount, c.LastActive,
+	}
+}
+
+func (c *Channel) setSummary(s channelSummary) {
+	c.ID, c.NetworkID, c.Name, c.Topic = s.ID, s.NetworkID, s.Name, s.Topic
+	c.JoinCount, c.PartCount, c.MessageCount, c.LastActive = s.JoinCount, s.PartCount, s.MessageCount, s.LastActive
+}
+
+// load decodes the rest of the channel the first time it's used, dropping
+// the ids of the messages pruned while it wasn't loaded. A channel that fails
+// to decode is nil, it keeps what was read so it's saved again as it was, see
+// LoadErrors.
+func (c *Channel) load(s *Stats) *Channel {
+	if c == nil {
+		return nil
+	}
+
+	c.lazyMut.Lock()
+	defer c.lazyMut.Unlock()
+
+	if c.lazy == nil {
+		return c
+	}
+	if c.loadErr != nil {
+		return nil
+	}
+
+	summary := c.summary()
+	if err := gob.NewDecoder(bytes.NewReader(c.lazy)).Decode((*channelFields)(c)); err != nil {
+		// the summary is saved along with the channel as it was read
+		c.setSummary(summary)
+		c.loadErr = fmt.Errorf("Failed to load channel %s: %w", c.Name, err)
+		s.opts.logger().Error("Failed to load a channel", "channel", c.Name, "err", err)
+		return nil
+	}
+	c.lazy = nil
+	migrateIDs(&c.MessageIDs, &c.MessageRanges, &c.MessageCount)
+	c.MessageRanges.prune(s.PrunedBefore)
+	c.pruneDated(s.PrunedDates)
+
+	return c
+}
+
+// loaded checks if the whole channel was decoded.
+func (c *Channel) loaded() bool {
+	c.lazyMut.Lock()
+	defer c.lazyMut.Unlock()
+
+	return c.lazy == nil
+}
+
+// LoadErrors returns the errors of the channels that failed to load. Those
+// channels aren't found and their messages aren't counted, data.db keeps them
+// as they were.
+func (s *Stats) LoadErrors() []error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	ids := make([]uint, 0, len(s.Channels))
+	for id := range s.Channels {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var errs []error
+	for _, id := range ids {
+		c := s.Channels[id]
+		c.lazyMut.Lock()
+		if c.loadErr != nil {
+			errs = append(errs, c.loadErr)
+		}
+		c.lazyMut.Unlock()
+	}
+	return errs
+}
+
+// legacyStats is how the stats were saved before channels were loaded
+// lazily.
+type legacyStats struct {
+	Channels map[uint]*channelFields
+	Networks map[uint]*Network
+	Users    map[uint]*User
+
+	NetworkIDCount uint
+	MessageIDCount uint
+	ChannelIDCount uint
+	UserIDCount    uint
+
+	Imports map[string]ImportRanges
+	Marks   []IDMark
+}
+
+// stats converts the legacy stats.
+func (l *legacyStats) stats() *Stats {
+	s := &Stats{
+		Channels: make(map[uint]*Channel, len(l.Channels)),
+		Networks: l.Networks,
+		Users:    l.Users,
+
+		NetworkIDCount: l.NetworkIDCount,
+		MessageIDCount: l.MessageIDCount,
+		ChannelIDCount: l.ChannelIDCount,
+		UserIDCount:    l.UserIDCount,
+
+		Imports: l.Imports,
+		Marks:   l.Marks,
+	}
+
+	for id, c := range l.Channels {
+		s.Channels[id] = (*Channel)(c)
+	}
+	return s
+}
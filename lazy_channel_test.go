@@ -0,0 +1,186 @@
+package stats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/gob"
+	"testing"
+	"time"
+)
+
+// saveLoad saves the stats and loads them back, it changes the file opener
+// so it must not run in parallel.
+func saveLoad(t *testing.T, s *Stats) *Stats {
+	var b bytes.Buffer
+	fileOpener = &fakeFileOpener{&b}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	if err := s.Save(); err != nil {
+		t.Fatal("Should save the stats:", err)
+	}
+
+	loaded, err := loadDatabase(context.Background(), defaultPath)
+	if err != nil || loaded == nil {
+		t.Fatal("Should load the stats:", err)
+	}
+	return loaded
+}
+
+func TestChannel_lazyLoad(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "bar")
+
+	s = saveLoad(t, s)
+	c := s.Channels[1]
+	if c.loaded() {
+		t.Error("Should only load the summary of the channel.")
+	}
+	if c.Name != channel || c.LastActive.IsZero() || c.MessageCount != 2 || c.NetworkID != 1 {
+		t.Errorf("Should load the summary: %+v", c)
+	}
+	if c.WordCounter.All != nil {
+		t.Error("Should not decode the counters yet.")
+	}
+
+	// saved again without being loaded
+	s = saveLoad(t, s)
+	if c = s.GetChannel(network, channel); !c.loaded() || c.WordCounter.All["foo"] != 1 || c.MessageRanges.Len() != 2 {
+		t.Errorf("Should load the channel once used: %+v", c.WordCounter)
+	}
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "more foo")
+	if c.WordCounter.All["foo"] != 2 {
+		t.Error("Should keep counting the loaded channel.")
+	}
+}
+
+func TestChannel_lazyLoadTx(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	s = saveLoad(t, s)
+	s.View(func(tx *ReadTx) {
+		if tx.Channels[1].loaded() {
+			t.Error("Should not load the channel before it's used.")
+		}
+		if c := tx.Channel(1); c == nil || c.WordCounter.All["foo"] != 1 {
+			t.Error("Should load the channel:", c)
+		}
+		if tx.Channel(2) != nil {
+			t.Error("Should not find unknown channels.")
+		}
+	})
+}
+
+func TestChannel_lazyLoadPruned(t *testing.T) {
+	s := newStats()
+	date := time.Now().AddDate(0, 0, -10)
+	s.AddMessage(Msg, network, channel, hostmask, date, "old")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "new")
+
+	s = saveLoad(t, s)
+	s.opts.Retention = 5 * 24 * time.Hour
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.GetChannel(network, channel)
+	if c.MessageRanges.Len() != 1 || c.MessageRanges.First != 2 {
+		t.Error("Should prune the channel once loaded:", c.MessageRanges.All())
+	}
+	if c.MessageCount != 2 {
+		t.Error("Should keep counting the pruned messages:", c.MessageCount)
+	}
+}
+
+func TestChannel_lazyLoadPrunedDates(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now().AddDate(0, 0, -10), "old http://old.com")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "new http://new.com")
+
+	s = saveLoad(t, s)
+	s.opts.Retentions.URLs = 5 * 24 * time.Hour
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.GetChannel(network, channel)
+	if _, ok := c.URLCounter.All["http://old.com"]; ok || c.URLCounter.All["http://new.com"] != 1 {
+		t.Error("Should prune the urls of the channel once loaded:", c.URLCounter.All)
+	}
+	if c.MessageRanges.Len() != 2 {
+		t.Error("Should keep the messages without a retention:", c.MessageRanges.All())
+	}
+}
+
+func TestChannel_lazyLoadBroken(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	s = saveLoad(t, s)
+	broken := []byte("not a channel")
+	s.Channels[1].lazy = broken
+
+	if c := s.GetChannel(network, channel); c != nil {
+		t.Error("Should not find a channel that failed to load:", c)
+	}
+	if errs := s.LoadErrors(); len(errs) != 1 {
+		t.Error("Should return the error of the channel:", errs)
+	}
+
+	count := s.MessageIDCount
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "more foo")
+	if s.MessageIDCount != count {
+		t.Error("Should not count the messages of the channel.")
+	}
+
+	s = saveLoad(t, s)
+	c := s.Channels[1]
+	if !bytes.Equal(c.lazy, broken) || c.Name != channel || c.MessageCount != 1 {
+		t.Errorf("Should save the channel as it was read: %q %+v", c.lazy, c.summary())
+	}
+}
+
+func TestStats_loadLegacyDatabase(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	legacy := legacyStats{
+		Channels:       map[uint]*channelFields{1: (*channelFields)(s.Channels[1])},
+		Networks:       s.Networks,
+		Users:          s.Users,
+		NetworkIDCount: s.NetworkIDCount,
+		MessageIDCount: s.MessageIDCount,
+		ChannelIDCount: s.ChannelIDCount,
+		UserIDCount:    s.UserIDCount,
+		Imports:        s.Imports,
+		Marks:          s.Marks,
+	}
+
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if err := gob.NewEncoder(gz).Encode(legacy); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+
+	fileOpener = &fakeFileOpener{&b}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	loaded, err := loadDatabase(context.Background(), defaultPath)
+	if err != nil || loaded == nil {
+		t.Fatal("Should load stats saved before channels were lazy:", err)
+	}
+	if c := loaded.GetChannel(network, channel); c == nil || c.WordCounter.All["foo"] != 1 || c.MessageCount != 1 {
+		t.Error("Should load the whole channel:", c)
+	}
+	if loaded.MessageIDCount != s.MessageIDCount || loaded.GetUser(network, nick) == nil {
+		t.Error("Should load the rest of the stats.")
+	}
+}
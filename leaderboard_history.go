@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// WeekChampion is the user who said the most lines in a channel in a week.
+type WeekChampion struct {
+	Week   string `json:"week"`
+	UserID uint   `json:"userid"`
+	Lines  uint   `json:"lines"`
+}
+
+// Streak is a run of consecutive weeks a user spent at #1.
+type Streak struct {
+	UserID uint   `json:"userid"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Weeks  uint   `json:"weeks"`
+}
+
+// LeaderboardHistory snapshots the #1 talker of every week. Lines holds the
+// counts of the week in progress, which is added to Weeks once it is over.
+// Past keeps the counts of the weeks that are over, so messages played back
+// late still count toward their week. Weeks that were over before Past was
+// kept have none, late messages of those are skipped.
+type LeaderboardHistory struct {
+	Week  string
+	Lines map[uint]uint
+	Weeks []WeekChampion
+	Past  map[string]map[uint]uint
+}
+
+func NewLeaderboardHistory() LeaderboardHistory {
+	return LeaderboardHistory{
+		Lines: make(map[uint]uint),
+		Weeks: make([]WeekChampion, 0),
+		Past:  make(map[string]map[uint]uint),
+	}
+}
+
+// weekOf returns the ISO week of a date, eg. 2014-W09.
+func weekOf(date time.Time) string {
+	year, week := date.ISOWeek()
+	return fmt.Sprintf("%04d-W%02d", year, week)
+}
+
+// addMessage
+func (l *LeaderboardHistory) addMessage(message *Message) {
+	week := weekOf(message.Date)
+
+	if week < l.Week {
+		l.addLate(week, message.UserID)
+		return
+	}
+
+	if week > l.Week {
+		if champ, ok := l.current(); ok {
+			l.Weeks = append(l.Weeks, champ)
+			l.past()[l.Week] = l.Lines
+		}
+		l.Week = week
+		l.Lines = make(map[uint]uint)
+	}
+
+	l.Lines[message.UserID]++
+}
+
+// addLate counts a line toward a week that is over, crowning its champion
+// again.
+func (l *LeaderboardHistory) addLate(week string, userID uint) {
+	i := sort.Search(len(l.Weeks), func(i int) bool {
+		return l.Weeks[i].Week >= week
+	})
+	over := i < len(l.Weeks) && l.Weeks[i].Week == week
+
+	lines, ok := l.past()[week]
+	if !ok {
+		if over {
+			return
+		}
+		lines = make(map[uint]uint)
+		l.Past[week] = lines
+	}
+	lines[userID]++
+
+	champ, _ := champion(week, lines)
+	if over {
+		l.Weeks[i] = champ
+		return
+	}
+	l.Weeks = append(l.Weeks, WeekChampion{})
+	copy(l.Weeks[i+1:], l.Weeks[i:])
+	l.Weeks[i] = champ
+}
+
+// past returns Past, made if the history was saved before it was kept.
+func (l *LeaderboardHistory) past() map[string]map[uint]uint {
+	if l.Past == nil {
+		l.Past = make(map[string]map[uint]uint)
+	}
+	return l.Past
+}
+
+// current returns the leader of the week in progress.
+func (l *LeaderboardHistory) current() (WeekChampion, bool) {
+	return champion(l.Week, l.Lines)
+}
+
+// champion returns the user with the most lines of a week.
+func champion(week string, lines map[uint]uint) (WeekChampion, bool) {
+	champ := WeekChampion{Week: week}
+
+	for id, lines := range lines {
+		if lines > champ.Lines || lines == champ.Lines && id < champ.UserID {
+			champ.UserID, champ.Lines = id, lines
+		}
+	}
+
+	return champ, champ.Lines > 0
+}
+
+// Champions returns the #1 of every week, including the week in progress.
+func (l *LeaderboardHistory) Champions() []WeekChampion {
+	champs := make([]WeekChampion, len(l.Weeks), len(l.Weeks)+1)
+	copy(champs, l.Weeks)
+
+	if champ, ok := l.current(); ok {
+		champs = append(champs, champ)
+	}
+
+	return champs
+}
+
+// Reigning returns the current streak of the reigning champion.
+func (l *LeaderboardHistory) Reigning() (Streak, bool) {
+	streaks := l.streaks()
+	if len(streaks) == 0 {
+		return Streak{}, false
+	}
+
+	return streaks[len(streaks)-1], true
+}
+
+// LongestStreak returns the longest run at #1. The earliest streak wins ties.
+func (l *LeaderboardHistory) LongestStreak() (Streak, bool) {
+	var longest Streak
+	for _, s := range l.streaks() {
+		if s.Weeks > longest.Weeks {
+			longest = s
+		}
+	}
+
+	return longest, longest.Weeks > 0
+}
+
+// streaks groups consecutive weeks with the same champion.
+func (l *LeaderboardHistory) streaks() []Streak {
+	var streaks []Streak
+
+	for _, champ := range l.Champions() {
+		if n := len(streaks); n > 0 && streaks[n-1].UserID == champ.UserID {
+			streaks[n-1].To = champ.Week
+			streaks[n-1].Weeks++
+			continue
+		}
+
+		streaks = append(streaks, Streak{
+			UserID: champ.UserID,
+			From:   champ.Week,
+			To:     champ.Week,
+			Weeks:  1,
+		})
+	}
+
+	return streaks
+}
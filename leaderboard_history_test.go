@@ -0,0 +1,83 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeaderboardHistory(t *testing.T) {
+	t.Parallel()
+
+	l := NewLeaderboardHistory()
+	week := 7 * 24 * time.Hour
+	start := time.Date(2014, 3, 3, 8, 0, 0, 0, time.UTC) // a monday
+
+	if _, ok := l.Reigning(); ok {
+		t.Error("Should not have a champion yet.")
+	}
+
+	talk := func(userID uint, date time.Time, lines int) {
+		for i := 0; i < lines; i++ {
+			l.addMessage(&Message{UserID: userID, Date: date})
+		}
+	}
+
+	talk(1, start, 3)
+	talk(2, start, 2)
+	talk(1, start.Add(week), 1)
+	talk(2, start.Add(2*week), 4)
+	talk(1, start, 10) // played back late, still counted toward their week
+	talk(2, start.Add(3*week), 1)
+
+	champs := l.Champions()
+	if len(champs) != 4 {
+		t.Fatal("Should have four weeks, Got:", champs)
+	}
+
+	if champs[0].Week != "2014-W10" || champs[0].UserID != 1 || champs[0].Lines != 13 {
+		t.Error("User 1 should have won the first week, Got:", champs[0])
+	}
+
+	if len(l.Weeks) != 3 {
+		t.Error("The week in progress should not be snapshotted yet.")
+	}
+
+	if s, _ := l.Reigning(); s.UserID != 2 || s.Weeks != 2 || s.From != "2014-W12" {
+		t.Error("User 2 should be reigning for two weeks, Got:", s)
+	}
+
+	if s, _ := l.LongestStreak(); s.UserID != 1 || s.Weeks != 2 {
+		t.Error("User 1 should have the earliest longest streak, Got:", s)
+	}
+}
+
+func TestLeaderboardHistory_late(t *testing.T) {
+	t.Parallel()
+
+	l := NewLeaderboardHistory()
+	week := 7 * 24 * time.Hour
+	start := time.Date(2014, 3, 3, 8, 0, 0, 0, time.UTC) // a monday
+
+	l.addMessage(&Message{UserID: 1, Date: start.Add(2 * week)})
+	l.addMessage(&Message{UserID: 2, Date: start.Add(3 * week)})
+	// the history before imported afterwards
+	l.addMessage(&Message{UserID: 1, Date: start})
+	l.addMessage(&Message{UserID: 1, Date: start.Add(week)})
+	l.addMessage(&Message{UserID: 2, Date: start.Add(week)})
+	l.addMessage(&Message{UserID: 2, Date: start.Add(week)})
+
+	champs := l.Champions()
+	if len(champs) != 4 || champs[0].Week != "2014-W10" || champs[1].UserID != 2 || champs[1].Lines != 2 {
+		t.Fatal("Should crown the champions of the weeks played back in order:", champs)
+	}
+	if s, _ := l.LongestStreak(); s.UserID != 1 || s.Weeks != 1 || s.From != "2014-W10" {
+		t.Error("Should break the streaks the weeks played back break:", s)
+	}
+
+	// weeks that were over before their lines were kept
+	delete(l.Past, "2014-W12")
+	l.addMessage(&Message{UserID: 2, Date: start.Add(2 * week)})
+	if champs := l.Champions(); champs[2].UserID != 1 || champs[2].Lines != 1 {
+		t.Error("Should not crown champions without the lines of their week:", champs[2])
+	}
+}
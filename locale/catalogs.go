@@ -0,0 +1,59 @@
+package locale
+
+// English is the default locale, its messages are their own translation.
+var English = &Locale{Name: "en"}
+
+// French is the locale of the French communities, grouping the digits by
+// narrow no-break spaces.
+var French = &Locale{
+	Name:       "fr",
+	Thousands:  "\u202f",
+	Decimal:    ",",
+	DateFormat: "02/01/2006",
+	TimeFormat: "02/01/2006 15:04 MST",
+	Messages: map[string]string{
+		"Generated %s.":            "Généré le %s.",
+		"%s on %s":                 "%s sur %s",
+		"%s messages.":             "%s messages.",
+		"Nick":                     "Pseudo",
+		"Lines":                    "Lignes",
+		"Words":                    "Mots",
+		"Random quote":             "Citation au hasard",
+		"Hour":                     "Heure",
+		"Most used words":          "Mots les plus utilisés",
+		"Most pasted urls":         "Liens les plus collés",
+		"Nothing was counted yet.": "Rien n'a encore été compté.",
+
+		"%s in %s: %s lines.":            "%s sur %s : %s lignes.",
+		"Busiest day: %s with %s lines.": "Jour le plus actif : %s avec %s lignes.",
+		"Top talker: %s with %s lines.":  "Plus bavard : %s avec %s lignes.",
+		"New topic: %s":                  "Nouveau sujet : %s",
+	},
+}
+
+// German is the locale of the German communities.
+var German = &Locale{
+	Name:       "de",
+	Thousands:  ".",
+	Decimal:    ",",
+	DateFormat: "02.01.2006",
+	TimeFormat: "02.01.2006 15:04 MST",
+	Messages: map[string]string{
+		"Generated %s.":            "Erstellt am %s.",
+		"%s on %s":                 "%s auf %s",
+		"%s messages.":             "%s Nachrichten.",
+		"Nick":                     "Nick",
+		"Lines":                    "Zeilen",
+		"Words":                    "Wörter",
+		"Random quote":             "Zufälliges Zitat",
+		"Hour":                     "Stunde",
+		"Most used words":          "Häufigste Wörter",
+		"Most pasted urls":         "Häufigste Links",
+		"Nothing was counted yet.": "Noch wurde nichts gezählt.",
+
+		"%s in %s: %s lines.":            "%s in %s: %s Zeilen.",
+		"Busiest day: %s with %s lines.": "Aktivster Tag: %s mit %s Zeilen.",
+		"Top talker: %s with %s lines.":  "Vielredner: %s mit %s Zeilen.",
+		"New topic: %s":                  "Neues Thema: %s",
+	},
+}
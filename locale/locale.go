@@ -0,0 +1,213 @@
+// Package locale translates the text of the reports and digests, and formats
+// their numbers and dates the way a community reads them:
+//
+//	l, _ := locale.Lookup("de")
+//	l.Sprintf("%s messages.", l.Number(1234)) // 1.234 Nachrichten.
+//
+// The messages of a locale are keyed by their English format, those it
+// doesn't translate are left in English. Other languages are loaded from
+// json catalogs with Load and looked up once they're registered.
+package locale
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDateFormat = "2006-01-02"
+	defaultTimeFormat = "2006-01-02 15:04 MST"
+)
+
+// Locale is a message catalog and how numbers and dates are written, a nil
+// Locale is English.
+type Locale struct {
+	// Name is the language tag of the locale, eg. fr or pt-BR.
+	Name string `json:"name"`
+	// Thousands groups the digits of the numbers by three, "," when empty.
+	// Decimal separates their fraction, "." when empty.
+	Thousands string `json:"thousands"`
+	Decimal   string `json:"decimal"`
+	// DateFormat and TimeFormat are the time.Format layouts of the days
+	// and of the dates with their time, 2006-01-02 and
+	// 2006-01-02 15:04 MST when empty.
+	DateFormat string `json:"date_format"`
+	TimeFormat string `json:"time_format"`
+	// Messages are the translations of the messages, keyed by their English
+	// format. The verbs of a translation must be those of its message.
+	Messages map[string]string `json:"messages"`
+}
+
+// Message returns the translation of a message, or the message when it isn't
+// translated.
+func (l *Locale) Message(message string) string {
+	if l == nil {
+		return message
+	}
+	if translated, ok := l.Messages[message]; ok && len(translated) > 0 {
+		return translated
+	}
+	return message
+}
+
+// Sprintf formats the translation of a message with its arguments, the
+// numbers should be formatted with Number first.
+func (l *Locale) Sprintf(format string, args ...interface{}) string {
+	return fmt.Sprintf(l.Message(format), args...)
+}
+
+// Number writes an integer or float with the separators of the locale, the
+// floats with as few digits as tell them apart. Anything else is written as
+// fmt does.
+func (l *Locale) Number(n interface{}) string {
+	var s string
+	switch n := n.(type) {
+	case int:
+		s = strconv.FormatInt(int64(n), 10)
+	case int32:
+		s = strconv.FormatInt(int64(n), 10)
+	case int64:
+		s = strconv.FormatInt(n, 10)
+	case uint:
+		s = strconv.FormatUint(uint64(n), 10)
+	case uint32:
+		s = strconv.FormatUint(uint64(n), 10)
+	case uint64:
+		s = strconv.FormatUint(n, 10)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'f', -1, 32)
+	case float64:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	default:
+		return fmt.Sprint(n)
+	}
+
+	thousands, decimal := ",", "."
+	if l != nil && len(l.Thousands) > 0 {
+		thousands = l.Thousands
+	}
+	if l != nil && len(l.Decimal) > 0 {
+		decimal = l.Decimal
+	}
+
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	integer, fraction := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		integer, fraction = s[:i], decimal+s[i+1:]
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(fraction)
+	return b.String()
+}
+
+// Date writes the day of a date.
+func (l *Locale) Date(t time.Time) string {
+	if l == nil || len(l.DateFormat) == 0 {
+		return t.Format(defaultDateFormat)
+	}
+	return t.Format(l.DateFormat)
+}
+
+// Time writes a date with its time.
+func (l *Locale) Time(t time.Time) string {
+	if l == nil || len(l.TimeFormat) == 0 {
+		return t.Format(defaultTimeFormat)
+	}
+	return t.Format(l.TimeFormat)
+}
+
+// Load reads a locale from its json catalog, eg.
+//
+//	{"name": "fr", "thousands": " ", "decimal": ",", "date_format": "02/01/2006",
+//	 "messages": {"%s messages.": "%s messages."}}
+func Load(r io.Reader) (*Locale, error) {
+	var l Locale
+	if err := json.NewDecoder(r).Decode(&l); err != nil {
+		return nil, err
+	}
+	if len(l.Name) == 0 {
+		return nil, errors.New("A locale must have a name.")
+	}
+	return &l, nil
+}
+
+// LoadFile reads a locale from a json catalog file, see Load.
+func LoadFile(path string) (*Locale, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return l, nil
+}
+
+var (
+	registryMut sync.RWMutex
+	registry    = map[string]*Locale{}
+)
+
+func init() {
+	for _, l := range []*Locale{English, French, German} {
+		Register(l)
+	}
+}
+
+// Register makes a locale available to Lookup by its name, in place of the
+// one registered under the name before.
+func Register(l *Locale) {
+	registryMut.Lock()
+	defer registryMut.Unlock()
+
+	registry[strings.ToLower(l.Name)] = l
+}
+
+// Lookup finds a registered locale by its name, in any case. English is
+// returned for the empty name.
+func Lookup(name string) (*Locale, bool) {
+	if len(name) == 0 {
+		return English, true
+	}
+
+	registryMut.RLock()
+	defer registryMut.RUnlock()
+
+	l, ok := registry[strings.ToLower(name)]
+	return l, ok
+}
+
+// Names are the names of the registered locales, sorted.
+func Names() []string {
+	registryMut.RLock()
+	defer registryMut.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for _, l := range registry {
+		names = append(names, l.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,123 @@
+package locale
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocale_Sprintf(t *testing.T) {
+	t.Parallel()
+
+	if got := German.Sprintf("%s messages.", German.Number(1234)); got != "1.234 Nachrichten." {
+		t.Error("Should translate the message, got", got)
+	}
+	if got := German.Sprintf("Not translated %d", 1); got != "Not translated 1" {
+		t.Error("Should leave the messages not translated in English, got", got)
+	}
+
+	var l *Locale
+	if got := l.Sprintf("%s on %s", "#go", "zkpq"); got != "#go on zkpq" {
+		t.Error("Should be English without a locale, got", got)
+	}
+}
+
+func TestLocale_Number(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		l    *Locale
+		n    interface{}
+		want string
+	}{
+		{nil, 0, "0"},
+		{nil, 999, "999"},
+		{English, uint(1000), "1,000"},
+		{English, int64(-1234567), "-1,234,567"},
+		{French, 1234567.5, "1\u202f234\u202f567,5"},
+		{German, uint64(100000), "100.000"},
+		{German, "many", "many"},
+	}
+	for _, test := range tests {
+		if got := test.l.Number(test.n); got != test.want {
+			t.Errorf("%v should be written %q, is %q", test.n, test.want, got)
+		}
+	}
+}
+
+func TestLocale_Date(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2014, 3, 5, 18, 30, 0, 0, time.UTC)
+	if got := English.Date(date); got != "2014-03-05" {
+		t.Error("Should write the day, got", got)
+	}
+	if got := German.Date(date); got != "05.03.2014" {
+		t.Error("Should write the day as the locale does, got", got)
+	}
+	if got := French.Time(date); got != "05/03/2014 18:30 UTC" {
+		t.Error("Should write the time as the locale does, got", got)
+	}
+	var l *Locale
+	if got := l.Time(date); got != "2014-03-05 18:30 UTC" {
+		t.Error("Should write the time without a locale, got", got)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	l, err := Load(strings.NewReader(`{"name": "pt-BR", "decimal": ",", "messages": {"Lines": "Linhas"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Message("Lines") != "Linhas" || l.Number(0.5) != "0,5" {
+		t.Error("Should load the catalog:", l)
+	}
+
+	if _, ok := Lookup("pt-br"); ok {
+		t.Error("Should not look up locales that aren't registered.")
+	}
+	Register(l)
+	if got, ok := Lookup("PT-BR"); !ok || got != l {
+		t.Error("Should look up the registered locale in any case.")
+	}
+
+	if _, err := Load(strings.NewReader(`{"messages": {}}`)); err == nil {
+		t.Error("Should refuse a locale without a name.")
+	}
+	if _, err := Load(strings.NewReader(`{`)); err == nil {
+		t.Error("Should refuse bad json.")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	if l, ok := Lookup(""); !ok || l != English {
+		t.Error("Should default to English.")
+	}
+	for _, name := range []string{"en", "fr", "de"} {
+		if _, ok := Lookup(name); !ok {
+			t.Error("Should have the locale", name)
+		}
+	}
+	if _, ok := Lookup("tlh"); ok {
+		t.Error("Should not find unknown locales.")
+	}
+}
+
+var verbs = regexp.MustCompile(`%[a-z]`)
+
+func TestCatalogs(t *testing.T) {
+	t.Parallel()
+
+	for _, l := range []*Locale{French, German} {
+		for message, translated := range l.Messages {
+			if got, want := verbs.FindAllString(translated, -1), verbs.FindAllString(message, -1); strings.Join(got, "") != strings.Join(want, "") {
+				t.Errorf("%s: %q should have the verbs of %q", l.Name, translated, message)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// AppService receives the events of the rooms an application service is
+// interested in as transactions pushed by the homeserver. Serve it on the
+// url given in the application service's registration.
+type AppService struct {
+	Adapter *Adapter
+	// HSToken is the hs_token of the registration, requests without it are
+	// refused.
+	HSToken string
+
+	mut  sync.Mutex
+	seen map[string]struct{}
+}
+
+const transactionsPath = "/transactions/"
+
+// ServeHTTP handles PUT /_matrix/app/v1/transactions/{txnId} and the older
+// unversioned /transactions/{txnId}. Transactions are retried by the
+// homeserver until they succeed, so ones already handled are ignored.
+func (as *AppService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	i := strings.Index(r.URL.Path, transactionsPath)
+	if r.Method != "PUT" || i < 0 {
+		writeError(w, http.StatusNotFound, "M_UNRECOGNIZED", "Unrecognized request")
+		return
+	}
+
+	token := r.URL.Query().Get("access_token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if len(as.HSToken) == 0 || token != as.HSToken {
+		writeError(w, http.StatusForbidden, "M_FORBIDDEN", "Bad token")
+		return
+	}
+
+	var txn struct {
+		Events []Event `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&txn); err != nil {
+		writeError(w, http.StatusBadRequest, "M_NOT_JSON", "Bad transaction")
+		return
+	}
+
+	id := r.URL.Path[i+len(transactionsPath):]
+
+	as.mut.Lock()
+	if as.seen == nil {
+		as.seen = make(map[string]struct{})
+	}
+	_, seen := as.seen[id]
+	as.seen[id] = struct{}{}
+	as.mut.Unlock()
+
+	if !seen {
+		for _, ev := range txn.Events {
+			as.Adapter.HandleEvent(ev)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte("{}"))
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(struct {
+		Code    string `json:"errcode"`
+		Message string `json:"error"`
+	}{code, message})
+}
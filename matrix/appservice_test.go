@@ -0,0 +1,51 @@
+package matrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestAppService(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+	a.Rooms[room] = "#deviate"
+	as := &AppService{Adapter: a, HSToken: "secret"}
+
+	ev := message("@dylan:zqz.ca", "m.text", "hello")
+	body, _ := json.Marshal(map[string][]Event{"events": {ev}})
+
+	put := func(path string) int {
+		r := httptest.NewRequest("PUT", path, bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		as.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := put("/_matrix/app/v1/transactions/1?access_token=wrong"); code != http.StatusForbidden {
+		t.Error("Should refuse bad tokens, Got:", code)
+	}
+	if code := put("/_matrix/app/v1/transactions/1?access_token=secret"); code != http.StatusOK {
+		t.Error("Should accept the transaction, Got:", code)
+	}
+	if code := put("/_matrix/app/v1/transactions/1?access_token=secret"); code != http.StatusOK {
+		t.Error("Should accept retried transactions, Got:", code)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should count the message once.")
+	}
+
+	if code := put("/_matrix/app/v1/users/@dylan:zqz.ca?access_token=secret"); code != http.StatusNotFound {
+		t.Error("Should not handle other requests, Got:", code)
+	}
+}
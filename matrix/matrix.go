@@ -0,0 +1,285 @@
+// Package matrix feeds the events of Matrix rooms into a Stats database, so
+// communities bridged between IRC and Matrix get one set of stats. Events
+// arrive either by syncing as a client, see Client, or by registering as an
+// application service, see AppService.
+package matrix
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Event is a Matrix room event, only the fields needed for stats are decoded.
+type Event struct {
+	Type      string          `json:"type"`
+	EventID   string          `json:"event_id"`
+	RoomID    string          `json:"room_id"`
+	Sender    string          `json:"sender"`
+	StateKey  *string         `json:"state_key,omitempty"`
+	Timestamp int64           `json:"origin_server_ts"`
+	Content   json.RawMessage `json:"content"`
+	Unsigned  struct {
+		PrevContent json.RawMessage `json:"prev_content,omitempty"`
+	} `json:"unsigned"`
+}
+
+type messageContent struct {
+	MsgType string `json:"msgtype"`
+	Body    string `json:"body"`
+}
+
+type memberContent struct {
+	Membership  string `json:"membership"`
+	DisplayName string `json:"displayname"`
+	Reason      string `json:"reason"`
+}
+
+// Adapter maps Matrix events onto AddMessage calls.
+type Adapter struct {
+	Stats *stats.Stats
+	// Network is the network the rooms are counted under. To merge the
+	// stats with a bridged IRC network use the IRC network's name.
+	Network string
+	// Rooms maps room ids to channel names. Rooms not in it are named after
+	// their canonical alias, or their id if they have none.
+	Rooms map[string]string
+
+	// UseMXIDs counts users by the localpart of their Matrix id instead of
+	// their display name.
+	UseMXIDs bool
+	// NameSuffixes are stripped from display names, bridges often mark
+	// puppeted users with a suffix such as " (IRC)".
+	NameSuffixes []string
+
+	mut     sync.Mutex
+	names   map[string]string // room id + mxid to display name
+	aliases map[string]string // room id to canonical alias
+}
+
+// defaultSuffixes are stripped from display names if NameSuffixes is nil.
+var defaultSuffixes = []string{" (IRC)"}
+
+// New creates an adapter feeding the stats under the network.
+func New(s *stats.Stats, network string) *Adapter {
+	return &Adapter{
+		Stats:   s,
+		Network: network,
+		Rooms:   make(map[string]string),
+	}
+}
+
+// HandleEvent adds a room event to the stats, returning false if the event is
+// not something that is counted.
+func (a *Adapter) HandleEvent(ev Event) bool {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	kind, sender, message, ok := a.convert(ev)
+	if !ok {
+		return false
+	}
+
+	channel := a.channel(ev.RoomID)
+	if kind == stats.Nick {
+		channel = ""
+	}
+
+	date := time.Unix(0, ev.Timestamp*int64(time.Millisecond))
+
+	a.Stats.AddMessage(kind, a.Network, channel, sender, date, message)
+	return true
+}
+
+// HandleState learns the display names and aliases of a room from a state
+// event without counting it, for the state sent when first syncing a room.
+func (a *Adapter) HandleState(ev Event) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	switch ev.Type {
+	case "m.room.member":
+		var m memberContent
+		if ev.StateKey == nil || json.Unmarshal(ev.Content, &m) != nil {
+			return
+		}
+		a.setName(ev.RoomID, *ev.StateKey, m)
+	case "m.room.canonical_alias":
+		a.learnAlias(ev)
+	}
+}
+
+// convert maps an event onto the kind, sender hostmask and message of
+// AddMessage.
+func (a *Adapter) convert(ev Event) (kind stats.MsgKind, sender, message string, ok bool) {
+	switch ev.Type {
+	case "m.room.message":
+		var m messageContent
+		if json.Unmarshal(ev.Content, &m) != nil {
+			return kind, "", "", false
+		}
+
+		switch m.MsgType {
+		case "m.text":
+			kind = stats.Msg
+		case "m.emote":
+			kind = stats.Action
+		case "m.notice":
+			kind = stats.Notice
+		default:
+			return kind, "", "", false
+		}
+		return kind, a.hostmask(ev.RoomID, ev.Sender), m.Body, true
+
+	case "m.room.topic":
+		var t struct {
+			Topic string `json:"topic"`
+		}
+		if json.Unmarshal(ev.Content, &t) != nil {
+			return kind, "", "", false
+		}
+		return stats.Topic, a.hostmask(ev.RoomID, ev.Sender), t.Topic, true
+
+	case "m.room.member":
+		return a.convertMember(ev)
+
+	case "m.room.canonical_alias":
+		a.learnAlias(ev)
+	}
+
+	return kind, "", "", false
+}
+
+// convertMember maps membership changes onto joins, parts, kicks, bans and
+// nick changes.
+func (a *Adapter) convertMember(ev Event) (kind stats.MsgKind, sender, message string, ok bool) {
+	var m, prev memberContent
+	if ev.StateKey == nil || json.Unmarshal(ev.Content, &m) != nil {
+		return kind, "", "", false
+	}
+	if len(ev.Unsigned.PrevContent) > 0 {
+		json.Unmarshal(ev.Unsigned.PrevContent, &prev)
+	}
+
+	target := *ev.StateKey
+	targetMask := a.hostmask(ev.RoomID, target)
+	defer a.setName(ev.RoomID, target, m)
+
+	switch m.Membership {
+	case "join":
+		if prev.Membership != "join" {
+			a.setName(ev.RoomID, target, m)
+			return stats.Join, a.hostmask(ev.RoomID, target), "", true
+		}
+		if a.UseMXIDs || a.displayName(target, m) == a.displayName(target, prev) {
+			return kind, "", "", false
+		}
+		return stats.Nick, targetMask, a.displayName(target, m), true
+
+	case "leave":
+		if target == ev.Sender {
+			return stats.Part, targetMask, m.Reason, true
+		}
+		return stats.Kick, a.hostmask(ev.RoomID, ev.Sender), strings.TrimSpace(a.nick(ev.RoomID, target) + " " + m.Reason), true
+
+	case "ban":
+		return stats.Mode, a.hostmask(ev.RoomID, ev.Sender), "+b", true
+	}
+
+	return kind, "", "", false
+}
+
+func (a *Adapter) learnAlias(ev Event) {
+	var c struct {
+		Alias string `json:"alias"`
+	}
+	if json.Unmarshal(ev.Content, &c) != nil || len(c.Alias) == 0 {
+		return
+	}
+
+	if a.aliases == nil {
+		a.aliases = make(map[string]string)
+	}
+	a.aliases[ev.RoomID] = stripServer(c.Alias)
+}
+
+func (a *Adapter) setName(room, mxid string, m memberContent) {
+	if a.names == nil {
+		a.names = make(map[string]string)
+	}
+
+	if m.Membership == "join" {
+		a.names[room+" "+mxid] = a.displayName(mxid, m)
+	} else {
+		delete(a.names, room+" "+mxid)
+	}
+}
+
+// channel is the name a room is counted under.
+func (a *Adapter) channel(room string) string {
+	if name, ok := a.Rooms[room]; ok {
+		return name
+	}
+	if alias, ok := a.aliases[room]; ok {
+		return alias
+	}
+	return room
+}
+
+// displayName is the name of a user in stats according to their member
+// event. Display names that can't be nicks fall back to the Matrix id.
+func (a *Adapter) displayName(mxid string, m memberContent) string {
+	if a.UseMXIDs || len(m.DisplayName) == 0 {
+		return localpart(mxid)
+	}
+
+	suffixes := a.NameSuffixes
+	if suffixes == nil {
+		suffixes = defaultSuffixes
+	}
+
+	name := m.DisplayName
+	for _, s := range suffixes {
+		name = strings.TrimSuffix(name, s)
+	}
+
+	if len(name) == 0 || strings.ContainsAny(name, " \t!@") {
+		return localpart(mxid)
+	}
+	return name
+}
+
+// nick is the nick of a user in a room.
+func (a *Adapter) nick(room, mxid string) string {
+	if name, ok := a.names[room+" "+mxid]; ok {
+		return name
+	}
+	return localpart(mxid)
+}
+
+// hostmask builds a hostmask for a user out of their nick and Matrix id,
+// @dylan:zqz.ca known as Dylan becomes Dylan!dylan@zqz.ca.
+func (a *Adapter) hostmask(room, mxid string) string {
+	user, server := localpart(mxid), ""
+	if i := strings.IndexByte(mxid, ':'); i >= 0 {
+		server = mxid[i+1:]
+	}
+	return a.nick(room, mxid) + "!" + user + "@" + server
+}
+
+// localpart strips the sigil and server from a Matrix user id.
+func localpart(mxid string) string {
+	return strings.TrimPrefix(stripServer(mxid), "@")
+}
+
+// stripServer strips the server from a Matrix id or alias, #go:matrix.org
+// becomes #go.
+func stripServer(id string) string {
+	if i := strings.IndexByte(id, ':'); i >= 0 {
+		return id[:i]
+	}
+	return id
+}
@@ -0,0 +1,135 @@
+package matrix
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+const room = "!abc:zqz.ca"
+
+func strptr(s string) *string {
+	return &s
+}
+
+func event(typ, sender string, stateKey *string, content, prev interface{}) Event {
+	ev := Event{Type: typ, RoomID: room, Sender: sender, StateKey: stateKey, Timestamp: 1393660800000}
+	ev.Content, _ = json.Marshal(content)
+	if prev != nil {
+		ev.Unsigned.PrevContent, _ = json.Marshal(prev)
+	}
+	return ev
+}
+
+func member(mxid, membership, name string, prev interface{}) Event {
+	return event("m.room.member", mxid, strptr(mxid), map[string]string{"membership": membership, "displayname": name}, prev)
+}
+
+func message(sender, msgtype, body string) Event {
+	return event("m.room.message", sender, nil, map[string]string{"msgtype": msgtype, "body": body}, nil)
+}
+
+func TestAdapter_HandleEvent(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+	a.Rooms[room] = "#deviate"
+
+	a.HandleEvent(member("@dylan:zqz.ca", "join", "dylan (IRC)", nil))
+	a.HandleEvent(message("@dylan:zqz.ca", "m.text", "hello there"))
+	a.HandleEvent(message("@dylan:zqz.ca", "m.emote", "waves"))
+	if a.HandleEvent(message("@dylan:zqz.ca", "m.image", "cat.png")) {
+		t.Error("Should not count images.")
+	}
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Should have counted the room as its channel.")
+	}
+
+	u := s.GetUser("zkpq", "dylan")
+	if u == nil || u.Lines != 1 || u.MessageRanges.Len() != 3 {
+		t.Fatal("Should count dylan by the display name without the suffix:", u)
+	}
+
+	a.HandleEvent(member("@dylan:zqz.ca", "join", "dilly", map[string]string{"membership": "join", "displayname": "dylan (IRC)"}))
+	if u.NickChanges != 1 {
+		t.Error("Should count display name changes as nick changes.")
+	}
+
+	a.HandleEvent(message("@dylan:zqz.ca", "m.text", "renamed"))
+	if d := s.GetUser("zkpq", "dilly"); d == nil || d.Lines != 1 {
+		t.Error("Should count messages under the new display name.")
+	}
+}
+
+func TestAdapter_Kick(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+
+	a.HandleEvent(event("m.room.canonical_alias", "@aaron:zqz.ca", strptr(""), map[string]string{"alias": "#deviate:zqz.ca"}, nil))
+	a.HandleEvent(member("@aaron:zqz.ca", "join", "", nil))
+	a.HandleEvent(member("@knivey:zqz.ca", "join", "knivey", nil))
+	a.HandleEvent(event("m.room.member", "@aaron:zqz.ca", strptr("@knivey:zqz.ca"),
+		map[string]string{"membership": "leave", "reason": "bye"}, map[string]string{"membership": "join"}))
+
+	if s.GetChannel("zkpq", "#deviate") == nil {
+		t.Fatal("Should name the room after its alias.")
+	}
+
+	if aaron := s.GetUser("zkpq", "aaron"); aaron == nil || aaron.KickCounters.Sent != 1 {
+		t.Error("Should count aaron's kick.")
+	}
+	if knivey := s.GetUser("zkpq", "knivey"); knivey == nil || knivey.KickCounters.Received != 1 {
+		t.Error("Should count knivey being kicked.")
+	}
+}
+
+func TestAdapter_UseMXIDs(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "matrix")
+	a.UseMXIDs = true
+
+	a.HandleEvent(member("@dylan:zqz.ca", "join", "Dylan J", nil))
+	a.HandleEvent(message("@dylan:zqz.ca", "m.text", "hello"))
+
+	if u := s.GetUser("matrix", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should count users by their Matrix id.")
+	}
+}
+
+func TestDisplayName(t *testing.T) {
+	t.Parallel()
+
+	a := &Adapter{}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"", "dylan"},
+		{"dylan_ (IRC)", "dylan_"},
+		{"Dylan J", "dylan"},
+		{"DylanJ", "DylanJ"},
+	}
+
+	for _, test := range tests {
+		if got := a.displayName("@dylan:zqz.ca", memberContent{DisplayName: test.name}); got != test.want {
+			t.Errorf("%q: want %q, got %q", test.name, test.want, got)
+		}
+	}
+}
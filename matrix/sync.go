@@ -0,0 +1,115 @@
+package matrix
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// syncTimeout is how long the homeserver may hold a sync request open.
+const syncTimeout = 30 * time.Second
+
+// Client syncs with a homeserver as a regular user and feeds the events of
+// the rooms it has joined into an adapter.
+type Client struct {
+	Adapter *Adapter
+	// Homeserver is the base url of the homeserver, eg. https://matrix.org.
+	Homeserver  string
+	AccessToken string
+	// Since is the sync token to continue from. Save it between runs to
+	// avoid counting events twice. When empty the first sync only learns
+	// the state of the rooms and skips their recent history.
+	Since string
+
+	HTTP *http.Client
+}
+
+type syncResponse struct {
+	NextBatch string `json:"next_batch"`
+	Rooms     struct {
+		Join map[string]struct {
+			State struct {
+				Events []Event `json:"events"`
+			} `json:"state"`
+			Timeline struct {
+				Events []Event `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+	} `json:"rooms"`
+}
+
+// Sync runs a single sync, returning the number of events counted.
+func (c *Client) Sync() (int, error) {
+	query := url.Values{"timeout": {fmt.Sprint(int(syncTimeout / time.Millisecond))}}
+	if len(c.Since) > 0 {
+		query.Set("since", c.Since)
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimSuffix(c.Homeserver, "/")+"/_matrix/client/r0/sync?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.AccessToken)
+
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 2 * syncTimeout}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("matrix: sync failed: %s", resp.Status)
+	}
+
+	var sr syncResponse
+	if err = json.NewDecoder(resp.Body).Decode(&sr); err != nil {
+		return 0, err
+	}
+
+	initial := len(c.Since) == 0
+	counted := 0
+	for room, r := range sr.Rooms.Join {
+		for _, ev := range r.State.Events {
+			ev.RoomID = room
+			c.Adapter.HandleState(ev)
+		}
+		for _, ev := range r.Timeline.Events {
+			ev.RoomID = room
+			if initial {
+				c.Adapter.HandleState(ev)
+			} else if c.Adapter.HandleEvent(ev) {
+				counted++
+			}
+		}
+	}
+
+	c.Since = sr.NextBatch
+	return counted, nil
+}
+
+// Run syncs until stop is closed, waiting a little after failed syncs.
+func (c *Client) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if _, err := c.Sync(); err != nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package matrix
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestClient_Sync(t *testing.T) {
+	t.Parallel()
+
+	var tokens []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_matrix/client/r0/sync" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "nope", http.StatusForbidden)
+			return
+		}
+		tokens = append(tokens, r.URL.Query().Get("since"))
+
+		var resp syncResponse
+		resp.NextBatch = "s" + string(rune('0'+len(tokens)))
+		resp.Rooms.Join = map[string]struct {
+			State struct {
+				Events []Event `json:"events"`
+			} `json:"state"`
+			Timeline struct {
+				Events []Event `json:"events"`
+			} `json:"timeline"`
+		}{}
+
+		r2 := resp.Rooms.Join[room]
+		r2.State.Events = []Event{member("@dylan:zqz.ca", "join", "dylan", nil)}
+		r2.Timeline.Events = []Event{message("@dylan:zqz.ca", "m.text", "hello")}
+		resp.Rooms.Join[room] = r2
+
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+	a.Rooms[room] = "#deviate"
+	c := &Client{Adapter: a, Homeserver: server.URL, AccessToken: "secret"}
+
+	if n, err := c.Sync(); err != nil || n != 0 {
+		t.Fatal("The first sync should only learn state:", n, err)
+	}
+	if n, err := c.Sync(); err != nil || n != 1 {
+		t.Fatal("Should have counted the message:", n, err)
+	}
+
+	if len(tokens) != 2 || tokens[0] != "" || tokens[1] != "s1" || c.Since != "s2" {
+		t.Error("Should continue from the last sync:", tokens, c.Since)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should have counted dylan's message.")
+	}
+
+	c.AccessToken = "wrong"
+	if _, err := c.Sync(); err == nil {
+		t.Error("Should fail when the homeserver refuses the sync.")
+	}
+}
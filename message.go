@@ -16,8 +16,50 @@ const (
 	Mode
 	Topic
 	Action
+	// Notice is for NOTICE messages that should be told apart from Msg
+	Notice
+	// Nick is for nick changes, the message is the new nick
+	Nick
 )
 
+var kindNames = map[MsgKind]string{
+	Msg:    "message",
+	Part:   "part",
+	Join:   "join",
+	Quit:   "quit",
+	Kick:   "kick",
+	Mode:   "mode",
+	Topic:  "topic",
+	Action: "action",
+	Notice: "notice",
+	Nick:   "nick",
+}
+
+// String returns the name of the message kind.
+func (k MsgKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// ParseMsgKind finds the kind with the name returned by String.
+func ParseMsgKind(name string) (MsgKind, bool) {
+	for k, n := range kindNames {
+		if n == name {
+			return k, true
+		}
+	}
+
+	return 0, false
+}
+
+// isChat checks if the kind is something a user said rather than an event.
+func (k MsgKind) isChat() bool {
+	return k == Msg || k == Action || k == Notice
+}
+
 type Message struct {
 	ID        uint
 	Date      time.Time
@@ -25,4 +67,20 @@ type Message struct {
 	ChannelID uint
 	Message   string
 	Kind      MsgKind
+
+	// features are those of the channel while the message is counted.
+	features Features
+
+	// split and splitWords are the tokens of the message while it's
+	// counted, see tokens.
+	split      *Tokens
+	splitWords *Tokens
+}
+
+// kept is a copy of the message for the counters that keep it, the message
+// being counted is reused for the next one.
+func (m *Message) kept() Message {
+	k := *m
+	k.split, k.splitWords = nil, nil
+	return k
 }
@@ -0,0 +1,175 @@
+package stats
+
+import "time"
+
+// defaultDedupWindow is how long messages are remembered by default to skip
+// them when they're added twice.
+const defaultDedupWindow = 24 * time.Hour
+
+// FNV-1a, hashed by hand so the messages added don't allocate a hash.
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// MessageSet remembers the recent messages of a network by a hash of their
+// kind, channel, nick, second and text, so messages without msgids that are
+// played back or imported twice are only counted once. Counts keeps how
+// many times a message was said in its second when it's more than once.
+//
+// It has its limits:
+//   - Messages are told apart to the second. A late message is taken for
+//     those said the same second in the same channel by the same nick with
+//     the same text, as many times as they were said, only its repeats past
+//     those are counted. The late messages are matched one second after
+//     the other, a second played back twice in a row is taken for one with
+//     twice the repeats.
+//   - Messages older than the window, measured from the newest message, are
+//     forgotten: history played back from further than the window is
+//     counted again.
+type MessageSet struct {
+	Hashes map[uint64]int64
+	Counts map[uint64]uint
+	Newest int64
+
+	pruneAt int
+	// runs counts the late messages of the second runSec as they're played
+	// back, to match them with the messages already said.
+	runSec int64
+	runs   map[uint64]uint
+}
+
+// add remembers the message, it returns false if it was already known.
+// Messages in order are always new: only those older than the network's last
+// activity can be played back, it's those that are looked up.
+func (m *MessageSet) add(raw *RawMessage, late bool, window time.Duration) bool {
+	if m.Hashes == nil {
+		m.Hashes = make(map[uint64]int64)
+	}
+
+	sec := raw.Date.Unix()
+	h := hashMessage(raw, sec)
+	if _, ok := m.Hashes[h]; ok {
+		if late && m.played(h, sec) <= m.count(h) {
+			return false
+		}
+		if m.Counts == nil {
+			m.Counts = make(map[uint64]uint)
+		}
+		m.Counts[h] = m.count(h) + 1
+	} else if late {
+		m.played(h, sec)
+	}
+
+	m.Hashes[h] = sec
+	if sec > m.Newest {
+		m.Newest = sec
+	}
+
+	if len(m.Hashes) >= m.pruneAt {
+		m.prune(window)
+	}
+	return true
+}
+
+// count is how many times a message known was said.
+func (m *MessageSet) count(h uint64) uint {
+	if n, ok := m.Counts[h]; ok {
+		return n
+	}
+	return 1
+}
+
+// played counts a late message among those of its second played back so far,
+// returning how many times it was.
+func (m *MessageSet) played(h uint64, sec int64) uint {
+	if m.runs == nil {
+		m.runs = make(map[uint64]uint)
+	}
+	if sec != m.runSec {
+		m.runSec = sec
+		for h := range m.runs {
+			delete(m.runs, h)
+		}
+	}
+	m.runs[h]++
+	return m.runs[h]
+}
+
+// prune forgets the messages older than the window, and waits for the set to
+// double in size before pruning again.
+func (m *MessageSet) prune(window time.Duration) {
+	oldest := m.Newest - int64(window/time.Second)
+	for h, sec := range m.Hashes {
+		if sec < oldest {
+			delete(m.Hashes, h)
+			delete(m.Counts, h)
+		}
+	}
+
+	m.pruneAt = 2 * len(m.Hashes)
+	if m.pruneAt < minMsgIDPrune {
+		m.pruneAt = minMsgIDPrune
+	}
+}
+
+// hashMessage hashes who said what where at the second. The channel and nick
+// are case insensitive and the nick is the hostmask's, logs often have no
+// user and host.
+func hashMessage(raw *RawMessage, sec int64) uint64 {
+	nick := raw.Hostmask
+	for i := 0; i < len(nick); i++ {
+		if nick[i] == '!' || nick[i] == '@' {
+			nick = nick[:i]
+			break
+		}
+	}
+
+	h := uint64(fnvOffset)
+	h = hashByte(h, byte(raw.Kind))
+	h = hashLower(h, raw.Channel)
+	h = hashLower(h, nick)
+	for i := 0; i < 8; i++ {
+		h = hashByte(h, byte(sec>>(8*i)))
+	}
+	for i := 0; i < len(raw.Message); i++ {
+		h = hashByte(h, raw.Message[i])
+	}
+	return h
+}
+
+// hashLower hashes the text in lower case and a separator after it.
+func hashLower(h uint64, text string) uint64 {
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		h = hashByte(h, c)
+	}
+	return hashByte(h, 0)
+}
+
+func hashByte(h uint64, c byte) uint64 {
+	return (h ^ uint64(c)) * fnvPrime
+}
+
+// dedupWindow returns the dedup window, or its default.
+func (o Options) dedupWindow() time.Duration {
+	if o.DedupWindow == 0 {
+		return defaultDedupWindow
+	}
+	return o.DedupWindow
+}
+
+// duplicate checks if the message was already added to n, its network, when
+// it's added again later on. Messages with msgids are told apart by them, see
+// AddMessageID, they're remembered for those added again without.
+func (s *Stats) duplicate(n *Network, raw *RawMessage) bool {
+	if s.opts.DedupWindow < 0 {
+		return false
+	}
+
+	late := len(raw.MsgID) == 0 && raw.Date.Before(n.LastActive)
+	return !n.Recent.add(raw, late, s.opts.dedupWindow())
+}
@@ -0,0 +1,144 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMessageSet(t *testing.T) {
+	t.Parallel()
+
+	var m MessageSet
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	raw := RawMessage{Kind: Msg, Channel: channel, Hostmask: hostmask, Date: day, Message: "hello"}
+
+	if !m.add(&raw, false, time.Hour) {
+		t.Error("Should add new messages.")
+	}
+	if !m.add(&raw, false, time.Hour) {
+		t.Error("Should add repeats in order, they're said again.")
+	}
+	if m.add(&raw, true, time.Hour) {
+		t.Error("Should skip repeats played back late.")
+	}
+
+	same := raw
+	same.Channel, same.Hostmask = "#TEST", "PHISH"
+	same.Date = day.Add(999 * time.Millisecond)
+	if m.add(&same, true, time.Hour) {
+		t.Error("Should skip repeats within the second, by case insensitive nick and channel.")
+	}
+	if !m.add(&raw, true, time.Hour) {
+		t.Error("Should add late repeats past those said.")
+	}
+
+	for _, other := range []RawMessage{
+		{Kind: Action, Channel: channel, Hostmask: hostmask, Date: day, Message: "hello"},
+		{Kind: Msg, Channel: "#other", Hostmask: hostmask, Date: day, Message: "hello"},
+		{Kind: Msg, Channel: channel, Hostmask: "other", Date: day, Message: "hello"},
+		{Kind: Msg, Channel: channel, Hostmask: hostmask, Date: day.Add(time.Second), Message: "hello"},
+		{Kind: Msg, Channel: channel, Hostmask: hostmask, Date: day, Message: "Hello"},
+	} {
+		if !m.add(&other, true, time.Hour) {
+			t.Error("Should tell other messages apart:", other)
+		}
+	}
+
+	// the limit of the window
+	for i := 0; i < minMsgIDPrune; i++ {
+		late := RawMessage{Kind: Msg, Date: day.Add(2 * time.Hour), Message: fmt.Sprint(i)}
+		m.add(&late, false, time.Hour)
+	}
+	if !m.add(&raw, true, time.Hour) {
+		t.Error("Should have forgotten messages older than the window.")
+	}
+	if _, ok := m.Counts[hashMessage(&raw, day.Unix())]; ok {
+		t.Error("Should forget the counts of the messages forgotten.")
+	}
+}
+
+func TestMessageSet_repeats(t *testing.T) {
+	t.Parallel()
+
+	var m MessageSet
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	lol := RawMessage{Kind: Msg, Channel: channel, Hostmask: hostmask, Date: day, Message: "lol"}
+	hi := RawMessage{Kind: Msg, Channel: channel, Hostmask: hostmask, Date: day, Message: "hi"}
+
+	next := lol
+	next.Date = day.Add(time.Second)
+
+	// a log imported late for the first time, repeats within the second
+	// interleaved with other lines
+	log := []RawMessage{lol, hi, lol, lol, next}
+	for _, raw := range log {
+		if !m.add(&raw, true, time.Hour) {
+			t.Error("Should count the repeats of a first import:", raw.Message)
+		}
+	}
+
+	// imported again, twice
+	for i := 0; i < 2; i++ {
+		for _, raw := range log {
+			if m.add(&raw, true, time.Hour) {
+				t.Error("Should skip the repeats imported again:", raw.Message)
+			}
+		}
+	}
+
+	// the limit of the second: a late line that's the same as one said in
+	// its second is taken for it
+	other := lol
+	other.Date = day.Add(500 * time.Millisecond)
+	if m.add(&other, true, time.Hour) {
+		t.Error("Should take late lines for those said in the same second.")
+	}
+	other.Date = day.Add(2 * time.Second)
+	if !m.add(&other, true, time.Hour) {
+		t.Error("Should tell lines said another second apart.")
+	}
+}
+
+func TestStats_duplicate(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	s.AddMessage(Msg, network, channel, hostmask, date, "lol")
+	s.AddMessage(Msg, network, channel, hostmask, date, "lol")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(time.Minute), "later")
+
+	// the log of the same lines imported again
+	s.AddMessage(Msg, network, channel, "phish", date, "lol")
+	s.AddMessage(Msg, network, channel, hostmask, date, "lol")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Minute), "earlier")
+
+	if !s.AddMessageID("m1", Msg, network, channel, hostmask, date, "later") {
+		t.Error("Should tell messages with msgids apart by them.")
+	}
+
+	if u := s.GetUser(network, nick); u.Lines != 5 {
+		t.Error("Should count the lines imported again once:", u.Lines)
+	}
+
+	// an older log imported for the first time, then again
+	for i := 0; i < 2; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Hour), "lol")
+		s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Hour), "lol")
+		s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Hour+time.Second), "same")
+	}
+	if u := s.GetUser(network, nick); u.Lines != 8 {
+		t.Error("Should count the repeats of a first import once:", u.Lines)
+	}
+
+	s = newStats()
+	s.SetOptions(Options{DedupWindow: -1})
+	s.AddMessage(Msg, network, channel, hostmask, date, "lol")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(time.Minute), "later")
+	s.AddMessage(Msg, network, channel, hostmask, date, "lol")
+	if u := s.GetUser(network, nick); u.Lines != 3 || len(s.GetNetwork(network).Recent.Hashes) != 0 {
+		t.Error("Should not skip repeats without a window:", u.Lines)
+	}
+}
@@ -0,0 +1,72 @@
+package stats
+
+import "time"
+
+// MessageSink receives every message counted by the stats, for example to
+// keep the full history searchable while the stats keep the aggregates. Sink
+// is called with the stats locked, it must not block nor call the methods of
+// the stats.
+type MessageSink interface {
+	Sink(m SinkMessage)
+}
+
+// MessageSinks passes every message to each of the sinks in turn.
+type MessageSinks []MessageSink
+
+// Sink passes the message to each sink.
+func (ms MessageSinks) Sink(m SinkMessage) {
+	for _, s := range ms {
+		s.Sink(m)
+	}
+}
+
+// SinkMessage is a counted message along with the names it was sent under.
+type SinkMessage struct {
+	ID       uint
+	Kind     MsgKind
+	Network  string
+	Channel  string
+	Nick     string
+	Hostmask string
+	Date     time.Time
+	Message  string
+	// Tags are the tags processors set on the message.
+	Tags map[string]string
+	// ChannelStats is the channel the message was counted in, nil for
+	// messages without a channel. It may only be read during Sink.
+	ChannelStats *Channel
+	// Location is the timezone the channel is read in, see
+	// Options.Location.
+	Location *time.Location
+}
+
+// sinkMessage passes a counted message to the sink, if there is one.
+func (s *Stats) sinkMessage(n *Network, c *Channel, u *User, m *Message, pm ProcessedMessage) {
+	if s.opts.Sink == nil {
+		return
+	}
+
+	sm := SinkMessage{
+		ID:       m.ID,
+		Kind:     m.Kind,
+		Network:  n.Name,
+		Nick:     u.Nick,
+		Hostmask: pm.Hostmask,
+		Date:     m.Date,
+		Message:  m.Message,
+		Tags:     pm.Tags,
+		Location: s.opts.location(n.Name, ""),
+	}
+	if c != nil {
+		sm.Channel = c.Name
+		sm.ChannelStats = c
+		sm.Location = s.opts.location(n.Name, c.Name)
+	}
+	if s.opts.AggregateOnly {
+		sm.Message = ""
+	}
+
+	s.shared.Lock()
+	s.opts.Sink.Sink(sm)
+	s.shared.Unlock()
+}
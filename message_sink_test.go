@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+type sinkRecorder []SinkMessage
+
+func (r *sinkRecorder) Sink(m SinkMessage) {
+	*r = append(*r, m)
+}
+
+func TestStats_Sink(t *testing.T) {
+	t.Parallel()
+
+	var r sinkRecorder
+	s := newStats()
+	s.SetOptions(Options{Sink: &r})
+
+	date := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, date, "hello")
+	s.AddMessage(Quit, network, "", hostmask, date.Add(time.Minute), "bye")
+
+	if len(r) != 2 {
+		t.Fatal("Should have sunk both messages:", r)
+	}
+
+	m := r[0]
+	if m.ID == 0 || m.Kind != Msg || m.Network != network || m.Channel != channel ||
+		m.Nick != nick || m.Hostmask != hostmask || !m.Date.Equal(date) || m.Message != "hello" {
+		t.Error("Wrong message:", m)
+	}
+
+	if r[1].Kind != Quit || r[1].Channel != "" {
+		t.Error("Should sink messages without a channel:", r[1])
+	}
+}
+
+func TestMessageSinks(t *testing.T) {
+	t.Parallel()
+
+	var a, b sinkRecorder
+	MessageSinks{&a, &b}.Sink(SinkMessage{Message: "hello"})
+
+	if len(a) != 1 || len(b) != 1 {
+		t.Error("Should pass the message to every sink.")
+	}
+}
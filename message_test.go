@@ -0,0 +1,27 @@
+package stats
+
+import "testing"
+
+func TestMsgKind_String(t *testing.T) {
+	t.Parallel()
+
+	if Action.String() != "action" {
+		t.Error("Should return the name of the kind.")
+	}
+
+	if MsgKind(100).String() != "unknown" {
+		t.Error("Should handle unknown kinds.")
+	}
+}
+
+func TestParseMsgKind(t *testing.T) {
+	t.Parallel()
+
+	if k, ok := ParseMsgKind("action"); !ok || k != Action {
+		t.Error("Should find the kind by name.")
+	}
+
+	if _, ok := ParseMsgKind("shout"); ok {
+		t.Error("Should reject unknown names.")
+	}
+}
@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"expvar"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics are how the stats are doing rather than what they counted, so
+// operators can spot when adding messages or saving gets slower.
+type Metrics struct {
+	// Messages is how many messages were added since the stats were
+	// created or loaded, MessagesPerSecond how many a second over the
+	// last minute.
+	Messages          uint64  `json:"messages"`
+	MessagesPerSecond float64 `json:"messages_per_second"`
+	// LockWait is the time spent in all waiting for the stats to be locked,
+	// to add messages or to read them.
+	LockWait time.Duration `json:"lock_wait_ns"`
+
+	// Saves and SaveErrors count the saves written and failed.
+	Saves      uint64 `json:"saves"`
+	SaveErrors uint64 `json:"save_errors"`
+	// SaveDuration is how long the last save took, SnapshotDuration how
+	// much of it the stats were locked for, to clone them.
+	SaveDuration     time.Duration `json:"save_duration_ns"`
+	SnapshotDuration time.Duration `json:"snapshot_duration_ns"`
+	// DBSize is the size of data.db as the last save wrote it.
+	DBSize int64 `json:"db_size"`
+
+	// ClampedDates and RejectedDates count the messages dated out of
+	// bounds, see Options.MaxFutureSkew and NotBefore.
+	ClampedDates  uint64 `json:"clamped_dates"`
+	RejectedDates uint64 `json:"rejected_dates"`
+}
+
+// rateWindow is how many seconds MessagesPerSecond is averaged over.
+const rateWindow = 60
+
+// metrics are updated as messages are added, without locking.
+type metrics struct {
+	messages atomic.Uint64
+	lockWait atomic.Int64
+
+	saves            atomic.Uint64
+	saveErrors       atomic.Uint64
+	saveDuration     atomic.Int64
+	snapshotDuration atomic.Int64
+	dbSize           atomic.Int64
+
+	clampedDates  atomic.Uint64
+	rejectedDates atomic.Uint64
+
+	// rate are the messages added each second, the one being counted and
+	// those of the window before it
+	rate [rateWindow + 1]rateSecond
+}
+
+type rateSecond struct {
+	second atomic.Int64
+	n      atomic.Uint64
+}
+
+// added counts a message added at the time.
+func (m *metrics) added(now time.Time) {
+	m.messages.Add(1)
+
+	sec := now.Unix()
+	r := &m.rate[sec%int64(len(m.rate))]
+	if old := r.second.Load(); old != sec && r.second.CompareAndSwap(old, sec) {
+		// a message added meanwhile may be lost, it's an estimate
+		r.n.Store(0)
+	}
+	r.n.Add(1)
+}
+
+// waited adds the time since start to the time spent waiting for locks.
+func (m *metrics) waited(start time.Time) {
+	m.lockWait.Add(int64(time.Since(start)))
+}
+
+// saved records a save that took d, of which snapshot with the stats locked.
+func (m *metrics) saved(d, snapshot time.Duration, size int64, err error) {
+	if err != nil {
+		m.saveErrors.Add(1)
+		return
+	}
+
+	m.saves.Add(1)
+	m.saveDuration.Store(int64(d))
+	m.snapshotDuration.Store(int64(snapshot))
+	m.dbSize.Store(size)
+}
+
+// perSecond averages the messages added over the complete seconds of the
+// window before now.
+func (m *metrics) perSecond(now time.Time) float64 {
+	sec := now.Unix()
+
+	var n uint64
+	for i := range m.rate {
+		r := &m.rate[i]
+		if s := r.second.Load(); s < sec && s >= sec-rateWindow {
+			n += r.n.Load()
+		}
+	}
+	return float64(n) / rateWindow
+}
+
+// Metrics returns how the stats are doing, see Metrics.
+func (s *Stats) Metrics() Metrics {
+	m := &s.metrics
+	return Metrics{
+		Messages:          m.messages.Load(),
+		MessagesPerSecond: m.perSecond(time.Now()),
+		LockWait:          time.Duration(m.lockWait.Load()),
+		Saves:             m.saves.Load(),
+		SaveErrors:        m.saveErrors.Load(),
+		SaveDuration:      time.Duration(m.saveDuration.Load()),
+		SnapshotDuration:  time.Duration(m.snapshotDuration.Load()),
+		DBSize:            m.dbSize.Load(),
+		ClampedDates:      m.clampedDates.Load(),
+		RejectedDates:     m.rejectedDates.Load(),
+	}
+}
+
+// PublishExpvar publishes the metrics as the expvar with the name, served as
+// json on /debug/vars along with the runtime's by the expvar package. Like
+// expvar.Publish it panics if the name is already taken.
+func (s *Stats) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return s.Metrics()
+	}))
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+)
+
+func TestStats_Metrics(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+	s.AddBatch([]BatchMessage{
+		{Kind: Msg, Network: network, Channel: channel, Hostmask: hostmask, Date: time.Now(), Message: "more foo"},
+		{Kind: Msg, Network: network, Channel: channel, Hostmask: hostmask, Date: time.Now(), Message: "and foo"},
+	})
+
+	o := &fakeFileOpener{&bytes.Buffer{}}
+	fileOpener = o
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	if err := s.Save(); err != nil {
+		t.Fatal("Should save the stats:", err)
+	}
+
+	m := s.Metrics()
+	if m.Messages != 3 {
+		t.Error("Should count the messages added:", m.Messages)
+	}
+	if m.Saves != 1 || m.SaveErrors != 0 {
+		t.Error("Should count the saves:", m.Saves, m.SaveErrors)
+	}
+	if m.DBSize != int64(o.Len()) {
+		t.Error("Should keep the size of data.db:", m.DBSize, o.Len())
+	}
+	if m.SaveDuration <= 0 || m.SnapshotDuration > m.SaveDuration {
+		t.Error("Should time the save and the snapshot within it:", m.SaveDuration, m.SnapshotDuration)
+	}
+	if m.LockWait <= 0 {
+		t.Error("Should time waiting for the locks:", m.LockWait)
+	}
+}
+
+func TestMetrics_perSecond(t *testing.T) {
+	t.Parallel()
+
+	var m metrics
+	now := time.Unix(1000000, 500)
+
+	add := func(ago time.Duration, n int) {
+		for i := 0; i < n; i++ {
+			m.added(now.Add(-ago))
+		}
+	}
+	add(time.Second, 60)
+	add(30*time.Second, 30)
+	add(rateWindow*time.Second, 30)
+	// the second still being counted and those before the window
+	add(0, 100)
+	add((rateWindow+1)*time.Second, 100)
+
+	if r := m.perSecond(now); r != 2 {
+		t.Error("Should average the last minute:", r)
+	}
+	if r := m.perSecond(now.Add(time.Hour)); r != 0 {
+		t.Error("Should forget what's older than the window:", r)
+	}
+	if n := m.messages.Load(); n != 320 {
+		t.Error("Should count every message:", n)
+	}
+
+	// seconds reused after going around the window start over
+	later := now.Add((rateWindow + 1) * time.Second)
+	m.added(later.Add(-time.Second))
+	if r := m.perSecond(later); r != 1.0/rateWindow {
+		t.Error("Should reset reused seconds:", r)
+	}
+}
+
+func TestStats_PublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+	s.PublishExpvar("stats_test")
+
+	v := expvar.Get("stats_test")
+	if v == nil {
+		t.Fatal("Should publish the metrics.")
+	}
+
+	var m Metrics
+	if err := json.Unmarshal([]byte(v.String()), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.Messages != 1 {
+		t.Error("Should serve the current metrics:", m)
+	}
+}
@@ -0,0 +1,94 @@
+package stats
+
+import "time"
+
+const (
+	// defaultMsgIDRetention is how long msgids are remembered by default.
+	defaultMsgIDRetention = 7 * 24 * time.Hour
+	// minMsgIDPrune is the fewest msgids remembered before pruning.
+	minMsgIDPrune = 1024
+)
+
+// MsgIDSet remembers the IRCv3 msgids of the recent messages of a network, so
+// that messages a bouncer plays back again are only counted once. Msgids
+// older than the retention, measured from the newest message, are forgotten.
+type MsgIDSet struct {
+	IDs    map[string]time.Time
+	Newest time.Time
+
+	pruneAt int
+}
+
+// add remembers the msgid, it returns false if the msgid was already known.
+func (m *MsgIDSet) add(id string, date time.Time, retention time.Duration) bool {
+	if m.IDs == nil {
+		m.IDs = make(map[string]time.Time)
+	}
+	if _, ok := m.IDs[id]; ok {
+		return false
+	}
+
+	m.IDs[id] = date
+	if date.After(m.Newest) {
+		m.Newest = date
+	}
+
+	if len(m.IDs) >= m.pruneAt {
+		m.prune(retention)
+	}
+	return true
+}
+
+// prune forgets the msgids older than the retention, and waits for the set
+// to double in size before pruning again.
+func (m *MsgIDSet) prune(retention time.Duration) {
+	oldest := m.Newest.Add(-retention)
+	for id, date := range m.IDs {
+		if date.Before(oldest) {
+			delete(m.IDs, id)
+		}
+	}
+
+	m.pruneAt = 2 * len(m.IDs)
+	if m.pruneAt < minMsgIDPrune {
+		m.pruneAt = minMsgIDPrune
+	}
+}
+
+// msgIDRetention returns the msgid retention, or its default.
+func (o Options) msgIDRetention() time.Duration {
+	if o.MsgIDRetention == 0 {
+		return defaultMsgIDRetention
+	}
+	return o.MsgIDRetention
+}
+
+// AddMessageID adds a message carrying an IRCv3 msgid. Messages whose msgid
+// was already added to the network are skipped, as are their repeats when a
+// bouncer plays back history. It reports whether the message was added.
+func (s *Stats) AddMessageID(msgid string, kind MsgKind, network, channel, hostmask string, date time.Time, message string) bool {
+	raw := RawMessage{
+		MsgID:    msgid,
+		Kind:     kind,
+		Network:  network,
+		Channel:  channel,
+		Hostmask: hostmask,
+		Date:     date,
+		Message:  message,
+	}
+	n := s.lockRaw(&raw)
+	defer s.unlockNetwork(n)
+
+	return s.addMessageID(n, raw)
+}
+
+// addMessageID adds a prepared message to n, the network it's counted on,
+// unless its msgid was already added.
+func (s *Stats) addMessageID(n *Network, raw RawMessage) bool {
+	if len(raw.MsgID) > 0 && !n.MsgIDs.add(raw.MsgID, raw.Date, s.opts.msgIDRetention()) {
+		return false
+	}
+
+	s.addRawMessage(n, raw)
+	return true
+}
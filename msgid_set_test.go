@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMsgIDSet(t *testing.T) {
+	t.Parallel()
+
+	var m MsgIDSet
+	day := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	if !m.add("a", day, time.Hour) || m.add("a", day, time.Hour) {
+		t.Error("Should only add a msgid once.")
+	}
+
+	for i := 0; i < minMsgIDPrune; i++ {
+		m.add(fmt.Sprint(i), day.Add(2*time.Hour), time.Hour)
+	}
+
+	if _, ok := m.IDs["a"]; ok {
+		t.Error("Should have forgotten msgids older than the retention.")
+	}
+	if len(m.IDs) != minMsgIDPrune {
+		t.Error("Should keep recent msgids, Got:", len(m.IDs))
+	}
+}
+
+func TestStats_AddMessageID(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	date := time.Now()
+
+	if !s.AddMessageID("abc", Msg, network, channel, hostmask, date, "hello") {
+		t.Error("Should add the message.")
+	}
+	if s.AddMessageID("abc", Msg, network, channel, hostmask, date, "hello") {
+		t.Error("Should skip the played back message.")
+	}
+	if !s.AddMessageID("", Msg, network, channel, hostmask, date, "no msgid") {
+		t.Error("Should add messages without a msgid.")
+	}
+
+	if u := s.GetUser(network, nick); u.Lines != 2 {
+		t.Error("Should have counted two lines, Got:", u.Lines)
+	}
+}
+
+func TestStats_AddMessageLate(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{FloodThreshold: 2})
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	s.AddMessage(Msg, network, channel, hostmask, date, "now")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Hour), "played back")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Hour+time.Second), "played back")
+
+	u := s.GetUser(network, nick)
+	if u.Lines != 3 {
+		t.Error("Should count played back lines.")
+	}
+	if !u.LastSeen.Equal(date) || !s.GetChannel(network, channel).LastActive.Equal(date) {
+		t.Error("Played back messages should not move LastSeen backwards.")
+	}
+	if u.Bursts.Floods != 0 {
+		t.Error("Played back messages should not count as bursts.")
+	}
+	if u.Quotes.Last.Message != "now" {
+		t.Error("Played back messages should not be the last quote:", u.Quotes.Last.Message)
+	}
+	if u.MaxConsecutive != 0 {
+		t.Error("Played back messages should not make streaks:", u.MaxConsecutive)
+	}
+
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(-time.Minute), "s/now/then/")
+	if u.SelfCorrections != 0 {
+		t.Error("Played back corrections should not correct newer messages.")
+	}
+}
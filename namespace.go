@@ -0,0 +1,147 @@
+package stats
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrBadOwner is the error of the owner keys ValidOwner refuses.
+var ErrBadOwner = errors.New("Bad owner")
+
+// ValidOwner checks an owner key: 1 to 64 lowercase letters, digits, - or _,
+// so that it's safe as a file name and in a url.
+func ValidOwner(owner string) error {
+	if len(owner) == 0 || len(owner) > 64 {
+		return fmt.Errorf("%w: %q must be 1 to 64 characters", ErrBadOwner, owner)
+	}
+	for _, r := range owner {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' {
+			return fmt.Errorf("%w: %q may only have a-z, 0-9, - and _", ErrBadOwner, owner)
+		}
+	}
+	return nil
+}
+
+// Namespaces keeps the stats of unrelated owners apart in one process, eg. a
+// hosted bot serving many communities. Each owner has their own Stats, saved
+// to <Dir>/<owner>.db with their own options, nothing is shared between
+// them.
+type Namespaces struct {
+	// Dir is the directory the databases of the owners are in.
+	Dir string
+	// Options are the options of an owner's stats, set when they're opened.
+	// The stats are opened with the defaults when nil.
+	Options func(owner string) Options
+
+	mut   sync.RWMutex
+	stats map[string]*Stats
+}
+
+// NewNamespaces keeps the databases of the owners in dir.
+func NewNamespaces(dir string) *Namespaces {
+	return &Namespaces{Dir: dir, stats: make(map[string]*Stats)}
+}
+
+// Path is the database of an owner.
+func (ns *Namespaces) Path(owner string) string {
+	return filepath.Join(ns.Dir, owner+".db")
+}
+
+// Open returns the stats of an owner, loading their database the first time
+// they're asked for, or starting them empty when there's none yet. The
+// directory is made when it doesn't exist.
+func (ns *Namespaces) Open(owner string) (*Stats, error) {
+	if err := ValidOwner(owner); err != nil {
+		return nil, err
+	}
+	if s, ok := ns.Lookup(owner); ok {
+		return s, nil
+	}
+
+	ns.mut.Lock()
+	defer ns.mut.Unlock()
+
+	if s, ok := ns.stats[owner]; ok {
+		return s, nil
+	}
+	if err := ns.mkdir(); err != nil {
+		return nil, err
+	}
+	s, err := OpenStats(ns.Path(owner))
+	if err != nil {
+		return nil, err
+	}
+	if ns.Options != nil {
+		s.SetOptions(ns.Options(owner))
+	}
+	ns.stats[owner] = s
+	return s, nil
+}
+
+// Lookup returns the stats of an owner if they were opened.
+func (ns *Namespaces) Lookup(owner string) (*Stats, bool) {
+	ns.mut.RLock()
+	defer ns.mut.RUnlock()
+
+	s, ok := ns.stats[owner]
+	return s, ok
+}
+
+// Owners are the owners whose stats were opened, sorted.
+func (ns *Namespaces) Owners() []string {
+	ns.mut.RLock()
+	defer ns.mut.RUnlock()
+
+	owners := make([]string, 0, len(ns.stats))
+	for owner := range ns.stats {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	return owners
+}
+
+// each calls fn with the stats of every owner opened, returning the errors of
+// those it failed for with their owner.
+func (ns *Namespaces) each(fn func(*Stats) error) error {
+	var errs []error
+	for _, owner := range ns.Owners() {
+		s, _ := ns.Lookup(owner)
+		if err := fn(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", owner, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mkdir makes the directory of the databases, and its parents, unless they
+// exist.
+func (ns *Namespaces) mkdir() error {
+	return os.MkdirAll(ns.Dir, 0755)
+}
+
+// Save saves the stats of every owner, see Stats.Save. The directory is made
+// again when it was removed.
+func (ns *Namespaces) Save() error {
+	if err := ns.mkdir(); err != nil {
+		return err
+	}
+	return ns.each((*Stats).Save)
+}
+
+// SaveNow saves the stats of every owner right away, see Stats.SaveNow.
+func (ns *Namespaces) SaveNow() error {
+	if err := ns.mkdir(); err != nil {
+		return err
+	}
+	return ns.each((*Stats).SaveNow)
+}
+
+// Prune prunes the stats of every owner by their own retention, see
+// Stats.Prune.
+func (ns *Namespaces) Prune() error {
+	return ns.each((*Stats).Prune)
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidOwner(t *testing.T) {
+	t.Parallel()
+
+	for _, owner := range []string{"acme", "go-nuts", "team_2"} {
+		if err := ValidOwner(owner); err != nil {
+			t.Error("Should accept the owner", owner, err)
+		}
+	}
+	for _, owner := range []string{"", "Acme", "../etc", "a b", string(make([]byte, 65))} {
+		if err := ValidOwner(owner); !errors.Is(err, ErrBadOwner) {
+			t.Errorf("Should refuse the owner %q, got %v", owner, err)
+		}
+	}
+}
+
+func TestNamespaces(t *testing.T) {
+	fileOpener = osFileOpener{}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	dir, err := ioutil.TempDir("", "namespaces")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// the directory of the namespaces is made once they're used
+	dir = filepath.Join(dir, "var", "owners")
+
+	ns := NewNamespaces(dir)
+	ns.Options = func(owner string) Options {
+		if owner == "acme" {
+			return Options{Retention: time.Hour}
+		}
+		return Options{}
+	}
+
+	acme, err := ns.Open("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ns.Open("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := ns.Open("acme"); again != acme {
+		t.Error("Should open the stats of an owner once.")
+	}
+	if _, err := ns.Open("../acme"); !errors.Is(err, ErrBadOwner) {
+		t.Error("Should refuse bad owners, got", err)
+	}
+	if acme.opts.Retention != time.Hour || other.opts.Retention != 0 {
+		t.Error("Should give each owner their own options.")
+	}
+	if acme.Path() != ns.Path("acme") {
+		t.Error("Should save the owner's stats to their own database:", acme.Path())
+	}
+
+	acme.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello there")
+	if other.GetUser(network, nick) != nil {
+		t.Error("Should keep the owners apart.")
+	}
+
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatal("Should make the directory of the namespaces:", err)
+	}
+	os.RemoveAll(dir)
+	if err := ns.SaveNow(); err != nil {
+		t.Fatal("Should make the directory again to save:", err)
+	}
+	if got := ns.Owners(); len(got) != 2 || got[0] != "acme" || got[1] != "other" {
+		t.Error("Should list the owners opened:", got)
+	}
+
+	reopened := NewNamespaces(dir)
+	s, err := reopened.Open("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 1 {
+		t.Error("Should load the owner's stats from their database:", u)
+	}
+	if s, _ := reopened.Open("other"); s.GetUser(network, nick) != nil {
+		t.Error("Should load the other owner's stats apart.")
+	}
+	if _, ok := reopened.Lookup("nobody"); ok {
+		t.Error("Should not look up owners who weren't opened.")
+	}
+}
@@ -3,27 +3,44 @@ package stats
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Network struct {
 	HourlyChart
-	Quotes quotes
-	URLCounter
-	WordCounter
+	Quotes      quotes
+	URLCounter  `json:"URLCounter"`
+	WordCounter `json:"WordCounter"`
 
 	ID         uint
 	Name       string
 	ChannelIDs []uint
 	UserIDs    []uint
+	// MessageRanges are the ids of the messages, MessageCount is how many
+	// messages were counted, MessageRanges forgets the ones pruned.
+	MessageRanges IDRanges
+	MessageCount  uint
+	// MessageIDs are the ids of stats saved before MessageRanges, they're
+	// moved to it when loaded.
 	MessageIDs []uint
 
 	LastActive time.Time
+	MsgIDs     MsgIDSet
+	Recent     MessageSet
 
 	channels map[string]*Channel
 	users    map[string]*User
+	// strings are shared by the counters of the network, see interner.
+	strings interner
+	// message and its tokens are reused for every message added to the
+	// network instead of allocated, see Stats.addMessage.
+	message           Message
+	split, splitWords Tokens
 
 	stats *Stats
+	// mut is the network's share of the stats' lock, see lockNetwork.
+	mut sync.RWMutex
 }
 
 func (n *Network) addChannel(c *Channel) {
@@ -33,42 +50,63 @@ func (n *Network) addChannel(c *Channel) {
 
 func (n *Network) addUser(u *User) {
 	n.UserIDs = append(n.UserIDs, u.ID)
-	n.users[strings.ToLower(u.Nick)] = u
+	n.users[n.fold(u.Nick)] = u
 }
 
 func (n *Network) addMessage(m *Message) {
-	n.MessageIDs = append(n.MessageIDs, m.ID)
+	if n.keepsID(m) {
+		n.MessageRanges.add(m.ID)
+	}
+	n.MessageCount++
 
 	if m.Kind == Msg {
 		n.HourlyChart.addMessage(m)
 		n.Quotes.addMessage(m)
 		n.URLCounter.addMessage(m)
-		n.WordCounter.addMessage(m)
+		if !m.features.NoWords {
+			n.WordCounter.addTokens(n.wordTokens(m))
+		}
+	}
+
+	if m.Date.After(n.LastActive) {
+		n.LastActive = m.Date
 	}
+}
 
-	n.LastActive = m.Date
+// keepsID checks if the id of the message is kept, it isn't when only
+// aggregates are or when its channel keeps no messages.
+func (n *Network) keepsID(m *Message) bool {
+	return (n.stats == nil || !n.stats.opts.AggregateOnly) && !m.features.NoStorage
 }
 
-// buildIndexes builds the internal maps that relate data
+// buildIndexes builds the internal maps that relate data, the channels are
+// found by their names in lower case and the users by their folded nicks.
 func (n *Network) buildIndexes(s *Stats) {
-	n.channels = make(map[string]*Channel)
-	n.users = make(map[string]*User)
+	n.channels = make(map[string]*Channel, len(n.ChannelIDs))
 	n.stats = s
 
 	for _, cID := range n.ChannelIDs {
 		c := n.stats.Channels[cID]
 
-		n.channels[c.Name] = c
+		n.channels[strings.ToLower(c.Name)] = c
 	}
 
+	n.indexUsers()
+}
+
+// indexUsers indexes the users by their nicks folded as the network folds
+// them, see Options.CaseMapping.
+func (n *Network) indexUsers() {
+	n.users = make(map[string]*User, len(n.UserIDs))
+
 	for _, uID := range n.UserIDs {
 		u := n.stats.Users[uID]
 
-		n.users[u.Nick] = u
+		n.users[n.fold(u.Nick)] = u
 	}
 }
 
 // String returns a the name of the channel and some basic stats.
 func (n *Network) String() string {
-	return fmt.Sprintf("Network: %s, Channels: %d, Messages: %d", n.Name, len(n.ChannelIDs), len(n.MessageIDs))
+	return fmt.Sprintf("Network: %s, Channels: %d, Messages: %d", n.Name, len(n.ChannelIDs), n.MessageCount)
 }
@@ -8,7 +8,7 @@ import (
 func TestNetwork_buildIndexes(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Msg, network, channel, nick, time.Now(), "some foo")
 
 	n := s.Networks[1]
@@ -43,9 +43,10 @@ func TestNetwork_Stringer(t *testing.T) {
 	t.Parallel()
 
 	n := &Network{
-		Name:       "foo",
-		ChannelIDs: []uint{1, 2, 3},
-		MessageIDs: []uint{1, 2, 3},
+		Name:          "foo",
+		ChannelIDs:    []uint{1, 2, 3},
+		MessageRanges: idRanges([]uint{1, 2, 3}),
+		MessageCount:  3,
 	}
 
 	if n.String() != "Network: foo, Channels: 3, Messages: 3" {
@@ -4,34 +4,39 @@ import "strings"
 
 type NickReferences map[string]uint
 
-var punctuationReplacer = strings.NewReplacer(
-	".", "",
-	",", "",
-	":", "",
-	";", "",
-	"!", "",
-	"?", "",
-	"@", "",
+// The prefixes nick lists show before the nicks of owners, admins, ops,
+// halfops and voices, and the punctuation said around a nick. Neither is part
+// of a nick on irc.
+const (
+	nickPrefixes = "~&@%+"
+	nickOpeners  = "(\"'"
+	nickSuffixes = ".,:;!?'\")"
 )
 
+// mentionedNick trims a word down to the nick it may mention, eg. "@bob",
+// "(bob)" and "bob?" mention bob.
+func mentionedNick(word string) string {
+	word = strings.TrimLeft(word, nickOpeners+nickPrefixes)
+	return strings.TrimRight(word, nickSuffixes)
+}
+
+// addMessage counts the users of the channel mentioned by the message, by
+// their nicks folded as the network folds them.
 func (r NickReferences) addMessage(network *Network, channel *Channel, message *Message) {
 	if channel == nil {
 		return
 	}
 
-	msg := punctuationReplacer.Replace(message.Message)
-	msg = strings.ToLower(msg)
-	words := strings.Fields(msg)
-
-	for _, word := range words {
-		var u *User
-		var ok bool
-		if u, ok = network.users[word]; !ok {
+	t := message.tokens()
+	for _, word := range t.Fields {
+		nick := network.fold(mentionedNick(word))
+		u, ok := network.users[nick]
+		if !ok {
 			continue
 		}
 
 		if _, ok = channel.UserIDs[u.ID]; ok {
-			r[word]++
+			r[t.intern(nick)]++
 		}
 	}
 }
@@ -7,7 +7,7 @@ import (
 
 func TestNickReferencs(t *testing.T) {
 	t.Parallel()
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Msg, network, channel, "Scott", time.Now(), "Hey fish")
 	s.AddMessage(Msg, network, channel, "fish", time.Now(), "Scott: Don't even talk to me...")
 
@@ -30,3 +30,31 @@ func TestNickReferencs(t *testing.T) {
 	}
 
 }
+
+func TestNickReferences_prefixes(t *testing.T) {
+	t.Parallel()
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "Scott", time.Now(), "hi")
+	s.AddMessage(Msg, network, channel, "fish", time.Now(), "@Scott: hey. scott? +scott, (scott) ~SCOTT!")
+
+	c := s.GetChannel(network, channel)
+	if len(c.NickReferences) != 1 || c.NickReferences["scott"] != 5 {
+		t.Error("Should count every mention of scott as one nick, counted", c.NickReferences)
+	}
+}
+
+func TestNickReferences_caseMapping(t *testing.T) {
+	t.Parallel()
+	s := newStats()
+	s.SetOptions(Options{CaseMappings: map[string]CaseMapping{network: RFC1459Case}})
+	s.AddMessage(Msg, network, channel, "[Scott]", time.Now(), "hi")
+	s.AddMessage(Msg, network, channel, "fish", time.Now(), "{scott}: hey, [SCOTT]")
+
+	c := s.GetChannel(network, channel)
+	if len(c.NickReferences) != 1 || c.NickReferences["{scott}"] != 2 {
+		t.Error("Should count the mentions of [Scott] as one nick, counted", c.NickReferences)
+	}
+	if u := s.GetUser(network, "{SCOTT}"); u == nil || u.Nick != "[Scott]" {
+		t.Error("Should find [Scott] by the nick folded.")
+	}
+}
@@ -0,0 +1,256 @@
+package stats
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Options holds optional, pluggable behaviour for a Stats instance. Options
+// are not persisted with the database and must be set again after loading.
+type Options struct {
+	// LanguageDetector, when set, is used to guess the language of every
+	// message so that channels and users can report their language mix.
+	LanguageDetector LanguageDetector
+
+	// SentimentAnalyzer, when set, scores the mood of every message so that
+	// channels and users can report mood trends over time.
+	SentimentAnalyzer SentimentAnalyzer
+
+	// ApplyCorrections rewrites a user's previous message when they correct
+	// it with s/old/new/, so quotes reflect what they meant to say.
+	ApplyCorrections bool
+
+	// Words controls which tokens are counted by the word and letter
+	// counters.
+	Words WordOptions
+
+	// TopK, when set, makes new word and URL counters keep approximate
+	// counts in a fixed size count-min sketch instead of an exact count of
+	// every token ever seen.
+	TopK SketchOptions
+
+	// FloodThreshold is how many messages a user must send within a minute
+	// to be flooding (default 10).
+	FloodThreshold uint
+
+	// SilenceThreshold is how long a channel must be quiet before the next
+	// message counts as starting a conversation (default 30 minutes).
+	SilenceThreshold time.Duration
+
+	// ReactionWords replaces the default list of short acknowledgments
+	// ("this", "+1", "^", "lol", ...) credited as reactions.
+	ReactionWords []string
+
+	// MsgIDRetention is how long the msgids of messages are remembered to
+	// skip messages played back twice (default 7 days).
+	MsgIDRetention time.Duration
+	// DedupWindow is how long messages are remembered to skip those added
+	// twice without a msgid, when logs are imported again or history is
+	// played back (default 1 day). Messages aren't remembered when negative.
+	// See MessageSet for how they're told apart.
+	DedupWindow time.Duration
+
+	// Sink, when set, is given every message as it is counted.
+	Sink MessageSink
+
+	// Processors are run in order on every message added, see Processor.
+	Processors []Processor
+
+	// RawStore, when set, keeps every message added so the stats can be
+	// rebuilt from them, see Rebuild.
+	RawStore RawStore
+	// Retention is how long the messages are kept, in the raw store and by
+	// id, once Prune is called. The counters keep counting them. Messages
+	// are kept forever when zero.
+	Retention time.Duration
+	// Retentions keep the urls, quotes and aggregates for retentions of
+	// their own, the Retention by default.
+	Retentions Retentions
+	// AggregateOnly counts the messages without keeping them, for stats
+	// without logs: the raw store isn't given any, sinks are given them
+	// without their text and no message ids are kept. Only the quotes keep
+	// the text of a few messages.
+	AggregateOnly bool
+
+	// MinSaveInterval is the least time between two saves, the saves asked
+	// for sooner are coalesced into one at the end of the interval. Every
+	// save is written at once when zero.
+	MinSaveInterval time.Duration
+	// Canonical saves data.db as json with sorted keys instead of gob, so
+	// two saves of the same stats are byte for byte the same, for golden
+	// files, diffs and deduplicating backups. It's bigger and slower, and
+	// every channel is loaded to be saved. Both are loaded either way.
+	Canonical bool
+
+	// Location is the timezone the hours and days of the stats are read
+	// in, so the busiest hour and record day are the community's rather
+	// than the server's. They're counted in UTC and turned into the
+	// timezone when read, see HourlyChart.In and DayCounter.In, so every
+	// channel of a user counts the same. UTC when nil.
+	Location *time.Location
+	// Locations are the timezones of the networks and channels that aren't
+	// in Location, by the name of the network or by the names of the
+	// network and channel apart by a space, eg. "freenode #go-nuts".
+	Locations map[string]*time.Location
+
+	// Charset is the charset the text of messages that isn't UTF-8 is
+	// decoded from, such as the Latin1 of old logs. Text that isn't UTF-8
+	// is replaced with U+FFFD when nil.
+	Charset *Charset
+	// Charsets are the charsets of the networks that aren't Charset, by
+	// the name of the network.
+	Charsets map[string]*Charset
+
+	// MaxMessageLength is the longest text of a message kept, in bytes,
+	// longer ones are cut (default 4096). The text isn't cut when
+	// negative. Line breaks and control characters are dropped from the
+	// text either way, but for its formatting.
+	MaxMessageLength int
+
+	// MaxFutureSkew is how far past now a message may be dated, those of
+	// bouncers with broken clocks or sent across an NTP jump dated later
+	// are rejected, or dated now with ClampDates. Dates aren't checked
+	// against now when zero.
+	MaxFutureSkew time.Duration
+	// NotBefore is the earliest date of a message, such as when the
+	// network or channel started. Older ones, often dated 1970 by a clock
+	// that was reset, are rejected, or dated NotBefore with ClampDates.
+	// Messages without a date are left alone.
+	NotBefore time.Time
+	// ClampDates dates the messages out of bounds to the bound they
+	// cross instead of rejecting them. Metrics counts both.
+	ClampDates bool
+
+	// CaseMapping is how nicks are told apart, as the CASEMAPPING a server
+	// announces, so "[bob]" and "{bob}" are one user on rfc1459 networks.
+	// Every letter's case is folded by default.
+	CaseMapping CaseMapping
+	// CaseMappings are the case mappings of the networks that aren't
+	// CaseMapping, by the name of the network.
+	CaseMappings map[string]CaseMapping
+
+	// Features turns off the counters and storage of every channel that
+	// cost the most, see Features. Everything is on by default.
+	Features Features
+	// ChannelFeatures are the features of the networks and channels that
+	// aren't Features, keyed as Locations are.
+	ChannelFeatures map[string]Features
+
+	// ChannelAliases count the messages of channels in others, so a
+	// channel renamed or bridged to another, on the same network or not,
+	// is one channel: "oftc #chan-bridge": "libera #chan". They're keyed
+	// as Locations are and name a network and channel apart by a space,
+	// aliases aren't followed further. The messages of an alias are
+	// counted in its channel by the users of its network, and broken down
+	// by where they were said in Channel.Sources. The raw store keeps
+	// where they were said.
+	ChannelAliases map[string]string
+	// aliased are the channels ChannelAliases alias, in lower case.
+	aliased map[string]bool
+
+	// Logger is where the stats log the messages they turn away, channels
+	// that fail to load, saves and their durations, slog.Default() when
+	// nil. What's found wrong while data.db is loaded is logged to
+	// slog.Default(), it's loaded before the options are set.
+	Logger *slog.Logger
+
+	// Clock is the time the stats prune by, bound the dates of the
+	// messages by and count today's lines of the channels by, the
+	// system's when nil. Set a ManualClock to test the stats at a time, or
+	// a ReplayClock to replay logs as if they were said now.
+	Clock Clock
+
+	// PseudonymKey is the secret the pseudonyms of the nicks of the
+	// channels with Pseudonyms are made with, see Features. The same key
+	// gives the same pseudonyms, keep it to link the users across rebuilds
+	// and keep it secret, the nicks could be guessed from their pseudonyms
+	// with it. Without one the messages of the channels with Pseudonyms
+	// aren't counted at all.
+	PseudonymKey []byte
+}
+
+// SetOptions replaces the options used when adding messages.
+func (s *Stats) SetOptions(o Options) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.opts = o
+	if len(o.Locations) > 0 {
+		s.opts.Locations = make(map[string]*time.Location, len(o.Locations))
+		for k, loc := range o.Locations {
+			s.opts.Locations[strings.ToLower(k)] = loc
+		}
+	}
+	if len(o.Charsets) > 0 {
+		s.opts.Charsets = make(map[string]*Charset, len(o.Charsets))
+		for k, c := range o.Charsets {
+			s.opts.Charsets[strings.ToLower(k)] = c
+		}
+	}
+	if len(o.CaseMappings) > 0 {
+		s.opts.CaseMappings = make(map[string]CaseMapping, len(o.CaseMappings))
+		for k, m := range o.CaseMappings {
+			s.opts.CaseMappings[strings.ToLower(k)] = m
+		}
+	}
+
+	if len(o.ChannelAliases) > 0 {
+		s.opts.ChannelAliases = make(map[string]string, len(o.ChannelAliases))
+		for k, target := range o.ChannelAliases {
+			s.opts.ChannelAliases[strings.ToLower(k)] = target
+		}
+	}
+	s.opts.aliased = aliasSources(o.ChannelAliases)
+
+	if len(o.ChannelFeatures) > 0 {
+		s.opts.ChannelFeatures = make(map[string]Features, len(o.ChannelFeatures))
+		for k, f := range o.ChannelFeatures {
+			s.opts.ChannelFeatures[strings.ToLower(k)] = f
+		}
+	}
+
+	s.opts.checkPseudonymKey()
+
+	// the users are indexed by their nicks folded as their networks fold them
+	for _, n := range s.Networks {
+		n.indexUsers()
+	}
+}
+
+// location is the timezone the stats of a channel are read in, the
+// network's when the channel is empty.
+func (o *Options) location(network, channel string) *time.Location {
+	if len(o.Locations) > 0 {
+		if len(channel) > 0 {
+			if loc, ok := o.Locations[importKey(network, channel)]; ok {
+				return loc
+			}
+		}
+		if loc, ok := o.Locations[strings.ToLower(network)]; ok {
+			return loc
+		}
+	}
+
+	if o.Location == nil {
+		return time.UTC
+	}
+	return o.Location
+}
+
+// logger is where the stats log, see Options.Logger.
+func (o *Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.Default()
+	}
+	return o.Logger
+}
+
+// Location is the timezone the hours and days of a channel are to be read
+// in, see Options.Location. The network's when the channel is empty.
+func (s *Stats) Location(network, channel string) *time.Location {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.opts.location(network, channel)
+}
@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats_Location(t *testing.T) {
+	t.Parallel()
+
+	toronto := time.FixedZone("EST", -5*60*60)
+	tokyo := time.FixedZone("JST", 9*60*60)
+
+	s := newStats()
+	s.SetOptions(Options{
+		Location:  toronto,
+		Locations: map[string]*time.Location{network + " " + "#Tokyo": tokyo},
+	})
+
+	// 2am on the 2nd in utc is 9pm on the 1st in toronto, 11am in tokyo
+	date := time.Date(2014, 1, 2, 2, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, date, "hello")
+	s.AddMessage(Msg, network, "#tokyo", hostmask, date, "hello")
+
+	c := s.GetChannel(network, channel)
+	if c.HourlyChart[2] != 1 || c.Days.Day("2014-01-02") != 1 {
+		t.Error("Should count the hour and day in utc:", c.HourlyChart, c.Days.Lines)
+	}
+	loc := s.Location(network, channel)
+	if c.HourlyChart.In(loc, date)[21] != 1 || c.Days.DayIn("2014-01-01", loc) != 1 {
+		t.Error("Should read the hour and day in the stats' timezone:", c.HourlyChart.In(loc, date), c.Days.In(loc))
+	}
+	c = s.GetChannel(network, "#tokyo")
+	loc = s.Location(network, "#tokyo")
+	if c.HourlyChart.In(loc, date)[11] != 1 || c.Days.DayIn("2014-01-02", loc) != 1 {
+		t.Error("Should read the hour and day in the channel's timezone:", c.HourlyChart.In(loc, date), c.Days.In(loc))
+	}
+
+	// the user's hours are the same in both channels
+	u := s.GetUser(network, nick)
+	if u.HourlyChart[2] != 2 || u.ChannelUsers["#tokyo"].HourlyChart[2] != 1 {
+		t.Error("Should count the hours of users in utc:", u.HourlyChart)
+	}
+	if d := u.Quotes.Last.Date; !d.Equal(date) || d.Location() != time.UTC {
+		t.Error("Should keep the date in utc:", d)
+	}
+
+	if loc := s.Location(network, "#TOKYO"); loc != tokyo {
+		t.Error("Should find the channel's timezone:", loc)
+	}
+	if loc := s.Location(network, ""); loc != toronto {
+		t.Error("Should default to the stats' timezone:", loc)
+	}
+	if loc := newStats().Location(network, channel); loc != time.UTC {
+		t.Error("Should default to utc:", loc)
+	}
+}
+
+func TestStats_Logger(t *testing.T) {
+	var b bytes.Buffer
+	fileOpener = &fakeFileOpener{&b}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	var logged bytes.Buffer
+	s := newStats()
+	s.SetOptions(Options{
+		MaxFutureSkew: time.Minute,
+		Logger:        slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now().Add(time.Hour), "from the future")
+	if err := s.SaveNow(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`msg="Rejected a message out of bounds" network=test_network channel=#test`, `msg="Saved data.db" duration=`} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("Should log %s, logged %s", want, logged.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package stats
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// optOutKey is the key of a nick of the network in OptOuts.
+func (o *Options) optOutKey(network, nick string) string {
+	return strings.ToLower(network) + " " + o.caseMapping(network).Fold(nick)
+}
+
+// OptOut opts the nick on the network out of the stats: the messages they
+// say from now on are neither counted nor stored, and what was counted of
+// them is forgotten, see ForgetUser. The lines a bridge relays for them aren't
+// counted either, the raw store keeps them as the bridge's. The opt outs are
+// saved with the stats. Nothing tells that the nick is its user's, forgetting
+// is for the operators, users opting themselves out by their nicks should
+// StopCounting.
+func (s *Stats) OptOut(network, nick string) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.stopCounting(network, nick)
+	for _, forgotten := range []string{nick, s.opts.Pseudonym(network, nick)} {
+		if err := s.forgetUser(network, forgotten); err != nil && !errors.Is(err, ErrUnknownUser) {
+			return err
+		}
+	}
+	return nil
+}
+
+// StopCounting opts the nick on the network out of the stats as OptOut does,
+// but keeps what was counted of them: whoever takes a nick may stop it being
+// counted, and its user may OptIn again, but they can't erase its history.
+func (s *Stats) StopCounting(network, nick string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.stopCounting(network, nick)
+}
+
+// stopCounting is StopCounting with the stats locked for writing.
+func (s *Stats) stopCounting(network, nick string) {
+	if s.OptOuts == nil {
+		s.OptOuts = make(map[string]time.Time)
+	}
+	s.OptOuts[s.opts.optOutKey(network, nick)] = s.opts.now()
+}
+
+// OptIn counts the messages of a nick that opted out again from now on, what
+// was forgotten of them stays forgotten. It's false if they hadn't opted out.
+func (s *Stats) OptIn(network, nick string) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	key := s.opts.optOutKey(network, nick)
+	if _, ok := s.OptOuts[key]; !ok {
+		return false
+	}
+	delete(s.OptOuts, key)
+	return true
+}
+
+// OptedOut checks if the nick on the network opted out.
+func (s *Stats) OptedOut(network, nick string) bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	_, ok := s.OptOuts[s.opts.optOutKey(network, nick)]
+	return ok
+}
+
+// OptedOutUsers are the network and nick of the users who opted out, apart
+// by a space and sorted.
+func (s *Stats) OptedOutUsers() []string {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	users := make([]string, 0, len(s.OptOuts))
+	for key := range s.OptOuts {
+		users = append(users, key)
+	}
+	sort.Strings(users)
+	return users
+}
+
+// optedOut checks if the sender of a message opted out, on the network the
+// channel is aliased to. It's called with the stats locked.
+func (s *Stats) optedOut(network, channel, hostmask string) bool {
+	if len(s.OptOuts) == 0 {
+		return false
+	}
+	h, ok := ParseHostmask(hostmask)
+	if !ok {
+		return false
+	}
+	_, ok = s.OptOuts[s.opts.optOutKey(s.opts.aliasNetwork(network, channel), h.Nick)]
+	return ok
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats_OptOut(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "optout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.SetOptions(Options{RawStore: store, Processors: []Processor{NewBridgeProcessor("relay")}})
+
+	now := time.Now()
+	s.AddMessage(Msg, network, channel, hostmask, now, "hello there")
+	s.AddMessage(Msg, network, channel, "alice!a@zqz.ca", now, "hi phish")
+
+	if s.OptedOut(network, nick) {
+		t.Error("Should not have opted out yet.")
+	}
+	if err = s.OptOut(network, "PHISH"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.OptedOut(network, nick) || s.GetUser(network, nick) != nil {
+		t.Error("Should opt the user out and forget them.")
+	}
+	if got := s.OptedOutUsers(); len(got) != 1 || got[0] != network+" "+nick {
+		t.Error("Should list the users who opted out:", got)
+	}
+
+	s.AddMessage(Msg, network, channel, hostmask, now.Add(time.Second), "not counted")
+	s.AddMessage(Msg, network, channel, "relay!r@zqz.ca", now.Add(2*time.Second), "<phish> relayed")
+	if s.GetUser(network, nick) != nil {
+		t.Error("Should not count the messages of the user who opted out.")
+	}
+	if c := s.GetChannel(network, channel); c.MessageCount != 2 {
+		t.Error("Should keep the totals without the messages after the opt out:", c.MessageCount)
+	}
+
+	var kept []string
+	if err = store.Replay(func(raw RawMessage) { kept = append(kept, raw.Message) }); err != nil {
+		t.Fatal(err)
+	}
+	// the relayed line is the bridge's until it's processed
+	if len(kept) != 2 || kept[0] != "hi phish" || kept[1] != "<phish> relayed" {
+		t.Error("Should store none of the messages of the user who opted out:", kept)
+	}
+
+	if !s.OptIn(network, nick) || s.OptIn(network, nick) {
+		t.Error("Should opt the user in once.")
+	}
+	s.AddMessage(Msg, network, channel, hostmask, now.Add(3*time.Second), "back")
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 1 {
+		t.Error("Should count the user again once they opt in:", u)
+	}
+}
+
+func TestStats_StopCounting(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello there")
+	s.StopCounting(network, "PHISH")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "not counted")
+
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 1 || !s.OptedOut(network, nick) {
+		t.Error("Should stop counting the user and keep what was counted:", u)
+	}
+}
+
+func TestStats_OptOutSaved(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	if err := s.OptOut(network, nick); err != nil {
+		t.Fatal(err)
+	}
+	if clone := s.clone(); len(clone.OptOuts) != 1 {
+		t.Error("Should save the opt outs:", clone.OptOuts)
+	}
+}
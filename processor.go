@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Processor is custom logic run on every message added to the stats. Process
+// is called before the message is counted and may change it, tag it or drop
+// it by returning false, but not move it to another network: the network it
+// was added to is put back. Counted is called once it has been counted. Both
+// are called with the stats locked, one message at a time, and must not call
+// the methods of the stats.
+type Processor interface {
+	Process(m *ProcessedMessage) bool
+	Counted(m ProcessedMessage)
+}
+
+// ProcessedMessage is a message on its way through the processors.
+type ProcessedMessage struct {
+	Kind     MsgKind
+	Network  string
+	Channel  string
+	Hostmask string
+	Date     time.Time
+	Message  string
+
+	// Tags are free for processors to note things about the message for
+	// the processors after them and the sink. They are not kept in the
+	// stats.
+	Tags map[string]string
+}
+
+// Tag sets a tag on the message.
+func (m *ProcessedMessage) Tag(key, value string) {
+	if m.Tags == nil {
+		m.Tags = make(map[string]string)
+	}
+	m.Tags[key] = value
+}
+
+// ProcessorFunc is a Processor that only runs before messages are counted.
+type ProcessorFunc func(m *ProcessedMessage) bool
+
+// Process calls the function.
+func (f ProcessorFunc) Process(m *ProcessedMessage) bool {
+	return f(m)
+}
+
+// Counted does nothing.
+func (f ProcessorFunc) Counted(m ProcessedMessage) {}
+
+// ProcessorFactory creates a processor from its arguments, as given after
+// the = in a processor spec.
+type ProcessorFactory func(args string) (Processor, error)
+
+var (
+	processorsMut sync.RWMutex
+	processors    = make(map[string]ProcessorFactory)
+)
+
+// RegisterProcessor makes a processor available by name. Registering a name
+// twice replaces the first processor.
+func RegisterProcessor(name string, f ProcessorFactory) {
+	processorsMut.Lock()
+	defer processorsMut.Unlock()
+
+	processors[name] = f
+}
+
+// ProcessorNames lists the registered processors in alphabetical order.
+func ProcessorNames() []string {
+	processorsMut.RLock()
+	defer processorsMut.RUnlock()
+
+	names := make([]string, 0, len(processors))
+	for name := range processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewProcessor creates a registered processor from a spec of its name and
+// arguments, such as bridge=discordbot,matrixbot.
+func NewProcessor(spec string) (Processor, error) {
+	name, args := spec, ""
+	if i := strings.IndexByte(spec, '='); i >= 0 {
+		name, args = spec[:i], spec[i+1:]
+	}
+
+	processorsMut.RLock()
+	f, ok := processors[name]
+	processorsMut.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown processor %s, the processors are: %s", name, strings.Join(ProcessorNames(), ", "))
+	}
+
+	return f(args)
+}
+
+// process runs the processors on a message, returning false if one of them
+// dropped it.
+func (s *Stats) process(m *ProcessedMessage) bool {
+	if len(s.opts.Processors) == 0 {
+		return true
+	}
+
+	s.shared.Lock()
+	defer s.shared.Unlock()
+
+	for _, p := range s.opts.Processors {
+		if !p.Process(m) {
+			return false
+		}
+	}
+	return true
+}
+
+// counted tells the processors a message was counted.
+func (s *Stats) counted(m ProcessedMessage) {
+	if len(s.opts.Processors) == 0 {
+		return
+	}
+
+	s.shared.Lock()
+	defer s.shared.Unlock()
+
+	for _, p := range s.opts.Processors {
+		p.Counted(m)
+	}
+}
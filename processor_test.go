@@ -0,0 +1,140 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingProcessor struct {
+	counted []ProcessedMessage
+}
+
+func (c *countingProcessor) Process(m *ProcessedMessage) bool {
+	m.Tag("seen", "yes")
+	return true
+}
+
+func (c *countingProcessor) Counted(m ProcessedMessage) {
+	c.counted = append(c.counted, m)
+}
+
+func TestStats_Processors(t *testing.T) {
+	t.Parallel()
+
+	drop := ProcessorFunc(func(m *ProcessedMessage) bool {
+		return !strings.HasPrefix(m.Message, "!")
+	})
+	upper := ProcessorFunc(func(m *ProcessedMessage) bool {
+		m.Message = strings.ToUpper(m.Message)
+		return true
+	})
+	counter := &countingProcessor{}
+	var r sinkRecorder
+
+	s := newStats()
+	s.SetOptions(Options{Processors: []Processor{drop, upper, counter}, Sink: &r})
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "!stats")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello")
+
+	u := s.GetUser(network, nick)
+	if u == nil || u.Lines != 1 {
+		t.Fatal("Should have dropped the command:", u)
+	}
+	if u.Quotes.Last.Message != "HELLO" {
+		t.Error("Should count the changed message:", u.Quotes.Last.Message)
+	}
+
+	if len(counter.counted) != 1 || counter.counted[0].Message != "HELLO" {
+		t.Error("Should tell processors what was counted:", counter.counted)
+	}
+	if len(r) != 1 || r[0].Tags["seen"] != "yes" {
+		t.Error("Should pass the tags to the sink:", r)
+	}
+}
+
+func TestStats_ProcessorsKeepTheNetwork(t *testing.T) {
+	t.Parallel()
+
+	move := ProcessorFunc(func(m *ProcessedMessage) bool {
+		m.Network = "elsewhere"
+		return true
+	})
+	s := newStats()
+	s.SetOptions(Options{Processors: []Processor{move}})
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello")
+	if c := s.GetChannel(network, channel); c == nil || c.MessageCount != 1 {
+		t.Error("Should count the message on the network it was added to:", c)
+	}
+	if s.GetNetwork("elsewhere") != nil {
+		t.Error("Should not move the message to another network.")
+	}
+}
+
+func TestNewProcessor(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewProcessor("nope"); err == nil || !strings.Contains(err.Error(), "bridge") {
+		t.Error("Should list the processors for unknown ones:", err)
+	}
+	if _, err := NewProcessor("bridge"); err == nil {
+		t.Error("The bridge processor should need bots.")
+	}
+
+	p, err := NewProcessor("bridge=discordbot, matrixbot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := p.(*BridgeProcessor); len(b.Bots) != 2 {
+		t.Error("Should have both bots:", b.Bots)
+	}
+}
+
+func TestBridgeProcessor(t *testing.T) {
+	t.Parallel()
+
+	b := NewBridgeProcessor("DiscordBot")
+	tests := []struct {
+		hostmask, message string
+		want              ProcessedMessage
+	}{
+		{"discordbot!b@zqz.ca", "<al\u200bice> hi there", ProcessedMessage{Kind: Msg, Hostmask: "alice", Message: "hi there"}},
+		{"discordbot", "[bob] hello", ProcessedMessage{Kind: Msg, Hostmask: "bob", Message: "hello"}},
+		{"discordbot", "* carol waves", ProcessedMessage{Kind: Action, Hostmask: "carol", Message: "waves"}},
+		{"discordbot", "Connected to discord", ProcessedMessage{Kind: Msg, Hostmask: "discordbot", Message: "Connected to discord"}},
+		{"dylan", "<alice> not a bridge", ProcessedMessage{Kind: Msg, Hostmask: "dylan", Message: "<alice> not a bridge"}},
+		{"discordbot", "<\x0304dave\x0f> \x02loud\x02", ProcessedMessage{Kind: Msg, Hostmask: "dave", Message: "loud"}},
+	}
+
+	for _, test := range tests {
+		m := ProcessedMessage{Kind: Msg, Hostmask: test.hostmask, Message: test.message}
+		if !b.Process(&m) {
+			t.Error("Should never drop messages.")
+		}
+		if m.Kind != test.want.Kind || m.Hostmask != test.want.Hostmask || m.Message != test.want.Message {
+			t.Errorf("%q: Expected: %v, Got: %v", test.message, test.want, m)
+		}
+		if bridged := m.Hostmask != test.hostmask; bridged != (m.Tags["bridge"] == "discordbot") {
+			t.Errorf("%q: Wrong tags: %v", test.message, m.Tags)
+		}
+	}
+}
+
+func TestBridgeProcessor_Network(t *testing.T) {
+	t.Parallel()
+
+	b := NewBridgeProcessor("relay")
+	b.Network = network
+
+	m := ProcessedMessage{Kind: Msg, Network: "othernet", Hostmask: "relay", Message: "<alice> hi"}
+	if b.Process(&m); m.Hostmask != "relay" {
+		t.Error("Should leave the relays on other networks alone.")
+	}
+
+	m.Network = network
+	if b.Process(&m); m.Hostmask != "alice" || m.Message != "hi" {
+		t.Error("Should unwrap the relay:", m)
+	}
+}
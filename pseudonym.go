@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// pseudonymPrefix starts the pseudonyms.
+const pseudonymPrefix = "anon-"
+
+// Pseudonym is the pseudonym a nick of the network is counted by in the
+// channels with Pseudonyms, the HMAC-SHA256 of the folded nick by the
+// PseudonymKey. A nick has the same pseudonym in every channel of the
+// network, however it's written, so its messages are still counted as the
+// same user's. Nobody without the key can tell whose pseudonym it is. Every
+// nick is made a pseudonym of, those looking like pseudonyms too, so nobody
+// takes another's pseudonym by taking it as their nick.
+func (o *Options) Pseudonym(network, nick string) string {
+	mac := hmac.New(sha256.New, o.PseudonymKey)
+	mac.Write([]byte(strings.ToLower(network)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(o.caseMapping(network).Fold(nick)))
+	return pseudonymPrefix + hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// pseudonymize replaces the nicks of a message of a channel with Pseudonyms
+// by their pseudonyms: its sender's, whose user and host are dropped, the
+// new nick of a nick change and the target of a kick. The nicks mentioned in
+// the text are kept. The nicks are those of the network the channel is
+// aliased to, see Options.ChannelAliases. The messages pseudonymized already,
+// such as those of the raw store, are left as they are. Without a
+// PseudonymKey the pseudonyms could be made by anyone, the message can't be
+// counted and false is returned.
+func (o *Options) pseudonymize(raw *RawMessage) bool {
+	if raw.Pseudonymized || !o.features(raw.Network, raw.Channel).Pseudonyms {
+		return true
+	}
+	if len(o.PseudonymKey) == 0 {
+		return false
+	}
+	raw.Pseudonymized = true
+
+	network := o.aliasNetwork(raw.Network, raw.Channel)
+	raw.Hostmask = o.pseudonymHostmask(network, raw.Hostmask)
+	switch raw.Kind {
+	case Nick:
+		raw.Message = o.Pseudonym(network, raw.Message)
+	case Kick:
+		target, reason, _ := strings.Cut(raw.Message, " ")
+		raw.Message = strings.TrimSpace(o.Pseudonym(network, target) + " " + reason)
+	}
+	return true
+}
+
+// checkPseudonymKey logs the channels with Pseudonyms that can't be counted
+// without a PseudonymKey.
+func (o *Options) checkPseudonymKey() {
+	if len(o.PseudonymKey) > 0 {
+		return
+	}
+	if o.Features.Pseudonyms {
+		o.logger().Error("Pseudonyms need a PseudonymKey, the messages aren't counted")
+	}
+	for key, f := range o.ChannelFeatures {
+		if f.Pseudonyms {
+			o.logger().Error("Pseudonyms need a PseudonymKey, the messages aren't counted", "channel", key)
+		}
+	}
+}
+
+// pseudonymHostmask is the pseudonym of the nick of a hostmask, servers keep
+// their names.
+func (o *Options) pseudonymHostmask(network, hostmask string) string {
+	h, ok := ParseHostmask(hostmask)
+	if !ok || h.IsServer() {
+		return hostmask
+	}
+	return o.Pseudonym(network, h.Nick)
+}
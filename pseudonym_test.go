@@ -0,0 +1,112 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptions_Pseudonym(t *testing.T) {
+	t.Parallel()
+
+	o := Options{PseudonymKey: []byte("hunter2")}
+	p := o.Pseudonym(network, nick)
+	if !strings.HasPrefix(p, pseudonymPrefix) || len(p) != len(pseudonymPrefix)+16 || strings.Contains(p, nick) {
+		t.Error("Should make a pseudonym of the nick:", p)
+	}
+	if o.Pseudonym(network, "PHISH") != p || o.Pseudonym(strings.ToUpper(network), nick) != p {
+		t.Error("Should give a nick the same pseudonym however it's written.")
+	}
+	if o.Pseudonym("other_network", nick) == p || o.Pseudonym(network, "bob") == p {
+		t.Error("Should give the nicks of each network their own pseudonyms.")
+	}
+	if (&Options{PseudonymKey: []byte("other")}).Pseudonym(network, nick) == p {
+		t.Error("Should make the pseudonyms with the key.")
+	}
+	if o.Pseudonym(network, p) == p {
+		t.Error("Should not take the nicks looking like pseudonyms for pseudonyms.")
+	}
+}
+
+func TestStats_Pseudonyms(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "pseudonyms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.SetOptions(Options{
+		RawStore:        store,
+		PseudonymKey:    []byte("hunter2"),
+		ChannelFeatures: map[string]Features{importKey(network, channel): {Pseudonyms: true}},
+	})
+	p := s.opts.Pseudonym(network, nick)
+
+	now := time.Now()
+	s.AddMessage(Msg, network, channel, hostmask, now, "hello there")
+	s.AddMessage(Msg, network, channel, "PHISH!other@zqz.ca", now.Add(time.Second), "it's me")
+	s.AddMessage(Kick, network, channel, "bob!b@zqz.ca", now.Add(2*time.Second), "phish go away")
+	s.AddMessage(Msg, network, "#open", hostmask, now.Add(3*time.Second), "not hidden")
+
+	if s.GetUser(network, nick) == nil {
+		t.Error("Should count the nick in the channels without pseudonyms.")
+	}
+	u := s.GetUser(network, p)
+	if u == nil || u.Lines != 2 || len(u.Hostmask) > 0 || u.KickCounters.Received != 1 {
+		t.Fatal("Should count the user by their pseudonym:", u)
+	}
+	if c := s.GetChannel(network, channel); len(c.UserIDs) != 2 {
+		t.Error("Should count the kicker by their pseudonym too:", c.UserIDs)
+	}
+
+	err = store.Replay(func(raw RawMessage) {
+		if raw.Channel == channel && (strings.Contains(raw.Hostmask, "zqz.ca") || strings.HasPrefix(raw.Message, nick)) {
+			t.Error("Should keep the pseudonyms in the raw store:", raw)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the raw store is pseudonymized already
+	if err = s.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser(network, p); u == nil || u.Lines != 2 {
+		t.Error("Should count the user by the same pseudonym once rebuilt:", u)
+	}
+
+	// a nick taking the pseudonym of another is made a pseudonym of too
+	s.AddMessage(Msg, network, channel, p+"!evil@zqz.ca", now.Add(4*time.Second), "it's me, phish")
+	if u := s.GetUser(network, p); u.Lines != 2 {
+		t.Error("Should not count the nick of another's pseudonym as theirs:", u.Lines)
+	}
+}
+
+func TestStats_PseudonymsWithoutKey(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{ChannelFeatures: map[string]Features{importKey(network, channel): {Pseudonyms: true}}})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello there")
+	s.AddMessage(Msg, network, "#open", hostmask, time.Now(), "not hidden")
+
+	if c := s.GetChannel(network, channel); c != nil {
+		t.Error("Should not count the channels with pseudonyms without a key:", c)
+	}
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 1 {
+		t.Error("Should count the other channels:", u)
+	}
+}
@@ -4,15 +4,35 @@ import "math/rand"
 
 const randomQuoteProbability = 10
 
+// quotes keep copies of messages, their ID is 0 until a message is quoted.
 type quotes struct {
-	Last   *Message
-	Random *Message
+	Last   Message
+	Random Message
 }
 
+// addMessage quotes the message as the last one unless a later message was
+// already quoted, and sometimes as the random one. Messages of channels
+// without quotes aren't, see Features.
 func (q *quotes) addMessage(m *Message) {
-	q.Last = m
+	if m.features.NoQuotes {
+		return
+	}
+
+	if q.Last.ID == 0 || !m.Date.Before(q.Last.Date) {
+		q.Last = m.kept()
+	}
 
 	if rand.Intn(randomQuoteProbability) == 0 {
-		q.Random = m
+		q.Random = m.kept()
+	}
+}
+
+// correct rewrites the text of the message with the id where it's quoted.
+func (q *quotes) correct(id uint, text string) {
+	if q.Last.ID == id {
+		q.Last.Message = text
+	}
+	if q.Random.ID == id {
+		q.Random.Message = text
 	}
 }
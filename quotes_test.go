@@ -12,21 +12,21 @@ func TestQuotes(t *testing.T) {
 	var q quotes
 	m := &Message{ID: 4}
 
-	if q.Random != nil {
+	if q.Random.ID != 0 {
 		t.Error("Random message should not be set.")
 	}
 
-	if q.Last != nil {
+	if q.Last.ID != 0 {
 		t.Error("Last message should not be set.")
 	}
 
 	q.addMessage(m)
 
-	if q.Random != m {
+	if q.Random.ID != m.ID {
 		t.Error("Random message should be set")
 	}
 
-	if q.Last != m {
+	if q.Last.ID != m.ID {
 		t.Error("Last message should be set")
 	}
 
@@ -34,53 +34,69 @@ func TestQuotes(t *testing.T) {
 
 	q.addMessage(m2)
 
-	if q.Random != m {
+	if q.Random.ID != m.ID {
 		t.Error("Random message should not change")
 	}
 
-	if q.Last != m2 {
+	if q.Last.ID != m2.ID {
 		t.Error("Last message be updated")
 	}
 }
 
 func TestQuotesUpdates(t *testing.T) {
 	rand.Seed(7075) // returns (0,0,0,0) - dont ask
-	s := NewStats()
+	s := newStats()
 	n := s.addNetwork(network)
 	c := s.addChannel(n, channel)
 	u := s.addUser(n, nick)
 	cu := u.addChannelUser(channel)
 
-	if n.Quotes.Last != nil && n.Quotes.Random != nil {
+	if n.Quotes.Last.ID != 0 && n.Quotes.Random.ID != 0 {
 		t.Error("Last message and random message should not be set.")
 	}
-	if c.Quotes.Last != nil && c.Quotes.Random != nil {
+	if c.Quotes.Last.ID != 0 && c.Quotes.Random.ID != 0 {
 		t.Error("Last message and random message should not be set.")
 	}
-	if u.Quotes.Last != nil && u.Quotes.Random != nil {
+	if u.Quotes.Last.ID != 0 && u.Quotes.Random.ID != 0 {
 		t.Error("Last message and random message should not be set.")
 	}
 
 	m := s.addMessage(Msg, n, c, u, cu, time.Now(), "nihao")
 
-	if n.Quotes.Random != m {
+	if n.Quotes.Random.ID != m.ID {
 		t.Error("Random message should be set")
 	}
-	if c.Quotes.Random != m {
+	if c.Quotes.Random.ID != m.ID {
 		t.Error("Random message should be set")
 	}
 
-	if u.Quotes.Random != m {
+	if u.Quotes.Random.ID != m.ID {
 		t.Error("Random message should be set")
 	}
 
-	if n.Quotes.Last != m {
+	if n.Quotes.Last.ID != m.ID {
 		t.Error("Last message should be set")
 	}
-	if c.Quotes.Last != m {
+	if c.Quotes.Last.ID != m.ID {
 		t.Error("Last message should be set")
 	}
-	if u.Quotes.Last != m {
+	if u.Quotes.Last.ID != m.ID {
 		t.Error("Last message should be set")
 	}
 }
+
+func TestQuotes_keptMessages(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "bob", time.Now(), "first")
+	s.AddMessage(Topic, network, channel, "bob", time.Now(), "a topic")
+	s.AddMessage(Msg, network, channel, "aaron", time.Now(), "second")
+
+	if q := s.GetUser(network, "bob").Quotes.Last; q.Message != "first" || q.split != nil {
+		t.Error("Should keep a copy of the messages quoted:", q)
+	}
+	if topics := s.GetChannel(network, channel).Topics; len(topics) != 1 || topics[0].Message != "a topic" {
+		t.Error("Should keep a copy of the topics:", topics)
+	}
+}
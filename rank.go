@@ -0,0 +1,148 @@
+package stats
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrBadCursor is the error of the cursors ParseRankCursor can't read.
+var ErrBadCursor = errors.New("Bad rank cursor")
+
+// Rank is the place of a user in the ranking of a channel.
+type Rank struct {
+	// Position is the place of the user from 1, for the most lines said.
+	Position int    `json:"position"`
+	UserID   uint   `json:"userid"`
+	Nick     string `json:"nick"`
+	Lines    uint   `json:"lines"`
+}
+
+// RankCursor marks a user's place in the ranking of a channel, to go on from
+// it with another RankIterator. The users said the most lines are ranked
+// first, then by nick and id, so the place stays between the same users when
+// others say more in between pages.
+type RankCursor struct {
+	Lines  uint
+	Nick   string
+	UserID uint
+}
+
+// String encodes the cursor, url safe, for ParseRankCursor.
+func (rc RankCursor) String() string {
+	s := strconv.FormatUint(uint64(rc.Lines), 10) + " " + strconv.FormatUint(uint64(rc.UserID), 10) + " " + rc.Nick
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
+
+// ParseRankCursor reads a cursor encoded by RankCursor.String.
+func ParseRankCursor(s string) (RankCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return RankCursor{}, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+
+	parts := strings.SplitN(string(b), " ", 3)
+	if len(parts) != 3 {
+		return RankCursor{}, fmt.Errorf("%w: %q", ErrBadCursor, s)
+	}
+	lines, err := strconv.ParseUint(parts[0], 10, 0)
+	if err != nil {
+		return RankCursor{}, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 0)
+	if err != nil {
+		return RankCursor{}, fmt.Errorf("%w: %v", ErrBadCursor, err)
+	}
+	return RankCursor{Lines: uint(lines), Nick: parts[2], UserID: uint(id)}, nil
+}
+
+// before checks if the cursor ranks before the user.
+func (rc RankCursor) before(r Rank) bool {
+	if rc.Lines != r.Lines {
+		return rc.Lines > r.Lines
+	}
+	if rc.Nick != r.Nick {
+		return rc.Nick < r.Nick
+	}
+	return rc.UserID < r.UserID
+}
+
+// RankIterator walks the users of a channel who said something, from the
+// most lines said, see Channel.RankIterator.
+type RankIterator struct {
+	ranks []Rank
+	next  int
+}
+
+// RankIterator ranks the users of the channel as they are in the read
+// transaction, the iterator may be used once View returns.
+func (c *Channel) RankIterator(tx *ReadTx) *RankIterator {
+	key := strings.ToLower(c.Name)
+	ranks := make([]Rank, 0, len(c.UserIDs))
+	for id := range c.UserIDs {
+		u, ok := tx.Users[id]
+		if !ok {
+			continue
+		}
+		if cu, ok := u.ChannelUsers[key]; ok && cu.Lines > 0 {
+			ranks = append(ranks, Rank{UserID: id, Nick: cu.Nick, Lines: cu.Lines})
+		}
+	}
+
+	sort.Slice(ranks, func(i, j int) bool {
+		return RankCursor{ranks[i].Lines, ranks[i].Nick, ranks[i].UserID}.before(ranks[j])
+	})
+	for i := range ranks {
+		ranks[i].Position = i + 1
+	}
+	return &RankIterator{ranks: ranks}
+}
+
+// Len is how many users are ranked.
+func (it *RankIterator) Len() int {
+	return len(it.ranks)
+}
+
+// Seek moves to a position, Next returns the user ranked there first. The
+// positions start from 1.
+func (it *RankIterator) Seek(position int) {
+	switch {
+	case position < 1:
+		it.next = 0
+	case position > len(it.ranks):
+		it.next = len(it.ranks)
+	default:
+		it.next = position - 1
+	}
+}
+
+// SeekCursor moves past the place of a cursor, Next returns the user ranked
+// after it first.
+func (it *RankIterator) SeekCursor(rc RankCursor) {
+	it.next = sort.Search(len(it.ranks), func(i int) bool {
+		return rc.before(it.ranks[i])
+	})
+}
+
+// Next returns the next user ranked, false once every user was.
+func (it *RankIterator) Next() (Rank, bool) {
+	if it.next >= len(it.ranks) {
+		return Rank{}, false
+	}
+	r := it.ranks[it.next]
+	it.next++
+	return r, true
+}
+
+// Cursor marks the place of the last user Next returned, to go on after it.
+// It's false until Next returns one.
+func (it *RankIterator) Cursor() (RankCursor, bool) {
+	if it.next == 0 {
+		return RankCursor{}, false
+	}
+	r := it.ranks[it.next-1]
+	return RankCursor{Lines: r.Lines, Nick: r.Nick, UserID: r.UserID}, true
+}
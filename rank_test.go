@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// ranked returns the nicks Next returns, up to n of them.
+func ranked(it *RankIterator, n int) []string {
+	var nicks []string
+	for len(nicks) < n {
+		r, ok := it.Next()
+		if !ok {
+			break
+		}
+		nicks = append(nicks, r.Nick)
+	}
+	return nicks
+}
+
+func TestChannel_RankIterator(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	lines := map[string]int{"alice": 3, "bob": 2, "carol": 2, "dave": 1}
+	for nick, n := range lines {
+		for i := 0; i < n; i++ {
+			s.AddMessage(Msg, network, channel, nick, time.Now(), "hi")
+		}
+	}
+	s.AddMessage(Join, network, channel, "lurker", time.Now(), "")
+
+	var it *RankIterator
+	s.View(func(tx *ReadTx) {
+		it = tx.GetChannel(network, channel).RankIterator(tx)
+	})
+	if it.Len() != 4 {
+		t.Error("Should rank the users who said something, ranked", it.Len())
+	}
+
+	first, _ := it.Next()
+	if first.Position != 1 || first.Nick != "alice" || first.Lines != 3 {
+		t.Error("Should rank alice first:", first)
+	}
+	if got := ranked(it, 2); len(got) != 2 || got[0] != "bob" || got[1] != "carol" {
+		t.Error("Should rank the ties by nick:", got)
+	}
+	cursor, ok := it.Cursor()
+	if !ok {
+		t.Fatal("Should mark the place of carol.")
+	}
+
+	it.Seek(4)
+	if r, _ := it.Next(); r.Position != 4 || r.Nick != "dave" {
+		t.Error("Should seek the fourth user:", r)
+	}
+	if _, ok := it.Next(); ok {
+		t.Error("Should stop after the last user.")
+	}
+
+	// bob passes alice, the page after carol is still dave
+	for i := 0; i < 5; i++ {
+		s.AddMessage(Msg, network, channel, "bob", time.Now(), "hi")
+	}
+	parsed, err := ParseRankCursor(cursor.String())
+	if err != nil || parsed != cursor {
+		t.Fatal("Should parse the cursor encoded:", parsed, err)
+	}
+	s.View(func(tx *ReadTx) {
+		it = tx.GetChannel(network, channel).RankIterator(tx)
+	})
+	it.SeekCursor(parsed)
+	if r, _ := it.Next(); r.Nick != "dave" || r.Position != 4 {
+		t.Error("Should go on after carol:", r)
+	}
+
+	it.Seek(0)
+	if got := ranked(it, 2); got[0] != "bob" || got[1] != "alice" {
+		t.Error("Should rank bob first now:", got)
+	}
+}
+
+func TestParseRankCursor(t *testing.T) {
+	t.Parallel()
+
+	rc := RankCursor{Lines: 10, Nick: "we ird|nick", UserID: 3}
+	if got, err := ParseRankCursor(rc.String()); err != nil || got != rc {
+		t.Error("Should parse nicks with spaces and symbols:", got, err)
+	}
+	for _, bad := range []string{"!!", "MTA", "YSBiIGM"} {
+		if _, err := ParseRankCursor(bad); !errors.Is(err, ErrBadCursor) {
+			t.Errorf("Should refuse %q, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// RawMessage is a message as it was added to the stats, before the
+// processors saw it.
+type RawMessage struct {
+	// MsgID is the msgid it was added with, if any.
+	MsgID    string
+	Kind     MsgKind
+	Network  string
+	Channel  string
+	Hostmask string
+	Date     time.Time
+	Message  string
+	// Pseudonymized is set once the nicks were replaced by their
+	// pseudonyms, so they aren't replaced again when it's added again.
+	Pseudonymized bool
+}
+
+// RawStore retains every message added to the stats so they can be counted
+// again from scratch with Rebuild.
+type RawStore interface {
+	// Append keeps a message, it's called with the stats locked.
+	Append(m RawMessage)
+	// Replay calls f with every message kept, in the order they were added.
+	Replay(f func(RawMessage)) error
+}
+
+// RawPruner is a RawStore that can forget old messages, see Prune.
+type RawPruner interface {
+	// PruneRaw drops the messages dated before the time.
+	PruneRaw(before time.Time) error
+}
+
+// RawForgetter is a RawStore that can forget the messages of a user, see
+// ForgetUser.
+type RawForgetter interface {
+	// ForgetRaw drops the messages forget is true for.
+	ForgetRaw(forget func(RawMessage) bool) error
+}
+
+// rawLine is how a raw message is written to a file.
+type rawLine struct {
+	MsgID    string    `json:"msgid,omitempty"`
+	Kind     string    `json:"kind"`
+	Network  string    `json:"network"`
+	Channel  string    `json:"channel,omitempty"`
+	Hostmask string    `json:"hostmask"`
+	Date     time.Time `json:"date"`
+	Message  string    `json:"message,omitempty"`
+	// Pseudonymized is omitted for the messages with their nicks.
+	Pseudonymized bool `json:"pseudonymized,omitempty"`
+}
+
+// FileRawStore keeps raw messages in a file as json lines, one message per
+// line, so they can be compressed and moved to cold storage with ordinary
+// tools.
+type FileRawStore struct {
+	mut  sync.Mutex
+	path string
+	file *os.File
+	w    *bufio.Writer
+	err  error
+}
+
+// OpenFileRawStore opens the store at path, creating it if it doesn't exist.
+// Messages are appended after the ones already in it.
+func OpenFileRawStore(path string) (*FileRawStore, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileRawStore{path: path, file: f, w: bufio.NewWriter(f)}, nil
+}
+
+// Append writes a message to the end of the file. Failed writes are kept for
+// Err, later messages are dropped.
+func (fs *FileRawStore) Append(m RawMessage) {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if fs.err != nil {
+		return
+	}
+
+	b, err := json.Marshal(rawLine{m.MsgID, m.Kind.String(), m.Network, m.Channel, m.Hostmask, m.Date, m.Message, m.Pseudonymized})
+	if err == nil {
+		b = append(b, '\n')
+		_, err = fs.w.Write(b)
+	}
+	fs.err = err
+}
+
+// Replay reads the messages back from the start of the file.
+func (fs *FileRawStore) Replay(f func(RawMessage)) error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if err := fs.w.Flush(); err != nil {
+		return err
+	}
+
+	r := io.NewSectionReader(fs.file, 0, 1<<62)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		var l rawLine
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return fmt.Errorf("Bad raw message on line %d: %v", line, err)
+		}
+		kind, ok := ParseMsgKind(l.Kind)
+		if !ok {
+			return fmt.Errorf("Bad raw message on line %d: unknown kind %s", line, l.Kind)
+		}
+
+		f(RawMessage{l.MsgID, kind, l.Network, l.Channel, l.Hostmask, l.Date, l.Message, l.Pseudonymized})
+	}
+	return scanner.Err()
+}
+
+// PruneRaw rewrites the file without the messages dated before the time.
+func (fs *FileRawStore) PruneRaw(before time.Time) error {
+	return fs.rewrite(func(line []byte) bool {
+		var l struct {
+			Date time.Time `json:"date"`
+		}
+		return json.Unmarshal(line, &l) == nil && l.Date.Before(before)
+	})
+}
+
+// ForgetRaw rewrites the file without the messages forget is true for.
+func (fs *FileRawStore) ForgetRaw(forget func(RawMessage) bool) error {
+	return fs.rewrite(func(line []byte) bool {
+		var l rawLine
+		if json.Unmarshal(line, &l) != nil {
+			return false
+		}
+		kind, ok := ParseMsgKind(l.Kind)
+		return ok && forget(RawMessage{l.MsgID, kind, l.Network, l.Channel, l.Hostmask, l.Date, l.Message, l.Pseudonymized})
+	})
+}
+
+// rewrite rewrites the file without the lines drop is true for, bad lines
+// are kept for Replay to report.
+func (fs *FileRawStore) rewrite(drop func(line []byte) bool) error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if fs.err != nil {
+		return fs.err
+	}
+	if err := fs.w.Flush(); err != nil {
+		return err
+	}
+
+	tmp, err := os.OpenFile(fs.path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	scanner := bufio.NewScanner(io.NewSectionReader(fs.file, 0, 1<<62))
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		if drop(scanner.Bytes()) {
+			continue
+		}
+		w.Write(scanner.Bytes())
+		w.WriteByte('\n')
+	}
+
+	err = scanner.Err()
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fs.path)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	fs.file.Close()
+	fs.file, fs.w = tmp, bufio.NewWriter(tmp)
+	return nil
+}
+
+// Flush writes the buffered messages to the file.
+func (fs *FileRawStore) Flush() error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	if fs.err != nil {
+		return fs.err
+	}
+	return fs.w.Flush()
+}
+
+// Err is the first write that failed.
+func (fs *FileRawStore) Err() error {
+	fs.mut.Lock()
+	defer fs.mut.Unlock()
+
+	return fs.err
+}
+
+// Close flushes and closes the file.
+func (fs *FileRawStore) Close() error {
+	err := fs.Flush()
+	if cerr := fs.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Rebuild throws away every counter and counts the messages of the raw store
+// again from scratch, after a counter was fixed or to enable new options on
+// the history. Processors are run on the messages again, sinks aren't told
+// about them. The ranges of logs imported are kept, the messages pruned from
+// the raw store are lost.
+func (s *Stats) Rebuild() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	store := s.opts.RawStore
+	if store == nil {
+		return errors.New("Can't rebuild the stats without a raw store.")
+	}
+
+	opts := s.opts
+	s.opts.RawStore, s.opts.Sink = nil, nil
+	defer func() { s.opts = opts }()
+
+	old := s.reset()
+	err := store.Replay(func(m RawMessage) {
+		s.addRaw(m)
+	})
+	if err != nil {
+		// leave the stats as they were rather than half rebuilt
+		s.restore(old)
+		return err
+	}
+	return nil
+}
+
+// counts are the counted parts of the stats, swapped out while rebuilding.
+type counts struct {
+	channels      map[uint]*Channel
+	networks      map[uint]*Network
+	users         map[uint]*User
+	networkByName map[string]*Network
+	ids           [4]uint
+	marks         []IDMark
+	prunedBefore  uint
+}
+
+// reset empties the stats, returning what was counted before.
+func (s *Stats) reset() counts {
+	old := counts{
+		s.Channels, s.Networks, s.Users, s.networkByName,
+		[4]uint{s.NetworkIDCount, s.MessageIDCount, s.ChannelIDCount, s.UserIDCount},
+		s.Marks, s.PrunedBefore,
+	}
+
+	empty := newStats()
+	s.restore(counts{
+		empty.Channels, empty.Networks, empty.Users, empty.networkByName,
+		[4]uint{empty.NetworkIDCount, empty.MessageIDCount, empty.ChannelIDCount, empty.UserIDCount},
+		nil, 0,
+	})
+	return old
+}
+
+func (s *Stats) restore(c counts) {
+	s.Channels, s.Networks, s.Users, s.networkByName = c.channels, c.networks, c.users, c.networkByName
+	s.NetworkIDCount, s.MessageIDCount, s.ChannelIDCount, s.UserIDCount = c.ids[0], c.ids[1], c.ids[2], c.ids[3]
+	s.Marks, s.PrunedBefore = c.marks, c.prunedBefore
+}
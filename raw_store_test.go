@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats_Rebuild(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	if s.Rebuild() == nil {
+		t.Error("Should need a raw store to rebuild.")
+	}
+
+	var r sinkRecorder
+	s.SetOptions(Options{RawStore: store, Sink: &r})
+
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, date, "hello there")
+	s.AddMessageID("abc", Action, network, channel, "aaron!a@zqz.ca", date.Add(time.Second), "waves")
+	s.AddMessage(Msg, network, channel, "spambot!s@zqz.ca", date.Add(2*time.Second), "buy now")
+	if err = store.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// count everything again without the spam bot
+	s.SetOptions(Options{RawStore: store, Sink: &r, Processors: []Processor{
+		ProcessorFunc(func(m *ProcessedMessage) bool {
+			return m.Hostmask != "spambot!s@zqz.ca"
+		}),
+	}})
+	if err = s.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r) != 3 {
+		t.Error("Should not tell sinks about the rebuilt messages:", len(r))
+	}
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 1 {
+		t.Error("Should count the messages again:", u)
+	}
+	if s.GetUser(network, "spambot") != nil {
+		t.Error("Should run the processors on the messages again.")
+	}
+	if s.AddMessageID("abc", Action, network, channel, "aaron!a@zqz.ca", date.Add(time.Second), "waves") {
+		t.Error("Should remember the msgids again.")
+	}
+
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(time.Minute), "still kept")
+	if err = s.Rebuild(); err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 2 {
+		t.Error("Should keep the messages added after rebuilding:", u)
+	}
+}
+
+func TestStats_RebuildFailed(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "raw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "raw.jsonl")
+	if err = ioutil.WriteFile(path, []byte("{\"kind\":\"message\",\"network\":\"zkpq\",\"hostmask\":\"x\"}\nnot json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenFileRawStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello")
+	s.SetOptions(Options{RawStore: store})
+
+	if s.Rebuild() == nil {
+		t.Error("Should fail on bad raw messages.")
+	}
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 1 {
+		t.Error("Should leave the stats as they were:", u)
+	}
+}
@@ -0,0 +1,74 @@
+package stats
+
+import "strings"
+
+var defaultReactions = map[string]struct{}{
+	"this":   struct{}{},
+	"^":      struct{}{},
+	"^^":     struct{}{},
+	"^this":  struct{}{},
+	"this^":  struct{}{},
+	"+1":     struct{}{},
+	"lol":    struct{}{},
+	"lmao":   struct{}{},
+	"haha":   struct{}{},
+	"same":   struct{}{},
+	"agreed": struct{}{},
+	"nice":   struct{}{},
+	"ty":     struct{}{},
+	"thanks": struct{}{},
+}
+
+// ReactionTracker remembers who spoke last in a channel so that short
+// acknowledgments can be credited to the message they react to.
+type ReactionTracker struct {
+	LastUserID uint
+	Count      uint
+}
+
+// isReaction checks if a message is a short acknowledgment. Custom reaction
+// words replace the default list when given.
+func isReaction(message string, words []string) bool {
+	message = strings.ToLower(strings.TrimRight(strings.TrimSpace(message), "!."))
+
+	if words == nil {
+		_, ok := defaultReactions[message]
+		return ok
+	}
+
+	for _, w := range words {
+		if message == w {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addReaction credits the previous speaker of a channel with a reaction when
+// a different user acknowledges their message, returning them if they were.
+func (s *Stats) addReaction(c *Channel, u *User, cu *User, message *Message) *User {
+	previous := c.Reactions.LastUserID
+	c.Reactions.LastUserID = u.ID
+
+	if previous == 0 || previous == u.ID || !isReaction(stripFormatting(message.Message), s.opts.ReactionWords) {
+		return nil
+	}
+
+	author, ok := s.user(previous)
+	if !ok {
+		return nil
+	}
+
+	c.Reactions.Count++
+	u.Reactions.Sent++
+	author.Reactions.Received++
+
+	if cu != nil {
+		cu.Reactions.Sent++
+	}
+	if acu, ok := author.ChannelUsers[strings.ToLower(c.Name)]; ok {
+		acu.Reactions.Received++
+	}
+	return author
+}
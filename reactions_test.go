@@ -0,0 +1,55 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsReaction(t *testing.T) {
+	t.Parallel()
+
+	for _, m := range []string{"this", "+1", "^", "LOL!", " same. "} {
+		if !isReaction(m, nil) {
+			t.Errorf("%q should be a reaction.", m)
+		}
+	}
+
+	for _, m := range []string{"this is great", "hello", ""} {
+		if isReaction(m, nil) {
+			t.Errorf("%q should not be a reaction.", m)
+		}
+	}
+
+	if isReaction("lol", []string{"kek"}) || !isReaction("kek", []string{"kek"}) {
+		t.Error("Custom words should replace the defaults.")
+	}
+}
+
+func TestStats_Reactions(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "alice", time.Now(), "lol")
+	s.AddMessage(Msg, network, channel, "alice", time.Now(), "vim is better than emacs")
+	s.AddMessage(Msg, network, channel, "alice", time.Now(), "this")
+	s.AddMessage(Msg, network, channel, "bob", time.Now(), "this")
+	s.AddMessage(Msg, network, channel, "carol", time.Now(), "+1")
+
+	alice, bob := s.GetUser(network, "alice"), s.GetUser(network, "bob")
+
+	if alice.Reactions.Received != 1 || alice.Reactions.Sent != 0 {
+		t.Error("Alice should have received one reaction.", alice.Reactions)
+	}
+
+	if bob.Reactions.Sent != 1 || bob.Reactions.Received != 1 {
+		t.Error("Bob should have given and received a reaction.", bob.Reactions)
+	}
+
+	if alice.ChannelUsers[channel].Reactions.Received != 1 {
+		t.Error("Should credit the channel user.")
+	}
+
+	if c := s.GetChannel(network, channel); c.Reactions.Count != 2 {
+		t.Error("Channel should have two reactions.")
+	}
+}
@@ -0,0 +1,408 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// IDMark dates the message ids: the messages from ID up to the next mark are
+// dated before the end of Day. The messages before the first mark, counted
+// before marks were kept, go with it.
+type IDMark struct {
+	ID  uint
+	Day time.Time
+}
+
+// mark dates the message with the id, starting a new mark when it's dated
+// past the day of the last one. It's called with the shared lock held.
+func (s *Stats) mark(id uint, date time.Time) {
+	day := date.Truncate(24 * time.Hour)
+	if n := len(s.Marks); n > 0 && day.Before(s.Marks[n-1].Day.Add(24*time.Hour)) {
+		return
+	}
+	s.Marks = append(s.Marks, IDMark{ID: id, Day: day})
+}
+
+// Retentions are how long each category of what's kept of the messages is
+// kept, besides their text kept for the Retention. A category is kept for the
+// Retention when its retention is 0 and forever when it's negative, eg. the
+// text for 30 days, the urls for a year and the aggregates forever.
+type Retentions struct {
+	// URLs are the urls counted.
+	URLs time.Duration
+	// Quotes are the quotes and the topics.
+	Quotes time.Duration
+	// Aggregates are the users of the channels with their counters and
+	// the lines and moods of the days.
+	Aggregates time.Duration
+}
+
+// Cutoffs are the dates each category of what's kept is pruned before, zero
+// for the categories kept forever.
+type Cutoffs struct {
+	Text       time.Time
+	URLs       time.Time
+	Quotes     time.Time
+	Aggregates time.Time
+}
+
+// any checks if anything is pruned.
+func (c Cutoffs) any() bool {
+	return !c.Text.IsZero() || !c.URLs.IsZero() || !c.Quotes.IsZero() || !c.Aggregates.IsZero()
+}
+
+// cutoffs are the dates the categories are pruned before at the time.
+func (o *Options) cutoffs(now time.Time) Cutoffs {
+	cutoff := func(retention time.Duration) time.Time {
+		if retention == 0 {
+			retention = o.Retention
+		}
+		if retention <= 0 {
+			return time.Time{}
+		}
+		return now.Add(-retention)
+	}
+	return Cutoffs{
+		Text:       cutoff(o.Retention),
+		URLs:       cutoff(o.Retentions.URLs),
+		Quotes:     cutoff(o.Retentions.Quotes),
+		Aggregates: cutoff(o.Retentions.Aggregates),
+	}
+}
+
+// Cutoffs are the dates each category is pruned before as of the time of
+// the stats, see Retentions. What's dated before them is kept until the next
+// Prune, exports should leave it out.
+func (tx *ReadTx) Cutoffs() Cutoffs {
+	return tx.s.opts.cutoffs(tx.s.opts.now())
+}
+
+// Kept checks if what's dated at the time is kept by the cutoff, everything
+// is when it's zero.
+func Kept(cutoff, date time.Time) bool {
+	return cutoff.IsZero() || !date.Before(cutoff)
+}
+
+// Prune forgets what's older than the retentions. The messages older than the
+// Retention are dropped from the raw store and from the message ids of the
+// networks, channels and users, the counters, MessageCount among them, keep
+// counting them. The urls, quotes and aggregates are pruned by their own
+// Retentions: the urls not pasted within theirs, the quotes and topics said
+// before it, the users in channels they weren't seen in within it and the
+// days before it. Nothing is pruned without a retention.
+func (s *Stats) Prune() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	cutoffs := s.opts.cutoffs(s.opts.now())
+	if !cutoffs.any() {
+		return nil
+	}
+	s.PrunedDates = cutoffs
+	for _, n := range s.Networks {
+		n.pruneDated(cutoffs)
+	}
+	for _, c := range s.Channels {
+		// the others are pruned when they're loaded
+		if c.loaded() {
+			c.pruneDated(cutoffs)
+		}
+	}
+	for _, u := range s.Users {
+		u.pruneDated(cutoffs)
+	}
+
+	if cutoffs.Text.IsZero() {
+		return nil
+	}
+	before := cutoffs.Text
+
+	if p, ok := s.opts.RawStore.(RawPruner); ok {
+		if err := p.PruneRaw(before); err != nil {
+			return err
+		}
+	}
+
+	i, oldest := s.prunedMarks(before)
+	if i == 0 {
+		return nil
+	}
+	s.Marks = append([]IDMark(nil), s.Marks[i:]...)
+	s.PrunedBefore = oldest
+
+	for _, n := range s.Networks {
+		n.MessageRanges.prune(oldest)
+	}
+	for _, c := range s.Channels {
+		// the others are pruned when they're loaded
+		if c.loaded() {
+			c.MessageRanges.prune(oldest)
+		}
+	}
+	for _, u := range s.Users {
+		u.MessageRanges.prune(oldest)
+		for _, cu := range u.ChannelUsers {
+			cu.MessageRanges.prune(oldest)
+		}
+	}
+	return nil
+}
+
+// prunedMarks finds the marks whose messages are all older than the time,
+// the first i, and the id of the oldest message kept.
+func (s *Stats) prunedMarks(before time.Time) (i int, oldest uint) {
+	i = sort.Search(len(s.Marks), func(i int) bool {
+		return s.Marks[i].Day.Add(24 * time.Hour).After(before)
+	})
+	oldest = s.MessageIDCount
+	if i < len(s.Marks) {
+		oldest = s.Marks[i].ID
+	}
+	return i, oldest
+}
+
+// PrunePlan is what Prune would forget, see PlanPrune.
+type PrunePlan struct {
+	// Before is the date the messages dated before are forgotten, zero
+	// when they're kept.
+	Before time.Time
+	// Cutoffs are the dates each category is pruned before.
+	Cutoffs Cutoffs
+	// Channels are the channels something would be forgotten in, sorted by
+	// network and channel.
+	Channels []PrunedChannel
+	// Messages are the message ids forgotten on every network, those of
+	// messages outside channels, such as quits, included.
+	Messages uint
+	// Raw is how many messages the raw store would drop, it's only
+	// counted for the raw stores that are pruned.
+	Raw int
+}
+
+// PrunedChannel is what Prune would forget in a channel.
+type PrunedChannel struct {
+	Network string
+	Channel string
+	// Messages are the message ids forgotten, the days of the first and
+	// last as the marks date them.
+	Messages uint
+	From     time.Time
+	To       time.Time
+	// Users are the users forgotten in the channel, not seen in it since
+	// before the retention of the aggregates.
+	Users int
+}
+
+// PlanPrune reports what Prune would forget without forgetting it, so a
+// retention can be checked before it's applied. The raw store is read to
+// count the messages it would drop. The plan is empty without a retention.
+func (s *Stats) PlanPrune() (PrunePlan, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	cutoffs := s.opts.cutoffs(s.opts.now())
+	if !cutoffs.any() {
+		return PrunePlan{}, nil
+	}
+	plan := PrunePlan{Before: cutoffs.Text, Cutoffs: cutoffs}
+
+	// the channels are found by the keys of the channel users
+	type channelKey struct {
+		network uint
+		channel string
+	}
+	channels := make(map[channelKey]*PrunedChannel)
+	pruned := func(n *Network, c *Channel) *PrunedChannel {
+		key := channelKey{n.ID, strings.ToLower(c.Name)}
+		if p, ok := channels[key]; ok {
+			return p
+		}
+		p := &PrunedChannel{Network: n.Name, Channel: c.Name}
+		channels[key] = p
+		return p
+	}
+
+	// no marks are before the zero date of the messages kept
+	if i, oldest := s.prunedMarks(plan.Before); i > 0 {
+		for _, n := range s.Networks {
+			plan.Messages += n.MessageRanges.countBefore(oldest)
+			for _, id := range n.ChannelIDs {
+				c := s.Channels[id].load(s)
+				if c == nil {
+					continue
+				}
+				first, last, count := c.MessageRanges.before(oldest)
+				if count == 0 {
+					continue
+				}
+				p := pruned(n, c)
+				p.Messages = count
+				p.From, p.To = s.markDay(first), s.markDay(last)
+			}
+		}
+	}
+
+	for _, u := range s.Users {
+		n := s.Networks[u.NetworkID]
+		for key, cu := range u.ChannelUsers {
+			if n == nil || cutoffs.Aggregates.IsZero() || cu.LastSeen.IsZero() || !cu.LastSeen.Before(cutoffs.Aggregates) {
+				continue
+			}
+			if c := n.channels[key]; c != nil {
+				pruned(n, c).Users++
+			}
+		}
+	}
+
+	for _, p := range channels {
+		plan.Channels = append(plan.Channels, *p)
+	}
+	sort.Slice(plan.Channels, func(i, j int) bool {
+		a, b := plan.Channels[i], plan.Channels[j]
+		if a.Network != b.Network {
+			return a.Network < b.Network
+		}
+		return a.Channel < b.Channel
+	})
+
+	if _, ok := s.opts.RawStore.(RawPruner); ok && !plan.Before.IsZero() {
+		err := s.opts.RawStore.Replay(func(m RawMessage) {
+			if m.Date.Before(plan.Before) {
+				plan.Raw++
+			}
+		})
+		if err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// markDay is the day the marks date a message id in.
+func (s *Stats) markDay(id uint) time.Time {
+	i := sort.Search(len(s.Marks), func(i int) bool { return s.Marks[i].ID > id })
+	// the messages before the first mark go with it
+	if i > 0 {
+		i--
+	}
+	if i < len(s.Marks) {
+		return s.Marks[i].Day
+	}
+	return time.Time{}
+}
+
+// pruneDated forgets the urls, quotes and aggregates of the network dated
+// before their cutoffs.
+func (n *Network) pruneDated(cutoffs Cutoffs) {
+	if !cutoffs.URLs.IsZero() {
+		n.URLCounter.prune(cutoffs.URLs)
+	}
+	if !cutoffs.Quotes.IsZero() {
+		n.Quotes.prune(cutoffs.Quotes)
+	}
+}
+
+// pruneDated forgets the urls, quotes, topics and days of the channel dated
+// before their cutoffs.
+func (c *Channel) pruneDated(cutoffs Cutoffs) {
+	if !cutoffs.URLs.IsZero() {
+		c.URLCounter.prune(cutoffs.URLs)
+	}
+	if !cutoffs.Quotes.IsZero() {
+		c.Quotes.prune(cutoffs.Quotes)
+		topics := c.LastTopics.Topics[:0]
+		for _, t := range c.LastTopics.Topics {
+			if !t.Date.Before(cutoffs.Quotes) {
+				topics = append(topics, t)
+			}
+		}
+		c.LastTopics.Topics = topics
+	}
+	if !cutoffs.Aggregates.IsZero() {
+		day := cutoffs.Aggregates.UTC().Format(dayFormat)
+		for d := range c.Days.Lines {
+			if d < day {
+				delete(c.Days.Lines, d)
+				delete(c.Days.Hours, d)
+			}
+		}
+		c.Mood.prune(day)
+	}
+}
+
+// pruneDated forgets the quotes of the user dated before the cutoff, and the
+// user in the channels they weren't seen in since the cutoff of the
+// aggregates, mostly channels they only passed through. Their messages still
+// count for the users and the channels.
+func (u *User) pruneDated(cutoffs Cutoffs) {
+	if !cutoffs.Quotes.IsZero() {
+		u.Quotes.prune(cutoffs.Quotes)
+	}
+	if !cutoffs.Aggregates.IsZero() {
+		u.Mood.prune(cutoffs.Aggregates.UTC().Format(dayFormat))
+	}
+	for key, cu := range u.ChannelUsers {
+		// undated users are kept
+		if !cutoffs.Aggregates.IsZero() && !cu.LastSeen.IsZero() && cu.LastSeen.Before(cutoffs.Aggregates) {
+			delete(u.ChannelUsers, key)
+			continue
+		}
+		if !cutoffs.Quotes.IsZero() {
+			cu.Quotes.prune(cutoffs.Quotes)
+		}
+	}
+}
+
+// prune forgets the quotes said before the time.
+func (q *quotes) prune(before time.Time) {
+	if q.Last.ID != 0 && q.Last.Date.Before(before) {
+		q.Last = Message{}
+	}
+	if q.Random.ID != 0 && q.Random.Date.Before(before) {
+		q.Random = Message{}
+	}
+}
+
+// prune forgets the moods of the days before the day, formatted as
+// 2006-01-02.
+func (m MoodSeries) prune(day string) {
+	for d := range m {
+		if d < day {
+			delete(m, d)
+		}
+	}
+}
+
+// migrateMessageIDs moves the message ids of stats saved before they were
+// kept in ranges, counting the messages of those saved before MessageCount
+// was. It's called once the network's indexes are built.
+func (n *Network) migrateMessageIDs() {
+	migrateIDs(&n.MessageIDs, &n.MessageRanges, &n.MessageCount)
+	for _, id := range n.ChannelIDs {
+		c := n.stats.Channels[id]
+		// the others are moved when they're loaded
+		if c.loaded() {
+			migrateIDs(&c.MessageIDs, &c.MessageRanges, &c.MessageCount)
+		}
+	}
+	for _, id := range n.UserIDs {
+		u := n.stats.Users[id]
+		migrateIDs(&u.MessageIDs, &u.MessageRanges, &u.MessageCount)
+		for _, cu := range u.ChannelUsers {
+			migrateIDs(&cu.MessageIDs, &cu.MessageRanges, &cu.MessageCount)
+		}
+	}
+}
+
+func migrateIDs(ids *[]uint, ranges *IDRanges, count *uint) {
+	if len(*ids) == 0 {
+		return
+	}
+
+	if *count == 0 {
+		*count = uint(len(*ids))
+	}
+	*ranges = idRanges(*ids)
+	*ids = nil
+}
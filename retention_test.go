@@ -0,0 +1,269 @@
+package stats
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStats_Prune(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.SetOptions(Options{RawStore: store})
+
+	now := time.Now()
+	s.AddMessage(Msg, network, channel, hostmask, now.AddDate(0, 0, -40), "hello")
+	s.AddMessage(Msg, network, channel, hostmask, now.AddDate(0, 0, -35), "there")
+	s.AddMessage(Msg, network, channel, hostmask, now, "again")
+
+	if err = s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if u := s.GetUser(network, nick); u.MessageRanges.Len() != 3 {
+		t.Error("Should keep everything without a retention:", u.MessageRanges.All())
+	}
+
+	s.SetOptions(Options{RawStore: store, Retention: 30 * 24 * time.Hour})
+	if err = s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	u := s.GetUser(network, nick)
+	if u.MessageRanges.Len() != 1 || u.MessageCount != 3 || u.Lines != 3 {
+		t.Error("Should prune the old ids and keep counting them:", u.MessageRanges.All(), u.MessageCount, u.Lines)
+	}
+	c := s.GetChannel(network, channel)
+	if c.MessageRanges.Len() != 1 || c.MessageCount != 3 {
+		t.Error("Should prune the channel:", c.MessageRanges.All(), c.MessageCount)
+	}
+	if cu := u.ChannelUsers[channel]; cu.MessageRanges.Len() != 1 || cu.MessageCount != 3 {
+		t.Error("Should prune the user in the channel:", cu.MessageRanges.All(), cu.MessageCount)
+	}
+	if n := s.GetNetwork(network); n.MessageRanges.Len() != 1 || n.MessageCount != 3 {
+		t.Error("Should prune the network:", n.MessageRanges.All(), n.MessageCount)
+	}
+
+	var kept []string
+	if err = store.Replay(func(m RawMessage) { kept = append(kept, m.Message) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 1 || kept[0] != "again" {
+		t.Error("Should prune the raw store:", kept)
+	}
+
+	// the store keeps appending to the pruned file
+	s.AddMessage(Msg, network, channel, hostmask, now, "more")
+	kept = kept[:0]
+	if err = store.Replay(func(m RawMessage) { kept = append(kept, m.Message) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 2 || kept[1] != "more" {
+		t.Error("Should add to the pruned raw store:", kept)
+	}
+}
+
+func TestStats_PruneChannelUsers(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{Retention: 30 * 24 * time.Hour})
+
+	now := time.Now()
+	s.AddMessage(Msg, network, "#once", hostmask, now.AddDate(0, 0, -40), "hello")
+	s.AddMessage(Msg, network, channel, hostmask, now.AddDate(0, 0, -40), "hello")
+	s.AddMessage(Msg, network, channel, hostmask, now, "again")
+
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	u := s.GetUser(network, nick)
+	if _, ok := u.ChannelUsers["#once"]; ok {
+		t.Error("Should drop the user in channels they weren't seen in lately.")
+	}
+	if cu, ok := u.ChannelUsers[channel]; !ok || cu.Lines != 2 {
+		t.Error("Should keep the user in channels they were seen in:", cu)
+	}
+	if u.Lines != 3 || s.GetChannel(network, "#once").MessageCount != 1 {
+		t.Error("Should keep counting the messages for the user and channel.")
+	}
+}
+
+func TestStats_PruneRetentions(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{
+		Retention:  30 * 24 * time.Hour,
+		Retentions: Retentions{URLs: 365 * 24 * time.Hour, Quotes: 60 * 24 * time.Hour, Aggregates: -1},
+	})
+
+	now := time.Now()
+	aaron := "aaron!aaron@bitfission.com"
+	s.AddMessage(Msg, network, channel, hostmask, now.AddDate(0, 0, -400), "see http://old.com")
+	s.AddMessage(Topic, network, channel, hostmask, now.AddDate(0, 0, -400), "an old topic")
+	s.AddMessage(Msg, network, channel, aaron, now.AddDate(0, 0, -100), "hi")
+	s.AddMessage(Msg, network, channel, hostmask, now.AddDate(0, 0, -40), "see http://new.com")
+	s.AddMessage(Msg, network, channel, hostmask, now, "again")
+
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.GetChannel(network, channel)
+	if _, ok := c.URLCounter.All["http://old.com"]; ok || c.URLCounter.All["http://new.com"] != 1 {
+		t.Error("Should keep the urls for a retention of their own:", c.URLCounter.All)
+	}
+	if n := s.GetNetwork(network); n.URLCounter.All["http://old.com"] != 0 || n.URLCounter.All["http://new.com"] != 1 {
+		t.Error("Should prune the urls of the network:", n.URLCounter.All)
+	}
+	if len(c.LastTopics.Topics) != 0 {
+		t.Error("Should forget the topics with the quotes:", c.LastTopics.Topics)
+	}
+
+	a := s.GetUser(network, "aaron")
+	cu, ok := a.ChannelUsers[channel]
+	if !ok || cu.Lines != 1 {
+		t.Fatal("Should keep the aggregates forever:", cu)
+	}
+	if a.Quotes.Last.ID != 0 || cu.Quotes.Last.ID != 0 || cu.Quotes.Random.ID != 0 {
+		t.Error("Should forget the old quotes:", a.Quotes, cu.Quotes)
+	}
+	if u := s.GetUser(network, nick); u.Quotes.Last.Message != "again" {
+		t.Error("Should keep the recent quotes:", u.Quotes.Last)
+	}
+	if c.MessageRanges.Len() != 1 || c.Days.Lines[now.AddDate(0, 0, -400).UTC().Format(dayFormat)] == 0 {
+		t.Error("Should prune the messages and keep the days:", c.MessageRanges.All(), c.Days.Lines)
+	}
+	s.View(func(tx *ReadTx) {
+		if cutoffs := tx.Cutoffs(); cutoffs.Text.IsZero() || !cutoffs.Aggregates.IsZero() || !cutoffs.Quotes.After(cutoffs.URLs) {
+			t.Error("Should cut the categories off by their retentions:", cutoffs)
+		}
+	})
+
+	s.SetOptions(Options{Retentions: Retentions{Aggregates: 60 * 24 * time.Hour}})
+	if err := s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetUser(network, "aaron").ChannelUsers[channel]; ok {
+		t.Error("Should forget the users not seen within the retention of the aggregates.")
+	}
+	for day := range c.Days.Lines {
+		if day < now.AddDate(0, 0, -60).UTC().Format(dayFormat) {
+			t.Error("Should forget the old days:", day)
+		}
+	}
+	for day := range c.Mood {
+		if day < now.AddDate(0, 0, -60).UTC().Format(dayFormat) {
+			t.Error("Should forget the old moods:", day)
+		}
+	}
+	if c.MessageRanges.Len() != 1 || c.MessageCount != 5 {
+		t.Error("Should keep the messages without a retention:", c.MessageRanges.All(), c.MessageCount)
+	}
+}
+
+func TestStats_PlanPrune(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "retention")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	s := newStats()
+	s.SetOptions(Options{RawStore: store})
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -40).UTC()
+	s.AddMessage(Msg, network, "#once", hostmask, old, "hello")
+	s.AddMessage(Msg, network, channel, hostmask, old, "hello")
+	s.AddMessage(Msg, network, channel, hostmask, now.AddDate(0, 0, -35), "there")
+	s.AddMessage(Quit, network, "", hostmask, now.AddDate(0, 0, -35), "bye")
+	s.AddMessage(Msg, network, channel, hostmask, now, "again")
+
+	if plan, err := s.PlanPrune(); err != nil || len(plan.Channels) != 0 || plan.Messages != 0 {
+		t.Error("Should plan nothing without a retention:", plan, err)
+	}
+
+	s.SetOptions(Options{RawStore: store, Retentions: Retentions{Aggregates: 30 * 24 * time.Hour}})
+	if plan, err := s.PlanPrune(); err != nil || !plan.Before.IsZero() || plan.Messages != 0 || plan.Raw != 0 || len(plan.Channels) != 1 || plan.Channels[0].Users != 1 {
+		t.Error("Should only plan forgetting the aggregates without a retention of the text:", plan, err)
+	}
+
+	s.SetOptions(Options{RawStore: store, Retention: 30 * 24 * time.Hour})
+	plan, err := s.PlanPrune()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if plan.Messages != 4 || plan.Raw != 4 || len(plan.Channels) != 2 {
+		t.Fatal("Should plan forgetting the old messages:", plan)
+	}
+	if c := plan.Channels[0]; c.Channel != "#once" || c.Messages != 1 || c.Users != 1 || !c.From.Equal(old.Truncate(24*time.Hour)) {
+		t.Error("Should plan forgetting #once and its user:", c)
+	}
+	if c := plan.Channels[1]; c.Channel != channel || c.Messages != 2 || c.Users != 0 || !c.To.After(c.From) {
+		t.Error("Should plan forgetting the old messages of #test:", c)
+	}
+
+	// nothing was forgotten
+	if u := s.GetUser(network, nick); u.MessageRanges.Len() != 5 || len(u.ChannelUsers) != 2 {
+		t.Error("Should not prune while planning:", u.MessageRanges.All(), u.ChannelUsers)
+	}
+	var kept int
+	if err = store.Replay(func(m RawMessage) { kept++ }); err != nil || kept != 5 {
+		t.Error("Should not prune the raw store while planning, kept", kept, err)
+	}
+
+	if err = s.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if plan, _ := s.PlanPrune(); plan.Messages != 0 || plan.Raw != 0 || len(plan.Channels) != 0 {
+		t.Error("Should plan nothing once pruned:", plan)
+	}
+}
+
+func TestStats_mark(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	day := time.Date(2014, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	s.mark(1, day.Add(8*time.Hour))
+	s.mark(2, day.Add(20*time.Hour))
+	s.mark(3, day.Add(-48*time.Hour))
+	s.mark(4, day.Add(30*time.Hour))
+
+	want := []IDMark{{1, day}, {4, day.Add(24 * time.Hour)}}
+	if len(s.Marks) != len(want) {
+		t.Fatal("Should mark new days only:", s.Marks)
+	}
+	for i := range want {
+		if s.Marks[i].ID != want[i].ID || !s.Marks[i].Day.Equal(want[i].Day) {
+			t.Error("Wrong mark:", i, s.Marks[i])
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package stats
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultMaxMessageLength is the longest text of a message kept by default,
+// in bytes. It's far above the 512 bytes of an irc line, for the chats and
+// mails that are longer.
+const defaultMaxMessageLength = 4096
+
+// maxMessageLength returns the longest text of a message, or its default. The
+// text isn't cut when negative.
+func (o *Options) maxMessageLength() int {
+	if o.MaxMessageLength == 0 {
+		return defaultMaxMessageLength
+	}
+	return o.MaxMessageLength
+}
+
+// sanitize makes the text of a message safe to keep, quote and render: the
+// line breaks and tabs are turned into spaces, the other control characters
+// are dropped but for the formatting codes, see stripFormatting, and the text
+// is cut to the max bytes without splitting a character. It must be UTF-8,
+// see Charset.Decode. Text that's already safe is returned as it is.
+func sanitize(text string, max int) string {
+	if safe(text, max) {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text))
+	for i, r := range text {
+		switch {
+		case r == '\n' && i > 0 && text[i-1] == '\r':
+			// \r\n is a single line break
+			continue
+		case r == '\r' || r == '\n' || r == '\t':
+			r = ' '
+		case isControl(r):
+			continue
+		}
+
+		if max >= 0 && b.Len()+utf8.RuneLen(r) > max {
+			break
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// safe checks if text has no control characters but for the formatting codes
+// and is no longer than max, unless max is negative.
+func safe(text string, max int) bool {
+	if max >= 0 && len(text) > max {
+		return false
+	}
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		// the C1 controls are encoded as 0xc2 0x80 to 0xc2 0x9f
+		if c < ' ' && !isFormatting(c) || c == 0x7f || c == 0xc2 && i+1 < len(text) && text[i+1] < 0xa0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isControl checks if a character is a control character other than the
+// formatting codes: the C0 controls, DEL and the C1 controls.
+func isControl(r rune) bool {
+	return r < ' ' && !isFormatting(byte(r)) || r >= 0x7f && r < 0xa0
+}
+
+// sanitize makes the text of a message and the name of its channel safe to
+// keep, see sanitize.
+func (o *Options) sanitize(raw *RawMessage) {
+	raw.Message = sanitize(raw.Message, o.maxMessageLength())
+	raw.Channel = sanitize(raw.Channel, -1)
+}
@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		max  int
+		want string
+	}{
+		{"plain", 10, "plain"},
+		{"two\r\nlines\nand\rmore", -1, "two lines and more"},
+		{"tab\tbed", -1, "tab bed"},
+		{"nul\x00 bell\x07 del\x7f", -1, "nul bell del"},
+		{"c1 \u0085\u009f", -1, "c1 "},
+		{"\x02bold\x02 \x0304red\x03", -1, "\x02bold\x02 \x0304red\x03"},
+		{"cut here", 3, "cut"},
+		{"café", 4, "caf"},
+		{"a\nb€", 4, "a b"},
+		{" kept", -1, " kept"},
+		{"", 0, ""},
+	}
+
+	for _, test := range tests {
+		if got := sanitize(test.text, test.max); got != test.want {
+			t.Errorf("%q cut at %d should sanitize to %q, Got: %q", test.text, test.max, test.want, got)
+		}
+	}
+}
+
+func TestStats_sanitize(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{MaxMessageLength: 8})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hi\r\nthere\x00 and more")
+
+	u := s.GetUser(network, nick)
+	if u.Quotes.Last.Message != "hi there" {
+		t.Errorf("Should keep the text sanitized, kept %q", u.Quotes.Last.Message)
+	}
+
+	s = newStats()
+	long := strings.Repeat("a", defaultMaxMessageLength+10)
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), long)
+	if u := s.GetUser(network, nick); len(u.Quotes.Last.Message) != defaultMaxMessageLength {
+		t.Error("Should cut the text at the default length, kept", len(u.Quotes.Last.Message))
+	}
+
+	s = newStats()
+	s.SetOptions(Options{MaxMessageLength: -1})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), long)
+	if u := s.GetUser(network, nick); u.Quotes.Last.Message != long {
+		t.Error("Shouldn't cut the text when the length is negative.")
+	}
+}
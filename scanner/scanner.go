@@ -1,28 +1,84 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
 	"os"
-	"regexp"
+	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/importer"
 )
 
 var (
 	parserFlag = flag.String("parser", "weechat", "A named internal log parser or a parser file to load.")
 	netFlag    = flag.String("network", "", "The network where the log file came from.")
 	chanFlag   = flag.String("channel", "", "The channel where the log file came from.")
+	langFlag   = flag.Bool("languages", false, "Detect the language of each message.")
+	moodFlag   = flag.Bool("sentiment", false, "Score the sentiment of each message.")
+	zoneFlag   = flag.String("timezone", "", "The timezone the log was written in, eg. America/Toronto.")
+	progFlag   = flag.Bool("progress", false, "Report the progress of each file.")
+	tailFlag   = flag.Bool("tail", false, "Keep following the files and import lines as they are written.")
+	syslogFlag = flag.String("syslog", "", "Receive log lines over syslog instead of files, eg. udp://:5514 or tcp://:5514.")
+	jrnlFlag   = flag.Bool("journal", false, "Read log lines from the output of journalctl -o json on standard in.")
+	tagFlag    = flag.String("tag", "", "Only accept syslog or journal messages logged with this tag.")
+	slackFlag  = flag.Bool("slack", false, "The files are Slack workspace export zips.")
+	mboxFlag   = flag.Bool("mbox", false, "The files are mailing list archives in the mbox format.")
+	delayFlag  = flag.Duration("delay", importer.DefaultFetchDelay, "The least time between two requests when fetching logs over http.")
+	stateFlag  = flag.String("fetched", "", "A file remembering the logs fetched over http, so an interrupted backfill resumes where it stopped.")
+	procFlag   = flag.String("processors", "", "Semicolon separated processors run on every message, eg. bridge=discordbot,matrixbot.")
 )
 
 var usage = `
 Scanner should be invoked with one or more filenames. Use * as to use standard in
 as an input file.
 
-Available Parsers: weechat
+Available Parsers: %s
+
+The irssi, mirc, hexchat and eggdrop parsers take the day of each line from the
+markers they write when a log is opened or the day changes. Dates in logs are
+read as UTC unless a -timezone is given. Lines falling in a range of a channel's
+logs that was already imported are skipped.
+
+With -tail the scanner keeps running after importing the files, following them
+and any logs created in their directories, and saves the stats every few
+minutes and when interrupted.
+
+With -syslog or -journal the lines come from syslog messages or journal entries
+instead of files, parsed with the given parser. A custom parser whose date
+format line is left empty takes the date of each message instead. Its regexes
+may capture the channel of each line for bots that log many channels together.
+
+Files may also be http urls of published logs: a log, an index page linking to
+logs and the directories below it, or an archive.org item such as
+https://archive.org/details/some-logs. Requests are spaced by -delay and the
+urls imported are remembered in the -fetched file when one is given.
+
+Files may also be s3://bucket/prefix urls, importing every log under the
+prefix of the bucket, and remembering them in the -fetched file too. The
+credentials and region are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+AWS_SESSION_TOKEN and AWS_REGION, public buckets need none. Set
+AWS_ENDPOINT_URL to import from other storage speaking the S3 api, such as
+MinIO.
+
+With -slack every file is a Slack workspace export zip. Each channel of the
+export is counted as the channel of the same name, the network is slack unless
+one is given.
+
+With -mbox every file is a mailing list archive. A list is counted as the
+channel named after its List-Id, or after the file, and the network is mail
+unless one is given. The lines of the mails that aren't quoted count as lines
+said, and threads set the topic of the list.
+
+The znc parser also accepts directories laid out like znc's log module
+(<network>/<channel>/<YYYY-MM-DD>.log), the network, channel and date of each
+file are taken from its path unless given as options.
 
 To invoke scanner with a custom parser simply define a file that starts with a date
 format and supplies a regex for the following in order, ensuring all named regex args
@@ -35,6 +91,7 @@ are supplied:
   action  [date, nick, action]
   mode    [date, mode, nick]
   topic   [date, nick, action]
+  nick    [date, nick, new] (optional)
 
 Example file:
 2006-01-02 15:04:05
@@ -51,30 +108,69 @@ scanner [options] <filenames...>
 func main() {
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
-		fmt.Fprintln(os.Stderr, usage)
+		fmt.Fprintf(os.Stderr, usage, strings.Join(importer.Names(), ", "))
 		flag.PrintDefaults()
 	}
 	flag.Parse()
 
+	streaming := len(*syslogFlag) > 0 || *jrnlFlag
 	remaining := flag.Args()
-	if len(remaining) == 0 {
+	if len(remaining) == 0 && !streaming {
 		fmt.Fprintln(os.Stderr, "Must pass in at least one file name.")
 		os.Exit(1)
 	}
 
-	if len(*netFlag) == 0 {
+	sc, err := newScanner(*netFlag, *chanFlag, *parserFlag, remaining...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Problem creating scanner:", err)
+		os.Exit(1)
+	}
+
+	// parsers that read the network and channel from the path don't need them
+	_, fromPath := sc.factory(nil).(importer.PathParser)
+	if *slackFlag {
+		sc.slack, fromPath = true, true
+	}
+	if *mboxFlag {
+		sc.mbox, fromPath = true, true
+	}
+	if len(*netFlag) == 0 && !fromPath {
 		fmt.Fprintln(os.Stderr, "Must specify the network.")
 		os.Exit(1)
 	}
-	if len(*chanFlag) == 0 {
+	if len(*chanFlag) == 0 && !fromPath && !streaming {
 		fmt.Fprintln(os.Stderr, "Must specify the channel.")
 		os.Exit(1)
 	}
 
-	sc, err := newScanner(*netFlag, *chanFlag, *parserFlag, remaining...)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Problem creating scanner:", err)
-		os.Exit(1)
+	if len(*zoneFlag) > 0 {
+		if sc.location, err = time.LoadLocation(*zoneFlag); err != nil {
+			fmt.Fprintln(os.Stderr, "Unknown timezone:", err)
+			os.Exit(1)
+		}
+	}
+
+	if *progFlag {
+		sc.progress = reportProgress
+	}
+	sc.delay, sc.state = *delayFlag, *stateFlag
+
+	if *langFlag {
+		sc.options.LanguageDetector = stats.NewStopwordDetector()
+	}
+	if *moodFlag {
+		sc.options.SentimentAnalyzer = stats.NewLexiconAnalyzer()
+	}
+	for _, spec := range strings.Split(*procFlag, ";") {
+		if spec = strings.TrimSpace(spec); len(spec) == 0 {
+			continue
+		}
+		p, err := stats.NewProcessor(spec)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		sc.options.Processors = append(sc.options.Processors, p)
 	}
 
 	stats, err := sc.parse()
@@ -82,7 +178,22 @@ func main() {
 		fmt.Fprintln(os.Stderr, "Failed parsing sources:", err)
 		os.Exit(1)
 	}
-	stats.Save()
+
+	if *tailFlag {
+		if err = sc.tail(stats); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed following sources:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if streaming {
+		if err = sc.stream(stats, *syslogFlag, *tagFlag); err != nil {
+			fmt.Fprintln(os.Stderr, "Failed receiving log lines:", err)
+			os.Exit(1)
+		}
+		return
+	}
+	save(stats)
 }
 
 type scanner struct {
@@ -90,32 +201,15 @@ type scanner struct {
 	network   string
 	channel   string
 
-	parser parser
-}
-
-type parser struct {
-	dateFormat string
-	message    *regexp.Regexp
-	join       *regexp.Regexp
-	part       *regexp.Regexp
-	kick       *regexp.Regexp
-	quit       *regexp.Regexp
-	action     *regexp.Regexp
-	mode       *regexp.Regexp
-	topic      *regexp.Regexp
-}
-
-var weechat = parser{
-	dateFormat: "2006-01-02 15:04:05",
-
-	message: regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t(?:[@&+])?(?P<nick>[^\s\-]+)\t(?P<message>.*)$`),
-	join:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t-->\t(?P<nick>.*) \((?P<host>.*)\) has joined (?P<channel>(?:&|#)\w+)$`),
-	quit:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t<--\t(?P<nick>.*) \((?P<host>.*)\) has quit \((?P<message>.*)\)$`),
-	part:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t<--\t(?P<nick>.*) \((?P<host>.*)\) has left (?P<channel>(?:&|#)\w+)(?: \((?P<message>.*)\))?$`),
-	kick:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t<--\t(?P<nick>.*) has kicked (?P<target>.*) \((?P<message>.*)\)$`),
-	topic:   regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t--\t(?P<nick>.*) has changed topic for (?P<channel>(?:&|#)\w+) from "(?P<topic>.*)"$`),
-	mode:    regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t--\tMode (?P<channel>(?:&|#)\w+) \[(?P<mode>\S+)[^\]]*\] by (?P<nick>.*)$`),
-	action:  regexp.MustCompile(`^(?P<date>[0-9:\- ]*)\t *\t(?P<nick>.*) (?P<action>.*)$`),
+	factory  importer.Factory
+	options  stats.Options
+	location *time.Location
+	progress func(importer.Progress)
+	slack    bool
+	mbox     bool
+	// delay and state are the settings of fetching logs over http.
+	delay time.Duration
+	state string
 }
 
 func newScanner(network, channel, parser string, files ...string) (*scanner, error) {
@@ -123,243 +217,268 @@ func newScanner(network, channel, parser string, files ...string) (*scanner, err
 		network:   network,
 		channel:   channel,
 		filenames: files,
+		location:  time.UTC,
 	}
 
-	switch parser {
-	case "weechat":
-		sc.parser = weechat
-	default:
-		var err error
-		if sc.parser, err = loadParser(parser); err != nil {
-			return nil, err
-		}
+	var ok bool
+	if sc.factory, ok = importer.Lookup(parser); ok {
+		return sc, nil
 	}
 
-	return sc, nil
-}
-
-func loadParser(filename string) (parser, error) {
-	var p parser
-
-	f, err := os.Open(filename)
+	f, err := os.Open(parser)
 	if err != nil {
-		return p, err
+		return nil, err
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
-	var line = 0
-	for ; scanner.Scan(); line++ {
-		switch line {
-		case 0:
-			p.dateFormat = scanner.Text()
-		case 1:
-			p.message, err = regexp.Compile(scanner.Text())
-		case 2:
-			p.join, err = regexp.Compile(scanner.Text())
-		case 3:
-			p.part, err = regexp.Compile(scanner.Text())
-		case 4:
-			p.kick, err = regexp.Compile(scanner.Text())
-		case 5:
-			p.quit, err = regexp.Compile(scanner.Text())
-		case 6:
-			p.action, err = regexp.Compile(scanner.Text())
-		case 7:
-			p.mode, err = regexp.Compile(scanner.Text())
-		case 8:
-			p.topic, err = regexp.Compile(scanner.Text())
-		}
-
-		if err != nil {
-			return p, fmt.Errorf("Failed to parse line: %d of %s: %v", line, filename, err)
-		}
+	format, err := importer.LoadFormat(f)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load %s: %w", parser, err)
 	}
+	sc.factory = format.New
 
-	if err = scanner.Err(); err != nil {
-		return p, fmt.Errorf("Failed to parse file: %v", err)
-	}
-	if line != 9 {
-		return p, fmt.Errorf("Must supply a line for each of: dateFormat, message, join, part, kick, quit, action, mode, topic")
-	}
-	return p, nil
+	return sc, nil
+}
+
+func (sc *scanner) importer(s *stats.Stats) *importer.Importer {
+	im := importer.New(s)
+	im.Network, im.Channel = sc.network, sc.channel
+	im.Progress = sc.progress
+	return im
 }
 
 func (sc *scanner) parse() (*stats.Stats, error) {
-	stats := stats.NewStats()
+	stats, err := stats.NewStats()
+	if err != nil {
+		return nil, err
+	}
+	stats.SetOptions(sc.options)
+
+	im := sc.importer(stats)
 	for _, file := range sc.filenames {
-		if file == "*" {
-			if err := sc.parseReader(stats, os.Stdin); err != nil {
+		if sc.slack {
+			if err := im.ImportSlack(file); err != nil {
 				return nil, err
 			}
-		} else {
-			f, err := os.Open(file)
-			if err != nil {
+		} else if sc.mbox {
+			if err := im.ImportMbox(file); err != nil {
 				return nil, err
 			}
-
-			err = sc.parseReader(stats, f)
-			f.Close()
-			if err != nil {
+		} else if strings.HasPrefix(file, "http://") || strings.HasPrefix(file, "https://") {
+			if err := sc.fetch(im, file); err != nil {
 				return nil, err
 			}
+		} else if strings.HasPrefix(file, "s3://") {
+			if err := sc.fetchS3(im, file); err != nil {
+				return nil, err
+			}
+		} else if file == "*" {
+			if err := sc.parseReader(stats, os.Stdin); err != nil {
+				return nil, err
+			}
+		} else if err := im.ImportPath(file, sc.factory, sc.location); err != nil {
+			return nil, err
 		}
 	}
 
 	return stats, nil
 }
 
-func (sc *scanner) parseReader(s *stats.Stats, r io.Reader) error {
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		sc.parseLine(s, scanner.Text())
-	}
-
-	return scanner.Err()
-}
-
-func (sc *scanner) parseLine(s *stats.Stats, line string) {
-	if r := findData(sc.parser.join, line); r != nil {
-
-		nick, dateString, _ := r["nick"], r["date"], r["channel"]
-
-		if len(nick) == 0 || len(dateString) == 0 {
-			return
-		}
-
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
-		if err != nil {
-			return
-		}
-
-		s.AddMessage(stats.Join, sc.network, sc.channel, nick, date, "")
-
-	} else if r := findData(sc.parser.part, line); r != nil {
-
-		nick, dateString, _, message := r["nick"], r["date"], r["channel"], r["message"]
+// fetch imports the logs published at a url, skipping and adding to the
+// urls in the state file.
+func (sc *scanner) fetch(im *importer.Importer, url string) error {
+	f := importer.NewFetcher(im, sc.factory, sc.location)
+	f.Delay = sc.delay
 
-		if len(nick) == 0 || len(dateString) == 0 {
-			return
+	state, err := sc.openState(f.Done)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		defer state.Close()
+		f.Fetched = func(url string) {
+			fmt.Fprintln(state, url)
 		}
+	}
+	return f.Fetch(url)
+}
 
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
-		if err != nil {
-			return
+// fetchS3 imports the logs under an s3://bucket/prefix url with the
+// credentials of the environment, skipping and adding to the objects in the
+// state file.
+func (sc *scanner) fetchS3(im *importer.Importer, url string) error {
+	f := importer.NewS3Importer(im, sc.factory, sc.location)
+	f.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	f.Region = os.Getenv("AWS_REGION")
+	f.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	f.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	f.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+
+	state, err := sc.openState(f.Done)
+	if err != nil {
+		return err
+	}
+	if state != nil {
+		defer state.Close()
+		f.Fetched = func(url string) {
+			fmt.Fprintln(state, url)
 		}
+	}
+	return f.Import(url)
+}
 
-		s.AddMessage(stats.Part, sc.network, sc.channel, nick, date, message)
-
-	} else if r = findData(sc.parser.quit, line); r != nil {
-
-		nick, dateString, message := r["nick"], r["date"], r["message"]
+// openState reads the urls remembered in the state file into done and opens
+// it for appending the urls imported next. There's no file without a state
+// file.
+func (sc *scanner) openState(done map[string]bool) (*os.File, error) {
+	if len(sc.state) == 0 {
+		return nil, nil
+	}
 
-		if len(nick) == 0 || len(dateString) == 0 || len(message) == 0 {
-			return
+	if b, err := ioutil.ReadFile(sc.state); err == nil {
+		for _, url := range strings.Split(string(b), "\n") {
+			if len(url) > 0 {
+				done[url] = true
+			}
 		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
 
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
-		if err != nil {
-			return
-		}
+	return os.OpenFile(sc.state, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
 
-		s.AddMessage(stats.Quit, sc.network, "", nick, date, message)
+// parseReader parses every line of the reader and adds the messages to the
+// stats in a single batch.
+func (sc *scanner) parseReader(s *stats.Stats, r io.Reader) error {
+	_, err := sc.importer(s).Import(importer.Source{Name: "stdin"}, r, sc.factory(sc.location))
+	return err
+}
 
-	} else if r = findData(sc.parser.message, line); r != nil {
+// parseLine parses a single line and adds it straight to the stats.
+func (sc *scanner) parseLine(s *stats.Stats, line string) {
+	l, ok := sc.factory(sc.location).ParseLine(line)
+	if !ok {
+		return
+	}
 
-		nick, dateString, message := r["nick"], r["date"], r["message"]
+	channel := sc.channel
+	if l.Kind == stats.Quit || l.Kind == stats.Nick {
+		channel = ""
+	}
+	s.AddMessage(l.Kind, sc.network, channel, l.Nick, l.Date, l.Message)
+}
 
-		if len(nick) == 0 || len(dateString) == 0 || len(message) == 0 {
-			return
-		}
+// saveInterval is how often the stats are saved while tailing.
+const saveInterval = 5 * time.Minute
 
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
-		if err != nil {
-			return
+// tail follows the files until interrupted, saving the stats as it goes.
+func (sc *scanner) tail(s *stats.Stats) error {
+	var paths []string
+	for _, file := range sc.filenames {
+		if file != "*" {
+			paths = append(paths, file)
 		}
+	}
 
-		s.AddMessage(stats.Msg, sc.network, sc.channel, nick, date, message)
-	} else if r = findData(sc.parser.kick, line); r != nil {
-		nick, dateString, target := r["nick"], r["date"], r["target"]
+	tailer := importer.NewTailer(sc.importer(s), sc.factory, sc.location)
+	return serve(s, func(stop <-chan struct{}) error {
+		return tailer.Run(paths, stop)
+	})
+}
 
-		if len(nick) == 0 || len(dateString) == 0 || len(target) == 0 {
-			return
-		}
+// stream imports the lines of syslog messages received on addr, or of the
+// journal entries on standard in when addr is empty.
+func (sc *scanner) stream(s *stats.Stats, addr, tag string) error {
+	st := importer.NewStream(sc.importer(s), sc.factory(sc.location), importer.Source{})
+	st.Tag = tag
 
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
-		if err != nil {
-			return
-		}
-		s.AddMessage(stats.Kick, sc.network, sc.channel, nick, date, target)
-	} else if r = findData(sc.parser.mode, line); r != nil {
-		nick, dateString, mode := r["nick"], r["date"], r["mode"]
+	if len(addr) == 0 {
+		return serve(s, func(stop <-chan struct{}) error {
+			return st.ReadJournal(os.Stdin)
+		})
+	}
 
-		if len(nick) == 0 || len(dateString) == 0 || len(mode) == 0 {
-			return
-		}
+	u, err := url.Parse(addr)
+	if err != nil {
+		return err
+	}
 
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
+	var closer io.Closer
+	var run func() error
+	switch u.Scheme {
+	case "udp":
+		conn, err := net.ListenPacket("udp", u.Host)
 		if err != nil {
-			return
+			return err
 		}
-
-		s.AddMessage(stats.Mode, sc.network, sc.channel, nick, date, mode)
-	} else if r = findData(sc.parser.topic, line); r != nil {
-		nick, dateString, topic := r["nick"], r["date"], r["topic"]
-
-		if len(nick) == 0 || len(dateString) == 0 || len(topic) == 0 {
-			return
-		}
-
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
+		closer, run = conn, func() error { return st.ServeSyslog(conn) }
+	case "tcp":
+		l, err := net.Listen("tcp", u.Host)
 		if err != nil {
-			return
-		}
-
-		s.AddMessage(stats.Topic, sc.network, sc.channel, nick, date, topic)
-	} else if r = findData(sc.parser.action, line); r != nil {
-		nick, dateString, action := r["nick"], r["date"], r["action"]
-
-		if len(nick) == 0 || len(dateString) == 0 || len(action) == 0 {
-			return
+			return err
 		}
+		closer, run = l, func() error { return st.ServeSyslogStream(l) }
+	default:
+		return fmt.Errorf("Unknown syslog protocol: %s", u.Scheme)
+	}
 
-		date, err := time.Parse(sc.parser.dateFormat, dateString)
-		if err != nil {
-			return
+	return serve(s, func(stop <-chan struct{}) error {
+		go func() {
+			<-stop
+			closer.Close()
+		}()
+
+		if err := run(); err != nil {
+			select {
+			case <-stop:
+				return nil
+			default:
+				return err
+			}
 		}
-
-		s.AddMessage(stats.Action, sc.network, sc.channel, nick, date, action)
-	}
+		return nil
+	})
 }
 
-func findIndex(haystack []string, needle string) int {
-	for i, v := range haystack {
-		if needle == v {
-			return i
+// serve runs until interrupted or run returns, saving the stats every few
+// minutes and when done.
+func serve(s *stats.Stats, run func(stop <-chan struct{}) error) error {
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	go func() { done <- run(stop) }()
+
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	for {
+		select {
+		case <-ticker.C:
+			save(s)
+		case <-signals:
+			close(stop)
+			save(s)
+			return nil
+		case err := <-done:
+			save(s)
+			return err
 		}
 	}
-
-	return -1
 }
 
-func findData(regex *regexp.Regexp, line string) map[string]string {
-	results := make(map[string]string)
-
-	r := regex.FindStringSubmatch(line)
-
-	if r == nil {
-		return nil
-	}
-
-	names := regex.SubexpNames()
-
-	for i, n := range names[1:] {
-		results[n] = r[i+1]
+func save(s *stats.Stats) {
+	if err := s.Save(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
 	}
+}
 
-	if host := results["host"]; len(host) > 0 {
-		results["nick"] += "!" + host
+// reportProgress prints the progress of an import to standard error.
+func reportProgress(p importer.Progress) {
+	if p.Done {
+		fmt.Fprintf(os.Stderr, "%s: %d lines, %d parsed, %d already imported\n", p.Source, p.Lines, p.Parsed, p.Duplicates)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: %d lines (%d bytes)\n", p.Source, p.Lines, p.Bytes)
 	}
-
-	return results
 }
@@ -7,6 +7,38 @@ import (
 	"github.com/DylanJ/stats"
 )
 
+// sunk keeps the messages the stats count.
+type sunk []stats.SinkMessage
+
+func (s *sunk) Sink(m stats.SinkMessage) {
+	*s = append(*s, m)
+}
+
+// newStats creates stats keeping the messages they count.
+func newStats(t testing.TB) (*stats.Stats, *sunk) {
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := &sunk{}
+	s.SetOptions(stats.Options{Sink: messages})
+	return s, messages
+}
+
+// parse parses a line with a weechat scanner, returning the messages counted.
+func parse(t *testing.T, line string) (*stats.Stats, sunk) {
+	s, messages := newStats(t)
+
+	sc, err := newScanner("network", "#deviate", "weechat", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sc.parseLine(s, line)
+	return s, *messages
+}
+
 const (
 	weechatJoin        = `2013-08-07 16:49:40	-->	dylan (dylan@zqz.ca) has joined #deviate`
 	weechatQuit        = `2013-08-07 16:52:04	<--	knivey (knivey@zkpq-5EEAFC38.dhcp.embarqhsd.net) has quit (Ping timeout: 181 seconds)`
@@ -22,7 +54,7 @@ const weechatFile = weechatJoin + "\n" + weechatQuit + "\n" +
 	weechatPartMessage + "\n"
 
 func Benchmark_parseLine(b *testing.B) {
-	s := stats.NewStats()
+	s, _ := newStats(b)
 
 	sc, err := newScanner("network", "#deviate", "weechat", "file")
 	if err != nil {
@@ -36,130 +68,75 @@ func Benchmark_parseLine(b *testing.B) {
 
 func TestScanner_parseLine_Quit(t *testing.T) {
 	t.Parallel()
-	s := stats.NewStats()
 
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
+	_, messages := parse(t, weechatQuit)
+	if len(messages) != 1 {
+		t.Fatal("Should count the quit:", messages)
 	}
 
-	sc.parseLine(s, weechatQuit)
-
-	var m *stats.Message
-	if m = s.Messages[1]; m == nil {
-		t.Error("Should be able to get first message from stats.")
+	if m := messages[0]; m.Kind != stats.Quit || m.Nick != "knivey" {
+		t.Error("Kind should be Quit MsgKind:", m.Kind, m.Nick)
 	}
-
-	if m.Kind != stats.Quit {
-		t.Error("Kind should be Quit MsgKind.")
-	}
-
-	if m.ChannelID != 0 {
-		t.Error("ChannelID should be 0")
+	if m := messages[0]; m.Channel != "" || m.ChannelStats != nil {
+		t.Error("Quits should have no channel:", m.Channel)
 	}
 }
 
 func TestScanner_parseLine_Action(t *testing.T) {
 	t.Parallel()
 
-	s := stats.NewStats()
-
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sc.parseLine(s, weechatAction)
-
-	if len(s.Messages) > 0 {
-		t.Error("It should ignore action messages (for now)")
+	if _, messages := parse(t, weechatAction); len(messages) != 0 {
+		t.Error("It should ignore action messages (for now):", messages)
 	}
 }
 
 func TestScanner_parseLine_Topic(t *testing.T) {
 	t.Parallel()
 
-	s := stats.NewStats()
-
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sc.parseLine(s, weechatTopic)
-
-	if len(s.Messages) > 0 {
-		t.Error("It should ignore topic messages (for now)")
+	if _, messages := parse(t, weechatTopic); len(messages) != 0 {
+		t.Error("It should ignore when the topic was set:", messages)
 	}
 }
 
 func TestScanner_parseLine_Message(t *testing.T) {
 	t.Parallel()
 
-	s := stats.NewStats()
-
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sc.parseLine(s, weechatMessage)
-
-	var m *stats.Message
-	if m = s.Messages[1]; m == nil {
-		t.Error("Should be able to get first message from stats.")
+	_, messages := parse(t, weechatMessage)
+	if len(messages) != 1 {
+		t.Fatal("Should count the message:", messages)
 	}
 
-	if m.Kind != stats.Msg {
-		t.Error("Kind should be Msg MsgKind.")
+	if m := messages[0]; m.Kind != stats.Msg || m.Nick != "Aaron" || m.Message != "dylan: Auth with my bot for +v" {
+		t.Error("Kind should be Msg MsgKind:", m.Kind, m.Nick, m.Message)
 	}
 }
 
 func TestScanner_parseLine_PartWithMessage(t *testing.T) {
 	t.Parallel()
 
-	s := stats.NewStats()
-
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
+	_, messages := parse(t, weechatPartMessage)
+	if len(messages) != 1 {
+		t.Fatal("Should count the part:", messages)
 	}
 
-	sc.parseLine(s, weechatPartMessage)
-
-	var m *stats.Message
-	if m = s.Messages[1]; m == nil {
-		t.Error("Should be able to get first message from stats.")
+	if m := messages[0]; m.Message != "peace out" {
+		t.Error("Should have part message inside Message:", m.Message)
 	}
-
-	if m.Message != "peace out" {
-		t.Error("Should have part message inside Message.")
-	}
-
-	if m.Kind != stats.Part {
-		t.Error("Kind should be Part MsgKind.")
+	if m := messages[0]; m.Kind != stats.Part {
+		t.Error("Kind should be Part MsgKind:", m.Kind)
 	}
 }
 
 func TestScanner_parseLine_Join(t *testing.T) {
 	t.Parallel()
 
-	s := stats.NewStats()
-
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
+	_, messages := parse(t, weechatJoin)
+	if len(messages) != 1 {
+		t.Fatal("Should count the join:", messages)
 	}
 
-	sc.parseLine(s, weechatJoin)
-
-	var m *stats.Message
-	if m = s.Messages[1]; m == nil {
-		t.Error("Should be able to get first message from stats.")
-	}
-
-	if m.Kind != stats.Join {
-		t.Error("Kind should be Join MsgKind.")
+	if m := messages[0]; m.Kind != stats.Join {
+		t.Error("Kind should be Join MsgKind:", m.Kind)
 	}
 }
 
@@ -168,10 +145,10 @@ func TestScanner_NewScanner(t *testing.T) {
 
 	var s *scanner
 	var e error
-	if s, e = newScanner("network", "#deviate", "weechat", "file"); e != nil {
+	if s, e = newScanner("foo", "bar", "weechat", "file"); e != nil {
 		t.Fatal(e)
 	} else if s == nil {
-		t.Error("Should return weechat scanner.")
+		t.Fatal("Should return weechat scanner.")
 	}
 
 	if s.filenames[0] != "file" {
@@ -185,6 +162,10 @@ func TestScanner_NewScanner(t *testing.T) {
 	if s.channel != "bar" {
 		t.Error(`Should set channel to "bar"`)
 	}
+
+	if _, e = newScanner("foo", "bar", "no such parser", "file"); e == nil {
+		t.Error("Should fail without a parser.")
+	}
 }
 
 func TestScanner_ParseReader(t *testing.T) {
@@ -197,41 +178,32 @@ func TestScanner_ParseReader(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	var s = stats.NewStats()
-	var n *stats.Network
-
+	s, messages := newStats(t)
 	if err = sc.parseReader(s, reader); err != nil {
 		t.Fatal("Error parsing stats:", err)
 	}
 
-	if n = s.GetNetwork("test_network"); n == nil {
+	if n := s.GetNetwork("network"); n == nil {
 		t.Error("Should be able to get network.")
 	}
 
-	if c := s.GetChannel("test_network", "#deviate"); c == nil {
+	if c := s.GetChannel("network", "#deviate"); c == nil {
 		t.Error("Should be able to get channel.")
 	}
 
-	if u := s.GetUser("test_network", "dylan"); u == nil {
+	if u := s.GetUser("network", "dylan"); u == nil {
 		t.Error("Should be able to find user from log.")
 	}
 
-	if len(s.Messages) == 0 {
-		t.Error("Should have a message.")
+	if len(*messages) != 5 {
+		t.Error("Should count every line:", len(*messages))
 	}
 }
 
 func TestScanner_parseLine(t *testing.T) {
 	t.Parallel()
 
-	s := stats.NewStats()
-
-	sc, err := newScanner("network", "#deviate", "weechat", "file")
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	sc.parseLine(s, weechatJoin)
+	s, messages := parse(t, weechatJoin)
 
 	if n := s.GetNetwork("network"); n == nil {
 		t.Error("Stats should have the network.")
@@ -245,24 +217,20 @@ func TestScanner_parseLine(t *testing.T) {
 		t.Error("Stats should have user who joined.")
 	}
 
-	if len(s.Messages) != 1 {
-		t.Error("There should only be one message.")
-	}
-
-	var m *stats.Message
-	if m = s.Messages[1]; m == nil {
-		t.Error("Should be able to get first message from stats.")
+	if len(messages) != 1 {
+		t.Fatal("There should only be one message.")
 	}
 
+	m := messages[0]
 	if m.Date.IsZero() {
 		t.Error("Date should have been initialized.")
 	}
 
-	if m.ChannelID != s.Channels[1].ID {
-		t.Error("ChannelID should not be nil.")
+	if m.Channel != "#deviate" || m.ChannelStats != s.Channels[1] {
+		t.Error("Should count the message in the channel:", m.Channel)
 	}
 
-	if m.UserID != s.Users[1].ID {
-		t.Error("UserID should not be nil.")
+	if m.Nick != s.Users[1].Nick {
+		t.Error("Should count the message for the user:", m.Nick)
 	}
 }
@@ -0,0 +1,121 @@
+package stats
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNoRawStore is the error of searching stats that keep no messages, see
+// Options.RawStore.
+var ErrNoRawStore = errors.New("The stats keep no messages to search, there's no raw store")
+
+// defaultSearchMax is how many messages a search finds unless asked for
+// another number.
+const defaultSearchMax = 20
+
+// SearchLimits narrow down the messages a search finds.
+type SearchLimits struct {
+	// Regexp matches the query as a regular expression, it's matched as
+	// text in any case otherwise.
+	Regexp bool
+	// Since and Until bound the dates of the messages found, they're
+	// unbounded when zero.
+	Since time.Time
+	Until time.Time
+	// Nick finds only the messages of the nick, as the network folds it.
+	Nick string
+	// SkipPrefix, when set, skips the messages starting with it, such as
+	// the commands of bots.
+	SkipPrefix string
+	// Max is how many messages are found at most, the most recent ones
+	// (default 20).
+	Max int
+}
+
+// Search finds the messages said in a channel that match the query, the most
+// recent first. They're searched in the raw store, so only the messages it
+// still keeps are found, see Retention. The store is read from the start with
+// the stats unlocked, but messages being added wait on a store being read.
+// The text of the messages is matched without its formatting.
+func (s *Stats) Search(network, channel, query string, limits SearchLimits) ([]RawMessage, error) {
+	s.mut.RLock()
+	store := s.opts.RawStore
+	caseMapping := s.opts.caseMapping(network)
+	s.mut.RUnlock()
+
+	if store == nil {
+		return nil, ErrNoRawStore
+	}
+
+	match, err := searchMatcher(query, limits.Regexp)
+	if err != nil {
+		return nil, err
+	}
+
+	max := limits.Max
+	if max <= 0 {
+		max = defaultSearchMax
+	}
+	nick := caseMapping.Fold(limits.Nick)
+
+	var found []RawMessage
+	err = store.Replay(func(m RawMessage) {
+		switch {
+		case !m.Kind.isChat():
+			return
+		case !strings.EqualFold(m.Channel, channel) || !strings.EqualFold(m.Network, network):
+			return
+		case !limits.Since.IsZero() && m.Date.Before(limits.Since):
+			return
+		case !limits.Until.IsZero() && m.Date.After(limits.Until):
+			return
+		case len(limits.SkipPrefix) > 0 && strings.HasPrefix(m.Message, limits.SkipPrefix):
+			return
+		}
+		if len(nick) > 0 {
+			h, ok := ParseHostmask(m.Hostmask)
+			if !ok || caseMapping.Fold(h.Nick) != nick {
+				return
+			}
+		}
+		if !match(stripFormatting(m.Message)) {
+			return
+		}
+
+		found = append(found, m)
+		// only the most recent are kept, the store is read in order
+		if len(found) >= 2*max {
+			found = append(found[:0], found[len(found)-max:]...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(found) > max {
+		found = found[len(found)-max:]
+	}
+	for i, j := 0, len(found)-1; i < j; i, j = i+1, j-1 {
+		found[i], found[j] = found[j], found[i]
+	}
+	return found, nil
+}
+
+// searchMatcher matches the text of messages with the query, as a regular
+// expression or as text in any case.
+func searchMatcher(query string, isRegexp bool) (func(text string) bool, error) {
+	if isRegexp {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+
+	query = strings.ToLower(query)
+	return func(text string) bool {
+		return strings.Contains(strings.ToLower(text), query)
+	}, nil
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStats_Search(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	if _, err := s.Search(network, channel, "foo", SearchLimits{}); !errors.Is(err, ErrNoRawStore) {
+		t.Error("Should need a raw store:", err)
+	}
+
+	s.SetOptions(Options{RawStore: &recordingStore{}, CaseMapping: RFC1459Case})
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, hostmask, date, "the Go gopher")
+	s.AddMessage(Action, network, channel, "[aaron]", date.Add(time.Hour), "likes \x02go\x02 too")
+	s.AddMessage(Topic, network, channel, hostmask, date.Add(2*time.Hour), "go go go")
+	s.AddMessage(Msg, network, "#other", hostmask, date.Add(3*time.Hour), "go elsewhere")
+	s.AddMessage(Msg, network, channel, hostmask, date.Add(4*time.Hour), "rust")
+
+	tests := []struct {
+		query  string
+		limits SearchLimits
+		want   []string
+	}{
+		{"GO", SearchLimits{}, []string{"likes \x02go\x02 too", "the Go gopher"}},
+		{"go", SearchLimits{Max: 1}, []string{"likes \x02go\x02 too"}},
+		{"go", SearchLimits{Nick: "{AARON}"}, []string{"likes \x02go\x02 too"}},
+		{"go", SearchLimits{Until: date.Add(time.Minute)}, []string{"the Go gopher"}},
+		{"", SearchLimits{Since: date.Add(time.Minute)}, []string{"rust", "likes \x02go\x02 too"}},
+		{`^(the|rust)`, SearchLimits{Regexp: true}, []string{"rust", "the Go gopher"}},
+		{"likes go", SearchLimits{}, []string{"likes \x02go\x02 too"}},
+		{"python", SearchLimits{}, nil},
+		{"", SearchLimits{SkipPrefix: "the", Until: date.Add(time.Minute)}, nil},
+	}
+	for _, test := range tests {
+		found, err := s.Search(network, "#TEST", test.query, test.limits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(found) != len(test.want) {
+			t.Errorf("%q %+v should find %q, found %v", test.query, test.limits, test.want, found)
+			continue
+		}
+		for i, m := range found {
+			if m.Message != test.want[i] {
+				t.Errorf("%q %+v should find %q, found %q", test.query, test.limits, test.want[i], m.Message)
+			}
+		}
+	}
+
+	if _, err := s.Search(network, channel, "(", SearchLimits{Regexp: true}); err == nil {
+		t.Error("Should refuse bad regular expressions.")
+	}
+}
+
+func TestStats_SearchMostRecent(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{RawStore: &recordingStore{}})
+	date := time.Now().Add(-time.Hour)
+	for i := 0; i < 100; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, date.Add(time.Duration(i)*time.Second), "spam")
+	}
+
+	found, err := s.Search(network, channel, "spam", SearchLimits{Max: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || !found[0].Date.Equal(date.Add(99*time.Second)) || !found[2].Date.Equal(date.Add(97*time.Second)) {
+		t.Error("Should find the most recent messages first:", found)
+	}
+}
@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// SentimentAnalyzer scores the mood of a message. Score should return a
+// value between -1 (negative) and 1 (positive), with 0 being neutral.
+type SentimentAnalyzer interface {
+	Score(message string) float64
+}
+
+var defaultLexicon = map[string]float64{
+	"good": 1, "great": 1, "awesome": 1, "love": 1, "nice": 1, "cool": 1,
+	"thanks": 1, "thank": 1, "happy": 1, "lol": 0.5, "haha": 0.5, "yay": 1,
+	"excellent": 1, "fun": 1, "win": 1, "best": 1, "like": 0.5, ":)": 1,
+	":D": 1, ":-)": 1, ";)": 0.5, "XD": 1,
+
+	"bad": -1, "terrible": -1, "awful": -1, "hate": -1, "sad": -1,
+	"sucks": -1, "suck": -1, "broken": -1, "worst": -1, "angry": -1,
+	"ugh": -1, "annoying": -1, "fail": -1, "wrong": -0.5, "sorry": -0.5,
+	":(": -1, ":-(": -1, ":'(": -1, ":<": -1,
+}
+
+var negations = map[string]struct{}{
+	"not":   struct{}{},
+	"no":    struct{}{},
+	"never": struct{}{},
+	"don't": struct{}{},
+	"isn't": struct{}{},
+	"dont":  struct{}{},
+	"isnt":  struct{}{},
+}
+
+// LexiconAnalyzer is a simple SentimentAnalyzer that averages the scores of
+// the words it knows, flipping the score of a word preceded by a negation.
+type LexiconAnalyzer struct {
+	Lexicon map[string]float64
+}
+
+// NewLexiconAnalyzer creates a LexiconAnalyzer using the built in lexicon.
+func NewLexiconAnalyzer() *LexiconAnalyzer {
+	return &LexiconAnalyzer{Lexicon: defaultLexicon}
+}
+
+// Score returns the average score of the known words in the message.
+func (l *LexiconAnalyzer) Score(message string) float64 {
+	var total float64
+	var matches int
+	negate := false
+
+	for _, word := range strings.Fields(message) {
+		score, ok := l.Lexicon[word]
+		if !ok {
+			word = strings.ToLower(strings.Trim(word, ".,:;!?\"'()"))
+			score, ok = l.Lexicon[word]
+		}
+
+		if ok {
+			if negate {
+				score = -score
+			}
+			total += score
+			matches++
+		}
+
+		_, negate = negations[word]
+	}
+
+	if matches == 0 {
+		return 0
+	}
+
+	return total / float64(matches)
+}
+
+// MoodDay is the accumulated sentiment of a single day.
+type MoodDay struct {
+	Total    float64
+	Messages uint
+}
+
+// Mood returns the average sentiment of the day.
+func (m MoodDay) Mood() float64 {
+	if m.Messages == 0 {
+		return 0
+	}
+
+	return m.Total / float64(m.Messages)
+}
+
+// MoodPoint is a single point in a mood trend.
+type MoodPoint struct {
+	Day  string  `json:"day"`
+	Mood float64 `json:"mood"`
+}
+
+// MoodSeries keeps the sentiment of messages bucketed by day.
+type MoodSeries map[string]MoodDay
+
+func (m MoodSeries) addScore(date time.Time, score float64) {
+	day := date.Format(dayFormat)
+
+	d := m[day]
+	d.Total += score
+	d.Messages++
+	m[day] = d
+}
+
+// Trend returns the average mood of every day in chronological order.
+func (m MoodSeries) Trend() []MoodPoint {
+	points := make([]MoodPoint, 0, len(m))
+
+	for day, d := range m {
+		points = append(points, MoodPoint{Day: day, Mood: d.Mood()})
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		return points[i].Day < points[j].Day
+	})
+
+	return points
+}
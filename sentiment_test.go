@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLexiconAnalyzer(t *testing.T) {
+	t.Parallel()
+
+	a := NewLexiconAnalyzer()
+
+	if score := a.Score("this is great, thanks!"); score != 1 {
+		t.Errorf("Expected: 1, Got: %v", score)
+	}
+
+	if score := a.Score("this is not good"); score != -1 {
+		t.Errorf("Should negate the score, Got: %v", score)
+	}
+
+	if score := a.Score("great but broken :("); score >= 0 {
+		t.Errorf("Should be negative, Got: %v", score)
+	}
+
+	if score := a.Score("the sky is blue"); score != 0 {
+		t.Errorf("Should be neutral, Got: %v", score)
+	}
+}
+
+func TestMoodSeries(t *testing.T) {
+	t.Parallel()
+
+	m := make(MoodSeries)
+	day1 := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.Add(24 * time.Hour)
+
+	m.addScore(day2, -1)
+	m.addScore(day1, 1)
+	m.addScore(day1, 0)
+
+	trend := m.Trend()
+
+	if len(trend) != 2 {
+		t.Fatal("Should have two days.")
+	}
+
+	if trend[0].Day != "2014-01-01" || trend[0].Mood != 0.5 {
+		t.Error("First day is incorrect:", trend[0])
+	}
+
+	if trend[1].Day != "2014-01-02" || trend[1].Mood != -1 {
+		t.Error("Second day is incorrect:", trend[1])
+	}
+}
+
+func TestStats_Sentiment(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{SentimentAnalyzer: NewLexiconAnalyzer()})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "i love this")
+	s.AddMessage(Join, network, channel, hostmask, time.Now(), "")
+
+	if trend := s.Channels[1].Mood.Trend(); len(trend) != 1 || trend[0].Mood != 1 {
+		t.Error("Channel should have a positive mood.")
+	}
+
+	if trend := s.Users[1].Mood.Trend(); len(trend) != 1 || trend[0].Mood != 1 {
+		t.Error("User should have a positive mood.")
+	}
+
+	if d := s.Users[1].ChannelUsers[channel].Mood; len(d) != 1 {
+		t.Error("Channel user should have a mood.")
+	}
+}
@@ -1,12 +1,15 @@
 package stats
 
 import (
+	"bufio"
+	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/gob"
 	"fmt"
-	"log"
 	"math/rand"
 	"os"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -20,6 +23,9 @@ func init() {
 
 var fileOpener FileOpener = osFileOpener{}
 
+// defaultPath is the database of NewStats.
+const defaultPath = "data.db"
+
 type Stats struct {
 	Channels map[uint]*Channel
 	Networks map[uint]*Network
@@ -32,23 +38,88 @@ type Stats struct {
 	ChannelIDCount uint
 	UserIDCount    uint
 
-	mut sync.RWMutex
+	// Imports are the ranges of logs already imported for each channel.
+	Imports map[string]ImportRanges
+	// Marks date the message ids so that old ones can be pruned.
+	Marks []IDMark
+	// PrunedBefore is the oldest message id kept, channels that weren't
+	// loaded when the messages were pruned drop theirs once loaded.
+	PrunedBefore uint
+	// PrunedDates are the cutoffs Prune last pruned by, channels that
+	// weren't loaded prune what's dated before them once loaded.
+	PrunedDates Cutoffs
+	// OptOuts are when the users who opted out did, by network and folded
+	// nick apart by a space, see OptOut.
+	OptOuts map[string]time.Time
+
+	opts Options
+	// path is the database the stats are loaded from and saved to.
+	path string
+	mut  sync.RWMutex
+	// shared guards what the networks share while messages are added to
+	// several of them at once under lockNetwork: the id counters, the maps
+	// by id, the raw store, the processors and the sink.
+	shared sync.Mutex
+	// saveMut keeps saves from writing over each other, snapshotSize is
+	// the size of the last snapshot to allocate the next one at once.
+	saveMut      sync.Mutex
+	snapshotSize int
+	// throttleMut guards when the stats were last saved, the timer of the
+	// save coalesced for later and the error of the last one that failed,
+	// see MinSaveInterval.
+	throttleMut sync.Mutex
+	lastSave    time.Time
+	saveTimer   *time.Timer
+	saveErr     error
+
+	// repaired are the problems Validate repaired when data.db was loaded.
+	repaired []error
+
+	subs    subscribers
+	metrics metrics
+}
+
+// NewStats loads the stats from data.db, empty stats are created when there's
+// no database yet. A database that can't be read is an error rather than
+// empty stats, saving those would write over it.
+func NewStats() (*Stats, error) {
+	return NewStatsContext(context.Background())
 }
 
-// NewStats initializes a Stats struct.
-func NewStats() *Stats {
-	s, err := loadDatabase()
+// NewStatsContext is NewStats, giving up loading data.db once the context is
+// done with its error.
+func NewStatsContext(ctx context.Context) (*Stats, error) {
+	return OpenStatsContext(ctx, defaultPath)
+}
 
+// OpenStats is NewStats with the database at path instead of data.db, the
+// stats are saved to it.
+func OpenStats(path string) (*Stats, error) {
+	return OpenStatsContext(context.Background(), path)
+}
+
+// OpenStatsContext is OpenStats, giving up loading the database once the
+// context is done with its error.
+func OpenStatsContext(ctx context.Context, path string) (*Stats, error) {
+	s, err := loadDatabase(ctx, path)
 	if err != nil {
-		fmt.Printf("Error'd: %v\n", err)
-		return nil
+		return nil, fmt.Errorf("Failed loading %s: %w", path, err)
 	}
 
-	if s != nil {
-		return s
+	if s == nil {
+		s = newStats()
 	}
+	s.path = path
+	return s, nil
+}
+
+// Path is the database the stats are saved to.
+func (s *Stats) Path() string {
+	return s.path
+}
 
-	// load from stats.db
+// newStats creates empty stats.
+func newStats() *Stats {
 	return &Stats{
 		Channels: make(map[uint]*Channel),
 		Networks: make(map[uint]*Network),
@@ -60,61 +131,228 @@ func NewStats() *Stats {
 		MessageIDCount: 1,
 		ChannelIDCount: 1,
 		UserIDCount:    1,
+
+		Imports: make(map[string]ImportRanges),
+		OptOuts: make(map[string]time.Time),
+
+		path: defaultPath,
 	}
 }
 
-// GetNetwork retrieves a network by its name return nil if not found
+// GetNetwork retrieves a network by its name return nil if not found. The
+// network keeps changing as messages are added, read it inside of View.
 func (s *Stats) GetNetwork(network string) *Network {
-	return s.networkByName[network]
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.lookupNetwork(network)
 }
 
-// GetChannel retrieves a channel from the specified network by name
+// GetChannel retrieves a channel from the specified network by name. The
+// channel keeps changing as messages are added, read it inside of View or
+// copy it with ChannelSummary. It's nil if the channel failed to load, see
+// LoadErrors.
 func (s *Stats) GetChannel(network, channel string) *Channel {
-	if n := s.GetNetwork(network); n != nil {
-		return n.channels[channel]
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	n := s.lookupNetwork(network)
+	if n == nil {
+		return nil
 	}
+	n.mut.RLock()
+	defer n.mut.RUnlock()
 
-	return nil
+	return n.channels[strings.ToLower(channel)].load(s)
 }
 
-// GetUser retrieves a user from the specified network by name
+// GetUser retrieves a user from the specified network by name. The user keeps
+// changing as messages are added, read it inside of View or copy it with
+// UserSummary.
 func (s *Stats) GetUser(network, nick string) *User {
-	if n := s.GetNetwork(network); n != nil {
-		return n.users[nick]
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	n := s.lookupNetwork(network)
+	if n == nil {
+		return nil
+	}
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	return n.users[n.fold(nick)]
+}
+
+// lookupNetwork finds a network by its name in any case, as the channels and
+// users of lookupChannel and lookupUser are.
+func (s *Stats) lookupNetwork(network string) *Network {
+	return s.networkByName[strings.ToLower(network)]
+}
+
+func (s *Stats) lookupChannel(network, channel string) *Channel {
+	if n := s.lookupNetwork(network); n != nil {
+		return n.channels[strings.ToLower(channel)].load(s)
 	}
 
 	return nil
 }
 
-// AddMessage adds a message to the stats.
+func (s *Stats) lookupUser(network, nick string) *User {
+	if n := s.lookupNetwork(network); n != nil {
+		return n.users[n.fold(nick)]
+	}
+
+	return nil
+}
+
+// AddMessage adds a message to the stats. Messages of different networks are
+// added concurrently.
 func (s *Stats) AddMessage(kind MsgKind, network string, channel string, hostmask string, date time.Time, message string) {
+	raw := RawMessage{
+		Kind:     kind,
+		Network:  network,
+		Channel:  channel,
+		Hostmask: hostmask,
+		Date:     date,
+		Message:  message,
+	}
+	n := s.lockRaw(&raw)
+	defer s.unlockNetwork(n)
+
+	s.addRawMessage(n, raw)
+}
+
+// prepare makes the text of a message UTF-8 and safe to keep, see
+// Options.Charset and MaxMessageLength.
+func (o *Options) prepare(raw *RawMessage) {
+	o.decode(raw)
+	o.sanitize(raw)
+}
+
+// addRaw prepares a message and adds it with the stats locked for writing,
+// see addMessageID.
+func (s *Stats) addRaw(raw RawMessage) bool {
+	s.opts.prepare(&raw)
+	return s.addMessageID(s.getNetwork(s.opts.aliasNetwork(raw.Network, raw.Channel)), raw)
+}
+
+// addRawMessage keeps a prepared message in the raw store and counts it in
+// n, the network its channel is counted on, unless it was already added or
+// is dated out of bounds.
+func (s *Stats) addRawMessage(n *Network, raw RawMessage) {
+	if s.optedOut(raw.Network, raw.Channel, raw.Hostmask) {
+		return
+	}
+	pseudonyms := s.opts.features(raw.Network, raw.Channel).Pseudonyms
+	if !s.opts.pseudonymize(&raw) {
+		return
+	}
+	if c, ok := s.opts.Clock.(*ReplayClock); ok {
+		c.observe(raw.Date)
+	}
+	if !s.checkDate(&raw) || s.duplicate(n, &raw) {
+		return
+	}
+
+	if s.opts.RawStore != nil && !s.opts.AggregateOnly && !s.opts.features(raw.Network, raw.Channel).NoStorage {
+		s.shared.Lock()
+		s.opts.RawStore.Append(raw)
+		s.shared.Unlock()
+	}
+
+	source := raw.Network + " " + raw.Channel
+	raw.Network, raw.Channel, _ = s.opts.alias(raw.Network, raw.Channel)
+
+	pm := ProcessedMessage{
+		Kind:     raw.Kind,
+		Network:  raw.Network,
+		Channel:  raw.Channel,
+		Hostmask: raw.Hostmask,
+		Date:     raw.Date,
+		Message:  raw.Message,
+	}
+	if !s.process(&pm) {
+		return
+	}
+	if !strings.EqualFold(pm.Network, raw.Network) {
+		// only the network of the message is locked
+		s.opts.logger().Debug("Put back the network a processor moved a message to", "network", raw.Network, "to", pm.Network)
+		pm.Network = raw.Network
+	}
+	if pm.Hostmask != raw.Hostmask && s.optedOut(raw.Network, raw.Channel, pm.Hostmask) {
+		return
+	}
+	if pseudonyms && pm.Hostmask != raw.Hostmask {
+		// the processors may credit another nick, such as a bridge's
+		pm.Hostmask = s.opts.pseudonymHostmask(pm.Network, pm.Hostmask)
+	}
+
+	// servers set modes and send notices of their own, they aren't users
+	h, ok := ParseHostmask(pm.Hostmask)
+	if !ok || h.IsServer() {
+		return
+	}
 
 	var c *Channel
 	var cu *User
 
-	n := s.getNetwork(network)
-	u := s.getUser(n, hostmask)
+	u := s.getUser(n, h.Nick)
+	u.seenAs(h)
 
 	// channel can be blank (for example a QUIT message has no channel)
-	if channel != "" {
-		c = s.getChannel(n, channel)
-		cu = s.getChannelUser(u, channel)
+	if pm.Channel != "" {
+		// a channel that failed to load can't count its messages
+		if c = s.getChannel(n, pm.Channel); c == nil {
+			return
+		}
+		cu = s.getChannelUser(u, pm.Channel)
+		s.addSource(c, n.Name, source)
 	}
 
-	s.addMessage(kind, n, c, u, cu, date, message)
+	m := s.addMessage(pm.Kind, n, c, u, cu, pm.Date, pm.Message)
+	s.counted(pm)
+	s.sinkMessage(n, c, u, m, pm)
 }
 
+// addMessage counts a message. The message returned is the network's, it's
+// only valid until the next message is added to the network.
 func (s *Stats) addMessage(k MsgKind, n *Network, c *Channel, u *User, cu *User, d time.Time, m string) *Message {
+	s.shared.Lock()
 	id := s.MessageIDCount
 	s.MessageIDCount++
+	s.mark(id, d)
+	s.shared.Unlock()
+	s.metrics.added(time.Now())
 
-	message := &Message{
+	d = d.UTC()
+	var channel string
+	if c != nil {
+		channel = c.Name
+	}
+	message := &n.message
+	*message = Message{
 		ID:        id,
 		Date:      d,
 		UserID:    u.ID,
 		ChannelID: 0,
 		Message:   m,
 		Kind:      k,
+		features:  s.opts.features(n.Name, channel),
+	}
+	message.split = n.tokenize(&n.split, m)
+
+	// messages played back from the past can't take part in aggregates
+	// that depend on the order of messages, such as bursts and reactions
+	late := d.Before(n.LastActive)
+	if c != nil {
+		late = d.Before(c.LastActive)
+	}
+
+	// corrections of messages played back late would rewrite newer ones
+	if k == Msg && !late {
+		if corr := parseCorrection(m); corr != nil {
+			s.addCorrection(n, c, u, cu, corr)
+		}
 	}
 
 	if c != nil {
@@ -133,19 +371,90 @@ func (s *Stats) addMessage(k MsgKind, n *Network, c *Channel, u *User, cu *User,
 		}
 	}
 
+	subscribed := s.subscribed()
+	if subscribed && u.MessageCount == 0 {
+		s.publish(StatEvent{Kind: NewUserEvent, Network: n.Name, Channel: channel, Nick: u.Nick, Date: d})
+	}
+
 	n.addMessage(message)
 	u.addMessage(n, c, message)
 
+	if k.isChat() && !late {
+		s.addBurst(c, u, cu, message)
+
+		if c != nil {
+			s.addStarter(c, u, cu, message)
+			author := s.addReaction(c, u, cu, message)
+
+			if subscribed {
+				s.publishRecord(n, c, message)
+				if author != nil {
+					s.publish(StatEvent{
+						Kind:    ReactionEvent,
+						Network: n.Name,
+						Channel: c.Name,
+						Nick:    author.Nick,
+						From:    u.Nick,
+						Date:    d,
+						Count:   author.Reactions.Received,
+					})
+				}
+			}
+		}
+	}
+
+	if k == Msg && s.opts.LanguageDetector != nil {
+		s.addLanguage(c, u, cu, s.opts.LanguageDetector.Detect(m))
+	}
+
+	if k == Msg && s.opts.SentimentAnalyzer != nil {
+		s.addMood(c, u, cu, d, s.opts.SentimentAnalyzer.Score(m))
+	}
+
+	message.forgetTokens()
+
 	return message
 }
 
+// addLanguage credits a detected language to the channel and users.
+func (s *Stats) addLanguage(c *Channel, u *User, cu *User, lang string) {
+	u.LanguageCounter.addLanguage(lang)
+
+	if c != nil {
+		c.LanguageCounter.addLanguage(lang)
+	}
+
+	if cu != nil {
+		cu.LanguageCounter.addLanguage(lang)
+	}
+}
+
+// addMood adds a sentiment score to the mood of the channel and users.
+func (s *Stats) addMood(c *Channel, u *User, cu *User, d time.Time, score float64) {
+	u.Mood.addScore(d, score)
+
+	if c != nil {
+		c.Mood.addScore(d, score)
+	}
+
+	if cu != nil {
+		cu.Mood.addScore(d, score)
+	}
+}
+
 func (s *Stats) addChannel(n *Network, name string) *Channel {
+	s.shared.Lock()
 	id := s.ChannelIDCount
 	s.ChannelIDCount++
+	s.shared.Unlock()
 
-	c := newChannel(id, n, name)
+	c := newChannel(id, n, n.strings.intern(name))
+	c.URLCounter.bound(s.opts.TopK)
+	c.WordCounter.bound(s.opts.TopK)
 
+	s.shared.Lock()
 	s.Channels[c.ID] = c
+	s.shared.Unlock()
 
 	n.addChannel(c)
 
@@ -153,32 +462,49 @@ func (s *Stats) addChannel(n *Network, name string) *Channel {
 }
 
 func (s *Stats) addUser(n *Network, nick string) *User {
+	s.shared.Lock()
 	id := s.UserIDCount
 	s.UserIDCount++
+	s.shared.Unlock()
 
-	u := NewUser(id, n.ID, nick)
+	u := NewUser(id, n.ID, n.strings.intern(nick))
+	u.WordCounter.bound(s.opts.TopK)
 
+	s.shared.Lock()
 	s.Users[id] = u
+	s.shared.Unlock()
 
 	n.addUser(u)
 
 	return u
 }
 
+// user finds a user by id while a message is being added, other networks
+// may be adding users at the same time.
+func (s *Stats) user(id uint) (*User, bool) {
+	s.shared.Lock()
+	defer s.shared.Unlock()
+
+	u, ok := s.Users[id]
+	return u, ok
+}
+
 // getChannelUser
 func (s *Stats) getChannelUser(user *User, channel string) *User {
 	channel = strings.ToLower(channel)
 	if cu, ok := user.ChannelUsers[channel]; ok {
 		return cu
 	} else {
-		return user.addChannelUser(channel)
+		cu = user.addChannelUser(channel)
+		cu.WordCounter.bound(s.opts.TopK)
+		return cu
 	}
 }
 
 func (s *Stats) getUser(n *Network, nameOrHost string) *User {
 	nick := irc.Nick(nameOrHost)
 
-	if u, ok := n.users[strings.ToLower(nick)]; ok {
+	if u, ok := n.users[n.fold(nick)]; ok {
 		return u
 	} else {
 		return s.addUser(n, nick)
@@ -187,7 +513,7 @@ func (s *Stats) getUser(n *Network, nameOrHost string) *User {
 
 func (s *Stats) getChannel(n *Network, name string) *Channel {
 	if c, ok := n.channels[strings.ToLower(name)]; ok {
-		return c
+		return c.load(s)
 	} else {
 		return s.addChannel(n, name)
 	}
@@ -211,7 +537,6 @@ func (s *Stats) addNetwork(name string) *Network {
 		stats:       s,
 		ChannelIDs:  make([]uint, 0),
 		UserIDs:     make([]uint, 0),
-		MessageIDs:  make([]uint, 0),
 		URLCounter:  NewURLCounter(),
 		WordCounter: NewWordCounter(),
 
@@ -219,85 +544,418 @@ func (s *Stats) addNetwork(name string) *Network {
 		users:    make(map[string]*User),
 	}
 
+	n.URLCounter.bound(s.opts.TopK)
+	n.WordCounter.bound(s.opts.TopK)
+
 	s.Networks[id] = n
 	s.networkByName[strings.ToLower(name)] = n
 
 	return n
 }
 
-// Save writes the statistics to data.db.
-func (s *Stats) Save() bool {
-	f, _ := fileOpener.Create("data.db")
-	defer f.Close()
+// Save writes the statistics to their database, data.db unless opened with
+// OpenStats. The stats are only locked while they're cloned, messages are
+// added again while the clone is encoded, compressed and written.
+//
+// With a MinSaveInterval the saves asked for within the interval of the last
+// one are coalesced into a single save at the end of the interval. Save
+// returns at once for them, with the error of the last coalesced save if it
+// failed. The error is returned once, by the next Save or SaveNow.
+func (s *Stats) Save() error {
+	return s.SaveContext(context.Background())
+}
 
-	gz := gzip.NewWriter(f)
-	defer gz.Close()
+// SaveContext is Save, giving up once the context is done. A save that's
+// given up doesn't write the database, the one written last is kept whole.
+func (s *Stats) SaveContext(ctx context.Context) error {
+	if s.deferSave() {
+		return s.coalescedErr()
+	}
 
-	enc := gob.NewEncoder(gz)
-	err := enc.Encode(s)
+	return s.SaveNowContext(ctx)
+}
+
+// SaveNow writes the statistics to their database right away whatever the
+// MinSaveInterval, for the last save before exiting. A save coalesced for
+// later is written along with it. When the save is written but the last
+// coalesced save failed, that error is returned.
+func (s *Stats) SaveNow() error {
+	return s.SaveNowContext(context.Background())
+}
+
+// SaveNowContext is SaveNow, giving up once the context is done as
+// SaveContext does.
+func (s *Stats) SaveNowContext(ctx context.Context) error {
+	if err := s.save(ctx); err != nil {
+		s.coalescedErr()
+		return err
+	}
+	if err := s.coalescedErr(); err != nil {
+		return fmt.Errorf("A coalesced save failed: %w", err)
+	}
+
+	return nil
+}
 
+// coalescedErr returns the error of the last coalesced save and forgets it.
+func (s *Stats) coalescedErr() error {
+	s.throttleMut.Lock()
+	defer s.throttleMut.Unlock()
+
+	err := s.saveErr
+	s.saveErr = nil
+	return err
+}
+
+// save writes the statistics to data.db, unless the context is done before
+// it's written. Once data.db is being written it's written whole.
+func (s *Stats) save(ctx context.Context) error {
+	s.saveMut.Lock()
+	defer s.saveMut.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	s.mut.RLock()
+	logger := s.opts.logger()
+	s.mut.RUnlock()
+
+	s.throttleMut.Lock()
+	if s.saveTimer != nil {
+		s.saveTimer.Stop()
+		s.saveTimer = nil
+	}
+	s.lastSave = time.Now()
+	s.throttleMut.Unlock()
+
+	snapshot, err := s.snapshot()
+	locked := time.Since(start)
+	var b []byte
+	if err == nil {
+		b, err = snapshot.encode(s.snapshotSize)
+	}
+	if err != nil {
+		s.metrics.saved(time.Since(start), locked, 0, err)
+		return fmt.Errorf("Failed encoding the stats: %w", err)
+	}
+	s.snapshotSize = len(b)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	n, err := writeDatabase(s.path, b)
+	s.metrics.saved(time.Since(start), locked, n, err)
 	if err != nil {
-		log.Fatal("encode error:", err)
+		return fmt.Errorf("Failed saving %s: %w", s.path, err)
+	}
+	logger.Debug("Saved data.db", "duration", time.Since(start), "locked", locked, "bytes", n, "path", s.path)
+
+	return nil
+}
+
+// writeDatabase compresses the snapshot into the database at path, returning
+// the size it was written in.
+func writeDatabase(path string, snapshot []byte) (int64, error) {
+	f, err := fileOpener.Create(path)
+	if err != nil {
+		return 0, err
+	}
+
+	w := &countingWriter{w: f}
+	gz := gzip.NewWriter(w)
+	if _, err = gz.Write(snapshot); err == nil {
+		err = gz.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return w.n, err
+}
+
+// deferSave checks if a save must wait for the end of the MinSaveInterval,
+// scheduling it unless it already is.
+func (s *Stats) deferSave() bool {
+	s.mut.RLock()
+	interval := s.opts.MinSaveInterval
+	s.mut.RUnlock()
+
+	if interval <= 0 {
 		return false
 	}
 
+	s.throttleMut.Lock()
+	defer s.throttleMut.Unlock()
+
+	if s.saveTimer != nil {
+		return true
+	}
+
+	wait := interval - time.Since(s.lastSave)
+	if wait <= 0 {
+		return false
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(wait, func() {
+		// SaveNow may have written it meanwhile
+		s.throttleMut.Lock()
+		pending := s.saveTimer == timer
+		s.throttleMut.Unlock()
+
+		if pending {
+			if err := s.save(context.Background()); err != nil {
+				s.mut.RLock()
+				logger := s.opts.logger()
+				s.mut.RUnlock()
+				logger.Error("A coalesced save failed", "err", err)
+				s.throttleMut.Lock()
+				s.saveErr = err
+				s.throttleMut.Unlock()
+			}
+		}
+	})
+	s.saveTimer = timer
 	return true
 }
 
-// buildIndexes builds the internal maps that relate data
+// snapshot clones the stats as they are, with them locked for reading. The
+// channels are all loaded first when the stats are saved as json, see
+// encodeCanonical.
+func (s *Stats) snapshot() (*Stats, error) {
+	s.rlock()
+	defer s.runlock()
+
+	if s.opts.Canonical {
+		if err := s.loadChannels(); err != nil {
+			return nil, err
+		}
+	}
+	return s.clone(), nil
+}
+
+// encode encodes a snapshot, in a buffer of the size of the last one.
+func (s *Stats) encode(size int) ([]byte, error) {
+	var b bytes.Buffer
+	b.Grow(size)
+
+	var err error
+	if s.opts.Canonical {
+		err = s.encodeCanonical(&b)
+	} else {
+		err = gob.NewEncoder(&b).Encode(s)
+	}
+	return b.Bytes(), err
+}
+
+// buildIndexes builds the internal maps that relate data, the networks are
+// found by their names in lower case as they're looked up.
 func (s *Stats) buildIndexes() {
-	s.networkByName = make(map[string]*Network)
+	s.networkByName = make(map[string]*Network, len(s.Networks))
 
+	networks := make(chan *Network, len(s.Networks))
 	for _, n := range s.Networks {
-		s.networkByName[n.Name] = n
-		n.buildIndexes(s)
+		s.networkByName[strings.ToLower(n.Name)] = n
+		networks <- n
 	}
+	close(networks)
+
+	// the networks share nothing but the maps by id, which are only read
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(s.Networks) {
+		workers = len(s.Networks)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for n := range networks {
+				n.buildIndexes(s)
+				n.migrateMessageIDs()
+			}
+		}()
+	}
+	wg.Wait()
 }
 
-// loadDatabase reads data.db and populates a Stats struct.
-func loadDatabase() (*Stats, error) {
-	file, err := fileOpener.Open("./data.db")
+// loadDatabase reads the database at path and populates a Stats struct, until
+// the context is done.
+func loadDatabase(ctx context.Context, path string) (*Stats, error) {
+	stats, err := readDatabase(ctx, path, func(d *gob.Decoder) (*Stats, error) {
+		var stats Stats
+		err := d.Decode(&stats)
+		return &stats, err
+	})
+
+	if err != nil && ctx.Err() == nil {
+		// stats saved before channels were loaded lazily
+		legacy, lerr := readDatabase(ctx, path, func(d *gob.Decoder) (*Stats, error) {
+			var legacy legacyStats
+			err := d.Decode(&legacy)
+			return legacy.stats(), err
+		})
+		if lerr == nil {
+			stats, err = legacy, nil
+		}
+	}
+
+	if stats == nil || err != nil {
+		return nil, err
+	}
+
+	stats.buildIndexes()
+	// there's no logger of the options yet, see Repaired
+	stats.repaired = stats.validate()
+
+	return stats, nil
+}
+
+// readDatabase opens the database at path and decodes it with decode, the
+// stats are nil when there's no database yet. Databases saved as json are
+// decoded as such.
+func readDatabase(ctx context.Context, path string, decode func(*gob.Decoder) (*Stats, error)) (*Stats, error) {
+	file, err := fileOpener.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
+	r, err := gzip.NewReader(contextReader{ctx: ctx, r: file})
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		} else {
-			fmt.Println("Some other error: %v", err)
-			return nil, err
-		}
+		return nil, err
 	}
-
-	r, _ := gzip.NewReader(file)
 	defer r.Close()
-	decoder := gob.NewDecoder(r)
-	var stats Stats
 
-	if err = decoder.Decode(&stats); err != nil {
+	var stats *Stats
+	br := bufio.NewReader(r)
+	if isCanonical(br) {
+		stats, err = decodeCanonical(br)
+	} else {
+		stats, err = decode(gob.NewDecoder(br))
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	stats.buildIndexes()
-
-	return &stats, nil
+	return stats, nil
 }
 
-// Lock proxies the RWMutex's Lock function.
+// Lock locks the whole stats for writing.
+//
+// Deprecated: the methods of Stats lock it themselves, use Update to add
+// several messages at once. While locked only the fields may be used,
+// calling the methods deadlocks.
 func (s *Stats) Lock() {
 	s.mut.Lock()
 }
 
-// Unlock proxies the RWMutex's Unlock function.
+// Unlock unlocks the stats locked with Lock.
+//
+// Deprecated: see Lock.
 func (s *Stats) Unlock() {
 	s.mut.Unlock()
 }
 
-// RLock proxies the RWMutex's RLock function.
+// RLock locks the whole stats for reading, waiting for the messages being
+// added to every network.
+//
+// Deprecated: use View to read the stats. While locked only the fields may
+// be used, calling the methods deadlocks.
 func (s *Stats) RLock() {
-	s.mut.RLock()
+	s.rlock()
 }
 
-// RUnlock proxies the RWMutex's Unlock function.
+// RUnlock unlocks the stats locked with RLock.
+//
+// Deprecated: see RLock.
 func (s *Stats) RUnlock() {
+	s.runlock()
+}
+
+// LockNetwork locks the stats for adding messages to a single network, the
+// network is created if it's new.
+//
+// Deprecated: AddMessage and AddMessageID lock the network themselves.
+// While locked only the fields of the network may be used, calling the
+// methods deadlocks.
+func (s *Stats) LockNetwork(network string) {
+	s.lockNetwork(func() string { return network })
+}
+
+// UnlockNetwork unlocks the network locked with LockNetwork.
+//
+// Deprecated: see LockNetwork.
+func (s *Stats) UnlockNetwork(network string) {
+	s.unlockNetwork(s.networkByName[strings.ToLower(network)])
+}
+
+// lock locks the whole stats for writing, counting the wait in the metrics.
+func (s *Stats) lock() {
+	defer s.metrics.waited(time.Now())
+
+	s.mut.Lock()
+}
+
+// rlock locks the whole stats for reading, along with every network.
+func (s *Stats) rlock() {
+	defer s.metrics.waited(time.Now())
+
+	s.mut.RLock()
+	for _, n := range s.Networks {
+		n.mut.RLock()
+	}
+}
+
+func (s *Stats) runlock() {
+	for _, n := range s.Networks {
+		n.mut.RUnlock()
+	}
+	s.mut.RUnlock()
+}
+
+// lockNetwork locks the stats for adding messages to a single network, the
+// network is created if it's new. Networks locked this way are added to
+// concurrently: a bot on many networks doesn't wait on one lock for all of
+// them. Until unlockNetwork only messages of the network may be added, and
+// nothing may be read but the network itself. The name of the network is
+// read with the stats locked for reading, as often as it's created.
+func (s *Stats) lockNetwork(name func() string) *Network {
+	defer s.metrics.waited(time.Now())
+
+	for {
+		s.mut.RLock()
+		network := name()
+		if n, ok := s.networkByName[strings.ToLower(network)]; ok {
+			n.mut.Lock()
+			return n
+		}
+		s.mut.RUnlock()
+
+		s.mut.Lock()
+		s.getNetwork(network)
+		s.mut.Unlock()
+	}
+}
+
+// lockRaw prepares a message to be added and locks the network it's counted
+// on, that of its channel's alias when it has one. The message is prepared
+// first so the alias is that of the channel as it's counted.
+func (s *Stats) lockRaw(raw *RawMessage) *Network {
+	received := *raw
+	return s.lockNetwork(func() string {
+		*raw = received
+		s.opts.prepare(raw)
+		return s.opts.aliasNetwork(raw.Network, raw.Channel)
+	})
+}
+
+func (s *Stats) unlockNetwork(n *Network) {
+	n.mut.Unlock()
 	s.mut.RUnlock()
 }
@@ -2,6 +2,14 @@ package stats
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
 )
@@ -23,7 +31,7 @@ const (
 func TestStats_GetterMethods(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	if n := s.GetNetwork(network); n != nil {
 		t.Error("Network should be nil.")
@@ -55,7 +63,7 @@ func TestStats_GetterMethods(t *testing.T) {
 func TestStats_AddMessage(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "tree foo http://google.com")
 
@@ -113,7 +121,7 @@ func TestStats_AddMessage(t *testing.T) {
 func TestStats_AddSlapMessage(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Action, network, channel, "dylan", time.Now(), "slaps fish around a bit with a large trout")
 
 	n := s.networkByName[network]
@@ -153,7 +161,7 @@ func TestStats_AddSlapMessage(t *testing.T) {
 func TestStats_AddKickMessage(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Kick, network, channel, "dylan", time.Now(), "fish")
 
 	n := s.networkByName[network]
@@ -191,7 +199,7 @@ func TestStats_AddKickMessage(t *testing.T) {
 func TestStats_AddMessageBlankChannel(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	s.AddMessage(Msg, network, "", hostmask, time.Now(), "some foo")
 
@@ -203,7 +211,7 @@ func TestStats_AddMessageBlankChannel(t *testing.T) {
 func TestStats_addNetwork(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	if len(s.Networks) != 0 {
 		t.Error("Network should not exist at this point.")
@@ -225,7 +233,7 @@ func TestStats_addNetwork(t *testing.T) {
 func TestStats_getNetwork(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	if len(s.Networks) != 0 {
 		t.Error("Network should not exist at this point.")
@@ -253,7 +261,7 @@ func TestStats_getNetwork(t *testing.T) {
 func TestStats_getChannel(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	n := s.addNetwork(network)
 
@@ -281,7 +289,7 @@ func TestStats_getChannel(t *testing.T) {
 func TestStats_getUser(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	n := s.addNetwork(network)
 
@@ -309,7 +317,7 @@ func TestStats_getUser(t *testing.T) {
 func TestStats_buildIndexes(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
 	s.networkByName = nil
 
@@ -324,6 +332,30 @@ func TestStats_buildIndexes(t *testing.T) {
 	}
 }
 
+func TestStats_buildIndexesNetworks(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	for i := 0; i < 10; i++ {
+		net := fmt.Sprint("network", i)
+		s.AddMessage(Msg, net, channel, hostmask, time.Now(), "some foo")
+		u := s.GetUser(net, nick)
+		u.MessageRanges, u.MessageIDs = IDRanges{}, []uint{1, 2}
+	}
+
+	s.buildIndexes()
+
+	for i := 0; i < 10; i++ {
+		net := fmt.Sprint("network", i)
+		if s.GetChannel(net, channel) == nil {
+			t.Error("Should look up the channels of", net)
+		}
+		if u := s.GetUser(net, nick); u == nil || u.MessageRanges.Len() != 2 {
+			t.Error("Should migrate the users of", net, u)
+		}
+	}
+}
+
 func TestStats_SaveLoadDB(t *testing.T) {
 	t.Parallel()
 
@@ -331,17 +363,17 @@ func TestStats_SaveLoadDB(t *testing.T) {
 		fileOpener = &nilFileOpener{}
 	}()
 
-	s := NewStats()
+	s := newStats()
 	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
 
 	b := bytes.Buffer{}
 	fileOpener = &fakeFileOpener{&b}
 
-	if s.Save() != true {
-		t.Error("Should be able to create data.db.")
+	if err := s.Save(); err != nil {
+		t.Error("Should be able to create data.db:", err)
 	}
 
-	s, e := loadDatabase()
+	s, e := loadDatabase(context.Background(), defaultPath)
 
 	if e != nil {
 		t.Error("Should not be nil.")
@@ -351,3 +383,344 @@ func TestStats_SaveLoadDB(t *testing.T) {
 		t.Error("Should have loaded DB.")
 	}
 }
+
+// failingOpener fails to open and create every file.
+type failingOpener struct {
+	err error
+}
+
+func (o failingOpener) Open(name string) (io.ReadCloser, error) {
+	return nil, o.err
+}
+
+func (o failingOpener) Create(name string) (io.WriteCloser, error) {
+	return nil, o.err
+}
+
+func TestStats_NewStatsErrors(t *testing.T) {
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	if s, err := NewStats(); err != nil || s == nil || len(s.Networks) != 0 {
+		t.Error("Should create empty stats without a database:", err)
+	}
+
+	fileOpener = &fakeFileOpener{bytes.NewBufferString("not a database")}
+	if s, err := NewStats(); err == nil || s != nil {
+		t.Error("Should fail loading a broken database:", s)
+	}
+
+	fileOpener = failingOpener{os.ErrPermission}
+	if _, err := NewStats(); err == nil {
+		t.Error("Should fail when the database can't be opened.")
+	}
+}
+
+func TestStats_SaveErrors(t *testing.T) {
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	fileOpener = failingOpener{os.ErrPermission}
+	if err := s.Save(); err == nil {
+		t.Error("Should fail when data.db can't be created.")
+	}
+	if m := s.Metrics(); m.SaveErrors != 1 || m.Saves != 0 {
+		t.Error("Should count the failed save:", m.SaveErrors, m.Saves)
+	}
+}
+
+// blockingOpener creates files that wait for release before they're
+// written.
+type blockingOpener struct {
+	fakeFileOpener
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (o *blockingOpener) Create(name string) (io.WriteCloser, error) {
+	return o, nil
+}
+
+func (o *blockingOpener) Write(b []byte) (int, error) {
+	select {
+	case o.writing <- struct{}{}:
+	default:
+	}
+	<-o.release
+	return o.fakeFileOpener.Write(b)
+}
+
+func TestStats_SaveDoesNotBlock(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	o := &blockingOpener{
+		fakeFileOpener: fakeFileOpener{&bytes.Buffer{}},
+		writing:        make(chan struct{}, 1),
+		release:        make(chan struct{}),
+	}
+	fileOpener = o
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	saved := make(chan error)
+	go func() { saved <- s.Save() }()
+
+	<-o.writing
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "more foo")
+	close(o.release)
+
+	if err := <-saved; err != nil {
+		t.Fatal("Should save the stats:", err)
+	}
+	if c := s.GetChannel(network, channel); c.MessageCount != 2 {
+		t.Error("Should add messages while saving:", c.MessageCount)
+	}
+
+	loaded, err := loadDatabase(context.Background(), defaultPath)
+	if err != nil || loaded.GetChannel(network, channel).MessageCount != 1 {
+		t.Error("Should save the stats as they were when the save started:", err)
+	}
+}
+
+// countingOpener counts the files created.
+type countingOpener struct {
+	fakeFileOpener
+	mut     sync.Mutex
+	created int
+}
+
+func (o *countingOpener) Create(name string) (io.WriteCloser, error) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	o.created++
+	o.Reset()
+	return o, nil
+}
+
+func (o *countingOpener) count() int {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	return o.created
+}
+
+func TestStats_SaveCoalesced(t *testing.T) {
+	s := newStats()
+	s.SetOptions(Options{MinSaveInterval: 50 * time.Millisecond})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	o := &countingOpener{fakeFileOpener: fakeFileOpener{&bytes.Buffer{}}}
+	fileOpener = o
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	for i := 0; i < 10; i++ {
+		if err := s.Save(); err != nil {
+			t.Fatal("Should save:", err)
+		}
+	}
+	if n := o.count(); n != 1 {
+		t.Error("Should coalesce the saves after the first, Got:", n)
+	}
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "more foo")
+	time.Sleep(100 * time.Millisecond)
+	if n := o.count(); n != 2 {
+		t.Error("Should write the coalesced saves at the end of the interval, Got:", n)
+	}
+
+	s.SaveNow()
+	s.Save()
+	if err := s.SaveNow(); err != nil {
+		t.Fatal("Should save now:", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := o.count(); n != 4 {
+		t.Error("Should write the coalesced save along with SaveNow, Got:", n)
+	}
+}
+
+func TestStats_SaveCoalescedError(t *testing.T) {
+	s := newStats()
+	s.SetOptions(Options{MinSaveInterval: 200 * time.Millisecond})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	fileOpener = failingOpener{os.ErrPermission}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	if err := s.Save(); !errors.Is(err, os.ErrPermission) {
+		t.Error("Should return why the save failed:", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Error("Should coalesce the save:", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if err := s.Save(); !errors.Is(err, os.ErrPermission) {
+		t.Error("Should return the error of the coalesced save:", err)
+	}
+	if err := s.Save(); err != nil {
+		t.Error("Should return the error once:", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if m := s.Metrics(); m.SaveErrors != 3 {
+		t.Fatal("Should count the failed saves:", m.SaveErrors)
+	}
+
+	fileOpener = &fakeFileOpener{&bytes.Buffer{}}
+	if err := s.SaveNow(); !errors.Is(err, os.ErrPermission) {
+		t.Error("Should return the error of the coalesced save along with SaveNow:", err)
+	}
+	if err := s.SaveNow(); err != nil {
+		t.Error("Should save now:", err)
+	}
+}
+
+func TestStats_AddMessageConcurrently(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{Processors: []Processor{NewBridgeProcessor("relay")}})
+
+	const networks, messages = 4, 200
+	var wg sync.WaitGroup
+	for i := 0; i < networks; i++ {
+		wg.Add(1)
+		go func(network string) {
+			defer wg.Done()
+			for j := 0; j < messages; j++ {
+				s.AddMessage(Msg, network, channel, fmt.Sprintf("user%d", j%10), time.Now(), "lol")
+				s.AddMessage(Action, network, channel, hostmask, time.Now(), "slaps user1 around a bit with a large trout")
+			}
+		}(fmt.Sprintf("network%d", i))
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			s.View(func(tx *ReadTx) {
+				for _, u := range tx.Users {
+					_ = u.Lines
+				}
+			})
+		}
+	}()
+	wg.Wait()
+
+	if len(s.Networks) != networks || s.MessageIDCount != 1+networks*messages*2 {
+		t.Fatal("Should count every message:", len(s.Networks), s.MessageIDCount)
+	}
+	ids := make(map[uint]bool)
+	for _, n := range s.Networks {
+		for _, id := range n.MessageRanges.All() {
+			if ids[id] {
+				t.Fatal("Message ids should be unique:", id)
+			}
+			ids[id] = true
+		}
+		// user1 is slapped from the moment it first spoke
+		if u := s.GetUser(n.Name, "user1"); u == nil || u.SlapCounters.Received != messages-1 {
+			t.Error("Should count the slaps of every network:", n.Name, u)
+		}
+	}
+}
+
+func TestStats_AggregateOnly(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "aggregate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := OpenFileRawStore(filepath.Join(dir, "raw.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	var r sinkRecorder
+	s := newStats()
+	s.SetOptions(Options{RawStore: store, Sink: &r, AggregateOnly: true})
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello there")
+
+	u := s.GetUser(network, nick)
+	if u == nil || u.Lines != 1 || u.Words != 2 || u.MessageCount != 1 {
+		t.Fatal("Should count the message:", u)
+	}
+	if u.MessageRanges.Len() != 0 || s.GetChannel(network, channel).MessageRanges.Len() != 0 || s.GetNetwork(network).MessageRanges.Len() != 0 {
+		t.Error("Should not keep the message ids.")
+	}
+	if u.Quotes.Last.ID == 0 || u.Quotes.Last.Message != "hello there" {
+		t.Error("Should keep the quotes:", u.Quotes.Last)
+	}
+
+	if len(r) != 1 || len(r[0].Message) != 0 || r[0].Nick != nick {
+		t.Error("Should give sinks the message without its text:", r)
+	}
+
+	kept := 0
+	if err = store.Replay(func(RawMessage) { kept++ }); err != nil {
+		t.Fatal(err)
+	}
+	if kept != 0 {
+		t.Error("Should not keep raw messages:", kept)
+	}
+}
+
+// benchMessages are the kinds of lines a busy channel sees.
+var benchMessages = []string{
+	"hello there, how is everyone doing today?",
+	"check out http://golang.org/doc/effective_go.html it's good",
+	"lol :D",
+	"phish: that's what I said! #golang @dylan",
+	"WHY IS THIS SO SLOW",
+	"s/slow/fast/",
+	"this",
+}
+
+func BenchmarkStats_AddMessage(b *testing.B) {
+	s := newStats()
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	nicks := []string{"phish", "aaron", "dylan", "knivey"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.AddMessage(Msg, network, channel, nicks[i%len(nicks)], date.Add(time.Duration(i)*time.Second), benchMessages[i%len(benchMessages)])
+	}
+}
+
+func BenchmarkStats_Save(b *testing.B) {
+	s := newStats()
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < 10000; i++ {
+		s.AddMessage(Msg, network, channel, fmt.Sprintf("user%d", i%100), date.Add(time.Duration(i)*time.Second), benchMessages[i%len(benchMessages)])
+	}
+
+	var buf bytes.Buffer
+	fileOpener = &fakeFileOpener{&buf}
+	defer func() {
+		fileOpener = &nilFileOpener{}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		s.Save()
+	}
+}
@@ -0,0 +1,219 @@
+package statsbot
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+const dayFormat = "2006-01-02"
+
+// Privmsger sends messages to a channel, an irc.Writer is one.
+type Privmsger interface {
+	Privmsg(target string, args ...interface{}) error
+}
+
+// Announcer announces what happens in the channels of a network in the
+// channels themselves: a summary of every day at midnight, and as they happen
+// the days beating the channel's record for lines and the karma users are
+// given by the others reacting to them. Run it with a writer connected to the
+// network, it subscribes to the events of the stats.
+type Announcer struct {
+	Stats   *stats.Stats
+	Writer  Privmsger
+	Network string
+	// Channels limits the announcements to these channels, when empty every
+	// channel of the network is announced.
+	Channels []string
+
+	Summary bool
+	Records bool
+	Karma   bool
+	// Location is the timezone whose midnight ends the day of the
+	// summaries, by default the one the stats read the network's days in.
+	// The channels read in a timezone of their own are then summed up on
+	// their own days. The records are always on the days of the stats, see
+	// stats.DayRecordEvent.
+	Location *time.Location
+}
+
+type announcement struct {
+	channel string
+	text    string
+}
+
+// NewAnnouncer creates an announcer for a network, with every announcement
+// turned on.
+func NewAnnouncer(s *stats.Stats, w Privmsger, network string) *Announcer {
+	return &Announcer{
+		Stats:   s,
+		Writer:  w,
+		Network: network,
+		Summary: true,
+		Records: true,
+		Karma:   true,
+	}
+}
+
+// announces checks if the announcer covers a channel.
+func (a *Announcer) announces(network, channel string) bool {
+	if len(channel) == 0 || !strings.EqualFold(network, a.Network) {
+		return false
+	}
+	if len(a.Channels) == 0 {
+		return true
+	}
+	for _, c := range a.Channels {
+		if strings.EqualFold(c, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run sends the announcements until stop is closed.
+func (a *Announcer) Run(stop <-chan struct{}) {
+	events := a.subscribe()
+	defer a.Stats.Unsubscribe(events)
+
+	for {
+		now := a.Stats.Now()
+		timer := time.NewTimer(a.nextMidnight(now).Sub(now))
+
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case e := <-events:
+			timer.Stop()
+			if an, ok := a.announcement(e); ok {
+				a.Writer.Privmsg(an.channel, an.text)
+			}
+		case <-timer.C:
+			if a.Summary {
+				yesterday := a.Stats.Now().In(a.location()).Add(-12 * time.Hour).Format(dayFormat)
+				for _, an := range a.summaries(yesterday) {
+					a.Writer.Privmsg(an.channel, an.text)
+				}
+			}
+		}
+	}
+}
+
+// subscribe subscribes to the events of the network announced, if any.
+func (a *Announcer) subscribe() <-chan stats.StatEvent {
+	var kinds []stats.StatEventKind
+	if a.Records {
+		kinds = append(kinds, stats.DayRecordEvent)
+	}
+	if a.Karma {
+		kinds = append(kinds, stats.ReactionEvent)
+	}
+	if len(kinds) == 0 {
+		// nothing is ever received from nil, the summaries are all there is
+		return nil
+	}
+	return a.Stats.Subscribe(stats.EventFilter{Kinds: kinds, Network: a.Network})
+}
+
+// announcement is the announcement of an event, ok is false for the events
+// not announced. Only the events of the messages said just now are, history
+// played back can't break records nor give karma.
+func (a *Announcer) announcement(e stats.StatEvent) (an announcement, ok bool) {
+	if !a.announces(e.Network, e.Channel) {
+		return an, false
+	}
+	if d := a.Stats.Now().Sub(e.Date); d > liveWindow || d < -liveWindow {
+		return an, false
+	}
+
+	switch {
+	case e.Kind == stats.DayRecordEvent && a.Records:
+		return announcement{e.Channel, fmt.Sprintf("New record! %s has seen %d lines today, beating the %d of its best day so far.", e.Channel, e.Count, e.Previous)}, true
+	case e.Kind == stats.ReactionEvent && a.Karma:
+		return announcement{e.Channel, fmt.Sprintf("%s gave %s karma, %d so far.", e.From, e.Nick, e.Count)}, true
+	}
+	return an, false
+}
+
+// summaries sums up a day in every channel that was active.
+func (a *Announcer) summaries(day string) []announcement {
+	var summaries []announcement
+	a.Stats.View(func(tx *stats.ReadTx) {
+		summaries = a.summarize(tx, day)
+	})
+	return summaries
+}
+
+func (a *Announcer) summarize(tx *stats.ReadTx, day string) []announcement {
+	n := tx.GetNetwork(a.Network)
+	if n == nil {
+		return nil
+	}
+
+	var summaries []announcement
+	for _, id := range n.ChannelIDs {
+		c := tx.Channels[id]
+		if c == nil || !a.announces(a.Network, c.Name) {
+			continue
+		}
+		if c = tx.Channel(id); c == nil {
+			continue
+		}
+
+		loc := a.Location
+		if loc == nil {
+			loc = tx.Location(a.Network, c.Name)
+		}
+		from, err := time.ParseInLocation(dayFormat, day, loc)
+		if err != nil {
+			continue
+		}
+		days := c.Days.In(loc)
+		lines := days.Day(day)
+		if lines == 0 {
+			continue
+		}
+
+		text := fmt.Sprintf("%s in %s: %d lines.", day, c.Name, lines)
+		if firstID, lastID, ok := c.DailySpeakers.Between(from, from.AddDate(0, 0, 1)); ok {
+			first, last := a.nick(tx, firstID), a.nick(tx, lastID)
+			if first == last {
+				text += fmt.Sprintf(" %s spoke first and had the last word.", first)
+			} else {
+				text += fmt.Sprintf(" %s spoke first and %s had the last word.", first, last)
+			}
+		}
+		if record, _ := days.Record(); record == day && len(days.Lines) > 1 {
+			text += " A new record!"
+		}
+
+		summaries = append(summaries, announcement{c.Name, text})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].channel < summaries[j].channel })
+	return summaries
+}
+
+func (a *Announcer) nick(tx *stats.ReadTx, id uint) string {
+	if u, ok := tx.Users[id]; ok {
+		return u.Nick
+	}
+	return "someone"
+}
+
+func (a *Announcer) location() *time.Location {
+	if a.Location == nil {
+		return a.Stats.Location(a.Network, "")
+	}
+	return a.Location
+}
+
+// nextMidnight is the first midnight after now.
+func (a *Announcer) nextMidnight(now time.Time) time.Time {
+	now = now.In(a.location())
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+}
@@ -0,0 +1,212 @@
+package statsbot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// announced are the announcements of the events sent so far.
+func announced(a *Announcer, events <-chan stats.StatEvent) []announcement {
+	var list []announcement
+	for {
+		select {
+		case e := <-events:
+			if an, ok := a.announcement(e); ok {
+				list = append(list, an)
+			}
+		default:
+			return list
+		}
+	}
+}
+
+func TestAnnouncer_Records(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2014, 3, 2, 12, 0, 0, 0, time.UTC)
+	s.SetOptions(stats.Options{Clock: stats.NewManualClock(now)})
+	a := NewAnnouncer(s, &fakeWriter{}, "network")
+	events := a.subscribe()
+
+	yesterday := now.AddDate(0, 0, -1)
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", yesterday, "one")
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", yesterday, "two")
+	if got := announced(a, events); len(got) != 0 {
+		t.Fatal("History should not break records:", got)
+	}
+
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now, "one")
+	s.AddMessage(stats.Join, "network", "#chan", "phish", now, "")
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now, "two")
+	if got := announced(a, events); len(got) != 0 {
+		t.Fatal("Should only announce days beating the record:", got)
+	}
+
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now, "three")
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now, "four")
+	got := announced(a, events)
+	if len(got) != 1 {
+		t.Fatal("Should announce the record once:", got)
+	}
+	if an := got[0]; an.channel != "#chan" || !strings.Contains(an.text, "3 lines today, beating the 2") {
+		t.Error("Wrong announcement:", an)
+	}
+
+	s.AddMessage(stats.Msg, "other", "#chan", "dylan", yesterday, "elsewhere")
+	for i := 0; i < 3; i++ {
+		s.AddMessage(stats.Msg, "other", "#chan", "dylan", now, "elsewhere")
+	}
+	if got := announced(a, events); len(got) != 0 {
+		t.Error("Should only watch its own network:", got)
+	}
+}
+
+func TestAnnouncer_Karma(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2014, 3, 2, 12, 0, 0, 0, time.UTC)
+	s.SetOptions(stats.Options{Clock: stats.NewManualClock(now)})
+	a := NewAnnouncer(s, &fakeWriter{}, "network")
+	a.Channels = []string{"#chan"}
+	events := a.subscribe()
+
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now.Add(-time.Hour), "old news")
+	s.AddMessage(stats.Msg, "network", "#chan", "phish", now.Add(-time.Hour), "thanks")
+	if got := announced(a, events); len(got) != 0 {
+		t.Fatal("History should not give karma:", got)
+	}
+
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now, "here's the fix")
+	s.AddMessage(stats.Msg, "network", "#chan", "phish", now, "thanks!")
+	s.AddMessage(stats.Msg, "network", "#other", "dylan", now, "here too")
+	s.AddMessage(stats.Msg, "network", "#other", "phish", now, "thanks")
+	got := announced(a, events)
+	if len(got) != 1 || got[0].channel != "#chan" || got[0].text != "phish gave dylan karma, 2 so far." {
+		t.Error("Should announce the karma given in the channels announced:", got)
+	}
+
+	a.Karma = false
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", now, "again")
+	s.AddMessage(stats.Msg, "network", "#chan", "phish", now, "thanks")
+	if got := announced(a, events); len(got) != 0 {
+		t.Error("Should not announce karma when turned off:", got)
+	}
+}
+
+func TestAnnouncer_summaries(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewAnnouncer(s, &fakeWriter{}, "network")
+	a.Channels = []string{"#chan", "#quiet"}
+
+	day := time.Date(2014, 1, 1, 10, 0, 0, 0, time.UTC)
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", day.AddDate(0, 0, -1), "hello")
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", day, "hello")
+	s.AddMessage(stats.Msg, "network", "#chan", "phish", day.Add(time.Hour), "hi")
+	s.AddMessage(stats.Msg, "network", "#quiet", "dylan", day.AddDate(0, 0, -1), "hello")
+	s.AddMessage(stats.Msg, "network", "#ignored", "dylan", day, "hello")
+
+	summaries := a.summaries("2014-01-01")
+	if len(summaries) != 1 {
+		t.Fatal("Should sum up the active channels announced:", summaries)
+	}
+
+	want := "2014-01-01 in #chan: 2 lines. dylan spoke first and phish had the last word. A new record!"
+	if summaries[0].channel != "#chan" || summaries[0].text != want {
+		t.Errorf("Expected: %q, Got: %q", want, summaries[0].text)
+	}
+}
+
+func TestAnnouncer_summariesLocation(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetOptions(stats.Options{Location: time.FixedZone("EST", -5*60*60)})
+	a := NewAnnouncer(s, &fakeWriter{}, "network")
+
+	// 9pm on the 1st and 1am on the 2nd in toronto
+	day := time.Date(2014, 1, 2, 2, 0, 0, 0, time.UTC)
+	s.AddMessage(stats.Msg, "network", "#chan", "dylan", day, "hello")
+	s.AddMessage(stats.Msg, "network", "#chan", "phish", day.Add(4*time.Hour), "hi")
+
+	summaries := a.summaries("2014-01-01")
+	want := "2014-01-01 in #chan: 1 lines. dylan spoke first and had the last word. A new record!"
+	if len(summaries) != 1 || summaries[0].text != want {
+		t.Errorf("Expected: %q, Got: %v", want, summaries)
+	}
+}
+
+// chanWriter sends the messages written to it down the channel.
+type chanWriter chan string
+
+func (c chanWriter) Privmsg(target string, args ...interface{}) error {
+	c <- target + " " + fmt.Sprint(args...)
+	return nil
+}
+
+func TestAnnouncer_Run(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := make(chanWriter, 10)
+	a := NewAnnouncer(s, w, "network")
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		a.Run(stop)
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// the reactions before the announcer subscribed aren't announced
+	for i := 0; i < 1000; i++ {
+		s.AddMessage(stats.Msg, "network", "#chan", "dylan", time.Now(), "hello")
+		s.AddMessage(stats.Msg, "network", "#chan", "phish", time.Now(), "thanks")
+
+		select {
+		case m := <-w:
+			if !strings.HasPrefix(m, "#chan phish gave dylan karma") {
+				t.Error("Should have sent the announcement:", m)
+			}
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+	t.Error("Should have announced the karma.")
+}
+
+func TestAnnouncer_nextMidnight(t *testing.T) {
+	t.Parallel()
+
+	a := &Announcer{Location: time.UTC}
+	got := a.nextMidnight(time.Date(2014, 12, 31, 23, 59, 0, 0, time.UTC))
+	if !got.Equal(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Wrong midnight:", got)
+	}
+}
@@ -0,0 +1,64 @@
+package statsbot
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// sweepUsers is how many users a rate limiter remembers before forgetting
+// those that could run a burst of commands again.
+const sweepUsers = 1024
+
+// RateLimiter limits how often each user runs commands, so one user can't
+// make the bot flood a channel: Burst commands at once, then one every Every.
+// It's safe to use from the goroutines of every network.
+type RateLimiter struct {
+	Burst int
+	Every time.Duration
+
+	mut sync.Mutex
+	// next is when each user by network and nick has used up their burst,
+	// users who could run a whole burst again are forgotten.
+	next map[string]time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing burst commands at once,
+// then one every every.
+func NewRateLimiter(burst int, every time.Duration) *RateLimiter {
+	return &RateLimiter{Burst: burst, Every: every}
+}
+
+// Allow checks if a user may run a command now, and counts it if they may.
+func (l *RateLimiter) Allow(network, nick string, now time.Time) bool {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.next == nil {
+		l.next = make(map[string]time.Time)
+	}
+	if len(l.next) >= sweepUsers {
+		for key, next := range l.next {
+			if !next.After(now) {
+				delete(l.next, key)
+			}
+		}
+	}
+
+	burst := l.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	key := strings.ToLower(network) + " " + strings.ToLower(nick)
+	next := l.next[key]
+	if next.Before(now) {
+		next = now
+	}
+	if next.Sub(now) > time.Duration(burst-1)*l.Every {
+		return false
+	}
+
+	l.next[key] = next.Add(l.Every)
+	return true
+}
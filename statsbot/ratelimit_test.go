@@ -0,0 +1,43 @@
+package statsbot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_Allow(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(2, 10*time.Second)
+	now := time.Now()
+
+	if !l.Allow("network", "bob", now) || !l.Allow("network", "BOB", now) {
+		t.Error("Should allow a burst of two.")
+	}
+	if l.Allow("network", "bob", now) {
+		t.Error("Should refuse past the burst.")
+	}
+	if !l.Allow("network", "alice", now) || !l.Allow("other", "bob", now) {
+		t.Error("Should limit each user of each network on its own.")
+	}
+	if !l.Allow("network", "bob", now.Add(10*time.Second)) || l.Allow("network", "bob", now.Add(10*time.Second)) {
+		t.Error("Should allow one more every ten seconds.")
+	}
+	if !l.Allow("network", "bob", now.Add(time.Minute)) || !l.Allow("network", "bob", now.Add(time.Minute)) {
+		t.Error("Should allow a whole burst once rested.")
+	}
+}
+
+func TestRateLimiter_sweep(t *testing.T) {
+	t.Parallel()
+
+	l := NewRateLimiter(1, time.Second)
+	now := time.Now()
+	for i := 0; i < sweepUsers; i++ {
+		l.Allow("network", string(rune('a'+i%26))+string(rune('a'+i/26)), now)
+	}
+	l.Allow("network", "late", now.Add(time.Hour))
+	if len(l.next) != 1 {
+		t.Errorf("Should forget the users who rested, remembers %d", len(l.next))
+	}
+}
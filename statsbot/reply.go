@@ -0,0 +1,64 @@
+package statsbot
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxLine is the longest irc line in bytes, its \r\n included.
+	maxLine = 512
+	// maxSource is the longest source the server puts in front of a line
+	// it relays, ":nick!user@host " of the longest nick, user and host
+	// servers commonly allow.
+	maxSource = 1 + 30 + 1 + 10 + 1 + 63 + 1
+	// truncated ends the last line of a reply that was cut.
+	truncated = "..."
+)
+
+// SplitReply splits a reply to a target into the texts of PRIVMSGs that fit
+// in an irc line once the server relays them, at spaces where it can and
+// never within a character. Line breaks and NULs, which would end the line
+// or break it, become spaces. A reply needing more than maxLines lines is cut
+// and its last line ends with ...; every line is returned when maxLines is
+// <= 0.
+func SplitReply(target, reply string, maxLines int) []string {
+	reply = strings.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', 0:
+			return ' '
+		}
+		return r
+	}, reply)
+	reply = strings.TrimSpace(reply)
+
+	max := maxLine - maxSource - len("PRIVMSG  :\r\n") - len(target)
+	var lines []string
+	for len(reply) > 0 {
+		if maxLines > 0 && len(lines) == maxLines-1 && len(reply) > max {
+			lines = append(lines, cut(reply, max-len(truncated))+truncated)
+			break
+		}
+
+		line := cut(reply, max)
+		if len(line) < len(reply) {
+			if i := strings.LastIndexByte(line, ' '); i > 0 {
+				line = line[:i]
+			}
+		}
+		lines = append(lines, strings.TrimSpace(line))
+		reply = strings.TrimSpace(reply[len(line):])
+	}
+	return lines
+}
+
+// cut cuts text to at most max bytes, on a character boundary.
+func cut(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
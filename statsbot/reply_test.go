@@ -0,0 +1,45 @@
+package statsbot
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitReply(t *testing.T) {
+	t.Parallel()
+
+	if lines := SplitReply("#chan", "hello\r\nthere\x00!", 3); len(lines) != 1 || lines[0] != "hello  there !" {
+		t.Error("Should turn line breaks into spaces:", lines)
+	}
+	if lines := SplitReply("#chan", "  ", 3); len(lines) != 0 {
+		t.Error("Should send nothing of empty replies:", lines)
+	}
+
+	max := maxLine - maxSource - len("PRIVMSG #chan :\r\n")
+	long := strings.Repeat("word ", 200)
+	lines := SplitReply("#chan", long, 0)
+	if len(lines) != 3 {
+		t.Fatalf("Should split into three lines, split into %d", len(lines))
+	}
+	for _, l := range lines {
+		if len(l) > max || strings.HasPrefix(l, " ") || strings.HasSuffix(l, " ") || strings.HasSuffix(l, "wor") {
+			t.Errorf("Should split at spaces within %d bytes: %q", max, l)
+		}
+	}
+	if strings.Join(lines, " ") != strings.TrimSpace(long) {
+		t.Error("Should keep every word.")
+	}
+
+	lines = SplitReply("#chan", long, 2)
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "...") || len(lines[1]) > max {
+		t.Error("Should cut the reply at two lines:", lines)
+	}
+
+	lines = SplitReply("#chan", strings.Repeat("é", 400), 0)
+	for _, l := range lines {
+		if !utf8.ValidString(l) || len(l) > max {
+			t.Errorf("Should split within characters: %q", l)
+		}
+	}
+}
@@ -0,0 +1,509 @@
+// Package statsbot is an ultimateq extension that feeds every event the bot
+// sees into a Stats database and answers a few commands in channels:
+//
+//	!stats [nick]  lines, words and words per line of a user
+//	!seen <nick>   when a user was last seen
+//	!top [n[-m]]   the top talkers of the channel, from the nth or the
+//	               nth to the mth, eg. !top 11-20
+//	!url           the most linked urls of the channel
+//	!grep <text>   the last lines of the channel saying the text, or
+//	               matching /regexp/, when the stats have a raw store
+//	!optout        stops counting the lines of the user, see
+//	               stats.StopCounting; the operators forget those counted
+//	!optin         counts the lines of the user again
+//
+// Register the handler for raw events on the bot, for example:
+//
+//	b.Register("", "", irc.RAW, statsbot.New(s))
+//
+// Set a Limiter so no user can make the bot flood a channel, the commands of a
+// user over the limit aren't answered. Replies are split to fit in irc lines
+// with SplitReply, up to MaxReplyLines.
+//
+// The lines the bot says itself aren't counted unless CountSelf is set, tell
+// the handler its nick with SetNick and what it sends with Sent. Lines the
+// server echoes back, with echo-message, are only counted once.
+//
+// An Announcer run with the stats announces daily summaries in the channels,
+// and record days and karma as they happen.
+package statsbot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/aarondl/ultimateq/irc"
+)
+
+const (
+	defaultPrefix = "!"
+	topCount      = 5
+	ctcpDelim     = "\x01"
+	// grepCount is how many lines !grep replies with.
+	grepCount = 3
+	// maxReplyLines is how many lines a reply is split into at most.
+	maxReplyLines = 3
+)
+
+// Handler feeds irc events into a Stats and answers commands.
+type Handler struct {
+	Stats *stats.Stats
+
+	// Prefix is the character commands start with, "!" by default.
+	Prefix string
+	// TopCount is how many entries the top commands list, 5 by default and
+	// all of them when <= 0.
+	TopCount int
+	// CountSelf counts the lines the bot says, its replies and
+	// announcements, as those of its nick. They aren't by default.
+	CountSelf bool
+	// Limiter, when set, limits how often each user runs commands, those
+	// over the limit aren't answered.
+	Limiter *RateLimiter
+	// MaxReplyLines is how many lines a reply is split into at most, 3 by
+	// default and all of them when <= 0, see SplitReply.
+	MaxReplyLines int
+
+	// mut guards the bot's nicks by network and the lines it sent that may
+	// still be echoed back, they're set from the goroutines of every network.
+	mut   sync.Mutex
+	nicks map[string]string
+	sent  map[string][]sentLine
+}
+
+// sentLine is a line the bot sent, counted as it was sent.
+type sentLine struct {
+	channel, text string
+	date          time.Time
+}
+
+// echoWindow is how long a line sent may take to be echoed back, the lines
+// sent longer ago are forgotten.
+const echoWindow = time.Minute
+
+// New creates a handler feeding the given stats.
+func New(s *stats.Stats) *Handler {
+	return &Handler{
+		Stats:         s,
+		Prefix:        defaultPrefix,
+		TopCount:      topCount,
+		MaxReplyLines: maxReplyLines,
+	}
+}
+
+// HandleRaw adds the event to the stats and runs any command it contains.
+func (h *Handler) HandleRaw(w irc.Writer, ev *irc.Event) {
+	channel, reply := h.Handle(ev.NetworkID, ev.Name, ev.Sender, ev.Args, eventTime(ev))
+	for _, line := range h.Lines(channel, reply) {
+		h.Sent(ev.NetworkID, channel, line)
+		w.Privmsg(channel, line)
+	}
+}
+
+// Lines splits a reply into the lines to send to the channel, see SplitReply
+// and MaxReplyLines.
+func (h *Handler) Lines(channel, reply string) []string {
+	return SplitReply(channel, reply, h.MaxReplyLines)
+}
+
+// SetNick tells the handler the bot's nick on a network, once registered
+// and after the nick is taken. The bot's nick changes are followed.
+func (h *Handler) SetNick(network, nick string) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if h.nicks == nil {
+		h.nicks = make(map[string]string)
+	}
+	h.nicks[strings.ToLower(network)] = nick
+}
+
+// Sent tells the handler the bot says a line in a channel, before it's sent so
+// its echo can't come first. It's counted with CountSelf, and its echo isn't
+// counted again.
+func (h *Handler) Sent(network, channel, text string) {
+	h.mut.Lock()
+	nick, ok := h.nicks[strings.ToLower(network)]
+	if !h.CountSelf || !ok || !isChannel(channel) {
+		h.mut.Unlock()
+		return
+	}
+
+	now := time.Now()
+	if h.sent == nil {
+		h.sent = make(map[string][]sentLine)
+	}
+	key := strings.ToLower(network)
+	h.sent[key] = append(unechoed(h.sent[key], now), sentLine{channel, text, now})
+	h.mut.Unlock()
+
+	kind, text := stats.Msg, text
+	if action, isAction := unpackAction(text); isAction {
+		kind, text = stats.Action, action
+	}
+	h.Stats.AddMessage(kind, network, channel, nick, now, text)
+}
+
+// self checks if a line was said by the bot. A line it says is skipped
+// when it isn't counted, or when it was already counted as it was sent.
+func (h *Handler) self(network string, mask stats.Hostmask, kind stats.MsgKind, channel, message string) (self, skip bool) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	key := strings.ToLower(network)
+	nick, ok := h.nicks[key]
+	if !ok || !strings.EqualFold(nick, mask.Nick) {
+		return false, false
+	}
+
+	if kind == stats.Nick {
+		h.nicks[key] = message
+	}
+	if kind != stats.Msg && kind != stats.Action && kind != stats.Notice {
+		return true, false
+	}
+	if !h.CountSelf {
+		return true, true
+	}
+
+	if kind == stats.Action {
+		message = ctcpDelim + "ACTION " + message + ctcpDelim
+	}
+	sent := unechoed(h.sent[key], time.Now())
+	for i, l := range sent {
+		if strings.EqualFold(l.channel, channel) && l.text == message {
+			h.sent[key] = append(sent[:i], sent[i+1:]...)
+			return true, true
+		}
+	}
+	h.sent[key] = sent
+	return true, false
+}
+
+// unechoed drops the lines sent that are too old to still be echoed.
+func unechoed(sent []sentLine, now time.Time) []sentLine {
+	i := 0
+	for i < len(sent) && now.Sub(sent[i].date) > echoWindow {
+		i++
+	}
+	return sent[i:]
+}
+
+// Handle adds a raw irc event to the stats, for use without ultimateq. If the
+// event was a command the reply and the channel to send it to are returned,
+// split it into lines to send with Lines.
+func (h *Handler) Handle(network, name, sender string, args []string, date time.Time) (channel, reply string) {
+	return h.handle(network, name, sender, args, date, "", true)
+}
+
+// HandleTagged is Handle for events carrying IRCv3 message tags, such as the
+// history a bouncer plays back. The server-time tag dates the event and its
+// msgid skips events that were already counted. Events played back in a
+// batch, or dated in the past, never run commands.
+func (h *Handler) HandleTagged(network, name, sender string, args []string, tags map[string]string) (channel, reply string) {
+	date, live := time.Now(), true
+	if t, err := time.Parse(time.RFC3339Nano, tags["time"]); err == nil {
+		date, live = t, time.Since(t) < liveWindow
+	}
+	if _, batched := tags["batch"]; batched {
+		live = false
+	}
+
+	return h.handle(network, name, sender, args, date, tags["msgid"], live)
+}
+
+// liveWindow is how old a server-time may be for the event to still be live.
+const liveWindow = time.Minute
+
+func (h *Handler) handle(network, name, sender string, args []string, date time.Time, msgid string, live bool) (channel, reply string) {
+	kind, channel, message, ok := Convert(name, args)
+	if !ok {
+		return "", ""
+	}
+
+	// servers set modes and send notices of their own, they aren't users
+	mask, valid := stats.ParseHostmask(sender)
+	if !valid || mask.IsServer() {
+		return "", ""
+	}
+
+	// the bot doesn't answer itself
+	self, skip := h.self(network, mask, kind, channel, message)
+	if skip {
+		return "", ""
+	}
+	if self {
+		live = false
+	}
+
+	added := h.Stats.AddMessageID(msgid, kind, network, channel, sender, date, message)
+
+	if added && live && kind == stats.Msg && len(channel) > 0 {
+		return channel, h.command(network, channel, mask.Nick, date, message)
+	}
+
+	return "", ""
+}
+
+// Convert maps the name and arguments of an irc event onto the arguments of
+// AddMessage, ok is false for events that should not be counted.
+func Convert(name string, args []string) (kind stats.MsgKind, channel, message string, ok bool) {
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	switch name {
+	case irc.PRIVMSG:
+		kind, message = stats.Msg, arg(1)
+		if action, isAction := unpackAction(message); isAction {
+			kind, message = stats.Action, action
+		}
+	case irc.NOTICE:
+		kind, message = stats.Notice, arg(1)
+	case irc.JOIN:
+		kind = stats.Join
+	case irc.PART:
+		kind, message = stats.Part, arg(1)
+	case irc.QUIT:
+		return stats.Quit, "", arg(0), true
+	case irc.KICK:
+		kind, message = stats.Kick, strings.TrimSpace(arg(1)+" "+arg(2))
+	case irc.NICK:
+		return stats.Nick, "", arg(0), true
+	case irc.TOPIC:
+		kind, message = stats.Topic, arg(1)
+	case irc.MODE:
+		kind, message = stats.Mode, arg(1)
+	default:
+		return kind, "", "", false
+	}
+
+	// private messages and user modes are not counted
+	channel = arg(0)
+	return kind, channel, message, isChannel(channel)
+}
+
+func isChannel(name string) bool {
+	return len(name) > 0 && strings.ContainsRune("#&!+", rune(name[0]))
+}
+
+// unpackAction returns the text of a CTCP ACTION message.
+func unpackAction(message string) (string, bool) {
+	if !strings.HasPrefix(message, ctcpDelim+"ACTION ") {
+		return "", false
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(message, ctcpDelim+"ACTION "), ctcpDelim), true
+}
+
+func eventTime(ev *irc.Event) time.Time {
+	if ev.Time.IsZero() {
+		return time.Now()
+	}
+	return ev.Time
+}
+
+// commands are the names of the commands answered.
+var commands = map[string]bool{"stats": true, "seen": true, "top": true, "url": true, "grep": true, "optout": true, "optin": true}
+
+// command runs a command of a nick and returns the reply, or nothing if the
+// message was not a command or the nick is over the rate limit.
+func (h *Handler) command(network, channel, nick string, date time.Time, message string) string {
+	if !strings.HasPrefix(message, h.Prefix) {
+		return ""
+	}
+
+	args := strings.Fields(strings.TrimPrefix(message, h.Prefix))
+	if len(args) == 0 || !commands[strings.ToLower(args[0])] {
+		return ""
+	}
+	if h.Limiter != nil && !h.Limiter.Allow(network, nick, date) {
+		return ""
+	}
+	// searching reads the raw store and opting out writes the stats, they
+	// mustn't be in View
+	switch strings.ToLower(args[0]) {
+	case "grep":
+		return h.grep(network, channel, args[1:])
+	case "optout":
+		return h.optOut(network, nick)
+	case "optin":
+		if !h.Stats.OptIn(network, nick) {
+			return nick + ": your lines are counted already"
+		}
+		return nick + ": your lines are counted again"
+	}
+
+	var reply string
+	h.Stats.View(func(tx *stats.ReadTx) {
+		switch strings.ToLower(args[0]) {
+		case "stats":
+			if len(args) < 2 {
+				reply = h.channelStats(tx, network, channel)
+			} else {
+				reply = h.userStats(tx, network, args[1])
+			}
+		case "seen":
+			if len(args) < 2 {
+				reply = "usage: " + h.Prefix + "seen <nick>"
+			} else {
+				reply = h.seen(tx, network, args[1])
+			}
+		case "top":
+			reply = h.top(tx, network, channel, args[1:])
+		case "url":
+			reply = h.urls(tx, network, channel)
+		}
+	})
+
+	return reply
+}
+
+func (h *Handler) channelStats(tx *stats.ReadTx, network, channel string) string {
+	c := tx.GetChannel(network, channel)
+	if c == nil {
+		return "no stats for " + channel
+	}
+
+	return fmt.Sprintf("%s: %d users, %d words counted", c.Name, len(c.UserIDs), c.WordCounter.Count)
+}
+
+func (h *Handler) userStats(tx *stats.ReadTx, network, nick string) string {
+	u := tx.GetUser(network, strings.ToLower(nick))
+	if u == nil {
+		return "I don't know " + nick
+	}
+
+	return fmt.Sprintf("%s: %d lines, %d words, %.1f words per line",
+		u.Nick, u.Lines, u.Words, u.WordsPerLine())
+}
+
+func (h *Handler) seen(tx *stats.ReadTx, network, nick string) string {
+	u := tx.GetUser(network, strings.ToLower(nick))
+	if u == nil || u.LastSeen.IsZero() {
+		return "I haven't seen " + nick
+	}
+
+	ago := tx.Now().Sub(u.LastSeen) / time.Second * time.Second
+	return fmt.Sprintf("%s was last seen %v ago", u.Nick, ago)
+}
+
+// top lists the top talkers of the channel, from the position or in the
+// positions asked for, eg. !top 11 or !top 11-20, TopCount of them at most.
+func (h *Handler) top(tx *stats.ReadTx, network, channel string, args []string) string {
+	c := tx.GetChannel(network, channel)
+	if c == nil {
+		return "no stats for " + channel
+	}
+
+	from, to := 1, 0
+	if len(args) > 0 {
+		var err error
+		if from, to, err = positions(args[0]); err != nil {
+			return "usage: " + h.Prefix + "top [from[-to]]"
+		}
+	}
+	count := h.TopCount
+	if to > 0 && (count <= 0 || to-from+1 < count) {
+		count = to - from + 1
+	}
+
+	it := c.RankIterator(tx)
+	it.Seek(from)
+	var parts []string
+	for count <= 0 || len(parts) < count {
+		r, ok := it.Next()
+		if !ok {
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%d. %s (%d)", r.Position, r.Nick, r.Lines))
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("only %d talkers in %s", it.Len(), c.Name)
+	}
+
+	return "top talkers: " + strings.Join(parts, ", ")
+}
+
+// positions reads a position, or a range of them, eg. 11 or 11-20.
+func positions(arg string) (from, to int, err error) {
+	first, last := arg, ""
+	if i := strings.IndexByte(arg, '-'); i >= 0 {
+		first, last = arg[:i], arg[i+1:]
+	}
+	if from, err = strconv.Atoi(first); err != nil || from < 1 {
+		return 0, 0, fmt.Errorf("bad position %q", arg)
+	}
+	if i := strings.IndexByte(arg, '-'); i < 0 {
+		return from, 0, nil
+	}
+	if to, err = strconv.Atoi(last); err != nil || to < from {
+		return 0, 0, fmt.Errorf("bad positions %q", arg)
+	}
+	return from, to, nil
+}
+
+func (h *Handler) urls(tx *stats.ReadTx, network, channel string) string {
+	c := tx.GetChannel(network, channel)
+	if c == nil || len(c.URLCounter.Top) == 0 {
+		return "no urls for " + channel
+	}
+
+	top := c.URLCounter.TopN(h.TopCount)
+	parts := make([]string, len(top))
+	for i, t := range top {
+		parts[i] = fmt.Sprintf("%s (%d)", t.Token, t.Count)
+	}
+
+	return "top urls: " + strings.Join(parts, ", ")
+}
+
+// optOut stops counting the nick. The nick isn't proof of who's behind it,
+// what was counted of it is only forgotten by the operators.
+func (h *Handler) optOut(network, nick string) string {
+	h.Stats.StopCounting(network, nick)
+	return nick + ": your lines aren't counted anymore, " + h.Prefix + "optin to be counted again; ask the operators to forget those counted"
+}
+
+// grep finds the last lines of the channel matching the words of the query,
+// as a regular expression when it's between slashes.
+func (h *Handler) grep(network, channel string, query []string) string {
+	if len(query) == 0 {
+		return "usage: " + h.Prefix + "grep <text> or " + h.Prefix + "grep /regexp/"
+	}
+
+	text := strings.Join(query, " ")
+	// the lines searching are found otherwise, this one first
+	limits := stats.SearchLimits{Max: grepCount, SkipPrefix: h.Prefix}
+	if len(text) > 2 && strings.HasPrefix(text, "/") && strings.HasSuffix(text, "/") {
+		text, limits.Regexp = text[1:len(text)-1], true
+	}
+
+	found, err := h.Stats.Search(network, channel, text, limits)
+	switch {
+	case errors.Is(err, stats.ErrNoRawStore):
+		return "no lines are kept to search"
+	case err != nil:
+		return "bad search: " + err.Error()
+	case len(found) == 0:
+		return "nothing said matches " + text
+	}
+
+	parts := make([]string, len(found))
+	for i, m := range found {
+		nick := m.Hostmask
+		if mask, ok := stats.ParseHostmask(m.Hostmask); ok {
+			nick = mask.Nick
+		}
+		parts[i] = fmt.Sprintf("[%s] <%s> %s", m.Date.UTC().Format("2006-01-02 15:04"), nick, m.Message)
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,381 @@
+package statsbot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/aarondl/ultimateq/irc"
+)
+
+type fakeWriter struct {
+	messages []string
+}
+
+func (f *fakeWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (f *fakeWriter) Privmsg(target string, args ...interface{}) error {
+	f.messages = append(f.messages, target+" "+fmt.Sprint(args...))
+	return nil
+}
+
+func (f *fakeWriter) Notice(target string, args ...interface{}) error {
+	return nil
+}
+
+func event(name, sender string, args ...string) *irc.Event {
+	return &irc.Event{
+		Name:      name,
+		Sender:    sender,
+		Args:      args,
+		Time:      time.Now(),
+		NetworkID: "network",
+	}
+}
+
+func TestHandler_HandleRaw(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.JOIN, "bob!b@host", "#chan"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "hello there"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "\x01ACTION waves\x01"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "me", "private"))
+	h.HandleRaw(w, event(irc.KICK, "bob!b@host", "#chan", "alice", "bye"))
+	h.HandleRaw(w, event(irc.MODE, "bob!b@host", "#chan", "+o", "alice"))
+	h.HandleRaw(w, event(irc.NICK, "bob!b@host", "robert"))
+	h.HandleRaw(w, event(irc.MODE, "irc.server.net", "#chan", "+nt"))
+	h.HandleRaw(w, event(irc.NOTICE, "irc.server.net", "#chan", "server notice"))
+
+	if u := s.GetUser("network", "irc.server.net"); u != nil {
+		t.Error("Should not count the server as a user.")
+	}
+
+	u := s.GetUser("network", "bob")
+	if u == nil {
+		t.Fatal("Should have added bob.")
+	}
+
+	if u.Lines != 1 {
+		t.Error("Should have counted one line, Got:", u.Lines)
+	}
+
+	if u.TextByKind[stats.Action].Lines != 1 {
+		t.Error("Should have counted the action.")
+	}
+
+	if u.KickCounters.Sent != 1 || u.Ops != 1 || u.NickChanges != 1 {
+		t.Error("Should have counted the kick, mode and nick change.")
+	}
+
+	if len(w.messages) != 0 {
+		t.Error("Should not reply to regular messages.")
+	}
+}
+
+func TestHandler_HandleTagged(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+
+	past := map[string]string{"time": "2014-03-01T08:00:00.000Z", "msgid": "abc"}
+	if _, reply := h.HandleTagged("network", irc.PRIVMSG, "bob!b@host", []string{"#chan", "!top"}, past); len(reply) > 0 {
+		t.Error("Should not run commands played back from the past.")
+	}
+	h.HandleTagged("network", irc.PRIVMSG, "bob!b@host", []string{"#chan", "!top"}, past)
+
+	u := s.GetUser("network", "bob")
+	if u == nil || u.Lines != 1 {
+		t.Fatal("Should have counted the played back message once.")
+	}
+	if want := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC); !u.LastSeen.Equal(want) {
+		t.Error("Should date the message by its server-time, Got:", u.LastSeen)
+	}
+
+	batched := map[string]string{"batch": "1", "msgid": "def"}
+	if _, reply := h.HandleTagged("network", irc.PRIVMSG, "bob!b@host", []string{"#chan", "!top"}, batched); len(reply) > 0 {
+		t.Error("Should not run commands played back in a batch.")
+	}
+
+	if _, reply := h.HandleTagged("network", irc.PRIVMSG, "bob!b@host", []string{"#chan", "!top"}, nil); len(reply) == 0 {
+		t.Error("Should run live commands.")
+	}
+}
+
+func TestHandler_self(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	h.SetNick("network", "bot")
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.JOIN, "Bot!b@host", "#chan"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "!top"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bot!b@host", "#chan", "!top"))
+	h.HandleRaw(w, event(irc.NICK, "bot!b@host", "bot_"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bot_!b@host", "#chan", "echoed"))
+
+	if len(w.messages) != 1 {
+		t.Error("Should only answer bob, answered", w.messages)
+	}
+	u := s.GetUser("network", "bot")
+	if u == nil || u.Lines != 0 || u.MessageCount != 2 {
+		t.Fatal("Should count the bot's join and nick change but not its lines:", u)
+	}
+	if u := s.GetUser("network", "bot_"); u != nil && u.Lines != 0 {
+		t.Error("Should follow the bot's nick change.")
+	}
+}
+
+func TestHandler_CountSelf(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	h.CountSelf = true
+	h.SetNick("network", "bot")
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "!seen bob"))
+	h.Sent("network", "#chan", "\x01ACTION waves\x01")
+	h.Sent("network", "bob", "a private message")
+
+	// with echo-message the lines come back once sent
+	h.HandleRaw(w, event(irc.PRIVMSG, "bot!b@host", "#chan", w.messages[0][len("#chan "):]))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bot!b@host", "#chan", "\x01ACTION waves\x01"))
+	// said by another client of the bot's
+	h.HandleRaw(w, event(irc.PRIVMSG, "bot!b@host", "#chan", "\x01ACTION waves\x01"))
+
+	u := s.GetUser("network", "bot")
+	if u == nil {
+		t.Fatal("Should count the lines of the bot.")
+	}
+	if u.Lines != 1 || u.TextByKind[stats.Action].Lines != 2 {
+		t.Errorf("Should count the reply and the actions once each, counted %d lines and %d actions",
+			u.Lines, u.TextByKind[stats.Action].Lines)
+	}
+	if s.GetChannel("network", "bob") != nil {
+		t.Error("Shouldn't count private messages.")
+	}
+	if len(w.messages) != 1 {
+		t.Error("Shouldn't answer the bot's own lines, answered", w.messages)
+	}
+}
+
+func TestHandler_Commands(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", "look http://google.com"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", "and http://google.com again"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "alice", "#chan", "nice"))
+
+	tests := []struct {
+		command string
+		expect  string
+	}{
+		{"!stats bob", "#chan bob: 2 lines"},
+		{"!seen bob", "#chan bob was last seen"},
+		{"!seen nobody", "#chan I haven't seen nobody"},
+		{"!top", "#chan top talkers: 1. carol (4), 2. bob (2), 3. alice (1)"},
+		{"!url", "#chan top urls: http://google.com (2)"},
+	}
+
+	for _, test := range tests {
+		w.messages = nil
+		h.HandleRaw(w, event(irc.PRIVMSG, "carol", "#chan", test.command))
+
+		if len(w.messages) != 1 || !strings.HasPrefix(w.messages[0], test.expect) {
+			t.Errorf("%s Expected: %q, Got: %q", test.command, test.expect, w.messages)
+		}
+	}
+
+	h.TopCount = 1
+	w.messages = nil
+	h.HandleRaw(w, event(irc.PRIVMSG, "carol", "#chan", "!top"))
+	if exp := "#chan top talkers: 1. carol (6)"; len(w.messages) != 1 || w.messages[0] != exp {
+		t.Errorf("Should list TopCount talkers, Expected: %q, Got: %q", exp, w.messages)
+	}
+
+	h.TopCount = 0
+	pages := []struct {
+		command string
+		expect  string
+	}{
+		{"!top 2", "#chan top talkers: 2. bob (2), 3. alice (1)"},
+		{"!top 2-2", "#chan top talkers: 2. bob (2)"},
+		{"!top 9", "#chan only 3 talkers in #chan"},
+		{"!top 3-1", "#chan usage: !top [from[-to]]"},
+	}
+	for _, test := range pages {
+		w.messages = nil
+		h.HandleRaw(w, event(irc.PRIVMSG, "carol", "#chan", test.command))
+
+		if len(w.messages) != 1 || w.messages[0] != test.expect {
+			t.Errorf("%s Expected: %q, Got: %q", test.command, test.expect, w.messages)
+		}
+	}
+}
+
+func TestHandler_grep(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.PRIVMSG, "carol", "#chan", "!grep gophers"))
+	if exp := "#chan no lines are kept to search"; len(w.messages) != 1 || w.messages[0] != exp {
+		t.Errorf("Should need a raw store, Expected: %q, Got: %q", exp, w.messages)
+	}
+
+	s.SetOptions(stats.Options{RawStore: &memoryStore{}})
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", "gophers are great"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "alice", "#chan", "I like gophers"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "alice", "#other", "gophers elsewhere"))
+
+	tests := []struct {
+		command string
+		expect  string
+	}{
+		{"!grep", "#chan usage: !grep <text>"},
+		{"!grep GOPHERS", "#chan [" + time.Now().UTC().Format("2006-01-02")},
+		{"!grep /^gophers/", "#chan ["},
+		{"!grep /(/", "#chan bad search:"},
+		{"!grep rust", "#chan nothing said matches rust"},
+	}
+	for _, test := range tests {
+		w.messages = nil
+		h.HandleRaw(w, event(irc.PRIVMSG, "carol", "#chan", test.command))
+		if len(w.messages) != 1 || !strings.HasPrefix(w.messages[0], test.expect) {
+			t.Errorf("%s Expected: %q, Got: %q", test.command, test.expect, w.messages)
+		}
+	}
+
+	w.messages = nil
+	h.HandleRaw(w, event(irc.PRIVMSG, "carol", "#chan", "!grep gophers"))
+	if len(w.messages) != 1 || !strings.Contains(w.messages[0], "<alice> I like gophers | [") || !strings.HasSuffix(w.messages[0], "<bob> gophers are great") {
+		t.Error("Should reply with the lines found, the last first:", w.messages)
+	}
+}
+
+func TestHandler_optOut(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", "hello"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", "!optout"))
+	if len(w.messages) != 1 || !strings.HasPrefix(w.messages[0], "#chan bob: your lines aren't counted anymore") {
+		t.Error("Should opt the user out:", w.messages)
+	}
+	// the command was counted before it was handled
+	if u := s.GetUser("network", "bob"); u == nil || u.Lines != 2 || !s.OptedOut("network", "bob") {
+		t.Error("Should stop counting the user who opted out, without forgetting them by their nick:", u)
+	}
+
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", "not counted"))
+	if u := s.GetUser("network", "bob"); u.Lines != 2 {
+		t.Error("Should not count the lines of the user who opted out.")
+	}
+
+	tests := []struct {
+		command string
+		expect  string
+	}{
+		{"!optin", "#chan bob: your lines are counted again"},
+		{"!optin", "#chan bob: your lines are counted already"},
+	}
+	for _, test := range tests {
+		w.messages = nil
+		h.HandleRaw(w, event(irc.PRIVMSG, "bob", "#chan", test.command))
+		if len(w.messages) != 1 || w.messages[0] != test.expect {
+			t.Errorf("%s Expected: %q, Got: %q", test.command, test.expect, w.messages)
+		}
+	}
+	// the second !optin was said once they were counted again
+	if u := s.GetUser("network", "bob"); u == nil || u.Lines != 3 {
+		t.Error("Should count the user again once they opt in:", u)
+	}
+}
+
+// memoryStore is a raw store keeping the messages in memory.
+type memoryStore struct {
+	messages []stats.RawMessage
+}
+
+func (m *memoryStore) Append(raw stats.RawMessage) { m.messages = append(m.messages, raw) }
+
+func (m *memoryStore) Replay(f func(stats.RawMessage)) error {
+	for _, raw := range m.messages {
+		f(raw)
+	}
+	return nil
+}
+
+func TestHandler_limits(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := New(s)
+	h.Limiter = NewRateLimiter(1, time.Hour)
+	w := &fakeWriter{}
+
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "!nothing"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "!top"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "!top"))
+	h.HandleRaw(w, event(irc.PRIVMSG, "alice!a@host", "#chan", "!seen bob"))
+	if len(w.messages) != 2 || !strings.HasPrefix(w.messages[0], "#chan top talkers") || !strings.HasPrefix(w.messages[1], "#chan bob was last seen") {
+		t.Error("Should answer each user once, and not count unknown commands:", w.messages)
+	}
+
+	h.Limiter = nil
+	h.MaxReplyLines = 2
+	for i := 0; i < 300; i++ {
+		s.AddMessage(stats.Msg, "network", "#chan", "spammer", time.Now(), fmt.Sprintf("http://example.com/%d-%s", i, strings.Repeat("x", 40)))
+	}
+	h.TopCount = 0
+	w.messages = nil
+	h.HandleRaw(w, event(irc.PRIVMSG, "bob!b@host", "#chan", "!url"))
+	if len(w.messages) != 2 || !strings.HasSuffix(w.messages[1], "...") {
+		t.Error("Should split long replies into two lines at most:", len(w.messages))
+	}
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"fmt"
 	"sort"
+	"time"
 
 	"github.com/DylanJ/stats"
 )
@@ -27,17 +27,49 @@ type UserJSON struct {
 	SSlaps         uint                    `json:"sslaps"`
 	RSlaps         uint                    `json:"rslaps"`
 	NickReferences map[string]uint         `json:"nickreferences"`
+	Language       string                  `json:"language"`
+	Mood           []stats.MoodPoint       `json:"mood"`
+	Corrections    uint                    `json:"corrections"`
+	Kinds          map[string]float64      `json:"kinds"`
+	FirstOfDay     uint                    `json:"firstofday"`
+	LastOfDay      uint                    `json:"lastofday"`
+	MaxPerMinute   uint                    `json:"maxperminute"`
+	Floods         uint                    `json:"floods"`
+	Starts         uint                    `json:"starts"`
+	SReactions     uint                    `json:"sreactions"`
+	RReactions     uint                    `json:"rreactions"`
 	Modes          stats.ModeCounters      `json:"modes"`
 	Basic          stats.BasicTextCounters `json:"basic"`
 }
 
 type ChannelStatsJSON struct {
-	TopUsers    []*UserJSON       `json:"users"`
-	HourlyChart stats.HourlyChart `json:"hourly"`
-	TopURLs     []stats.TopToken  `json:"urls"`
-	TopWords    []stats.TopToken  `json:"words"`
-	TopSwears   []stats.TopToken  `json:"swears"`
-	SwearCount  uint              `json:"swearcount"`
+	Name        string               `json:"name"`
+	TopUsers    []*UserJSON          `json:"users"`
+	HourlyChart stats.HourlyChart    `json:"hourly"`
+	TopURLs     []stats.TopToken     `json:"urls"`
+	TopWords    []stats.TopToken     `json:"words"`
+	TopSwears   []stats.TopToken     `json:"swears"`
+	SwearCount  uint                 `json:"swearcount"`
+	Languages   []stats.TopToken     `json:"languages"`
+	Mood        []stats.MoodPoint    `json:"mood"`
+	Hashtags    []stats.TopToken     `json:"hashtags"`
+	Handles     []stats.TopToken     `json:"handles"`
+	Kinds       map[string]float64   `json:"kinds"`
+	Floods      []stats.FloodEvent   `json:"floods"`
+	Starters    []stats.TopToken     `json:"starters"`
+	Champions   []stats.WeekChampion `json:"champions"`
+	Reigning    stats.Streak         `json:"reigning"`
+	Longest     stats.Streak         `json:"longest"`
+}
+
+// kindFractions returns the fraction of lines of each kind keyed by name.
+func kindFractions(k stats.KindTextCounters) map[string]float64 {
+	fractions := make(map[string]float64, len(k))
+	for kind := range k {
+		fractions[kind.String()] = k.Fraction(kind)
+	}
+
+	return fractions
 }
 
 type ByMessageCount []*UserJSON
@@ -46,20 +78,21 @@ func (a ByMessageCount) Len() int           { return len(a) }
 func (a ByMessageCount) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a ByMessageCount) Less(i, j int) bool { return a[i].MessageCount < a[j].MessageCount }
 
-func topUsers(s *stats.Stats, c *stats.Channel) []*UserJSON {
+// topUsers lists the users of a channel, their hours in the timezone of the
+// channel.
+func topUsers(tx *stats.ReadTx, c *stats.Channel, loc *time.Location) []*UserJSON {
 	var users []*UserJSON
 	users = make([]*UserJSON, 0)
+	// the quotes past their retention aren't shown before they're pruned
+	quotes := tx.Cutoffs().Quotes
 
 	for id, _ := range c.UserIDs {
-		if u, ok := s.Users[id]; ok {
-
-			fmt.Printf("%#v\n\n\n", u.Quotes)
-
+		if u, ok := tx.Users[id]; ok {
 			user := &UserJSON{
 				ID:             id,
 				Name:           u.Nick,
 				MessageCount:   u.BasicTextCounters.Lines,
-				HourlyChart:    u.HourlyChart,
+				HourlyChart:    u.HourlyChart.In(loc, tx.Now()),
 				Vocabulary:     u.WordCounter.Top,
 				VocabularySize: len(u.WordCounter.All),
 				TopSwears:      u.SwearCounter.Top,
@@ -74,12 +107,23 @@ func topUsers(s *stats.Stats, c *stats.Channel) []*UserJSON {
 				SSlaps:         u.SlapCounters.Sent,
 				RSlaps:         u.SlapCounters.Received,
 				NickReferences: u.NickReferences,
+				Language:       u.DominantLanguage(),
+				Mood:           u.Mood.Trend(),
+				Corrections:    u.SelfCorrections,
+				Kinds:          kindFractions(u.TextByKind),
+				FirstOfDay:     c.DailySpeakers.First[id],
+				LastOfDay:      c.DailySpeakers.Last[id],
+				MaxPerMinute:   u.Bursts.MaxPerMinute,
+				Floods:         u.Bursts.Floods,
+				Starts:         u.ConversationStarts,
+				SReactions:     u.Reactions.Sent,
+				RReactions:     u.Reactions.Received,
 				Modes:          u.ModeCounters,
 				Basic:          u.BasicTextCounters,
 			}
 
-			if m := u.Quotes.Random; m != nil {
-				user.Message = u.Quotes.Random.Message
+			if m := u.Quotes.Random; m.ID != 0 && stats.Kept(quotes, m.Date) {
+				user.Message = m.Message
 			}
 
 			users = append(users, user)
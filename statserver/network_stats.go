@@ -0,0 +1,11 @@
+package main
+
+import "github.com/DylanJ/stats"
+
+type NetworkStatsJSON struct {
+	Name        string                 `json:"name"`
+	HourlyChart stats.HourlyChart      `json:"hourly"`
+	TopURLs     []stats.TopToken       `json:"urls"`
+	TopWords    []stats.TopToken       `json:"words"`
+	Overlap     []stats.ChannelOverlap `json:"overlap"`
+}
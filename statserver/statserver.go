@@ -1,58 +1,253 @@
 package main
 
 import (
+	"encoding/json"
 	"errors"
+	"flag"
+	"io/ioutil"
+	"log/slog"
 	"net/http"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
 
 	"github.com/DylanJ/stats"
+	"github.com/DylanJ/stats/access"
 	"github.com/aarondl/jsonware"
 )
 
 const (
 	assetURL       = "/assuts/"
 	localAssetPath = "./html/assets"
+
+	// defaultTopURLs is how many urls a channel's stats list unless asked
+	// for another number
+	defaultTopURLs = 15
 )
 
 var st *stats.Stats
 
+// accessConfig is the json of the -access file, the same as the keys of the
+// ircstats configuration: the private channels of each network, or * for all
+// of them, the channels that each of the api_tokens may read, as a network
+// and channel apart by a space or *, and if a token is required.
+type accessConfig struct {
+	Private      map[string][]string `json:"private"`
+	APITokens    map[string][]string `json:"api_tokens"`
+	RequireToken bool                `json:"require_token"`
+}
+
+// loadAccess loads who may read the stats from the -access file, everyone may
+// read every channel without one.
+func loadAccess(path string) (*access.Access, error) {
+	var c accessConfig
+	if len(path) > 0 {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &c); err != nil {
+			return nil, err
+		}
+	}
+	if c.RequireToken && len(c.APITokens) == 0 {
+		return nil, errors.New("Can't require_token without api_tokens.")
+	}
+	return access.New(c.Private, c.APITokens, c.RequireToken), nil
+}
+
 func main() {
-	s := stats.NewStats()
-	StartServer(":8080", s)
+	accessPath := flag.String("access", "", "the json of the private channels and the api_tokens allowed them")
+	flag.Parse()
+
+	a, err := loadAccess(*accessPath)
+	if err != nil {
+		slog.Error("Failed loading the access", "err", err)
+		os.Exit(1)
+	}
+	s, err := stats.NewStats()
+	if err != nil {
+		slog.Error("Failed loading the stats", "err", err)
+		os.Exit(1)
+	}
+	StartServer(":8080", s, a)
 }
 
-// StartServer starts the webserver that will serve the stats pages.
-func StartServer(bind string, s *stats.Stats) {
+// StartServer starts the webserver that will serve the stats pages, those of
+// the private channels only to the tokens a allows them.
+func StartServer(bind string, s *stats.Stats, a *access.Access) {
 	st = s
 
 	http.Handle(assetURL, http.StripPrefix(assetURL, http.FileServer(http.Dir(localAssetPath))))
-	http.Handle("/api.json", jsonware.JSON(testHandler))
+	http.Handle("/api.json", a.Handler(jsonware.JSON(testHandler)))
+	http.Handle("/network.json", a.Handler(jsonware.JSON(networkHandler)))
+	http.Handle("/channels.json", a.Handler(jsonware.JSON(channelsHandler)))
 
 	http.ListenAndServe(bind, nil)
 }
 
-func testHandler(w http.ResponseWriter, r *http.Request) (*ChannelStatsJSON, error) {
-	st.RLock()
-	defer st.RUnlock()
+func testHandler(w http.ResponseWriter, r *http.Request) (data *ChannelStatsJSON, err error) {
+	st.View(func(tx *stats.ReadTx) {
+		data, err = channelStats(tx, r)
+	})
+	return data, err
+}
 
+func channelStats(tx *stats.ReadTx, r *http.Request) (*ChannelStatsJSON, error) {
 	network := r.Form.Get("network")
 	channel := r.Form.Get("channel")
 
-	ch := st.GetChannel(network, channel)
-	if ch == nil {
+	// the private channels are as unknown as those never counted
+	ch := tx.GetChannel(network, channel)
+	if ch == nil || !access.RequestVisibility(r).Allows(tx.Networks[ch.NetworkID].Name, ch.Name) {
 		return nil, jsonware.JSONErr{
 			Status: 404,
 			Err:    errors.New("Channel does not exist."),
 		}
 	}
 
+	return channelJSON(tx, ch, r), nil
+}
+
+// channelJSON builds the stats of a channel, with the top lists as long as
+// asked for and the hours in the channel's timezone.
+func channelJSON(tx *stats.ReadTx, ch *stats.Channel, r *http.Request) *ChannelStatsJSON {
+	top := topLimit(r, 0)
+	loc := tx.Location(r.Form.Get("network"), ch.Name)
+	users := topUsers(tx, ch, loc)
+
 	data := &ChannelStatsJSON{
-		HourlyChart: ch.HourlyChart,
-		TopURLs:     ch.URLCounter.Top[:15],
-		TopWords:    ch.WordCounter.Top,
-		TopSwears:   ch.SwearCounter.Top,
-		TopUsers:    topUsers(st, ch),
+		Name:        ch.Name,
+		HourlyChart: ch.HourlyChart.In(loc, tx.Now()),
+		TopURLs:     ch.URLCounter.TopN(topLimit(r, defaultTopURLs)),
+		TopWords:    ch.WordCounter.TopN(top),
+		TopSwears:   ch.SwearCounter.TopN(top),
+		TopUsers:    users[:stats.Limit(top, len(users))],
 		SwearCount:  ch.SwearCounter.Count,
+		Languages:   ch.LanguageCounter.TopN(top),
+		Mood:        ch.Mood.Trend(),
+		Hashtags:    ch.HashtagCounter.TopN(top),
+		Handles:     ch.HandleCounter.TopN(top),
+		Kinds:       kindFractions(ch.TextByKind),
+		Floods:      ch.FloodHistory.Floods,
+		Starters:    ch.Starters.TopN(top),
+		Champions:   ch.Leaderboard.Champions(),
+	}
+
+	data.Reigning, _ = ch.Leaderboard.Reigning()
+	data.Longest, _ = ch.Leaderboard.LongestStreak()
+
+	return data
+}
+
+func channelsHandler(w http.ResponseWriter, r *http.Request) (data []*ChannelStatsJSON, err error) {
+	st.View(func(tx *stats.ReadTx) {
+		data, err = channelsStats(tx, r)
+	})
+	return data, err
+}
+
+// channelsStats builds the stats of every channel of a network, sorted by
+// name. The channels are built in parallel, by as many workers as there are
+// CPUs.
+func channelsStats(tx *stats.ReadTx, r *http.Request) ([]*ChannelStatsJSON, error) {
+	n := tx.GetNetwork(r.Form.Get("network"))
+	if n == nil {
+		return nil, jsonware.JSONErr{
+			Status: 404,
+			Err:    errors.New("Network does not exist."),
+		}
+	}
+
+	visible := access.RequestVisibility(r)
+	indexes := make(chan int, len(n.ChannelIDs))
+	for i := range n.ChannelIDs {
+		indexes <- i
 	}
+	close(indexes)
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(n.ChannelIDs) {
+		workers = len(n.ChannelIDs)
+	}
+
+	// every worker fills in the channels it took, there's nothing to guard
+	built := make([]*ChannelStatsJSON, len(n.ChannelIDs))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if ch := tx.Channel(n.ChannelIDs[i]); ch != nil && visible.Allows(n.Name, ch.Name) {
+					built[i] = channelJSON(tx, ch, r)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	data := make([]*ChannelStatsJSON, 0, len(built))
+	for _, c := range built {
+		if c != nil {
+			data = append(data, c)
+		}
+	}
+	sort.Slice(data, func(i, j int) bool { return data[i].Name < data[j].Name })
 
 	return data, nil
 }
+
+func networkHandler(w http.ResponseWriter, r *http.Request) (data *NetworkStatsJSON, err error) {
+	st.View(func(tx *stats.ReadTx) {
+		data, err = networkStats(tx, r)
+	})
+	return data, err
+}
+
+// networkStats builds the stats of a network. They sum up all of its channels,
+// so they're only served to those who may read every one of them.
+func networkStats(tx *stats.ReadTx, r *http.Request) (*NetworkStatsJSON, error) {
+	n := tx.GetNetwork(r.Form.Get("network"))
+	if n == nil || !allowsNetwork(tx, n, access.RequestVisibility(r)) {
+		return nil, jsonware.JSONErr{
+			Status: 404,
+			Err:    errors.New("Network does not exist."),
+		}
+	}
+
+	data := &NetworkStatsJSON{
+		Name:        n.Name,
+		HourlyChart: n.HourlyChart.In(tx.Location(n.Name, ""), tx.Now()),
+		TopURLs:     n.URLCounter.TopN(topLimit(r, 0)),
+		TopWords:    n.WordCounter.TopN(topLimit(r, 0)),
+		Overlap:     n.ChannelOverlap(1),
+	}
+
+	return data, nil
+}
+
+// allowsNetwork checks if every channel of a network may be served.
+func allowsNetwork(tx *stats.ReadTx, n *stats.Network, visible access.Visibility) bool {
+	if visible == nil {
+		return true
+	}
+	for _, id := range n.ChannelIDs {
+		if !visible.Allows(n.Name, tx.Channels[id].Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// topLimit is how long the top lists asked for with ?top=n are, def when
+// not asked. top=0 lists everything kept.
+func topLimit(r *http.Request, def int) int {
+	n, err := strconv.Atoi(r.Form.Get("top"))
+	if err != nil {
+		return def
+	}
+	return n
+}
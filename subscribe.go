@@ -0,0 +1,209 @@
+package stats
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriptionBuffer is how many events a subscriber can fall behind by
+// before the next ones are dropped.
+const subscriptionBuffer = 64
+
+// StatEventKind is what a StatEvent is about.
+type StatEventKind int
+
+// These are the kinds of events the stats compute.
+const (
+	// NewUserEvent is the first message of a user the network hadn't seen.
+	NewUserEvent StatEventKind = iota
+	// DayRecordEvent is a channel saying more lines on a day than on its
+	// best day so far, the days are those the stats read the channel in,
+	// see Options.Location. It's sent once a day at most.
+	DayRecordEvent
+	// ReactionEvent is a user's message being acknowledged by another, the
+	// karma of the stats, see Options.ReactionWords.
+	ReactionEvent
+)
+
+var statEventKindNames = map[StatEventKind]string{
+	NewUserEvent:   "new user",
+	DayRecordEvent: "day record",
+	ReactionEvent:  "reaction",
+}
+
+// String returns the name of the kind.
+func (k StatEventKind) String() string {
+	if name, ok := statEventKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// StatEvent is something the stats computed from a message as it was counted.
+type StatEvent struct {
+	Kind    StatEventKind
+	Network string
+	// Channel is empty for the new users first seen outside channels, such
+	// as when quitting.
+	Channel string
+	// Nick is the new user, or the user reacted to.
+	Nick string
+	// From is the user who reacted, for ReactionEvent.
+	From string
+	// Date is the date of the message.
+	Date time.Time
+	// Count is the lines said that day for DayRecordEvent, and the
+	// reactions the user has received for ReactionEvent.
+	Count uint
+	// Previous is the lines of the record day beaten, for DayRecordEvent.
+	Previous uint
+}
+
+// EventFilter picks the events a subscriber is sent.
+type EventFilter struct {
+	// Kinds are the kinds of events sent, every kind when empty.
+	Kinds []StatEventKind
+	// Network and Channel, when set, only send the events of the network
+	// and channel, in any case.
+	Network string
+	Channel string
+}
+
+// matches checks if the filter picks the event.
+func (f EventFilter) matches(e StatEvent) bool {
+	if len(f.Network) > 0 && !strings.EqualFold(f.Network, e.Network) {
+		return false
+	}
+	if len(f.Channel) > 0 && !strings.EqualFold(f.Channel, e.Channel) {
+		return false
+	}
+	if len(f.Kinds) == 0 {
+		return true
+	}
+	for _, k := range f.Kinds {
+		if k == e.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// subscription is a subscriber and the events it's sent.
+type subscription struct {
+	filter EventFilter
+	events chan StatEvent
+}
+
+// subscribers are the subscriptions to the events of the stats, and the
+// records of the channels they're computed against.
+type subscribers struct {
+	mut   sync.Mutex
+	subs  []*subscription
+	count int
+	// records are the record days of the channels by id, for the day of
+	// the last message.
+	records map[uint]*dayRecord
+}
+
+// dayRecord is the record a channel's day has to beat.
+type dayRecord struct {
+	day    string
+	record uint
+	broken bool
+}
+
+// Subscribe sends the events picked by the filter as they're computed, to
+// react to records being broken, reactions and new users, for webhooks,
+// announcers or live dashboards. Events are computed as the messages are
+// counted, history played back included, check their dates to tell them
+// apart. A subscriber falling behind misses events rather than holding up
+// the stats. The events are sent until Unsubscribe.
+func (s *Stats) Subscribe(filter EventFilter) <-chan StatEvent {
+	sub := &subscription{filter: filter, events: make(chan StatEvent, subscriptionBuffer)}
+
+	s.subs.mut.Lock()
+	defer s.subs.mut.Unlock()
+	s.subs.subs = append(s.subs.subs, sub)
+	s.subs.count++
+	return sub.events
+}
+
+// Unsubscribe stops sending events to a subscriber and closes its channel.
+// It does nothing for channels that aren't subscribed.
+func (s *Stats) Unsubscribe(events <-chan StatEvent) {
+	s.subs.mut.Lock()
+	defer s.subs.mut.Unlock()
+
+	for i, sub := range s.subs.subs {
+		if sub.events == events {
+			s.subs.subs = append(s.subs.subs[:i], s.subs.subs[i+1:]...)
+			s.subs.count--
+			close(sub.events)
+			break
+		}
+	}
+	if s.subs.count == 0 {
+		s.subs.records = nil
+	}
+}
+
+// subscribed checks if anyone is sent events, they're computed otherwise.
+func (s *Stats) subscribed() bool {
+	s.subs.mut.Lock()
+	defer s.subs.mut.Unlock()
+
+	return s.subs.count > 0
+}
+
+// publish sends an event to the subscribers picking it.
+func (s *Stats) publish(e StatEvent) {
+	s.subs.mut.Lock()
+	defer s.subs.mut.Unlock()
+
+	for _, sub := range s.subs.subs {
+		if !sub.filter.matches(e) {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+		}
+	}
+}
+
+// publishRecord sends a DayRecordEvent when the message made its channel's
+// day beat the record of the other days.
+func (s *Stats) publishRecord(n *Network, c *Channel, message *Message) {
+	loc := s.opts.location(n.Name, c.Name)
+	day := message.Date.In(loc).Format(dayFormat)
+
+	s.subs.mut.Lock()
+	if s.subs.records == nil {
+		s.subs.records = make(map[uint]*dayRecord)
+	}
+	r, ok := s.subs.records[c.ID]
+	if !ok || r.day != day {
+		_, record := c.Days.In(loc).Record(day)
+		r = &dayRecord{day: day, record: record}
+		s.subs.records[c.ID] = r
+	}
+	lines := c.Days.DayIn(day, loc)
+	// a channel's first day beats nothing
+	broken := !r.broken && r.record > 0 && lines > r.record
+	if broken {
+		r.broken = true
+	}
+	s.subs.mut.Unlock()
+
+	if broken {
+		s.publish(StatEvent{
+			Kind:     DayRecordEvent,
+			Network:  n.Name,
+			Channel:  c.Name,
+			Date:     message.Date,
+			Count:    lines,
+			Previous: r.record,
+		})
+	}
+}
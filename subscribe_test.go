@@ -0,0 +1,122 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+// received takes the events sent so far.
+func received(events <-chan StatEvent) []StatEvent {
+	var got []StatEvent
+	for {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		default:
+			return got
+		}
+	}
+}
+
+func TestStats_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	all := s.Subscribe(EventFilter{})
+	reactions := s.Subscribe(EventFilter{Kinds: []StatEventKind{ReactionEvent}, Channel: "#TEST"})
+	other := s.Subscribe(EventFilter{Network: "other"})
+
+	date := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.AddMessage(Msg, network, channel, "alice", date, "vim is better than emacs")
+	s.AddMessage(Msg, network, channel, "bob", date, "+1")
+	s.AddMessage(Msg, network, "#elsewhere", "alice", date, "hi")
+
+	got := received(all)
+	if len(got) != 3 {
+		t.Fatal("Should send every event, sent", got)
+	}
+	if e := got[0]; e.Kind != NewUserEvent || e.Nick != "alice" || e.Channel != channel || !e.Date.Equal(date) {
+		t.Error("Should send alice being new, sent", e)
+	}
+	if e := got[1]; e.Kind != NewUserEvent || e.Nick != "bob" {
+		t.Error("Should send bob being new, sent", e)
+	}
+	if e := got[2]; e.Kind != ReactionEvent || e.Nick != "alice" || e.From != "bob" || e.Count != 1 {
+		t.Error("Should send bob reacting to alice, sent", e)
+	}
+
+	if got := received(reactions); len(got) != 1 || got[0].Kind != ReactionEvent {
+		t.Error("Should only send the reactions of the channel, sent", got)
+	}
+	if got := received(other); len(got) != 0 {
+		t.Error("Should only send the events of the network, sent", got)
+	}
+
+	s.Unsubscribe(all)
+	if _, ok := <-all; ok {
+		t.Error("Should close the channel once unsubscribed.")
+	}
+	s.Unsubscribe(all)
+}
+
+func TestStats_SubscribeDayRecord(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, day, "foo")
+	}
+
+	records := s.Subscribe(EventFilter{Kinds: []StatEventKind{DayRecordEvent}})
+	next := day.AddDate(0, 0, 1)
+	for i := 0; i < 5; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, next, "bar")
+	}
+
+	got := received(records)
+	if len(got) != 1 {
+		t.Fatal("Should send the record once, sent", got)
+	}
+	if e := got[0]; e.Channel != channel || e.Count != 3 || e.Previous != 2 {
+		t.Error("Should send the third line beating the two of the day before, sent", e)
+	}
+}
+
+func TestStats_SubscribeDayRecordLocation(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{Location: time.FixedZone("EST", -5*60*60)})
+	// the evening of the 1st in toronto, the 2nd in utc
+	day := time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, day.Add(-4*time.Hour), "foo")
+	}
+
+	records := s.Subscribe(EventFilter{Kinds: []StatEventKind{DayRecordEvent}})
+	s.AddMessage(Msg, network, channel, hostmask, day, "bar")
+	if got := received(records); len(got) != 0 {
+		t.Fatal("Should not beat anything on the first day of the channel's timezone, sent", got)
+	}
+	for i := 0; i < 4; i++ {
+		s.AddMessage(Msg, network, channel, hostmask, day.Add(12*time.Hour), "bar")
+	}
+	if got := received(records); len(got) != 1 || got[0].Count != 4 || got[0].Previous != 3 {
+		t.Error("Should beat the record of the day before in the channel's timezone, sent", got)
+	}
+}
+
+func TestStats_SubscribeBehind(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	events := s.Subscribe(EventFilter{Kinds: []StatEventKind{NewUserEvent}})
+	for i := 0; i < subscriptionBuffer+10; i++ {
+		s.AddMessage(Msg, network, channel, string(rune('a'+i%26))+string(rune('a'+i/26)), time.Now(), "hi")
+	}
+
+	if got := received(events); len(got) != subscriptionBuffer {
+		t.Errorf("Should drop the events of a subscriber behind, kept %d", len(got))
+	}
+}
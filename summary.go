@@ -0,0 +1,167 @@
+package stats
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChannelSummary is a copy of what was counted in a channel. It shares
+// nothing with the stats, so it may be kept and read without locking them
+// while messages are added, unlike the Channel it was copied from.
+type ChannelSummary struct {
+	Network    string
+	Name       string
+	Topic      string
+	Messages   uint
+	Users      int
+	LastActive time.Time
+	// LinesToday are the lines said today so far, the day of the clock of
+	// the stats in the timezone of the channel.
+	LinesToday uint
+	// HourlyChart is in UTC, see HourlyChart.In.
+	HourlyChart  HourlyChart
+	TopWords     TopTokenArray
+	TopURLs      TopTokenArray
+	Questions    uint
+	Exclamations uint
+	AllCaps      uint
+	Swears       uint
+	LastQuote    Message
+	RandomQuote  Message
+}
+
+// UserSummary is a copy of what was counted of a user on a network, see
+// ChannelSummary.
+type UserSummary struct {
+	Network  string
+	Nick     string
+	Hostmask string
+	Messages uint
+	BasicTextCounters
+	// HourlyChart is in UTC, see HourlyChart.In.
+	HourlyChart  HourlyChart
+	TopWords     TopTokenArray
+	Questions    uint
+	Exclamations uint
+	AllCaps      uint
+	Swears       uint
+	LastSeen     time.Time
+	LastQuote    Message
+	RandomQuote  Message
+	// Channels are the names of the channels the user was seen in, in
+	// lower case and sorted.
+	Channels []string
+}
+
+// ChannelSummary copies what was counted in a channel, ok is false when
+// there's no such channel or it failed to load.
+func (s *Stats) ChannelSummary(network, channel string) (summary ChannelSummary, ok bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	n := s.lookupNetwork(network)
+	if n == nil {
+		return ChannelSummary{}, false
+	}
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	c := n.channels[strings.ToLower(channel)].load(s)
+	if c == nil {
+		return ChannelSummary{}, false
+	}
+	return c.summarize(n), true
+}
+
+// UserSummary copies what was counted of a user, ok is false when there's no
+// such user.
+func (s *Stats) UserSummary(network, nick string) (summary UserSummary, ok bool) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	n := s.lookupNetwork(network)
+	if n == nil {
+		return UserSummary{}, false
+	}
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	u := n.users[n.fold(nick)]
+	if u == nil {
+		return UserSummary{}, false
+	}
+	return u.summarize(n), true
+}
+
+// ChannelSummary is Stats.ChannelSummary inside of View.
+func (tx *ReadTx) ChannelSummary(network, channel string) (ChannelSummary, bool) {
+	n := tx.s.lookupNetwork(network)
+	c := tx.GetChannel(network, channel)
+	if c == nil {
+		return ChannelSummary{}, false
+	}
+	return c.summarize(n), true
+}
+
+// UserSummary is Stats.UserSummary inside of View.
+func (tx *ReadTx) UserSummary(network, nick string) (UserSummary, bool) {
+	n := tx.s.lookupNetwork(network)
+	u := tx.GetUser(network, nick)
+	if u == nil {
+		return UserSummary{}, false
+	}
+	return u.summarize(n), true
+}
+
+// summarize copies the channel of the network.
+func (c *Channel) summarize(n *Network) ChannelSummary {
+	loc := n.stats.opts.location(n.Name, c.Name)
+	today := n.stats.opts.now().In(loc).Format(dayFormat)
+
+	return ChannelSummary{
+		Network:      n.Name,
+		Name:         c.Name,
+		Topic:        c.Topic,
+		Messages:     c.MessageCount,
+		Users:        len(c.UserIDs),
+		LastActive:   c.LastActive,
+		LinesToday:   c.Days.DayIn(today, loc),
+		HourlyChart:  c.HourlyChart,
+		TopWords:     append(TopTokenArray(nil), c.WordCounter.Top...),
+		TopURLs:      append(TopTokenArray(nil), c.URLCounter.Top...),
+		Questions:    uint(c.QuestionsCount),
+		Exclamations: uint(c.ExclamationsCount),
+		AllCaps:      uint(c.AllCapsCount),
+		Swears:       c.SwearCounter.Count,
+		LastQuote:    c.Quotes.Last,
+		RandomQuote:  c.Quotes.Random,
+	}
+}
+
+// summarize copies the user of the network.
+func (u *User) summarize(n *Network) UserSummary {
+	channels := make([]string, 0, len(u.ChannelUsers))
+	for name := range u.ChannelUsers {
+		channels = append(channels, name)
+	}
+	sort.Strings(channels)
+
+	return UserSummary{
+		Network:           n.Name,
+		Nick:              u.Nick,
+		Hostmask:          u.Hostmask,
+		Messages:          u.MessageCount,
+		BasicTextCounters: u.BasicTextCounters,
+		HourlyChart:       u.HourlyChart,
+		TopWords:          append(TopTokenArray(nil), u.WordCounter.Top...),
+		Questions:         uint(u.QuestionsCount),
+		Exclamations:      uint(u.ExclamationsCount),
+		AllCaps:           uint(u.AllCapsCount),
+		Swears:            u.SwearCounter.Count,
+		LastSeen:          u.LastSeen,
+		LastQuote:         u.Quotes.Last,
+		RandomQuote:       u.Quotes.Random,
+		Channels:          channels,
+	}
+}
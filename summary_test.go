@@ -0,0 +1,98 @@
+package stats
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats_ChannelSummary(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "why https://zqz.ca?")
+	s.AddMessage(Msg, network, channel, "aaron", time.Now(), "HELLO THERE")
+
+	c, ok := s.ChannelSummary(network, "#TEST")
+	if !ok {
+		t.Fatal("Should find the channel.")
+	}
+	if c.Network != network || c.Name != channel || c.Messages != 2 || c.Users != 2 || c.Questions != 1 || c.AllCaps != 1 {
+		t.Error("Should copy the counters of the channel:", c)
+	}
+	if len(c.TopURLs) != 1 || c.LastQuote.Message != "HELLO THERE" {
+		t.Error("Should copy the top urls and quotes:", c.TopURLs, c.LastQuote)
+	}
+
+	// the copy doesn't change with the channel
+	c.TopWords[0].Count = 100
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "more")
+	if got := s.GetChannel(network, channel); got.WordCounter.Top[0].Count == 100 || c.Messages != 2 {
+		t.Error("Should share nothing with the channel.")
+	}
+
+	if _, ok := s.ChannelSummary(network, "#nowhere"); ok {
+		t.Error("Shouldn't find channels that don't exist.")
+	}
+	if _, ok := s.ChannelSummary("nowhere", channel); ok {
+		t.Error("Shouldn't find channels of networks that don't exist.")
+	}
+}
+
+func TestStats_UserSummary(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+	s.AddMessage(Msg, network, "#other", hostmask, time.Now(), "bar!")
+
+	u, ok := s.UserSummary(network, "PHISH")
+	if !ok {
+		t.Fatal("Should find the user.")
+	}
+	if u.Nick != nick || u.Hostmask != hostmask || u.Messages != 2 || u.Lines != 2 || u.Words != 3 || u.Exclamations != 1 {
+		t.Error("Should copy the counters of the user:", u)
+	}
+	if len(u.Channels) != 2 || u.Channels[0] != "#other" || u.Channels[1] != channel {
+		t.Error("Should list the channels of the user:", u.Channels)
+	}
+	if _, ok := s.UserSummary(network, "nobody"); ok {
+		t.Error("Shouldn't find users that don't exist.")
+	}
+
+	s.View(func(tx *ReadTx) {
+		if u, ok := tx.UserSummary(network, nick); !ok || u.Messages != 2 {
+			t.Error("Should copy the user inside of View:", u)
+		}
+		if c, ok := tx.ChannelSummary(network, channel); !ok || c.Messages != 1 {
+			t.Error("Should copy the channel inside of View:", c)
+		}
+	})
+}
+
+func TestStats_summariesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "first")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some words")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c, _ := s.ChannelSummary(network, channel)
+			u, _ := s.UserSummary(network, nick)
+			if c.Messages == 0 || u.Lines == 0 {
+				t.Error("Should copy what was counted so far.")
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -5,7 +5,11 @@ import (
 	"strings"
 )
 
-var swearRegex = regexp.MustCompile(`ass[^aeiou][[:alpha:]]*|[[:alpha:]]*(?:fuck|tits|whore|bitch|cunt|pussy|dick|fag|shit|nigger|cock)[[:alpha:]]*`)
+// swearRoots are the words swears are built on, ass only counts when it's
+// not followed by a vowel.
+var swearRoots = []string{"ass", "fuck", "tits", "whore", "bitch", "cunt", "pussy", "dick", "fag", "shit", "nigger", "cock"}
+
+var swearRegex = regexp.MustCompile(`ass[^aeiou][[:alpha:]]*|[[:alpha:]]*(?:` + strings.Join(swearRoots[1:], "|") + `)[[:alpha:]]*`)
 
 type SwearCounter struct {
 	TokenCounter
@@ -18,13 +22,25 @@ func NewSwearCounter() SwearCounter {
 }
 
 func (s *SwearCounter) addMessage(message *Message) {
-	words := strings.Fields(message.Message)
+	t := message.tokens()
+	if !hasSwearRoot(t.lower) {
+		return
+	}
 
-	for _, word := range words {
-		r := swearRegex.FindStringSubmatch(strings.ToLower(word))
+	for _, word := range t.Lower {
+		if swear := swearRegex.FindString(word); len(swear) > 0 {
+			s.addToken(t.intern(swear))
+		}
+	}
+}
 
-		if len(r) > 0 {
-			s.addToken(r[0])
+// hasSwearRoot checks if lowercased text could hold a swear, most messages
+// don't and are skipped without running the regexp on every word.
+func hasSwearRoot(text string) bool {
+	for _, root := range swearRoots {
+		if strings.Contains(text, root) {
+			return true
 		}
 	}
+	return false
 }
@@ -0,0 +1,119 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// pollTimeout is how long the Bot API may hold a getUpdates request open.
+const pollTimeout = 30 * time.Second
+
+// DefaultAPI is the Bot API server used when the client doesn't name one.
+const DefaultAPI = "https://api.telegram.org"
+
+// Client polls the Bot API for the updates of a bot added to the groups and
+// feeds them into an adapter. The bot needs its privacy mode turned off to
+// see every message of a group.
+type Client struct {
+	Adapter *Adapter
+	Token   string
+	// API is the base url of the Bot API server, DefaultAPI when empty.
+	API string
+	// Offset is the id of the next update to fetch. Updates before it are
+	// forgotten by the server.
+	Offset int64
+
+	HTTP *http.Client
+}
+
+// NewClient creates a client for the bot with the token.
+func NewClient(a *Adapter, token string) *Client {
+	return &Client{
+		Adapter: a,
+		Token:   token,
+	}
+}
+
+type updatesResponse struct {
+	OK          bool     `json:"ok"`
+	Description string   `json:"description"`
+	Result      []Update `json:"result"`
+}
+
+// Poll fetches the waiting updates once, returning the number of updates
+// counted.
+func (c *Client) Poll() (int, error) {
+	api := c.API
+	if len(api) == 0 {
+		api = DefaultAPI
+	}
+
+	query := url.Values{
+		"timeout":         {fmt.Sprint(int(pollTimeout / time.Second))},
+		"allowed_updates": {`["message"]`},
+	}
+	if c.Offset > 0 {
+		query.Set("offset", fmt.Sprint(c.Offset))
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = &http.Client{Timeout: 2 * pollTimeout}
+	}
+
+	resp, err := client.Get(strings.TrimSuffix(api, "/") + "/bot" + c.Token + "/getUpdates?" + query.Encode())
+	if err != nil {
+		// the error holds the url and with it the token
+		return 0, fmt.Errorf("telegram: getUpdates failed: %v", stripToken(err, c.Token))
+	}
+	defer resp.Body.Close()
+
+	var ur updatesResponse
+	if err = json.NewDecoder(resp.Body).Decode(&ur); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, err
+	}
+	if resp.StatusCode != http.StatusOK || !ur.OK {
+		return 0, fmt.Errorf("telegram: getUpdates failed: %s %s", resp.Status, ur.Description)
+	}
+
+	counted := 0
+	for _, u := range ur.Result {
+		if c.Adapter.HandleUpdate(u) {
+			counted++
+		}
+		if u.UpdateID >= c.Offset {
+			c.Offset = u.UpdateID + 1
+		}
+	}
+	return counted, nil
+}
+
+// Run polls until stop is closed, waiting a little after failed polls.
+func (c *Client) Run(stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		if _, err := c.Poll(); err != nil {
+			select {
+			case <-stop:
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
+
+func stripToken(err error, token string) string {
+	if len(token) == 0 {
+		return err.Error()
+	}
+	return strings.Replace(err.Error(), token, "<token>", -1)
+}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestClient_Poll(t *testing.T) {
+	t.Parallel()
+
+	var offsets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botsecret/getUpdates" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"ok":false,"description":"Unauthorized"}`))
+			return
+		}
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+
+		json.NewEncoder(w).Encode(updatesResponse{OK: true, Result: []Update{
+			message(41, dylan, "hello"),
+			message(42, aaron, "hi"),
+		}})
+	}))
+	defer server.Close()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(New(s, "zkpq"), "secret")
+	c.API = server.URL
+
+	if n, err := c.Poll(); err != nil || n != 2 {
+		t.Fatal("Should count both messages:", n, err)
+	}
+	if n, err := c.Poll(); err != nil || n != 0 {
+		t.Fatal("Should not count the same updates twice:", n, err)
+	}
+	if len(offsets) != 2 || offsets[0] != "" || offsets[1] != "43" || c.Offset != 43 {
+		t.Error("Should continue from the last update:", offsets, c.Offset)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should have counted dylan's message.")
+	}
+
+	c.Token = "wrong"
+	if _, err := c.Poll(); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Error("Should fail when the bot api refuses the token:", err)
+	}
+}
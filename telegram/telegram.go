@@ -0,0 +1,176 @@
+// Package telegram feeds the messages of Telegram groups into a Stats
+// database, so groups bridged to an IRC channel get stats covering both
+// sides. Updates come from the Bot API, either by polling with a Client or by
+// handing the adapter the updates posted to a webhook.
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// Update is a Bot API update, only the fields needed for stats are decoded.
+type Update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *Message `json:"message"`
+}
+
+// Message is a message sent to a chat.
+type Message struct {
+	MessageID      int64  `json:"message_id"`
+	From           *User  `json:"from"`
+	Chat           Chat   `json:"chat"`
+	Date           int64  `json:"date"`
+	Text           string `json:"text"`
+	Caption        string `json:"caption"`
+	NewChatMembers []User `json:"new_chat_members"`
+	LeftChatMember *User  `json:"left_chat_member"`
+	NewChatTitle   string `json:"new_chat_title"`
+}
+
+// User is a Telegram user or bot.
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+}
+
+// Chat is the chat a message was sent to.
+type Chat struct {
+	ID       int64  `json:"id"`
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Username string `json:"username"`
+}
+
+// defaultNetwork is the network groups are counted under when the adapter
+// doesn't name one.
+const defaultNetwork = "telegram"
+
+// Adapter maps the updates of groups onto AddMessage calls.
+type Adapter struct {
+	Stats *stats.Stats
+	// Network is the network the groups are counted under. To merge the
+	// stats with a bridged IRC network use the IRC network's name.
+	Network string
+	// Chats maps chat ids to channel names. Groups not in it are named after
+	// their public username, or their title.
+	Chats map[int64]string
+	// IgnoreBots skips the messages of bots, such as the bridge relaying
+	// messages from IRC which are already counted.
+	IgnoreBots bool
+
+	mut sync.Mutex
+}
+
+// New creates an adapter feeding the stats under the network.
+func New(s *stats.Stats, network string) *Adapter {
+	if len(network) == 0 {
+		network = defaultNetwork
+	}
+	return &Adapter{
+		Stats:      s,
+		Network:    network,
+		Chats:      make(map[int64]string),
+		IgnoreBots: true,
+	}
+}
+
+// HandleUpdate adds the message of an update to the stats, returning false if
+// there was nothing to count. Only messages sent to groups are counted.
+// Messages carry ids, an update handed over twice is counted once.
+func (a *Adapter) HandleUpdate(u Update) bool {
+	m := u.Message
+	if m == nil || (m.Chat.Type != "group" && m.Chat.Type != "supergroup") {
+		return false
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	channel := a.channel(m.Chat)
+	date := time.Unix(m.Date, 0)
+	msgid := fmt.Sprintf("telegram:%d:%d", m.Chat.ID, m.MessageID)
+
+	counted := false
+	add := func(kind stats.MsgKind, from User, id, message string) {
+		if a.IgnoreBots && from.IsBot {
+			return
+		}
+		if a.Stats.AddMessageID(id, kind, a.Network, channel, hostmask(from), date, message) {
+			counted = true
+		}
+	}
+
+	switch {
+	case len(m.NewChatMembers) > 0:
+		for _, joined := range m.NewChatMembers {
+			add(stats.Join, joined, msgid+":"+strconv.FormatInt(joined.ID, 10), "")
+		}
+	case m.LeftChatMember != nil:
+		add(stats.Part, *m.LeftChatMember, msgid, "")
+	case len(m.NewChatTitle) > 0 && m.From != nil:
+		add(stats.Topic, *m.From, msgid, m.NewChatTitle)
+	case m.From != nil:
+		text := m.Text
+		if len(text) == 0 {
+			text = m.Caption
+		}
+		// messages of several lines count as a line each like they would
+		// on irc
+		for i, line := range strings.Split(text, "\n") {
+			if line = strings.TrimSpace(line); len(line) == 0 {
+				continue
+			}
+			kind := stats.Msg
+			if strings.HasPrefix(line, "/me ") {
+				kind, line = stats.Action, strings.TrimSpace(line[4:])
+			}
+			add(kind, *m.From, msgid+":"+strconv.Itoa(i), line)
+		}
+	}
+
+	return counted
+}
+
+var channelJunk = regexp.MustCompile(`[^\pL\pN_-]+`)
+
+// channel is the name a group is counted under.
+func (a *Adapter) channel(c Chat) string {
+	if name, ok := a.Chats[c.ID]; ok {
+		return name
+	}
+	if len(c.Username) > 0 {
+		return "#" + c.Username
+	}
+	if name := strings.Trim(channelJunk.ReplaceAllString(strings.ToLower(c.Title), "-"), "-"); len(name) > 0 {
+		return "#" + name
+	}
+	return "#" + strconv.FormatInt(c.ID, 10)
+}
+
+// hostmask builds a hostmask out of the nick and id of a user, so users stay
+// apart even when they share a name.
+func hostmask(u User) string {
+	return nick(u) + "!" + strconv.FormatInt(u.ID, 10) + "@telegram"
+}
+
+// nick is the name a user is counted under: their username, or their first
+// name when they have none and it can be a nick.
+func nick(u User) string {
+	if len(u.Username) > 0 {
+		return u.Username
+	}
+	if len(u.FirstName) > 0 && !strings.ContainsAny(u.FirstName, " \t!@") {
+		return u.FirstName
+	}
+	return strconv.FormatInt(u.ID, 10)
+}
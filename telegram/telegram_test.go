@@ -0,0 +1,136 @@
+package telegram
+
+import (
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+var (
+	group  = Chat{ID: -100, Type: "supergroup", Title: "Deviate Chat"}
+	dylan  = User{ID: 1, FirstName: "Dylan", Username: "dylan"}
+	aaron  = User{ID: 2, FirstName: "Aaron"}
+	bridge = User{ID: 3, IsBot: true, Username: "ircbridge_bot"}
+)
+
+func message(id int64, from User, text string) Update {
+	return Update{UpdateID: id, Message: &Message{MessageID: id, From: &from, Chat: group, Date: 1393660800, Text: text}}
+}
+
+func TestAdapter_HandleUpdate(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+
+	if !a.HandleUpdate(message(1, dylan, "hello\nthere")) {
+		t.Error("Should count the message.")
+	}
+	a.HandleUpdate(message(2, aaron, "/me waves"))
+	if a.HandleUpdate(message(1, dylan, "hello\nthere")) {
+		t.Error("Should count an update handed over twice once.")
+	}
+	if a.HandleUpdate(message(3, bridge, "<phish> from irc")) {
+		t.Error("Should ignore bots.")
+	}
+
+	private := message(4, dylan, "psst")
+	private.Message.Chat = Chat{ID: 1, Type: "private"}
+	if a.HandleUpdate(private) {
+		t.Error("Should ignore private chats.")
+	}
+
+	if c := s.GetChannel("zkpq", "#deviate-chat"); c == nil {
+		t.Fatal("Should name the group after its title.")
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 2 {
+		t.Error("Should count each line of dylan's message:", u)
+	}
+	if u := s.GetUser("zkpq", "aaron"); u == nil || u.TextByKind[stats.Action].Lines != 1 {
+		t.Error("Should count aaron's action:", u)
+	}
+	if s.GetUser("zkpq", "ircbridge_bot") != nil {
+		t.Error("Should not count the bridge.")
+	}
+}
+
+func TestAdapter_membership(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "")
+	a.Chats[group.ID] = "#deviate"
+
+	joined := message(1, dylan, "")
+	joined.Message.NewChatMembers = []User{dylan, aaron}
+	if !a.HandleUpdate(joined) {
+		t.Error("Should count the joins.")
+	}
+
+	left := message(2, aaron, "")
+	left.Message.LeftChatMember = &aaron
+	a.HandleUpdate(left)
+
+	title := message(3, dylan, "")
+	title.Message.NewChatTitle = "Deviants"
+	a.HandleUpdate(title)
+
+	c := s.GetChannel(defaultNetwork, "#deviate")
+	if c == nil {
+		t.Fatal("Should name the group from the chats.")
+	}
+	if u := s.GetUser(defaultNetwork, "aaron"); u == nil || u.MessageRanges.Len() != 2 {
+		t.Error("Should count aaron's join and part:", u)
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "Deviants" {
+		t.Error("Should count the new title as a topic:", c.Topics)
+	}
+}
+
+func TestAdapter_channel(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+	a.Chats[5] = "#named"
+
+	tests := []struct {
+		chat Chat
+		want string
+	}{
+		{Chat{ID: 5, Title: "ignored"}, "#named"},
+		{Chat{ID: 6, Username: "golang", Title: "Go"}, "#golang"},
+		{Chat{ID: 7, Title: "Go Nuts!"}, "#go-nuts"},
+		{Chat{ID: -8, Title: "🙂"}, "#-8"},
+	}
+
+	for _, test := range tests {
+		if got := a.channel(test.chat); got != test.want {
+			t.Errorf("%v: Expected: %s, Got: %s", test.chat, test.want, got)
+		}
+	}
+}
+
+func TestNick(t *testing.T) {
+	t.Parallel()
+
+	if got := hostmask(dylan); got != "dylan!1@telegram" {
+		t.Error("Should use the username:", got)
+	}
+	if got := nick(aaron); got != "Aaron" {
+		t.Error("Should fall back to the first name:", got)
+	}
+	if got := nick(User{ID: 9, FirstName: "Mary Jane"}); got != "9" {
+		t.Error("Should fall back to the id:", got)
+	}
+}
@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Webhook receives the updates the Bot API posts to a bot's webhook, an
+// alternative to polling with a Client. Serve it on the url given to
+// setWebhook.
+type Webhook struct {
+	Adapter *Adapter
+	// Secret is the secret_token given to setWebhook, requests without it
+	// are refused.
+	Secret string
+}
+
+// ServeHTTP handles a posted update. Updates are retried by the Bot API until
+// they succeed, so a bad update is acknowledged rather than retried forever.
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Updates must be posted.", http.StatusMethodNotAllowed)
+		return
+	}
+	if len(wh.Secret) == 0 || r.Header.Get("X-Telegram-Bot-Api-Secret-Token") != wh.Secret {
+		http.Error(w, "Bad secret token.", http.StatusForbidden)
+		return
+	}
+
+	var u Update
+	if err := json.NewDecoder(r.Body).Decode(&u); err == nil {
+		wh.Adapter.HandleUpdate(u)
+	}
+}
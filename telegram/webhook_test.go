@@ -0,0 +1,44 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wh := &Webhook{Adapter: New(s, "zkpq"), Secret: "secret"}
+
+	body, _ := json.Marshal(message(1, dylan, "hello"))
+	post := func(secret string) int {
+		r := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		r.Header.Set("X-Telegram-Bot-Api-Secret-Token", secret)
+		w := httptest.NewRecorder()
+		wh.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post("wrong"); code != http.StatusForbidden {
+		t.Error("Should refuse bad secrets, Got:", code)
+	}
+	if code := post("secret"); code != http.StatusOK {
+		t.Error("Should accept the update, Got:", code)
+	}
+	if code := post("secret"); code != http.StatusOK {
+		t.Error("Should accept retried updates, Got:", code)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should count the message once.")
+	}
+}
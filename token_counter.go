@@ -4,6 +4,10 @@ type TokenCounter struct {
 	All   map[string]uint
 	Top   TopTokenArray
 	Count uint
+
+	// Sketch replaces All with approximate counts when the counter is
+	// bounded.
+	Sketch *CountMinSketch
 }
 
 // NewTokens initializes the Tokens map.
@@ -14,10 +18,43 @@ func NewTokenCounter() TokenCounter {
 	}
 }
 
+// bound switches the counter to approximate counting in a count-min sketch,
+// so that memory no longer grows with the number of distinct tokens.
+func (tc *TokenCounter) bound(o SketchOptions) {
+	if !o.enabled() {
+		return
+	}
+
+	tc.Sketch = NewCountMinSketch(o.Width, o.Depth)
+	tc.All = nil
+}
+
 func (tc *TokenCounter) addToken(token string) {
-	tc.All[token]++
-	count := tc.All[token]
+	var count uint
+
+	if tc.Sketch != nil {
+		count = tc.Sketch.add(token)
+	} else {
+		tc.All[token]++
+		count = tc.All[token]
+	}
 
 	tc.Top.insert(token, count)
 	tc.Count++
 }
+
+// TopN returns up to n of the most counted tokens, all of those kept when
+// n <= 0.
+func (tc *TokenCounter) TopN(n int) TopTokenArray {
+	return tc.Top.First(n)
+}
+
+// CountOf returns the number of times a token was seen. Bounded counters
+// return an estimate that may be too high but is never too low.
+func (tc *TokenCounter) CountOf(token string) uint {
+	if tc.Sketch != nil {
+		return tc.Sketch.Estimate(token)
+	}
+
+	return tc.All[token]
+}
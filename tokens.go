@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokens are the pieces of a line of text the counters count. A message is
+// split once and its tokens are shared by the counters of the network,
+// channel and users it's counted in, rather than each counter splitting it
+// again.
+type Tokens struct {
+	// Fields are the text split around white space, Lower are the same
+	// fields in lower case.
+	Fields []string
+	Lower  []string
+	// Terms are the words of the text in lower case, as the word counters
+	// count them. URLs are the urls in the text.
+	Terms []string
+	URLs  []string
+	// Words and Letters are counted like countWords and countLetters do,
+	// Classes are the classes of the characters.
+	Words   uint
+	Letters uint
+	Classes CharClassCounters
+	// AllCaps is set when the text is shouted, see isAllCaps.
+	AllCaps bool
+
+	lower   string
+	strings *interner
+}
+
+// tokenize splits a line of text into its tokens.
+func tokenize(text string) *Tokens {
+	t := &Tokens{}
+	t.split(text)
+	return t
+}
+
+// split splits a line of text into the tokens, reusing their slices. The
+// formatting of the text isn't part of them.
+func (t *Tokens) split(text string) {
+	text = stripFormatting(text)
+	t.Words = uint(countWords(text))
+	t.Letters = uint(countLetters(text))
+	t.lower = strings.ToLower(text)
+	t.Fields = appendFields(t.Fields[:0], text)
+	t.Lower = appendFields(t.Lower[:0], t.lower)
+
+	t.Terms = t.Terms[:0]
+	for i, field := range t.Fields {
+		if _, ok := wordToken(field); ok {
+			word, _ := wordToken(t.Lower[i])
+			t.Terms = append(t.Terms, word)
+		}
+	}
+	t.URLs = findURLs(text, t.URLs[:0])
+	t.Classes = CharClassCounters{}
+	t.Classes.addText(text)
+	t.AllCaps = isAllCaps(text)
+}
+
+// appendFields appends the text split around white space like
+// strings.Fields does to fields.
+func appendFields(fields []string, text string) []string {
+	start := -1
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				fields = append(fields, text[start:i])
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, text[start:])
+	}
+	return fields
+}
+
+// tokenize splits a line of text said on the network into t, reusing its
+// slices, the strings the counters keep are interned. The network splits
+// every message into the same tokens, see Stats.addMessage.
+func (n *Network) tokenize(t *Tokens, text string) *Tokens {
+	t.split(text)
+	t.strings = &n.strings
+
+	for i, term := range t.Terms {
+		t.Terms[i] = t.intern(term)
+	}
+	for i, url := range t.URLs {
+		t.URLs[i] = t.intern(url)
+	}
+
+	return t
+}
+
+// intern returns the shared copy of a string kept by a counter, tokens split
+// outside of a network aren't interned.
+func (t *Tokens) intern(s string) string {
+	if t.strings == nil {
+		return s
+	}
+	return t.strings.intern(s)
+}
+
+// tokens are the tokens of the message, split the first time they're needed.
+func (m *Message) tokens() *Tokens {
+	if m.split == nil {
+		m.split = tokenize(m.Message)
+	}
+	return m.split
+}
+
+// wordTokens are the tokens of the part of a message counted as words
+// according to the network's options.
+func (n *Network) wordTokens(m *Message) *Tokens {
+	if n.stats == nil || !n.stats.opts.Words.excludes() {
+		return m.tokens()
+	}
+
+	if m.splitWords == nil {
+		fields := n.stats.opts.Words.wordFields(n, m.tokens().Fields)
+		m.splitWords = n.tokenize(&n.splitWords, strings.Join(fields, " "))
+	}
+	return m.splitWords
+}
+
+// forgetTokens drops the tokens once the message was counted, they're reused
+// for the next message.
+func (m *Message) forgetTokens() {
+	m.split, m.splitWords = nil, nil
+}
@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	t.Parallel()
+
+	tok := tokenize("Hey Bob, see http://google.com 42 :)")
+
+	if exp := []string{"Hey", "Bob,", "see", "http://google.com", "42", ":)"}; !reflect.DeepEqual(tok.Fields, exp) {
+		t.Error("Wrong fields:", tok.Fields)
+	}
+	if exp := []string{"hey", "bob,", "see", "http://google.com", "42", ":)"}; !reflect.DeepEqual(tok.Lower, exp) {
+		t.Error("Wrong lower case fields:", tok.Lower)
+	}
+	if exp := []string{"http://google.com"}; !reflect.DeepEqual(tok.URLs, exp) {
+		t.Error("Wrong urls:", tok.URLs)
+	}
+
+	var c BasicTextCounters
+	c.addText("Hey Bob, see http://google.com 42 :)")
+	if tok.Words != c.Words || tok.Letters != c.Letters || tok.Classes != c.CharClassCounters {
+		t.Errorf("Should count like the text counters: %+v %+v", tok, c)
+	}
+
+	if tok = tokenize("nothing here"); tok.URLs != nil {
+		t.Error("Should not find urls:", tok.URLs)
+	}
+}
+
+func TestMessage_tokens(t *testing.T) {
+	t.Parallel()
+
+	m := &Message{Message: "hi there"}
+	tok := m.tokens()
+	if m.tokens() != tok {
+		t.Error("Should split a message once.")
+	}
+
+	m.forgetTokens()
+	if m.split != nil || m.splitWords != nil {
+		t.Error("Should forget the tokens.")
+	}
+}
+
+func TestNetwork_wordTokens(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "bob", time.Now(), "hi")
+	n := s.GetNetwork(network)
+
+	m := &Message{Message: "bob: see http://google.com"}
+	if n.wordTokens(m) != m.tokens() {
+		t.Error("Should share the tokens without word options.")
+	}
+
+	s.opts.Words = WordOptions{ExcludeURLs: true, ExcludeNickPrefix: true}
+	tok := n.wordTokens(m)
+	if exp := []string{"see"}; !reflect.DeepEqual(tok.Fields, exp) {
+		t.Error("Should leave out the excluded words:", tok.Fields)
+	}
+	if exp := []string{"bob:", "see", "http://google.com"}; !reflect.DeepEqual(m.tokens().Fields, exp) {
+		t.Error("Should not change the message's tokens:", m.tokens().Fields)
+	}
+
+}
+
+func TestTokens_split(t *testing.T) {
+	t.Parallel()
+
+	var tok Tokens
+	tok.split("Hey Bob, see http://google.com 42 :)")
+	tok.split("see you\tat  http://zqz.ca/ ok")
+
+	exp := tokenize("see you\tat  http://zqz.ca/ ok")
+	if !reflect.DeepEqual(&tok, exp) {
+		t.Errorf("Should split over the tokens of the last text:\n%+v\n%+v", tok, *exp)
+	}
+
+	for _, text := range []string{"", "  ", "a", " a  b ", " é　ü\n"} {
+		if got, exp := appendFields(nil, text), strings.Fields(text); len(got) != len(exp) || len(exp) > 0 && !reflect.DeepEqual(got, exp) {
+			t.Errorf("%q: Expected: %q, Got: %q", text, exp, got)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package stats
 
 const topTokenMaxSize = 50
 
+// TopTokenArray holds the tokens with the highest counts, most counted first.
+// It's kept sorted as counts are added so reading a leaderboard never sorts
+// the counter it came from.
 type TopTokenArray []TopToken
 
 type TopToken struct {
@@ -9,47 +12,56 @@ type TopToken struct {
 	Count uint   `json:"count"`
 }
 
+// insert records the count of a token, keeping the highest count it was given.
+// A token is moved up past the tokens it now outnumbers and a new one takes
+// the place of the last when the array is full, which keeps the array exact
+// for counts that only go up.
 func (a *TopTokenArray) insert(token string, count uint) {
 	ta := *a // allow accessing token array without indirection everywhere
-	insertAt := -1
-	currentIndex := -1
+	i := ta.index(token)
 
-	if len(ta) == 0 {
+	switch {
+	case i >= 0:
+		if count <= ta[i].Count {
+			return
+		}
+		ta[i].Count = count
+	case len(ta) < topTokenMaxSize:
 		ta = append(ta, TopToken{token, count})
-		*a = ta
+		i = len(ta) - 1
+	case count > ta[len(ta)-1].Count:
+		i = len(ta) - 1
+		ta[i] = TopToken{token, count}
+	default:
 		return
 	}
 
-	for i, t := range ta {
-		if insertAt == -1 && count > t.Count {
-			insertAt = i
-		}
-
-		if currentIndex == -1 && token == t.Token {
-			currentIndex = i
-		}
+	for ; i > 0 && ta[i-1].Count < ta[i].Count; i-- {
+		ta[i-1], ta[i] = ta[i], ta[i-1]
+	}
+	*a = ta
+}
 
-		if currentIndex != -1 && insertAt != -1 {
-			break
+func (a TopTokenArray) index(token string) int {
+	for i, t := range a {
+		if t.Token == token {
+			return i
 		}
 	}
+	return -1
+}
 
-	if currentIndex >= 0 {
-		if insertAt < 0 {
-			return
-		}
-		if insertAt < currentIndex {
-			ta[currentIndex].Token, ta[insertAt].Token =
-				ta[insertAt].Token, ta[currentIndex].Token
-			ta[insertAt].Count = count
-		} else {
-			ta[currentIndex].Count = count
-		}
-	} else if len(ta) < topTokenMaxSize {
-		ta = append(ta, TopToken{token, count})
-		*a = ta
-	} else if insertAt >= 0 {
-		ta[insertAt].Token = token
-		ta[insertAt].Count = count
+// First returns up to n of the tokens with the highest counts, all of them
+// when n <= 0.
+func (a TopTokenArray) First(n int) TopTokenArray {
+	return a[:Limit(n, len(a))]
+}
+
+// Limit is how many entries of a list of length a top n shows: n, no more
+// than there are, or all of them when n <= 0.
+func Limit(n, length int) int {
+	if n <= 0 || n > length {
+		return length
 	}
+	return n
 }
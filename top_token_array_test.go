@@ -0,0 +1,126 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestTopTokenArray_insert(t *testing.T) {
+	t.Parallel()
+
+	var a TopTokenArray
+	a.insert("a", 1)
+	a.insert("b", 1)
+	a.insert("b", 2)
+	a.insert("c", 5)
+
+	if exp := (TopTokenArray{{"c", 5}, {"b", 2}, {"a", 1}}); fmt.Sprint(a) != fmt.Sprint(exp) {
+		t.Error("Should move tokens up past the ones they outnumber:", a)
+	}
+
+	a.insert("c", 3)
+	if a[0].Count != 5 {
+		t.Error("Should keep the highest count of a token:", a)
+	}
+}
+
+func TestTopTokenArray_insertFull(t *testing.T) {
+	t.Parallel()
+
+	tc := NewTokenCounter()
+	for i := 0; i < topTokenMaxSize+20; i++ {
+		for j := 0; j <= i%(topTokenMaxSize+5); j++ {
+			tc.addToken(fmt.Sprint(i))
+		}
+	}
+
+	if len(tc.Top) != topTokenMaxSize {
+		t.Fatal("Should hold at most the max tokens:", len(tc.Top))
+	}
+	if !sort.SliceIsSorted(tc.Top, func(i, j int) bool { return tc.Top[i].Count > tc.Top[j].Count }) {
+		t.Error("Should keep the tokens sorted:", tc.Top)
+	}
+
+	counts := make([]int, 0, len(tc.All))
+	for _, c := range tc.All {
+		counts = append(counts, int(c))
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+	for i, tok := range tc.Top {
+		if int(tok.Count) != counts[i] || tc.All[tok.Token] != tok.Count {
+			t.Errorf("%d Expected count %d, Got: %v", i, counts[i], tok)
+		}
+	}
+}
+
+func TestTopTokenArray_First(t *testing.T) {
+	t.Parallel()
+
+	a := TopTokenArray{{"a", 3}, {"b", 2}}
+	if f := a.First(1); len(f) != 1 || f[0].Token != "a" {
+		t.Error("Should return the first tokens:", f)
+	}
+	if f := a.First(15); len(f) != 2 {
+		t.Error("Should return every token when there are fewer:", f)
+	}
+	if f := a.First(0); len(f) != 2 {
+		t.Error("Should return every token for 0:", f)
+	}
+	if f := a.First(-1); len(f) != 2 {
+		t.Error("Should return every token for negative counts:", f)
+	}
+	if f := TopTokenArray(nil).First(5); len(f) != 0 {
+		t.Error("Should be safe on empty arrays:", f)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		n, length, expect int
+	}{
+		{5, 10, 5},
+		{10, 5, 5},
+		{0, 5, 5},
+		{-3, 5, 5},
+		{3, 0, 0},
+	}
+
+	for _, test := range tests {
+		if got := Limit(test.n, test.length); got != test.expect {
+			t.Errorf("Limit(%d, %d) Expected: %d, Got: %d", test.n, test.length, test.expect, got)
+		}
+	}
+}
+
+func TestTokenCounter_TopN(t *testing.T) {
+	t.Parallel()
+
+	c := NewTokenCounter()
+	c.addToken("a")
+	c.addToken("a")
+	c.addToken("b")
+
+	if top := c.TopN(1); len(top) != 1 || top[0].Token != "a" {
+		t.Error("Should return the most counted tokens:", top)
+	}
+	if top := c.TopN(0); len(top) != 2 {
+		t.Error("Should return every token kept for 0:", top)
+	}
+}
+
+func BenchmarkTopTokenArray_insert(b *testing.B) {
+	tc := NewTokenCounter()
+	tokens := make([]string, 10000)
+	for i := range tokens {
+		tokens[i] = fmt.Sprint("token", i%(i/10+1))
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tc.addToken(tokens[i%len(tokens)])
+	}
+}
@@ -0,0 +1,143 @@
+package twitch
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultServer is the irc gateway of Twitch chat.
+const DefaultServer = "irc.chat.twitch.tv:6697"
+
+// Client connects to Twitch chat, joins the channels and feeds what is said
+// in them into an adapter.
+type Client struct {
+	Adapter *Adapter
+	// Server is the host:port of the gateway, DefaultServer over tls when
+	// empty.
+	Server string
+	// Nick and Token log in as a Twitch user, Token being an oauth token.
+	// Without them the client reads chat anonymously.
+	Nick  string
+	Token string
+	// Channels are the channels to join, eg. #somestreamer.
+	Channels []string
+}
+
+// NewClient creates a client reading the chat of the channels anonymously.
+func NewClient(a *Adapter, channels ...string) *Client {
+	return &Client{Adapter: a, Channels: channels}
+}
+
+// errReconnect is returned when Twitch asks clients to reconnect.
+var errReconnect = errors.New("twitch: asked to reconnect")
+
+// Run connects to Twitch until stop is closed, reconnecting a little after
+// the connection is lost. Failures are passed to errs when it isn't nil.
+func (c *Client) Run(stop <-chan struct{}, errs func(error)) {
+	for {
+		conn, err := c.dial()
+		if err == nil {
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-stop:
+					conn.Close()
+				case <-done:
+				}
+			}()
+			err = c.Serve(conn)
+			close(done)
+			conn.Close()
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if err != nil && err != errReconnect && errs != nil {
+			errs(err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if len(c.Server) == 0 {
+		return tls.DialWithDialer(dialer, "tcp", DefaultServer, nil)
+	}
+	return dialer.Dial("tcp", c.Server)
+}
+
+// Serve logs in over an open connection, joins the channels and handles chat
+// until the connection is closed.
+func (c *Client) Serve(conn io.ReadWriter) error {
+	nick, pass := strings.ToLower(c.Nick), c.Token
+	if len(nick) == 0 || len(pass) == 0 {
+		// anonymous logins are justinfan and any number
+		nick, pass = fmt.Sprintf("justinfan%d", 10000+rand.Intn(90000)), "SCHMOOPIIE"
+	} else if !strings.HasPrefix(pass, "oauth:") {
+		pass = "oauth:" + pass
+	}
+
+	send := func(line string) error {
+		_, err := io.WriteString(conn, line+"\r\n")
+		return err
+	}
+	for _, line := range []string{
+		"CAP REQ :twitch.tv/tags twitch.tv/commands twitch.tv/membership",
+		"PASS " + pass,
+		"NICK " + nick,
+	} {
+		if err := send(line); err != nil {
+			return err
+		}
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		l, ok := ParseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		switch l.Command {
+		case "PING":
+			if err := send("PONG :" + l.arg(0)); err != nil {
+				return err
+			}
+		case "001":
+			if len(c.Channels) > 0 {
+				if err := send("JOIN " + strings.ToLower(strings.Join(c.Channels, ","))); err != nil {
+					return err
+				}
+			}
+		case "NOTICE":
+			if l.arg(0) == "*" && strings.Contains(l.arg(1), "authentication failed") {
+				return errors.New("twitch: " + l.arg(1))
+			}
+		case "RECONNECT":
+			return errReconnect
+		default:
+			c.Adapter.HandleLine(l)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
@@ -0,0 +1,84 @@
+package twitch
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestClient_Serve(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(New(s, ""), "#Deviate")
+	c.Nick, c.Token = "StatsBot", "hunter2"
+
+	server, conn := net.Pipe()
+	done := make(chan error)
+	go func() { done <- c.Serve(conn) }()
+
+	r := bufio.NewReader(server)
+	expect := func(want string) {
+		got, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want+"\r\n" {
+			t.Errorf("Expected: %q, Got: %q", want, got)
+		}
+	}
+	send := func(line string) {
+		io.WriteString(server, line+"\r\n")
+	}
+
+	expect("CAP REQ :twitch.tv/tags twitch.tv/commands twitch.tv/membership")
+	expect("PASS oauth:hunter2")
+	expect("NICK statsbot")
+	send(":tmi.twitch.tv 001 statsbot :Welcome, GLHF!")
+	expect("JOIN #deviate")
+	send("PING :tmi.twitch.tv")
+	expect("PONG :tmi.twitch.tv")
+	send("@display-name=Aaron;id=1;tmi-sent-ts=1393660800000 :aaron!aaron@aaron.tmi.twitch.tv PRIVMSG #deviate :hello")
+	send(":tmi.twitch.tv RECONNECT")
+
+	if err := <-done; err != errReconnect {
+		t.Error("Should stop when asked to reconnect:", err)
+	}
+	if u := s.GetUser("twitch", "aaron"); u == nil || u.Lines != 1 {
+		t.Error("Should count the chat:", u)
+	}
+}
+
+func TestClient_anonymous(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(New(s, ""))
+	server, conn := net.Pipe()
+	done := make(chan error)
+	go func() { done <- c.Serve(conn) }()
+
+	r := bufio.NewReader(server)
+	r.ReadString('\n')
+	if pass, _ := r.ReadString('\n'); pass != "PASS SCHMOOPIIE\r\n" {
+		t.Error("Wrong password:", pass)
+	}
+	if nick, _ := r.ReadString('\n'); !strings.HasPrefix(nick, "NICK justinfan") {
+		t.Error("Should log in anonymously:", nick)
+	}
+
+	io.WriteString(server, ":tmi.twitch.tv NOTICE * :Login authentication failed\r\n")
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Error("Should fail when the login is refused:", err)
+	}
+}
@@ -0,0 +1,238 @@
+// Package twitch feeds the chat of Twitch channels into a Stats database.
+// Twitch chat is almost irc: a Client connects to its irc gateway, or lines
+// read elsewhere are handed to an Adapter, and every Twitch channel is counted
+// as the channel of the same name.
+package twitch
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+	"github.com/aarondl/ultimateq/irc"
+)
+
+// defaultNetwork is the network channels are counted under when the adapter
+// doesn't name one.
+const defaultNetwork = "twitch"
+
+const ctcpDelim = "\x01"
+
+// Adapter maps the lines of Twitch chat onto AddMessage calls.
+type Adapter struct {
+	Stats   *stats.Stats
+	Network string
+
+	mut sync.Mutex
+}
+
+// New creates an adapter feeding the stats under the network.
+func New(s *stats.Stats, network string) *Adapter {
+	if len(network) == 0 {
+		network = defaultNetwork
+	}
+	return &Adapter{
+		Stats:   s,
+		Network: network,
+	}
+}
+
+// Line is a line of Twitch chat, an irc line carrying IRCv3 tags.
+type Line struct {
+	Tags    map[string]string
+	Prefix  string
+	Command string
+	Args    []string
+}
+
+var tagUnescaper = strings.NewReplacer(`\:`, ";", `\s`, " ", `\\`, `\`, `\r`, "\r", `\n`, "\n")
+
+// ParseLine parses a raw line of Twitch chat.
+func ParseLine(raw string) (Line, bool) {
+	raw = strings.TrimRight(raw, "\r\n")
+	var l Line
+
+	if strings.HasPrefix(raw, "@") {
+		var tags string
+		tags, raw = split(raw[1:])
+		l.Tags = make(map[string]string)
+		for _, tag := range strings.Split(tags, ";") {
+			kv := strings.SplitN(tag, "=", 2)
+			if len(kv) == 2 {
+				l.Tags[kv[0]] = tagUnescaper.Replace(kv[1])
+			} else {
+				l.Tags[kv[0]] = ""
+			}
+		}
+	}
+	if strings.HasPrefix(raw, ":") {
+		l.Prefix, raw = split(raw[1:])
+	}
+
+	l.Command, raw = split(raw)
+	if len(l.Command) == 0 {
+		return l, false
+	}
+	l.Command = strings.ToUpper(l.Command)
+
+	for len(raw) > 0 {
+		if raw[0] == ':' {
+			l.Args = append(l.Args, raw[1:])
+			break
+		}
+		var arg string
+		arg, raw = split(raw)
+		l.Args = append(l.Args, arg)
+	}
+	return l, true
+}
+
+func split(s string) (string, string) {
+	s = strings.TrimLeft(s, " ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], strings.TrimLeft(s[i+1:], " ")
+	}
+	return s, ""
+}
+
+func (l Line) arg(i int) string {
+	if i < len(l.Args) {
+		return l.Args[i]
+	}
+	return ""
+}
+
+// HandleLine adds a line of chat to the stats, returning false if there was
+// nothing to count. Messages are counted once even when handed over twice,
+// by the id Twitch gives them.
+func (a *Adapter) HandleLine(l Line) bool {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	channel := strings.ToLower(l.arg(0))
+	if !strings.HasPrefix(channel, "#") {
+		return false
+	}
+
+	date := time.Now()
+	if ms, err := strconv.ParseInt(l.Tags["tmi-sent-ts"], 10, 64); err == nil {
+		date = time.Unix(0, ms*int64(time.Millisecond))
+	}
+	msgid := ""
+	if id := l.Tags["id"]; len(id) > 0 {
+		msgid = "twitch:" + id
+	}
+
+	var kind stats.MsgKind
+	message := ""
+	switch l.Command {
+	case "PRIVMSG":
+		kind, message = stats.Msg, emotes(l.arg(1), l.Tags["emotes"])
+		if strings.HasPrefix(message, ctcpDelim+"ACTION ") {
+			kind, message = stats.Action, strings.Trim(message[len(ctcpDelim+"ACTION "):], ctcpDelim)
+		} else if strings.HasPrefix(message, ctcpDelim) {
+			return false
+		}
+	case "USERNOTICE":
+		// subscriptions and raids, only the message a subscriber may add
+		// is something they said
+		if message = emotes(l.arg(1), l.Tags["emotes"]); len(message) == 0 {
+			return false
+		}
+		kind = stats.Msg
+	case "JOIN":
+		kind = stats.Join
+	case "PART":
+		kind = stats.Part
+	default:
+		return false
+	}
+	if len(strings.TrimSpace(message)) == 0 && (kind == stats.Msg || kind == stats.Action) {
+		return false
+	}
+
+	hostmask := a.hostmask(l)
+	if len(hostmask) == 0 {
+		return false
+	}
+
+	return a.Stats.AddMessageID(msgid, kind, a.Network, channel, hostmask, date, message)
+}
+
+// hostmask is login!login@twitch.tv with the display name as the nick when
+// it's only the login capitalized, localized names don't make nicks.
+func (a *Adapter) hostmask(l Line) string {
+	login := l.Tags["login"]
+	if len(login) == 0 {
+		login = irc.Nick(l.Prefix)
+	}
+	if len(login) == 0 {
+		return ""
+	}
+	login = strings.ToLower(login)
+
+	nick := login
+	if display := l.Tags["display-name"]; strings.EqualFold(display, login) {
+		nick = display
+	}
+	return nick + "!" + login + "@twitch.tv"
+}
+
+type emoteRange struct {
+	start, end int
+}
+
+// emotes rewrites the emotes a message holds as :name:, the way other chats
+// write their custom emoji, so they are counted as emoticons. The emotes tag
+// lists the ranges of each emote, eg. 25:0-4,12-16/1902:6-10, counted in
+// characters.
+func emotes(message, tag string) string {
+	if len(tag) == 0 {
+		return message
+	}
+
+	var ranges []emoteRange
+	for _, emote := range strings.Split(tag, "/") {
+		parts := strings.SplitN(emote, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		for _, r := range strings.Split(parts[1], ",") {
+			bounds := strings.SplitN(r, "-", 2)
+			if len(bounds) != 2 {
+				continue
+			}
+			start, err1 := strconv.Atoi(bounds[0])
+			end, err2 := strconv.Atoi(bounds[1])
+			if err1 == nil && err2 == nil && start <= end {
+				ranges = append(ranges, emoteRange{start, end})
+			}
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].start == ranges[j].start {
+			return ranges[i].end > ranges[j].end
+		}
+		return ranges[i].start > ranges[j].start
+	})
+
+	runes := []rune(message)
+	last := len(runes)
+	for _, r := range ranges {
+		// overlapping or out of range emotes mean the tag is wrong
+		if r.end >= last {
+			continue
+		}
+		name := string(runes[r.start : r.end+1])
+		if strings.HasPrefix(name, ":") {
+			continue
+		}
+		replaced := append([]rune(":"+name+":"), runes[r.end+1:]...)
+		runes = append(runes[:r.start], replaced...)
+		last = r.start
+	}
+	return string(runes)
+}
@@ -0,0 +1,92 @@
+package twitch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+func handle(t *testing.T, a *Adapter, raw string) bool {
+	l, ok := ParseLine(raw)
+	if !ok {
+		t.Fatal("Failed to parse:", raw)
+	}
+	return a.HandleLine(l)
+}
+
+func TestAdapter_HandleLine(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "")
+
+	msg := `@badge-info=;badges=broadcaster/1;color=#0000FF;display-name=ZkPq;emotes=25:0-4,12-16/1902:6-10;id=b34ccfc7;login=zkpq;room-id=1337;tmi-sent-ts=1393660800000;user-id=1337 :zkpq!zkpq@zkpq.tmi.twitch.tv PRIVMSG #Deviate :Kappa Keepo Kappa`
+	if !handle(t, a, msg) {
+		t.Fatal("Should count messages.")
+	}
+	if handle(t, a, msg) {
+		t.Error("Should count messages once.")
+	}
+
+	c := s.GetChannel("twitch", "#deviate")
+	if c == nil {
+		t.Fatal("Should count the channel.")
+	}
+	if c.EmoticonCounter.All[":Kappa:"] != 2 || c.EmoticonCounter.All[":Keepo:"] != 1 {
+		t.Error("Should count the emotes as emoticons:", c.EmoticonCounter.All)
+	}
+
+	u := s.GetUser("twitch", "zkpq")
+	if u == nil || u.Nick != "ZkPq" || !u.LastSeen.Equal(time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Fatal("Should count the user under their display name, dated by twitch:", u)
+	}
+
+	handle(t, a, "@display-name=ZkPq;id=2 :zkpq!zkpq@zkpq.tmi.twitch.tv PRIVMSG #deviate :\x01ACTION waves\x01")
+	if u.TextByKind[stats.Action].Lines != 1 {
+		t.Error("Should count actions.")
+	}
+
+	handle(t, a, "@display-name=아기상어;id=3;login=babyshark :babyshark!babyshark@babyshark.tmi.twitch.tv PRIVMSG #deviate :hi")
+	if s.GetUser("twitch", "babyshark") == nil {
+		t.Error("Localized display names should fall back to the login.")
+	}
+
+	if handle(t, a, "@id=4;login=sub;msg-id=sub;system-msg=sub\\ssubscribed :tmi.twitch.tv USERNOTICE #deviate") {
+		t.Error("Should not count notices without a message.")
+	}
+	if !handle(t, a, "@id=5;login=resub;display-name=Resub;msg-id=resub;emotes= :tmi.twitch.tv USERNOTICE #deviate :two years!") {
+		t.Error("Should count the messages of resubscriptions.")
+	}
+
+	if !handle(t, a, ":phish!phish@phish.tmi.twitch.tv JOIN #deviate") || !handle(t, a, ":phish!phish@phish.tmi.twitch.tv PART #deviate") {
+		t.Error("Should count joins and parts.")
+	}
+	if handle(t, a, "@emote-only=0;room-id=1337 :tmi.twitch.tv ROOMSTATE #deviate") {
+		t.Error("Should not count room states.")
+	}
+}
+
+func TestEmotes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		message, tag, want string
+	}{
+		{"no emotes", "", "no emotes"},
+		{"Kappa", "25:0-4", ":Kappa:"},
+		{"héllo Kappa!", "25:6-10", "héllo :Kappa:!"},
+		{"Kappa", "25:0-10", "Kappa"},
+		{"Kappa", "25:0-4,0-2", ":Kappa:"},
+		{"Kappa", "broken", "Kappa"},
+	}
+
+	for _, test := range tests {
+		if got := emotes(test.message, test.tag); got != test.want {
+			t.Errorf("%q %q: Expected: %q, Got: %q", test.message, test.tag, test.want, got)
+		}
+	}
+}
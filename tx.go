@@ -0,0 +1,132 @@
+package stats
+
+import "time"
+
+// ReadTx reads the stats as they are at one point in time, see View. Its maps
+// are the stats' own and must not be changed. Only the summary of the
+// channels in Channels may be loaded yet: their ids, names, topics and
+// message counts; get them with Channel to read the rest.
+type ReadTx struct {
+	Channels map[uint]*Channel
+	Networks map[uint]*Network
+	Users    map[uint]*User
+
+	s *Stats
+}
+
+// WriteTx adds messages to the stats, see Update. Everything it adds is
+// counted before anything else reads the stats.
+type WriteTx struct {
+	ReadTx
+}
+
+// View calls f with the stats locked for reading, so everything f reads is
+// consistent. Messages are added again when f returns, f must not call the
+// methods of the stats, only those of tx.
+func (s *Stats) View(f func(tx *ReadTx)) {
+	s.rlock()
+	defer s.runlock()
+
+	f(s.readTx())
+}
+
+// Update calls f with the stats locked for writing, for adding messages
+// together with checks on what was already added, like importing logs. f
+// must not call the methods of the stats, only those of tx.
+func (s *Stats) Update(f func(tx *WriteTx)) {
+	s.lock()
+	defer s.mut.Unlock()
+
+	f(&WriteTx{*s.readTx()})
+}
+
+func (s *Stats) readTx() *ReadTx {
+	return &ReadTx{
+		Channels: s.Channels,
+		Networks: s.Networks,
+		Users:    s.Users,
+		s:        s,
+	}
+}
+
+// Location is the timezone the hours and days of a channel are to be read
+// in, see Stats.Location.
+func (tx *ReadTx) Location(network, channel string) *time.Location {
+	return tx.s.opts.location(network, channel)
+}
+
+// GetNetwork retrieves a network by its name return nil if not found
+func (tx *ReadTx) GetNetwork(network string) *Network {
+	return tx.s.lookupNetwork(network)
+}
+
+// Channel retrieves a channel by its id, loading all of it. It's nil if the
+// channel failed to load, see Stats.LoadErrors.
+func (tx *ReadTx) Channel(id uint) *Channel {
+	return tx.s.Channels[id].load(tx.s)
+}
+
+// GetChannel retrieves a channel from the specified network by name
+func (tx *ReadTx) GetChannel(network, channel string) *Channel {
+	return tx.s.lookupChannel(network, channel)
+}
+
+// GetUser retrieves a user from the specified network by name
+func (tx *ReadTx) GetUser(network, nick string) *User {
+	return tx.s.lookupUser(network, nick)
+}
+
+// Imported checks if logs of the channel at the date were already imported.
+func (tx *ReadTx) Imported(network, channel string, date time.Time) bool {
+	return tx.s.imported(network, channel, date)
+}
+
+// AddMessage adds a message to the stats.
+func (tx *WriteTx) AddMessage(kind MsgKind, network string, channel string, hostmask string, date time.Time, message string) {
+	tx.s.addRaw(RawMessage{
+		Kind:     kind,
+		Network:  network,
+		Channel:  channel,
+		Hostmask: hostmask,
+		Date:     date,
+		Message:  message,
+	})
+}
+
+// AddMessageID adds a message carrying an IRCv3 msgid, see
+// Stats.AddMessageID.
+func (tx *WriteTx) AddMessageID(msgid string, kind MsgKind, network, channel, hostmask string, date time.Time, message string) bool {
+	raw := RawMessage{
+		MsgID:    msgid,
+		Kind:     kind,
+		Network:  network,
+		Channel:  channel,
+		Hostmask: hostmask,
+		Date:     date,
+		Message:  message,
+	}
+	return tx.s.addRaw(raw)
+}
+
+// AddEvent checks an event and adds it to the stats, see Stats.AddEvent.
+func (tx *WriteTx) AddEvent(e Event) error {
+	raw, err := e.raw()
+	if err != nil {
+		return err
+	}
+
+	tx.s.addRaw(raw)
+	return nil
+}
+
+// AddBatch adds many messages at once in chronological order, see
+// Stats.AddBatch.
+func (tx *WriteTx) AddBatch(batch []BatchMessage) {
+	tx.s.addBatch(batch)
+}
+
+// MarkImported records that the logs of a channel from start to end have been
+// imported.
+func (tx *WriteTx) MarkImported(network, channel string, start, end time.Time) {
+	tx.s.markImported(network, channel, start, end)
+}
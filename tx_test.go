@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats_View(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello")
+
+	s.View(func(tx *ReadTx) {
+		c := tx.GetChannel(network, channel)
+		if c == nil {
+			t.Fatal("Should find the channel.")
+		}
+		u := tx.GetUser(network, nick)
+		if u == nil || u.Lines != 1 {
+			t.Error("Should find the user:", u)
+		}
+		if n := tx.GetNetwork(network); n == nil || tx.Networks[n.ID] != n {
+			t.Error("Should find the network:", n)
+		}
+		if tx.Users[u.ID] != u || tx.Channels[c.ID] != c {
+			t.Error("Should read the maps by id.")
+		}
+	})
+}
+
+func TestStats_Update(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	date := time.Date(2014, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	// two imports of the same log racing each other count it once
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.Update(func(tx *WriteTx) {
+				if tx.Imported(network, channel, date) {
+					return
+				}
+				tx.AddBatch([]BatchMessage{{Msg, network, channel, hostmask, date, "hi"}})
+				tx.AddMessage(Msg, network, channel, hostmask, date, "there")
+				if !tx.AddMessageID("a", Msg, network, channel, hostmask, date, "again") {
+					t.Error("Should add a new msgid.")
+				}
+				tx.MarkImported(network, channel, date, date)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if u := s.GetUser(network, nick); u == nil || u.Lines != 3 {
+		t.Error("Should count the update once:", u)
+	}
+	if !s.Imported(network, channel, date) {
+		t.Error("Should mark the range imported.")
+	}
+}
@@ -0,0 +1,72 @@
+package stats
+
+import "unicode"
+
+const zeroWidthJoiner = '\u200d'
+
+// isIdeograph reports whether r belongs to a script that is written without
+// spaces between words, in which case every character counts as a word.
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai)
+}
+
+// isEmoji reports whether r is a pictographic symbol such as an emoji.
+func isEmoji(r rune) bool {
+	return unicode.Is(unicode.So, r) || (r >= 0x1f300 && r <= 0x1faff)
+}
+
+// isJoiner reports whether r modifies or joins the rune before it rather
+// than standing on its own (combining marks, variation selectors, ZWJ and
+// emoji skin tone modifiers).
+func isJoiner(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Me, r) ||
+		r == zeroWidthJoiner || (r >= 0x1f3fb && r <= 0x1f3ff)
+}
+
+// countWords counts the words in a message. Whitespace separates words, and
+// in addition every ideograph and emoji is counted as a word of its own, so
+// that CJK text and emoji-only lines get meaningful word counts.
+func countWords(message string) int {
+	words := 0
+	inWord := false
+	joined := false
+
+	for _, r := range message {
+		switch {
+		case unicode.IsSpace(r):
+			inWord = false
+		case isJoiner(r):
+			joined = r == zeroWidthJoiner
+		case isIdeograph(r), isEmoji(r):
+			if !joined {
+				words++
+			}
+			inWord = false
+		default:
+			if !inWord {
+				words++
+			}
+			inWord = true
+		}
+
+		if r != zeroWidthJoiner {
+			joined = false
+		}
+	}
+
+	return words
+}
+
+// countLetters counts the visible characters in a message, ignoring
+// whitespace and runes that only modify the character before them.
+func countLetters(message string) int {
+	letters := 0
+
+	for _, r := range message {
+		if !unicode.IsSpace(r) && !isJoiner(r) {
+			letters++
+		}
+	}
+
+	return letters
+}
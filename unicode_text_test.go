@@ -0,0 +1,53 @@
+package stats
+
+import "testing"
+
+func TestCountWords(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		message string
+		words   int
+	}{
+		{"", 0},
+		{"I pity the fool", 4},
+		{"  spaced\tout  ", 2},
+		{"héllo wörld", 2},
+		{"привет как дела", 3},
+		{"我喜欢猫", 4},
+		{"今日は good", 4},
+		{"lol 😂😂", 3},
+		{"nice👍", 2},
+		{"👍🏽", 1},
+		{"👨\u200d👩\u200d👧", 1},
+		{":) :(", 2},
+	}
+
+	for _, test := range tests {
+		if got := countWords(test.message); got != test.words {
+			t.Errorf("%q Expected: %d, Got: %d", test.message, test.words, got)
+		}
+	}
+}
+
+func TestCountLetters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		message string
+		letters int
+	}{
+		{"", 0},
+		{"I pity the fool", 12},
+		{"héllo", 5},
+		{"he\u0301llo", 5},
+		{"我喜欢猫", 4},
+		{"👍🏽 ok", 3},
+	}
+
+	for _, test := range tests {
+		if got := countLetters(test.message); got != test.letters {
+			t.Errorf("%q Expected: %d, Got: %d", test.message, test.letters, got)
+		}
+	}
+}
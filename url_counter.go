@@ -1,22 +1,219 @@
 package stats
 
-import "regexp"
-
-var tokenRegexURL = regexp.MustCompile(`(?:(?:[^\s:/?#]+)://|www\.)(?:[^\s/?#]+\.)*(?:[A-Za-z0-9][^\s/?#]*\.[A-Za-z]{2,6})(?:/[^\s#\?]+)?/?(?:\?[^\s#]*)?(?:#[^\s]*)?`)
+import (
+	"strings"
+	"time"
+)
 
 type URLCounter struct {
 	TokenCounter
+	// Seen is when each url was last pasted, so the urls can be pruned by
+	// their own retention. Those pasted before it was kept are undated.
+	Seen map[string]time.Time
 }
 
 func NewURLCounter() URLCounter {
 	return URLCounter{
-		NewTokenCounter(),
+		TokenCounter: NewTokenCounter(),
+		Seen:         make(map[string]time.Time),
 	}
 }
 
 func (u *URLCounter) addMessage(m *Message) {
-	results := tokenRegexURL.FindAllStringSubmatch(m.Message, -1)
-	for _, v := range results {
-		u.TokenCounter.addToken(v[0])
+	if m.features.NoURLs {
+		return
+	}
+
+	for _, url := range m.tokens().URLs {
+		u.TokenCounter.addToken(url)
+		if u.Seen == nil {
+			u.Seen = make(map[string]time.Time)
+		}
+		if m.Date.After(u.Seen[url]) {
+			u.Seen[url] = m.Date
+		}
+	}
+}
+
+// prune forgets the urls last pasted before the time, the undated ones are
+// kept.
+func (u *URLCounter) prune(before time.Time) {
+	for url, seen := range u.Seen {
+		if seen.Before(before) {
+			u.forget(url)
+			delete(u.Seen, url)
+		}
+	}
+}
+
+// mayHaveURL checks if text could hold a url before scanning it for one.
+func mayHaveURL(text string) bool {
+	return strings.Contains(text, "://") || strings.Contains(text, "www.")
+}
+
+// hasURL checks if there's a url in the text.
+func hasURL(text string) bool {
+	if !mayHaveURL(text) {
+		return false
+	}
+
+	start, _ := nextURL(text)
+	return start >= 0
+}
+
+// findURLs appends the urls in the text to urls. A url starts with a scheme
+// followed by :// or with www., its host ends with a top level domain made
+// of letters and it may go on with a port, a path, a query and a fragment.
+// Punctuation ending a sentence or closing brackets around the url aren't
+// part of it. The urls are cut from the text, only growing urls allocates.
+func findURLs(text string, urls []string) []string {
+	if !mayHaveURL(text) {
+		return urls
+	}
+
+	for {
+		start, end := nextURL(text)
+		if start < 0 {
+			return urls
+		}
+
+		urls = append(urls, text[start:end])
+		text = text[end:]
+	}
+}
+
+// nextURL finds where the first url in the text starts and ends, start is
+// -1 when there's none.
+func nextURL(text string) (start, end int) {
+	for from := 0; from < len(text); {
+		rest := text[from:]
+		scheme, www := strings.Index(rest, "://"), strings.Index(rest, "www.")
+		if scheme < 0 && www < 0 {
+			break
+		}
+
+		var host int
+		if www >= 0 && (scheme < 0 || www < scheme) {
+			start, host = from+www, from+www
+			if start > 0 && isHostByte(text[start-1]) {
+				// in the middle of a word
+				from = start + len("www.")
+				continue
+			}
+		} else {
+			host = from + scheme + len("://")
+			start = from + scheme
+			for start > from && isSchemeByte(text[start-1]) {
+				start--
+			}
+			// schemes start with a letter
+			for start < from+scheme && !isLetterByte(text[start]) {
+				start++
+			}
+			if start == from+scheme {
+				from = host
+				continue
+			}
+		}
+
+		if end = scanHost(text, host); end < 0 {
+			from = host
+			continue
+		}
+		return start, scanPath(text, host, end)
+	}
+
+	return -1, -1
+}
+
+// scanHost returns where the host starting at i ends, it's -1 when it isn't
+// a host with a top level domain.
+func scanHost(text string, i int) int {
+	end := i
+	for end < len(text) && isHostByte(text[end]) {
+		end++
 	}
+	for end > i && (text[end-1] == '.' || text[end-1] == '-') {
+		end--
+	}
+
+	dot := strings.LastIndexByte(text[i:end], '.')
+	if dot <= 0 {
+		return -1
+	}
+
+	tld := text[i+dot+1 : end]
+	if len(tld) < 2 {
+		return -1
+	}
+	for j := 0; j < len(tld); j++ {
+		if !isLetterByte(tld[j]) && tld[j] < 0x80 {
+			return -1
+		}
+	}
+
+	return end
+}
+
+// scanPath returns where the url whose host ends at i ends, after its port,
+// path, query and fragment.
+func scanPath(text string, host, i int) int {
+	if i+1 < len(text) && text[i] == ':' && isDigitByte(text[i+1]) {
+		for i++; i < len(text) && isDigitByte(text[i]); i++ {
+		}
+	}
+
+	if i >= len(text) || text[i] != '/' && text[i] != '?' && text[i] != '#' {
+		return i
+	}
+
+	end := i
+	for end < len(text) && !isSpaceByte(text[end]) {
+		end++
+	}
+
+	// punctuation and brackets around the url
+	for end > i {
+		switch text[end-1] {
+		case '.', ',', ';', ':', '!', '?', '\'', '"', '>':
+			end--
+			continue
+		case ')':
+			if strings.Count(text[host:end], "(") < strings.Count(text[host:end], ")") {
+				end--
+				continue
+			}
+		case ']':
+			if strings.Count(text[host:end], "[") < strings.Count(text[host:end], "]") {
+				end--
+				continue
+			}
+		}
+		break
+	}
+
+	return end
+}
+
+func isLetterByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func isDigitByte(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f'
+}
+
+// isSchemeByte checks if a byte may be part of a scheme, eg. svn+ssh.
+func isSchemeByte(b byte) bool {
+	return isLetterByte(b) || isDigitByte(b) || b == '+' || b == '-' || b == '.'
+}
+
+// isHostByte checks if a byte may be part of a host name, bytes of non ascii
+// characters are for internationalized names.
+func isHostByte(b byte) bool {
+	return isLetterByte(b) || isDigitByte(b) || b == '-' || b == '.' || b == '_' || b >= 0x80
 }
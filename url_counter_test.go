@@ -2,7 +2,9 @@ package stats
 
 import (
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestTokenCounter_URL(t *testing.T) {
@@ -57,3 +59,100 @@ func TestTokenCounter_URL(t *testing.T) {
 		}
 	}
 }
+
+func TestFindURLs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text   string
+		expect []string
+	}{
+		{"http://google.com", []string{"http://google.com"}},
+		{"see https://zqz.ca/a/b?c=d#e, it's great", []string{"https://zqz.ca/a/b?c=d#e"}},
+		{"go to www.zqz.ca.", []string{"www.zqz.ca"}},
+		{"(http://en.wikipedia.org/wiki/Go_(language))", []string{"http://en.wikipedia.org/wiki/Go_(language)"}},
+		{"<http://zqz.ca:8080/x> and svn+ssh://git.zqz.ca", []string{"http://zqz.ca:8080/x", "svn+ssh://git.zqz.ca"}},
+		{"http://a.com,http://b.com", []string{"http://a.com", "http://b.com"}},
+		{"http://bücher.de/ok", []string{"http://bücher.de/ok"}},
+		{"file.txt and e.g. i.e. and version 1.2.3", nil},
+		{"http://localhost:8080 and ://zqz.ca and http://1.2", nil},
+		{"awww.zqz.ca is not a url", nil},
+	}
+
+	for _, test := range tests {
+		if urls := findURLs(test.text, nil); !reflect.DeepEqual(urls, test.expect) {
+			t.Errorf("%q Expected: %q, Got: %q", test.text, test.expect, urls)
+		}
+	}
+
+	if !hasURL("x:http://zqz.ca") || hasURL("zqz.ca") {
+		t.Error("Should find urls in words.")
+	}
+}
+
+func TestFindURLs_allocs(t *testing.T) {
+	urls := make([]string, 0, 4)
+	allocs := testing.AllocsPerRun(100, func() {
+		findURLs("look at http://zqz.ca/x and www.google.com now", urls[:0])
+		findURLs("nothing to see here, move along", urls[:0])
+	})
+	if allocs != 0 {
+		t.Error("Should not allocate, Got:", allocs)
+	}
+}
+
+func BenchmarkFindURLs(b *testing.B) {
+	texts := []string{
+		"look at http://zqz.ca/a/b?c=d it's great",
+		"nothing to see here, move along",
+		"www.google.com or (https://en.wikipedia.org/wiki/Go_(language))",
+	}
+	urls := make([]string, 0, 4)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		urls = findURLs(texts[i%len(texts)], urls[:0])
+	}
+}
+
+func BenchmarkURLCounter(b *testing.B) {
+	tc := NewURLCounter()
+	messages := make([]*Message, 1000)
+	for i := range messages {
+		if i%4 == 0 {
+			messages[i] = &Message{Message: fmt.Sprintf("look at http://zqz.ca/%d it's great", i%300)}
+		} else {
+			messages[i] = &Message{Message: "nothing to see here, move along"}
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := messages[i%len(messages)]
+		m.forgetTokens()
+		tc.addMessage(m)
+		_ = tc.Top[:1]
+	}
+}
+
+func TestURLCounter_prune(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	u := NewURLCounter()
+	u.addMessage(&Message{Message: "http://old.com http://both.com", Date: now.AddDate(-2, 0, 0)})
+	u.addMessage(&Message{Message: "http://both.com http://new.com", Date: now})
+
+	u.prune(now.AddDate(-1, 0, 0))
+	if _, ok := u.All["http://old.com"]; ok || u.Top.index("http://old.com") >= 0 {
+		t.Error("Should forget the urls not pasted since the cutoff:", u.All)
+	}
+	if u.All["http://both.com"] != 2 || u.All["http://new.com"] != 1 {
+		t.Error("Should keep the urls pasted since the cutoff:", u.All)
+	}
+	if _, ok := u.Seen["http://old.com"]; ok || !u.Seen["http://both.com"].Equal(now) {
+		t.Error("Should date the urls by when they were last pasted:", u.Seen)
+	}
+}
@@ -7,29 +7,48 @@ import (
 
 type User struct {
 	HourlyChart
-	WordCounter
-	SwearCounter
-	EmoticonCounter
+	WordCounter     `json:"WordCounter"`
+	SwearCounter    `json:"SwearCounter"`
+	EmoticonCounter `json:"EmoticonCounter"`
 	QuestionsCount
 	ExclamationsCount
 	AllCapsCount
-	BasicTextCounters
-	ModeCounters
+	BasicTextCounters `json:"BasicTextCounters"`
+	ModeCounters      `json:"ModeCounters"`
 	NickReferences
+	LanguageCounter `json:"LanguageCounter"`
 
+	TextByKind   KindTextCounters
 	KickCounters SendRecvCounters
 	SlapCounters SendRecvCounters
+	Reactions    SendRecvCounters
+	Bursts       BurstCounter
 	Quotes       quotes
+	Mood         MoodSeries
 
-	ID           uint
-	Nick         string
+	ID   uint
+	Nick string
+	// Hostmask, Username and Host are those the user was last seen with,
+	// users from chats without hostmasks have none.
 	Hostmask     string
+	Username     string
+	Host         string
 	NetworkID    uint
-	MessageIDs   []uint
 	ChannelUsers map[string]*User
+	// MessageRanges are the ids of the messages, MessageCount is how many
+	// messages were counted, MessageRanges forgets the ones pruned.
+	MessageRanges IDRanges
+	MessageCount  uint
+	// MessageIDs are the ids of stats saved before MessageRanges, they're
+	// moved to it when loaded.
+	MessageIDs []uint
 
-	LastSeen       time.Time
-	MaxConsecutive uint
+	LastSeen        time.Time
+	MaxConsecutive  uint
+	SelfCorrections uint
+
+	ConversationStarts uint
+	NickChanges        uint
 }
 
 func NewUser(id uint, networkID uint, nick string) *User {
@@ -37,18 +56,31 @@ func NewUser(id uint, networkID uint, nick string) *User {
 		ID:           id,
 		Nick:         nick,
 		NetworkID:    networkID,
-		MessageIDs:   make([]uint, 0),
 		ChannelUsers: make(map[string]*User),
 
 		WordCounter:     NewWordCounter(),
 		SwearCounter:    NewSwearCounter(),
 		EmoticonCounter: NewEmoticonCounter(),
 		NickReferences:  make(NickReferences),
+		LanguageCounter: NewLanguageCounter(),
+		Mood:            make(MoodSeries),
+		TextByKind:      make(KindTextCounters),
 	}
 
 	return &user
 }
 
+// seenAs keeps the parts of the hostmask a message of the user came from.
+func (u *User) seenAs(h Hostmask) {
+	if len(h.User) == 0 && len(h.Host) == 0 {
+		return
+	}
+
+	if h.User != u.Username || h.Host != u.Host {
+		u.Hostmask, u.Username, u.Host = h.String(), h.User, h.Host
+	}
+}
+
 // newChannelUser
 func (u *User) addChannelUser(channel string) *User {
 	cu := NewUser(u.ID, u.NetworkID, u.Nick)
@@ -57,28 +89,45 @@ func (u *User) addChannelUser(channel string) *User {
 }
 
 func (u *User) addMessage(network *Network, channel *Channel, message *Message) {
-	u.MessageIDs = append(u.MessageIDs, message.ID)
+	if network.keepsID(message) {
+		u.MessageRanges.add(message.ID)
+	}
+	u.MessageCount++
 
 	if message.Kind == Msg {
+		words := network.wordTokens(message)
+
 		u.HourlyChart.addMessage(message)
 		u.Quotes.addMessage(message)
-		u.WordCounter.addMessage(message)
+		if !message.features.NoWords {
+			u.WordCounter.addTokens(words)
+		}
 		u.SwearCounter.addMessage(message)
 		u.EmoticonCounter.addMessage(message)
-		u.BasicTextCounters.addMessage(message)
+		u.BasicTextCounters.addTokens(words)
 		u.QuestionsCount.addMessage(message)
 		u.ExclamationsCount.addMessage(message)
 		u.AllCapsCount.addMessage(message)
 		u.NickReferences.addMessage(network, channel, message)
 	}
 
+	if message.Kind.isChat() {
+		u.TextByKind.addTokens(message.Kind, network.wordTokens(message))
+	}
+
 	if message.Kind == Mode {
 		u.ModeCounters.addMessage(message)
 	}
 
-	u.LastSeen = message.Date
+	if message.Kind == Nick {
+		u.NickChanges++
+	}
+
+	if message.Date.After(u.LastSeen) {
+		u.LastSeen = message.Date
+	}
 }
 
 func (u *User) String() string {
-	return fmt.Sprintf("User: %s, Messages: %d", u.Nick, len(u.MessageIDs))
+	return fmt.Sprintf("User: %s, Messages: %d", u.Nick, u.MessageCount)
 }
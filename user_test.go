@@ -8,7 +8,7 @@ import (
 func TestUser_BasicTextCounters(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "a b c d ef")
 
@@ -26,7 +26,7 @@ func TestUser_BasicTextCounters(t *testing.T) {
 func TestUser_EmoticonCounter(t *testing.T) {
 	t.Parallel()
 
-	s := NewStats()
+	s := newStats()
 
 	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "you wanna come over ;) ;)")
 
@@ -41,11 +41,37 @@ func TestUser_String(t *testing.T) {
 	t.Parallel()
 
 	u := &User{
-		Nick:       "foo",
-		MessageIDs: []uint{1, 2, 3},
+		Nick:          "foo",
+		MessageRanges: idRanges([]uint{1, 2, 3}),
+		MessageCount:  3,
 	}
 
 	if u.String() != "User: foo, Messages: 3" {
 		t.Error("Didn't return correct string")
 	}
 }
+
+func TestUser_TextByKind(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "hello")
+	s.AddMessage(Action, network, channel, hostmask, time.Now(), "waves at everyone")
+	s.AddMessage(Notice, network, channel, hostmask, time.Now(), "psst")
+	s.AddMessage(Join, network, channel, hostmask, time.Now(), "")
+
+	u := s.Users[1]
+
+	if len(u.TextByKind) != 3 {
+		t.Error("Should only break down chat kinds.")
+	}
+
+	if u.TextByKind[Action].Words != 3 {
+		t.Error("Should have counted the words of the action.")
+	}
+
+	if f := s.Channels[1].TextByKind.Fraction(Action); f != 1.0/3 {
+		t.Error("A third of the channel's lines should be actions, Got:", f)
+	}
+}
@@ -0,0 +1,164 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Validate checks the invariants that tie the stats together and repairs
+// those that don't hold, returning what it repaired. The networks, channels
+// and users must be indexed by their names in lower case, the ids they refer
+// to must exist and the id counters must be past the ids in use. It's run
+// when the stats are loaded, the problems found then are logged.
+func (s *Stats) Validate() []error {
+	s.lock()
+	defer s.mut.Unlock()
+
+	return s.validate()
+}
+
+// Repaired returns the problems Validate repaired when the stats were loaded
+// from data.db, they aren't logged since the stats have no options yet. They're
+// repaired in data.db once the stats are saved.
+func (s *Stats) Repaired() []error {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return append([]error(nil), s.repaired...)
+}
+
+// validate is Validate with the stats locked for writing.
+func (s *Stats) validate() []error {
+	var errs []error
+
+	byName := make(map[string]*Network, len(s.Networks))
+	ids := make([]uint, 0, len(s.Networks))
+	for id := range s.Networks {
+		ids = append(ids, id)
+	}
+	// in order, so the problems are found in the same order every time
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	for _, id := range ids {
+		n := s.Networks[id]
+		key := strings.ToLower(n.Name)
+		if other, ok := byName[key]; ok {
+			errs = append(errs, fmt.Errorf("Networks %d and %d are both named %s, only %d is indexed", other.ID, id, n.Name, other.ID))
+			continue
+		}
+		byName[key] = n
+		if s.networkByName[key] != n {
+			errs = append(errs, fmt.Errorf("Network %s isn't indexed by its name", n.Name))
+		}
+
+		errs = append(errs, s.validateNetwork(n)...)
+	}
+	var stale []string
+	for key, n := range s.networkByName {
+		if byName[key] != n {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	for _, key := range stale {
+		errs = append(errs, fmt.Errorf("Network %s is indexed by the name %s", s.networkByName[key].Name, key))
+	}
+	s.networkByName = byName
+
+	var channel, user uint
+	for id := range s.Channels {
+		if id > channel {
+			channel = id
+		}
+	}
+	for id := range s.Users {
+		if id > user {
+			user = id
+		}
+	}
+	var network uint
+	if len(ids) > 0 {
+		network = ids[len(ids)-1]
+	}
+
+	s.NetworkIDCount = validCount(&errs, "network", s.NetworkIDCount, network)
+	s.ChannelIDCount = validCount(&errs, "channel", s.ChannelIDCount, channel)
+	s.UserIDCount = validCount(&errs, "user", s.UserIDCount, user)
+
+	return errs
+}
+
+// validateNetwork checks the channels and users of a network exist, belong to
+// it and are indexed by their names, dropping those that don't.
+func (s *Stats) validateNetwork(n *Network) []error {
+	var errs []error
+	if n.stats != s {
+		errs = append(errs, fmt.Errorf("Network %s isn't tied to its stats", n.Name))
+		n.stats = s
+	}
+
+	channels := make(map[string]*Channel, len(n.ChannelIDs))
+	channelIDs := n.ChannelIDs[:0]
+	for _, id := range n.ChannelIDs {
+		c, ok := s.Channels[id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("Network %s has the channel %d which doesn't exist", n.Name, id))
+			continue
+		case c.NetworkID != n.ID:
+			errs = append(errs, fmt.Errorf("Network %s has the channel %s of the network %d", n.Name, c.Name, c.NetworkID))
+			continue
+		}
+
+		key := strings.ToLower(c.Name)
+		if other, ok := channels[key]; ok {
+			errs = append(errs, fmt.Errorf("Channels %d and %d of network %s are both named %s, only %d is indexed", other.ID, id, n.Name, c.Name, other.ID))
+			continue
+		}
+		if n.channels[key] != c {
+			errs = append(errs, fmt.Errorf("Channel %s of network %s isn't indexed by its name", c.Name, n.Name))
+		}
+		channels[key] = c
+		channelIDs = append(channelIDs, id)
+	}
+	n.ChannelIDs, n.channels = channelIDs, channels
+
+	users := make(map[string]*User, len(n.UserIDs))
+	userIDs := n.UserIDs[:0]
+	for _, id := range n.UserIDs {
+		u, ok := s.Users[id]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("Network %s has the user %d which doesn't exist", n.Name, id))
+			continue
+		case u.NetworkID != n.ID:
+			errs = append(errs, fmt.Errorf("Network %s has the user %s of the network %d", n.Name, u.Nick, u.NetworkID))
+			continue
+		}
+
+		key := n.fold(u.Nick)
+		if other, ok := users[key]; ok {
+			errs = append(errs, fmt.Errorf("Users %d and %d of network %s are both named %s, only %d is indexed", other.ID, id, n.Name, u.Nick, other.ID))
+			continue
+		}
+		if n.users[key] != u {
+			errs = append(errs, fmt.Errorf("User %s of network %s isn't indexed by its nick", u.Nick, n.Name))
+		}
+		users[key] = u
+		userIDs = append(userIDs, id)
+	}
+	n.UserIDs, n.users = userIDs, users
+
+	return errs
+}
+
+// validCount returns an id counter that's past the last id in use.
+func validCount(errs *[]error, kind string, count, last uint) uint {
+	if count > last {
+		return count
+	}
+
+	*errs = append(*errs, fmt.Errorf("The next %s id %d is already in use, it's now %d", kind, count, last+1))
+	return last + 1
+}
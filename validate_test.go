@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStats_lookupAnyCase(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, "Test_Network", "#Test", "Phish!"+name+"@"+host, time.Now(), "some foo")
+
+	s = saveLoad(t, s)
+	if errs := s.Validate(); len(errs) > 0 {
+		t.Error("Loaded stats should be valid:", errs)
+	}
+
+	for _, net := range []string{"Test_Network", "test_network", "TEST_NETWORK"} {
+		if n := s.GetNetwork(net); n == nil || n.Name != "Test_Network" {
+			t.Errorf("Should find the network as %s after loading.", net)
+		}
+		if c := s.GetChannel(net, "#TEST"); c == nil || c.Name != "#Test" {
+			t.Errorf("Should find the channel on %s after loading.", net)
+		}
+		if u := s.GetUser(net, "pHiSh"); u == nil || u.Nick != "Phish" {
+			t.Errorf("Should find the user on %s after loading.", net)
+		}
+	}
+
+	// messages added after loading go to the same network, channel and user
+	s.AddMessage(Msg, network, "#test", hostmask, time.Now(), "bar")
+	if len(s.Networks) != 1 || len(s.Channels) != 1 || len(s.Users) != 1 {
+		t.Errorf("Should add to what was loaded, have %d networks, %d channels and %d users.",
+			len(s.Networks), len(s.Channels), len(s.Users))
+	}
+	if c := s.GetChannel(network, channel); c.MessageCount != 2 {
+		t.Error("Should count both messages, counted", c.MessageCount)
+	}
+}
+
+func TestStats_Validate(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+	s.AddMessage(Msg, network, "#other", "aaron", time.Now(), "bar")
+
+	if errs := s.Validate(); len(errs) > 0 {
+		t.Fatal("Should be valid:", errs)
+	}
+
+	n := s.GetNetwork(network)
+	delete(s.networkByName, network)
+	s.networkByName["Test_Network"] = n
+	n.users["PHISH"] = n.users[nick]
+	delete(n.users, nick)
+	n.ChannelIDs = append(n.ChannelIDs, 42)
+	s.UserIDCount = 2
+
+	errs := s.Validate()
+	want := []string{
+		"Network test_network isn't indexed by its name",
+		"Network test_network has the channel 42 which doesn't exist",
+		"User phish of network test_network isn't indexed by its nick",
+		"Network test_network is indexed by the name Test_Network",
+		"The next user id 2 is already in use, it's now 3",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Should find %d problems, found %d: %v", len(want), len(errs), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != want[i] {
+			t.Errorf("Problem %d should be %q, is %q", i, want[i], err)
+		}
+	}
+
+	if errs := s.Validate(); len(errs) > 0 {
+		t.Error("Should have repaired the problems:", errs)
+	}
+	if s.GetNetwork(network) != n || s.GetUser(network, nick) == nil || len(n.ChannelIDs) != 2 {
+		t.Error("Should index the network and user and drop the missing channel.")
+	}
+	s.AddMessage(Msg, network, channel, "newbie", time.Now(), "hi")
+	if u := s.GetUser(network, "newbie"); u == nil || u.ID != 3 {
+		t.Error("Should give new users ids that aren't in use.")
+	}
+}
+
+func TestStats_validateDuplicates(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+
+	// a second channel of the same name, as if two were created in
+	// different cases
+	n := s.GetNetwork(network)
+	c := s.addChannel(n, "#Test")
+
+	// the second channel took the place of the first in the index
+	errs := s.Validate()
+	if len(errs) != 2 || !strings.Contains(errs[1].Error(), "Channels 1 and 2 of network test_network are both named #Test") {
+		t.Fatal("Should find the duplicate channel:", errs)
+	}
+	if got := s.GetChannel(network, "#TEST"); got == c || got == nil {
+		t.Error("Should keep indexing the first channel.")
+	}
+	if len(n.ChannelIDs) != 1 {
+		t.Error("Should drop the duplicate from the network, it has", n.ChannelIDs)
+	}
+}
+
+func TestStats_Repaired(t *testing.T) {
+	s := newStats()
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "some foo")
+	s.UserIDCount = 1
+
+	s = saveLoad(t, s)
+	if errs := s.Repaired(); len(errs) != 1 || !strings.Contains(errs[0].Error(), "next user id") {
+		t.Error("Should keep what was repaired when loading:", errs)
+	}
+	if errs := s.Validate(); len(errs) > 0 {
+		t.Error("Should have repaired the stats when loading:", errs)
+	}
+}
@@ -1,12 +1,5 @@
 package stats
 
-import (
-	"regexp"
-	"strings"
-)
-
-var tokenRegexWord = regexp.MustCompile(`^([a-zA-Z]+)[\?!;,\.]?$`)
-
 type WordCounter struct {
 	TokenCounter
 }
@@ -18,10 +11,36 @@ func NewWordCounter() WordCounter {
 }
 
 func (w *WordCounter) addMessage(m *Message) {
-	words := strings.Fields(m.Message)
-	for _, v := range words {
-		if r := tokenRegexWord.FindStringSubmatch(v); r != nil {
-			w.TokenCounter.addToken(strings.ToLower(r[1]))
+	w.addTokens(m.tokens())
+}
+
+func (w *WordCounter) addText(text string) {
+	w.addTokens(tokenize(text))
+}
+
+func (w *WordCounter) addTokens(t *Tokens) {
+	for _, word := range t.Terms {
+		w.TokenCounter.addToken(word)
+	}
+}
+
+// wordToken finds the word in a token made of ascii letters, optionally
+// followed by a single punctuation mark.
+func wordToken(token string) (string, bool) {
+	if n := len(token); n > 1 {
+		switch token[n-1] {
+		case '?', '!', ';', ',', '.':
+			token = token[:n-1]
+		}
+	}
+	if len(token) == 0 {
+		return "", false
+	}
+
+	for i := 0; i < len(token); i++ {
+		if c := token[i]; !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return "", false
 		}
 	}
+	return token, true
 }
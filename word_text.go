@@ -0,0 +1,76 @@
+package stats
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WordOptions control which tokens of a message count as words for the word
+// and letter counters.
+type WordOptions struct {
+	// ExcludeURLs skips tokens that look like URLs.
+	ExcludeURLs bool
+	// ExcludeNickPrefix skips a leading "nick:" or "nick," addressing a
+	// known user.
+	ExcludeNickPrefix bool
+	// ExcludePunctuation skips tokens made up only of punctuation.
+	ExcludePunctuation bool
+}
+
+// wordText returns the part of a message that should be counted as words.
+func (o WordOptions) wordText(network *Network, message string) string {
+	if !o.excludes() {
+		return message
+	}
+
+	return strings.Join(o.wordFields(network, strings.Fields(message)), " ")
+}
+
+// excludes checks if any tokens are left out of the words.
+func (o WordOptions) excludes() bool {
+	return o.ExcludeURLs || o.ExcludeNickPrefix || o.ExcludePunctuation
+}
+
+// wordFields returns the fields of a message that should be counted as words.
+func (o WordOptions) wordFields(network *Network, fields []string) []string {
+	kept := make([]string, 0, len(fields))
+
+	for i, f := range fields {
+		if i == 0 && o.ExcludeNickPrefix && isNickPrefix(network, f) {
+			continue
+		}
+		if o.ExcludeURLs && hasURL(f) {
+			continue
+		}
+		if o.ExcludePunctuation && isPunctuation(f) {
+			continue
+		}
+
+		kept = append(kept, f)
+	}
+
+	return kept
+}
+
+// isNickPrefix checks if a token addresses a user on the network, eg. "bob:"
+// or "@bob," as nick lists show the nicks of ops.
+func isNickPrefix(network *Network, token string) bool {
+	if network == nil || !strings.HasSuffix(token, ":") && !strings.HasSuffix(token, ",") {
+		return false
+	}
+
+	nick := strings.TrimLeft(token[:len(token)-1], nickPrefixes)
+	_, ok := network.users[network.fold(nick)]
+	return ok
+}
+
+// isPunctuation checks if a token consists only of punctuation
+func isPunctuation(token string) bool {
+	for _, r := range token {
+		if !unicode.IsPunct(r) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWordOptions_wordText(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.AddMessage(Msg, network, channel, "bob", time.Now(), "hi")
+	n := s.GetNetwork(network)
+
+	message := "Bob: see http://google.com ... it's :) great"
+
+	if text := (WordOptions{}).wordText(n, message); text != message {
+		t.Error("Should not change the message without options.")
+	}
+
+	tests := []struct {
+		opts   WordOptions
+		expect string
+	}{
+		{WordOptions{ExcludeURLs: true}, "Bob: see ... it's :) great"},
+		{WordOptions{ExcludeNickPrefix: true}, "see http://google.com ... it's :) great"},
+		{WordOptions{ExcludePunctuation: true}, "Bob: see http://google.com it's great"},
+		{WordOptions{true, true, true}, "see it's great"},
+	}
+
+	for _, test := range tests {
+		if text := test.opts.wordText(n, message); text != test.expect {
+			t.Errorf("%+v Expected: %q, Got: %q", test.opts, test.expect, text)
+		}
+	}
+
+	opts := WordOptions{ExcludeNickPrefix: true}
+	if text := opts.wordText(n, "Note: unknown nick"); text != "Note: unknown nick" {
+		t.Error("Should only strip the prefix of known nicks, Got:", text)
+	}
+}
+
+func TestStats_WordOptions(t *testing.T) {
+	t.Parallel()
+
+	s := newStats()
+	s.SetOptions(Options{Words: WordOptions{ExcludeURLs: true, ExcludeNickPrefix: true}})
+
+	s.AddMessage(Msg, network, channel, "bob", time.Now(), "hi")
+	s.AddMessage(Msg, network, channel, hostmask, time.Now(), "bob, look http://google.com")
+
+	u := s.GetUser(network, nick)
+	if u.Words != 1 || u.Letters != 4 {
+		t.Errorf("Should only count one word, Got: %d words %d letters", u.Words, u.Letters)
+	}
+
+	if _, ok := s.GetChannel(network, channel).WordCounter.All["bob"]; ok {
+		t.Error("Should not count the addressed nick as a word.")
+	}
+}
@@ -0,0 +1,167 @@
+package xmpp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Component connects to a server as an external component (XEP-0114), joins
+// the rooms and feeds what is said in them into an adapter. The server must
+// have the component's domain and secret configured.
+type Component struct {
+	Adapter *Adapter
+	// Addr is the host:port of the server's component port.
+	Addr   string
+	Domain string
+	Secret string
+
+	// Rooms are the bare jids of the rooms to join, as Nick.
+	Rooms []string
+	Nick  string
+}
+
+const (
+	componentNS = "jabber:component:accept"
+	streamNS    = "http://etherx.jabber.org/streams"
+)
+
+// Run connects to the server until stop is closed, reconnecting a little
+// after the connection is lost.
+func (c *Component) Run(stop <-chan struct{}) {
+	for {
+		conn, err := net.DialTimeout("tcp", c.Addr, 30*time.Second)
+		if err == nil {
+			done := make(chan struct{})
+			go func() {
+				select {
+				case <-stop:
+					conn.Close()
+				case <-done:
+				}
+			}()
+			c.Serve(conn)
+			close(done)
+			conn.Close()
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// Serve authenticates over an open connection to the server, joins the rooms
+// and handles stanzas until the stream ends.
+func (c *Component) Serve(conn io.ReadWriter) error {
+	dec := xml.NewDecoder(conn)
+
+	if _, err := fmt.Fprintf(conn, "<stream:stream xmlns='%s' xmlns:stream='%s' to='%s'>", componentNS, streamNS, escape(c.Domain)); err != nil {
+		return err
+	}
+
+	id, err := streamID(dec)
+	if err != nil {
+		return err
+	}
+
+	sum := sha1.Sum([]byte(id + c.Secret))
+	if _, err = fmt.Fprintf(conn, "<handshake>%s</handshake>", hex.EncodeToString(sum[:])); err != nil {
+		return err
+	}
+
+	se, err := nextElement(dec)
+	if err != nil {
+		return err
+	}
+	if se.Name.Local != "handshake" {
+		return errors.New("xmpp: the server refused the handshake")
+	}
+	dec.Skip()
+
+	for _, room := range c.Rooms {
+		// the history of the room is left out, it was counted when it was
+		// said if the component was around
+		_, err = fmt.Fprintf(conn, "<presence from='stats@%s' to='%s/%s'><x xmlns='http://jabber.org/protocol/muc'><history maxstanzas='0'/></x></presence>",
+			escape(c.Domain), escape(room), escape(c.Nick))
+		if err != nil {
+			return err
+		}
+	}
+
+	for {
+		se, err := nextElement(dec)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		switch se.Name.Local {
+		case "message", "presence":
+			var st Stanza
+			if err = dec.DecodeElement(&st, &se); err != nil {
+				return err
+			}
+			c.Adapter.HandleStanza(st)
+		case "error":
+			return errors.New("xmpp: stream error")
+		default:
+			if err = dec.Skip(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// streamID reads the server's stream header for the id the handshake is
+// made with.
+func streamID(dec *xml.Decoder) (string, error) {
+	se, err := nextElement(dec)
+	if err != nil {
+		return "", err
+	}
+	if se.Name.Local != "stream" {
+		return "", fmt.Errorf("xmpp: expected a stream, got %s", se.Name.Local)
+	}
+	for _, attr := range se.Attr {
+		if attr.Name.Local == "id" {
+			return attr.Value, nil
+		}
+	}
+	return "", errors.New("xmpp: the stream has no id")
+}
+
+// nextElement reads up to the next element opened, the end of the stream is
+// io.EOF.
+func nextElement(dec *xml.Decoder) (xml.StartElement, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			if t.Name.Local == "stream" {
+				return xml.StartElement{}, io.EOF
+			}
+		}
+	}
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,71 @@
+package xmpp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+func TestComponent_Serve(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer client.Close()
+
+	sum := sha1.Sum([]byte("s1" + "hunter2"))
+	handshake := fmt.Sprintf("<handshake>%s</handshake>", hex.EncodeToString(sum[:]))
+
+	joined := make(chan string, 1)
+	go func() {
+		defer server.Close()
+		r := bufio.NewReader(server)
+
+		header, _ := r.ReadString('>')
+		if !strings.Contains(header, "to='stats.zqz.ca'") {
+			t.Error("Should open a stream to its domain:", header)
+		}
+		fmt.Fprint(server, "<stream:stream xmlns='jabber:component:accept' xmlns:stream='http://etherx.jabber.org/streams' id='s1'>")
+
+		got := make([]byte, len(handshake))
+		if _, err := r.Read(got); err != nil || string(got) != handshake {
+			t.Error("Bad handshake:", string(got))
+			return
+		}
+		fmt.Fprint(server, "<handshake/>")
+
+		presence, _ := r.ReadString('>')
+		joined <- presence
+		fmt.Fprint(server, "<message from='deviate@conference.zqz.ca/dylan' type='groupchat'><body>hello</body></message>")
+		fmt.Fprint(server, "<iq type='get' id='ping'><ping xmlns='urn:xmpp:ping'/></iq>")
+		fmt.Fprint(server, "</stream:stream>")
+	}()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Component{
+		Adapter: New(s, "zkpq"),
+		Domain:  "stats.zqz.ca",
+		Secret:  "hunter2",
+		Rooms:   []string{room},
+		Nick:    "stats",
+	}
+
+	if err := c.Serve(client); err != nil {
+		t.Fatal(err)
+	}
+	if presence := <-joined; !strings.Contains(presence, "to='deviate@conference.zqz.ca/stats'") {
+		t.Error("Should join the rooms:", presence)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 1 {
+		t.Error("Should count the message.")
+	}
+}
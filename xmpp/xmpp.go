@@ -0,0 +1,274 @@
+// Package xmpp feeds the messages of XMPP multi-user chat rooms into a Stats
+// database as another network, for IRC communities mirrored to MUC rooms.
+// Stanzas come from a Component connected to the server, or from any client
+// library handing them to the adapter.
+package xmpp
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DylanJ/stats"
+)
+
+// defaultNetwork is the network the rooms are counted under when the adapter
+// doesn't name one.
+const defaultNetwork = "xmpp"
+
+// MUC status codes of the presences the adapter cares about.
+const (
+	statusSelf       = 110
+	statusNickChange = 303
+	statusKicked     = 307
+)
+
+// Stanza is a message or presence stanza, only the fields needed for stats
+// are decoded.
+type Stanza struct {
+	XMLName xml.Name
+	From    string  `xml:"from,attr"`
+	To      string  `xml:"to,attr"`
+	ID      string  `xml:"id,attr"`
+	Type    string  `xml:"type,attr"`
+	Body    string  `xml:"body"`
+	Subject *string `xml:"subject"`
+	// Delay is set on the messages of the room's history.
+	Delay *struct {
+		Stamp time.Time `xml:"stamp,attr"`
+	} `xml:"urn:xmpp:delay delay"`
+	StanzaIDs []struct {
+		ID string `xml:"id,attr"`
+		By string `xml:"by,attr"`
+	} `xml:"urn:xmpp:sid:0 stanza-id"`
+	MUC *struct {
+		Item struct {
+			JID  string `xml:"jid,attr"`
+			Nick string `xml:"nick,attr"`
+		} `xml:"item"`
+		Statuses []struct {
+			Code int `xml:"code,attr"`
+		} `xml:"status"`
+	} `xml:"http://jabber.org/protocol/muc#user x"`
+}
+
+// Adapter maps the stanzas of MUC rooms onto AddMessage calls.
+type Adapter struct {
+	Stats *stats.Stats
+	// Network is the network the rooms are counted under. To merge the
+	// stats with a mirrored IRC network use the IRC network's name.
+	Network string
+	// Rooms maps the bare jids of rooms to channel names. Rooms not in it
+	// are named after the local part of their jid.
+	Rooms map[string]string
+
+	mut       sync.Mutex
+	occupants map[string]string // occupant jid to hostmask
+}
+
+// New creates an adapter feeding the stats under the network.
+func New(s *stats.Stats, network string) *Adapter {
+	if len(network) == 0 {
+		network = defaultNetwork
+	}
+	return &Adapter{
+		Stats:   s,
+		Network: network,
+		Rooms:   make(map[string]string),
+	}
+}
+
+// HandleStanza adds a groupchat message or an occupant's presence to the
+// stats, returning false if there was nothing to count.
+func (a *Adapter) HandleStanza(st Stanza) bool {
+	room, nick := splitJID(st.From)
+	if len(nick) == 0 {
+		// sent by the room itself
+		return false
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	switch st.XMLName.Local {
+	case "message":
+		if st.Type != "groupchat" {
+			return false
+		}
+		return a.message(room, st)
+	case "presence":
+		return a.presence(room, nick, st)
+	}
+	return false
+}
+
+func (a *Adapter) message(room string, st Stanza) bool {
+	date := time.Now()
+	if st.Delay != nil && !st.Delay.Stamp.IsZero() {
+		date = st.Delay.Stamp
+	}
+
+	msgid := a.msgid(room, st)
+	mask := a.hostmask(st.From)
+	channel := a.channel(room)
+
+	if st.Subject != nil && len(st.Body) == 0 {
+		return a.Stats.AddMessageID(msgid, stats.Topic, a.Network, channel, mask, date, *st.Subject)
+	}
+
+	counted := false
+	// messages of several lines count as a line each like they would on irc
+	for i, line := range strings.Split(st.Body, "\n") {
+		if line = strings.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		kind := stats.Msg
+		if strings.HasPrefix(line, "/me ") {
+			kind, line = stats.Action, strings.TrimSpace(line[4:])
+		}
+
+		id := msgid
+		if len(id) > 0 && i > 0 {
+			id += ":" + strconv.Itoa(i)
+		}
+		if a.Stats.AddMessageID(id, kind, a.Network, channel, mask, date, line) {
+			counted = true
+		}
+	}
+	return counted
+}
+
+// presence counts occupants joining, leaving and changing their nick.
+// Presences of occupants already in the room only update their status.
+func (a *Adapter) presence(room, nick string, st Stanza) bool {
+	if a.occupants == nil {
+		a.occupants = make(map[string]string)
+	}
+
+	var codes []int
+	realJID := ""
+	if st.MUC != nil {
+		realJID = st.MUC.Item.JID
+		for _, s := range st.MUC.Statuses {
+			codes = append(codes, s.Code)
+		}
+	}
+	if hasCode(codes, statusSelf) {
+		// the presence of whoever feeds the adapter
+		return false
+	}
+
+	mask := occupantMask(nick, realJID, st.From)
+	_, present := a.occupants[st.From]
+
+	var kind stats.MsgKind
+	channel, message := a.channel(room), ""
+
+	switch {
+	case st.Type == "unavailable" && hasCode(codes, statusNickChange):
+		if st.MUC == nil || len(st.MUC.Item.Nick) == 0 {
+			return false
+		}
+		delete(a.occupants, st.From)
+		kind, channel, message = stats.Nick, "", nickJunk.Replace(st.MUC.Item.Nick)
+		mask = a.hostmask(st.From)
+		// the presence of the new nick is a change, not a join
+		next := room + "/" + st.MUC.Item.Nick
+		a.occupants[next] = occupantMask(st.MUC.Item.Nick, realJID, next)
+	case st.Type == "unavailable":
+		mask = a.hostmask(st.From)
+		delete(a.occupants, st.From)
+		kind = stats.Part
+		if hasCode(codes, statusKicked) {
+			message = "kicked"
+		}
+	case len(st.Type) == 0:
+		a.occupants[st.From] = mask
+		if present {
+			return false
+		}
+		kind = stats.Join
+	default:
+		return false
+	}
+
+	a.Stats.AddMessage(kind, a.Network, channel, mask, time.Now(), message)
+	return true
+}
+
+// msgid is the id messages are deduplicated by: the id the room gave it,
+// or the id given by the sender.
+func (a *Adapter) msgid(room string, st Stanza) string {
+	for _, sid := range st.StanzaIDs {
+		if strings.EqualFold(sid.By, room) && len(sid.ID) > 0 {
+			return "xmpp:" + room + ":" + sid.ID
+		}
+	}
+	if len(st.ID) > 0 {
+		return "xmpp:" + st.From + ":" + st.ID
+	}
+	return ""
+}
+
+// channel is the name a room is counted under.
+func (a *Adapter) channel(room string) string {
+	if name, ok := a.Rooms[room]; ok {
+		return name
+	}
+	if i := strings.IndexByte(room, '@'); i > 0 {
+		return "#" + room[:i]
+	}
+	return "#" + room
+}
+
+// hostmask is the hostmask of an occupant, from their presence when it was
+// seen.
+func (a *Adapter) hostmask(occupant string) string {
+	if mask, ok := a.occupants[occupant]; ok {
+		return mask
+	}
+	_, nick := splitJID(occupant)
+	return occupantMask(nick, "", occupant)
+}
+
+// occupantMask builds a hostmask for an occupant out of their real jid when
+// the room shares it, dylan@zqz.ca/phone known as Dylan becomes
+// Dylan!dylan@zqz.ca. In anonymous rooms the room's domain is the host.
+func occupantMask(nick, realJID, occupant string) string {
+	nick = nickJunk.Replace(nick)
+	if bare, _ := splitJID(realJID); len(bare) > 0 {
+		if i := strings.IndexByte(bare, '@'); i > 0 {
+			return nick + "!" + bare[:i] + "@" + bare[i+1:]
+		}
+	}
+
+	room, _ := splitJID(occupant)
+	host := room
+	if i := strings.IndexByte(room, '@'); i >= 0 {
+		host = room[i+1:]
+	}
+	return nick + "!" + nick + "@" + host
+}
+
+// nickJunk are the characters MUC nicks may have but hostmasks can't.
+var nickJunk = strings.NewReplacer(" ", "_", "\t", "_", "!", "_", "@", "_")
+
+// splitJID splits a jid into its bare jid and resource, the resource of an
+// occupant jid is their nick.
+func splitJID(jid string) (bare, resource string) {
+	if i := strings.IndexByte(jid, '/'); i >= 0 {
+		return jid[:i], jid[i+1:]
+	}
+	return jid, ""
+}
+
+func hasCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package xmpp
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/DylanJ/stats"
+)
+
+const room = "deviate@conference.zqz.ca"
+
+func stanza(t *testing.T, raw string) Stanza {
+	var st Stanza
+	if err := xml.Unmarshal([]byte(raw), &st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestAdapter_message(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "zkpq")
+
+	msgs := []string{
+		`<message from='deviate@conference.zqz.ca/dylan' type='groupchat' id='1'>
+			<body>hello
+there</body><stanza-id xmlns='urn:xmpp:sid:0' id='a1' by='deviate@conference.zqz.ca'/></message>`,
+		`<message from='deviate@conference.zqz.ca/dylan' type='groupchat' id='1'>
+			<body>hello
+there</body><stanza-id xmlns='urn:xmpp:sid:0' id='a1' by='deviate@conference.zqz.ca'/></message>`,
+		`<message from='deviate@conference.zqz.ca/aaron' type='groupchat'><body>/me waves</body>
+			<delay xmlns='urn:xmpp:delay' stamp='2014-03-01T08:00:00Z'/></message>`,
+		`<message from='deviate@conference.zqz.ca/carol' type='groupchat'><subject>go go go</subject></message>`,
+		`<message from='deviate@conference.zqz.ca/aaron' type='chat'><body>private</body></message>`,
+		`<message from='deviate@conference.zqz.ca' type='groupchat'><body>This room is not anonymous</body></message>`,
+	}
+	counted := 0
+	for _, m := range msgs {
+		if a.HandleStanza(stanza(t, m)) {
+			counted++
+		}
+	}
+	if counted != 3 {
+		t.Error("Should count the message once, the action and the subject, Got:", counted)
+	}
+
+	c := s.GetChannel("zkpq", "#deviate")
+	if c == nil {
+		t.Fatal("Should name the room after its jid.")
+	}
+	if len(c.Topics) != 1 || c.Topics[0].Message != "go go go" {
+		t.Error("Should count the subject as a topic:", c.Topics)
+	}
+
+	if u := s.GetUser("zkpq", "dylan"); u == nil || u.Lines != 2 {
+		t.Error("Should count each line of dylan's message:", u)
+	}
+	u := s.GetUser("zkpq", "aaron")
+	if u == nil || u.TextByKind[stats.Action].Lines != 1 {
+		t.Fatal("Should count aaron's action:", u)
+	}
+	if u.LastSeen.Year() != 2014 {
+		t.Error("Should date history by its delay:", u.LastSeen)
+	}
+}
+
+func TestAdapter_presence(t *testing.T) {
+	t.Parallel()
+
+	s, err := stats.NewStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := New(s, "")
+	a.Rooms[room] = "#chat"
+
+	presences := []struct {
+		raw   string
+		count bool
+	}{
+		{`<presence from='deviate@conference.zqz.ca/stats'><x xmlns='http://jabber.org/protocol/muc#user'>
+			<status code='110'/></x></presence>`, false},
+		{`<presence from='deviate@conference.zqz.ca/Dylan J'><x xmlns='http://jabber.org/protocol/muc#user'>
+			<item jid='dylan@zqz.ca/phone'/></x></presence>`, true},
+		{`<presence from='deviate@conference.zqz.ca/Dylan J'><show>away</show></presence>`, false},
+		{`<presence from='deviate@conference.zqz.ca/Dylan J' type='unavailable'><x xmlns='http://jabber.org/protocol/muc#user'>
+			<item nick='dilly'/><status code='303'/></x></presence>`, true},
+		{`<presence from='deviate@conference.zqz.ca/dilly'/>`, false},
+		{`<presence from='deviate@conference.zqz.ca/dilly' type='unavailable'/>`, true},
+	}
+	for _, p := range presences {
+		if counted := a.HandleStanza(stanza(t, p.raw)); counted != p.count {
+			t.Errorf("%s: Expected: %v, Got: %v", p.raw, p.count, counted)
+		}
+	}
+
+	if s.GetUser(defaultNetwork, "stats") != nil {
+		t.Error("Should not count its own presence.")
+	}
+	if u := s.GetUser(defaultNetwork, "dylan_j"); u == nil || u.NickChanges != 1 {
+		t.Error("Should count the nick change:", u)
+	}
+	if u := s.GetUser(defaultNetwork, "dilly"); u == nil || u.MessageRanges.Len() != 1 {
+		t.Error("Should count dilly leaving:", u)
+	}
+}
+
+func TestOccupantMask(t *testing.T) {
+	t.Parallel()
+
+	if got := occupantMask("Dylan J", "dylan@zqz.ca/phone", room+"/Dylan J"); got != "Dylan_J!dylan@zqz.ca" {
+		t.Error("Should use the real jid:", got)
+	}
+	if got := occupantMask("aaron", "", room+"/aaron"); got != "aaron!aaron@conference.zqz.ca" {
+		t.Error("Should fall back to the room's domain:", got)
+	}
+}